@@ -1,10 +1,11 @@
 package aod
 
 import (
-	"log"
 	"math"
+	"time"
 
 	"master/internal/db"
+	"master/internal/logging"
 )
 
 // BuildAffinityMatrix computes affinity scores for each (taskType, workerID) pair
@@ -20,9 +21,13 @@ import (
 //	SpeedAdvantage = τ / worker_avg_runtime
 //	SLAReliability = sla_success_count / completed_tasks
 //
+// Runtimes and SLA outcomes are time-decayed: a record's weight halves every halfLife
+// of age (measured from its finish time to now), so recent tasks count more than old ones.
+// A halfLife of 0 weights all history equally.
+//
 // Returns a nested map: map[taskType][workerID]float64
 // Affinity values are clipped to [-5.0, +5.0] for numerical stability
-func BuildAffinityMatrix(history []db.TaskHistory) map[string]map[string]float64 {
+func BuildAffinityMatrix(history []db.TaskHistory, halfLife time.Duration, now time.Time) map[string]map[string]float64 {
 	affinity := make(map[string]map[string]float64)
 
 	// Define the 6 standardized task types
@@ -59,21 +64,8 @@ func BuildAffinityMatrix(history []db.TaskHistory) map[string]map[string]float64
 				continue
 			}
 
-			// Compute SpeedAdvantage = τ / worker_avg_runtime
-			workerAvgRuntime := computeWorkerAvgRuntime(taskType, workerID, history)
-			speedAdvantage := 0.0
-			if workerAvgRuntime > 0 {
-				speedAdvantage = baselineTau / workerAvgRuntime
-			}
-
-			// Compute SLAReliability = sla_success_count / completed_tasks
-			slaSuccessCount := 0
-			for _, record := range pairHistory {
-				if record.SLASuccess {
-					slaSuccessCount++
-				}
-			}
-			slaReliability := float64(slaSuccessCount) / float64(len(pairHistory))
+			speedAdvantage := computeSpeed(baselineTau, pairHistory, halfLife, now)
+			slaReliability := computeSLAReliability(pairHistory, halfLife, now)
 
 			// Compute affinity: NO WEIGHTS, direct sum
 			rawAffinity := speedAdvantage + slaReliability
@@ -83,7 +75,7 @@ func BuildAffinityMatrix(history []db.TaskHistory) map[string]map[string]float64
 
 			affinity[taskType][workerID] = clippedAffinity
 
-			log.Printf("📊 Affinity[%s][%s] = %.3f (speed=%.3f, SLA=%.3f)",
+			logging.Infof("📊 Affinity[%s][%s] = %.3f (speed=%.3f, SLA=%.3f)",
 				taskType, workerID, clippedAffinity, speedAdvantage, slaReliability)
 		}
 	}
@@ -142,19 +134,57 @@ func computeBaselineRuntime(taskType string, history []db.TaskHistory) float64 {
 	return totalRuntime / float64(len(filtered))
 }
 
-// computeWorkerAvgRuntime computes the average runtime for a specific (taskType, workerID) pair.
-func computeWorkerAvgRuntime(taskType, workerID string, history []db.TaskHistory) float64 {
-	filtered := filterHistory(history, taskType, workerID)
-	if len(filtered) == 0 {
+// computeSpeed computes SpeedAdvantage = τ / worker_avg_runtime for one (taskType, workerID) pair,
+// using the decay-weighted average runtime of pairHistory
+func computeSpeed(baselineTau float64, pairHistory []db.TaskHistory, halfLife time.Duration, now time.Time) float64 {
+	totalWeight := 0.0
+	weightedRuntime := 0.0
+	for _, record := range pairHistory {
+		weight := decayWeight(record, halfLife, now)
+		totalWeight += weight
+		weightedRuntime += weight * record.ActualRuntime
+	}
+	if totalWeight <= 0 || weightedRuntime <= 0 {
 		return 0.0
 	}
 
-	totalRuntime := 0.0
-	for _, record := range filtered {
-		totalRuntime += record.ActualRuntime
+	return baselineTau / (weightedRuntime / totalWeight)
+}
+
+// computeSLAReliability computes the decay-weighted fraction of pairHistory that met its SLA deadline
+func computeSLAReliability(pairHistory []db.TaskHistory, halfLife time.Duration, now time.Time) float64 {
+	totalWeight := 0.0
+	successWeight := 0.0
+	for _, record := range pairHistory {
+		weight := decayWeight(record, halfLife, now)
+		totalWeight += weight
+		if record.SLASuccess {
+			successWeight += weight
+		}
+	}
+	if totalWeight <= 0 {
+		return 0.0
 	}
 
-	return totalRuntime / float64(len(filtered))
+	return successWeight / totalWeight
+}
+
+// decayWeight returns a history record's weight: 1 for a task that just finished, halving every halfLife of age
+func decayWeight(record db.TaskHistory, halfLife time.Duration, now time.Time) float64 {
+	if halfLife <= 0 {
+		return 1.0
+	}
+
+	finished := record.ActualFinish
+	if finished.IsZero() {
+		finished = record.ArrivalTime
+	}
+	age := now.Sub(finished)
+	if age < 0 {
+		age = 0
+	}
+
+	return math.Pow(0.5, age.Seconds()/halfLife.Seconds())
 }
 
 // filterHistory returns TaskHistory records matching both taskType and workerID
@@ -0,0 +1,45 @@
+package aod
+
+import (
+	"testing"
+	"time"
+
+	"master/internal/db"
+)
+
+// TestAffinityDecayFavorsRecentPerformance tests that recent poor performance outweighs older good performance
+func TestAffinityDecayFavorsRecentPerformance(t *testing.T) {
+	now := time.Now()
+	var history []db.TaskHistory
+	add := func(workerID string, age time.Duration, runtime float64, slaSuccess bool, count int) {
+		for i := 0; i < count; i++ {
+			history = append(history, db.TaskHistory{
+				WorkerID:      workerID,
+				Type:          "cpu-light",
+				ActualFinish:  now.Add(-age),
+				ActualRuntime: runtime,
+				SLASuccess:    slaSuccess,
+				Tau:           10,
+			})
+		}
+	}
+	// worker-a was fast two days ago but has slowed down and missed its deadlines in the last hour
+	add("worker-a", 48*time.Hour, 5, true, 6)
+	add("worker-a", time.Hour, 40, false, 2)
+	// worker-b has been mediocre but steady
+	add("worker-b", time.Hour, 20, true, 2)
+	add("worker-b", time.Hour, 20, false, 2)
+
+	flat := BuildAffinityMatrix(history, 0, now)["cpu-light"]
+	if flat["worker-a"] <= flat["worker-b"] {
+		t.Fatalf("Expected worker-a to lead without decay, got %.3f vs %.3f", flat["worker-a"], flat["worker-b"])
+	}
+
+	decayed := BuildAffinityMatrix(history, 6*time.Hour, now)["cpu-light"]
+	if decayed["worker-a"] >= decayed["worker-b"] {
+		t.Errorf("Expected worker-a's recent slowdown to drop it below worker-b, got %.3f vs %.3f", decayed["worker-a"], decayed["worker-b"])
+	}
+	if decayed["worker-a"] >= flat["worker-a"] {
+		t.Errorf("Expected decay to lower worker-a's affinity, got %.3f (was %.3f)", decayed["worker-a"], flat["worker-a"])
+	}
+}
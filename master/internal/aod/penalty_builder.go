@@ -1,10 +1,10 @@
 package aod
 
 import (
-	"log"
 	"math"
 
 	"master/internal/db"
+	"master/internal/logging"
 )
 
 // BuildPenaltyVector computes penalty scores for each worker based on
@@ -50,7 +50,7 @@ func BuildPenaltyVector(workerStats []db.WorkerStats) map[string]float64 {
 
 		penalty[stats.WorkerID] = clippedPenalty
 
-		log.Printf("⚠️  Penalty[%s] = %.3f (SLA_fail=%.3f, overload=%.3f, energy=%.3f)",
+		logging.Debugf("⚠️  Penalty[%s] = %.3f (SLA_fail=%.3f, overload=%.3f, energy=%.3f)",
 			stats.WorkerID, clippedPenalty, slaFailRate, overloadRate, energyNorm)
 	}
 
@@ -0,0 +1,81 @@
+package aod
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"master/internal/db"
+	"master/internal/logging"
+	"master/internal/telemetry"
+)
+
+// DefaultTauBootstrapWindow is how far back task history is read when bootstrapping tau
+const DefaultTauBootstrapWindow = 7 * 24 * time.Hour
+
+// DefaultTauBootstrapMinSamples is the fewest runtimes a task type needs before its median replaces the default tau
+const DefaultTauBootstrapMinSamples = 5
+
+// TaskHistorySource provides historical task executions (implemented by db.HistoryDB)
+type TaskHistorySource interface {
+	GetTaskHistory(ctx context.Context, since time.Time, until time.Time) ([]db.TaskHistory, error)
+}
+
+// BootstrapTau initializes each task type's tau from the median (p50) runtime seen over the last window
+// Task types with fewer than minSamples runtimes keep their current (default) tau
+// Returns the tau values that were set, keyed by task type
+func BootstrapTau(ctx context.Context, source TaskHistorySource, store telemetry.TauStore, window time.Duration, minSamples int) (map[string]float64, error) {
+	if window <= 0 {
+		window = DefaultTauBootstrapWindow
+	}
+	if minSamples <= 0 {
+		minSamples = DefaultTauBootstrapMinSamples
+	}
+
+	until := time.Now()
+	history, err := source.GetTaskHistory(ctx, until.Add(-window), until)
+	if err != nil {
+		return nil, fmt.Errorf("fetch task history: %w", err)
+	}
+
+	medians := MedianRuntimes(history, minSamples)
+	for taskType, tau := range medians {
+		store.SetTau(taskType, tau)
+		logging.Debugf("  - %s: %.1fs (p50 of history)", taskType, tau)
+	}
+	return medians, nil
+}
+
+// MedianRuntimes returns the median actual runtime per task type, for types with at least minSamples runtimes
+// Cache hits and records without a runtime are ignored since they say nothing about execution time
+func MedianRuntimes(history []db.TaskHistory, minSamples int) map[string]float64 {
+	runtimes := make(map[string][]float64)
+	for _, task := range history {
+		if task.CacheHit || task.ActualRuntime <= 0 || task.Type == "" {
+			continue
+		}
+		runtimes[task.Type] = append(runtimes[task.Type], task.ActualRuntime)
+	}
+
+	medians := make(map[string]float64)
+	for taskType, values := range runtimes {
+		if len(values) < minSamples {
+			continue
+		}
+		medians[taskType] = median(values)
+	}
+	return medians
+}
+
+// median returns the middle value of values (the mean of the two middle values for an even count)
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
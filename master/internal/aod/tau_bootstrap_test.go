@@ -0,0 +1,54 @@
+package aod
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	"master/internal/telemetry"
+)
+
+// staticHistory serves a fixed task history regardless of the requested window
+type staticHistory struct {
+	tasks []db.TaskHistory
+}
+
+func (s *staticHistory) GetTaskHistory(ctx context.Context, since time.Time, until time.Time) ([]db.TaskHistory, error) {
+	return s.tasks, nil
+}
+
+// TestBootstrapTauUsesMedianRuntimePerType tests that bootstrapped tau equals each type's median runtime
+func TestBootstrapTauUsesMedianRuntimePerType(t *testing.T) {
+	var tasks []db.TaskHistory
+	add := func(taskType string, runtimes ...float64) {
+		for _, runtime := range runtimes {
+			tasks = append(tasks, db.TaskHistory{Type: taskType, ActualRuntime: runtime})
+		}
+	}
+	add(telemetry.TaskTypeCPULight, 2, 9, 3, 4, 100)       // median 4
+	add(telemetry.TaskTypeGPUTraining, 300, 100, 200, 400) // median 250 (even count)
+	add(telemetry.TaskTypeMemoryHeavy, 50, 60)             // too few samples: keeps default
+	tasks = append(tasks, db.TaskHistory{Type: telemetry.TaskTypeCPULight, ActualRuntime: 0.01, CacheHit: true})
+
+	store := telemetry.NewInMemoryTauStore()
+	defaultMemoryTau := store.GetTau(telemetry.TaskTypeMemoryHeavy)
+
+	set, err := BootstrapTau(context.Background(), &staticHistory{tasks: tasks}, store, 24*time.Hour, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := store.GetTau(telemetry.TaskTypeCPULight); got != 4 {
+		t.Errorf("Expected cpu-light tau 4, got %.2f", got)
+	}
+	if got := store.GetTau(telemetry.TaskTypeGPUTraining); got != 250 {
+		t.Errorf("Expected gpu-training tau 250, got %.2f", got)
+	}
+	if got := store.GetTau(telemetry.TaskTypeMemoryHeavy); got != defaultMemoryTau {
+		t.Errorf("Expected memory-heavy to keep default tau %.2f, got %.2f", defaultMemoryTau, got)
+	}
+	if len(set) != 2 {
+		t.Errorf("Expected 2 bootstrapped task types, got %v", set)
+	}
+}
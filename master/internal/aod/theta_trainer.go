@@ -2,10 +2,10 @@ package aod
 
 import (
 	"fmt"
-	"log"
 	"math"
 
 	"master/internal/db"
+	"master/internal/logging"
 	"master/internal/scheduler"
 
 	"gonum.org/v1/gonum/mat"
@@ -26,26 +26,26 @@ import (
 func TrainTheta(history []db.TaskHistory) scheduler.Theta {
 	// Need at least 10 data points for meaningful regression
 	if len(history) < 10 {
-		log.Printf("⚠️  Insufficient data for Theta training (%d records), using defaults", len(history))
+		logging.Warnf("⚠️  Insufficient data for Theta training (%d records), using defaults", len(history))
 		return getDefaultTheta()
 	}
 
 	// Build regression matrix (X) and target vector (y)
 	X, y, err := buildRegressionMatrix(history)
 	if err != nil {
-		log.Printf("⚠️  Failed to build regression matrix: %v, using defaults", err)
+		logging.Warnf("⚠️  Failed to build regression matrix: %v, using defaults", err)
 		return getDefaultTheta()
 	}
 
 	if len(X) < 10 {
-		log.Printf("⚠️  Insufficient valid data points (%d), using defaults", len(X))
+		logging.Warnf("⚠️  Insufficient valid data points (%d), using defaults", len(X))
 		return getDefaultTheta()
 	}
 
 	// Solve linear regression: θ = (X^T X)^(-1) X^T y
 	thetaVec, err := solveLinearRegression(X, y)
 	if err != nil {
-		log.Printf("⚠️  Linear regression failed: %v, using defaults", err)
+		logging.Warnf("⚠️  Linear regression failed: %v, using defaults", err)
 		return getDefaultTheta()
 	}
 
@@ -57,7 +57,7 @@ func TrainTheta(history []db.TaskHistory) scheduler.Theta {
 		Theta4: boundTheta(thetaVec[3], "θ4"),
 	}
 
-	log.Printf("✓ Theta trained successfully: θ1=%.3f, θ2=%.3f, θ3=%.3f, θ4=%.3f",
+	logging.Infof("✓ Theta trained successfully: θ1=%.3f, θ2=%.3f, θ3=%.3f, θ4=%.3f",
 		theta.Theta1, theta.Theta2, theta.Theta3, theta.Theta4)
 
 	return theta
@@ -165,7 +165,7 @@ func solveLinearRegression(X [][]float64, y []float64) ([]float64, error) {
 	// Check if matrix is singular (determinant near zero)
 	det := mat.Det(&xtx)
 	if math.Abs(det) < 1e-10 {
-		log.Printf("⚠️  X^T X is near-singular (det=%.2e), adding regularization", det)
+		logging.Warnf("⚠️  X^T X is near-singular (det=%.2e), adding regularization", det)
 		// Add small regularization to diagonal (Ridge regression)
 		for i := 0; i < 4; i++ {
 			xtx.Set(i, i, xtx.At(i, i)+0.01)
@@ -200,17 +200,17 @@ func solveLinearRegression(X [][]float64, y []float64) ([]float64, error) {
 // Theta values outside [0, 2.0] are clipped and logged as warnings.
 func boundTheta(value float64, name string) float64 {
 	if math.IsNaN(value) || math.IsInf(value, 0) {
-		log.Printf("⚠️  %s is invalid (%.3f), using 0.1", name, value)
+		logging.Warnf("⚠️  %s is invalid (%.3f), using 0.1", name, value)
 		return 0.1
 	}
 
 	if value < 0 {
-		log.Printf("⚠️  %s is negative (%.3f), clipping to 0.0", name, value)
+		logging.Warnf("⚠️  %s is negative (%.3f), clipping to 0.0", name, value)
 		return 0.0
 	}
 
 	if value > 2.0 {
-		log.Printf("⚠️  %s is too large (%.3f), clipping to 2.0", name, value)
+		logging.Warnf("⚠️  %s is too large (%.3f), clipping to 2.0", name, value)
 		return 2.0
 	}
 
@@ -4,15 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"time"
 
 	"master/internal/db"
+	"master/internal/logging"
 	"master/internal/scheduler"
 )
 
-// RunTraining executes one complete AOD training cycle.
+// DefaultTrainingWindow is how much history a scheduled training cycle learns from
+const DefaultTrainingWindow = 24 * time.Hour
+
+// TrainingHistorySource provides the history a training cycle learns from (implemented by db.HistoryDB)
+type TrainingHistorySource interface {
+	TaskHistorySource
+	GetWorkerStats(ctx context.Context, since time.Time, until time.Time) ([]db.WorkerStats, error)
+}
+
+// RunTraining executes one complete AOD training cycle on the last DefaultTrainingWindow of history.
 //
 // This function:
 // 1. Fetches historical task and worker data from the database
@@ -25,52 +34,59 @@ import (
 //   - ctx: Context for cancellation and timeout
 //   - historyDB: Database connection for fetching historical data
 //   - paramsOutputPath: File path to save the optimized GAParams JSON
+//   - affinityHalfLife: Age at which a history record counts half as much toward affinity (0 = no decay)
 //
 // Returns: error if any step fails
-func RunTraining(ctx context.Context, historyDB *db.HistoryDB, paramsOutputPath string) error {
-	log.Println("🧬 Starting AOD training cycle...")
-	startTime := time.Now()
-
-	// Step 1: Fetch historical data (last 24 hours)
+func RunTraining(ctx context.Context, historyDB TrainingHistorySource, paramsOutputPath string, affinityHalfLife time.Duration) error {
 	until := time.Now()
-	since := until.Add(-24 * time.Hour)
+	return RunTrainingWindow(ctx, historyDB, until.Add(-DefaultTrainingWindow), until, paramsOutputPath, affinityHalfLife)
+}
+
+// RunTrainingWindow executes one AOD training cycle on the history between since and until.
+// Affinity decay is measured from until, so replaying a past window gives the parameters a cycle
+// run at that time would have produced. The parameters are only written to paramsOutputPath;
+// they take effect only if that is the file the RTS scheduler loads.
+func RunTrainingWindow(ctx context.Context, historyDB TrainingHistorySource, since, until time.Time, paramsOutputPath string, affinityHalfLife time.Duration) error {
+	logging.Info("🧬 Starting AOD training cycle...")
+	startTime := time.Now()
 
-	log.Printf("📊 Fetching task history from %s to %s", since.Format(time.RFC3339), until.Format(time.RFC3339))
+	// Step 1: Fetch historical data
+	logging.Infof("📊 Fetching task history from %s to %s", since.Format(time.RFC3339), until.Format(time.RFC3339))
 	history, err := historyDB.GetTaskHistory(ctx, since, until)
 	if err != nil {
 		return fmt.Errorf("fetch task history: %w", err)
 	}
 
-	log.Printf("📊 Fetching worker stats from %s to %s", since.Format(time.RFC3339), until.Format(time.RFC3339))
+	logging.Infof("📊 Fetching worker stats from %s to %s", since.Format(time.RFC3339), until.Format(time.RFC3339))
 	workerStats, err := historyDB.GetWorkerStats(ctx, since, until)
 	if err != nil {
 		return fmt.Errorf("fetch worker stats: %w", err)
 	}
 
-	log.Printf("✓ Retrieved %d task history records and %d worker stats", len(history), len(workerStats))
+	logging.Infof("✓ Retrieved %d task history records and %d worker stats", len(history), len(workerStats))
 
 	// Step 2: Check if we have sufficient data
 	minDataPoints := 2 // Minimum tasks required for meaningful training
 	if len(history) < minDataPoints {
-		log.Printf("⚠️  Insufficient data (%d tasks < %d required), using default parameters", len(history), minDataPoints)
+		logging.Warnf("⚠️  Insufficient data (%d tasks < %d required), using default parameters", len(history), minDataPoints)
 		return saveDefaultParams(paramsOutputPath)
 	}
 
 	// Step 3: Train Theta using linear regression
-	log.Println("🔧 Training Theta parameters using linear regression...")
+	logging.Info("🔧 Training Theta parameters using linear regression...")
 	theta := TrainTheta(history)
-	log.Printf("✓ Theta trained: θ₁=%.4f, θ₂=%.4f, θ₃=%.4f, θ₄=%.4f",
+	logging.Infof("✓ Theta trained: θ₁=%.4f, θ₂=%.4f, θ₃=%.4f, θ₄=%.4f",
 		theta.Theta1, theta.Theta2, theta.Theta3, theta.Theta4)
 
 	// Step 4: Build affinity matrix using direct computation (NO GA evolution, NO weights)
-	log.Println("🔧 Building affinity matrix using direct computation...")
-	affinityMatrix := BuildAffinityMatrix(history)
-	log.Printf("✓ Affinity matrix built with %d task types", len(affinityMatrix))
+	logging.Info("🔧 Building affinity matrix using direct computation...")
+	affinityMatrix := BuildAffinityMatrix(history, affinityHalfLife, until)
+	logging.Infof("✓ Affinity matrix built with %d task types", len(affinityMatrix))
 
 	// Step 5: Build penalty vector using direct computation
-	log.Println("🔧 Building penalty vector...")
+	logging.Info("🔧 Building penalty vector...")
 	penaltyVector := BuildPenaltyVector(workerStats)
-	log.Printf("✓ Penalty vector built for %d workers", len(penaltyVector))
+	logging.Infof("✓ Penalty vector built for %d workers", len(penaltyVector))
 
 	// Step 6: Create GAParams structure (simplified - no weights)
 	params := scheduler.GAParams{
@@ -86,7 +102,7 @@ func RunTraining(ctx context.Context, historyDB *db.HistoryDB, paramsOutputPath
 	}
 
 	elapsed := time.Since(startTime)
-	log.Printf("✅ AOD training completed in %s, parameters saved to %s", elapsed, paramsOutputPath)
+	logging.Infof("✅ AOD training completed in %s, parameters saved to %s", elapsed, paramsOutputPath)
 
 	return nil
 }
@@ -102,7 +118,7 @@ func saveParams(params scheduler.GAParams, filePath string) error {
 		return fmt.Errorf("write file: %w", err)
 	}
 
-	log.Printf("✓ AOD parameters saved to %s", filePath)
+	logging.Infof("✓ AOD parameters saved to %s", filePath)
 	return nil
 }
 
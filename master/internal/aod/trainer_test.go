@@ -0,0 +1,49 @@
+package aod
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"master/internal/db"
+)
+
+// windowRecorder records the windows history was requested for
+type windowRecorder struct {
+	taskSince, taskUntil   time.Time
+	statsSince, statsUntil time.Time
+}
+
+func (w *windowRecorder) GetTaskHistory(ctx context.Context, since time.Time, until time.Time) ([]db.TaskHistory, error) {
+	w.taskSince, w.taskUntil = since, until
+	return nil, nil
+}
+
+func (w *windowRecorder) GetWorkerStats(ctx context.Context, since time.Time, until time.Time) ([]db.WorkerStats, error) {
+	w.statsSince, w.statsUntil = since, until
+	return nil, nil
+}
+
+// TestRunTrainingWindowUsesSuppliedBounds tests that a replayed epoch fetches history for exactly the given window
+func TestRunTrainingWindowUsesSuppliedBounds(t *testing.T) {
+	since := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2025, time.June, 3, 12, 0, 0, 0, time.UTC)
+	out := filepath.Join(t.TempDir(), "replay.json")
+
+	recorder := &windowRecorder{}
+	if err := RunTrainingWindow(context.Background(), recorder, since, until, out, time.Hour); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !recorder.taskSince.Equal(since) || !recorder.taskUntil.Equal(until) {
+		t.Errorf("Expected task history for %s to %s, got %s to %s", since, until, recorder.taskSince, recorder.taskUntil)
+	}
+	if !recorder.statsSince.Equal(since) || !recorder.statsUntil.Equal(until) {
+		t.Errorf("Expected worker stats for %s to %s, got %s to %s", since, until, recorder.statsSince, recorder.statsUntil)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("Expected parameters written to %s, got %v", out, err)
+	}
+}
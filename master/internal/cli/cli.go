@@ -10,11 +10,14 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"master/internal/aod"
 	"master/internal/db"
 	"master/internal/server"
 	"master/internal/storage"
+	"master/internal/telemetry"
 	pb "master/proto"
 
 	"github.com/chzyer/readline"
@@ -25,11 +28,25 @@ type CLI struct {
 	masterServer *server.MasterServer
 	fileStorage  *storage.FileStorageService
 	rl           *readline.Instance
+	userID       string // Owner of tasks submitted from the CLI
+
+	// Command history persisted across sessions ("" disables persistence)
+	historyPath string
+	history     []string
+
+	// GA training replay (nil history disables the replay command)
+	trainingHistory  aod.TrainingHistorySource
+	activeParamsPath string
+	affinityHalfLife time.Duration
+
+	// Scheduler simulation: runtime estimates and SLA multiplier RTS is simulated with
+	tauStore      telemetry.TauStore
+	slaMultiplier float64
 }
 
 // NewCLI creates a new CLI instance
 func NewCLI(srv *server.MasterServer, fs *storage.FileStorageService) *CLI {
-	rl, err := readline.New("master> ")
+	rl, err := readline.New(DefaultPrompt)
 	if err != nil {
 		log.Fatalf("Failed to create readline instance: %v", err)
 	}
@@ -37,6 +54,47 @@ func NewCLI(srv *server.MasterServer, fs *storage.FileStorageService) *CLI {
 		masterServer: srv,
 		fileStorage:  fs,
 		rl:           rl,
+		userID:       DefaultUser,
+	}
+}
+
+// SetPrompt replaces the interactive prompt
+func (c *CLI) SetPrompt(prompt string) {
+	if prompt == "" {
+		prompt = DefaultPrompt
+	}
+	c.rl.SetPrompt(prompt)
+}
+
+// SetHistoryFile loads command history from path so arrow keys reach earlier sessions
+// New commands are saved back to the same file ("" disables persistence)
+func (c *CLI) SetHistoryFile(path string) {
+	c.historyPath = path
+	c.history = nil
+	if path == "" {
+		return
+	}
+
+	commands, err := loadHistory(path)
+	if err != nil {
+		log.Printf("⚠️  Failed to load command history from %s: %v", path, err)
+		return
+	}
+	for _, command := range commands {
+		c.rl.SaveHistory(command)
+	}
+	c.history = commands
+}
+
+// recordHistory appends a command to the persisted history
+func (c *CLI) recordHistory(command string) {
+	if c.historyPath == "" {
+		return
+	}
+
+	c.history = trimHistory(append(c.history, command))
+	if err := saveHistory(c.historyPath, c.history); err != nil {
+		log.Printf("⚠️  Failed to save command history: %v", err)
 	}
 }
 
@@ -60,6 +118,7 @@ func (c *CLI) Run() {
 		if input == "" {
 			continue
 		}
+		c.recordHistory(input)
 
 		parts := strings.Fields(input)
 		command := parts[0]
@@ -78,6 +137,8 @@ func (c *CLI) Run() {
 				continue
 			}
 			c.showWorkerStats(parts[1])
+		case "top":
+			c.liveTop()
 		case "internal-state":
 			if len(parts) != 1 {
 				fmt.Println("Usage: internal-state")
@@ -86,6 +147,16 @@ func (c *CLI) Run() {
 			c.liveInternalState()
 		case "fix-resources":
 			c.reconcileResources()
+		case "diff-state":
+			c.diffWorkerState()
+		case "reconcile":
+			if len(parts) < 2 {
+				fmt.Println("Usage: reconcile <worker_id>")
+				fmt.Println("  Reconciles one worker's allocations against its running tasks")
+				fmt.Println("  (use 'fix-resources' to reconcile the whole cluster)")
+				continue
+			}
+			c.reconcileWorker(parts[1])
 		case "list-tasks":
 			if len(parts) < 2 {
 				// No status provided - show all tasks categorically
@@ -96,26 +167,49 @@ func (c *CLI) Run() {
 			}
 		case "register":
 			if len(parts) < 3 {
-				fmt.Println("Usage: register <worker_id> <worker_ip:port>")
-				fmt.Println("Example: register worker-1 192.168.1.100:50052")
+				fmt.Println("Usage: register <worker_id> <worker_ip:port> [-cpu_cores <num>] [-mem <gb>] [-gpu_cores <num>]")
+				fmt.Println("  -cpu_cores, -mem, -gpu_cores: Capacity the worker is expected to have; a worker claiming")
+				fmt.Println("   far more when it connects is flagged (or rejected with REJECT_CAPACITY_MISMATCH=true)")
+				fmt.Println("Example: register worker-1 192.168.1.100:50052 -cpu_cores 8 -mem 32")
+				continue
+			}
+			declared, err := parseDeclaredCapacity(parts[3:])
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
 				continue
 			}
-			c.registerWorker(parts[1], parts[2])
+			c.registerWorker(parts[1], parts[2], declared)
 		case "unregister":
 			if len(parts) < 2 {
 				fmt.Println("Usage: unregister <worker_id>")
 				continue
 			}
 			c.unregisterWorker(parts[1])
-		case "task":
+		case "task", "submit":
 			if len(parts) < 2 {
-				fmt.Println("Usage: task <docker_image> [-name <task_name>] [-cpu_cores <num>] [-mem <gb>] [-storage <gb>] [-gpu_cores <num>]")
+				fmt.Println("Usage: task|submit <docker_image> [-name <task_name>] [-cpu_cores <num>] [-mem <gb>] [-storage <gb>] [-gpu_cores <num>] [-pin] [-cache] [-deadline <RFC3339>] [-locality <key>] [-prefer <worker_id>] [-anti_affinity <key>] [-restarts <n>] [-hold] [-mem_limit <gb>] [-grace <sec>] [-priority <n>] [-network <mode>] [-port <spec>]... [-workdir <dir>] [-run_as <user>] [-read_only]")
 				fmt.Println("  docker_image: Docker image to run")
 				fmt.Println("  -name: Custom task name (default: auto-generated from image name)")
 				fmt.Println("  -cpu_cores: CPU cores to allocate (default: 1.0)")
 				fmt.Println("  -mem: Memory in GB (default: 0.5)")
+				fmt.Println("  -mem_limit: Hard memory cap in GB; when above -mem, -mem becomes a soft reservation")
 				fmt.Println("  -storage: Storage in GB (default: 1.0)")
 				fmt.Println("  -gpu_cores: GPU cores to allocate (default: 0.0)")
+				fmt.Println("  -pin: Pin the container to dedicated CPU cores (latency-sensitive tasks)")
+				fmt.Println("  -cache: Deterministic task - reuse a cached result for the same image and command")
+				fmt.Println("  -deadline: Absolute deadline, e.g. 2025-06-01T17:00:00Z (task expires if still queued)")
+				fmt.Println("  -locality: Locality key - tasks sharing it prefer the worker that ran the last one")
+				fmt.Println("  -prefer: Worker to try first; the scheduler picks another if it cannot take the task")
+				fmt.Println("  -anti_affinity: Anti-affinity key - tasks sharing it are spread across worker zones")
+				fmt.Println("  -restarts: Restart the container on non-zero exit up to n times (default: 0)")
+				fmt.Println("  -grace: Seconds between SIGTERM and SIGKILL when the task is cancelled (default: 10)")
+				fmt.Println("  -hold: Stage the task without scheduling it until 'release <task_id>'")
+				fmt.Println("  -priority: Task priority (default: 0); at or above PREEMPTION_PRIORITY it may preempt lower-priority tasks")
+				fmt.Println("  -network: Container network mode: bridge (default), none, or one listed in TASK_NETWORKS")
+				fmt.Println("  -port: Publish a container port on the worker, e.g. 8080 (any host port) or 9000:8080; repeatable")
+				fmt.Println("  -workdir: Working directory inside the container (default: the image's WORKDIR)")
+				fmt.Println("  -run_as: User to run the container as - name, UID or UID:GID (default: the image's USER)")
+				fmt.Println("  -read_only: Mount the container's root filesystem read-only (/output stays writable)")
 				fmt.Println("\nNote: The scheduler will automatically select the best worker.")
 				fmt.Println("      Files generated in /output will be automatically collected and stored.")
 				fmt.Println("\nExamples:")
@@ -127,14 +221,24 @@ func (c *CLI) Run() {
 			c.submitTask(parts)
 		case "dispatch":
 			if len(parts) < 3 {
-				fmt.Println("Usage: dispatch <worker_id> <docker_image> [-name <task_name>] [-cpu_cores <num>] [-mem <gb>] [-storage <gb>] [-gpu_cores <num>]")
+				fmt.Println("Usage: dispatch <worker_id> <docker_image> [-name <task_name>] [-cpu_cores <num>] [-mem <gb>] [-storage <gb>] [-gpu_cores <num>] [-pin] [-cache] [-restarts <n>] [-mem_limit <gb>] [-grace <sec>] [-network <mode>] [-port <spec>]... [-workdir <dir>] [-run_as <user>] [-read_only]")
 				fmt.Println("  worker_id: Specific worker to dispatch task to")
 				fmt.Println("  docker_image: Docker image to run")
 				fmt.Println("  -name: Custom task name (default: auto-generated from image name)")
 				fmt.Println("  -cpu_cores: CPU cores to allocate (default: 1.0)")
 				fmt.Println("  -mem: Memory in GB (default: 0.5)")
+				fmt.Println("  -mem_limit: Hard memory cap in GB; when above -mem, -mem becomes a soft reservation")
 				fmt.Println("  -storage: Storage in GB (default: 1.0)")
 				fmt.Println("  -gpu_cores: GPU cores to allocate (default: 0.0)")
+				fmt.Println("  -pin: Pin the container to dedicated CPU cores (latency-sensitive tasks)")
+				fmt.Println("  -cache: Deterministic task - reuse a cached result for the same image and command")
+				fmt.Println("  -restarts: Restart the container on non-zero exit up to n times (default: 0)")
+				fmt.Println("  -grace: Seconds between SIGTERM and SIGKILL when the task is cancelled (default: 10)")
+				fmt.Println("  -network: Container network mode: bridge (default), none, or one listed in TASK_NETWORKS")
+				fmt.Println("  -port: Publish a container port on the worker, e.g. 8080 (any host port) or 9000:8080; repeatable")
+				fmt.Println("  -workdir: Working directory inside the container (default: the image's WORKDIR)")
+				fmt.Println("  -run_as: User to run the container as - name, UID or UID:GID (default: the image's USER)")
+				fmt.Println("  -read_only: Mount the container's root filesystem read-only (/output stays writable)")
 				fmt.Println("\nNote: This bypasses the scheduler and directly assigns to the specified worker.")
 				fmt.Println("      Files generated in /output will be automatically collected and stored.")
 				fmt.Println("\nExamples:")
@@ -160,8 +264,115 @@ func (c *CLI) Run() {
 				continue
 			}
 			c.cancelTask(parts[1])
+		case "release":
+			if len(parts) < 2 {
+				fmt.Println("Usage: release <task_id>")
+				fmt.Println("  task_id: ID of a task submitted with -hold")
+				fmt.Println("Example: release task-123")
+				continue
+			}
+			c.releaseTask(parts[1])
+		case "requeue":
+			if len(parts) < 2 {
+				fmt.Println("Usage: requeue <task_id>")
+				fmt.Println("  task_id: ID of a finished (e.g. failed) task to run again with the same spec")
+				fmt.Println("Example: requeue task-123")
+				continue
+			}
+			c.requeueTask(parts[1])
+		case "retry":
+			if len(parts) < 3 {
+				fmt.Println("Usage: retry <task_id> <worker_id>")
+				fmt.Println("  task_id: ID of a finished (e.g. failed) task to run again with the same spec")
+				fmt.Println("  worker_id: Worker to run the new task on (bypasses the scheduler)")
+				fmt.Println("Example: retry task-123 worker-2")
+				continue
+			}
+			c.retryTask(parts[1], parts[2])
+		case "recommend":
+			if len(parts) < 2 {
+				fmt.Println("Usage: recommend <task_id>")
+				fmt.Println("  task_id: ID of a finished task to suggest right-sized CPU/memory requests for")
+				fmt.Println("Example: recommend task-123")
+				continue
+			}
+			c.recommendResources(parts[1])
+		case "replay":
+			args, err := parseReplayArgs(parts[1:])
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println("Usage: replay --since <RFC3339> --until <RFC3339> [--out <file>]")
+				fmt.Println("  Re-runs GA training on a fixed history window and writes the parameters")
+				fmt.Printf("  to a file (default %s) without activating them\n", DefaultReplayOutput)
+				fmt.Println("Example: replay --since 2025-06-01T00:00:00Z --until 2025-06-02T00:00:00Z --out /tmp/june1.json")
+				continue
+			}
+			c.replayTraining(args)
+		case "simulate":
+			args, err := parseSimulateArgs(parts[1:], time.Now())
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println("Usage: simulate --scheduler <rts|round-robin> --since <RFC3339> [--until <RFC3339>]")
+				fmt.Println("  Replays past task arrivals against a scheduler on the registered workers")
+				fmt.Println("  and reports SLA success and utilization without running anything")
+				fmt.Println("Example: simulate --scheduler rts --since 2025-06-01T00:00:00Z")
+				continue
+			}
+			c.simulate(args)
 		case "queue":
 			c.showQueue()
+		case "queue-why":
+			if len(parts) < 2 {
+				fmt.Println("Usage: queue-why <task_id>")
+				fmt.Println("  task_id: ID of a queued task to explain")
+				fmt.Println("Example: queue-why task-123")
+				continue
+			}
+			c.explainQueuedTask(parts[1])
+		case "scheduler-stats":
+			c.showSchedulerStats()
+		case "gc-tasks":
+			policy := server.TaskGCPolicyFail
+			if len(parts) > 1 {
+				policy = parts[1]
+			}
+			if len(parts) > 2 || !server.ValidTaskGCPolicy(policy) {
+				fmt.Println("Usage: gc-tasks [fail|requeue]")
+				fmt.Println("  Cleans up 'running' tasks whose worker is inactive or unregistered")
+				fmt.Println("  fail: mark them failed (default); requeue: put them back on the queue")
+				continue
+			}
+			c.collectStuckTasks(policy)
+		case "join-token":
+			ttl := server.DefaultJoinTokenTTL
+			if len(parts) > 1 {
+				parsed, err := time.ParseDuration(parts[1])
+				if err != nil || parsed <= 0 {
+					fmt.Println("Usage: join-token [ttl]")
+					fmt.Println("  ttl: How long the token is valid, e.g. 1h or 30m (default 24h)")
+					continue
+				}
+				ttl = parsed
+			}
+			c.issueJoinToken(ttl)
+		case "prewarm":
+			if len(parts) < 3 {
+				fmt.Println("Usage: prewarm <worker_id> <docker_image> [docker_image...]")
+				fmt.Println("  worker_id: Worker that should pull the images")
+				fmt.Println("  docker_image: One or more images to pre-pull")
+				fmt.Println("Example: prewarm worker-1 docker.io/user/sample-task:latest")
+				continue
+			}
+			c.prewarmImages(parts[1], parts[2:])
+		case "validate-image":
+			if len(parts) < 3 {
+				fmt.Println("Usage: validate-image <worker_id> <docker_image>")
+				fmt.Println("  worker_id: Worker that should check the image")
+				fmt.Println("  docker_image: Image to check (pulled if missing, never run)")
+				fmt.Println("Example: validate-image worker-1 docker.io/user/sample-task:latest")
+				continue
+			}
+			c.validateImage(parts[1], parts[2])
 		case "files":
 			if len(parts) < 2 {
 				fmt.Println("Usage: files <user_id> [requesting_user]")
@@ -233,17 +444,33 @@ func (c *CLI) printHelp() {
 	fmt.Println("  status                         - Show cluster status")
 	fmt.Println("  workers                        - List all registered workers")
 	fmt.Println("  stats <worker_id>              - Show detailed stats for a worker")
+	fmt.Println("  top                            - Live view of the busiest workers and longest-running tasks")
 	fmt.Println("  internal-state                 - Dump complete in-memory state of all workers")
 	fmt.Println("  fix-resources                  - Fix stale resource allocations")
-	fmt.Println("  list-tasks [status]            - List all tasks (or filter by: pending/running/completed/failed)")
+	fmt.Println("  diff-state                     - Compare in-memory worker resources against the database")
+	fmt.Println("  reconcile <worker_id>          - Fix stale resource allocations on a single worker")
+	fmt.Println("  gc-tasks [fail|requeue]        - Clean up running tasks whose worker is inactive or gone")
+	fmt.Println("  join-token [ttl]               - Issue a token workers can auto-register with")
+	fmt.Println("  list-tasks [status]            - List all tasks (or filter by: held/pending/running/completed/failed)")
 	fmt.Println("  register <id> <ip:port>        - Manually register a worker")
 	fmt.Println("  unregister <id>                - Unregister a worker")
-	fmt.Println("  task <docker_img> [-cpu_cores <num>] [-mem <gb>] [-storage <gb>] [-gpu_cores <num>] [-k <1.5-2.5>] [-type <task_type>]")
+	fmt.Println("  task <docker_img> [-cpu_cores <num>] [-mem <gb>] [-storage <gb>] [-gpu_cores <num>] [-k <1.5-2.5>] [-type <task_type>] [-pin] [-cache] [-deadline <RFC3339>] [-locality <key>] [-prefer <worker_id>] [-anti_affinity <key>] [-restarts <n>] [-hold] [-mem_limit <gb>] [-grace <sec>] [-priority <n>]")
 	fmt.Println("                                 - Submit task (scheduler selects worker)")
+	fmt.Println("  submit <docker_img> [options]  - Same as task: queue for the scheduler to place on the best worker")
 	fmt.Println("  dispatch <worker_id> <docker_img> [options]  - Dispatch task directly to specific worker (testing)")
-	fmt.Println("  monitor <task_id>              - Monitor live logs for a task (press any key to exit)")
+	fmt.Println("  monitor <task_id>              - Monitor live logs and resource usage for a task (press any key to exit)")
 	fmt.Println("  cancel <task_id>               - Cancel a running task")
+	fmt.Println("  release <task_id>              - Queue a task submitted with -hold")
+	fmt.Println("  requeue <task_id>              - Submit a finished task again as a new task with the same spec")
+	fmt.Println("  retry <task_id> <worker_id>    - Run a finished task again as a new task on a specific worker")
+	fmt.Println("  recommend <task_id>            - Suggest right-sized CPU/memory requests from a task's measured usage")
+	fmt.Println("  replay --since <t> --until <t> [--out <file>] - Re-run GA training on a past window without activating it")
+	fmt.Println("  simulate --scheduler <rts|round-robin> --since <t> [--until <t>] - Replay past tasks against a scheduler")
 	fmt.Println("  queue                          - Show pending tasks in the queue")
+	fmt.Println("  queue-why <task_id>            - Explain why a queued task is waiting (per-worker shortfalls)")
+	fmt.Println("  scheduler-stats                - Show scheduling attempts, queue wait and assignment latency")
+	fmt.Println("  prewarm <worker_id> <image>... - Pre-pull images on a worker to cut task startup time")
+	fmt.Println("  validate-image <worker_id> <image> - Check an image exists and is usable on a worker")
 	fmt.Println("  files <user_id> [requesting_user]  - List all files for a user")
 	fmt.Println("  task-files <task_id> <user_id> [requesting_user]  - View files for a specific task")
 	fmt.Println("  download <task_id> <user_id> [requesting_user] [output_dir]  - Download all task files")
@@ -258,6 +485,7 @@ func (c *CLI) printHelp() {
 	fmt.Println("\nExamples:")
 	fmt.Println("  register worker-2 192.168.1.100:50052")
 	fmt.Println("  stats worker-1")
+	fmt.Println("  top")
 	fmt.Println("  internal-state")
 	fmt.Println("  task docker.io/user/sample-task:latest")
 	fmt.Println("  task docker.io/user/sample-task:latest -cpu_cores 2.0 -mem 1.0 -gpu_cores 1.0")
@@ -266,7 +494,17 @@ func (c *CLI) printHelp() {
 	fmt.Println("  dispatch worker-1 docker.io/user/sample-task:latest -cpu_cores 2.0 -mem 1.0")
 	fmt.Println("  monitor task-123")
 	fmt.Println("  cancel task-123")
+	fmt.Println("  release task-123")
+	fmt.Println("  recommend task-123")
+	fmt.Println("  replay --since 2025-06-01T00:00:00Z --until 2025-06-02T00:00:00Z --out /tmp/june1.json")
+	fmt.Println("  simulate --scheduler round-robin --since 2025-06-01T00:00:00Z")
 	fmt.Println("  queue")
+	fmt.Println("  queue-why task-123")
+	fmt.Println("  scheduler-stats")
+	fmt.Println("  prewarm worker-1 docker.io/user/sample-task:latest")
+	fmt.Println("  validate-image worker-1 docker.io/user/sample-task:latest")
+	fmt.Println("  reconcile worker-1")
+	fmt.Println("  gc-tasks requeue")
 	fmt.Println("  files alice")
 	fmt.Println("  task-files task-123 alice")
 	fmt.Println("  download task-123 alice")
@@ -578,9 +816,25 @@ func (c *CLI) submitTask(parts []string) {
 	reqMemory := 0.5
 	reqStorage := 1.0
 	reqGPU := 0.0
-	slaMultiplier := 2.0 // Default k value
-	taskType := ""       // Will be inferred if not specified
-	taskName := ""       // Optional task name
+	slaMultiplier := 2.0  // Default k value
+	taskType := ""        // Will be inferred if not specified
+	taskName := ""        // Optional task name
+	pinCPUs := false      // Pin container to dedicated cores
+	cacheable := false    // Allow the worker to serve a cached result
+	var deadline int64    // Optional absolute deadline (Unix timestamp)
+	localityKey := ""     // Related tasks sharing this key prefer the same worker
+	preferredWorker := "" // Worker tried first (soft hint)
+	antiAffinityKey := "" // Tasks sharing this key are spread across zones
+	maxRestarts := 0      // Container restarts allowed on non-zero exit
+	stopGrace := 0        // SIGTERM-to-SIGKILL grace on cancel (0 = worker default)
+	memLimit := 0.0       // Optional hard memory cap (GB); -mem becomes a soft reservation below it
+	hold := false         // Stage the task until released
+	priority := 0         // Higher is more important; may preempt lower-priority tasks
+	networkMode := ""     // Container network mode ("" = bridge)
+	var ports []string    // Container ports to publish on the worker host
+	workingDir := ""      // Working directory inside the container ("" = image default)
+	runAsUser := ""       // User the container runs as ("" = image default)
+	readOnly := false     // Mount the container's root filesystem read-only
 
 	// Parse flags
 	for i := 2; i < len(parts); i++ {
@@ -599,6 +853,15 @@ func (c *CLI) submitTask(parts []string) {
 					i++ // Skip the value
 				}
 			}
+		case "-mem_limit":
+			if i+1 < len(parts) {
+				if val, err := strconv.ParseFloat(parts[i+1], 64); err == nil && val >= 0 {
+					memLimit = val
+				} else {
+					fmt.Printf("⚠️  Warning: -mem_limit must be a non-negative number. Ignoring: %s\n", parts[i+1])
+				}
+				i++ // Skip the value
+			}
 		case "-storage":
 			if i+1 < len(parts) {
 				if val, err := strconv.ParseFloat(parts[i+1], 64); err == nil {
@@ -635,6 +898,85 @@ func (c *CLI) submitTask(parts []string) {
 				taskName = parts[i+1]
 				i++ // Skip the value
 			}
+		case "-pin":
+			pinCPUs = true
+		case "-cache":
+			cacheable = true
+		case "-hold":
+			hold = true
+		case "-deadline":
+			if i+1 < len(parts) {
+				if t, err := time.Parse(time.RFC3339, parts[i+1]); err == nil {
+					deadline = t.Unix()
+				} else {
+					fmt.Printf("⚠️  Warning: -deadline must be RFC3339 (e.g. 2025-06-01T17:00:00Z). Ignoring: %s\n", parts[i+1])
+				}
+				i++ // Skip the value
+			}
+		case "-locality":
+			if i+1 < len(parts) {
+				localityKey = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-prefer":
+			if i+1 < len(parts) {
+				preferredWorker = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-anti_affinity":
+			if i+1 < len(parts) {
+				antiAffinityKey = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-restarts":
+			if i+1 < len(parts) {
+				if val, err := strconv.Atoi(parts[i+1]); err == nil && val >= 0 {
+					maxRestarts = val
+				} else {
+					fmt.Printf("⚠️  Warning: -restarts must be a non-negative integer. Ignoring: %s\n", parts[i+1])
+				}
+				i++ // Skip the value
+			}
+		case "-grace":
+			if i+1 < len(parts) {
+				if val, err := strconv.Atoi(parts[i+1]); err == nil && val > 0 {
+					stopGrace = val
+				} else {
+					fmt.Printf("⚠️  Warning: -grace must be a positive number of seconds. Ignoring: %s\n", parts[i+1])
+				}
+				i++ // Skip the value
+			}
+		case "-priority":
+			if i+1 < len(parts) {
+				if val, err := strconv.Atoi(parts[i+1]); err == nil {
+					priority = val
+				} else {
+					fmt.Printf("⚠️  Warning: -priority must be an integer. Ignoring: %s\n", parts[i+1])
+				}
+				i++ // Skip the value
+			}
+		case "-network":
+			if i+1 < len(parts) {
+				networkMode = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-port":
+			if i+1 < len(parts) {
+				ports = append(ports, parts[i+1])
+				i++ // Skip the value
+			}
+		case "-workdir":
+			if i+1 < len(parts) {
+				workingDir = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-run_as":
+			if i+1 < len(parts) {
+				runAsUser = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-read_only":
+			readOnly = true
 		}
 	}
 
@@ -656,7 +998,7 @@ func (c *CLI) submitTask(parts []string) {
 	}
 
 	// Generate task ID
-	taskID := fmt.Sprintf("task-%d", time.Now().Unix())
+	taskID := server.NewTaskID()
 
 	// Generate default task name if not provided
 	if taskName == "" {
@@ -686,8 +1028,50 @@ func (c *CLI) submitTask(parts []string) {
 	fmt.Println("  Resource Requirements:")
 	fmt.Printf("    • CPU Cores:     %.2f cores\n", reqCPU)
 	fmt.Printf("    • Memory:        %.2f GB\n", reqMemory)
+	if memLimit > reqMemory {
+		fmt.Printf("    • Memory Cap:    %.2f GB (burst above request)\n", memLimit)
+	}
 	fmt.Printf("    • Storage:       %.2f GB\n", reqStorage)
 	fmt.Printf("    • GPU Cores:     %.2f cores\n", reqGPU)
+	if pinCPUs {
+		fmt.Println("    • CPU Pinning:   dedicated cores")
+	}
+	if cacheable {
+		fmt.Println("    • Cacheable:     reuse cached result if available")
+	}
+	if localityKey != "" {
+		fmt.Printf("    • Locality Key:  %s\n", localityKey)
+	}
+	if preferredWorker != "" {
+		fmt.Printf("    • Preferred:     %s (soft - falls back to any worker)\n", preferredWorker)
+	}
+	if antiAffinityKey != "" {
+		fmt.Printf("    • Anti-Affinity: %s (spread across zones)\n", antiAffinityKey)
+	}
+	if maxRestarts > 0 {
+		fmt.Printf("    • Max Restarts:  %d (on non-zero exit)\n", maxRestarts)
+	}
+	if stopGrace > 0 {
+		fmt.Printf("    • Stop Grace:    %ds (SIGTERM before SIGKILL)\n", stopGrace)
+	}
+	if priority != 0 {
+		fmt.Printf("    • Priority:      %d\n", priority)
+	}
+	if networkMode != "" {
+		fmt.Printf("    • Network:       %s\n", networkMode)
+	}
+	if workingDir != "" {
+		fmt.Printf("    • Working Dir:   %s\n", workingDir)
+	}
+	if runAsUser != "" {
+		fmt.Printf("    • Run As:        %s\n", runAsUser)
+	}
+	if readOnly {
+		fmt.Println("    • Root FS:       read-only (/output writable)")
+	}
+	if len(ports) > 0 {
+		fmt.Printf("    • Ports:         %s\n", strings.Join(ports, ", "))
+	}
 	fmt.Println("───────────────────────────────────────────────────────")
 	if taskType != "" {
 		fmt.Println("  Task Classification:")
@@ -699,24 +1083,44 @@ func (c *CLI) submitTask(parts []string) {
 		fmt.Println("───────────────────────────────────────────────────────")
 	}
 	fmt.Println("  SLA Configuration:")
-	fmt.Printf("    • SLA Multiplier (k): %.1f (Deadline = k × τ)\n", slaMultiplier)
+	if deadline > 0 {
+		fmt.Printf("    • Deadline:      %s (absolute, overrides k × τ)\n", time.Unix(deadline, 0).Format("2006-01-02 15:04:05"))
+	} else {
+		fmt.Printf("    • SLA Multiplier (k): %.1f (Deadline = k × τ)\n", slaMultiplier)
+	}
 	fmt.Println("───────────────────────────────────────────────────────")
 	fmt.Println("  Note: Scheduler will automatically select best worker")
 	fmt.Println("═══════════════════════════════════════════════════════")
 
 	task := &pb.Task{
-		TaskId:        taskID,
-		DockerImage:   dockerImage,
-		Command:       command,
-		ReqCpu:        reqCPU,
-		ReqMemory:     reqMemory,
-		ReqStorage:    reqStorage,
-		ReqGpu:        reqGPU,
-		TaskType:      taskType,
-		SlaMultiplier: slaMultiplier,
-		UserId:        "admin", // Default user for CLI tasks (can be made configurable)
-		TaskName:      taskName,
-		SubmittedAt:   submittedAt,
+		TaskId:             taskID,
+		DockerImage:        dockerImage,
+		Command:            command,
+		ReqCpu:             reqCPU,
+		ReqMemory:          reqMemory,
+		ReqStorage:         reqStorage,
+		ReqGpu:             reqGPU,
+		TaskType:           taskType,
+		SlaMultiplier:      slaMultiplier,
+		UserId:             c.userID,
+		TaskName:           taskName,
+		SubmittedAt:        submittedAt,
+		PinCpus:            pinCPUs,
+		Cacheable:          cacheable,
+		Deadline:           deadline,
+		LocalityKey:        localityKey,
+		PreferredWorkerId:  preferredWorker,
+		MaxRestarts:        int32(maxRestarts),
+		Hold:               hold,
+		MemLimit:           memLimit,
+		StopGracePeriodSec: int32(stopGrace),
+		AntiAffinityKey:    antiAffinityKey,
+		Priority:           int32(priority),
+		NetworkMode:        networkMode,
+		PortBindings:       ports,
+		WorkingDir:         workingDir,
+		RunAsUser:          runAsUser,
+		ReadOnlyRootfs:     readOnly,
 	}
 
 	err := c.submitTaskToMaster(task)
@@ -725,7 +1129,14 @@ func (c *CLI) submitTask(parts []string) {
 		return
 	}
 
-	fmt.Printf("\n✅ Task %s submitted successfully and queued for scheduling!\n", taskID)
+	if hold {
+		fmt.Printf("\n⏸️  Task %s submitted on hold\n", task.TaskId)
+		fmt.Printf("    Use 'release %s' to queue it for scheduling\n", task.TaskId)
+		return
+	}
+
+	// SubmitTask reassigns the ID if it collides with a stored task
+	fmt.Printf("\n✅ Task %s submitted successfully and queued for scheduling!\n", task.TaskId)
 	fmt.Println("    Use 'queue' command to view queued tasks")
 }
 
@@ -733,13 +1144,13 @@ func (c *CLI) submitTaskToMaster(task *pb.Task) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ack, err := c.masterServer.SubmitTask(ctx, task)
+	ack, err := c.masterServer.SubmitTaskAuto(ctx, task)
 	if err != nil {
 		return fmt.Errorf("failed to submit task: %w", err)
 	}
 
 	if !ack.Success {
-		return fmt.Errorf("task submission failed: %s", ack.Message)
+		return fmt.Errorf("task submission failed (%s): %s", ack.ErrorCode, ack.Message)
 	}
 
 	return nil
@@ -755,7 +1166,17 @@ func (c *CLI) dispatchTask(parts []string) {
 	reqMemory := 0.5
 	reqStorage := 1.0
 	reqGPU := 0.0
-	taskName := "" // Optional task name
+	taskName := ""     // Optional task name
+	pinCPUs := false   // Pin container to dedicated cores
+	cacheable := false // Allow the worker to serve a cached result
+	maxRestarts := 0   // Container restarts allowed on non-zero exit
+	stopGrace := 0     // SIGTERM-to-SIGKILL grace on cancel (0 = worker default)
+	memLimit := 0.0    // Optional hard memory cap (GB); -mem becomes a soft reservation below it
+	networkMode := ""  // Container network mode ("" = bridge)
+	var ports []string // Container ports to publish on the worker host
+	workingDir := ""   // Working directory inside the container ("" = image default)
+	runAsUser := ""    // User the container runs as ("" = image default)
+	readOnly := false  // Mount the container's root filesystem read-only
 
 	// Parse flags (starting from index 3 since we have worker_id and docker_image)
 	for i := 3; i < len(parts); i++ {
@@ -774,6 +1195,15 @@ func (c *CLI) dispatchTask(parts []string) {
 					i++ // Skip the value
 				}
 			}
+		case "-mem_limit":
+			if i+1 < len(parts) {
+				if val, err := strconv.ParseFloat(parts[i+1], 64); err == nil && val >= 0 {
+					memLimit = val
+				} else {
+					fmt.Printf("⚠️  Warning: -mem_limit must be a non-negative number. Ignoring: %s\n", parts[i+1])
+				}
+				i++ // Skip the value
+			}
 		case "-storage":
 			if i+1 < len(parts) {
 				if val, err := strconv.ParseFloat(parts[i+1], 64); err == nil {
@@ -793,11 +1223,55 @@ func (c *CLI) dispatchTask(parts []string) {
 				taskName = parts[i+1]
 				i++ // Skip the value
 			}
+		case "-pin":
+			pinCPUs = true
+		case "-cache":
+			cacheable = true
+		case "-restarts":
+			if i+1 < len(parts) {
+				if val, err := strconv.Atoi(parts[i+1]); err == nil && val >= 0 {
+					maxRestarts = val
+				} else {
+					fmt.Printf("⚠️  Warning: -restarts must be a non-negative integer. Ignoring: %s\n", parts[i+1])
+				}
+				i++ // Skip the value
+			}
+		case "-grace":
+			if i+1 < len(parts) {
+				if val, err := strconv.Atoi(parts[i+1]); err == nil && val > 0 {
+					stopGrace = val
+				} else {
+					fmt.Printf("⚠️  Warning: -grace must be a positive number of seconds. Ignoring: %s\n", parts[i+1])
+				}
+				i++ // Skip the value
+			}
+		case "-network":
+			if i+1 < len(parts) {
+				networkMode = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-port":
+			if i+1 < len(parts) {
+				ports = append(ports, parts[i+1])
+				i++ // Skip the value
+			}
+		case "-workdir":
+			if i+1 < len(parts) {
+				workingDir = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-run_as":
+			if i+1 < len(parts) {
+				runAsUser = parts[i+1]
+				i++ // Skip the value
+			}
+		case "-read_only":
+			readOnly = true
 		}
 	}
 
 	// Generate task ID
-	taskID := fmt.Sprintf("task-%d", time.Now().Unix())
+	taskID := server.NewTaskID()
 
 	// Generate default task name if not provided
 	if taskName == "" {
@@ -827,23 +1301,63 @@ func (c *CLI) dispatchTask(parts []string) {
 	fmt.Println("  Resource Requirements:")
 	fmt.Printf("    • CPU Cores:     %.2f cores\n", reqCPU)
 	fmt.Printf("    • Memory:        %.2f GB\n", reqMemory)
+	if memLimit > reqMemory {
+		fmt.Printf("    • Memory Cap:    %.2f GB (burst above request)\n", memLimit)
+	}
 	fmt.Printf("    • Storage:       %.2f GB\n", reqStorage)
 	fmt.Printf("    • GPU Cores:     %.2f cores\n", reqGPU)
+	if pinCPUs {
+		fmt.Println("    • CPU Pinning:   dedicated cores")
+	}
+	if cacheable {
+		fmt.Println("    • Cacheable:     reuse cached result if available")
+	}
+	if maxRestarts > 0 {
+		fmt.Printf("    • Max Restarts:  %d (on non-zero exit)\n", maxRestarts)
+	}
+	if stopGrace > 0 {
+		fmt.Printf("    • Stop Grace:    %ds (SIGTERM before SIGKILL)\n", stopGrace)
+	}
+	if networkMode != "" {
+		fmt.Printf("    • Network:       %s\n", networkMode)
+	}
+	if workingDir != "" {
+		fmt.Printf("    • Working Dir:   %s\n", workingDir)
+	}
+	if runAsUser != "" {
+		fmt.Printf("    • Run As:        %s\n", runAsUser)
+	}
+	if readOnly {
+		fmt.Println("    • Root FS:       read-only (/output writable)")
+	}
+	if len(ports) > 0 {
+		fmt.Printf("    • Ports:         %s\n", strings.Join(ports, ", "))
+	}
 	fmt.Println("───────────────────────────────────────────────────────")
 	fmt.Println("  ⚠️  NOTE: Bypassing scheduler - dispatching directly!")
 	fmt.Println("═══════════════════════════════════════════════════════")
 
 	task := &pb.Task{
-		TaskId:      taskID,
-		DockerImage: dockerImage,
-		Command:     command,
-		ReqCpu:      reqCPU,
-		ReqMemory:   reqMemory,
-		ReqStorage:  reqStorage,
-		ReqGpu:      reqGPU,
-		UserId:      "admin", // Default user for CLI tasks
-		TaskName:    taskName,
-		SubmittedAt: submittedAt,
+		TaskId:             taskID,
+		DockerImage:        dockerImage,
+		Command:            command,
+		ReqCpu:             reqCPU,
+		ReqMemory:          reqMemory,
+		ReqStorage:         reqStorage,
+		ReqGpu:             reqGPU,
+		UserId:             c.userID,
+		TaskName:           taskName,
+		SubmittedAt:        submittedAt,
+		PinCpus:            pinCPUs,
+		Cacheable:          cacheable,
+		MaxRestarts:        int32(maxRestarts),
+		MemLimit:           memLimit,
+		StopGracePeriodSec: int32(stopGrace),
+		NetworkMode:        networkMode,
+		PortBindings:       ports,
+		WorkingDir:         workingDir,
+		RunAsUser:          runAsUser,
+		ReadOnlyRootfs:     readOnly,
 	}
 
 	err := c.dispatchTaskToWorker(task, workerID)
@@ -852,7 +1366,7 @@ func (c *CLI) dispatchTask(parts []string) {
 		return
 	}
 
-	fmt.Printf("\n✅ Task %s dispatched directly to worker %s!\n", taskID, workerID)
+	fmt.Printf("\n✅ Task %s dispatched directly to worker %s!\n", task.TaskId, workerID)
 	fmt.Println("    Use 'monitor <task_id>' command to view task logs")
 }
 
@@ -868,13 +1382,45 @@ func (c *CLI) dispatchTaskToWorker(task *pb.Task, workerID string) error {
 	}
 
 	if !ack.Success {
-		return fmt.Errorf("task dispatch failed: %s", ack.Message)
+		return fmt.Errorf("task dispatch failed (%s): %s", ack.ErrorCode, ack.Message)
 	}
 
 	return nil
 }
 
-func (c *CLI) registerWorker(workerID, workerIP string) {
+// parseDeclaredCapacity reads the optional -cpu_cores, -mem and -gpu_cores flags of the register command
+// Returns nil when no capacity was declared
+func parseDeclaredCapacity(args []string) (*db.DeclaredCapacity, error) {
+	var declared db.DeclaredCapacity
+	for i := 0; i < len(args); i++ {
+		var target *float64
+		switch args[i] {
+		case "-cpu_cores":
+			target = &declared.CPU
+		case "-mem":
+			target = &declared.Memory
+		case "-gpu_cores":
+			target = &declared.GPU
+		default:
+			return nil, fmt.Errorf("unknown flag %s", args[i])
+		}
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("%s requires a value", args[i])
+		}
+		val, err := strconv.ParseFloat(args[i+1], 64)
+		if err != nil || val < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative number, got %s", args[i], args[i+1])
+		}
+		*target = val
+		i++ // Skip the value
+	}
+	if declared == (db.DeclaredCapacity{}) {
+		return nil, nil
+	}
+	return &declared, nil
+}
+
+func (c *CLI) registerWorker(workerID, workerIP string, declared *db.DeclaredCapacity) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -886,13 +1432,21 @@ func (c *CLI) registerWorker(workerID, workerIP string) {
 	}
 
 	// Use ManualRegisterAndNotify to both register and notify the worker
-	err := c.masterServer.ManualRegisterAndNotify(ctx, workerID, workerIP, masterID, masterAddress)
+	warning, err := c.masterServer.ManualRegisterAndNotify(ctx, workerID, workerIP, declared, masterID, masterAddress)
 	if err != nil {
 		fmt.Printf("❌ Failed to register worker: %v\n", err)
 		return
 	}
 
 	fmt.Printf("✅ Worker %s registered with address %s\n", workerID, workerIP)
+	if declared != nil {
+		fmt.Printf("   Declared capacity: %.1f CPU, %.1f GB memory, %.1f GPU\n", declared.CPU, declared.Memory, declared.GPU)
+	}
+	if warning != "" {
+		fmt.Printf("⚠️  Warning: %s\n", warning)
+		fmt.Println("   Check the address for typos - tasks cannot be assigned until the worker is reachable.")
+		return
+	}
 	fmt.Println("   Master is notifying worker... Check logs for confirmation.")
 }
 
@@ -936,7 +1490,7 @@ func (c *CLI) cancelTask(taskID string) {
 	}
 
 	if !ack.Success {
-		fmt.Printf("\n%s❌ Failed to cancel task:%s %s\n", red, reset, ack.Message)
+		fmt.Printf("\n%s❌ Failed to cancel task (%s):%s %s\n", red, ack.ErrorCode, reset, ack.Message)
 		return
 	}
 
@@ -944,6 +1498,63 @@ func (c *CLI) cancelTask(taskID string) {
 	fmt.Printf("%s   %s%s\n", yellow, ack.Message, reset)
 }
 
+// releaseTask moves a held task into the scheduling queue
+func (c *CLI) releaseTask(taskID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ack, err := c.masterServer.ReleaseHeldTask(ctx, &pb.TaskID{TaskId: taskID})
+	if err != nil {
+		fmt.Printf("❌ Error releasing task: %v\n", err)
+		return
+	}
+	if !ack.Success {
+		fmt.Printf("❌ Failed to release task (%s): %s\n", ack.ErrorCode, ack.Message)
+		return
+	}
+
+	fmt.Printf("▶️  %s\n", ack.Message)
+	fmt.Println("    Use 'queue' command to view queued tasks")
+}
+
+// requeueTask submits a copy of a finished task under a new ID
+func (c *CLI) requeueTask(taskID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	task, ack, err := c.masterServer.RequeueTask(ctx, taskID)
+	if err != nil {
+		fmt.Printf("❌ Error requeuing task: %v\n", err)
+		return
+	}
+	if !ack.Success {
+		fmt.Printf("❌ Failed to requeue task: %s\n", ack.Message)
+		return
+	}
+
+	fmt.Printf("🔁 Task %s requeued as %s\n", taskID, task.TaskId)
+	fmt.Println("    Use 'queue' command to view queued tasks")
+}
+
+// retryTask dispatches a copy of a finished task under a new ID to the given worker
+func (c *CLI) retryTask(taskID, workerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	task, ack, err := c.masterServer.RetryTaskOnWorker(ctx, taskID, workerID)
+	if err != nil {
+		fmt.Printf("❌ Error retrying task: %v\n", err)
+		return
+	}
+	if !ack.Success {
+		fmt.Printf("❌ Failed to retry task: %s\n", ack.Message)
+		return
+	}
+
+	fmt.Printf("🔁 Task %s retried as %s on worker %s\n", taskID, task.TaskId, workerID)
+	fmt.Printf("    Use 'monitor %s' to follow it\n", task.TaskId)
+}
+
 func (c *CLI) monitorTask(taskID string) {
 	// ANSI escape codes for terminal control
 	const (
@@ -970,12 +1581,32 @@ func (c *CLI) monitorTask(taskID string) {
 	// Clear screen and show header
 	fmt.Print(clearScreen + moveCursor)
 	fmt.Printf("%s%s═══════════════════════════════════════════════════════%s\n", bold, cyan, reset)
-	fmt.Printf("%s%s  TASK MONITOR - Live Logs%s\n", bold, cyan, reset)
+	fmt.Printf("%s%s  TASK MONITOR - Live Logs & Resources%s\n", bold, cyan, reset)
 	fmt.Printf("%s%s═══════════════════════════════════════════════════════%s\n", bold, cyan, reset)
 	fmt.Printf("%sTask ID:%s %s\n", bold, reset, taskID)
 	fmt.Printf("%sUser ID:%s %s\n", bold, reset, userID)
 	fmt.Printf("%s%s───────────────────────────────────────────────────────%s\n", bold, cyan, reset)
-	fmt.Printf("%s%sPress any key to exit%s\n\n", yellow, bold, reset)
+	fmt.Printf("%s%sPress any key to exit%s\n", yellow, bold, reset)
+
+	// The resource panel sits below the header (rows 8-11); logs scroll underneath it
+	const panelTop = 8
+	const logTop = panelTop + usagePanelHeight + 1
+	fmt.Printf("\033[%dr\033[%d;1H", logTop, logTop)
+	defer fmt.Print("\033[r\033[999;1H\n")
+
+	// Logs and the panel are written from different goroutines
+	var out sync.Mutex
+	history := &usageHistory{}
+	drawPanel := func(state string) {
+		out.Lock()
+		defer out.Unlock()
+		fmt.Print("\0337") // Save the log cursor
+		for i, line := range strings.Split(strings.TrimSuffix(renderUsagePanel(history.recent(), state), "\n"), "\n") {
+			fmt.Printf("\033[%d;1H\033[2K%s", panelTop+i, line)
+		}
+		fmt.Print("\0338")
+	}
+	drawPanel("connecting")
 
 	// Create context that can be cancelled
 	streamCtx, streamCancel := context.WithCancel(context.Background())
@@ -995,10 +1626,32 @@ func (c *CLI) monitorTask(taskID string) {
 	// Channel to signal streaming completion
 	streamDone := make(chan error, 1)
 
+	// Stream live resource samples into the panel
+	statsDone := make(chan struct{})
+	go func() {
+		defer close(statsDone)
+		err := c.masterServer.StreamTaskStatsFromWorker(streamCtx, taskID, func(stats *pb.TaskStats) error {
+			if stats.IsComplete {
+				drawPanel("stopped")
+				return nil
+			}
+			history.add(stats)
+			drawPanel("live")
+			return nil
+		})
+		if err != nil && streamCtx.Err() == nil {
+			drawPanel("not running")
+		}
+	}()
+
 	// Start streaming logs in goroutine
 	go func() {
-		err := c.masterServer.StreamTaskLogsUnified(streamCtx, taskID, userID, func(logLine string, isComplete bool, status string) error {
-			if logLine != "" {
+		err := c.masterServer.StreamTaskLogsUnified(streamCtx, taskID, userID, func(logLine, stream string, isComplete bool, status string) error {
+			out.Lock()
+			defer out.Unlock()
+			if logLine != "" && stream == "stderr" {
+				fmt.Printf("%s%s%s\n", red, logLine, reset)
+			} else if logLine != "" {
 				fmt.Println(logLine)
 			}
 			if isComplete {
@@ -1014,10 +1667,14 @@ func (c *CLI) monitorTask(taskID string) {
 	// Wait for either user input or stream completion
 	select {
 	case <-done:
+		streamCancel()
+		<-statsDone
 		fmt.Printf("\n%s%s═══════════════════════════════════════════════════════%s\n", bold, yellow, reset)
 		fmt.Printf("%s%s  Monitoring Stopped by User%s\n", bold, yellow, reset)
 		fmt.Printf("%s%s═══════════════════════════════════════════════════════%s\n", bold, yellow, reset)
 	case err := <-streamDone:
+		streamCancel()
+		<-statsDone
 		if err != nil {
 			fmt.Printf("\n%s%s═══════════════════════════════════════════════════════%s\n", bold, red, reset)
 			fmt.Printf("%s%s  Error: %v%s\n", bold, red, err, reset)
@@ -1030,11 +1687,31 @@ func (c *CLI) monitorTask(taskID string) {
 	}
 }
 
+// showSchedulerStats prints scheduler performance counters accumulated since startup
+func (c *CLI) showSchedulerStats() {
+	stats := c.masterServer.SchedulerMetrics().Stats()
+
+	fmt.Println("\n═══════════════════════════════════════════════════════")
+	fmt.Println("  📈 SCHEDULER STATS")
+	fmt.Println("═══════════════════════════════════════════════════════")
+	fmt.Printf("  Tasks Scheduled:        %d\n", stats.TasksScheduled)
+	fmt.Printf("  Scheduling Attempts:    %d (%d failed)\n", stats.SchedulingAttempts, stats.FailedAttempts)
+	fmt.Printf("  Avg Attempts/Success:   %.2f\n", stats.AvgAttemptsBeforeOK)
+	fmt.Printf("  Avg Queue Wait:         %s\n", stats.AvgQueueWait.Round(time.Millisecond))
+	fmt.Printf("  Assignment RPCs:        %d (%d failed)\n", stats.AssignmentRPCs, stats.AssignmentRPCFailures)
+	fmt.Printf("  Avg Assignment Latency: %s\n", stats.AvgAssignmentLatency.Round(time.Microsecond))
+	if stats.LastScheduledTimestamp > 0 {
+		fmt.Printf("  Last Scheduled:         %s\n", time.Unix(stats.LastScheduledTimestamp, 0).Format(time.RFC3339))
+	}
+	fmt.Println("═══════════════════════════════════════════════════════")
+}
+
 func (c *CLI) showQueue() {
 	queuedTasks := c.masterServer.GetQueuedTasks()
 
 	if len(queuedTasks) == 0 {
 		fmt.Println("\n✓ Task queue is empty")
+		c.printHeldTasks()
 		return
 	}
 
@@ -1071,12 +1748,127 @@ func (c *CLI) showQueue() {
 		}
 	}
 
+	c.printHeldTasks()
+
 	fmt.Println("\n═══════════════════════════════════════════════════════")
 	fmt.Println("  Note: Scheduler checks queue every 5s and assigns")
 	fmt.Println("  tasks to workers with available resources")
 	fmt.Println("═══════════════════════════════════════════════════════")
 }
 
+// explainQueuedTask shows why a queued task has not been assigned: its last error and what each worker lacks
+func (c *CLI) explainQueuedTask(taskID string) {
+	explanation, err := c.masterServer.ExplainQueuedTask(taskID)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+
+	task := explanation.Task
+	fmt.Printf("\n🔍 Why is %s waiting?\n", taskID)
+	fmt.Println("─────────────────────────────────────────────────────────")
+	fmt.Printf("  Queue position:  %d\n", explanation.Position)
+	fmt.Printf("  Time in queue:   %s\n", formatDuration(time.Since(explanation.QueuedAt)))
+	fmt.Printf("  Retry attempts:  %d\n", explanation.Retries)
+	if explanation.LastError != "" {
+		fmt.Printf("  Last error:      %s\n", explanation.LastError)
+	}
+	fmt.Printf("  Requires:        %.2f CPU, %.2f GB memory, %.2f GB storage, %.2f GPU\n",
+		task.ReqCpu, task.ReqMemory, task.ReqStorage, task.ReqGpu)
+
+	if len(explanation.Workers) == 0 {
+		fmt.Println("\n  ⚠️  No workers are registered")
+		fmt.Println()
+		return
+	}
+
+	fits := 0
+	fmt.Println("\n  Workers:")
+	for _, worker := range explanation.Workers {
+		if worker.Fits {
+			fits++
+			fmt.Printf("    ✓ %s: fits now\n", worker.WorkerID)
+			continue
+		}
+		fmt.Printf("    ✗ %s:\n", worker.WorkerID)
+		for _, reason := range worker.Reasons {
+			fmt.Printf("        - %s\n", reason)
+		}
+	}
+	if fits > 0 {
+		fmt.Printf("\n  💡 %d worker(s) can take it now; it should be assigned on the next queue pass (every 5s)\n", fits)
+	}
+	fmt.Println()
+}
+
+// printHeldTasks lists tasks submitted with -hold that are waiting to be released
+func (c *CLI) printHeldTasks() {
+	held := c.masterServer.GetHeldTasks()
+	if len(held) == 0 {
+		return
+	}
+
+	fmt.Printf("\n⏸️  %d task(s) on hold (not scheduled until released):\n", len(held))
+	for _, task := range held {
+		fmt.Printf("    • %s (%s)\n", task.TaskId, task.DockerImage)
+	}
+}
+
+// prewarmImages asks a worker to pre-pull images so future tasks skip the pull
+func (c *CLI) prewarmImages(workerID string, images []string) {
+	fmt.Printf("\n🔥 Prewarming %d image(s) on worker %s...\n", len(images), workerID)
+
+	// Pulling large images can take several minutes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	ack, err := c.masterServer.PrewarmWorkerImages(ctx, workerID, images)
+	if err != nil {
+		fmt.Printf("❌ Failed to prewarm images: %v\n", err)
+		return
+	}
+
+	for _, img := range ack.Pulled {
+		fmt.Printf("  ✓ Pulled:         %s\n", img)
+	}
+	for _, img := range ack.Cached {
+		fmt.Printf("  ✓ Already cached: %s\n", img)
+	}
+	for _, img := range ack.Failed {
+		fmt.Printf("  ❌ Failed:         %s\n", img)
+	}
+
+	if ack.Success {
+		fmt.Printf("\n✅ %s\n", ack.Message)
+	} else {
+		fmt.Printf("\n⚠️  %s\n", ack.Message)
+	}
+}
+
+// validateImage asks a worker whether an image exists and is usable, without running it
+func (c *CLI) validateImage(workerID string, image string) {
+	fmt.Printf("\n🔍 Validating image %s on worker %s...\n", image, workerID)
+
+	// Validation may need to pull the image
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	ack, err := c.masterServer.ValidateWorkerImage(ctx, workerID, image)
+	if err != nil {
+		fmt.Printf("❌ Failed to validate image: %v\n", err)
+		return
+	}
+
+	if !ack.Success {
+		fmt.Printf("❌ Image is not usable: %s\n", ack.Message)
+		return
+	}
+
+	fmt.Printf("✅ Image is usable: %s\n", ack.Message)
+	fmt.Printf("  Digest: %s\n", ack.Digest)
+	fmt.Printf("  Size:   %.2f MB\n", float64(ack.SizeBytes)/(1024*1024))
+}
+
 // reconcileResources triggers resource reconciliation to fix stale allocations
 func (c *CLI) reconcileResources() {
 	fmt.Println("\n🔄 Reconciling worker resources...")
@@ -1092,12 +1884,109 @@ func (c *CLI) reconcileResources() {
 	fmt.Println("   Run 'workers' to see updated resource allocations.")
 }
 
+// diffWorkerState prints where in-memory worker resources disagree with the database, without fixing anything
+func (c *CLI) diffWorkerState() {
+	fmt.Println("\n🔍 Comparing in-memory worker state against the database...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	diffs, err := c.masterServer.DiffWorkerState(ctx)
+	if err != nil {
+		fmt.Printf("❌ Failed to diff worker state: %v\n", err)
+		return
+	}
+
+	if len(diffs) == 0 {
+		fmt.Println("\n✓ No drift: in-memory state matches the database")
+		return
+	}
+
+	fmt.Printf("\n⚠ Drift detected in %d field(s):\n", len(diffs))
+	fmt.Printf("  %-20s %-18s %12s %12s\n", "WORKER", "FIELD", "MEMORY", "DATABASE")
+	for _, d := range diffs {
+		if d.Detail != "" {
+			fmt.Printf("  %-20s %s\n", d.WorkerID, d.Detail)
+			continue
+		}
+		fmt.Printf("  %-20s %-18s %12.2f %12.2f\n", d.WorkerID, d.Field, d.Memory, d.Database)
+	}
+	fmt.Println("\n💡 Use 'reconcile <worker_id>' or 'fix-resources' to correct drift")
+}
+
+// reconcileWorker reconciles a single worker's allocations against its running tasks
+func (c *CLI) reconcileWorker(workerID string) {
+	fmt.Printf("\n🔄 Reconciling resources for worker %s...\n", workerID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	change, err := c.masterServer.ReconcileWorker(ctx, workerID)
+	if err != nil {
+		fmt.Printf("❌ Failed to reconcile worker: %v\n", err)
+		return
+	}
+
+	if change.Before == change.After && change.TasksBefore == change.TasksAfter {
+		fmt.Println("\n✓ Worker allocations already match its running tasks")
+		return
+	}
+
+	fmt.Println("\n✓ Worker reconciled:")
+	fmt.Printf("  CPU:     %.2f → %.2f cores\n", change.Before.CPU, change.After.CPU)
+	fmt.Printf("  Memory:  %.2f → %.2f GB\n", change.Before.Memory, change.After.Memory)
+	fmt.Printf("  Storage: %.2f → %.2f GB\n", change.Before.Storage, change.After.Storage)
+	fmt.Printf("  GPU:     %.2f → %.2f cores\n", change.Before.GPU, change.After.GPU)
+	fmt.Printf("  Tasks:   %d → %d\n", change.TasksBefore, change.TasksAfter)
+}
+
+// collectStuckTasks fails or requeues running tasks whose worker is inactive or unregistered
+func (c *CLI) collectStuckTasks(policy string) {
+	fmt.Printf("\n🧹 Collecting stuck running tasks (policy: %s)...\n", policy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := c.masterServer.CollectStuckTasks(ctx, policy)
+	if err != nil {
+		fmt.Printf("❌ Failed to collect stuck tasks: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\n✓ Checked %d running task(s)\n", report.Checked)
+	for _, taskID := range report.Failed {
+		fmt.Printf("  ✗ %s marked failed\n", taskID)
+	}
+	for _, taskID := range report.Requeued {
+		fmt.Printf("  ↻ %s requeued\n", taskID)
+	}
+	for _, taskID := range report.Skipped {
+		fmt.Printf("  ⚠ %s could not be cleaned up\n", taskID)
+	}
+	if len(report.Failed)+len(report.Requeued)+len(report.Skipped) == 0 {
+		fmt.Println("  No stuck tasks found")
+	}
+}
+
+// issueJoinToken prints a cluster join token for worker auto-registration
+func (c *CLI) issueJoinToken(ttl time.Duration) {
+	token, expiresAt, err := c.masterServer.NewJoinToken(ttl)
+	if err != nil {
+		fmt.Printf("❌ Failed to issue join token: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\n🔑 Join token (expires %s):\n", expiresAt.Format(time.RFC3339))
+	fmt.Printf("  %s\n", token)
+	fmt.Println("\nStart workers with MASTER_ADDR=<master ip:port> JOIN_TOKEN=<token> to join automatically")
+}
+
 // listAllTasksCategorically lists all tasks organized by status
 func (c *CLI) listAllTasksCategorically() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	statuses := []string{"pending", "running", "completed", "failed"}
+	statuses := []string{"held", "pending", "running", "completed", "failed", "crashloop"}
 	allTasksByStatus := make(map[string][]*db.Task)
 	totalCount := 0
 
@@ -1136,6 +2025,10 @@ func (c *CLI) listAllTasksCategorically() {
 			statusEmoji = "✅"
 		case "failed":
 			statusEmoji = "❌"
+		case "crashloop":
+			statusEmoji = "🔁"
+		case "held":
+			statusEmoji = "⏸️ "
 		}
 
 		fmt.Printf("\n%s %s (%d task%s)\n", statusEmoji, strings.ToUpper(status), len(tasks), func() string {
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HistoryLimit is the number of commands kept in the persisted history file
+const HistoryLimit = 1000
+
+// DefaultPrompt is the interactive prompt used when none is configured
+const DefaultPrompt = "master> "
+
+// DefaultHistoryPath returns ~/.cloudai/history, or "" if the home directory is unknown
+func DefaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cloudai", "history")
+}
+
+// loadHistory reads persisted commands, oldest first; a missing file is an empty history
+func loadHistory(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open history: %w", err)
+	}
+	defer file.Close()
+
+	var commands []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			commands = append(commands, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+
+	return trimHistory(commands), nil
+}
+
+// saveHistory writes the most recent HistoryLimit commands to path (owner-only permissions)
+func saveHistory(path string, commands []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create history directory: %w", err)
+	}
+
+	var b strings.Builder
+	for _, command := range trimHistory(commands) {
+		b.WriteString(command)
+		b.WriteByte('\n')
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return nil
+}
+
+// trimHistory keeps only the most recent HistoryLimit commands
+func trimHistory(commands []string) []string {
+	if len(commands) > HistoryLimit {
+		return commands[len(commands)-HistoryLimit:]
+	}
+	return commands
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestHistoryRoundTrip tests that saved commands load back in the same order
+func TestHistoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".cloudai", "history")
+	commands := []string{"workers", "task docker.io/user/sample:latest -cpu_cores 2.0", "stats worker-1"}
+
+	if err := saveHistory(path, commands); err != nil {
+		t.Fatalf("Failed to save history: %v", err)
+	}
+
+	loaded, err := loadHistory(path)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(loaded) != len(commands) {
+		t.Fatalf("Expected %d commands, got %d", len(commands), len(loaded))
+	}
+	for i := range commands {
+		if loaded[i] != commands[i] {
+			t.Errorf("Command %d: expected %q, got %q", i, commands[i], loaded[i])
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat history file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected history file mode 0600, got %o", perm)
+	}
+}
+
+// TestHistoryKeepsMostRecent tests that only the newest HistoryLimit commands are persisted
+func TestHistoryKeepsMostRecent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	commands := make([]string, HistoryLimit+5)
+	for i := range commands {
+		commands[i] = fmt.Sprintf("stats worker-%d", i)
+	}
+	if err := saveHistory(path, commands); err != nil {
+		t.Fatalf("Failed to save history: %v", err)
+	}
+
+	loaded, err := loadHistory(path)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(loaded) != HistoryLimit {
+		t.Fatalf("Expected %d commands, got %d", HistoryLimit, len(loaded))
+	}
+	if loaded[0] != "stats worker-5" {
+		t.Errorf("Expected oldest kept command to be 'stats worker-5', got %q", loaded[0])
+	}
+
+	// A missing file is an empty history
+	missing, err := loadHistory(filepath.Join(t.TempDir(), "absent"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("Expected empty history for a missing file, got %v (err %v)", missing, err)
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	pb "master/proto"
+)
+
+// usageGraphWidth is how many recent samples the monitor's usage graphs show
+const usageGraphWidth = 30
+
+// usagePanelHeight is the number of lines renderUsagePanel produces
+const usagePanelHeight = 4
+
+// sparkBlocks are the bar heights of a usage graph, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// usageHistory keeps the most recent usage samples of the monitored task
+type usageHistory struct {
+	mu      sync.Mutex
+	samples []*pb.TaskStats
+}
+
+// add records a sample, dropping the oldest once the graph is full
+func (h *usageHistory) add(stats *pb.TaskStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = append(h.samples, stats)
+	if len(h.samples) > usageGraphWidth {
+		h.samples = h.samples[len(h.samples)-usageGraphWidth:]
+	}
+}
+
+// recent returns a copy of the recorded samples, oldest first
+func (h *usageHistory) recent() []*pb.TaskStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*pb.TaskStats(nil), h.samples...)
+}
+
+// renderUsagePanel formats the monitor's resource panel: a graph of recent CPU and memory samples with the latest reading
+// state describes the stats stream (live, stopped, ...) and is shown in the title
+func renderUsagePanel(samples []*pb.TaskStats, state string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "RESOURCES (%s)\n", state)
+
+	if len(samples) == 0 {
+		fmt.Fprintf(&b, "  CPU  %-*s\n", usageGraphWidth, "no samples")
+		fmt.Fprintf(&b, "  MEM  %-*s\n", usageGraphWidth, "no samples")
+	} else {
+		cpu := make([]float64, len(samples))
+		memory := make([]float64, len(samples))
+		cpuScale, memScale := 1.0, 0.0
+		for i, s := range samples {
+			cpu[i], memory[i] = s.CpuCores, s.MemoryGb
+			cpuScale = math.Max(cpuScale, s.CpuCores)
+			memScale = math.Max(memScale, s.MemoryGb)
+		}
+
+		// Memory is graphed against the container's limit when it has one
+		latest := samples[len(samples)-1]
+		memLimit := ""
+		if latest.MemoryLimitGb > 0 {
+			memScale = latest.MemoryLimitGb
+			memLimit = fmt.Sprintf(" / %.2f GB limit", latest.MemoryLimitGb)
+		}
+
+		fmt.Fprintf(&b, "  CPU  %-*s %6.2f cores\n", usageGraphWidth, sparkline(cpu, cpuScale), latest.CpuCores)
+		fmt.Fprintf(&b, "  MEM  %-*s %6.2f GB%s\n", usageGraphWidth, sparkline(memory, memScale), latest.MemoryGb, memLimit)
+	}
+
+	b.WriteString("───────────────────────────────────────────────────────\n")
+	return b.String()
+}
+
+// sparkline draws each value as a bar relative to scale; values at or above scale draw a full bar
+func sparkline(values []float64, scale float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if scale > 0 {
+			level = int(math.Round(v / scale * float64(len(sparkBlocks)-1)))
+		}
+		level = max(0, min(level, len(sparkBlocks)-1))
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	pb "master/proto"
+)
+
+// TestRenderUsagePanel tests that the panel graphs sample history and shows the latest reading against the memory limit
+func TestRenderUsagePanel(t *testing.T) {
+	samples := []*pb.TaskStats{
+		{CpuCores: 0.0, MemoryGb: 0.25, MemoryLimitGb: 2.0},
+		{CpuCores: 1.0, MemoryGb: 0.5, MemoryLimitGb: 2.0},
+		{CpuCores: 2.0, MemoryGb: 1.0, MemoryLimitGb: 2.0},
+		{CpuCores: 1.5, MemoryGb: 2.0, MemoryLimitGb: 2.0},
+	}
+
+	panel := renderUsagePanel(samples, "live")
+	lines := strings.Split(strings.TrimSuffix(panel, "\n"), "\n")
+	if len(lines) != usagePanelHeight {
+		t.Fatalf("Expected %d lines, got %d:\n%s", usagePanelHeight, len(lines), panel)
+	}
+	if lines[0] != "RESOURCES (live)" {
+		t.Errorf("Expected title with the stream state, got %q", lines[0])
+	}
+
+	// CPU is scaled to its peak (2 cores); memory to the 2 GB limit
+	expectedCPU := "  CPU  ▁▅█▆" + strings.Repeat(" ", usageGraphWidth-4) + "   1.50 cores"
+	if lines[1] != expectedCPU {
+		t.Errorf("Expected CPU line %q, got %q", expectedCPU, lines[1])
+	}
+	expectedMem := "  MEM  ▂▃▅█" + strings.Repeat(" ", usageGraphWidth-4) + "   2.00 GB / 2.00 GB limit"
+	if lines[2] != expectedMem {
+		t.Errorf("Expected memory line %q, got %q", expectedMem, lines[2])
+	}
+
+	empty := renderUsagePanel(nil, "not running")
+	if !strings.HasPrefix(empty, "RESOURCES (not running)\n") || !strings.Contains(empty, "CPU  no samples") {
+		t.Errorf("Expected an empty panel to say there are no samples, got:\n%s", empty)
+	}
+	if got := strings.Count(empty, "\n"); got != usagePanelHeight {
+		t.Errorf("Expected an empty panel to keep its height of %d lines, got %d", usagePanelHeight, got)
+	}
+}
+
+// TestUsageHistoryKeepsGraphWidth tests that the history drops the oldest samples beyond the graph width
+func TestUsageHistoryKeepsGraphWidth(t *testing.T) {
+	history := &usageHistory{}
+	for i := 0; i < usageGraphWidth+5; i++ {
+		history.add(&pb.TaskStats{Timestamp: int64(i)})
+	}
+	recent := history.recent()
+	if len(recent) != usageGraphWidth {
+		t.Fatalf("Expected %d samples, got %d", usageGraphWidth, len(recent))
+	}
+	if recent[0].Timestamp != 5 {
+		t.Errorf("Expected the oldest kept sample to be 5, got %d", recent[0].Timestamp)
+	}
+}
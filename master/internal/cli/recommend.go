@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"master/internal/db"
+)
+
+// recommendHeadroom is the margin added on top of p95 usage when suggesting a request
+const recommendHeadroom = 1.2
+
+// recommendMemoryStep is the granularity (GB) memory suggestions are rounded up to
+const recommendMemoryStep = 0.5
+
+// resourceRecommendations compares a task's requested CPU and memory with its sampled usage
+// and returns one suggestion per resource, e.g. "requested 4 CPU, p95 was 1.2, consider 2"
+// Returns nil when no usage was recorded
+func resourceRecommendations(reqCPU, reqMemory float64, usage *db.ResourceUsage) []string {
+	if usage == nil || usage.Samples == 0 {
+		return nil
+	}
+
+	// CPU is suggested in whole cores, memory in half-GB steps, never below one step
+	cpu := roundUpTo(usage.CPUP95*recommendHeadroom, 1)
+	memory := roundUpTo(usage.MemoryP95*recommendHeadroom, recommendMemoryStep)
+
+	return []string{
+		recommendation(reqCPU, usage.CPUP95, cpu, "CPU", ""),
+		recommendation(reqMemory, usage.MemoryP95, memory, "GB memory", " GB"),
+	}
+}
+
+// recommendation phrases the suggestion for one resource
+func recommendation(requested, p95, suggested float64, resource, unit string) string {
+	switch {
+	case suggested < requested:
+		return fmt.Sprintf("requested %s %s, p95 was %s%s, consider %s%s",
+			formatAmount(requested), resource, formatAmount(p95), unit, formatAmount(suggested), unit)
+	case suggested > requested:
+		return fmt.Sprintf("requested %s %s, p95 was %s%s, consider raising to %s%s",
+			formatAmount(requested), resource, formatAmount(p95), unit, formatAmount(suggested), unit)
+	default:
+		return fmt.Sprintf("requested %s %s, p95 was %s%s, request is right-sized",
+			formatAmount(requested), resource, formatAmount(p95), unit)
+	}
+}
+
+// roundUpTo rounds v up to a multiple of step, with a minimum of one step
+func roundUpTo(v, step float64) float64 {
+	rounded := math.Ceil(v/step) * step
+	if rounded < step {
+		return step
+	}
+	return rounded
+}
+
+// formatAmount prints a resource amount with at most two decimals and no trailing zeros
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func (c *CLI) recommendResources(taskID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	task, result, err := c.masterServer.GetTaskUsage(ctx, taskID)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+
+	suggestions := resourceRecommendations(task.ReqCPU, task.ReqMemory, result.Usage)
+	if len(suggestions) == 0 {
+		fmt.Printf("⚠️  No resource usage was recorded for task %s\n", taskID)
+		return
+	}
+
+	u := result.Usage
+	fmt.Printf("\n📏 Resource usage for %s (%d samples)\n", taskID, u.Samples)
+	fmt.Println("─────────────────────────────────────────────────────────")
+	fmt.Printf("  %-8s %8s %8s %8s %8s %10s\n", "", "min", "avg", "p95", "max", "requested")
+	fmt.Printf("  %-8s %8.2f %8.2f %8.2f %8.2f %10.2f\n", "CPU", u.CPUMin, u.CPUAvg, u.CPUP95, u.CPUMax, task.ReqCPU)
+	fmt.Printf("  %-8s %8.2f %8.2f %8.2f %8.2f %10.2f\n", "Mem (GB)", u.MemoryMin, u.MemoryAvg, u.MemoryP95, u.MemoryMax, task.ReqMemory)
+	fmt.Println("\n💡 Recommendations:")
+	for _, suggestion := range suggestions {
+		fmt.Printf("  • %s\n", suggestion)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"testing"
+
+	"master/internal/db"
+)
+
+// TestResourceRecommendations tests that sampled usage is turned into right-sizing suggestions
+func TestResourceRecommendations(t *testing.T) {
+	tests := []struct {
+		name      string
+		reqCPU    float64
+		reqMemory float64
+		usage     *db.ResourceUsage
+		expected  []string
+	}{
+		{
+			name:      "over-requested",
+			reqCPU:    4,
+			reqMemory: 8,
+			usage:     &db.ResourceUsage{CPUAvg: 0.8, CPUP95: 1.2, CPUMax: 1.9, MemoryAvg: 1.1, MemoryP95: 1.7, MemoryMax: 1.8, Samples: 120},
+			expected: []string{
+				"requested 4 CPU, p95 was 1.2, consider 2",
+				"requested 8 GB memory, p95 was 1.7 GB, consider 2.5 GB",
+			},
+		},
+		{
+			name:      "under-requested and right-sized",
+			reqCPU:    1,
+			reqMemory: 4,
+			usage:     &db.ResourceUsage{CPUP95: 0.98, MemoryP95: 3.1, Samples: 30},
+			expected: []string{
+				"requested 1 CPU, p95 was 0.98, consider raising to 2",
+				"requested 4 GB memory, p95 was 3.1 GB, request is right-sized",
+			},
+		},
+		{
+			name:      "no usage recorded",
+			reqCPU:    2,
+			reqMemory: 2,
+			usage:     &db.ResourceUsage{},
+			expected:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		got := resourceRecommendations(tt.reqCPU, tt.reqMemory, tt.usage)
+		if len(got) != len(tt.expected) {
+			t.Fatalf("%s: expected %d suggestions, got %d (%v)", tt.name, len(tt.expected), len(got), got)
+		}
+		for i := range tt.expected {
+			if got[i] != tt.expected[i] {
+				t.Errorf("%s: expected %q, got %q", tt.name, tt.expected[i], got[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"master/internal/aod"
+)
+
+// DefaultReplayOutput is where replayed GA parameters are written unless -out is given
+const DefaultReplayOutput = "config/ga_replay.json"
+
+// replayArgs is a parsed replay command
+type replayArgs struct {
+	since, until time.Time
+	out          string
+}
+
+// parseReplayArgs parses "--since <RFC3339> --until <RFC3339> [--out <file>]" (single-dash flags work too)
+func parseReplayArgs(args []string) (*replayArgs, error) {
+	parsed := &replayArgs{out: DefaultReplayOutput}
+	for i := 0; i < len(args); i++ {
+		flag := strings.TrimLeft(args[i], "-")
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("missing value for %s", args[i])
+		}
+		value := args[i+1]
+		i++
+
+		switch flag {
+		case "since", "until":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("--%s must be RFC3339 (e.g. 2025-06-01T00:00:00Z): %s", flag, value)
+			}
+			if flag == "since" {
+				parsed.since = t
+			} else {
+				parsed.until = t
+			}
+		case "out":
+			parsed.out = value
+		default:
+			return nil, fmt.Errorf("unknown flag %s", args[i-1])
+		}
+	}
+
+	if parsed.since.IsZero() || parsed.until.IsZero() {
+		return nil, fmt.Errorf("--since and --until are required")
+	}
+	if !parsed.since.Before(parsed.until) {
+		return nil, fmt.Errorf("--since must be before --until")
+	}
+	return parsed, nil
+}
+
+// SetTraining enables the replay command: history to train on, the params file the scheduler loads
+// (which replay refuses to overwrite) and the affinity half-life used by scheduled training
+func (c *CLI) SetTraining(history aod.TrainingHistorySource, activeParamsPath string, affinityHalfLife time.Duration) {
+	c.trainingHistory = history
+	c.activeParamsPath = activeParamsPath
+	c.affinityHalfLife = affinityHalfLife
+}
+
+// replayTraining runs a training cycle on a fixed historical window and writes the parameters without activating them
+func (c *CLI) replayTraining(args *replayArgs) {
+	if c.trainingHistory == nil {
+		fmt.Println("❌ Error: training history database not available")
+		return
+	}
+	if c.activeParamsPath != "" && filepath.Clean(args.out) == filepath.Clean(c.activeParamsPath) {
+		fmt.Printf("❌ Error: %s is the active scheduler parameters file; choose another -out\n", args.out)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	fmt.Printf("🧬 Replaying training on %s → %s...\n", args.since.Format(time.RFC3339), args.until.Format(time.RFC3339))
+	if err := aod.RunTrainingWindow(ctx, c.trainingHistory, args.since, args.until, args.out, c.affinityHalfLife); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ Parameters written to %s (not activated)\n", args.out)
+	if c.activeParamsPath != "" {
+		fmt.Printf("   Compare with the active parameters in %s\n", c.activeParamsPath)
+	}
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseReplayArgs tests that replay requires an ordered RFC3339 window and defaults the output file
+func TestParseReplayArgs(t *testing.T) {
+	args, err := parseReplayArgs(strings.Fields("--since 2025-06-01T00:00:00Z --until 2025-06-02T00:00:00Z"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if args.until.Sub(args.since).Hours() != 24 {
+		t.Errorf("Expected a 24h window, got %s to %s", args.since, args.until)
+	}
+	if args.out != DefaultReplayOutput {
+		t.Errorf("Expected default output %s, got %s", DefaultReplayOutput, args.out)
+	}
+
+	args, err = parseReplayArgs(strings.Fields("-since 2025-06-01T00:00:00Z -until 2025-06-01T06:00:00Z -out /tmp/a.json"))
+	if err != nil || args.out != "/tmp/a.json" {
+		t.Errorf("Expected single-dash flags and -out to be accepted, got %v (%v)", args, err)
+	}
+
+	for _, bad := range []string{
+		"--since 2025-06-01T00:00:00Z",
+		"--since 2025-06-02T00:00:00Z --until 2025-06-01T00:00:00Z",
+		"--since yesterday --until 2025-06-01T00:00:00Z",
+		"--since 2025-06-01T00:00:00Z --until 2025-06-02T00:00:00Z --window 1h",
+		"--since",
+	} {
+		if _, err := parseReplayArgs(strings.Fields(bad)); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
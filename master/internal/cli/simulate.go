@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"master/internal/db"
+	"master/internal/scheduler"
+	"master/internal/telemetry"
+)
+
+// simulateArgs is a parsed simulate command
+type simulateArgs struct {
+	scheduler    string
+	since, until time.Time
+}
+
+// parseSimulateArgs parses "--scheduler <rts|round-robin> --since <RFC3339> [--until <RFC3339>]" (single-dash flags work too)
+// until defaults to now
+func parseSimulateArgs(args []string, now time.Time) (*simulateArgs, error) {
+	parsed := &simulateArgs{until: now}
+	for i := 0; i < len(args); i++ {
+		flag := strings.TrimLeft(args[i], "-")
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("missing value for %s", args[i])
+		}
+		value := args[i+1]
+		i++
+
+		switch flag {
+		case "scheduler":
+			switch strings.ToLower(value) {
+			case "rts":
+				parsed.scheduler = "rts"
+			case "round-robin", "roundrobin", "rr":
+				parsed.scheduler = "round-robin"
+			default:
+				return nil, fmt.Errorf("unknown scheduler %s (use rts or round-robin)", value)
+			}
+		case "since", "until":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("--%s must be RFC3339 (e.g. 2025-06-01T00:00:00Z): %s", flag, value)
+			}
+			if flag == "since" {
+				parsed.since = t
+			} else {
+				parsed.until = t
+			}
+		default:
+			return nil, fmt.Errorf("unknown flag %s", args[i-1])
+		}
+	}
+
+	if parsed.scheduler == "" || parsed.since.IsZero() {
+		return nil, fmt.Errorf("--scheduler and --since are required")
+	}
+	if !parsed.since.Before(parsed.until) {
+		return nil, fmt.Errorf("--since must be before --until")
+	}
+	return parsed, nil
+}
+
+// SetSimulation gives the simulate command the runtime estimates and SLA multiplier RTS schedules with
+func (c *CLI) SetSimulation(tauStore telemetry.TauStore, slaMultiplier float64) {
+	c.tauStore = tauStore
+	c.slaMultiplier = slaMultiplier
+}
+
+// workerSnapshotSource provides the worker states recorded as workers joined and left (implemented by db.HistoryDB)
+type workerSnapshotSource interface {
+	GetWorkerSnapshots(ctx context.Context, until time.Time) ([]db.WorkerSnapshot, error)
+}
+
+// simulationWorkers returns the workers active during the window as recorded in history
+// Windows recorded before worker snapshots were kept fall back to the registered workers' capacities
+func (c *CLI) simulationWorkers(ctx context.Context, args *simulateArgs) ([]scheduler.SimWorker, error) {
+	var workers []scheduler.SimWorker
+	if source, ok := c.trainingHistory.(workerSnapshotSource); ok {
+		snapshots, err := source.GetWorkerSnapshots(ctx, args.until)
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range db.WorkersActiveDuring(snapshots, args.since, args.until) {
+			workers = append(workers, scheduler.SimWorker{
+				ID:      snapshot.WorkerID,
+				CPU:     snapshot.TotalCPU,
+				Memory:  snapshot.TotalMemory,
+				Storage: snapshot.TotalStorage,
+				GPU:     snapshot.TotalGPU,
+			})
+		}
+	}
+	if len(workers) > 0 {
+		return workers, nil
+	}
+
+	fmt.Println("⚠️  No worker states recorded for this window; simulating the currently registered workers")
+	for id, worker := range c.masterServer.GetWorkers() {
+		if worker.Info == nil {
+			continue
+		}
+		workers = append(workers, scheduler.SimWorker{
+			ID:      id,
+			CPU:     worker.Info.TotalCpu,
+			Memory:  worker.Info.TotalMemory,
+			Storage: worker.Info.TotalStorage,
+			GPU:     worker.Info.TotalGpu,
+		})
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers, nil
+}
+
+// simulate replays historical arrivals against a scheduler on the workers recorded for the window and prints the outcome
+func (c *CLI) simulate(args *simulateArgs) {
+	if c.trainingHistory == nil {
+		fmt.Println("❌ Error: task history database not available")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	workers, err := c.simulationWorkers(ctx, args)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	if len(workers) == 0 {
+		fmt.Println("❌ Error: no workers to simulate")
+		return
+	}
+
+	history, err := c.trainingHistory.GetTaskHistory(ctx, args.since, args.until)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+
+	sim := scheduler.NewSimulator(workers)
+	var sched scheduler.Scheduler = scheduler.NewRoundRobinScheduler()
+	if args.scheduler == "rts" {
+		tauStore := c.tauStore
+		if tauStore == nil {
+			tauStore = telemetry.NewInMemoryTauStore()
+		}
+		rts := scheduler.NewRTSScheduler(scheduler.NewRoundRobinScheduler(), tauStore, sim, c.activeParamsPath, c.slaMultiplier)
+		defer rts.Shutdown()
+		sched = rts
+	}
+
+	fmt.Printf("🧪 Simulating %d task(s) from %s → %s on %d worker(s)...\n",
+		len(history), args.since.Format(time.RFC3339), args.until.Format(time.RFC3339), len(workers))
+	report := sim.Run(sched, history)
+
+	fmt.Println("\n═══════════════════════════════════════════════════════")
+	fmt.Printf("  🧪 SIMULATION: %s\n", report.Scheduler)
+	fmt.Println("═══════════════════════════════════════════════════════")
+	fmt.Printf("  Tasks Replayed:         %d\n", report.Tasks)
+	fmt.Printf("  Placed:                 %d\n", report.Placed)
+	if report.Unplaced > 0 {
+		fmt.Printf("  Never Fit a Worker:     %d\n", report.Unplaced)
+	}
+	fmt.Printf("  SLA Success:            %.1f%% (%d/%d)\n", report.SLASuccessRate*100, report.SLAMet, report.Placed)
+	fmt.Printf("  Avg Queue Wait:         %s\n", report.AvgWait.Round(time.Second))
+	fmt.Printf("  Makespan:               %s\n", report.Makespan.Round(time.Second))
+	fmt.Println("───────────────────────────────────────────────────────")
+	fmt.Println("  Utilization:")
+	fmt.Printf("    • CPU:                %.1f%%\n", report.CPUUtilization*100)
+	fmt.Printf("    • Memory:             %.1f%%\n", report.MemoryUtilization*100)
+	fmt.Printf("    • GPU:                %.1f%%\n", report.GPUUtilization*100)
+	fmt.Println("═══════════════════════════════════════════════════════")
+	fmt.Println("  Nothing was run; runtimes are the recorded ones and queued tasks start first-come first-served")
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"master/internal/server"
+)
+
+// topRowLimit caps the number of workers and tasks shown in each section of the top view
+const topRowLimit = 10
+
+// topTaskRow is one running task in the top view
+type topTaskRow struct {
+	TaskID   string
+	WorkerID string
+	Running  time.Duration // Time since the task started running
+	Known    bool          // Start time was available from the task database
+}
+
+// sortWorkersByCPU returns the workers ordered by CPU usage, busiest first (ties by worker ID)
+func sortWorkersByCPU(workers []server.WorkerStateSnapshot) []server.WorkerStateSnapshot {
+	sorted := make([]server.WorkerStateSnapshot, len(workers))
+	copy(sorted, workers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CPUUsage != sorted[j].CPUUsage {
+			return sorted[i].CPUUsage > sorted[j].CPUUsage
+		}
+		return sorted[i].WorkerID < sorted[j].WorkerID
+	})
+	return sorted
+}
+
+// buildTopTasks collects running tasks from the snapshot, longest-running first
+// Tasks without a known start time sort after all timed tasks
+func buildTopTasks(snapshot *server.ClusterSnapshot, startedAt map[string]time.Time, now time.Time) []topTaskRow {
+	rows := []topTaskRow{}
+	for _, worker := range snapshot.Workers {
+		for _, taskID := range worker.RunningTasks {
+			row := topTaskRow{TaskID: taskID, WorkerID: worker.WorkerID}
+			if started, ok := startedAt[taskID]; ok && !started.IsZero() {
+				row.Running = now.Sub(started)
+				row.Known = true
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Known != rows[j].Known {
+			return rows[i].Known
+		}
+		if rows[i].Running != rows[j].Running {
+			return rows[i].Running > rows[j].Running
+		}
+		return rows[i].TaskID < rows[j].TaskID
+	})
+	return rows
+}
+
+// renderTop formats the top view for a snapshot, showing at most limit rows per section
+func renderTop(snapshot *server.ClusterSnapshot, tasks []topTaskRow, limit int) string {
+	var b strings.Builder
+
+	b.WriteString("╔═══════════════════════════════════════════════════════════════════════\n")
+	fmt.Fprintf(&b, "║  CLUSTER TOP - %s\n", snapshot.Timestamp.Format("15:04:05"))
+	fmt.Fprintf(&b, "║  Workers: %d active / %d total   Tasks: %d   CPU alloc: %.1f%%   Mem alloc: %.1f%%\n",
+		snapshot.ActiveWorkers, snapshot.TotalWorkers, snapshot.TotalTasks,
+		snapshot.CPUUtilization, snapshot.MemoryUtilization)
+	b.WriteString("╚═══════════════════════════════════════════════════════════════════════\n")
+
+	b.WriteString("\nWORKERS (by CPU usage)\n")
+	fmt.Fprintf(&b, "  %-20s %-9s %7s %7s %7s %6s  %s\n", "WORKER", "STATUS", "CPU%", "MEM%", "GPU%", "TASKS", "HEARTBEAT")
+	workers := sortWorkersByCPU(snapshot.Workers)
+	if len(workers) == 0 {
+		b.WriteString("  (no workers registered)\n")
+	}
+	for i, w := range workers {
+		if i >= limit {
+			fmt.Fprintf(&b, "  ... %d more\n", len(workers)-limit)
+			break
+		}
+		fmt.Fprintf(&b, "  %-20s %-9s %6.1f%% %6.1f%% %6.1f%% %6d  %s\n",
+			w.WorkerID, w.Status, w.CPUUsage, w.MemoryUsage, w.GPUUsage, w.TaskCount, w.HeartbeatAgo)
+	}
+
+	b.WriteString("\nTASKS (by time running)\n")
+	fmt.Fprintf(&b, "  %-24s %-20s %s\n", "TASK", "WORKER", "RUNNING")
+	if len(tasks) == 0 {
+		b.WriteString("  (no running tasks)\n")
+	}
+	for i, t := range tasks {
+		if i >= limit {
+			fmt.Fprintf(&b, "  ... %d more\n", len(tasks)-limit)
+			break
+		}
+		running := "unknown"
+		if t.Known {
+			running = formatDuration(t.Running)
+		}
+		fmt.Fprintf(&b, "  %-24s %-20s %s\n", t.TaskID, t.WorkerID, running)
+	}
+
+	return b.String()
+}
+
+// runningTaskStartTimes returns started_at for running tasks (empty when the task DB is unavailable)
+func (c *CLI) runningTaskStartTimes() map[string]time.Time {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	startedAt := make(map[string]time.Time)
+	tasks, err := c.masterServer.GetTasksByStatus(ctx, "running")
+	if err != nil {
+		return startedAt
+	}
+	for _, task := range tasks {
+		startedAt[task.TaskID] = task.StartedAt
+	}
+	return startedAt
+}
+
+// liveTop renders an aggregated, auto-refreshing view of the busiest workers and longest-running tasks
+func (c *CLI) liveTop() {
+	const clearScreen = "\033[2J"
+	const moveCursorHome = "\033[H"
+
+	fmt.Print(clearScreen + moveCursorHome)
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	// Exit the live view on any key press
+	done := make(chan bool)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		reader.ReadByte()
+		done <- true
+	}()
+
+	render := func() {
+		snapshot := c.masterServer.GetClusterSnapshot()
+		tasks := buildTopTasks(snapshot, c.runningTaskStartTimes(), time.Now())
+		fmt.Print(clearScreen + moveCursorHome)
+		fmt.Print(renderTop(snapshot, tasks, topRowLimit))
+		fmt.Print("\n(Press any key to exit)")
+	}
+
+	render()
+	for {
+		select {
+		case <-ticker.C:
+			render()
+		case <-done:
+			fmt.Print(clearScreen + moveCursorHome)
+			fmt.Println("Exiting top view...")
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"master/internal/server"
+)
+
+// seededSnapshot returns a cluster snapshot with three workers and three running tasks
+func seededSnapshot(now time.Time) *server.ClusterSnapshot {
+	return &server.ClusterSnapshot{
+		Timestamp:     now,
+		TotalWorkers:  3,
+		ActiveWorkers: 2,
+		Workers: []server.WorkerStateSnapshot{
+			{WorkerID: "worker-idle", Status: "active", CPUUsage: 5.0},
+			{WorkerID: "worker-busy", Status: "active", CPUUsage: 92.5, TaskCount: 2, RunningTasks: []string{"task-new", "task-old"}},
+			{WorkerID: "worker-mid", Status: "inactive", CPUUsage: 40.0, TaskCount: 1, RunningTasks: []string{"task-untracked"}},
+		},
+	}
+}
+
+// TestSortWorkersByCPU tests that the busiest worker is listed first
+func TestSortWorkersByCPU(t *testing.T) {
+	sorted := sortWorkersByCPU(seededSnapshot(time.Now()).Workers)
+
+	expected := []string{"worker-busy", "worker-mid", "worker-idle"}
+	for i, id := range expected {
+		if sorted[i].WorkerID != id {
+			t.Errorf("Position %d: expected %s, got %s", i, id, sorted[i].WorkerID)
+		}
+	}
+}
+
+// TestBuildTopTasksOrdersByTimeRunning tests that the longest-running task comes first and untimed tasks last
+func TestBuildTopTasksOrdersByTimeRunning(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	startedAt := map[string]time.Time{
+		"task-old": now.Add(-2 * time.Hour),
+		"task-new": now.Add(-30 * time.Second),
+	}
+
+	rows := buildTopTasks(seededSnapshot(now), startedAt, now)
+
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 running tasks, got %d", len(rows))
+	}
+	expected := []string{"task-old", "task-new", "task-untracked"}
+	for i, id := range expected {
+		if rows[i].TaskID != id {
+			t.Errorf("Position %d: expected %s, got %s", i, id, rows[i].TaskID)
+		}
+	}
+	if rows[0].Running != 2*time.Hour || rows[0].WorkerID != "worker-busy" {
+		t.Errorf("Expected task-old running 2h on worker-busy, got %s on %s", rows[0].Running, rows[0].WorkerID)
+	}
+	if rows[2].Known {
+		t.Error("Expected task without a start time to be marked unknown")
+	}
+}
+
+// TestRenderTopFormatting tests the rendered rows and the per-section limit
+func TestRenderTopFormatting(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := seededSnapshot(now)
+	rows := buildTopTasks(snapshot, map[string]time.Time{"task-old": now.Add(-2 * time.Hour)}, now)
+
+	out := renderTop(snapshot, rows, 2)
+
+	if !strings.Contains(out, "2 active / 3 total") {
+		t.Errorf("Expected worker totals in header, got:\n%s", out)
+	}
+	busy := strings.Index(out, "worker-busy")
+	mid := strings.Index(out, "worker-mid")
+	if busy < 0 || mid < 0 || busy > mid {
+		t.Errorf("Expected worker-busy listed before worker-mid, got:\n%s", out)
+	}
+	if strings.Contains(out, "worker-idle") {
+		t.Errorf("Expected worker-idle to be cut by the row limit, got:\n%s", out)
+	}
+	if !strings.Contains(out, "... 1 more") {
+		t.Errorf("Expected a truncation marker, got:\n%s", out)
+	}
+	if !strings.Contains(out, "2h 0m") {
+		t.Errorf("Expected task-old running time 2h 0m, got:\n%s", out)
+	}
+}
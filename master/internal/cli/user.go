@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+
+	"master/internal/db"
+)
+
+// DefaultUser is the user CLI tasks are submitted as when no user is configured
+const DefaultUser = "admin"
+
+// UserLookup finds a registered user by ID (implemented by db.UserDB, where the ID is the email)
+type UserLookup interface {
+	GetUserByEmail(email string) (*db.User, error)
+}
+
+// ResolveUser picks the user CLI tasks are submitted as: the --user flag, then CLOUDAI_USER, then DefaultUser
+// When users is non-nil an explicitly configured user must exist; the built-in admin default is always allowed
+func ResolveUser(flagUser, envUser string, users UserLookup) (string, error) {
+	user := flagUser
+	if user == "" {
+		user = envUser
+	}
+	if user == "" || user == DefaultUser {
+		return DefaultUser, nil
+	}
+
+	if users != nil {
+		if _, err := users.GetUserByEmail(user); err != nil {
+			return "", fmt.Errorf("unknown user %q: %w", user, err)
+		}
+	}
+	return user, nil
+}
+
+// SetUser sets the user that tasks submitted or dispatched from the CLI belong to
+func (c *CLI) SetUser(userID string) {
+	if userID == "" {
+		userID = DefaultUser
+	}
+	c.userID = userID
+}
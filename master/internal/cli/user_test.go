@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"master/internal/db"
+	"master/internal/server"
+)
+
+// fakeUsers is a UserLookup over a fixed set of emails
+type fakeUsers map[string]bool
+
+func (f fakeUsers) GetUserByEmail(email string) (*db.User, error) {
+	if !f[email] {
+		return nil, errors.New("user not found")
+	}
+	return &db.User{Email: email}, nil
+}
+
+// TestSubmittedTaskCarriesResolvedUser tests that CLI submissions use the resolved user instead of the admin default
+func TestSubmittedTaskCarriesResolvedUser(t *testing.T) {
+	users := fakeUsers{"alice@example.com": true, "bob@example.com": true}
+
+	// The flag takes precedence over CLOUDAI_USER
+	user, err := ResolveUser("alice@example.com", "bob@example.com", users)
+	if err != nil {
+		t.Fatalf("Expected user to resolve, got %v", err)
+	}
+	if user != "alice@example.com" {
+		t.Fatalf("Expected alice@example.com, got %q", user)
+	}
+
+	ms := server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	c := &CLI{masterServer: ms}
+	c.SetUser(user)
+	c.submitTask([]string{"task", "alpine:latest"})
+
+	queued := ms.GetQueuedTasks()
+	if len(queued) != 1 {
+		t.Fatalf("Expected 1 queued task, got %d", len(queued))
+	}
+	if queued[0].Task.UserId != "alice@example.com" {
+		t.Errorf("Expected task owned by alice@example.com, got %q", queued[0].Task.UserId)
+	}
+}
+
+// TestResolveUserRejectsUnknownUser tests that a user missing from the user database is rejected
+func TestResolveUserRejectsUnknownUser(t *testing.T) {
+	users := fakeUsers{"alice@example.com": true}
+
+	if _, err := ResolveUser("", "mallory@example.com", users); err == nil {
+		t.Error("Expected an unknown CLOUDAI_USER to be rejected")
+	}
+
+	// Without a user database the configured user is taken as given
+	if user, err := ResolveUser("", "mallory@example.com", nil); err != nil || user != "mallory@example.com" {
+		t.Errorf("Expected mallory@example.com without a user database, got %q (%v)", user, err)
+	}
+
+	// With nothing configured tasks fall back to the admin default
+	if user, err := ResolveUser("", "", users); err != nil || user != DefaultUser {
+		t.Errorf("Expected default user %q, got %q (%v)", DefaultUser, user, err)
+	}
+}
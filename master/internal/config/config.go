@@ -1,9 +1,12 @@
 package config
 
 import (
-	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"master/internal/logging"
 
 	"github.com/joho/godotenv"
 )
@@ -17,6 +20,103 @@ type Config struct {
 	MongoDBDatabase string
 	HTTPPort        string  // HTTP port for telemetry API
 	SLAMultiplier   float64 // SLA multiplier (k), range [1.5, 2.5], default 2.0
+	// HTTPShutdownGracePeriod is how long in-flight HTTP requests may run after shutdown starts
+	HTTPShutdownGracePeriod time.Duration
+
+	// MinHeartbeatInterval is the floor between processed heartbeats of a worker; faster ones are ignored (0 = no floor)
+	MinHeartbeatInterval time.Duration
+	// Mongo client pool size and timeouts applied to every DB connection
+	MongoMaxPoolSize            uint64
+	MongoConnectTimeout         time.Duration
+	MongoServerSelectionTimeout time.Duration
+	// ReconnectConcurrency limits concurrent reconnection dials to inactive workers
+	ReconnectConcurrency int
+	// QueueConcurrency limits concurrent assignment attempts in one queue processing pass
+	QueueConcurrency int
+	// MaxQueueLength rejects new submissions while this many tasks are queued (0 = unbounded)
+	MaxQueueLength int
+	// PreemptionPriority lets queued tasks at or above it evict lower-priority running tasks (0 = disabled);
+	// PreemptionCooldown is the minimum time between preemptions on the same worker
+	PreemptionPriority int
+	PreemptionCooldown time.Duration
+	// FairShare orders the queue so users with the least recent consumption go first;
+	// FairShareHalfLife is the age at which consumed resource-seconds count half as much
+	FairShare         bool
+	FairShareHalfLife time.Duration
+	// AutoscaleWindow is how long the queue must stay backed up (or the cluster idle) before /api/autoscale recommends scaling
+	AutoscaleWindow time.Duration
+	// GRPCReflection registers the gRPC reflection service (for grpcurl debugging)
+	GRPCReflection bool
+	// gRPC keepalive on worker connections: ping after GRPCKeepaliveTime idle, drop the connection
+	// if the ping is not acked within GRPCKeepaliveTimeout, optionally even with no RPC in flight
+	GRPCKeepaliveTime                time.Duration
+	GRPCKeepaliveTimeout             time.Duration
+	GRPCKeepalivePermitWithoutStream bool
+	// Over-commit ratios applied to worker capacity per resource (1.0 = strict)
+	OvercommitCPU     float64
+	OvercommitMemory  float64
+	OvercommitStorage float64
+	OvercommitGPU     float64
+	// System reserve held back from tasks on every worker (OS and worker agent headroom)
+	ReserveCPU     float64
+	ReserveMemory  float64
+	ReserveStorage float64
+	ReserveGPU     float64
+	// NotifyWebhookURLs receive a JSON event when a task completes, fails or is cancelled
+	NotifyWebhookURLs []string
+	// CLIPrompt is the interactive prompt; CLIHistoryFile persists command history ("" = ~/.cloudai/history)
+	CLIPrompt      string
+	CLIHistoryFile string
+	// CLIUser owns tasks submitted from the CLI (overridden by the --user flag; "" = admin)
+	CLIUser string
+	// FileStorageBackend selects where task files are kept: "local" (default) or "s3"
+	FileStorageBackend string
+	// S3 settings used when FileStorageBackend is "s3" (AWS S3 or MinIO)
+	S3Endpoint  string
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+	// TauBootstrap seeds each task type's tau at startup from the p50 runtime in task history
+	TauBootstrap           bool
+	TauBootstrapWindow     time.Duration
+	TauBootstrapMinSamples int
+	// AffinityHalfLife is the age at which a task history record counts half as much toward worker affinity (0 = no decay)
+	AffinityHalfLife time.Duration
+	// TaskGCInterval periodically collects running tasks whose worker is gone (0 = disabled);
+	// TaskGCPolicy is "fail" (default) or "requeue"
+	TaskGCInterval time.Duration
+	TaskGCPolicy   string
+	// TaskRetentionDays deletes finished tasks with their results, assignments and files once older than this (0 = keep forever);
+	// TaskRetentionDryRun only logs what would be deleted
+	TaskRetentionDays   int
+	TaskRetentionDryRun bool
+	// RateLimits maps an HTTP route group (tasks, workers, files, auth) to "requests_per_second[:burst]"
+	// per client; groups without an entry are not rate limited
+	RateLimits map[string]string
+	// AutoRegister lets unknown workers join with a join token signed by JoinTokenSecret
+	// (off by default: every worker must be pre-registered)
+	AutoRegister    bool
+	JoinTokenSecret string
+	// AdminAPIKey must be sent in the X-Admin-Key header to privileged HTTP endpoints ("" leaves them open)
+	AdminAPIKey string
+	// TaskNetworks are network modes or Docker networks tasks may use besides bridge and none (e.g. host)
+	TaskNetworks []string
+	// CapacityTolerance flags a registering worker that claims more than this multiple of the capacity
+	// declared for it at manual registration (unset = server.DefaultCapacityTolerance);
+	// RejectCapacityMismatch refuses it instead of only warning
+	CapacityTolerance      float64
+	RejectCapacityMismatch bool
+	// TLS certificate and key for gRPC and the HTTP API, and the CA used to verify workers ("" = system roots);
+	// TLSRequireClientCert requires workers to present a certificate signed by TLSCAFile whose CN is their worker ID;
+	// AllowInsecure permits running without TLS (development only)
+	TLSCertFile          string
+	TLSKeyFile           string
+	TLSCAFile            string
+	TLSRequireClientCert bool
+	AllowInsecure        bool
+	// LogLevel is the minimum level written to the log: debug, info, warn or error
+	LogLevel string
 }
 
 // LoadConfig loads configuration from environment variables and .env file
@@ -33,10 +133,40 @@ func LoadConfig() *Config {
 	// Load SLA multiplier with validation
 	slaMultiplier := getEnvFloat("SCHED_SLA_MULTIPLIER", 2.0)
 	if slaMultiplier < 1.5 || slaMultiplier > 2.5 {
-		log.Printf("⚠️  Invalid SLA multiplier %.2f from env, using default 2.0", slaMultiplier)
+		logging.Warnf("⚠️  Invalid SLA multiplier %.2f from env, using default 2.0", slaMultiplier)
 		slaMultiplier = 2.0
 	}
 
+	reconnectConcurrency := getEnvInt("RECONNECT_MAX_CONCURRENCY", 8)
+	if reconnectConcurrency <= 0 {
+		logging.Warnf("⚠️  Invalid reconnect concurrency %d from env, using default 8", reconnectConcurrency)
+		reconnectConcurrency = 8
+	}
+
+	queueConcurrency := getEnvInt("QUEUE_ASSIGN_CONCURRENCY", 8)
+	if queueConcurrency <= 0 {
+		logging.Warnf("⚠️  Invalid queue assignment concurrency %d from env, using default 8", queueConcurrency)
+		queueConcurrency = 8
+	}
+
+	maxPool := getEnvInt("MONGO_MAX_POOL", 100)
+	if maxPool <= 0 {
+		logging.Warnf("⚠️  Invalid Mongo pool size %d from env, using default 100", maxPool)
+		maxPool = 100
+	}
+	connectTimeout := getEnvTimeout("MONGO_CONNECT_TIMEOUT", 10*time.Second)
+	selectionTimeout := getEnvTimeout("MONGO_SERVER_SELECTION_TIMEOUT", 5*time.Second)
+
+	overcommitCPU := getEnvOvercommit("OVERCOMMIT_CPU")
+	overcommitMemory := getEnvOvercommit("OVERCOMMIT_MEMORY")
+	overcommitStorage := getEnvOvercommit("OVERCOMMIT_STORAGE")
+	overcommitGPU := getEnvOvercommit("OVERCOMMIT_GPU")
+
+	reserveCPU := getEnvReserve("SYSTEM_RESERVE_CPU")
+	reserveMemory := getEnvReserve("SYSTEM_RESERVE_MEMORY")
+	reserveStorage := getEnvReserve("SYSTEM_RESERVE_STORAGE")
+	reserveGPU := getEnvReserve("SYSTEM_RESERVE_GPU")
+
 	var mongoURI string
 	if username != "" && password != "" {
 		mongoURI = "mongodb://" + username + ":" + password + "@" + host
@@ -51,7 +181,83 @@ func LoadConfig() *Config {
 		MongoDBURI:      mongoURI,
 		MongoDBDatabase: database,
 		HTTPPort:        httpPort,
-		SLAMultiplier:   slaMultiplier,
+
+		HTTPShutdownGracePeriod: getEnvTimeout("HTTP_SHUTDOWN_GRACE_PERIOD", 30*time.Second),
+
+		MinHeartbeatInterval: getEnvTimeout("MIN_HEARTBEAT_INTERVAL", time.Second),
+
+		MongoMaxPoolSize:            uint64(maxPool),
+		MongoConnectTimeout:         connectTimeout,
+		MongoServerSelectionTimeout: selectionTimeout,
+		SLAMultiplier:               slaMultiplier,
+
+		ReconnectConcurrency: reconnectConcurrency,
+		QueueConcurrency:     queueConcurrency,
+		MaxQueueLength:       getEnvInt("MAX_QUEUE_LENGTH", 0),
+		PreemptionPriority:   getEnvInt("PREEMPTION_PRIORITY", 0),
+		PreemptionCooldown:   getEnvTimeout("PREEMPTION_COOLDOWN", time.Minute),
+		FairShare:            getEnv("FAIR_SHARE", "false") == "true",
+		FairShareHalfLife:    getEnvTimeout("FAIR_SHARE_HALF_LIFE", time.Hour),
+		AutoscaleWindow:      getEnvTimeout("AUTOSCALE_WINDOW", 2*time.Minute),
+		GRPCReflection:       getEnv("GRPC_REFLECTION", "false") == "true",
+
+		GRPCKeepaliveTime:                getEnvTimeout("GRPC_KEEPALIVE_TIME", 30*time.Second),
+		GRPCKeepaliveTimeout:             getEnvTimeout("GRPC_KEEPALIVE_TIMEOUT", 10*time.Second),
+		GRPCKeepalivePermitWithoutStream: getEnv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "true") == "true",
+
+		OvercommitCPU:     overcommitCPU,
+		OvercommitMemory:  overcommitMemory,
+		OvercommitStorage: overcommitStorage,
+		OvercommitGPU:     overcommitGPU,
+
+		ReserveCPU:     reserveCPU,
+		ReserveMemory:  reserveMemory,
+		ReserveStorage: reserveStorage,
+		ReserveGPU:     reserveGPU,
+
+		NotifyWebhookURLs: getEnvList("NOTIFY_WEBHOOK_URLS"),
+
+		CLIPrompt:      getEnv("CLI_PROMPT", "master> "),
+		CLIHistoryFile: getEnv("CLI_HISTORY_FILE", ""),
+		CLIUser:        getEnv("CLOUDAI_USER", ""),
+
+		FileStorageBackend: strings.ToLower(getEnv("FILE_STORAGE_BACKEND", "local")),
+		S3Endpoint:         getEnv("S3_ENDPOINT", ""),
+		S3Bucket:           getEnv("S3_BUCKET", ""),
+		S3Region:           getEnv("S3_REGION", "us-east-1"),
+		S3AccessKey:        getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:        getEnv("S3_SECRET_KEY", ""),
+
+		TauBootstrap:           getEnv("TAU_BOOTSTRAP", "true") == "true",
+		TauBootstrapWindow:     getEnvTimeout("TAU_BOOTSTRAP_WINDOW", 7*24*time.Hour),
+		TauBootstrapMinSamples: getEnvInt("TAU_BOOTSTRAP_MIN_SAMPLES", 5),
+		AffinityHalfLife:       getEnvTimeout("AFFINITY_HALF_LIFE", 0),
+
+		TaskGCInterval: getEnvTimeout("TASK_GC_INTERVAL", 0),
+		TaskGCPolicy:   getEnvTaskGCPolicy("TASK_GC_POLICY"),
+
+		TaskRetentionDays:   getEnvInt("TASK_RETENTION_DAYS", 0),
+		TaskRetentionDryRun: getEnv("TASK_RETENTION_DRY_RUN", "false") == "true",
+
+		RateLimits: getEnvRateLimits(),
+
+		AutoRegister:    getEnv("AUTO_REGISTER", "false") == "true",
+		JoinTokenSecret: getEnv("JOIN_TOKEN_SECRET", ""),
+
+		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
+
+		TaskNetworks: getEnvList("TASK_NETWORKS"),
+
+		CapacityTolerance:      getEnvFloat("CAPACITY_TOLERANCE", 0),
+		RejectCapacityMismatch: getEnv("REJECT_CAPACITY_MISMATCH", "false") == "true",
+
+		TLSCertFile:          getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:           getEnv("TLS_KEY_FILE", ""),
+		TLSCAFile:            getEnv("TLS_CA_FILE", ""),
+		TLSRequireClientCert: getEnv("TLS_REQUIRE_CLIENT_CERT", "false") == "true",
+		AllowInsecure:        getEnv("ALLOW_INSECURE", "false") == "true",
+
+		LogLevel: getEnv("LOG_LEVEL", "info"),
 	}
 
 	return config
@@ -62,11 +268,11 @@ func loadDotEnv() {
 	paths := []string{".env", "../.env", "../../.env"}
 	for _, path := range paths {
 		if err := godotenv.Load(path); err == nil {
-			log.Printf("Loaded .env from %s", path)
+			logging.Infof("Loaded .env from %s", path)
 			return
 		}
 	}
-	log.Println("No .env file found, using environment variables")
+	logging.Info("No .env file found, using environment variables")
 }
 
 // getEnv gets an environment variable with a fallback value
@@ -83,7 +289,92 @@ func getEnvFloat(key string, fallback float64) float64 {
 		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
 			return parsed
 		}
-		log.Printf("⚠️  Invalid float value for %s: %s, using fallback %.2f", key, value, fallback)
+		logging.Warnf("⚠️  Invalid float value for %s: %s, using fallback %.2f", key, value, fallback)
 	}
 	return fallback
 }
+
+// getEnvInt gets an integer environment variable with a fallback value
+func getEnvInt(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		logging.Warnf("⚠️  Invalid integer value for %s: %s, using fallback %d", key, value, fallback)
+	}
+	return fallback
+}
+
+// getEnvList reads a comma-separated list, skipping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvOvercommit reads an over-commit ratio, falling back to 1.0 (strict) for non-positive values
+func getEnvOvercommit(key string) float64 {
+	ratio := getEnvFloat(key, 1.0)
+	if ratio <= 0 {
+		logging.Warnf("⚠️  Invalid over-commit ratio %.2f for %s, using default 1.0", ratio, key)
+		return 1.0
+	}
+	return ratio
+}
+
+// getEnvReserve reads a system reserve amount, falling back to 0 (no reserve) for negative values
+func getEnvReserve(key string) float64 {
+	reserve := getEnvFloat(key, 0.0)
+	if reserve < 0 {
+		logging.Warnf("⚠️  Invalid system reserve %.2f for %s, using default 0", reserve, key)
+		return 0.0
+	}
+	return reserve
+}
+
+// getEnvTaskGCPolicy reads the stuck task policy, falling back to "fail" for unknown values
+func getEnvTaskGCPolicy(key string) string {
+	policy := strings.ToLower(getEnv(key, "fail"))
+	if policy != "fail" && policy != "requeue" {
+		logging.Warnf("⚠️  Invalid task GC policy %q for %s, using default fail", policy, key)
+		return "fail"
+	}
+	return policy
+}
+
+// getEnvRateLimits reads RATE_LIMIT_<GROUP> for each HTTP route group, skipping unset groups
+func getEnvRateLimits() map[string]string {
+	limits := make(map[string]string)
+	for _, group := range []string{"tasks", "workers", "files", "auth"} {
+		if spec := getEnv("RATE_LIMIT_"+strings.ToUpper(group), ""); spec != "" {
+			limits[group] = spec
+		}
+	}
+	return limits
+}
+
+// getEnvTimeout reads a positive duration ("10s", "500ms" or plain seconds) with a fallback value
+func getEnvTimeout(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			logging.Warnf("⚠️  Invalid duration value for %s: %s, using fallback %s", key, value, fallback)
+			return fallback
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		logging.Warnf("⚠️  Invalid timeout %s for %s, using fallback %s", timeout, key, fallback)
+		return fallback
+	}
+	return timeout
+}
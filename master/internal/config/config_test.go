@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLoadConfigMongoPoolSettings tests parsing of the Mongo pool size and timeouts
+func TestLoadConfigMongoPoolSettings(t *testing.T) {
+	t.Setenv("MONGO_MAX_POOL", "250")
+	t.Setenv("MONGO_CONNECT_TIMEOUT", "3s")
+	t.Setenv("MONGO_SERVER_SELECTION_TIMEOUT", "20")
+
+	cfg := LoadConfig()
+
+	if cfg.MongoMaxPoolSize != 250 {
+		t.Errorf("Expected pool size 250, got %d", cfg.MongoMaxPoolSize)
+	}
+	if cfg.MongoConnectTimeout != 3*time.Second {
+		t.Errorf("Expected connect timeout 3s, got %s", cfg.MongoConnectTimeout)
+	}
+	if cfg.MongoServerSelectionTimeout != 20*time.Second {
+		t.Errorf("Expected plain seconds to parse as 20s, got %s", cfg.MongoServerSelectionTimeout)
+	}
+}
+
+// TestLoadConfigMongoPoolDefaults tests that invalid values fall back to the defaults
+func TestLoadConfigMongoPoolDefaults(t *testing.T) {
+	t.Setenv("MONGO_MAX_POOL", "-5")
+	t.Setenv("MONGO_CONNECT_TIMEOUT", "soon")
+	t.Setenv("MONGO_SERVER_SELECTION_TIMEOUT", "0s")
+
+	cfg := LoadConfig()
+
+	if cfg.MongoMaxPoolSize != 100 {
+		t.Errorf("Expected default pool size 100, got %d", cfg.MongoMaxPoolSize)
+	}
+	if cfg.MongoConnectTimeout != 10*time.Second {
+		t.Errorf("Expected default connect timeout 10s, got %s", cfg.MongoConnectTimeout)
+	}
+	if cfg.MongoServerSelectionTimeout != 5*time.Second {
+		t.Errorf("Expected default selection timeout 5s, got %s", cfg.MongoServerSelectionTimeout)
+	}
+}
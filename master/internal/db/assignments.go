@@ -9,7 +9,6 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Assignment represents a task-worker assignment in the database
@@ -29,7 +28,7 @@ type AssignmentDB struct {
 
 // NewAssignmentDB creates a new AssignmentDB instance
 func NewAssignmentDB(ctx context.Context, cfg *config.Config) (*AssignmentDB, error) {
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI))
+	client, err := mongo.Connect(ctx, ClientOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("connect to mongodb: %w", err)
 	}
@@ -46,6 +45,14 @@ func NewAssignmentDB(ctx context.Context, cfg *config.Config) (*AssignmentDB, er
 	}, nil
 }
 
+// NewAssignmentDBFromClient creates a AssignmentDB on top of an existing client connection
+func NewAssignmentDBFromClient(client *mongo.Client, database string) *AssignmentDB {
+	return &AssignmentDB{
+		client:     client,
+		collection: client.Database(database).Collection("ASSIGNMENTS"),
+	}
+}
+
 // CreateAssignment inserts a new assignment into the database
 func (db *AssignmentDB) CreateAssignment(ctx context.Context, assignment *Assignment) error {
 	assignment.AssignedAt = time.Now()
@@ -3,10 +3,10 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"master/internal/config"
+	"master/internal/logging"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -22,6 +22,29 @@ type FileMetadata struct {
 	FilePaths   []string  `bson:"file_paths"`
 	StoragePath string    `bson:"storage_path"`
 	UploadedAt  time.Time `bson:"uploaded_at"`
+
+	// Sizes and checksums of the files (absent for uploads before they were recorded)
+	Files []FileEntry `bson:"files,omitempty"`
+}
+
+// FileEntry describes one stored result file
+type FileEntry struct {
+	Path   string `bson:"path" json:"path"`
+	Size   int64  `bson:"size" json:"size"`
+	SHA256 string `bson:"sha256,omitempty" json:"sha256,omitempty"`
+}
+
+// Manifest lists the task's files with their sizes and checksums
+// Records stored before sizes were recorded list their paths only
+func (m *FileMetadata) Manifest() []FileEntry {
+	if len(m.Files) > 0 {
+		return m.Files
+	}
+	entries := make([]FileEntry, 0, len(m.FilePaths))
+	for _, path := range m.FilePaths {
+		entries = append(entries, FileEntry{Path: path})
+	}
+	return entries
 }
 
 // FileMetadataDB handles file metadata operations
@@ -32,8 +55,7 @@ type FileMetadataDB struct {
 
 // NewFileMetadataDB creates a new FileMetadataDB instance
 func NewFileMetadataDB(ctx context.Context, cfg *config.Config) (*FileMetadataDB, error) {
-	clientOptions := options.Client().ApplyURI(cfg.MongoDBURI)
-	client, err := mongo.Connect(ctx, clientOptions)
+	client, err := mongo.Connect(ctx, ClientOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
@@ -67,7 +89,7 @@ func NewFileMetadataDB(ctx context.Context, cfg *config.Config) (*FileMetadataDB
 
 	_, err = collection.Indexes().CreateMany(ctx, indexes)
 	if err != nil {
-		log.Printf("Warning: failed to create indexes: %v", err)
+		logging.Warnf("Warning: failed to create indexes: %v", err)
 	}
 
 	return &FileMetadataDB{
@@ -76,6 +98,14 @@ func NewFileMetadataDB(ctx context.Context, cfg *config.Config) (*FileMetadataDB
 	}, nil
 }
 
+// NewFileMetadataDBFromClient creates a FileMetadataDB on top of an existing client connection
+func NewFileMetadataDBFromClient(client *mongo.Client, database string) *FileMetadataDB {
+	return &FileMetadataDB{
+		client:     client,
+		collection: client.Database(database).Collection("FILE_METADATA"),
+	}
+}
+
 // Close closes the database connection
 func (db *FileMetadataDB) Close(ctx context.Context) error {
 	return db.client.Disconnect(ctx)
@@ -87,7 +117,7 @@ func (db *FileMetadataDB) CreateFileMetadata(ctx context.Context, metadata *File
 
 	_, err := db.collection.InsertOne(ctx, metadata)
 	if err != nil {
-		log.Printf("Error creating file metadata: %v", err)
+		logging.Errorf("Error creating file metadata: %v", err)
 		return err
 	}
 
@@ -102,7 +132,7 @@ func (db *FileMetadataDB) GetFileMetadataByTask(ctx context.Context, taskID stri
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
-		log.Printf("Error getting file metadata: %v", err)
+		logging.Errorf("Error getting file metadata: %v", err)
 		return nil, err
 	}
 
@@ -114,14 +144,14 @@ func (db *FileMetadataDB) GetFileMetadataByUser(ctx context.Context, userID stri
 	cursor, err := db.collection.Find(ctx, bson.M{"user_id": userID},
 		options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}))
 	if err != nil {
-		log.Printf("Error finding file metadata: %v", err)
+		logging.Errorf("Error finding file metadata: %v", err)
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	var results []*FileMetadata
 	if err := cursor.All(ctx, &results); err != nil {
-		log.Printf("Error decoding file metadata: %v", err)
+		logging.Errorf("Error decoding file metadata: %v", err)
 		return nil, err
 	}
 
@@ -135,14 +165,14 @@ func (db *FileMetadataDB) GetFileMetadataByUserAndTaskName(ctx context.Context,
 		"task_name": taskName,
 	}, options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}))
 	if err != nil {
-		log.Printf("Error finding file metadata: %v", err)
+		logging.Errorf("Error finding file metadata: %v", err)
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	var results []*FileMetadata
 	if err := cursor.All(ctx, &results); err != nil {
-		log.Printf("Error decoding file metadata: %v", err)
+		logging.Errorf("Error decoding file metadata: %v", err)
 		return nil, err
 	}
 
@@ -153,7 +183,7 @@ func (db *FileMetadataDB) GetFileMetadataByUserAndTaskName(ctx context.Context,
 func (db *FileMetadataDB) DeleteFileMetadata(ctx context.Context, taskID string) error {
 	_, err := db.collection.DeleteOne(ctx, bson.M{"task_id": taskID})
 	if err != nil {
-		log.Printf("Error deleting file metadata: %v", err)
+		logging.Errorf("Error deleting file metadata: %v", err)
 		return err
 	}
 
@@ -164,7 +194,7 @@ func (db *FileMetadataDB) DeleteFileMetadata(ctx context.Context, taskID string)
 func (db *FileMetadataDB) UpdateFileMetadata(ctx context.Context, taskID string, update bson.M) error {
 	_, err := db.collection.UpdateOne(ctx, bson.M{"task_id": taskID}, bson.M{"$set": update})
 	if err != nil {
-		log.Printf("Error updating file metadata: %v", err)
+		logging.Errorf("Error updating file metadata: %v", err)
 		return err
 	}
 
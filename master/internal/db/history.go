@@ -3,10 +3,11 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
+	"sort"
 	"time"
 
 	"master/internal/config"
+	"master/internal/logging"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -33,6 +34,10 @@ type TaskHistory struct {
 	LoadAtStart   float64   `bson:"load_at_start"`  // Worker load when task started
 	Tau           float64   `bson:"tau"`            // Expected runtime (baseline)
 	SLAMultiplier float64   `bson:"sla_multiplier"` // k value used for deadline
+	CacheHit      bool      `bson:"cache_hit"`      // Result served from the worker's result cache
+
+	// UserID is the user who submitted the task (for usage showback)
+	UserID string `bson:"user_id"`
 }
 
 // WorkerStats represents aggregated statistics for a worker over a time period
@@ -52,17 +57,30 @@ type WorkerStats struct {
 	PeriodEnd     time.Time `bson:"period_end"`     // End of observation period
 }
 
+// WorkerSnapshot is a worker's capacity and whether it was taking tasks, recorded whenever it joins or leaves
+// Simulations replay history against the workers these describe rather than the workers registered today
+type WorkerSnapshot struct {
+	WorkerID     string    `bson:"worker_id"`
+	TotalCPU     float64   `bson:"total_cpu"`
+	TotalMemory  float64   `bson:"total_memory"`
+	TotalStorage float64   `bson:"total_storage"`
+	TotalGPU     float64   `bson:"total_gpu"`
+	IsActive     bool      `bson:"is_active"`
+	RecordedAt   time.Time `bson:"recorded_at"`
+}
+
 // HistoryDB handles historical telemetry data operations
 type HistoryDB struct {
-	client            *mongo.Client
-	tasksCollection   *mongo.Collection
-	assignCollection  *mongo.Collection
-	resultsCollection *mongo.Collection
+	client              *mongo.Client
+	tasksCollection     *mongo.Collection
+	assignCollection    *mongo.Collection
+	resultsCollection   *mongo.Collection
+	snapshotsCollection *mongo.Collection
 }
 
 // NewHistoryDB creates a new HistoryDB instance
 func NewHistoryDB(ctx context.Context, cfg *config.Config) (*HistoryDB, error) {
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI))
+	client, err := mongo.Connect(ctx, ClientOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("connect to mongodb: %w", err)
 	}
@@ -74,10 +92,11 @@ func NewHistoryDB(ctx context.Context, cfg *config.Config) (*HistoryDB, error) {
 	database := client.Database(cfg.MongoDBDatabase)
 
 	return &HistoryDB{
-		client:            client,
-		tasksCollection:   database.Collection("TASKS"),
-		assignCollection:  database.Collection("ASSIGNMENTS"),
-		resultsCollection: database.Collection("RESULTS"),
+		client:              client,
+		tasksCollection:     database.Collection("TASKS"),
+		assignCollection:    database.Collection("ASSIGNMENTS"),
+		resultsCollection:   database.Collection("RESULTS"),
+		snapshotsCollection: database.Collection("WORKER_SNAPSHOTS"),
 	}, nil
 }
 
@@ -94,7 +113,7 @@ func (db *HistoryDB) Close(ctx context.Context) error {
 // Returns only tasks with valid task types (one of the 6 standardized types)
 func (db *HistoryDB) GetTaskHistory(ctx context.Context, since time.Time, until time.Time) ([]TaskHistory, error) {
 	// DEBUG: Log the query parameters
-	log.Printf("🔍 DEBUG: Querying TASKS collection for completed_at between %s and %s", since.Format(time.RFC3339), until.Format(time.RFC3339))
+	logging.Infof("🔍 DEBUG: Querying TASKS collection for completed_at between %s and %s", since.Format(time.RFC3339), until.Format(time.RFC3339))
 
 	// MongoDB aggregation pipeline to join collections
 	pipeline := mongo.Pipeline{
@@ -160,6 +179,7 @@ func (db *HistoryDB) GetTaskHistory(ctx context.Context, since time.Time, until
 			{Key: "$project", Value: bson.D{
 				{Key: "task_id", Value: "$task_id"},
 				{Key: "worker_id", Value: "$assignment.worker_id"},
+				{Key: "user_id", Value: "$user_id"},
 				{Key: "type", Value: "$computed_type"}, // Use the computed type field from Stage 0
 				{Key: "arrival_time", Value: "$created_at"},
 				{Key: "deadline", Value: bson.D{
@@ -301,6 +321,9 @@ func (db *HistoryDB) GetTaskHistory(ctx context.Context, since time.Time, until
 						}}},
 					}},
 				}},
+				{Key: "cache_hit", Value: bson.D{
+					{Key: "$ifNull", Value: bson.A{"$result.cache_hit", false}},
+				}},
 				{Key: "sla_multiplier", Value: bson.D{
 					{Key: "$cond", Value: bson.D{
 						{Key: "if", Value: bson.D{{Key: "$gt", Value: bson.A{"$sla_multiplier", 0}}}},
@@ -346,15 +369,71 @@ func (db *HistoryDB) GetTaskHistory(ctx context.Context, since time.Time, until
 	}
 
 	// DEBUG: Log what was found
-	log.Printf("🔍 DEBUG: Query returned %d task history records", len(history))
+	logging.Infof("🔍 DEBUG: Query returned %d task history records", len(history))
 	if len(history) > 0 {
-		log.Printf("🔍 DEBUG: Sample task: ID=%s, Worker=%s, Type=%s, Status at DB query time",
+		logging.Infof("🔍 DEBUG: Sample task: ID=%s, Worker=%s, Type=%s, Status at DB query time",
 			history[0].TaskID, history[0].WorkerID, history[0].Type)
 	}
 
 	return history, nil
 }
 
+// RecordWorkerSnapshot stores a worker's state at snapshot.RecordedAt
+func (db *HistoryDB) RecordWorkerSnapshot(ctx context.Context, snapshot WorkerSnapshot) error {
+	if _, err := db.snapshotsCollection.InsertOne(ctx, snapshot); err != nil {
+		return fmt.Errorf("insert worker snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetWorkerSnapshots retrieves the worker snapshots recorded up to until, oldest first
+// Earlier snapshots are included because they describe the workers already present when a window starts
+func (db *HistoryDB) GetWorkerSnapshots(ctx context.Context, until time.Time) ([]WorkerSnapshot, error) {
+	cursor, err := db.snapshotsCollection.Find(ctx,
+		bson.M{"recorded_at": bson.M{"$lte": until}},
+		options.Find().SetSort(bson.D{{Key: "recorded_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("find worker snapshots: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var snapshots []WorkerSnapshot
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("decode worker snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// WorkersActiveDuring returns, for each worker active at some point between since and until, its latest active snapshot
+// snapshots must be oldest first; the result is sorted by worker ID
+func WorkersActiveDuring(snapshots []WorkerSnapshot, since, until time.Time) []WorkerSnapshot {
+	latest := make(map[string]WorkerSnapshot) // worker_id -> latest active snapshot up to until
+	activeInWindow := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		if snapshot.RecordedAt.After(until) {
+			break
+		}
+		// The last snapshot before the window is the worker's state when the window opens
+		if !snapshot.RecordedAt.After(since) {
+			activeInWindow[snapshot.WorkerID] = snapshot.IsActive
+		} else if snapshot.IsActive {
+			activeInWindow[snapshot.WorkerID] = true
+		}
+		if snapshot.IsActive {
+			latest[snapshot.WorkerID] = snapshot
+		}
+	}
+
+	workers := make([]WorkerSnapshot, 0, len(latest))
+	for workerID, snapshot := range latest {
+		if activeInWindow[workerID] {
+			workers = append(workers, snapshot)
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].WorkerID < workers[j].WorkerID })
+	return workers
+}
+
 // GetWorkerStats computes aggregated statistics for each worker over a time period
 // This data is used for penalty vector computation in the GA module
 func (db *HistoryDB) GetWorkerStats(ctx context.Context, since time.Time, until time.Time) ([]WorkerStats, error) {
@@ -422,6 +501,58 @@ func (db *HistoryDB) GetWorkerStats(ctx context.Context, since time.Time, until
 	return result, nil
 }
 
+// UserUsage is one user's resource consumption over a time period, for cost showback
+// Resource-seconds are the allocated resources multiplied by each task's runtime
+type UserUsage struct {
+	UserID          string  `json:"user_id"`
+	Tasks           int     `json:"tasks"`             // Finished (completed or failed) tasks
+	SLAMet          int     `json:"sla_met"`           // Tasks that finished within their deadline
+	SLASuccessRate  float64 `json:"sla_success_rate"`  // SLAMet / Tasks (1 when there are no tasks)
+	RuntimeSeconds  float64 `json:"runtime_seconds"`   // Sum of task runtimes
+	CPUSeconds      float64 `json:"cpu_seconds"`       // Sum of CPU-core-seconds
+	MemoryGBSeconds float64 `json:"memory_gb_seconds"` // Sum of memory-GB-seconds
+	GPUSeconds      float64 `json:"gpu_seconds"`       // Sum of GPU-seconds
+}
+
+// AggregateUserUsage totals task history per user, sorted by user ID
+// When userID is set only that user's tasks are counted, and the user is reported even without tasks
+func AggregateUserUsage(history []TaskHistory, userID string) []UserUsage {
+	usage := make(map[string]*UserUsage)
+	if userID != "" {
+		usage[userID] = &UserUsage{UserID: userID}
+	}
+
+	for _, task := range history {
+		if userID != "" && task.UserID != userID {
+			continue
+		}
+		u, exists := usage[task.UserID]
+		if !exists {
+			u = &UserUsage{UserID: task.UserID}
+			usage[task.UserID] = u
+		}
+		u.Tasks++
+		if task.SLASuccess {
+			u.SLAMet++
+		}
+		u.RuntimeSeconds += task.ActualRuntime
+		u.CPUSeconds += task.CPUUsed * task.ActualRuntime
+		u.MemoryGBSeconds += task.MemUsed * task.ActualRuntime
+		u.GPUSeconds += task.GPUUsed * task.ActualRuntime
+	}
+
+	result := make([]UserUsage, 0, len(usage))
+	for _, u := range usage {
+		u.SLASuccessRate = 1.0
+		if u.Tasks > 0 {
+			u.SLASuccessRate = float64(u.SLAMet) / float64(u.Tasks)
+		}
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result
+}
+
 // GetTaskHistoryByType retrieves task history filtered by task type
 // Useful for per-type analysis and tau computation
 func (db *HistoryDB) GetTaskHistoryByType(ctx context.Context, taskType string, since time.Time, until time.Time) ([]TaskHistory, error) {
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWorkersActiveDuring tests that a window replays the workers active at some point in it, with their latest capacity
+func TestWorkersActiveDuring(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+	snapshots := []WorkerSnapshot{
+		{WorkerID: "worker-a", TotalCPU: 4, IsActive: true, RecordedAt: at(0)},
+		{WorkerID: "worker-gone", TotalCPU: 8, IsActive: true, RecordedAt: at(1)},
+		{WorkerID: "worker-gone", TotalCPU: 8, IsActive: false, RecordedAt: at(2)},
+		{WorkerID: "worker-b", TotalCPU: 2, IsActive: true, RecordedAt: at(11)},
+		{WorkerID: "worker-a", TotalCPU: 16, IsActive: true, RecordedAt: at(12)}, // Re-registered with more CPU
+		{WorkerID: "worker-b", TotalCPU: 2, IsActive: false, RecordedAt: at(13)},
+		{WorkerID: "worker-late", TotalCPU: 32, IsActive: true, RecordedAt: at(30)},
+	}
+
+	workers := WorkersActiveDuring(snapshots, at(10), at(20))
+
+	if len(workers) != 2 {
+		t.Fatalf("Expected worker-a and worker-b, got %+v", workers)
+	}
+	if workers[0].WorkerID != "worker-a" || workers[0].TotalCPU != 16 {
+		t.Errorf("Expected worker-a with its latest 16 CPUs, got %s with %.0f", workers[0].WorkerID, workers[0].TotalCPU)
+	}
+	if workers[1].WorkerID != "worker-b" || workers[1].TotalCPU != 2 {
+		t.Errorf("Expected worker-b, which left during the window, got %s with %.0f", workers[1].WorkerID, workers[1].TotalCPU)
+	}
+}
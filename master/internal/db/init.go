@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	"master/internal/config"
 
@@ -20,6 +19,22 @@ var collections = []string{
 	"TASKS",
 	"ASSIGNMENTS",
 	"RESULTS",
+	"WORKER_SNAPSHOTS",
+}
+
+// ClientOptions builds the Mongo client options (URI, pool size and timeouts) from config
+func ClientOptions(cfg *config.Config) *options.ClientOptions {
+	opts := options.Client().ApplyURI(cfg.MongoDBURI)
+	if cfg.MongoMaxPoolSize > 0 {
+		opts.SetMaxPoolSize(cfg.MongoMaxPoolSize)
+	}
+	if cfg.MongoConnectTimeout > 0 {
+		opts.SetConnectTimeout(cfg.MongoConnectTimeout)
+	}
+	if cfg.MongoServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(cfg.MongoServerSelectionTimeout)
+	}
+	return opts
 }
 
 // EnsureCollections connects to the MongoDB instance and makes sure the
@@ -32,7 +47,7 @@ func EnsureCollections(ctx context.Context, cfg *config.Config) error {
 		return errors.New("missing MongoDB credentials in environment")
 	}
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI).SetServerSelectionTimeout(5*time.Second))
+	client, err := mongo.Connect(ctx, ClientOptions(cfg))
 	if err != nil {
 		return fmt.Errorf("connect mongo: %w", err)
 	}
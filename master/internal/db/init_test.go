@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"master/internal/config"
+)
+
+// TestClientOptions tests that pool size and timeouts from config reach the Mongo client options
+func TestClientOptions(t *testing.T) {
+	cfg := &config.Config{
+		MongoDBURI:                  "mongodb://localhost:27017",
+		MongoMaxPoolSize:            42,
+		MongoConnectTimeout:         7 * time.Second,
+		MongoServerSelectionTimeout: 2 * time.Second,
+	}
+
+	opts := ClientOptions(cfg)
+
+	if opts.MaxPoolSize == nil || *opts.MaxPoolSize != 42 {
+		t.Errorf("Expected max pool size 42, got %v", opts.MaxPoolSize)
+	}
+	if opts.ConnectTimeout == nil || *opts.ConnectTimeout != 7*time.Second {
+		t.Errorf("Expected connect timeout 7s, got %v", opts.ConnectTimeout)
+	}
+	if opts.ServerSelectionTimeout == nil || *opts.ServerSelectionTimeout != 2*time.Second {
+		t.Errorf("Expected server selection timeout 2s, got %v", opts.ServerSelectionTimeout)
+	}
+	if len(opts.Hosts) != 1 || opts.Hosts[0] != "localhost:27017" {
+		t.Errorf("Expected URI host localhost:27017, got %v", opts.Hosts)
+	}
+}
+
+// TestClientOptionsLeavesUnsetValues tests that zero config values keep the driver defaults
+func TestClientOptionsLeavesUnsetValues(t *testing.T) {
+	opts := ClientOptions(&config.Config{MongoDBURI: "mongodb://localhost:27017"})
+
+	if opts.MaxPoolSize != nil || opts.ConnectTimeout != nil || opts.ServerSelectionTimeout != nil {
+		t.Errorf("Expected driver defaults for unset values, got pool=%v connect=%v selection=%v",
+			opts.MaxPoolSize, opts.ConnectTimeout, opts.ServerSelectionTimeout)
+	}
+}
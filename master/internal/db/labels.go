@@ -0,0 +1,46 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ValidateAnnotations rejects annotation keys that Mongo cannot store as field names
+func ValidateAnnotations(annotations map[string]string) error {
+	for key := range annotations {
+		if key == "" {
+			return fmt.Errorf("annotation key must not be empty")
+		}
+		if strings.ContainsAny(key, ".$") {
+			return fmt.Errorf("annotation key %q must not contain '.' or '$'", key)
+		}
+	}
+	return nil
+}
+
+// labelsUpdate builds the update document for a display name and annotations patch
+// A nil display name is left untouched; an empty annotation value removes that key
+func labelsUpdate(displayName *string, annotations map[string]string) bson.M {
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+
+	if displayName != nil {
+		set["display_name"] = *displayName
+	}
+	for key, value := range annotations {
+		if value == "" {
+			unset["annotations."+key] = ""
+		} else {
+			set["annotations."+key] = value
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
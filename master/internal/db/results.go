@@ -3,24 +3,80 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"master/internal/config"
+	"master/internal/logging"
+	pb "master/proto"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // TaskResult represents a task result with logs stored in MongoDB
 type TaskResult struct {
-	TaskID      string    `bson:"task_id"`
-	WorkerID    string    `bson:"worker_id"`
-	Status      string    `bson:"status"` // "success", "failed"
-	Logs        string    `bson:"logs"`
-	CompletedAt time.Time `bson:"completed_at"`
-	SLASuccess  bool      `bson:"sla_success"` // Task 2.5: Whether task met its deadline
+	TaskID        string         `bson:"task_id"`
+	WorkerID      string         `bson:"worker_id"`
+	Status        string         `bson:"status"` // "success", "failed"
+	Logs          string         `bson:"logs"`
+	CompletedAt   time.Time      `bson:"completed_at"`
+	SLASuccess    bool           `bson:"sla_success"`              // Task 2.5: Whether task met its deadline
+	CacheHit      bool           `bson:"cache_hit"`                // Result served from the worker's result cache
+	FailureReason string         `bson:"failure_reason,omitempty"` // Why a failed task ended: oom, signal, exit_code or image_pull_timeout
+	ExitCode      int32          `bson:"exit_code"`                // Container exit code reported by the worker
+	ErrorMessage  string         `bson:"error_message,omitempty"`  // Error detail reported by the worker
+	Usage         *ResourceUsage `bson:"usage,omitempty"`          // Sampled container usage, for right-sizing
+	// Host ports the task's container ports were published on
+	PublishedPorts []PublishedPort `bson:"published_ports,omitempty"`
+	// Repo digest the task's image resolved to when it ran
+	ImageDigest string `bson:"image_digest,omitempty"`
+}
+
+// PublishedPort is a task container port published on its worker's host
+type PublishedPort struct {
+	ContainerPort string `bson:"container_port" json:"container_port"` // e.g. "8080/tcp"
+	HostIP        string `bson:"host_ip" json:"host_ip"`
+	HostPort      int32  `bson:"host_port" json:"host_port"`
+}
+
+// PublishedPortsFromProto converts the published ports a worker reported
+func PublishedPortsFromProto(ports []*pb.PortMapping) []PublishedPort {
+	var published []PublishedPort
+	for _, p := range ports {
+		published = append(published, PublishedPort{ContainerPort: p.ContainerPort, HostIP: p.HostIp, HostPort: p.HostPort})
+	}
+	return published
+}
+
+// ResourceUsage summarizes a task's sampled container usage: CPU in cores, memory in GB
+type ResourceUsage struct {
+	CPUMin    float64 `bson:"cpu_min" json:"cpu_min"`
+	CPUAvg    float64 `bson:"cpu_avg" json:"cpu_avg"`
+	CPUP95    float64 `bson:"cpu_p95" json:"cpu_p95"`
+	CPUMax    float64 `bson:"cpu_max" json:"cpu_max"`
+	MemoryMin float64 `bson:"memory_min" json:"memory_min"`
+	MemoryAvg float64 `bson:"memory_avg" json:"memory_avg"`
+	MemoryP95 float64 `bson:"memory_p95" json:"memory_p95"`
+	MemoryMax float64 `bson:"memory_max" json:"memory_max"`
+	Samples   int     `bson:"samples" json:"samples"`
+}
+
+// ResourceUsageFromProto converts the usage a worker reported (nil if it sent none)
+func ResourceUsageFromProto(u *pb.ResourceUsage) *ResourceUsage {
+	if u == nil {
+		return nil
+	}
+	return &ResourceUsage{
+		CPUMin:    u.CpuMin,
+		CPUAvg:    u.CpuAvg,
+		CPUP95:    u.CpuP95,
+		CPUMax:    u.CpuMax,
+		MemoryMin: u.MemoryMin,
+		MemoryAvg: u.MemoryAvg,
+		MemoryP95: u.MemoryP95,
+		MemoryMax: u.MemoryMax,
+		Samples:   int(u.Samples),
+	}
 }
 
 // ResultDB handles task results operations
@@ -31,7 +87,7 @@ type ResultDB struct {
 
 // NewResultDB creates a new ResultDB instance
 func NewResultDB(ctx context.Context, cfg *config.Config) (*ResultDB, error) {
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI))
+	client, err := mongo.Connect(ctx, ClientOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("connect to mongodb: %w", err)
 	}
@@ -48,6 +104,26 @@ func NewResultDB(ctx context.Context, cfg *config.Config) (*ResultDB, error) {
 	}, nil
 }
 
+// NewResultDBFromClient creates a ResultDB on top of an existing client connection
+func NewResultDBFromClient(client *mongo.Client, database string) *ResultDB {
+	return &ResultDB{
+		client:     client,
+		collection: client.Database(database).Collection("RESULTS"),
+	}
+}
+
+// ExitSummary describes how a failed task's container exited, e.g. "exited with code 137 (oom)"
+// Returns "" when the worker reported no exit code
+func (r *TaskResult) ExitSummary() string {
+	if r.ExitCode == 0 {
+		return ""
+	}
+	if r.FailureReason != "" {
+		return fmt.Sprintf("exited with code %d (%s)", r.ExitCode, r.FailureReason)
+	}
+	return fmt.Sprintf("exited with code %d", r.ExitCode)
+}
+
 // Close closes the database connection
 func (rdb *ResultDB) Close(ctx context.Context) error {
 	if rdb.client != nil {
@@ -62,7 +138,7 @@ func (rdb *ResultDB) CreateResult(ctx context.Context, result *TaskResult) error
 
 	_, err := rdb.collection.InsertOne(ctx, result)
 	if err != nil {
-		log.Printf("Error creating result: %v", err)
+		logging.Errorf("Error creating result: %v", err)
 		return err
 	}
 
@@ -77,25 +153,36 @@ func (rdb *ResultDB) GetResult(ctx context.Context, taskID string) (*TaskResult,
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
-		log.Printf("Error getting result: %v", err)
+		logging.Errorf("Error getting result: %v", err)
 		return nil, err
 	}
 
 	return &result, nil
 }
 
+// DeleteResults removes every stored result of a task; a task without results is not an error
+func (rdb *ResultDB) DeleteResults(ctx context.Context, taskID string) error {
+	_, err := rdb.collection.DeleteMany(ctx, bson.M{"task_id": taskID})
+	if err != nil {
+		logging.Errorf("Error deleting results: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 // GetResultsByWorker retrieves all results for a specific worker
 func (rdb *ResultDB) GetResultsByWorker(ctx context.Context, workerID string) ([]TaskResult, error) {
 	cursor, err := rdb.collection.Find(ctx, bson.M{"worker_id": workerID})
 	if err != nil {
-		log.Printf("Error querying results by worker: %v", err)
+		logging.Errorf("Error querying results by worker: %v", err)
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	var results []TaskResult
 	if err = cursor.All(ctx, &results); err != nil {
-		log.Printf("Error decoding results: %v", err)
+		logging.Errorf("Error decoding results: %v", err)
 		return nil, err
 	}
 
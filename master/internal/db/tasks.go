@@ -3,9 +3,11 @@ package db
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"master/internal/config"
+	"master/internal/logging"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -28,17 +30,50 @@ type Task struct {
 	// GUI fields: generic tagging
 	Tag    string  `bson:"tag,omitempty"`    // Generic tag field from GUI
 	KValue float64 `bson:"k_value,omitempty"` // K-value from GUI (same as SLAMultiplier)
+
+	// User-editable labels, set via PATCH /api/tasks/{id}
+	DisplayName string            `bson:"display_name,omitempty"`
+	Annotations map[string]string `bson:"annotations,omitempty"`
 	
 	// Scheduler fields: SLA and task classification
 	TaskType      string    `bson:"task_type,omitempty"`    // Task type: cpu-light, cpu-heavy, memory-heavy, gpu-inference, gpu-training, mixed
 	SLAMultiplier float64   `bson:"sla_multiplier"`         // k value: 1.5-2.5, default: 2.0 (prioritized over KValue if both set)
 	Deadline      time.Time `bson:"deadline,omitempty"`     // SLA deadline: arrival_time + k * tau
 	Tau           float64   `bson:"tau,omitempty"`          // Expected runtime baseline (seconds)
+	Priority      int32     `bson:"priority,omitempty"`     // Higher is more important; used for preemption
+	// Set on a requeued task: the task whose spec it was copied from
+	OriginalTaskID string `bson:"original_task_id,omitempty"`
+	// Client-supplied job ID the task can be looked up and cancelled by
+	ExternalRef string `bson:"external_ref,omitempty"`
+	// Container networking for service tasks: Docker network mode and ports to publish
+	NetworkMode  string   `bson:"network_mode,omitempty"`
+	PortBindings []string `bson:"port_bindings,omitempty"`
+	// Working directory and user inside the container (empty = image defaults)
+	WorkingDir string `bson:"working_dir,omitempty"`
+	RunAsUser  string `bson:"run_as_user,omitempty"`
+	// Root filesystem mounted read-only (the /output mount stays writable)
+	ReadOnlyRootFS bool `bson:"read_only_rootfs,omitempty"`
+	// Service readiness: command run in the started container, and seconds it may take to pass
+	HealthCheck   string `bson:"health_check,omitempty"`
+	HealthTimeout int32  `bson:"health_check_timeout_sec,omitempty"`
+
+	// Execution and placement options carried over when the task is requeued or retried
+	PinCPUs            bool    `bson:"pin_cpus,omitempty"`              // Dedicated CPU cores requested
+	Cacheable          bool    `bson:"cacheable,omitempty"`             // Result may be served from a worker's result cache
+	LocalityKey        string  `bson:"locality_key,omitempty"`          // Prefer the worker that last ran a task with this key
+	AbsoluteDeadline   int64   `bson:"absolute_deadline,omitempty"`     // Client-set absolute deadline (Unix timestamp)
+	MaxRestarts        int32   `bson:"max_restarts,omitempty"`          // Restarts allowed on a non-zero exit
+	MemLimit           float64 `bson:"mem_limit,omitempty"`             // Hard memory cap (GB) above req_memory
+	StopGracePeriodSec int32   `bson:"stop_grace_period_sec,omitempty"` // Seconds between SIGTERM and SIGKILL on cancel
+	AntiAffinityKey    string  `bson:"anti_affinity_key,omitempty"`     // Tasks sharing this key are spread across zones
+	PreferredWorkerID  string  `bson:"preferred_worker_id,omitempty"`   // Worker tried first when placing the task
+	ReservationID      string  `bson:"reservation_id,omitempty"`        // Capacity reservation the task draws from
 	
 	Status      string    `bson:"status"` // pending, running, completed, failed
 	CreatedAt   time.Time `bson:"created_at"`
 	StartedAt   time.Time `bson:"started_at,omitempty"`
 	CompletedAt time.Time `bson:"completed_at,omitempty"`
+	ReadyAt     time.Time `bson:"ready_at,omitempty"` // When the task's health check first passed
 }
 
 // TaskDB handles task-related database operations
@@ -49,7 +84,7 @@ type TaskDB struct {
 
 // NewTaskDB creates a new TaskDB instance
 func NewTaskDB(ctx context.Context, cfg *config.Config) (*TaskDB, error) {
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI))
+	client, err := mongo.Connect(ctx, ClientOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("connect to mongodb: %w", err)
 	}
@@ -60,12 +95,29 @@ func NewTaskDB(ctx context.Context, cfg *config.Config) (*TaskDB, error) {
 
 	collection := client.Database(cfg.MongoDBDatabase).Collection("TASKS")
 
+	// Index external references so cancelling by a client's job ID does not scan every task
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "external_ref", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		logging.Warnf("Warning: failed to create external_ref index: %v", err)
+	}
+
 	return &TaskDB{
 		client:     client,
 		collection: collection,
 	}, nil
 }
 
+// NewTaskDBFromClient creates a TaskDB on top of an existing client connection
+func NewTaskDBFromClient(client *mongo.Client, database string) *TaskDB {
+	return &TaskDB{
+		client:     client,
+		collection: client.Database(database).Collection("TASKS"),
+	}
+}
+
 // CreateTask inserts a new task into the database
 func (db *TaskDB) CreateTask(ctx context.Context, task *Task) error {
 	task.CreatedAt = time.Now()
@@ -93,6 +145,30 @@ func (db *TaskDB) GetTask(ctx context.Context, taskID string) (*Task, error) {
 	return &task, nil
 }
 
+// GetTaskByExternalRef retrieves the most recently created task with the given external reference
+func (db *TaskDB) GetTaskByExternalRef(ctx context.Context, ref string) (*Task, error) {
+	var task Task
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	err := db.collection.FindOne(ctx, bson.M{"external_ref": ref}, opts).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("no task with external ref %s: %w", ref, err)
+		}
+		return nil, fmt.Errorf("find task: %w", err)
+	}
+
+	return &task, nil
+}
+
+// TaskExists reports whether a task with the given ID is already stored
+func (db *TaskDB) TaskExists(ctx context.Context, taskID string) (bool, error) {
+	count, err := db.collection.CountDocuments(ctx, bson.M{"task_id": taskID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("count tasks: %w", err)
+	}
+	return count > 0, nil
+}
+
 // GetTasksByUser retrieves all tasks for a specific user
 func (db *TaskDB) GetTasksByUser(ctx context.Context, userID string) ([]*Task, error) {
 	cursor, err := db.collection.Find(ctx, bson.M{"user_id": userID})
@@ -125,6 +201,43 @@ func (db *TaskDB) GetAllTasks(ctx context.Context) ([]*Task, error) {
 	return tasks, nil
 }
 
+// unfinishedTasksFilter matches queued, pending, held and running tasks with the given owner, image and
+// annotation (key=value, or key for any value); empty arguments match anything
+func unfinishedTasksFilter(userID, image, label string) bson.M {
+	filter := bson.M{"status": bson.M{"$in": []string{"queued", "pending", "held", "running"}}}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+	if image != "" {
+		filter["docker_image"] = image
+	}
+	if label != "" {
+		key, value, hasValue := strings.Cut(label, "=")
+		if hasValue {
+			filter["annotations."+key] = value
+		} else {
+			filter["annotations."+key] = bson.M{"$exists": true}
+		}
+	}
+	return filter
+}
+
+// GetUnfinishedTasks retrieves the unfinished tasks with the given owner, image and annotation ("" matches anything)
+func (db *TaskDB) GetUnfinishedTasks(ctx context.Context, userID, image, label string) ([]*Task, error) {
+	cursor, err := db.collection.Find(ctx, unfinishedTasksFilter(userID, image, label))
+	if err != nil {
+		return nil, fmt.Errorf("find unfinished tasks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []*Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, fmt.Errorf("decode tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
 // GetTasksByStatus retrieves all tasks with a specific status
 func (db *TaskDB) GetTasksByStatus(ctx context.Context, status string) ([]*Task, error) {
 	cursor, err := db.collection.Find(ctx, bson.M{"status": status})
@@ -141,6 +254,25 @@ func (db *TaskDB) GetTasksByStatus(ctx context.Context, status string) ([]*Task,
 	return tasks, nil
 }
 
+// GetTasksCreatedBefore retrieves tasks in one of the given statuses that were created before cutoff
+func (db *TaskDB) GetTasksCreatedBefore(ctx context.Context, statuses []string, cutoff time.Time) ([]*Task, error) {
+	cursor, err := db.collection.Find(ctx, bson.M{
+		"status":     bson.M{"$in": statuses},
+		"created_at": bson.M{"$lt": cutoff},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find tasks created before %s: %w", cutoff.Format(time.RFC3339), err)
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []*Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, fmt.Errorf("decode tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
 // UpdateTaskStatus updates the status of a task
 func (db *TaskDB) UpdateTaskStatus(ctx context.Context, taskID string, status string) error {
 	update := bson.M{
@@ -152,7 +284,7 @@ func (db *TaskDB) UpdateTaskStatus(ctx context.Context, taskID string, status st
 	// Add timestamp fields based on status
 	if status == "running" {
 		update["$set"].(bson.M)["started_at"] = time.Now()
-	} else if status == "completed" || status == "failed" {
+	} else if status == "completed" || status == "failed" || status == "crashloop" {
 		update["$set"].(bson.M)["completed_at"] = time.Now()
 	}
 
@@ -172,6 +304,32 @@ func (db *TaskDB) UpdateTaskStatus(ctx context.Context, taskID string, status st
 	return nil
 }
 
+// SwapTaskStatus moves a task from one status to another only if it is still in the first
+// Returns false when the task is missing or in another status, so concurrent transitions happen once
+func (db *TaskDB) SwapTaskStatus(ctx context.Context, taskID, from, to string) (bool, error) {
+	result, err := db.collection.UpdateOne(
+		ctx,
+		bson.M{"task_id": taskID, "status": from},
+		bson.M{"$set": bson.M{"status": to}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("swap task status: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// MarkTaskReady records when a running task's health check passed
+func (db *TaskDB) MarkTaskReady(ctx context.Context, taskID string) error {
+	result, err := db.collection.UpdateOne(ctx, bson.M{"task_id": taskID}, bson.M{"$set": bson.M{"ready_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("mark task ready: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	return nil
+}
+
 // UpdateTaskMetadata updates task metadata fields such as tag and k_value
 func (db *TaskDB) UpdateTaskMetadata(ctx context.Context, taskID string, tag string, kValue float64) error {
 	update := bson.M{
@@ -192,6 +350,18 @@ func (db *TaskDB) UpdateTaskMetadata(ctx context.Context, taskID string, tag str
 	return nil
 }
 
+// UpdateTaskLabels updates a task's display name and merges its annotations
+func (db *TaskDB) UpdateTaskLabels(ctx context.Context, taskID string, displayName *string, annotations map[string]string) error {
+	result, err := db.collection.UpdateOne(ctx, bson.M{"task_id": taskID}, labelsUpdate(displayName, annotations))
+	if err != nil {
+		return fmt.Errorf("update task labels: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	return nil
+}
+
 // DeleteTask removes a task from the database
 func (db *TaskDB) DeleteTask(ctx context.Context, taskID string) error {
 	result, err := db.collection.DeleteOne(ctx, bson.M{"task_id": taskID})
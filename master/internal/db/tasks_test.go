@@ -129,3 +129,23 @@ func TestTagValues(t *testing.T) {
 		})
 	}
 }
+
+// TestUnfinishedTasksFilter tests that the batch cancel query only matches unfinished tasks with the requested fields
+func TestUnfinishedTasksFilter(t *testing.T) {
+	filter := unfinishedTasksFilter("alice", "trainer:latest", "team=ml")
+	if filter["user_id"] != "alice" || filter["docker_image"] != "trainer:latest" || filter["annotations.team"] != "ml" {
+		t.Errorf("Expected user, image and annotation to be matched, got %v", filter)
+	}
+	statuses, _ := filter["status"].(bson.M)["$in"].([]string)
+	if len(statuses) != 4 {
+		t.Errorf("Expected the 4 unfinished statuses, got %v", statuses)
+	}
+
+	filter = unfinishedTasksFilter("", "", "team")
+	if _, ok := filter["user_id"]; ok {
+		t.Errorf("Expected no user constraint, got %v", filter)
+	}
+	if exists, ok := filter["annotations.team"].(bson.M); !ok || exists["$exists"] != true {
+		t.Errorf("Expected a bare label key to match any value, got %v", filter["annotations.team"])
+	}
+}
@@ -11,7 +11,6 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,6 +24,9 @@ type User struct {
 	UpdatedAt    time.Time `bson:"updated_at" json:"updated_at"`
 }
 
+// ErrUserExists is returned by CreateUser when the email is already registered
+var ErrUserExists = errors.New("user with this email already exists")
+
 // UserDB handles user database operations
 type UserDB struct {
 	client     *mongo.Client
@@ -41,7 +43,7 @@ func NewUserDB(ctx context.Context, cfg *config.Config) (*UserDB, error) {
 		return nil, errors.New("missing MongoDB credentials in environment")
 	}
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI).SetServerSelectionTimeout(5*time.Second))
+	client, err := mongo.Connect(ctx, ClientOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("connect mongo: %w", err)
 	}
@@ -77,7 +79,7 @@ func (db *UserDB) CreateUser(name, email, password string) error {
 	var existingUser User
 	err := db.collection.FindOne(ctx, bson.M{"email": email}).Decode(&existingUser)
 	if err == nil {
-		return errors.New("user with this email already exists")
+		return ErrUserExists
 	}
 	if err != mongo.ErrNoDocuments {
 		return err
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultWorkerCacheTTL is how long GetAllWorkers serves cached results before re-reading Mongo
+const DefaultWorkerCacheTTL = 2 * time.Second
+
+// workerCache holds a short-lived copy of the worker registry for hot GetAllWorkers reads
+// Every write bumps the generation, so a read that raced a write never stores stale data
+type workerCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	workers    []WorkerDocument
+	loadedAt   time.Time
+	valid      bool
+	generation uint64
+}
+
+// get returns a copy of the cached workers if they are still fresh, plus the generation to store under
+func (c *workerCache) get(now time.Time) ([]WorkerDocument, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.valid || c.ttl <= 0 || now.Sub(c.loadedAt) >= c.ttl {
+		return nil, c.generation, false
+	}
+	return copyWorkerDocuments(c.workers), c.generation, true
+}
+
+// store caches workers read at the given generation, unless a write happened since
+func (c *workerCache) store(workers []WorkerDocument, generation uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl <= 0 || generation != c.generation {
+		return
+	}
+	c.workers = copyWorkerDocuments(workers)
+	c.loadedAt = now
+	c.valid = true
+}
+
+// invalidate drops the cached workers after a write
+func (c *workerCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.valid = false
+	c.workers = nil
+}
+
+// setTTL changes the cache lifetime; zero or negative disables caching
+func (c *workerCache) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+	c.generation++
+	c.valid = false
+	c.workers = nil
+}
+
+func copyWorkerDocuments(workers []WorkerDocument) []WorkerDocument {
+	if workers == nil {
+		return nil
+	}
+	out := make([]WorkerDocument, len(workers))
+	copy(out, workers)
+	return out
+}
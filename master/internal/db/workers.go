@@ -12,12 +12,12 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type WorkerDB struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	cache      workerCache
 }
 
 type WorkerDocument struct {
@@ -40,6 +40,30 @@ type WorkerDocument struct {
 	LastHeartbeat    int64     `bson:"last_heartbeat"`
 	RegisteredAt     time.Time `bson:"registered_at"`
 	UpdatedAt        time.Time `bson:"updated_at"`
+	// User-editable labels, set via PATCH /api/workers/{id}
+	DisplayName string            `bson:"display_name,omitempty"`
+	Annotations map[string]string `bson:"annotations,omitempty"`
+	// Zone is the topology label reported at registration (rack, AZ)
+	Zone string `bson:"zone,omitempty"`
+	// Maintenance is the recurring window during which the worker is cordoned (nil = none)
+	Maintenance *MaintenanceWindow `bson:"maintenance,omitempty"`
+	// Declared is the capacity an admin expects the worker to have (nil = not declared)
+	Declared *DeclaredCapacity `bson:"declared_capacity,omitempty"`
+}
+
+// DeclaredCapacity is the capacity an admin declares for a worker when registering it
+// The worker's own claims are checked against it; zero fields are not checked
+type DeclaredCapacity struct {
+	CPU    float64 `bson:"cpu"`
+	Memory float64 `bson:"memory"` // GB
+	GPU    float64 `bson:"gpu"`
+}
+
+// MaintenanceWindow is a recurring maintenance window: it opens whenever the cron expression
+// (minute hour day-of-month month day-of-week, master local time) matches and lasts Duration
+type MaintenanceWindow struct {
+	Cron     string        `bson:"cron"`
+	Duration time.Duration `bson:"duration"`
 }
 
 // NewWorkerDB creates a new WorkerDB instance
@@ -52,7 +76,7 @@ func NewWorkerDB(ctx context.Context, cfg *config.Config) (*WorkerDB, error) {
 		return nil, errors.New("missing MongoDB credentials in environment")
 	}
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI).SetServerSelectionTimeout(5*time.Second))
+	client, err := mongo.Connect(ctx, ClientOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("connect mongo: %w", err)
 	}
@@ -65,10 +89,27 @@ func NewWorkerDB(ctx context.Context, cfg *config.Config) (*WorkerDB, error) {
 	database := client.Database(cfg.MongoDBDatabase)
 	collection := database.Collection("WORKER_REGISTRY")
 
-	return &WorkerDB{
+	workerDB := &WorkerDB{
 		client:     client,
 		collection: collection,
-	}, nil
+	}
+	workerDB.cache.ttl = DefaultWorkerCacheTTL
+	return workerDB, nil
+}
+
+// NewWorkerDBFromClient creates a WorkerDB on top of an existing client connection
+func NewWorkerDBFromClient(client *mongo.Client, database string) *WorkerDB {
+	workerDB := &WorkerDB{
+		client:     client,
+		collection: client.Database(database).Collection("WORKER_REGISTRY"),
+	}
+	workerDB.cache.ttl = DefaultWorkerCacheTTL
+	return workerDB
+}
+
+// SetCacheTTL changes how long GetAllWorkers results are cached; zero disables the cache
+func (db *WorkerDB) SetCacheTTL(ttl time.Duration) {
+	db.cache.setTTL(ttl)
 }
 
 // Close closes the database connection
@@ -82,6 +123,8 @@ func (db *WorkerDB) Close(ctx context.Context) error {
 // RegisterWorker registers a new worker (manual registration with just ID and address)
 // workerIP should be in format "ip:port" (e.g., "192.168.1.100:50052")
 func (db *WorkerDB) RegisterWorker(ctx context.Context, workerID, workerIP string) error {
+	defer db.cache.invalidate()
+
 	doc := WorkerDocument{
 		WorkerID:     workerID,
 		WorkerIP:     workerIP, // Format: "ip:port"
@@ -113,6 +156,8 @@ func (db *WorkerDB) RegisterWorker(ctx context.Context, workerID, workerIP strin
 
 // UpdateWorkerInfo updates worker details (called when worker connects and sends full specs)
 func (db *WorkerDB) UpdateWorkerInfo(ctx context.Context, info *pb.WorkerInfo) error {
+	defer db.cache.invalidate()
+
 	filter := bson.M{"worker_id": info.WorkerId}
 
 	// Get current allocated resources to preserve them
@@ -138,6 +183,7 @@ func (db *WorkerDB) UpdateWorkerInfo(ctx context.Context, info *pb.WorkerInfo) e
 			"total_memory":      info.TotalMemory,
 			"total_storage":     info.TotalStorage,
 			"total_gpu":         info.TotalGpu,
+			"zone":              info.Zone,
 			"available_cpu":     availableCPU,
 			"available_memory":  availableMemory,
 			"available_storage": availableStorage,
@@ -162,6 +208,8 @@ func (db *WorkerDB) UpdateWorkerInfo(ctx context.Context, info *pb.WorkerInfo) e
 
 // UpdateWorkerResources updates worker resource specifications (called from manual registration)
 func (db *WorkerDB) UpdateWorkerResources(ctx context.Context, workerID string, totalCPU, totalMemory, totalStorage, totalGPU float64) error {
+	defer db.cache.invalidate()
+
 	filter := bson.M{"worker_id": workerID}
 
 	// Get current allocated resources to calculate available
@@ -208,6 +256,7 @@ func (db *WorkerDB) UpdateWorkerResources(ctx context.Context, workerID string,
 }
 
 // UpdateHeartbeat updates the last heartbeat timestamp
+// Heartbeats don't invalidate the GetAllWorkers cache; at heartbeat rates that would defeat it
 func (db *WorkerDB) UpdateHeartbeat(ctx context.Context, workerID string, timestamp int64) error {
 	filter := bson.M{"worker_id": workerID}
 	update := bson.M{
@@ -222,8 +271,92 @@ func (db *WorkerDB) UpdateHeartbeat(ctx context.Context, workerID string, timest
 	return err
 }
 
+// MarkInactive flags a worker as inactive without touching its heartbeat history
+func (db *WorkerDB) MarkInactive(ctx context.Context, workerID string) error {
+	defer db.cache.invalidate()
+
+	filter := bson.M{"worker_id": workerID}
+	update := bson.M{
+		"$set": bson.M{
+			"is_active":  false,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := db.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("mark worker inactive: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("worker %s not found", workerID)
+	}
+
+	return nil
+}
+
+// UpdateWorkerLabels updates a worker's display name and merges its annotations
+func (db *WorkerDB) UpdateWorkerLabels(ctx context.Context, workerID string, displayName *string, annotations map[string]string) error {
+	defer db.cache.invalidate()
+
+	result, err := db.collection.UpdateOne(ctx, bson.M{"worker_id": workerID}, labelsUpdate(displayName, annotations))
+	if err != nil {
+		return fmt.Errorf("update worker labels: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("worker %s not found", workerID)
+	}
+	return nil
+}
+
+// SetMaintenanceWindow sets a worker's maintenance window, or removes it when window is nil
+func (db *WorkerDB) SetMaintenanceWindow(ctx context.Context, workerID string, window *MaintenanceWindow) error {
+	defer db.cache.invalidate()
+
+	update := bson.M{"$set": bson.M{"maintenance": window, "updated_at": time.Now()}}
+	if window == nil {
+		update = bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"maintenance": ""},
+		}
+	}
+
+	result, err := db.collection.UpdateOne(ctx, bson.M{"worker_id": workerID}, update)
+	if err != nil {
+		return fmt.Errorf("set maintenance window: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("worker %s not found", workerID)
+	}
+	return nil
+}
+
+// SetDeclaredCapacity records the capacity an admin declared for a worker, or removes it when declared is nil
+func (db *WorkerDB) SetDeclaredCapacity(ctx context.Context, workerID string, declared *DeclaredCapacity) error {
+	defer db.cache.invalidate()
+
+	update := bson.M{"$set": bson.M{"declared_capacity": declared, "updated_at": time.Now()}}
+	if declared == nil {
+		update = bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"declared_capacity": ""},
+		}
+	}
+
+	result, err := db.collection.UpdateOne(ctx, bson.M{"worker_id": workerID}, update)
+	if err != nil {
+		return fmt.Errorf("set declared capacity: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("worker %s not found", workerID)
+	}
+	return nil
+}
+
 // UnregisterWorker removes a worker from the registry
 func (db *WorkerDB) UnregisterWorker(ctx context.Context, workerID string) error {
+	defer db.cache.invalidate()
+
 	filter := bson.M{"worker_id": workerID}
 	result, err := db.collection.DeleteOne(ctx, filter)
 	if err != nil {
@@ -254,7 +387,28 @@ func (db *WorkerDB) GetWorker(ctx context.Context, workerID string) (*WorkerDocu
 }
 
 // GetAllWorkers retrieves all registered workers
+// Results are cached for a short TTL and invalidated by writes; heartbeat timestamps may lag by up to the TTL
 func (db *WorkerDB) GetAllWorkers(ctx context.Context) ([]WorkerDocument, error) {
+	workers, generation, ok := db.cache.get(time.Now())
+	if ok {
+		return workers, nil
+	}
+
+	workers, err := db.findAllWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db.cache.store(workers, generation, time.Now())
+	return workers, nil
+}
+
+// GetAllWorkersUncached retrieves all registered workers straight from the database, bypassing the cache
+func (db *WorkerDB) GetAllWorkersUncached(ctx context.Context) ([]WorkerDocument, error) {
+	return db.findAllWorkers(ctx)
+}
+
+func (db *WorkerDB) findAllWorkers(ctx context.Context) ([]WorkerDocument, error) {
 	cursor, err := db.collection.Find(ctx, bson.M{})
 	if err != nil {
 		return nil, fmt.Errorf("find workers: %w", err)
@@ -265,7 +419,6 @@ func (db *WorkerDB) GetAllWorkers(ctx context.Context) ([]WorkerDocument, error)
 	if err := cursor.All(ctx, &workers); err != nil {
 		return nil, fmt.Errorf("decode workers: %w", err)
 	}
-
 	return workers, nil
 }
 
@@ -281,6 +434,8 @@ func (db *WorkerDB) WorkerExists(ctx context.Context, workerID string) (bool, er
 
 // AllocateResources allocates resources to a worker when a task is assigned
 func (db *WorkerDB) AllocateResources(ctx context.Context, workerID string, cpu, memory, storage, gpu float64) error {
+	defer db.cache.invalidate()
+
 	filter := bson.M{"worker_id": workerID}
 	update := bson.M{
 		"$inc": bson.M{
@@ -312,6 +467,8 @@ func (db *WorkerDB) AllocateResources(ctx context.Context, workerID string, cpu,
 
 // ReleaseResources releases resources from a worker when a task completes
 func (db *WorkerDB) ReleaseResources(ctx context.Context, workerID string, cpu, memory, storage, gpu float64) error {
+	defer db.cache.invalidate()
+
 	filter := bson.M{"worker_id": workerID}
 	update := bson.M{
 		"$inc": bson.M{
@@ -345,6 +502,7 @@ func (db *WorkerDB) ReleaseResources(ctx context.Context, workerID string, cpu,
 func (db *WorkerDB) SetWorkerResources(ctx context.Context, workerID string,
 	allocatedCPU, allocatedMemory, allocatedStorage, allocatedGPU float64,
 	availableCPU, availableMemory, availableStorage, availableGPU float64) error {
+	defer db.cache.invalidate()
 
 	filter := bson.M{"worker_id": workerID}
 	update := bson.M{
@@ -375,6 +533,8 @@ func (db *WorkerDB) SetWorkerResources(ctx context.Context, workerID string,
 
 // RegisterWorkerWithSpecs registers a new worker with full resource specifications (for manual registration)
 func (db *WorkerDB) RegisterWorkerWithSpecs(ctx context.Context, worker *WorkerDocument) error {
+	defer db.cache.invalidate()
+
 	// Check if worker already exists
 	filter := bson.M{"worker_id": worker.WorkerID}
 	count, err := db.collection.CountDocuments(ctx, filter)
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// countFinds counts find commands sent to Mongo so far
+func countFinds(mt *mtest.T) int {
+	finds := 0
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName == "find" {
+			finds++
+		}
+	}
+	return finds
+}
+
+// TestGetAllWorkersServesFromCache tests that a second read within the TTL skips Mongo and a write invalidates it
+func TestGetAllWorkersServesFromCache(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("cached read", func(mt *mtest.T) {
+		workerDB := NewWorkerDBFromClient(mt.Client, "cloudai")
+		workerDoc := bson.D{{Key: "worker_id", Value: "worker-1"}, {Key: "total_cpu", Value: 8.0}}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.WORKER_REGISTRY", mtest.FirstBatch, workerDoc))
+		first, err := workerDB.GetAllWorkers(context.Background())
+		if err != nil {
+			t.Fatalf("First read failed: %v", err)
+		}
+
+		second, err := workerDB.GetAllWorkers(context.Background())
+		if err != nil {
+			t.Fatalf("Second read failed: %v", err)
+		}
+		if finds := countFinds(mt); finds != 1 {
+			t.Errorf("Expected 1 find within the TTL, got %d", finds)
+		}
+		if len(first) != 1 || len(second) != 1 || second[0].WorkerID != "worker-1" {
+			t.Fatalf("Expected cached read to return worker-1, got %+v", second)
+		}
+
+		// A write must drop the cache so the next read goes back to Mongo
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, "cloudai.WORKER_REGISTRY", mtest.FirstBatch, workerDoc),
+		)
+		if err := workerDB.AllocateResources(context.Background(), "worker-1", 1, 1, 0, 0); err != nil {
+			t.Fatalf("AllocateResources failed: %v", err)
+		}
+		if _, err := workerDB.GetAllWorkers(context.Background()); err != nil {
+			t.Fatalf("Read after write failed: %v", err)
+		}
+		if finds := countFinds(mt); finds != 2 {
+			t.Errorf("Expected a write to invalidate the cache (2 finds), got %d", finds)
+		}
+	})
+
+	mt.Run("disabled cache", func(mt *mtest.T) {
+		workerDB := NewWorkerDBFromClient(mt.Client, "cloudai")
+		workerDB.SetCacheTTL(0)
+
+		for i := 0; i < 2; i++ {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.WORKER_REGISTRY", mtest.FirstBatch))
+			if _, err := workerDB.GetAllWorkers(context.Background()); err != nil {
+				t.Fatalf("Read %d failed: %v", i, err)
+			}
+		}
+		if finds := countFinds(mt); finds != 2 {
+			t.Errorf("Expected every read to hit Mongo with caching disabled, got %d finds", finds)
+		}
+	})
+}
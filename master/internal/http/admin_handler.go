@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"master/internal/logging"
+	"master/internal/server"
+)
+
+// AdminAPIHandler handles HTTP REST API requests for cluster administration
+type AdminAPIHandler struct {
+	masterServer *server.MasterServer
+	quietMode    bool
+}
+
+// NewAdminAPIHandler creates a new admin API handler
+func NewAdminAPIHandler(ms *server.MasterServer) *AdminAPIHandler {
+	return &AdminAPIHandler{
+		masterServer: ms,
+		quietMode:    true,
+	}
+}
+
+// HandleReconcile handles POST /api/admin/reconcile
+// Runs resource reconciliation and reports which workers were corrected
+func (h *AdminAPIHandler) HandleReconcile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	summary, err := h.masterServer.ReconcileWorkerResourcesWithSummary(ctx)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to reconcile resources: %v", err))
+		return
+	}
+
+	if summary.Skipped {
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Database not available")
+		return
+	}
+
+	corrected := make([]map[string]interface{}, 0, len(summary.Changes))
+	for _, change := range summary.Changes {
+		corrected = append(corrected, map[string]interface{}{
+			"worker_id":    change.WorkerID,
+			"before":       convertAllocationToJSON(change.Before),
+			"after":        convertAllocationToJSON(change.After),
+			"tasks_before": change.TasksBefore,
+			"tasks_after":  change.TasksAfter,
+		})
+	}
+
+	if !h.quietMode {
+		logging.Infof("Reconciliation via API: %d/%d workers corrected", summary.WorkersFixed, summary.WorkersChecked)
+	}
+
+	response := map[string]interface{}{
+		"success":           true,
+		"workers_checked":   summary.WorkersChecked,
+		"workers_corrected": summary.WorkersFixed,
+		"corrected_workers": corrected,
+		"started_at":        summary.StartedAt.Unix(),
+		"completed_at":      summary.CompletedAt.Unix(),
+		"duration_ms":       summary.CompletedAt.Sub(summary.StartedAt).Milliseconds(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// convertAllocationToJSON converts a ResourceAllocation to a JSON-friendly map
+func convertAllocationToJSON(alloc server.ResourceAllocation) map[string]interface{} {
+	return map[string]interface{}{
+		"cpu":     alloc.CPU,
+		"memory":  alloc.Memory,
+		"storage": alloc.Storage,
+		"gpu":     alloc.GPU,
+	}
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"master/internal/db"
+	"master/internal/server"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestHandleReconcile tests that POST /api/admin/reconcile corrects drifted allocations
+func TestHandleReconcile(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("corrects drifted worker", func(mt *mtest.T) {
+		taskDB := db.NewTaskDBFromClient(mt.Client, "cloudai")
+		assignmentDB := db.NewAssignmentDBFromClient(mt.Client, "cloudai")
+		ms := server.NewMasterServer(nil, taskDB, assignmentDB, nil, nil, nil, nil)
+
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 8.0, 16.0, 100.0, 1.0)
+
+		// Corrupt the in-memory allocation: only one 1-core task is actually running
+		worker, _ := ms.GetWorkerStats("worker-1")
+		worker.AllocatedCPU = 5.0
+		worker.AllocatedMemory = 10.0
+
+		// One running task in TASKS, assigned to worker-1 in ASSIGNMENTS
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, bson.D{
+				{Key: "task_id", Value: "task-1"},
+				{Key: "status", Value: "running"},
+				{Key: "req_cpu", Value: 1.0},
+				{Key: "req_memory", Value: 2.0},
+				{Key: "req_storage", Value: 0.0},
+				{Key: "req_gpu", Value: 0.0},
+			}),
+			mtest.CreateCursorResponse(0, "cloudai.ASSIGNMENTS", mtest.FirstBatch, bson.D{
+				{Key: "ass_id", Value: "ass-task-1"},
+				{Key: "task_id", Value: "task-1"},
+				{Key: "worker_id", Value: "worker-1"},
+			}),
+		)
+
+		handler := NewAdminAPIHandler(ms)
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/reconcile", nil)
+		rec := httptest.NewRecorder()
+		handler.HandleReconcile(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp struct {
+			WorkersCorrected int `json:"workers_corrected"`
+			CorrectedWorkers []struct {
+				WorkerID string             `json:"worker_id"`
+				Before   map[string]float64 `json:"before"`
+				After    map[string]float64 `json:"after"`
+			} `json:"corrected_workers"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		if resp.WorkersCorrected != 1 {
+			t.Errorf("Expected 1 corrected worker, got %d", resp.WorkersCorrected)
+		}
+		if len(resp.CorrectedWorkers) != 1 || resp.CorrectedWorkers[0].WorkerID != "worker-1" {
+			t.Fatalf("Expected worker-1 to be reported as corrected, got %+v", resp.CorrectedWorkers)
+		}
+		change := resp.CorrectedWorkers[0]
+		if change.Before["cpu"] != 5.0 || change.After["cpu"] != 1.0 {
+			t.Errorf("Expected CPU 5.0→1.0, got %.1f→%.1f", change.Before["cpu"], change.After["cpu"])
+		}
+		if change.After["memory"] != 2.0 {
+			t.Errorf("Expected memory to be corrected to 2.0, got %.1f", change.After["memory"])
+		}
+
+		if worker.AvailableCPU != 7.0 {
+			t.Errorf("Expected available CPU 7.0 after reconciliation, got %.1f", worker.AvailableCPU)
+		}
+	})
+
+	mt.Run("rejects non-POST", func(mt *mtest.T) {
+		handler := NewAdminAPIHandler(server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil))
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/reconcile", nil)
+		rec := httptest.NewRecorder()
+		handler.HandleReconcile(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405, got %d", rec.Code)
+		}
+	})
+}
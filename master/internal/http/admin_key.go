@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminKeyHeader is the request header carrying the admin API key
+const AdminKeyHeader = "X-Admin-Key"
+
+// adminRoute reports whether a request is a privileged operation: cluster administration, registering,
+// relabelling or expiring workers, changing their maintenance windows, cancelling tasks in bulk, and
+// creating or releasing capacity reservations
+func adminRoute(r *http.Request) bool {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/api/admin/"):
+		return true
+	case path == "/api/workers":
+		return r.Method == http.MethodPost
+	case strings.HasPrefix(path, "/api/workers/"):
+		return strings.HasSuffix(path, "/expire") || strings.HasSuffix(path, "/maintenance") || r.Method == http.MethodPatch
+	case path == "/api/tasks/cancel":
+		return true
+	case path == "/api/reservations", strings.HasPrefix(path, "/api/reservations/"):
+		return r.Method != http.MethodGet
+	default:
+		return false
+	}
+}
+
+// requireAdminKey rejects privileged requests whose X-Admin-Key header does not match key with 403
+// Other requests pass through unchecked
+func requireAdminKey(key string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !adminRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		given := r.Header.Get(AdminKeyHeader)
+		if given == "" {
+			writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, "Admin key required: set the "+AdminKeyHeader+" header")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(key)) != 1 {
+			writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, "Invalid admin key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminContextKey marks requests that presented the configured admin key
+type adminContextKey struct{}
+
+// isAdminRequest reports whether the request presented the configured admin key (never true when no key is configured)
+func isAdminRequest(r *http.Request) bool {
+	admin, _ := r.Context().Value(adminContextKey{}).(bool)
+	return admin
+}
+
+// SetAdminKey requires privileged endpoints to be called with this key in the X-Admin-Key header; "" leaves them open
+func (ts *TelemetryServer) SetAdminKey(key string) {
+	ts.adminKey = key
+}
+
+// adminKeyMiddleware applies the configured admin key, if any, and marks requests that present it
+func (ts *TelemetryServer) adminKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ts.adminKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminKeyHeader)), []byte(ts.adminKey)) == 1 {
+			r = r.WithContext(context.WithValue(r.Context(), adminContextKey{}, true))
+		}
+		requireAdminKey(ts.adminKey, next).ServeHTTP(w, r)
+	})
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"time"
@@ -68,53 +69,42 @@ type UserInfo struct {
 // HandleRegister handles user registration
 func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body")
 		return
 	}
 
 	// Validate input
 	if req.Name == "" || req.Email == "" || req.Password == "" {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "Name, email, and password are required",
-		})
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Name, email, and password are required")
 		return
 	}
 
 	if len(req.Password) < 6 {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "Password must be at least 6 characters",
-		})
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Password must be at least 6 characters")
 		return
 	}
 
 	// Create user
 	err := h.userDB.CreateUser(req.Name, req.Email, req.Password)
+	if errors.Is(err, db.ErrUserExists) {
+		writeJSONError(w, http.StatusConflict, ErrCodeConflict, err.Error())
+		return
+	}
 	if err != nil {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: err.Error(),
-		})
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to create user: "+err.Error())
 		return
 	}
 
 	// Get created user
 	user, err := h.userDB.GetUserByEmail(req.Email)
 	if err != nil {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "User created but failed to retrieve",
-		})
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, "User created but failed to retrieve")
 		return
 	}
 
@@ -134,26 +124,20 @@ func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 // HandleLogin handles user login
 func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body")
 		return
 	}
 
 	// Validate credentials
 	user, err := h.userDB.ValidateCredentials(req.Email, req.Password)
 	if err != nil {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "Invalid email or password",
-		})
+		writeJSONError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Invalid email or password")
 		return
 	}
 
@@ -180,10 +164,7 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(h.jwtSecret)
 	if err != nil {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "Failed to generate token",
-		})
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate token")
 		return
 	}
 
@@ -214,7 +195,7 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 // HandleLogout handles user logout
 func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -238,27 +219,21 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 // HandleMe returns current user information
 func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Get email from context (set by middleware)
 	email, ok := r.Context().Value("user_email").(string)
 	if !ok {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "Unauthorized",
-		})
+		writeJSONError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Get user from database
 	user, err := h.userDB.GetUserByEmail(email)
 	if err != nil {
-		json.NewEncoder(w).Encode(AuthResponse{
-			Success: false,
-			Message: "User not found",
-		})
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, "User not found")
 		return
 	}
 
@@ -275,6 +250,19 @@ func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// RequestUser returns the email of the user authenticated by the request's auth cookie, or "" if there is none
+func (h *AuthHandler) RequestUser(r *http.Request) string {
+	cookie, err := r.Cookie("auth_token")
+	if err != nil {
+		return ""
+	}
+	claims, err := h.VerifyToken(cookie.Value)
+	if err != nil {
+		return ""
+	}
+	return claims.Email
+}
+
 // VerifyToken verifies a JWT token and returns the claims
 func (h *AuthHandler) VerifyToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
@@ -0,0 +1,221 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"master/internal/server"
+)
+
+// CapacityAPIHandler handles HTTP REST API requests for cluster capacity planning
+type CapacityAPIHandler struct {
+	masterServer *server.MasterServer
+}
+
+// NewCapacityAPIHandler creates a new capacity API handler
+func NewCapacityAPIHandler(ms *server.MasterServer) *CapacityAPIHandler {
+	return &CapacityAPIHandler{masterServer: ms}
+}
+
+// sampleTask is the task size checked by HandleCapacity (defaults match the CLI task defaults)
+type sampleTask struct {
+	CPU     float64
+	Memory  float64
+	Storage float64
+	GPU     float64
+}
+
+// HandleCapacity handles GET /api/capacity?cpu=&memory=&storage=&gpu=
+// Reports total vs available resources, the queue backlog, and whether a task
+// of the requested size fits on any active worker right now
+func (h *CapacityAPIHandler) HandleCapacity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	sample := sampleTask{CPU: 1.0, Memory: 0.5, Storage: 1.0, GPU: 0.0}
+	for param, target := range map[string]*float64{
+		"cpu":     &sample.CPU,
+		"memory":  &sample.Memory,
+		"storage": &sample.Storage,
+		"gpu":     &sample.GPU,
+	} {
+		raw := r.URL.Query().Get(param)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("%s must be a non-negative number", param))
+			return
+		}
+		*target = value
+	}
+
+	snapshot := h.masterServer.GetClusterSnapshot()
+
+	fittingWorkers := 0
+	for _, worker := range snapshot.Workers {
+		if worker.Status == "active" &&
+			worker.AvailableCPU >= sample.CPU &&
+			worker.AvailableMemory >= sample.Memory &&
+			worker.AvailableStorage >= sample.Storage &&
+			worker.AvailableGPU >= sample.GPU {
+			fittingWorkers++
+		}
+	}
+
+	queued := h.masterServer.GetQueuedTasks()
+	var totalWait time.Duration
+	now := time.Now()
+	for _, qt := range queued {
+		totalWait += now.Sub(qt.QueuedAt)
+	}
+	avgWait := 0.0
+	if len(queued) > 0 {
+		avgWait = totalWait.Seconds() / float64(len(queued))
+	}
+
+	response := map[string]interface{}{
+		"success":        true,
+		"timestamp":      snapshot.Timestamp.Unix(),
+		"active_workers": snapshot.ActiveWorkers,
+		"total": map[string]interface{}{
+			"cpu":    snapshot.TotalCPU,
+			"memory": snapshot.TotalMemory,
+			"gpu":    snapshot.TotalGPU,
+		},
+		"available": map[string]interface{}{
+			"cpu":    snapshot.AvailableCPU,
+			"memory": snapshot.AvailableMemory,
+			"gpu":    snapshot.AvailableGPU,
+		},
+		"queue_length":           len(queued),
+		"avg_queue_wait_seconds": avgWait,
+		"sample_task": map[string]interface{}{
+			"cpu":     sample.CPU,
+			"memory":  sample.Memory,
+			"storage": sample.Storage,
+			"gpu":     sample.GPU,
+		},
+		"can_accept":      fittingWorkers > 0,
+		"fitting_workers": fittingWorkers,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleAutoscale handles GET /api/autoscale
+// Returns a scale_up/hold/scale_down hint for an external autoscaler, based on
+// queue length and utilization sustained over the autoscale window
+func (h *CapacityAPIHandler) HandleAutoscale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	now := time.Now()
+	rec := h.masterServer.GetAutoscaleRecommendation(now)
+
+	response := map[string]interface{}{
+		"success":          true,
+		"timestamp":        now.Unix(),
+		"recommendation":   rec.Action,
+		"reason":           rec.Reason,
+		"window_seconds":   rec.Window.Seconds(),
+		"samples":          rec.Samples,
+		"active_workers":   rec.ActiveWorkers,
+		"avg_queue_length": rec.AvgQueueLength,
+		"avg_utilization": map[string]interface{}{
+			"cpu":    rec.AvgCPUUtilization,
+			"memory": rec.AvgMemoryUtilization,
+			"gpu":    rec.AvgGPUUtilization,
+		},
+		"shortfall": map[string]interface{}{
+			"cpu":    rec.ShortfallCPU,
+			"memory": rec.ShortfallMemory,
+			"gpu":    rec.ShortfallGPU,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReservationRequest is the body of POST /api/reservations
+type ReservationRequest struct {
+	CPU        float64 `json:"cpu"`
+	Memory     float64 `json:"memory"`
+	GPU        float64 `json:"gpu"`
+	TTLSeconds int     `json:"ttl_seconds,omitempty"` // Default: 10 minutes
+}
+
+// HandleCreateReservation handles POST /api/reservations
+// Holds a block of CPU, memory and GPU across the cluster for a TTL; tasks submitted with the
+// returned reservation_id draw their resources from it
+func (h *CapacityAPIHandler) HandleCreateReservation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if req.CPU < 0 || req.Memory < 0 || req.GPU < 0 || req.TTLSeconds < 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "cpu, memory, gpu and ttl_seconds must not be negative")
+		return
+	}
+	if req.CPU == 0 && req.Memory == 0 && req.GPU == 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "At least one of cpu, memory or gpu must be greater than 0")
+		return
+	}
+
+	want := server.Capacity{CPU: req.CPU, Memory: req.Memory, GPU: req.GPU}
+	reservation, err := h.masterServer.ReserveCapacity(want, time.Duration(req.TTLSeconds)*time.Second, time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("Failed to reserve capacity: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// HandleReservation handles GET and DELETE /api/reservations/{id}
+// GET reports what the reservation has left; DELETE releases what it still holds
+func (h *CapacityAPIHandler) HandleReservation(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/reservations/")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing reservation ID")
+		return
+	}
+
+	var reservation *server.CapacityReservation
+	var exists bool
+	switch r.Method {
+	case http.MethodGet:
+		reservation, exists = h.masterServer.GetCapacityReservation(id)
+	case http.MethodDelete:
+		reservation, exists = h.masterServer.ReleaseCapacityReservation(id)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Capacity reservation %s not found", id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservation)
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"master/internal/server"
+	pb "master/proto"
+)
+
+// TestHandleCapacityCanAcceptFollowsAvailableResources tests that can_accept flips with the requested size
+func TestHandleCapacityCanAcceptFollowsAvailableResources(t *testing.T) {
+	ms := server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+	worker, _ := ms.GetWorkerStats("worker-1")
+	worker.IsActive = true
+	ms.EnqueueTask(&pb.Task{TaskId: "task-waiting", ReqCpu: 16.0}, "test")
+
+	handler := NewCapacityAPIHandler(ms)
+	query := func(params string) map[string]interface{} {
+		req := httptest.NewRequest(http.MethodGet, "/api/capacity"+params, nil)
+		rec := httptest.NewRecorder()
+		handler.HandleCapacity(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %q, got %d: %s", params, rec.Code, rec.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	small := query("?cpu=2&memory=4")
+	if small["can_accept"] != true {
+		t.Errorf("Expected a 2-core task to fit on a 4-core worker, got %v", small["can_accept"])
+	}
+	if small["queue_length"] != 1.0 {
+		t.Errorf("Expected queue length 1, got %v", small["queue_length"])
+	}
+	if total := small["total"].(map[string]interface{}); total["cpu"] != 4.0 {
+		t.Errorf("Expected total CPU 4, got %v", total["cpu"])
+	}
+
+	large := query("?cpu=8&memory=4")
+	if large["can_accept"] != false {
+		t.Errorf("Expected an 8-core task not to fit on a 4-core worker, got %v", large["can_accept"])
+	}
+
+	gpu := query("?cpu=1&gpu=1")
+	if gpu["can_accept"] != false {
+		t.Errorf("Expected a GPU task not to fit on a worker without GPUs, got %v", gpu["can_accept"])
+	}
+}
+
+// TestHandleCapacityRejectsInvalidSize tests that malformed sample sizes are rejected
+func TestHandleCapacityRejectsInvalidSize(t *testing.T) {
+	handler := NewCapacityAPIHandler(server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil))
+	for _, params := range []string{"?cpu=lots", "?memory=-1"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/capacity"+params, nil)
+		rec := httptest.NewRecorder()
+		handler.HandleCapacity(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", params, rec.Code)
+		}
+	}
+}
+
+// TestCapacityReservationDrawDownAndRelease tests reserving capacity, submitting a task against it and releasing the remainder
+func TestCapacityReservationDrawDownAndRelease(t *testing.T) {
+	ms := server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 8.0, 16.0, 100.0, 0.0)
+	worker, _ := ms.GetWorkerStats("worker-1")
+
+	handler := NewCapacityAPIHandler(ms)
+	taskHandler := NewTaskAPIHandler(ms, nil, nil, nil)
+	do := func(h http.HandlerFunc, method, url, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+		var resp map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec, resp
+	}
+
+	// More than the cluster has free cannot be reserved
+	if rec, _ := do(handler.HandleCreateReservation, http.MethodPost, "/api/reservations", `{"cpu": 16, "memory": 4}`); rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for an oversized reservation, got %d", rec.Code)
+	}
+
+	rec, resp := do(handler.HandleCreateReservation, http.MethodPost, "/api/reservations", `{"cpu": 4, "memory": 8, "ttl_seconds": 60}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %v", rec.Code, resp)
+	}
+	id, _ := resp["reservation_id"].(string)
+	if id == "" {
+		t.Fatalf("Expected a reservation ID, got %v", resp)
+	}
+	if worker.AvailableCPU != 4.0 || worker.AvailableMemory != 8.0 {
+		t.Fatalf("Expected the reservation to hold 4 CPU and 8 GB, got %.1f CPU and %.1f GB available", worker.AvailableCPU, worker.AvailableMemory)
+	}
+
+	// A task drawing on the reservation is accepted; it is charged only once placed
+	task := fmt.Sprintf(`{"docker_image": "busybox", "cpu_required": 1, "memory_required": 2, "reservation_id": %q}`, id)
+	if rec, resp := do(taskHandler.HandleCreateTask, http.MethodPost, "/api/tasks", task); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the task to be accepted, got %d: %v", rec.Code, resp)
+	}
+	_, resp = do(handler.HandleReservation, http.MethodGet, "/api/reservations/"+id, "")
+	if remaining := resp["remaining"].(map[string]interface{}); remaining["cpu"] != 4.0 || remaining["memory"] != 8.0 {
+		t.Errorf("Expected the queued task to leave 4 CPU and 8 GB, got %v", remaining)
+	}
+
+	// A task larger than what is left is rejected rather than queued
+	tooBig := fmt.Sprintf(`{"docker_image": "busybox", "cpu_required": 5, "memory_required": 1, "reservation_id": %q}`, id)
+	if rec, _ := do(taskHandler.HandleCreateTask, http.MethodPost, "/api/tasks", tooBig); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a task exceeding the reservation, got %d", rec.Code)
+	}
+	unknown := `{"docker_image": "busybox", "cpu_required": 1, "memory_required": 1, "reservation_id": "res-unknown"}`
+	if rec, _ := do(taskHandler.HandleCreateTask, http.MethodPost, "/api/tasks", unknown); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown reservation, got %d", rec.Code)
+	}
+
+	// Releasing returns what is still held to the worker
+	if rec, _ := do(handler.HandleReservation, http.MethodDelete, "/api/reservations/"+id, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on release, got %d", rec.Code)
+	}
+	if worker.AvailableCPU != 8.0 || worker.AvailableMemory != 16.0 {
+		t.Errorf("Expected the held capacity back on the worker, got %.1f CPU and %.1f GB available", worker.AvailableCPU, worker.AvailableMemory)
+	}
+	if rec, _ := do(handler.HandleReservation, http.MethodGet, "/api/reservations/"+id, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after release, got %d", rec.Code)
+	}
+}
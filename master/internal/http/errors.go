@@ -0,0 +1,73 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pb "master/proto"
+)
+
+// Error codes returned in the "code" field of JSON error responses
+// Failures reported by the master through a TaskAck use the ack's ErrorCode name instead (e.g. TASK_NOT_FOUND)
+const (
+	ErrCodeBadRequest       = "BAD_REQUEST"
+	ErrCodeUnauthorized     = "UNAUTHORIZED"
+	ErrCodeForbidden        = "FORBIDDEN"
+	ErrCodeNotFound         = "NOT_FOUND"
+	ErrCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	ErrCodeConflict         = "CONFLICT"
+	ErrCodeRateLimited      = "RATE_LIMITED"
+	ErrCodeInternal         = "INTERNAL_ERROR"
+	ErrCodeUnavailable      = "SERVICE_UNAVAILABLE"
+)
+
+// ErrorResponse is the body of every API error: {"error":{"code":...,"message":...}}
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail is a machine-readable error code with a human-readable message
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeJSONError writes a standard JSON error response with the given HTTP status
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorDetail{Code: code, Message: message}})
+}
+
+// ackErrorCode returns the error code for a failed ack: its ErrorCode name, or the code for status if it has none
+func ackErrorCode(ack *pb.TaskAck, status int) string {
+	if ack.ErrorCode != pb.ErrorCode_ERROR_CODE_NONE {
+		return ack.ErrorCode.String()
+	}
+	return errorCodeForStatus(status)
+}
+
+// errorCodeForStatus maps an HTTP status to its generic error code
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	default:
+		return ErrCodeInternal
+	}
+}
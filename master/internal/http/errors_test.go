@@ -0,0 +1,122 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"master/internal/server"
+)
+
+// TestErrorResponsesHaveStandardShape tests that handler errors use the {"error":{"code","message"}} body with the right status
+func TestErrorResponsesHaveStandardShape(t *testing.T) {
+	ms := server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	taskHandler := NewTaskAPIHandler(ms, nil, nil, nil)
+	workerHandler := NewWorkerAPIHandler(ms, nil, nil, nil)
+	authHandler := NewAuthHandler(nil)
+
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		method       string
+		path         string
+		body         string
+		expectedCode int
+		expectedErr  string
+	}{
+		{
+			name:         "create task with wrong method",
+			handler:      taskHandler.HandleCreateTask,
+			method:       http.MethodGet,
+			path:         "/api/tasks",
+			expectedCode: http.StatusMethodNotAllowed,
+			expectedErr:  ErrCodeMethodNotAllowed,
+		},
+		{
+			name:         "create task without image",
+			handler:      taskHandler.HandleCreateTask,
+			method:       http.MethodPost,
+			path:         "/api/tasks",
+			body:         `{"cpu_required": 1, "memory_required": 1}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  ErrCodeBadRequest,
+		},
+		{
+			name:         "create task with too long a stop grace",
+			handler:      taskHandler.HandleCreateTask,
+			method:       http.MethodPost,
+			path:         "/api/tasks",
+			body:         `{"docker_image": "busybox", "cpu_required": 1, "memory_required": 1, "stop_grace_period_sec": 3600}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  ErrCodeBadRequest,
+		},
+		{
+			name:         "cancel by ref without database",
+			handler:      taskHandler.HandleCancelByExternalRef,
+			method:       http.MethodDelete,
+			path:         "/api/tasks/by-ref/order-42",
+			expectedCode: http.StatusServiceUnavailable,
+			expectedErr:  "DATABASE_ERROR",
+		},
+		{
+			name:         "register with a short password",
+			handler:      authHandler.HandleRegister,
+			method:       http.MethodPost,
+			path:         "/api/auth/register",
+			body:         `{"name": "Alice", "email": "alice@example.com", "password": "abc"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  ErrCodeBadRequest,
+		},
+		{
+			name:         "login with a malformed body",
+			handler:      authHandler.HandleLogin,
+			method:       http.MethodPost,
+			path:         "/api/auth/login",
+			body:         `{"email":`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  ErrCodeBadRequest,
+		},
+		{
+			name:         "me without a session",
+			handler:      authHandler.HandleMe,
+			method:       http.MethodGet,
+			path:         "/api/auth/me",
+			expectedCode: http.StatusUnauthorized,
+			expectedErr:  ErrCodeUnauthorized,
+		},
+		{
+			name:         "expire unknown worker",
+			handler:      workerHandler.HandleExpireWorker,
+			method:       http.MethodPost,
+			path:         "/api/workers/missing/expire",
+			expectedCode: http.StatusNotFound,
+			expectedErr:  ErrCodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		tt.handler(rec, req)
+
+		if rec.Code != tt.expectedCode {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expectedCode, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: expected Content-Type application/json, got %q", tt.name, ct)
+		}
+
+		var resp ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: expected a JSON error body, got %q: %v", tt.name, rec.Body.String(), err)
+		}
+		if resp.Error.Code != tt.expectedErr {
+			t.Errorf("%s: expected error code %s, got %q", tt.name, tt.expectedErr, resp.Error.Code)
+		}
+		if resp.Error.Message == "" {
+			t.Errorf("%s: expected a non-empty error message", tt.name)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"master/internal/logging"
+	"master/internal/server"
+)
+
+// eventKeepaliveInterval is how often an idle event stream sends a comment line so proxies keep it open
+const eventKeepaliveInterval = 15 * time.Second
+
+// EventsAPIHandler streams cluster events to dashboards over Server-Sent Events
+type EventsAPIHandler struct {
+	masterServer *server.MasterServer
+}
+
+// NewEventsAPIHandler creates a new events API handler
+func NewEventsAPIHandler(ms *server.MasterServer) *EventsAPIHandler {
+	return &EventsAPIHandler{masterServer: ms}
+}
+
+// HandleEventStream handles GET /api/events/stream
+// Each cluster event is sent as "event: <type>" with the JSON-encoded event as data, until the client disconnects
+func (h *EventsAPIHandler) HandleEventStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, "Streaming not supported")
+		return
+	}
+
+	events, unsubscribe := h.masterServer.SubscribeEvents()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				logging.Warnf("⚠ Failed to encode %s event: %v", event.Type, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
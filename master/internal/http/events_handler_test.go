@@ -0,0 +1,71 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"master/internal/server"
+	pb "master/proto"
+)
+
+// TestEventStreamDeliversEventsInOrder tests that a stream subscriber sees a worker joining and then a task being queued
+func TestEventStreamDeliversEventsInOrder(t *testing.T) {
+	ms := server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	srv := httptest.NewServer(http.HandlerFunc(NewEventsAPIHandler(ms).HandleEventStream))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/events/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected Content-Type text/event-stream, got %q", ct)
+	}
+
+	// The handler subscribes before sending headers, so everything from here on is streamed
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+	ms.EnqueueTask(&pb.Task{TaskId: "task-1", ReqCpu: 1.0}, "test")
+
+	var received []server.ClusterEvent
+	scanner := bufio.NewScanner(resp.Body)
+	var eventType string
+	for len(received) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var event server.ClusterEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatalf("Failed to decode event data %q: %v", line, err)
+			}
+			if event.Type != eventType {
+				t.Errorf("Expected data type %s to match event line %s", event.Type, eventType)
+			}
+			received = append(received, event)
+		}
+	}
+	if len(received) < 2 {
+		t.Fatalf("Expected 2 events, got %d (%v)", len(received), scanner.Err())
+	}
+
+	if received[0].Type != server.EventWorkerJoined || received[0].WorkerID != "worker-1" {
+		t.Errorf("Expected worker_joined for worker-1 first, got %+v", received[0])
+	}
+	if received[1].Type != server.EventTaskQueued || received[1].TaskID != "task-1" {
+		t.Errorf("Expected task_queued for task-1 second, got %+v", received[1])
+	}
+}
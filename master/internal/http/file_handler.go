@@ -3,18 +3,20 @@ package http
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
 
+	"master/internal/db"
+	"master/internal/logging"
 	"master/internal/storage"
 )
 
 // FileAPIHandler handles HTTP REST API requests for file management
 type FileAPIHandler struct {
-	fileStorage *storage.FileStorageService
-	quietMode   bool
+	fileStorage    *storage.FileStorageService
+	fileMetadataDB *db.FileMetadataDB // Serves file manifests; nil disables them
+	quietMode      bool
 }
 
 // NewFileAPIHandler creates a new file API handler
@@ -25,6 +27,11 @@ func NewFileAPIHandler(fileStorage *storage.FileStorageService) *FileAPIHandler
 	}
 }
 
+// SetFileMetadataDB sets where file manifests (sizes and checksums) are read from
+func (h *FileAPIHandler) SetFileMetadataDB(fileMetadataDB *db.FileMetadataDB) {
+	h.fileMetadataDB = fileMetadataDB
+}
+
 // FileListResponse represents the JSON response for file listing
 type FileListResponse struct {
 	UserID string         `json:"user_id"`
@@ -34,8 +41,9 @@ type FileListResponse struct {
 
 // FileInfo represents individual file information
 type FileInfoJSON struct {
-	Path string `json:"path"`
-	Size int64  `json:"size"`
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	DownloadURL string `json:"download_url,omitempty"` // Presigned URL when files live in object storage
 }
 
 // TaskFileInfo represents file information for a single task
@@ -60,7 +68,7 @@ type FileDetailResponse struct {
 // Lists all files for a user with access control
 func (h *FileAPIHandler) HandleListFiles(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -70,17 +78,17 @@ func (h *FileAPIHandler) HandleListFiles(w http.ResponseWriter, r *http.Request)
 	targetUserID := r.URL.Query().Get("user_id")
 
 	if requestingUserID == "" {
-		http.Error(w, "Missing requesting_user parameter", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing requesting_user parameter")
 		return
 	}
 
 	if targetUserID == "" {
-		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing user_id parameter")
 		return
 	}
 
 	if h.fileStorage == nil {
-		http.Error(w, "File storage not available", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "File storage not available")
 		return
 	}
 
@@ -88,11 +96,11 @@ func (h *FileAPIHandler) HandleListFiles(w http.ResponseWriter, r *http.Request)
 	fileMetadataList, err := h.fileStorage.ListUserFilesWithAccess(requestingUserID, targetUserID)
 	if err != nil {
 		if strings.Contains(err.Error(), "access denied") {
-			http.Error(w, err.Error(), http.StatusForbidden)
+			writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, err.Error())
 			return
 		}
-		log.Printf("Error listing files for user %s (requested by %s): %v", targetUserID, requestingUserID, err)
-		http.Error(w, fmt.Sprintf("Failed to list files: %v", err), http.StatusInternalServerError)
+		logging.Errorf("Error listing files for user %s (requested by %s): %v", targetUserID, requestingUserID, err)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to list files: %v", err))
 		return
 	}
 
@@ -103,8 +111,9 @@ func (h *FileAPIHandler) HandleListFiles(w http.ResponseWriter, r *http.Request)
 		files := make([]FileInfoJSON, 0, len(metadata.Files))
 		for _, f := range metadata.Files {
 			files = append(files, FileInfoJSON{
-				Path: f.Path,
-				Size: f.Size,
+				Path:        f.Path,
+				Size:        f.Size,
+				DownloadURL: h.fileStorage.DownloadURL(metadata, f.Path),
 			})
 		}
 
@@ -127,7 +136,7 @@ func (h *FileAPIHandler) HandleListFiles(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 
 	if !h.quietMode {
-		log.Printf("✓ Listed %d task(s) for user %s (requested by %s)", len(tasks), targetUserID, requestingUserID)
+		logging.Infof("✓ Listed %d task(s) for user %s (requested by %s)", len(tasks), targetUserID, requestingUserID)
 	}
 }
 
@@ -135,7 +144,7 @@ func (h *FileAPIHandler) HandleListFiles(w http.ResponseWriter, r *http.Request)
 // Gets file details for a specific task with access control
 func (h *FileAPIHandler) HandleGetTaskFiles(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -143,7 +152,7 @@ func (h *FileAPIHandler) HandleGetTaskFiles(w http.ResponseWriter, r *http.Reque
 	// Expected format: /api/files/{task_id}
 	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(parts) < 3 {
-		http.Error(w, "Invalid URL format. Expected: /api/files/{task_id}", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid URL format. Expected: /api/files/{task_id}")
 		return
 	}
 	taskID := parts[2]
@@ -152,17 +161,17 @@ func (h *FileAPIHandler) HandleGetTaskFiles(w http.ResponseWriter, r *http.Reque
 	targetUserID := r.URL.Query().Get("user_id")
 
 	if requestingUserID == "" {
-		http.Error(w, "Missing requesting_user parameter", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing requesting_user parameter")
 		return
 	}
 
 	if targetUserID == "" {
-		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing user_id parameter")
 		return
 	}
 
 	if h.fileStorage == nil {
-		http.Error(w, "File storage not available", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "File storage not available")
 		return
 	}
 
@@ -170,15 +179,15 @@ func (h *FileAPIHandler) HandleGetTaskFiles(w http.ResponseWriter, r *http.Reque
 	metadata, err := h.fileStorage.GetTaskFilesWithAccess(requestingUserID, targetUserID, taskID)
 	if err != nil {
 		if strings.Contains(err.Error(), "access denied") {
-			http.Error(w, err.Error(), http.StatusForbidden)
+			writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, err.Error())
 			return
 		}
 		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
 			return
 		}
-		log.Printf("Error getting task files %s for user %s: %v", taskID, targetUserID, err)
-		http.Error(w, fmt.Sprintf("Failed to get task files: %v", err), http.StatusInternalServerError)
+		logging.Errorf("Error getting task files %s for user %s: %v", taskID, targetUserID, err)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to get task files: %v", err))
 		return
 	}
 
@@ -186,8 +195,9 @@ func (h *FileAPIHandler) HandleGetTaskFiles(w http.ResponseWriter, r *http.Reque
 	files := make([]FileInfoJSON, 0, len(metadata.Files))
 	for _, f := range metadata.Files {
 		files = append(files, FileInfoJSON{
-			Path: f.Path,
-			Size: f.Size,
+			Path:        f.Path,
+			Size:        f.Size,
+			DownloadURL: h.fileStorage.DownloadURL(metadata, f.Path),
 		})
 	}
 
@@ -203,15 +213,96 @@ func (h *FileAPIHandler) HandleGetTaskFiles(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(response)
 
 	if !h.quietMode {
-		log.Printf("✓ Retrieved task %s files for user %s (requested by %s)", taskID, targetUserID, requestingUserID)
+		logging.Infof("✓ Retrieved task %s files for user %s (requested by %s)", taskID, targetUserID, requestingUserID)
+	}
+}
+
+// FileManifestResponse represents the JSON response for a task's file manifest
+type FileManifestResponse struct {
+	TaskID    string         `json:"task_id"`
+	TaskName  string         `json:"task_name"`
+	UserID    string         `json:"user_id"`
+	Files     []db.FileEntry `json:"files"`
+	Count     int            `json:"count"`
+	TotalSize int64          `json:"total_size"`
+}
+
+// HandleGetTaskManifest handles GET /api/files/{task_id}/manifest?user_id=<user>&requesting_user=<user>
+// Lists a task's files with sizes and checksums from the file metadata, without downloading them
+func (h *FileAPIHandler) HandleGetTaskManifest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Expected format: /api/files/{task_id}/manifest
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[3] != "manifest" {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid URL format. Expected: /api/files/{task_id}/manifest")
+		return
+	}
+	taskID := parts[2]
+
+	requestingUserID := r.URL.Query().Get("requesting_user")
+	targetUserID := r.URL.Query().Get("user_id")
+
+	if requestingUserID == "" {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing requesting_user parameter")
+		return
+	}
+
+	if targetUserID == "" {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing user_id parameter")
+		return
+	}
+
+	if h.fileStorage == nil || h.fileMetadataDB == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "File metadata not available")
+		return
+	}
+
+	if err := h.fileStorage.GetAccessControl().CanAccessFiles(requestingUserID, targetUserID); err != nil {
+		writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, err.Error())
+		return
+	}
+
+	metadata, err := h.fileMetadataDB.GetFileMetadataByTask(r.Context(), taskID)
+	if err != nil {
+		logging.Errorf("Error getting file manifest for task %s: %v", taskID, err)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to get file manifest: %v", err))
+		return
+	}
+	// Files of another user's task are reported as missing rather than revealing they exist
+	if metadata == nil || metadata.UserID != targetUserID {
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("No files found for task %s", taskID))
+		return
+	}
+
+	files := metadata.Manifest()
+	response := FileManifestResponse{
+		TaskID:   metadata.TaskID,
+		TaskName: metadata.TaskName,
+		UserID:   metadata.UserID,
+		Files:    files,
+		Count:    len(files),
+	}
+	for _, f := range files {
+		response.TotalSize += f.Size
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	if !h.quietMode {
+		logging.Infof("✓ Listed manifest of task %s for user %s (requested by %s)", taskID, targetUserID, requestingUserID)
 	}
 }
 
 // HandleDownloadFile handles GET /api/files/{task_id}/download/{file_path}?user_id=<user>&requesting_user=<user>
-// Downloads a specific file with access control
+// Downloads a specific file with access control; Range requests are supported so interrupted downloads can resume
 func (h *FileAPIHandler) HandleDownloadFile(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -219,7 +310,7 @@ func (h *FileAPIHandler) HandleDownloadFile(w http.ResponseWriter, r *http.Reque
 	// Expected format: /api/files/{task_id}/download/{file_path}
 	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(parts) < 5 || parts[3] != "download" {
-		http.Error(w, "Invalid URL format. Expected: /api/files/{task_id}/download/{file_path}", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid URL format. Expected: /api/files/{task_id}/download/{file_path}")
 		return
 	}
 
@@ -231,51 +322,49 @@ func (h *FileAPIHandler) HandleDownloadFile(w http.ResponseWriter, r *http.Reque
 	targetUserID := r.URL.Query().Get("user_id")
 
 	if requestingUserID == "" {
-		http.Error(w, "Missing requesting_user parameter", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing requesting_user parameter")
 		return
 	}
 
 	if targetUserID == "" {
-		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing user_id parameter")
 		return
 	}
 
 	if h.fileStorage == nil {
-		http.Error(w, "File storage not available", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "File storage not available")
 		return
 	}
 
-	// Use access-controlled method to read file
-	fileData, err := h.fileStorage.ReadFileWithAccess(requestingUserID, targetUserID, taskID, filePath)
+	// Use access-controlled method to open the file; it is streamed rather than read into memory
+	file, modTime, err := h.fileStorage.OpenFileWithAccess(requestingUserID, targetUserID, taskID, filePath)
 	if err != nil {
 		if strings.Contains(err.Error(), "access denied") {
-			http.Error(w, err.Error(), http.StatusForbidden)
+			writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, err.Error())
 			return
 		}
 		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
 			return
 		}
-		log.Printf("Error reading file %s for task %s: %v", filePath, taskID, err)
-		http.Error(w, fmt.Sprintf("Failed to read file: %v", err), http.StatusInternalServerError)
+		logging.Errorf("Error reading file %s for task %s: %v", filePath, taskID, err)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to read file: %v", err))
 		return
 	}
+	defer file.Close()
 
 	// Set headers for file download
 	filename := filepath.Base(filePath)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
 	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(fileData)))
 
-	// Write file data
-	_, err = w.Write(fileData)
-	if err != nil {
-		log.Printf("Error writing file data: %v", err)
-		return
-	}
+	// ServeContent answers Range requests with 206 and Content-Range (and advertises Accept-Ranges),
+	// so an interrupted download can resume where it stopped; the modification time lets If-Range and
+	// If-Modified-Since validate a resumed download against the stored file
+	http.ServeContent(w, r, filename, modTime, file)
 
 	if !h.quietMode {
-		log.Printf("✓ Downloaded file %s for task %s (user: %s, requested by: %s)", filePath, taskID, targetUserID, requestingUserID)
+		logging.Infof("✓ Downloaded file %s for task %s (user: %s, requested by: %s)", filePath, taskID, targetUserID, requestingUserID)
 	}
 }
 
@@ -283,14 +372,14 @@ func (h *FileAPIHandler) HandleDownloadFile(w http.ResponseWriter, r *http.Reque
 // Deletes all files for a specific task with access control
 func (h *FileAPIHandler) HandleDeleteTaskFiles(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Extract task ID from URL path
 	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(parts) < 3 {
-		http.Error(w, "Invalid URL format. Expected: /api/files/{task_id}", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid URL format. Expected: /api/files/{task_id}")
 		return
 	}
 	taskID := parts[2]
@@ -299,17 +388,17 @@ func (h *FileAPIHandler) HandleDeleteTaskFiles(w http.ResponseWriter, r *http.Re
 	targetUserID := r.URL.Query().Get("user_id")
 
 	if requestingUserID == "" {
-		http.Error(w, "Missing requesting_user parameter", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing requesting_user parameter")
 		return
 	}
 
 	if targetUserID == "" {
-		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing user_id parameter")
 		return
 	}
 
 	if h.fileStorage == nil {
-		http.Error(w, "File storage not available", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "File storage not available")
 		return
 	}
 
@@ -317,15 +406,15 @@ func (h *FileAPIHandler) HandleDeleteTaskFiles(w http.ResponseWriter, r *http.Re
 	err := h.fileStorage.DeleteTaskFilesWithAccess(requestingUserID, targetUserID, taskID)
 	if err != nil {
 		if strings.Contains(err.Error(), "access denied") {
-			http.Error(w, err.Error(), http.StatusForbidden)
+			writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, err.Error())
 			return
 		}
 		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
 			return
 		}
-		log.Printf("Error deleting files for task %s: %v", taskID, err)
-		http.Error(w, fmt.Sprintf("Failed to delete files: %v", err), http.StatusInternalServerError)
+		logging.Errorf("Error deleting files for task %s: %v", taskID, err)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to delete files: %v", err))
 		return
 	}
 
@@ -337,6 +426,6 @@ func (h *FileAPIHandler) HandleDeleteTaskFiles(w http.ResponseWriter, r *http.Re
 	})
 
 	if !h.quietMode {
-		log.Printf("✓ Deleted files for task %s (user: %s, requested by: %s)", taskID, targetUserID, requestingUserID)
+		logging.Infof("✓ Deleted files for task %s (user: %s, requested by: %s)", taskID, targetUserID, requestingUserID)
 	}
 }
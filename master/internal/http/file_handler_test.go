@@ -0,0 +1,211 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	"master/internal/storage"
+	"master/internal/telemetry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestDownloadFileRange tests that a ranged download returns only the requested bytes with a 206 status
+func TestDownloadFileRange(t *testing.T) {
+	fileStorage, err := storage.NewFileStorageService(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file storage: %v", err)
+	}
+	taskDir := fileStorage.GetTaskStoragePath("alice", "train", time.Now().Unix(), "task-1")
+	if err := os.MkdirAll(taskDir, 0700); err != nil {
+		t.Fatalf("Failed to create task directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(taskDir, "result.bin"), []byte("0123456789abcdef"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	handler := NewFileAPIHandler(fileStorage)
+	url := "/api/files/task-1/download/result.bin?user_id=alice&requesting_user=alice"
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Range", "bytes=10-")
+	w := httptest.NewRecorder()
+	handler.HandleDownloadFile(w, req)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("Expected status 206, got %d", w.Code)
+	}
+	if body := w.Body.String(); body != "abcdef" {
+		t.Errorf("Expected body %q, got %q", "abcdef", body)
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 10-15/16" {
+		t.Errorf("Expected Content-Range %q, got %q", "bytes 10-15/16", got)
+	}
+	if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Expected Accept-Ranges %q, got %q", "bytes", got)
+	}
+
+	// Without a Range header the whole file is served
+	w = httptest.NewRecorder()
+	handler.HandleDownloadFile(w, httptest.NewRequest(http.MethodGet, url, nil))
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789abcdef" {
+		t.Errorf("Expected the full file with status 200, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+// TestDownloadFileRangeNotGzipped tests that a ranged download through the server's middleware chain stays uncompressed
+// even when the client accepts gzip, so Content-Range offsets still refer to the file's bytes
+func TestDownloadFileRangeNotGzipped(t *testing.T) {
+	fileStorage, err := storage.NewFileStorageService(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file storage: %v", err)
+	}
+	taskDir := fileStorage.GetTaskStoragePath("alice", "train", time.Now().Unix(), "task-1")
+	if err := os.MkdirAll(taskDir, 0700); err != nil {
+		t.Fatalf("Failed to create task directory: %v", err)
+	}
+	content := []byte(strings.Repeat("0123456789abcdef", 512)) // Compressible and well over gzipMinSize
+	if err := os.WriteFile(filepath.Join(taskDir, "result.log"), content, 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	ts := NewTelemetryServer(0, telemetry.NewTelemetryManager(30*time.Second))
+	ts.RegisterFileHandlers(NewFileAPIHandler(fileStorage))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files/task-1/download/result.log?user_id=alice&requesting_user=alice", nil)
+	req.Header.Set("Range", "bytes=100-4195")
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	ts.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("Expected status 206, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Expected no Content-Encoding on a ranged response, got %q", got)
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 100-4195/8192" {
+		t.Errorf("Expected Content-Range %q, got %q", "bytes 100-4195/8192", got)
+	}
+	if !bytes.Equal(w.Body.Bytes(), content[100:4196]) {
+		t.Errorf("Expected the identity bytes 100-4195, got %d bytes", w.Body.Len())
+	}
+}
+
+// TestDownloadFileResumeValidatesModTime tests that a resumed download only gets a range while the file is unchanged
+func TestDownloadFileResumeValidatesModTime(t *testing.T) {
+	fileStorage, err := storage.NewFileStorageService(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file storage: %v", err)
+	}
+	taskDir := fileStorage.GetTaskStoragePath("alice", "train", time.Now().Unix(), "task-1")
+	if err := os.MkdirAll(taskDir, 0700); err != nil {
+		t.Fatalf("Failed to create task directory: %v", err)
+	}
+	path := filepath.Join(taskDir, "result.bin")
+	if err := os.WriteFile(path, []byte("0123456789abcdef"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	handler := NewFileAPIHandler(fileStorage)
+	url := "/api/files/task-1/download/result.bin?user_id=alice&requesting_user=alice"
+
+	w := httptest.NewRecorder()
+	handler.HandleDownloadFile(w, httptest.NewRequest(http.MethodGet, url, nil))
+	lastModified := w.Header().Get("Last-Modified")
+	if lastModified != modTime.Format(http.TimeFormat) {
+		t.Fatalf("Expected Last-Modified %q, got %q", modTime.Format(http.TimeFormat), lastModified)
+	}
+
+	// Resuming against the same modification time gets the remaining bytes
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Range", "bytes=10-")
+	req.Header.Set("If-Range", lastModified)
+	w = httptest.NewRecorder()
+	handler.HandleDownloadFile(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "abcdef" {
+		t.Errorf("Expected the remaining bytes with status 206, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Once the file has changed the whole file is sent again instead of splicing old and new bytes
+	changed := modTime.Add(time.Hour)
+	if err := os.Chtimes(path, changed, changed); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+	w = httptest.NewRecorder()
+	handler.HandleDownloadFile(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789abcdef" {
+		t.Errorf("Expected the full file with status 200, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+// TestGetTaskManifest tests that the manifest lists a stored task's files with sizes and checksums for its owner only
+func TestGetTaskManifest(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("two files", func(mt *mtest.T) {
+		fileStorage, err := storage.NewFileStorageService(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create file storage: %v", err)
+		}
+		handler := NewFileAPIHandler(fileStorage)
+		handler.SetFileMetadataDB(db.NewFileMetadataDBFromClient(mt.Client, "cloudai"))
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.FILE_METADATA", mtest.FirstBatch, bson.D{
+			{Key: "task_id", Value: "task-1"},
+			{Key: "user_id", Value: "alice"},
+			{Key: "task_name", Value: "train"},
+			{Key: "file_paths", Value: bson.A{"model.bin", "logs/train.log"}},
+			{Key: "files", Value: bson.A{
+				bson.D{{Key: "path", Value: "model.bin"}, {Key: "size", Value: int64(1024)}, {Key: "sha256", Value: "aa11"}},
+				bson.D{{Key: "path", Value: "logs/train.log"}, {Key: "size", Value: int64(64)}, {Key: "sha256", Value: "bb22"}},
+			}},
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/files/task-1/manifest?user_id=alice&requesting_user=alice", nil)
+		w := httptest.NewRecorder()
+		handler.HandleGetTaskManifest(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response FileManifestResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response.TaskID != "task-1" || response.Count != 2 || response.TotalSize != 1088 {
+			t.Errorf("Expected task-1 with 2 files of 1088 bytes, got %s with %d files of %d bytes",
+				response.TaskID, response.Count, response.TotalSize)
+		}
+		expected := []db.FileEntry{
+			{Path: "model.bin", Size: 1024, SHA256: "aa11"},
+			{Path: "logs/train.log", Size: 64, SHA256: "bb22"},
+		}
+		for i, want := range expected {
+			if i >= len(response.Files) || response.Files[i] != want {
+				t.Errorf("Expected file %d to be %+v, got %+v", i, want, response.Files)
+			}
+		}
+
+		// Another user may not list alice's files
+		req = httptest.NewRequest(http.MethodGet, "/api/files/task-1/manifest?user_id=alice&requesting_user=bob", nil)
+		w = httptest.NewRecorder()
+		handler.HandleGetTaskManifest(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for another user, got %d", w.Code)
+		}
+	})
+}
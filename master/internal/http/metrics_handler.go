@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+
+	"master/internal/scheduler"
+)
+
+// MetricsHandler serves scheduler metrics in Prometheus text exposition format
+type MetricsHandler struct {
+	metrics *scheduler.SchedulerMetrics
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(metrics *scheduler.SchedulerMetrics) *MetricsHandler {
+	return &MetricsHandler{metrics: metrics}
+}
+
+// HandleMetrics handles GET /metrics
+func (h *MetricsHandler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	h.metrics.WritePrometheus(w)
+}
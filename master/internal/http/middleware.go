@@ -1,9 +1,11 @@
 package http
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
-	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 // AuthMiddleware wraps a handler and requires authentication
@@ -12,22 +14,14 @@ func (h *AuthHandler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// Get token from cookie
 		cookie, err := r.Cookie("auth_token")
 		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error": "Unauthorized: No authentication token",
-			})
+			writeJSONError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized: No authentication token")
 			return
 		}
 
 		// Verify token
 		claims, err := h.VerifyToken(cookie.Value)
 		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error": "Unauthorized: Invalid token",
-			})
+			writeJSONError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized: Invalid token")
 			return
 		}
 
@@ -39,3 +33,136 @@ func (h *AuthHandler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// gzipMinSize is the smallest response body worth compressing
+const gzipMinSize = 1024
+
+// compressedMagic lists leading bytes of formats that are already compressed
+var compressedMagic = [][]byte{
+	{0x1f, 0x8b},                  // gzip
+	{'P', 'K', 0x03, 0x04},        // zip (also jar, docx, ...)
+	{'B', 'Z', 'h'},               // bzip2
+	{0xfd, '7', 'z', 'X', 'Z', 0}, // xz
+	{0x28, 0xb5, 0x2f, 0xfd},      // zstd
+	{'7', 'z', 0xbc, 0xaf},        // 7z
+	{0x89, 'P', 'N', 'G'},         // png
+	{0xff, 0xd8, 0xff},            // jpeg
+}
+
+// gzipMiddleware compresses responses for clients that accept gzip once the body exceeds gzipMinSize
+// WebSocket upgrades, Range requests and partial responses (their byte offsets refer to the identity body),
+// responses that already set Content-Encoding and already-compressed payloads (detected by their leading bytes)
+// are passed through unchanged
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") ||
+			r.Header.Get("Upgrade") != "" || r.Header.Get("Range") != "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.finish()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipResponseWriter buffers the start of a response to decide whether to compress it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	buf     bytes.Buffer
+	status  int
+	decided bool
+	gz      *gzip.Writer // Non-nil once the response is being compressed
+}
+
+// WriteHeader records the status; it is sent once the compression decision is made
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if !g.decided {
+		g.status = status
+	}
+}
+
+// Write buffers until gzipMinSize bytes are seen, then streams compressed or plain output
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+
+	g.buf.Write(p)
+	if g.buf.Len() >= gzipMinSize {
+		if err := g.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends buffered output so far (the decision is made with what has been written)
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.decide()
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// decide picks plain or gzip output, writes the headers and drains the buffer
+func (g *gzipResponseWriter) decide() error {
+	g.decided = true
+	header := g.Header()
+	body := g.buf.Bytes()
+
+	if g.shouldCompress(body) {
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(body))
+		}
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		g.ResponseWriter.WriteHeader(g.status)
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+		_, err := g.gz.Write(body)
+		return err
+	}
+
+	g.ResponseWriter.WriteHeader(g.status)
+	_, err := g.ResponseWriter.Write(body)
+	return err
+}
+
+// shouldCompress reports whether a response starting with body is worth gzipping
+func (g *gzipResponseWriter) shouldCompress(body []byte) bool {
+	if len(body) < gzipMinSize || g.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	if g.status == http.StatusNoContent || g.status == http.StatusNotModified {
+		return false
+	}
+	if g.status == http.StatusPartialContent || g.Header().Get("Content-Range") != "" {
+		return false
+	}
+	for _, magic := range compressedMagic {
+		if bytes.HasPrefix(body, magic) {
+			return false
+		}
+	}
+	return true
+}
+
+// finish flushes any buffered output and closes the gzip stream
+func (g *gzipResponseWriter) finish() {
+	if !g.decided {
+		g.decide()
+	}
+	if g.gz != nil {
+		g.gz.Close()
+	}
+}
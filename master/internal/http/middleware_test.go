@@ -0,0 +1,147 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGzipMiddlewareCompressesLargeJSON tests that a large JSON response is gzip-encoded and decodes back intact
+func TestGzipMiddlewareCompressesLargeJSON(t *testing.T) {
+	logs := make([]string, 200)
+	for i := range logs {
+		logs[i] = fmt.Sprintf("line %d: container output that repeats and compresses well", i)
+	}
+	original, _ := json.Marshal(map[string]interface{}{"task_id": "task-1", "logs": logs})
+
+	handler := gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(original)))
+		w.Write(original)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks/task-1/logs", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected Content-Encoding gzip, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Errorf("Expected stale Content-Length to be dropped, got %q", rec.Header().Get("Content-Length"))
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+	}
+	if rec.Body.Len() >= len(original) {
+		t.Errorf("Expected compressed body smaller than %d bytes, got %d", len(original), rec.Body.Len())
+	}
+
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Failed to open gzip body: %v", err)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress body: %v", err)
+	}
+	if !bytes.Equal(decoded, original) {
+		t.Error("Expected decompressed body to match the original JSON")
+	}
+}
+
+// TestGzipMiddlewareSkipsSmallAndCompressedBodies tests that small and already-compressed responses pass through
+func TestGzipMiddlewareSkipsSmallAndCompressedBodies(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	gz.Write(bytes.Repeat([]byte("file contents "), 500))
+	gz.Close()
+
+	cases := map[string][]byte{
+		"small":      []byte(`{"success":true}`),
+		"compressed": archive.Bytes(),
+	}
+	for name, body := range cases {
+		handler := gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(body)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/files/task-1/download", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: expected no Content-Encoding, got %q", name, rec.Header().Get("Content-Encoding"))
+		}
+		if !bytes.Equal(rec.Body.Bytes(), body) {
+			t.Errorf("%s: expected body to pass through unchanged", name)
+		}
+	}
+}
+
+// TestAdminKeyMiddleware tests that admin endpoints reject a missing or wrong X-Admin-Key and accept the configured one
+func TestAdminKeyMiddleware(t *testing.T) {
+	ts := &TelemetryServer{}
+	ts.SetAdminKey("s3cret")
+	handler := ts.adminKeyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		method, path, key string
+		expected          int
+	}{
+		{http.MethodPost, "/api/admin/reconcile", "", http.StatusForbidden},
+		{http.MethodPost, "/api/admin/reconcile", "wrong", http.StatusForbidden},
+		{http.MethodPost, "/api/admin/reconcile", "s3cret", http.StatusOK},
+		{http.MethodPost, "/api/workers", "", http.StatusForbidden},
+		{http.MethodPost, "/api/workers", "s3cret", http.StatusOK},
+		{http.MethodPost, "/api/workers/worker-1/expire", "wrong", http.StatusForbidden},
+		{http.MethodPut, "/api/workers/worker-1/maintenance", "s3cret", http.StatusOK},
+		{http.MethodPatch, "/api/workers/worker-1", "", http.StatusForbidden},
+		{http.MethodPatch, "/api/workers/worker-1", "s3cret", http.StatusOK},
+		{http.MethodPost, "/api/tasks/cancel", "", http.StatusForbidden},
+		{http.MethodPost, "/api/tasks/cancel", "s3cret", http.StatusOK},
+		{http.MethodPost, "/api/reservations", "", http.StatusForbidden},
+		{http.MethodDelete, "/api/reservations/res-1", "s3cret", http.StatusOK},
+		// Reads and non-admin endpoints need no key
+		{http.MethodGet, "/api/reservations/res-1", "", http.StatusOK},
+		{http.MethodGet, "/api/workers", "", http.StatusOK},
+		{http.MethodGet, "/api/workers/worker-1", "", http.StatusOK},
+		{http.MethodGet, "/api/tasks", "", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.key != "" {
+			req.Header.Set(AdminKeyHeader, c.key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.expected {
+			t.Errorf("%s %s with key %q: expected status %d, got %d", c.method, c.path, c.key, c.expected, rec.Code)
+		}
+		if rec.Code == http.StatusForbidden {
+			var body ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code != ErrCodeForbidden {
+				t.Errorf("%s %s: expected a FORBIDDEN error body, got %s", c.method, c.path, rec.Body.String())
+			}
+		}
+	}
+
+	// Without a configured key every endpoint stays open
+	open := (&TelemetryServer{}).adminKeyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	open.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/reconcile", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 without a configured key, got %d", rec.Code)
+	}
+}
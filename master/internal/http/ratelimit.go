@@ -0,0 +1,170 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitIdleTTL is how long an untouched client bucket is kept before it is dropped
+const rateLimitIdleTTL = 10 * time.Minute
+
+// RateLimit is the sustained request rate and burst allowed per client in a route group
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// ParseRateLimit parses "requests_per_second[:burst]"; the burst defaults to the rate rounded up
+func ParseRateLimit(spec string) (RateLimit, error) {
+	rateStr, burstStr, hasBurst := strings.Cut(strings.TrimSpace(spec), ":")
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || rate <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate %q: must be a positive number of requests per second", rateStr)
+	}
+
+	burst := int(math.Ceil(rate))
+	if hasBurst {
+		burst, err = strconv.Atoi(burstStr)
+		if err != nil || burst < 1 {
+			return RateLimit{}, fmt.Errorf("invalid burst %q: must be a positive integer", burstStr)
+		}
+	}
+	return RateLimit{PerSecond: rate, Burst: burst}, nil
+}
+
+// routeGroup maps a request path to the route group its rate limit is configured under
+func routeGroup(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/tasks"), strings.HasPrefix(path, "/ws/tasks/"):
+		return "tasks"
+	case strings.HasPrefix(path, "/api/workers"):
+		return "workers"
+	case strings.HasPrefix(path, "/api/files"):
+		return "files"
+	case strings.HasPrefix(path, "/api/auth/"):
+		return "auth"
+	default:
+		return ""
+	}
+}
+
+// tokenBucket holds one client's tokens for one route group
+type tokenBucket struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+// RateLimiter enforces a token bucket per client and route group
+// Clients are identified by authenticated user when an identifier is set, otherwise by IP
+type RateLimiter struct {
+	mu        sync.Mutex
+	limits    map[string]RateLimit    // route group -> limit; groups without one are unlimited
+	buckets   map[string]*tokenBucket // "group|client" -> bucket
+	identify  func(r *http.Request) string
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a rate limiter with the given limits per route group (tasks, workers, files, auth)
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// SetIdentifier sets how an authenticated user is read from a request ("" falls back to the client IP)
+func (rl *RateLimiter) SetIdentifier(identify func(r *http.Request) string) {
+	rl.mu.Lock()
+	rl.identify = identify
+	rl.mu.Unlock()
+}
+
+// clientKey returns the authenticated user or, failing that, the client IP
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	rl.mu.Lock()
+	identify := rl.identify
+	rl.mu.Unlock()
+	if identify != nil {
+		if user := identify(r); user != "" {
+			return "user:" + user
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// allow takes a token for client in group, returning how long to wait when none is left
+func (rl *RateLimiter) allow(group, client string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limit, limited := rl.limits[group]
+	if !limited {
+		return true, 0
+	}
+
+	now := rl.now()
+	rl.sweep(now)
+
+	key := group + "|" + client
+	bucket, exists := rl.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), lastFill: now}
+		rl.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastFill).Seconds()
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed*limit.PerSecond)
+	bucket.lastFill = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bucket.tokens) / limit.PerSecond * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets idle for longer than rateLimitIdleTTL (they would be full again anyway)
+// This function assumes rl.mu is already locked by the caller
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rateLimitIdleTTL {
+		return
+	}
+	rl.lastSweep = now
+	for key, bucket := range rl.buckets {
+		if now.Sub(bucket.lastFill) > rateLimitIdleTTL {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// Middleware rejects requests over their route group's rate with 429 and a Retry-After header
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := routeGroup(r.URL.Path)
+		if group == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, wait := rl.allow(group, rl.clientKey(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSONError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "Rate limit exceeded, retry later")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
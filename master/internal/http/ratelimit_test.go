@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRateLimiterRejectsRapidSubmissions tests that task submissions beyond the configured rate get 429 with Retry-After
+func TestRateLimiterRejectsRapidSubmissions(t *testing.T) {
+	limit, err := ParseRateLimit("0.5:3")
+	if err != nil {
+		t.Fatalf("Failed to parse rate limit: %v", err)
+	}
+	rl := NewRateLimiter(map[string]RateLimit{"tasks": limit})
+	now := time.Unix(1700000000, 0)
+	rl.now = func() time.Time { return now }
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	submit := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"docker_image":"alpine"}`))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The burst is allowed, then further submissions are rejected
+	for i := 0; i < 3; i++ {
+		if rec := submit("/api/tasks", "10.0.0.1:40000"); rec.Code != http.StatusCreated {
+			t.Fatalf("Expected submission %d within the burst to succeed, got %d", i+1, rec.Code)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		rec := submit("/api/tasks", "10.0.0.1:40001")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected 429 beyond the burst, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "2" {
+			t.Errorf("Expected Retry-After 2 at 0.5 req/s, got %q", got)
+		}
+	}
+
+	// Other clients and route groups without a limit are unaffected
+	if rec := submit("/api/tasks", "10.0.0.2:40000"); rec.Code != http.StatusCreated {
+		t.Errorf("Expected another client to be allowed, got %d", rec.Code)
+	}
+	if rec := submit("/api/workers", "10.0.0.1:40000"); rec.Code != http.StatusCreated {
+		t.Errorf("Expected an unlimited route group to be allowed, got %d", rec.Code)
+	}
+
+	// A token is refilled after Retry-After has passed
+	now = now.Add(2 * time.Second)
+	if rec := submit("/api/tasks", "10.0.0.1:40000"); rec.Code != http.StatusCreated {
+		t.Errorf("Expected a submission after Retry-After to succeed, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"master/internal/logging"
+	"master/internal/scheduler"
+)
+
+// SchedulerAPIHandler handles HTTP REST API requests for live scheduler tuning
+type SchedulerAPIHandler struct {
+	rts       *scheduler.RTSScheduler
+	quietMode bool
+}
+
+// NewSchedulerAPIHandler creates a new scheduler API handler
+func NewSchedulerAPIHandler(rts *scheduler.RTSScheduler) *SchedulerAPIHandler {
+	return &SchedulerAPIHandler{
+		rts:       rts,
+		quietMode: true,
+	}
+}
+
+// HandleRisk handles GET/POST /api/scheduler/risk
+// GET returns the live Risk weights; POST accepts {"alpha":..,"beta":..} and updates them
+// Omitted fields keep their current value
+func (h *SchedulerAPIHandler) HandleRisk(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Alpha *float64 `json:"alpha"`
+			Beta  *float64 `json:"beta"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		if req.Alpha == nil && req.Beta == nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "At least one of alpha or beta is required")
+			return
+		}
+
+		risk, _ := h.rts.GetRisk()
+		if req.Alpha != nil {
+			risk.Alpha = *req.Alpha
+		}
+		if req.Beta != nil {
+			risk.Beta = *req.Beta
+		}
+
+		if err := h.rts.SetRisk(risk); err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid risk weights: %v", err))
+			return
+		}
+
+		if !h.quietMode {
+			logging.Infof("RTS risk weights updated via API: alpha=%.2f beta=%.2f", risk.Alpha, risk.Beta)
+		}
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	risk, overridden := h.rts.GetRisk()
+	response := map[string]interface{}{
+		"success":    true,
+		"alpha":      risk.Alpha,
+		"beta":       risk.Beta,
+		"overridden": overridden,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"master/internal/scheduler"
+	"master/internal/telemetry"
+	pb "master/proto"
+)
+
+// staticTelemetrySource serves a fixed set of worker views
+type staticTelemetrySource struct {
+	views []scheduler.WorkerView
+}
+
+func (s *staticTelemetrySource) GetWorkerViews(ctx context.Context) ([]scheduler.WorkerView, error) {
+	return s.views, nil
+}
+
+func (s *staticTelemetrySource) GetWorkerLoad(workerID string) float64 {
+	for _, v := range s.views {
+		if v.ID == workerID {
+			return v.Load
+		}
+	}
+	return 0.0
+}
+
+// TestHandleRiskRaisingBetaFavorsLessLoadedWorker tests that a live Beta change steers SelectWorker
+func TestHandleRiskRaisingBetaFavorsLessLoadedWorker(t *testing.T) {
+	// Two identical workers apart from load; the busier one sorts first by ID so it wins ties
+	source := &staticTelemetrySource{views: []scheduler.WorkerView{
+		{ID: "worker-busy", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.9},
+		{ID: "worker-idle", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.1},
+	}}
+	rts := scheduler.NewRTSScheduler(scheduler.NewRoundRobinScheduler(), telemetry.NewInMemoryTauStore(),
+		source, filepath.Join(t.TempDir(), "ga_output.json"), 2.0)
+	defer rts.Shutdown()
+
+	workers := map[string]*scheduler.WorkerInfo{
+		"worker-busy": {WorkerID: "worker-busy", IsActive: true, WorkerIP: "10.0.0.1:50052"},
+		"worker-idle": {WorkerID: "worker-idle", IsActive: true, WorkerIP: "10.0.0.2:50052"},
+	}
+	task := &pb.Task{TaskId: "task-1", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1}
+
+	handler := NewSchedulerAPIHandler(rts)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/scheduler/risk", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.HandleRisk(rec, req)
+		return rec
+	}
+
+	// With no load penalty (and no deadline pressure) the workers tie
+	if rec := post(`{"beta": 0}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rts.SelectWorker(task, workers); got != "worker-busy" {
+		t.Fatalf("Expected tie to go to the first worker by ID with beta=0, got %s", got)
+	}
+
+	if rec := post(`{"beta": 50}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rts.SelectWorker(task, workers); got != "worker-idle" {
+		t.Errorf("Expected raised beta to favor worker-idle, got %s", got)
+	}
+
+	risk, overridden := rts.GetRisk()
+	if risk.Beta != 50 || !overridden {
+		t.Errorf("Expected live beta 50 to be recorded as an override, got %.1f (overridden=%v)", risk.Beta, overridden)
+	}
+}
+
+// TestHandleRiskRejectsOutOfRange tests that weights outside the GA ranges are rejected
+func TestHandleRiskRejectsOutOfRange(t *testing.T) {
+	rts := scheduler.NewRTSScheduler(scheduler.NewRoundRobinScheduler(), telemetry.NewInMemoryTauStore(),
+		&staticTelemetrySource{}, filepath.Join(t.TempDir(), "ga_output.json"), 2.0)
+	defer rts.Shutdown()
+	handler := NewSchedulerAPIHandler(rts)
+
+	for _, body := range []string{`{"alpha": -1}`, `{"beta": 101}`, `{}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/scheduler/risk", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.HandleRisk(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	if _, overridden := rts.GetRisk(); overridden {
+		t.Error("Expected rejected updates to leave the risk weights untouched")
+	}
+}
@@ -50,6 +50,45 @@ type TaskRequest struct {
 	// New fields
 	Tag    string      `json:"tag,omitempty"`
 	KValue json.Number `json:"k_value,omitempty"`
+	// PinCPUs pins the container to dedicated cores on the worker
+	PinCPUs bool `json:"pin_cpus,omitempty"`
+	// Cacheable lets the worker return a cached result for an identical image and command
+	Cacheable bool `json:"cacheable,omitempty"`
+	// Deadline is an optional absolute deadline (RFC3339); it overrides the k*tau deadline
+	Deadline string `json:"deadline,omitempty"`
+	// LocalityKey groups related tasks so they prefer the worker that ran the previous one
+	LocalityKey string `json:"locality_key,omitempty"`
+	// PreferredWorkerID is tried first; if it cannot take the task the scheduler picks another worker
+	PreferredWorkerID string `json:"preferred_worker_id,omitempty"`
+	// ReservationID draws the task's resources from a capacity reservation made with POST /api/reservations
+	ReservationID string `json:"reservation_id,omitempty"`
+	// MaxRestarts restarts the container on non-zero exit up to this many times
+	MaxRestarts int32 `json:"max_restarts,omitempty"`
+	// MemoryLimit is an optional hard memory cap (GB); above memory_required the request becomes a soft reservation
+	MemoryLimit json.Number `json:"memory_limit,omitempty"`
+	// Hold stages the task as "held" until it is released
+	Hold bool `json:"hold,omitempty"`
+	// StopGracePeriodSec is the SIGTERM-to-SIGKILL grace when the task is cancelled (default: 10)
+	StopGracePeriodSec int32 `json:"stop_grace_period_sec,omitempty"`
+	// AntiAffinityKey spreads tasks sharing it across worker zones when possible
+	AntiAffinityKey string `json:"anti_affinity_key,omitempty"`
+	// Priority orders importance; at or above the master's PREEMPTION_PRIORITY the task may preempt lower-priority ones
+	Priority int32 `json:"priority,omitempty"`
+	// ExternalRef is the client's own job ID; the task can be cancelled by it via DELETE /api/tasks/by-ref/:ref
+	ExternalRef string `json:"external_ref,omitempty"`
+	// NetworkMode is the container's Docker network mode: bridge (default), none, or one allowed by TASK_NETWORKS
+	NetworkMode string `json:"network_mode,omitempty"`
+	// Ports are published on the worker host, e.g. "8080" (any host port) or "9000:8080"
+	Ports []string `json:"ports,omitempty"`
+	// WorkingDir and RunAsUser override the image's WORKDIR and USER (RunAsUser: name, UID or UID:GID)
+	WorkingDir string `json:"working_dir,omitempty"`
+	RunAsUser  string `json:"run_as_user,omitempty"`
+	// ReadOnlyRootFS mounts the container's root filesystem read-only; /output stays writable
+	ReadOnlyRootFS bool `json:"read_only_rootfs,omitempty"`
+	// HealthCheck runs in the started container (sh -c); the task is reported ready once it exits 0,
+	// and fails if it does not within HealthCheckTimeoutSec (0 = worker default)
+	HealthCheck           string `json:"health_check,omitempty"`
+	HealthCheckTimeoutSec int32  `json:"health_check_timeout_sec,omitempty"`
 }
 
 // parseFloat64 safely parses a json.Number to float64
@@ -77,21 +116,21 @@ type TaskResponse struct {
 // HandleCreateTask handles POST /api/tasks
 func (h *TaskAPIHandler) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Parse request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Failed to read request body")
 		return
 	}
 	defer r.Body.Close()
 
 	var taskReq TaskRequest
 	if err := json.Unmarshal(body, &taskReq); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
 		return
 	}
 
@@ -101,54 +140,130 @@ func (h *TaskAPIHandler) HandleCreateTask(w http.ResponseWriter, r *http.Request
 	gpuRequired := parseFloat64(taskReq.GPURequired, 0)
 	storageRequired := parseFloat64(taskReq.StorageRequired, 1024) // Default 1GB
 	kValue := parseFloat64(taskReq.KValue, 0)
+	memoryLimit := parseFloat64(taskReq.MemoryLimit, 0)
 
 	// Validate required fields
 	if taskReq.DockerImage == "" {
-		http.Error(w, "Missing required field: docker_image", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing required field: docker_image")
 		return
 	}
 	if cpuRequired <= 0 || memoryRequired <= 0 {
-		http.Error(w, "Invalid resource requirements: cpu_required and memory_required must be greater than 0", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid resource requirements: cpu_required and memory_required must be greater than 0")
 		return
 	}
 
 	// Validate K-value if provided (allowed range 1.5 to 2.5)
 	if taskReq.KValue != "" {
 		if kValue < 1.5 || kValue > 2.5 {
-			http.Error(w, "k_value must be between 1.5 and 2.5", http.StatusBadRequest)
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "k_value must be between 1.5 and 2.5")
 			return
 		}
 	} else {
 		kValue = 2.0 // Default SLA multiplier
 	}
 
+	// Parse absolute deadline if provided
+	var deadline int64
+	if taskReq.Deadline != "" {
+		t, err := time.Parse(time.RFC3339, taskReq.Deadline)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "deadline must be an RFC3339 timestamp")
+			return
+		}
+		deadline = t.Unix()
+	}
+
+	if memoryLimit < 0 || (memoryLimit > 0 && memoryLimit < memoryRequired) {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "memory_limit must not be negative or below memory_required")
+		return
+	}
+
+	if taskReq.MaxRestarts < 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "max_restarts must not be negative")
+		return
+	}
+
+	if taskReq.StopGracePeriodSec < 0 || taskReq.StopGracePeriodSec > server.MaxStopGracePeriodSec {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("stop_grace_period_sec must be between 0 and %d", server.MaxStopGracePeriodSec))
+		return
+	}
+
+	if taskReq.ReservationID != "" {
+		if _, exists := h.masterServer.GetCapacityReservation(taskReq.ReservationID); !exists {
+			writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Capacity reservation %s not found", taskReq.ReservationID))
+			return
+		}
+	}
+
 	// Create task protobuf with task_type and sla_multiplier
 	task := &pb.Task{
-		TaskId:        fmt.Sprintf("task-%d", time.Now().UnixNano()),
-		DockerImage:   taskReq.DockerImage,
-		Command:       taskReq.Command,
-		ReqCpu:        cpuRequired,
-		ReqMemory:     memoryRequired,
-		ReqStorage:    storageRequired,
-		ReqGpu:        gpuRequired,
-		UserId:        taskReq.UserID,
-		TaskType:      taskReq.Tag,         // Set task_type from tag field
-		SlaMultiplier: kValue,              // Set SLA multiplier
-		TaskName:      taskReq.DockerImage, // Default task name
-		SubmittedAt:   time.Now().Unix(),
+		TaskId:             server.NewTaskID(),
+		DockerImage:        taskReq.DockerImage,
+		Command:            taskReq.Command,
+		ReqCpu:             cpuRequired,
+		ReqMemory:          memoryRequired,
+		ReqStorage:         storageRequired,
+		ReqGpu:             gpuRequired,
+		UserId:             taskReq.UserID,
+		TaskType:           taskReq.Tag,         // Set task_type from tag field
+		SlaMultiplier:      kValue,              // Set SLA multiplier
+		TaskName:           taskReq.DockerImage, // Default task name
+		SubmittedAt:        time.Now().Unix(),
+		PinCpus:            taskReq.PinCPUs,
+		Cacheable:          taskReq.Cacheable,
+		Deadline:           deadline,
+		LocalityKey:        taskReq.LocalityKey,
+		PreferredWorkerId:  taskReq.PreferredWorkerID,
+		ReservationId:      taskReq.ReservationID,
+		MaxRestarts:        taskReq.MaxRestarts,
+		Hold:               taskReq.Hold,
+		MemLimit:           memoryLimit,
+		StopGracePeriodSec: taskReq.StopGracePeriodSec,
+		AntiAffinityKey:    taskReq.AntiAffinityKey,
+		Priority:           taskReq.Priority,
+		ExternalRef:        taskReq.ExternalRef,
+		NetworkMode:        taskReq.NetworkMode,
+		PortBindings:       taskReq.Ports,
+		WorkingDir:         taskReq.WorkingDir,
+		RunAsUser:          taskReq.RunAsUser,
+		ReadOnlyRootfs:     taskReq.ReadOnlyRootFS,
+
+		HealthCheck:           taskReq.HealthCheck,
+		HealthCheckTimeoutSec: taskReq.HealthCheckTimeoutSec,
 	}
 
 	// Submit task to master server
 	ctx := context.Background()
 	ack, err := h.masterServer.SubmitTask(ctx, task)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to submit task: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to submit task: %v", err))
+		return
+	}
+	if !ack.Success {
+		code := http.StatusInternalServerError
+		switch ack.ErrorCode {
+		case pb.ErrorCode_CLUSTER_AT_CAPACITY:
+			// The queue is drained by the scheduler every few seconds
+			w.Header().Set("Retry-After", "5")
+			code = http.StatusTooManyRequests
+		case pb.ErrorCode_INSUFFICIENT_CPU, pb.ErrorCode_INSUFFICIENT_MEMORY, pb.ErrorCode_INSUFFICIENT_GPU:
+			// Only a capacity reservation too small for the task is rejected at submission
+			code = http.StatusConflict
+		case pb.ErrorCode_INVALID_TASK_SPEC:
+			code = http.StatusBadRequest
+		}
+		writeJSONError(w, code, ackErrorCode(ack, code), ack.Message)
 		return
 	}
 
+	status := "queued"
+	if task.Hold {
+		status = "held"
+	}
+
 	response := TaskResponse{
 		TaskID:  task.TaskId,
-		Status:  "queued",
+		Status:  status,
 		Message: ack.Message,
 	}
 
@@ -168,12 +283,12 @@ func (h *TaskAPIHandler) HandleCreateTask(w http.ResponseWriter, r *http.Request
 // HandleListTasks handles GET /api/tasks
 func (h *TaskAPIHandler) HandleListTasks(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	if h.taskDB == nil {
-		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Database not available")
 		return
 	}
 
@@ -189,14 +304,14 @@ func (h *TaskAPIHandler) HandleListTasks(w http.ResponseWriter, r *http.Request)
 	if status != "" {
 		tasks, err = h.taskDB.GetTasksByStatus(ctx, status)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to retrieve tasks: %v", err), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to retrieve tasks: %v", err))
 			return
 		}
 	} else {
 		// Get all tasks
 		tasks, err = h.taskDB.GetAllTasks(ctx)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to retrieve tasks: %v", err), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to retrieve tasks: %v", err))
 			return
 		}
 	}
@@ -216,6 +331,8 @@ func (h *TaskAPIHandler) HandleListTasks(w http.ResponseWriter, r *http.Request)
 			"storage_required": task.ReqStorage,
 			"tag":              task.Tag,
 			"k_value":          task.KValue,
+			"display_name":     task.DisplayName,
+			"annotations":      task.Annotations,
 			"created_at":       task.CreatedAt.Unix(),
 		})
 	}
@@ -232,19 +349,19 @@ func (h *TaskAPIHandler) HandleListTasks(w http.ResponseWriter, r *http.Request)
 // HandleGetTask handles GET /api/tasks/:id
 func (h *TaskAPIHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Extract task ID from path
 	taskID := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
 	if taskID == "" || taskID == "api/tasks" {
-		http.Error(w, "Task ID required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Task ID required")
 		return
 	}
 
 	if h.taskDB == nil {
-		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Database not available")
 		return
 	}
 
@@ -253,7 +370,7 @@ func (h *TaskAPIHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
 	// Get task from database
 	task, err := h.taskDB.GetTask(ctx, taskID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Task not found: %v", err), http.StatusNotFound)
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Task not found: %v", err))
 		return
 	}
 
@@ -273,9 +390,16 @@ func (h *TaskAPIHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
 	if h.resultDB != nil {
 		if result, err := h.resultDB.GetResult(ctx, taskID); err == nil {
 			resultInfo = map[string]interface{}{
-				"status":       result.Status,
-				"completed_at": result.CompletedAt.Unix(),
-				"logs":         result.Logs,
+				"status":          result.Status,
+				"completed_at":    result.CompletedAt.Unix(),
+				"logs":            result.Logs,
+				"failure_reason":  result.FailureReason,
+				"exit_code":       result.ExitCode,
+				"error_message":   result.ErrorMessage,
+				"exit_summary":    result.ExitSummary(),
+				"usage":           result.Usage,
+				"published_ports": result.PublishedPorts,
+				"image_digest":    result.ImageDigest,
 			}
 		}
 	}
@@ -292,10 +416,96 @@ func (h *TaskAPIHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
 		"storage_required": task.ReqStorage,
 		"tag":              task.Tag,
 		"k_value":          task.KValue,
+		"display_name":     task.DisplayName,
+		"annotations":      task.Annotations,
+		"original_task_id": task.OriginalTaskID,
+		"external_ref":     task.ExternalRef,
+		"network_mode":     task.NetworkMode,
+		"ports":            task.PortBindings,
+		"working_dir":      task.WorkingDir,
+		"run_as_user":      task.RunAsUser,
+		"read_only_rootfs": task.ReadOnlyRootFS,
+		"health_check":     task.HealthCheck,
 		"created_at":       task.CreatedAt.Unix(),
 		"assignment":       assignmentInfo,
 		"result":           resultInfo,
 	}
+	if !task.ReadyAt.IsZero() {
+		response["ready_at"] = task.ReadyAt.Unix()
+	}
+	// While the task runs, its published ports come from its worker's heartbeats
+	if h.masterServer != nil {
+		if ports := h.masterServer.GetPublishedPorts(task.TaskID); len(ports) > 0 {
+			response["published_ports"] = db.PublishedPortsFromProto(ports)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LabelsRequest is the JSON body for PATCH /api/tasks/:id and PATCH /api/workers/:id
+// Omitted fields are left untouched; an empty annotation value removes that key
+type LabelsRequest struct {
+	DisplayName *string           `json:"display_name,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// decodeLabelsRequest parses and validates a labels patch body
+func decodeLabelsRequest(r *http.Request) (*LabelsRequest, error) {
+	var req LabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if req.DisplayName == nil && len(req.Annotations) == 0 {
+		return nil, fmt.Errorf("nothing to update: set display_name or annotations")
+	}
+	if err := db.ValidateAnnotations(req.Annotations); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// HandlePatchTask handles PATCH /api/tasks/:id (display name and annotations)
+func (h *TaskAPIHandler) HandlePatchTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskID := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
+	if taskID == "" || taskID == "api/tasks" {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Task ID required")
+		return
+	}
+
+	if h.taskDB == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Database not available")
+		return
+	}
+
+	req, err := decodeLabelsRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.taskDB.UpdateTaskLabels(context.Background(), taskID, req.DisplayName, req.Annotations); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Task not found: %v", err))
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to update task: %v", err))
+		return
+	}
+
+	response := TaskResponse{
+		TaskID:    taskID,
+		Status:    "updated",
+		Message:   "Task labels updated",
+		UpdatedAt: time.Now().Unix(),
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -304,14 +514,14 @@ func (h *TaskAPIHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
 // HandleDeleteTask handles DELETE /api/tasks/:id (cancel task)
 func (h *TaskAPIHandler) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Extract task ID from path
 	taskID := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
 	if taskID == "" || taskID == "api/tasks" {
-		http.Error(w, "Task ID required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Task ID required")
 		return
 	}
 
@@ -320,7 +530,7 @@ func (h *TaskAPIHandler) HandleDeleteTask(w http.ResponseWriter, r *http.Request
 	// Cancel task
 	_, err := h.masterServer.CancelTask(ctx, &pb.TaskID{TaskId: taskID})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to cancel task: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to cancel task: %v", err))
 		return
 	}
 
@@ -334,23 +544,176 @@ func (h *TaskAPIHandler) HandleDeleteTask(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(response)
 }
 
+// HandleCancelByExternalRef handles DELETE /api/tasks/by-ref/:ref (cancel the task submitted with that external ref)
+func (h *TaskAPIHandler) HandleCancelByExternalRef(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ref := strings.TrimPrefix(r.URL.Path, "/api/tasks/by-ref/")
+	if ref == "" {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "External reference required")
+		return
+	}
+
+	ack, err := h.masterServer.CancelByExternalRef(r.Context(), &pb.ExternalRef{Ref: ref})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to cancel task: %v", err))
+		return
+	}
+	if !ack.Success {
+		code := http.StatusConflict
+		switch ack.ErrorCode {
+		case pb.ErrorCode_TASK_NOT_FOUND:
+			code = http.StatusNotFound
+		case pb.ErrorCode_DATABASE_ERROR:
+			code = http.StatusServiceUnavailable
+		}
+		writeJSONError(w, code, ackErrorCode(ack, code), ack.Message)
+		return
+	}
+
+	response := map[string]interface{}{
+		"external_ref": ref,
+		"status":       "cancelled",
+		"message":      ack.Message,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CancelTasksRequest is the JSON body for POST /api/tasks/cancel; a task must match every field that is set
+// Without the admin key only RequestingUser's own tasks are matched
+type CancelTasksRequest struct {
+	Status      string `json:"status,omitempty"` // queued, retrying, held or running
+	UserID      string `json:"user_id,omitempty"`
+	DockerImage string `json:"docker_image,omitempty"`
+	Label       string `json:"label,omitempty"` // Annotation as key=value, or key to match any value
+
+	RequestingUser string `json:"requesting_user,omitempty"`
+}
+
+// HandleCancelTasks handles POST /api/tasks/cancel (cancel every unfinished task matching a filter)
+func (h *TaskAPIHandler) HandleCancelTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req CancelTasksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	filter := &pb.CancelFilter{
+		Status:         req.Status,
+		UserId:         req.UserID,
+		DockerImage:    req.DockerImage,
+		Label:          req.Label,
+		RequestingUser: req.RequestingUser,
+	}
+	cancelTasks := h.masterServer.CancelTasks
+	if isAdminRequest(r) {
+		cancelTasks = h.masterServer.CancelTasksAsAdmin
+	} else if req.RequestingUser == "" {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing requesting_user (or send the admin key to cancel any user's tasks)")
+		return
+	} else if req.UserID != "" && req.UserID != req.RequestingUser {
+		writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, "Only your own tasks can be cancelled without the admin key")
+		return
+	}
+
+	ack, err := cancelTasks(r.Context(), filter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to cancel tasks: %v", err))
+		return
+	}
+	if !ack.Success {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, ack.Message)
+		return
+	}
+
+	taskIDs := ack.TaskIds
+	if taskIDs == nil {
+		taskIDs = []string{}
+	}
+	response := map[string]interface{}{
+		"matched":   ack.Matched,
+		"cancelled": ack.Cancelled,
+		"failed":    ack.Failed,
+		"task_ids":  taskIDs,
+		"message":   ack.Message,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleRequeueTask handles POST /api/tasks/:id/requeue (run a finished task again under a new ID)
+func (h *TaskAPIHandler) HandleRequeueTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/requeue")
+	if taskID == "" || strings.Contains(taskID, "/") {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Task ID required")
+		return
+	}
+
+	task, ack, err := h.masterServer.RequeueTask(r.Context(), taskID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to requeue task: %v", err))
+		return
+	}
+	if !ack.Success {
+		code := http.StatusConflict
+		switch ack.ErrorCode {
+		case pb.ErrorCode_TASK_NOT_FOUND:
+			code = http.StatusNotFound
+		case pb.ErrorCode_DATABASE_ERROR:
+			code = http.StatusServiceUnavailable
+		case pb.ErrorCode_CLUSTER_AT_CAPACITY:
+			w.Header().Set("Retry-After", "5")
+			code = http.StatusTooManyRequests
+		}
+		writeJSONError(w, code, ackErrorCode(ack, code), ack.Message)
+		return
+	}
+
+	response := map[string]interface{}{
+		"task_id":          task.TaskId,
+		"original_task_id": task.OriginalTaskId,
+		"status":           "queued",
+		"message":          ack.Message,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
 // HandleGetTaskLogs handles GET /api/tasks/:id/logs
 func (h *TaskAPIHandler) HandleGetTaskLogs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Extract task ID from path - format is /api/tasks/{id}/logs
 	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/")
 	if len(pathParts) < 2 {
-		http.Error(w, "Task ID required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Task ID required")
 		return
 	}
 	taskID := pathParts[0]
 
 	if h.resultDB == nil {
-		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Database not available")
 		return
 	}
 
@@ -359,15 +722,19 @@ func (h *TaskAPIHandler) HandleGetTaskLogs(w http.ResponseWriter, r *http.Reques
 	// Get result which contains logs
 	result, err := h.resultDB.GetResult(ctx, taskID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Logs not found: %v", err), http.StatusNotFound)
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Logs not found: %v", err))
 		return
 	}
 
 	response := map[string]interface{}{
-		"task_id":      taskID,
-		"logs":         result.Logs,
-		"status":       result.Status,
-		"completed_at": result.CompletedAt.Unix(),
+		"task_id":        taskID,
+		"logs":           result.Logs,
+		"status":         result.Status,
+		"completed_at":   result.CompletedAt.Unix(),
+		"failure_reason": result.FailureReason,
+		"exit_code":      result.ExitCode,
+		"error_message":  result.ErrorMessage,
+		"exit_summary":   result.ExitSummary(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -382,7 +749,7 @@ func (h *TaskAPIHandler) HandleTaskLogsStream(w http.ResponseWriter, r *http.Req
 	taskID := strings.TrimSuffix(path, "/logs")
 
 	if taskID == "" {
-		http.Error(w, "Task ID required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Task ID required")
 		return
 	}
 
@@ -423,14 +790,18 @@ func (h *TaskAPIHandler) HandleTaskLogsStream(w http.ResponseWriter, r *http.Req
 	defer streamCancel()
 
 	// Stream logs using the master server's streaming function
-	err = h.masterServer.StreamTaskLogsUnified(streamCtx, taskID, userID, func(logLine string, isComplete bool, status string) error {
+	err = h.masterServer.StreamTaskLogsUnified(streamCtx, taskID, userID, func(logLine, stream string, isComplete bool, status string) error {
 		if logLine != "" {
-			// Send log line
-			if err := conn.WriteJSON(map[string]interface{}{
+			// Send log line, labelled with its stream when the worker reported one
+			message := map[string]interface{}{
 				"type":    "log",
 				"line":    logLine,
 				"task_id": taskID,
-			}); err != nil {
+			}
+			if stream != "" {
+				message["stream"] = stream
+			}
+			if err := conn.WriteJSON(message); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,273 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"master/internal/db"
+	"master/internal/server"
+	pb "master/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestHandlePatchTaskDisplayName tests that a display name set via PATCH shows up in GET /api/tasks/:id
+func TestHandlePatchTaskDisplayName(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("sets display name", func(mt *mtest.T) {
+		taskDB := db.NewTaskDBFromClient(mt.Client, "cloudai")
+		handler := NewTaskAPIHandler(nil, taskDB, nil, nil)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		body := `{"display_name": "nightly training", "annotations": {"team": "ml"}}`
+		req := httptest.NewRequest(http.MethodPatch, "/api/tasks/task-1", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.HandlePatchTask(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, bson.D{
+			{Key: "task_id", Value: "task-1"},
+			{Key: "docker_image", Value: "trainer:latest"},
+			{Key: "status", Value: "queued"},
+			{Key: "display_name", Value: "nightly training"},
+			{Key: "annotations", Value: bson.D{{Key: "team", Value: "ml"}}},
+		}))
+
+		req = httptest.NewRequest(http.MethodGet, "/api/tasks/task-1", nil)
+		rec = httptest.NewRecorder()
+		handler.HandleGetTask(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp struct {
+			DisplayName string            `json:"display_name"`
+			Annotations map[string]string `json:"annotations"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.DisplayName != "nightly training" {
+			t.Errorf("Expected display_name 'nightly training', got %q", resp.DisplayName)
+		}
+		if resp.Annotations["team"] != "ml" {
+			t.Errorf("Expected annotation team=ml, got %v", resp.Annotations)
+		}
+	})
+
+	mt.Run("rejects empty patch", func(mt *mtest.T) {
+		handler := NewTaskAPIHandler(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil)
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/tasks/task-1", strings.NewReader(`{}`))
+		rec := httptest.NewRecorder()
+		handler.HandlePatchTask(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rec.Code)
+		}
+	})
+}
+
+// TestHandleRequeueTaskCopiesSpec tests that requeuing a failed task queues a new task with the same spec
+func TestHandleRequeueTaskCopiesSpec(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	taskDoc := func(status string) bson.D {
+		return bson.D{
+			{Key: "task_id", Value: "task-1"},
+			{Key: "user_id", Value: "alice@example.com"},
+			{Key: "docker_image", Value: "trainer:latest"},
+			{Key: "command", Value: "python train.py"},
+			{Key: "req_cpu", Value: 2.0},
+			{Key: "req_memory", Value: 4.0},
+			{Key: "req_storage", Value: 10.0},
+			{Key: "req_gpu", Value: 1.0},
+			{Key: "status", Value: status},
+		}
+	}
+
+	mt.Run("failed task", func(mt *mtest.T) {
+		taskDB := db.NewTaskDBFromClient(mt.Client, "cloudai")
+		ms := server.NewMasterServer(nil, taskDB, nil, nil, nil, nil, nil)
+		handler := NewTaskAPIHandler(ms, taskDB, nil, nil)
+
+		// The original task lookup, the new ID collision check, then the insert
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, taskDoc("failed")),
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/tasks/task-1/requeue", nil)
+		rec := httptest.NewRecorder()
+		handler.HandleRequeueTask(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			TaskID         string `json:"task_id"`
+			OriginalTaskID string `json:"original_task_id"`
+			Status         string `json:"status"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.TaskID == "" || resp.TaskID == "task-1" {
+			t.Errorf("Expected a new task ID, got %q", resp.TaskID)
+		}
+		if resp.OriginalTaskID != "task-1" || resp.Status != "queued" {
+			t.Errorf("Expected a queued task linked to task-1, got %+v", resp)
+		}
+
+		queued := ms.GetQueuedTasks()
+		if len(queued) != 1 {
+			t.Fatalf("Expected 1 queued task, got %d", len(queued))
+		}
+		task := queued[0].Task
+		if task.TaskId != resp.TaskID || task.OriginalTaskId != "task-1" {
+			t.Errorf("Expected queued task %s linked to task-1, got %s linked to %q", resp.TaskID, task.TaskId, task.OriginalTaskId)
+		}
+		if task.DockerImage != "trainer:latest" || task.Command != "python train.py" || task.UserId != "alice@example.com" {
+			t.Errorf("Expected the original image, command and user, got %q %q %q", task.DockerImage, task.Command, task.UserId)
+		}
+		if task.ReqCpu != 2.0 || task.ReqMemory != 4.0 || task.ReqStorage != 10.0 || task.ReqGpu != 1.0 {
+			t.Errorf("Expected resources 2/4/10/1, got %.1f/%.1f/%.1f/%.1f", task.ReqCpu, task.ReqMemory, task.ReqStorage, task.ReqGpu)
+		}
+	})
+
+	mt.Run("running task", func(mt *mtest.T) {
+		taskDB := db.NewTaskDBFromClient(mt.Client, "cloudai")
+		ms := server.NewMasterServer(nil, taskDB, nil, nil, nil, nil, nil)
+		handler := NewTaskAPIHandler(ms, taskDB, nil, nil)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, taskDoc("running")))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/tasks/task-1/requeue", nil)
+		rec := httptest.NewRecorder()
+		handler.HandleRequeueTask(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected status 409 for a running task, got %d", rec.Code)
+		}
+		if len(ms.GetQueuedTasks()) != 0 {
+			t.Errorf("Expected nothing to be queued, got %d tasks", len(ms.GetQueuedTasks()))
+		}
+	})
+}
+
+// TestTaskLogsReturnWorkerExitCode tests that a worker result with exit code 137 is persisted and returned by the logs API
+func TestTaskLogsReturnWorkerExitCode(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("oom kill", func(mt *mtest.T) {
+		resultDB := db.NewResultDBFromClient(mt.Client, "cloudai")
+		ms := server.NewMasterServer(nil, nil, nil, resultDB, nil, nil, nil)
+		handler := NewTaskAPIHandler(ms, nil, nil, resultDB)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		ack, err := ms.ReportTaskCompletion(context.Background(), &pb.TaskResult{
+			TaskId:        "task-1",
+			WorkerId:      "worker-1",
+			Status:        "failed",
+			Logs:          "Killed",
+			FailureReason: "oom",
+			ExitCode:      137,
+			ErrorMessage:  "container exited with code 137 (oom)",
+		})
+		if err != nil || !ack.Success {
+			t.Fatalf("Expected the result to be accepted, got %v (%v)", ack, err)
+		}
+
+		// Serve the inserted document back for the logs fetch
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		var stored bson.D
+		if err := bson.Unmarshal(inserted, &stored); err != nil {
+			t.Fatalf("Failed to decode inserted result: %v", err)
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.RESULTS", mtest.FirstBatch, stored))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/tasks/task-1/logs", nil)
+		rec := httptest.NewRecorder()
+		handler.HandleGetTaskLogs(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			ExitCode     int32  `json:"exit_code"`
+			ErrorMessage string `json:"error_message"`
+			ExitSummary  string `json:"exit_summary"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.ExitCode != 137 {
+			t.Errorf("Expected exit code 137, got %d", resp.ExitCode)
+		}
+		if resp.ErrorMessage != "container exited with code 137 (oom)" {
+			t.Errorf("Expected the worker's error message, got %q", resp.ErrorMessage)
+		}
+		if resp.ExitSummary != "exited with code 137 (oom)" {
+			t.Errorf("Expected summary \"exited with code 137 (oom)\", got %q", resp.ExitSummary)
+		}
+	})
+}
+
+// TestHandleCancelTasksScopesToRequestingUser tests that batch cancel without the admin key only reaches the caller's tasks
+func TestHandleCancelTasksScopesToRequestingUser(t *testing.T) {
+	ms := server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.EnqueueTask(&pb.Task{TaskId: "task-alice", DockerImage: "bad/image:1", UserId: "alice"}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "task-bob", DockerImage: "bad/image:1", UserId: "bob"}, "test")
+	handler := NewTaskAPIHandler(ms, nil, nil, nil)
+
+	cancel := func(body string, admin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tasks/cancel", strings.NewReader(body))
+		if admin {
+			req = req.WithContext(context.WithValue(req.Context(), adminContextKey{}, true))
+		}
+		rec := httptest.NewRecorder()
+		handler.HandleCancelTasks(rec, req)
+		return rec
+	}
+
+	if rec := cancel(`{"docker_image": "bad/image:1"}`, false); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without requesting_user, got %d", rec.Code)
+	}
+	if rec := cancel(`{"user_id": "bob", "requesting_user": "alice"}`, false); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 cancelling another user's tasks, got %d", rec.Code)
+	}
+
+	rec := cancel(`{"docker_image": "bad/image:1", "requesting_user": "alice"}`, false)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		TaskIDs []string `json:"task_ids"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.TaskIDs) != 1 || resp.TaskIDs[0] != "task-alice" {
+		t.Errorf("Expected only task-alice cancelled, got %v", resp.TaskIDs)
+	}
+
+	// With the admin key any user's tasks can be matched
+	rec = cancel(`{"docker_image": "bad/image:1"}`, true)
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || len(resp.TaskIDs) != 1 || resp.TaskIDs[0] != "task-bob" {
+		t.Errorf("Expected the admin to cancel task-bob, got %d %v", rec.Code, resp.TaskIDs)
+	}
+}
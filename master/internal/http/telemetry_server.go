@@ -2,19 +2,23 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"master/internal/logging"
 	"master/internal/telemetry"
 
 	"github.com/gorilla/websocket"
 )
 
+// DefaultShutdownGracePeriod is how long Shutdown waits for in-flight HTTP requests before closing them
+const DefaultShutdownGracePeriod = 30 * time.Second
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins for simplicity (restrict in production)
@@ -38,6 +42,9 @@ type TelemetryServer struct {
 	ctx              context.Context
 	cancel           context.CancelFunc
 	quietMode        bool
+	rateLimiter      *RateLimiter  // nil disables API rate limiting
+	shutdownGrace    time.Duration // How long Shutdown lets in-flight requests finish
+	adminKey         string        // Required in X-Admin-Key by privileged endpoints ("" = not required)
 }
 
 // NewTelemetryServer creates a new HTTP server with WebSocket endpoints for telemetry streaming
@@ -53,6 +60,7 @@ func NewTelemetryServer(port int, telemetryMgr *telemetry.TelemetryManager) *Tel
 		ctx:              ctx,
 		cancel:           cancel,
 		quietMode:        true, // Enable quiet mode by default
+		shutdownGrace:    DefaultShutdownGracePeriod,
 	}
 
 	// WebSocket endpoints
@@ -67,7 +75,7 @@ func NewTelemetryServer(port int, telemetryMgr *telemetry.TelemetryManager) *Tel
 
 	ts.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
-		Handler: corsMiddleware(mux),
+		Handler: corsMiddleware(ts.rateLimitMiddleware(ts.adminKeyMiddleware(gzipMiddleware(mux)))),
 	}
 
 	// Set callback on telemetry manager to broadcast updates
@@ -82,7 +90,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000") // Vite dev server
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Admin-Key")
 		w.Header().Set("Access-Control-Allow-Credentials", "true") // Allow cookies
 
 		// Handle preflight requests
@@ -95,23 +103,58 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// SetRateLimiter enables per-client rate limiting of the API route groups; nil disables it
+func (ts *TelemetryServer) SetRateLimiter(rl *RateLimiter) {
+	ts.rateLimiter = rl
+}
+
+// rateLimitMiddleware applies the configured rate limiter, if any
+func (ts *TelemetryServer) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ts.rateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ts.rateLimiter.Middleware(next).ServeHTTP(w, r)
+	})
+}
+
 // SetQuietMode enables or disables verbose logging
 func (ts *TelemetryServer) SetQuietMode(quiet bool) {
 	ts.quietMode = quiet
 }
 
+// SetTLS serves the API over HTTPS (and WebSockets over wss) with the given configuration; nil serves plain HTTP
+func (ts *TelemetryServer) SetTLS(config *tls.Config) {
+	ts.server.TLSConfig = config
+}
+
 // Start starts the HTTP server with WebSocket support
 func (ts *TelemetryServer) Start() error {
-	log.Printf("Starting WebSocket telemetry server on %s", ts.server.Addr)
-	log.Printf("WebSocket endpoints:")
-	log.Printf("  - ws://localhost%s/ws/telemetry (all workers)", ts.server.Addr)
-	log.Printf("  - ws://localhost%s/ws/telemetry/{worker_id} (specific worker)", ts.server.Addr)
+	scheme := "ws"
+	if ts.server.TLSConfig != nil {
+		scheme = "wss"
+	}
+	logging.Infof("Starting WebSocket telemetry server on %s", ts.server.Addr)
+	logging.Infof("WebSocket endpoints:")
+	logging.Debugf("  - %s://localhost%s/ws/telemetry (all workers)", scheme, ts.server.Addr)
+	logging.Debugf("  - %s://localhost%s/ws/telemetry/{worker_id} (specific worker)", scheme, ts.server.Addr)
+	if ts.server.TLSConfig != nil {
+		// The certificate is already in TLSConfig
+		return ts.server.ListenAndServeTLS("", "")
+	}
 	return ts.server.ListenAndServe()
 }
 
+// SetShutdownGracePeriod sets how long Shutdown waits for in-flight requests (such as file downloads) to complete
+func (ts *TelemetryServer) SetShutdownGracePeriod(grace time.Duration) {
+	ts.shutdownGrace = grace
+}
+
 // Shutdown gracefully shuts down the server
+// WebSocket clients are closed right away; other in-flight requests get the grace period to complete before they are cut off
 func (ts *TelemetryServer) Shutdown() error {
-	log.Println("Shutting down WebSocket telemetry server...")
+	logging.Info("Shutting down WebSocket telemetry server...")
 	ts.cancel()
 
 	// Close all WebSocket connections
@@ -123,13 +166,19 @@ func (ts *TelemetryServer) Shutdown() error {
 	ts.clients = make(map[*WSClient]bool)
 	ts.clientsMu.Unlock()
 
-	return ts.server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), ts.shutdownGrace)
+	defer cancel()
+	if err := ts.server.Shutdown(ctx); err != nil {
+		logging.Warnf("⚠️  HTTP requests still in flight after %s, closing them: %v", ts.shutdownGrace, err)
+		return ts.server.Close()
+	}
+	return nil
 }
 
 // handleHealth returns a simple health check
 func (ts *TelemetryServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -139,6 +188,8 @@ func (ts *TelemetryServer) handleHealth(w http.ResponseWriter, r *http.Request)
 		"active_clients": ts.getClientCount(),
 		"workers":        ts.telemetryManager.GetWorkerCount(),
 		"active_workers": ts.telemetryManager.GetActiveWorkerCount(),
+
+		"dropped_heartbeats": ts.telemetryManager.TotalDroppedSamples(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -154,7 +205,7 @@ func (ts *TelemetryServer) handleTelemetryREST(w http.ResponseWriter, r *http.Re
 	}
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -178,13 +229,13 @@ func (ts *TelemetryServer) handleWorkerTelemetryREST(w http.ResponseWriter, r *h
 	}
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	workerTelemetry, exists := ts.telemetryManager.GetWorkerTelemetry(workerID)
 	if !exists {
-		http.Error(w, fmt.Sprintf("Worker %s not found", workerID), http.StatusNotFound)
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Worker %s not found", workerID))
 		return
 	}
 
@@ -196,7 +247,7 @@ func (ts *TelemetryServer) handleWorkerTelemetryREST(w http.ResponseWriter, r *h
 // handleWorkersREST returns basic info for all workers (REST endpoint)
 func (ts *TelemetryServer) handleWorkersREST(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -220,7 +271,7 @@ func (ts *TelemetryServer) handleWorkersREST(w http.ResponseWriter, r *http.Requ
 func (ts *TelemetryServer) handleAllWorkersWS(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		logging.Errorf("WebSocket upgrade error: %v", err)
 		return
 	}
 
@@ -234,7 +285,7 @@ func (ts *TelemetryServer) handleAllWorkersWS(w http.ResponseWriter, r *http.Req
 	defer ts.unregisterClient(client)
 
 	if !ts.quietMode {
-		log.Printf("WebSocket client connected (all workers)")
+		logging.Infof("WebSocket client connected (all workers)")
 	}
 
 	// Send initial telemetry data
@@ -257,13 +308,13 @@ func (ts *TelemetryServer) handleWorkerTelemetryWS(w http.ResponseWriter, r *htt
 	// Extract worker ID from path
 	workerID := strings.TrimPrefix(r.URL.Path, "/ws/telemetry/")
 	if workerID == "" {
-		http.Error(w, "Worker ID required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Worker ID required")
 		return
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		logging.Errorf("WebSocket upgrade error: %v", err)
 		return
 	}
 
@@ -277,7 +328,7 @@ func (ts *TelemetryServer) handleWorkerTelemetryWS(w http.ResponseWriter, r *htt
 	defer ts.unregisterClient(client)
 
 	if !ts.quietMode {
-		log.Printf("WebSocket client connected (worker: %s)", workerID)
+		logging.Infof("WebSocket client connected (worker: %s)", workerID)
 	}
 
 	// Send initial telemetry data for this worker
@@ -341,7 +392,7 @@ func convertTelemetryToJSON(data *telemetry.WorkerTelemetryData) map[string]inte
 func (ts *TelemetryServer) sendTelemetryToClient(client *WSClient, data map[string]interface{}) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		log.Printf("Error marshaling telemetry: %v", err)
+		logging.Errorf("Error marshaling telemetry: %v", err)
 		return
 	}
 
@@ -370,7 +421,7 @@ func (ts *TelemetryServer) readPump(client *WSClient) {
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				if !ts.quietMode {
-					log.Printf("WebSocket error: %v", err)
+					logging.Errorf("WebSocket error: %v", err)
 				}
 			}
 			break
@@ -427,7 +478,7 @@ func (ts *TelemetryServer) unregisterClient(client *WSClient) {
 		delete(ts.clients, client)
 		close(client.send)
 		if !ts.quietMode {
-			log.Printf("WebSocket client disconnected")
+			logging.Infof("WebSocket client disconnected")
 		}
 	}
 }
@@ -448,7 +499,7 @@ func (ts *TelemetryServer) RegisterTaskHandlers(handler *TaskAPIHandler) {
 		case http.MethodGet:
 			handler.HandleListTasks(w, r)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		}
 	})
 
@@ -456,18 +507,26 @@ func (ts *TelemetryServer) RegisterTaskHandlers(handler *TaskAPIHandler) {
 	ts.mux.HandleFunc("/ws/tasks/", handler.HandleTaskLogsStream)
 
 	ts.mux.HandleFunc("/api/tasks/", func(w http.ResponseWriter, r *http.Request) {
-		// Check if this is a /logs or /retry request
-		if strings.Contains(r.URL.Path, "/logs") {
+		// Check if this is a /cancel, /by-ref, /logs or /requeue request
+		if r.URL.Path == "/api/tasks/cancel" {
+			handler.HandleCancelTasks(w, r)
+		} else if strings.HasPrefix(r.URL.Path, "/api/tasks/by-ref/") {
+			handler.HandleCancelByExternalRef(w, r)
+		} else if strings.Contains(r.URL.Path, "/logs") {
 			handler.HandleGetTaskLogs(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/requeue") {
+			handler.HandleRequeueTask(w, r)
 		} else {
-			// Handle GET /api/tasks/{id} or DELETE /api/tasks/{id}
+			// Handle GET, PATCH or DELETE /api/tasks/{id}
 			switch r.Method {
 			case http.MethodGet:
 				handler.HandleGetTask(w, r)
+			case http.MethodPatch:
+				handler.HandlePatchTask(w, r)
 			case http.MethodDelete:
 				handler.HandleDeleteTask(w, r)
 			default:
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 			}
 		}
 	})
@@ -483,9 +542,17 @@ func (ts *TelemetryServer) RegisterWorkerHandlers(handler *WorkerAPIHandler) {
 		}
 	})
 	ts.mux.HandleFunc("/api/workers/", func(w http.ResponseWriter, r *http.Request) {
-		// Check if this is a /metrics request
+		// Check if this is a /metrics, /timeseries, /expire or /maintenance request
 		if strings.Contains(r.URL.Path, "/metrics") {
 			handler.HandleGetWorkerMetrics(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/timeseries") {
+			handler.HandleGetWorkerTimeseries(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/expire") {
+			handler.HandleExpireWorker(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/maintenance") {
+			handler.HandleWorkerMaintenance(w, r)
+		} else if r.Method == http.MethodPatch {
+			handler.HandlePatchWorker(w, r)
 		} else {
 			handler.HandleGetWorker(w, r)
 		}
@@ -499,15 +566,17 @@ func (ts *TelemetryServer) RegisterFileHandlers(handler *FileAPIHandler) {
 		if r.Method == http.MethodGet {
 			handler.HandleListFiles(w, r)
 		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		}
 	})
 
-	// Handle specific file operations: get task files, download file, delete files
+	// Handle specific file operations: get task files, file manifest, download file, delete files
 	ts.mux.HandleFunc("/api/files/", func(w http.ResponseWriter, r *http.Request) {
 		// Check if this is a download request: /api/files/{task_id}/download/{file_path}
 		if strings.Contains(r.URL.Path, "/download") {
 			handler.HandleDownloadFile(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/manifest") {
+			handler.HandleGetTaskManifest(w, r)
 		} else {
 			// /api/files/{task_id} - get task files or delete task files
 			switch r.Method {
@@ -516,12 +585,45 @@ func (ts *TelemetryServer) RegisterFileHandlers(handler *FileAPIHandler) {
 			case http.MethodDelete:
 				handler.HandleDeleteTaskFiles(w, r)
 			default:
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 			}
 		}
 	})
 }
 
+// RegisterAdminHandlers registers cluster administration API handlers
+func (ts *TelemetryServer) RegisterAdminHandlers(handler *AdminAPIHandler) {
+	ts.mux.HandleFunc("/api/admin/reconcile", handler.HandleReconcile)
+}
+
+// RegisterSchedulerHandlers registers live scheduler tuning API handlers
+func (ts *TelemetryServer) RegisterSchedulerHandlers(handler *SchedulerAPIHandler) {
+	ts.mux.HandleFunc("/api/scheduler/risk", handler.HandleRisk)
+}
+
+// RegisterCapacityHandlers registers capacity planning API handlers
+func (ts *TelemetryServer) RegisterCapacityHandlers(handler *CapacityAPIHandler) {
+	ts.mux.HandleFunc("/api/capacity", handler.HandleCapacity)
+	ts.mux.HandleFunc("/api/autoscale", handler.HandleAutoscale)
+	ts.mux.HandleFunc("/api/reservations", handler.HandleCreateReservation)
+	ts.mux.HandleFunc("/api/reservations/", handler.HandleReservation)
+}
+
+// RegisterUsageHandlers registers per-user usage report API handlers
+func (ts *TelemetryServer) RegisterUsageHandlers(handler *UsageAPIHandler) {
+	ts.mux.HandleFunc("/api/usage", handler.HandleUsage)
+}
+
+// RegisterMetricsHandlers registers the Prometheus scrape endpoint
+func (ts *TelemetryServer) RegisterMetricsHandlers(handler *MetricsHandler) {
+	ts.mux.HandleFunc("/metrics", handler.HandleMetrics)
+}
+
+// RegisterEventHandlers registers the cluster event stream
+func (ts *TelemetryServer) RegisterEventHandlers(handler *EventsAPIHandler) {
+	ts.mux.HandleFunc("/api/events/stream", handler.HandleEventStream)
+}
+
 // RegisterAuthHandlers registers authentication API handlers
 func (ts *TelemetryServer) RegisterAuthHandlers(handler *AuthHandler) {
 	// Public endpoints (no auth required)
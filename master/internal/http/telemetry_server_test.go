@@ -0,0 +1,59 @@
+package http
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"master/internal/telemetry"
+)
+
+// TestShutdownDrainsInFlightRequests tests that a slow request already in flight completes during shutdown instead of being cut off
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	ts := NewTelemetryServer(0, telemetry.NewTelemetryManager(30*time.Second))
+	ts.SetShutdownGracePeriod(5 * time.Second)
+
+	started := make(chan struct{})
+	ts.mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go ts.server.Serve(lis)
+
+	type result struct {
+		body string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + lis.Addr().String() + "/slow")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	if err := ts.Shutdown(); err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+
+	res := <-results
+	if res.err != nil {
+		t.Fatalf("Expected the in-flight request to complete, got %v", res.err)
+	}
+	if res.body != "done" {
+		t.Errorf("Expected body %q, got %q", "done", res.body)
+	}
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"master/internal/db"
+)
+
+// DefaultUsageWindow is the period a usage report covers when since is not given
+const DefaultUsageWindow = 30 * 24 * time.Hour
+
+// UsageHistorySource provides the finished task history usage is totalled from (implemented by db.HistoryDB)
+type UsageHistorySource interface {
+	GetTaskHistory(ctx context.Context, since time.Time, until time.Time) ([]db.TaskHistory, error)
+}
+
+// UsageAPIHandler handles HTTP REST API requests for per-user usage reports
+type UsageAPIHandler struct {
+	history     UsageHistorySource
+	requestUser func(r *http.Request) string // Identifies the signed-in caller ("" = nobody)
+}
+
+// NewUsageAPIHandler creates a new usage API handler
+func NewUsageAPIHandler(history UsageHistorySource) *UsageAPIHandler {
+	return &UsageAPIHandler{history: history}
+}
+
+// SetRequestUser sets how the signed-in caller is identified; callers without the admin key see only their own usage
+func (h *UsageAPIHandler) SetRequestUser(requestUser func(r *http.Request) string) {
+	h.requestUser = requestUser
+}
+
+// UsageResponse is a usage report: one entry per user, or only the requested user
+type UsageResponse struct {
+	Since time.Time      `json:"since"`
+	Until time.Time      `json:"until"`
+	User  string         `json:"user,omitempty"`
+	Users []db.UserUsage `json:"users"`
+	Count int            `json:"count"`
+}
+
+// HandleUsage handles GET /api/usage?user=&since=&until=
+// Totals the CPU-, memory- and GPU-seconds, task counts and SLA success of tasks finished in the period
+// since and until are RFC3339 timestamps (defaults: the last 30 days up to now)
+// Only requests with the admin key may read every user's usage or pick a user; others get their own
+func (h *UsageAPIHandler) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	user := query.Get("user")
+	if !isAdminRequest(r) {
+		caller := ""
+		if h.requestUser != nil {
+			caller = h.requestUser(r)
+		}
+		if caller == "" {
+			writeJSONError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Sign in to see your usage (or send the admin key to see every user's)")
+			return
+		}
+		if user != "" && user != caller {
+			writeJSONError(w, http.StatusForbidden, ErrCodeForbidden, "Only your own usage can be read without the admin key")
+			return
+		}
+		user = caller
+	}
+
+	until := time.Now()
+	if raw := query.Get("until"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid until %q: expected RFC3339", raw))
+			return
+		}
+		until = t
+	}
+	since := until.Add(-DefaultUsageWindow)
+	if raw := query.Get("since"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid since %q: expected RFC3339", raw))
+			return
+		}
+		since = t
+	}
+	if !since.Before(until) {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "since must be before until")
+		return
+	}
+
+	history, err := h.history.GetTaskHistory(r.Context(), since, until)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to load task history: %v", err))
+		return
+	}
+
+	users := db.AggregateUserUsage(history, user)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UsageResponse{Since: since, Until: until, User: user, Users: users, Count: len(users)})
+}
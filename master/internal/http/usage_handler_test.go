@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"master/internal/db"
+)
+
+// fakeUsageHistory returns seeded history rows finished within the requested period
+type fakeUsageHistory struct {
+	rows []db.TaskHistory
+}
+
+func (f *fakeUsageHistory) GetTaskHistory(ctx context.Context, since time.Time, until time.Time) ([]db.TaskHistory, error) {
+	var rows []db.TaskHistory
+	for _, row := range f.rows {
+		if !row.ActualFinish.Before(since) && !row.ActualFinish.After(until) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// TestHandleUsageTotalsPerUser tests that the usage report totals CPU-seconds, tasks and SLA success per user
+func TestHandleUsageTotalsPerUser(t *testing.T) {
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	handler := NewUsageAPIHandler(&fakeUsageHistory{rows: []db.TaskHistory{
+		{TaskID: "task-1", UserID: "alice", CPUUsed: 2.0, GPUUsed: 1.0, ActualRuntime: 100, SLASuccess: true, ActualFinish: day},
+		{TaskID: "task-2", UserID: "alice", CPUUsed: 0.5, ActualRuntime: 40, SLASuccess: false, ActualFinish: day},
+		{TaskID: "task-3", UserID: "bob", CPUUsed: 4.0, MemUsed: 2.0, ActualRuntime: 10, SLASuccess: true, ActualFinish: day},
+		// Outside the requested period
+		{TaskID: "task-4", UserID: "bob", CPUUsed: 8.0, ActualRuntime: 1000, SLASuccess: true, ActualFinish: day.AddDate(0, -2, 0)},
+	}})
+
+	query := func(params string) UsageResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/usage"+params, nil)
+		req = req.WithContext(context.WithValue(req.Context(), adminContextKey{}, true))
+		rec := httptest.NewRecorder()
+		handler.HandleUsage(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %q, got %d: %s", params, rec.Code, rec.Body.String())
+		}
+		var resp UsageResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	all := query("?since=2026-02-15T00:00:00Z&until=2026-03-15T00:00:00Z")
+	if all.Count != 2 || len(all.Users) != 2 {
+		t.Fatalf("Expected 2 users, got %+v", all.Users)
+	}
+	alice, bob := all.Users[0], all.Users[1]
+	if alice.UserID != "alice" || alice.CPUSeconds != 220 || alice.GPUSeconds != 100 || alice.Tasks != 2 {
+		t.Errorf("Expected alice with 220 CPU-seconds, 100 GPU-seconds and 2 tasks, got %+v", alice)
+	}
+	if alice.SLASuccessRate != 0.5 {
+		t.Errorf("Expected alice's SLA success rate 0.5, got %v", alice.SLASuccessRate)
+	}
+	if bob.UserID != "bob" || bob.CPUSeconds != 40 || bob.MemoryGBSeconds != 20 || bob.Tasks != 1 {
+		t.Errorf("Expected bob with 40 CPU-seconds, 20 memory-GB-seconds and 1 task, got %+v", bob)
+	}
+
+	one := query("?user=bob&since=2026-01-01T00:00:00Z&until=2026-03-15T00:00:00Z")
+	if one.User != "bob" || len(one.Users) != 1 || one.Users[0].CPUSeconds != 8040 {
+		t.Errorf("Expected only bob with 8040 CPU-seconds, got %+v", one.Users)
+	}
+
+	// A user without tasks is still reported, with zero usage
+	none := query("?user=carol&since=2026-02-15T00:00:00Z&until=2026-03-15T00:00:00Z")
+	if len(none.Users) != 1 || none.Users[0].Tasks != 0 || none.Users[0].SLASuccessRate != 1.0 {
+		t.Errorf("Expected carol with no tasks, got %+v", none.Users)
+	}
+
+	for _, params := range []string{"?since=yesterday", "?since=2026-03-15T00:00:00Z&until=2026-03-01T00:00:00Z"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/usage"+params, nil)
+		req = req.WithContext(context.WithValue(req.Context(), adminContextKey{}, true))
+		rec := httptest.NewRecorder()
+		handler.HandleUsage(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", params, rec.Code)
+		}
+	}
+}
+
+// TestHandleUsageScopesToCaller tests that without the admin key a caller sees only their own usage
+func TestHandleUsageScopesToCaller(t *testing.T) {
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	handler := NewUsageAPIHandler(&fakeUsageHistory{rows: []db.TaskHistory{
+		{TaskID: "task-1", UserID: "alice", CPUUsed: 2.0, ActualRuntime: 100, SLASuccess: true, ActualFinish: day},
+		{TaskID: "task-2", UserID: "bob", CPUUsed: 4.0, ActualRuntime: 10, SLASuccess: true, ActualFinish: day},
+	}})
+	const period = "since=2026-02-15T00:00:00Z&until=2026-03-15T00:00:00Z"
+
+	// Without a way to identify callers, only admins can read usage
+	req := httptest.NewRequest(http.MethodGet, "/api/usage?"+period, nil)
+	rec := httptest.NewRecorder()
+	handler.HandleUsage(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an anonymous caller, got %d", rec.Code)
+	}
+
+	handler.SetRequestUser(func(r *http.Request) string { return r.Header.Get("X-Test-User") })
+	get := func(user, params string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/usage?"+params, nil)
+		if user != "" {
+			req.Header.Set("X-Test-User", user)
+		}
+		rec := httptest.NewRecorder()
+		handler.HandleUsage(rec, req)
+		return rec
+	}
+
+	if rec := get("", period); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a caller who is not signed in, got %d", rec.Code)
+	}
+	if rec := get("alice", "user=bob&"+period); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for another user's usage, got %d", rec.Code)
+	}
+
+	for _, params := range []string{period, "user=alice&" + period} {
+		rec := get("alice", params)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %q, got %d: %s", params, rec.Code, rec.Body.String())
+		}
+		var resp UsageResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(resp.Users) != 1 || resp.Users[0].UserID != "alice" || resp.Users[0].CPUSeconds != 200 {
+			t.Errorf("Expected only alice with 200 CPU-seconds for %q, got %+v", params, resp.Users)
+		}
+	}
+}
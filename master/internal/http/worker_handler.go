@@ -3,8 +3,10 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,7 +38,7 @@ func NewWorkerAPIHandler(ms *server.MasterServer, workerDB *db.WorkerDB, assignm
 // HandleListWorkers handles GET /api/workers
 func (h *WorkerAPIHandler) HandleListWorkers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -93,6 +95,8 @@ func (h *WorkerAPIHandler) HandleListWorkers(w http.ResponseWriter, r *http.Requ
 			"memory_usage":        0.0,
 			"gpu_usage":           0.0,
 			"running_tasks_count": 0,
+			"display_name":        dbWorker.DisplayName,
+			"annotations":         dbWorker.Annotations,
 		}
 	}
 
@@ -150,14 +154,14 @@ func (h *WorkerAPIHandler) HandleListWorkers(w http.ResponseWriter, r *http.Requ
 // HandleGetWorker handles GET /api/workers/:id
 func (h *WorkerAPIHandler) HandleGetWorker(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Extract worker ID from path
 	workerID := strings.TrimPrefix(r.URL.Path, "/api/workers/")
 	if workerID == "" || workerID == "api/workers" {
-		http.Error(w, "Worker ID required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Worker ID required")
 		return
 	}
 
@@ -174,7 +178,7 @@ func (h *WorkerAPIHandler) HandleGetWorker(w http.ResponseWriter, r *http.Reques
 	// Get telemetry data
 	telemetryData, exists := h.telemetryManager.GetWorkerTelemetry(actualWorkerID)
 	if !exists {
-		http.Error(w, fmt.Sprintf("Worker %s not found", actualWorkerID), http.StatusNotFound)
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Worker %s not found", actualWorkerID))
 		return
 	}
 
@@ -192,6 +196,10 @@ func (h *WorkerAPIHandler) HandleGetWorker(w http.ResponseWriter, r *http.Reques
 				"total_gpu":      worker.TotalGPU,
 				"registered_at":  worker.RegisteredAt.Unix(),
 				"last_heartbeat": worker.LastHeartbeat,
+				"display_name":   worker.DisplayName,
+				"annotations":    worker.Annotations,
+				"maintenance":    maintenanceJSON(worker.Maintenance),
+				"declared":       declaredCapacityJSON(worker.Declared),
 			}
 		}
 	}
@@ -217,16 +225,185 @@ func (h *WorkerAPIHandler) HandleGetWorker(w http.ResponseWriter, r *http.Reques
 		"running_tasks": runningTasks,
 		"last_update":   telemetryData.LastUpdate,
 		"worker_info":   workerInfo,
+		"cordoned":      h.masterServer.IsWorkerCordoned(actualWorkerID),
+	}
+	if warning := h.masterServer.WorkerCapacityWarning(actualWorkerID); warning != "" {
+		response["capacity_warning"] = warning
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandlePatchWorker handles PATCH /api/workers/:id (display name and annotations)
+func (h *WorkerAPIHandler) HandlePatchWorker(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	workerID := strings.TrimPrefix(r.URL.Path, "/api/workers/")
+	if workerID == "" || strings.Contains(workerID, "/") {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Worker ID required")
+		return
+	}
+
+	req, err := decodeLabelsRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.masterServer.SetWorkerLabels(context.Background(), workerID, req.DisplayName, req.Annotations); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to update worker: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"success":   true,
+		"worker_id": workerID,
+		"message":   "Worker labels updated",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleExpireWorker handles POST /api/workers/:id/expire
+// Marks the worker inactive immediately; pass ?requeue=true to reschedule its running tasks
+func (h *WorkerAPIHandler) HandleExpireWorker(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	workerID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/workers/"), "/expire")
+	if workerID == "" || strings.Contains(workerID, "/") {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Worker ID required")
+		return
+	}
+	requeue := r.URL.Query().Get("requeue") == "true"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	expiry, err := h.masterServer.ExpireWorker(ctx, workerID, requeue)
+	if errors.Is(err, server.ErrWorkerNotFound) {
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "DATABASE_ERROR", err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"success":        true,
+		"worker_id":      expiry.WorkerID,
+		"requeued_tasks": expiry.RequeuedTasks,
+		"skipped_tasks":  expiry.SkippedTasks,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// MaintenanceRequest is the body of PUT /api/workers/:id/maintenance
+type MaintenanceRequest struct {
+	Cron     string `json:"cron"`     // e.g. "0 2 * * 6" for Saturdays at 02:00 (master local time)
+	Duration string `json:"duration"` // e.g. "2h"
+}
+
+// HandleWorkerMaintenance handles PUT and DELETE /api/workers/:id/maintenance
+// PUT sets a recurring maintenance window during which the worker is cordoned; DELETE removes it
+func (h *WorkerAPIHandler) HandleWorkerMaintenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	workerID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/workers/"), "/maintenance")
+	if workerID == "" || strings.Contains(workerID, "/") {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Worker ID required")
+		return
+	}
+
+	var window *db.MaintenanceWindow
+	if r.Method == http.MethodPut {
+		var req MaintenanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+			return
+		}
+		defer r.Body.Close()
+
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid duration %q", req.Duration))
+			return
+		}
+		window = &db.MaintenanceWindow{Cron: req.Cron, Duration: duration}
+		if err := server.ValidateMaintenanceWindow(window); err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.masterServer.SetWorkerMaintenance(ctx, workerID, window); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to update maintenance window: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"success":     true,
+		"worker_id":   workerID,
+		"maintenance": maintenanceJSON(window),
+		"cordoned":    h.masterServer.IsWorkerCordoned(workerID),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// declaredCapacityJSON formats a worker's declared capacity for API responses (nil when none was declared)
+func declaredCapacityJSON(declared *db.DeclaredCapacity) map[string]interface{} {
+	if declared == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"cpu":    declared.CPU,
+		"memory": declared.Memory,
+		"gpu":    declared.GPU,
+	}
+}
+
+// maintenanceJSON formats a maintenance window for API responses (nil when none is set)
+func maintenanceJSON(window *db.MaintenanceWindow) map[string]interface{} {
+	if window == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"cron":     window.Cron,
+		"duration": window.Duration.String(),
+	}
+}
+
 // HandleGetWorkerTasks handles GET /api/workers/:id/tasks
 func (h *WorkerAPIHandler) HandleGetWorkerTasks(w http.ResponseWriter, r *http.Request, workerID string) {
 	if h.assignmentDB == nil {
-		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Database not available")
 		return
 	}
 
@@ -235,7 +412,7 @@ func (h *WorkerAPIHandler) HandleGetWorkerTasks(w http.ResponseWriter, r *http.R
 	// Get all assignments for this worker
 	assignments, err := h.assignmentDB.GetAssignmentsByWorker(ctx, workerID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get worker tasks: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to get worker tasks: %v", err))
 		return
 	}
 
@@ -260,14 +437,14 @@ func (h *WorkerAPIHandler) HandleGetWorkerTasks(w http.ResponseWriter, r *http.R
 // HandleGetWorkerMetrics handles GET /api/workers/:id/metrics
 func (h *WorkerAPIHandler) HandleGetWorkerMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Extract worker ID from path - format is /api/workers/{id}/metrics
 	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/workers/"), "/")
 	if len(pathParts) < 2 {
-		http.Error(w, "Worker ID required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Worker ID required")
 		return
 	}
 	workerID := pathParts[0]
@@ -275,7 +452,7 @@ func (h *WorkerAPIHandler) HandleGetWorkerMetrics(w http.ResponseWriter, r *http
 	// Get telemetry data
 	telemetryData, exists := h.telemetryManager.GetWorkerTelemetry(workerID)
 	if !exists {
-		http.Error(w, fmt.Sprintf("Worker %s not found", workerID), http.StatusNotFound)
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Worker %s not found", workerID))
 		return
 	}
 
@@ -293,16 +470,85 @@ func (h *WorkerAPIHandler) HandleGetWorkerMetrics(w http.ResponseWriter, r *http
 	json.NewEncoder(w).Encode(response)
 }
 
+// DefaultTimeseriesPoints is the number of samples returned when ?points is omitted
+const DefaultTimeseriesPoints = 60
+
+// timeseriesMetrics maps the accepted ?metric values to the sample field they read
+var timeseriesMetrics = map[string]func(telemetry.TelemetrySample) float64{
+	"cpu":    func(s telemetry.TelemetrySample) float64 { return s.CpuUsage },
+	"memory": func(s telemetry.TelemetrySample) float64 { return s.MemoryUsage },
+	"gpu":    func(s telemetry.TelemetrySample) float64 { return s.GpuUsage },
+}
+
+// HandleGetWorkerTimeseries handles GET /api/workers/:id/timeseries?metric=cpu&points=60
+// Returns the last N samples of one metric, oldest first, for charting
+func (h *WorkerAPIHandler) HandleGetWorkerTimeseries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	workerID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/workers/"), "/timeseries")
+	if workerID == "" || strings.Contains(workerID, "/") {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "Worker ID required")
+		return
+	}
+
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		metric = "cpu"
+	}
+	valueOf, ok := timeseriesMetrics[metric]
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid metric %q (must be cpu, memory or gpu)", metric))
+		return
+	}
+
+	points := DefaultTimeseriesPoints
+	if value := r.URL.Query().Get("points"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "points must be a positive integer")
+			return
+		}
+		points = parsed
+	}
+
+	samples, exists := h.telemetryManager.GetWorkerHistory(workerID, points)
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("Worker %s not found", workerID))
+		return
+	}
+
+	series := make([]map[string]interface{}, 0, len(samples))
+	for _, sample := range samples {
+		series = append(series, map[string]interface{}{
+			"timestamp": sample.Timestamp,
+			"value":     valueOf(sample),
+		})
+	}
+
+	response := map[string]interface{}{
+		"worker_id": workerID,
+		"metric":    metric,
+		"points":    series,
+		"count":     len(series),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // HandleRegisterWorker handles POST /api/workers - Manual worker registration
 func (h *WorkerAPIHandler) HandleRegisterWorker(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// Check if workerDB is available
 	if h.workerDB == nil {
-		http.Error(w, "Worker registration is not available (database not connected)", http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Worker registration is not available (database not connected)")
 		return
 	}
 
@@ -310,34 +556,48 @@ func (h *WorkerAPIHandler) HandleRegisterWorker(w http.ResponseWriter, r *http.R
 	var req struct {
 		WorkerID string `json:"worker_id"`
 		WorkerIP string `json:"worker_ip"`
+		// Optional capacity the worker is expected to have, checked when it connects
+		DeclaredCPU    float64 `json:"declared_cpu,omitempty"`
+		DeclaredMemory float64 `json:"declared_memory,omitempty"`
+		DeclaredGPU    float64 `json:"declared_gpu,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid request body: %v", err))
 		return
 	}
 
 	// Validate required fields
 	if req.WorkerID == "" {
-		http.Error(w, "worker_id is required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "worker_id is required")
 		return
 	}
 	if req.WorkerIP == "" {
-		http.Error(w, "worker_ip is required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "worker_ip is required")
 		return
 	}
 
+	if req.DeclaredCPU < 0 || req.DeclaredMemory < 0 || req.DeclaredGPU < 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrCodeBadRequest, "declared capacity cannot be negative")
+		return
+	}
+	var declared *db.DeclaredCapacity
+	if req.DeclaredCPU > 0 || req.DeclaredMemory > 0 || req.DeclaredGPU > 0 {
+		declared = &db.DeclaredCapacity{CPU: req.DeclaredCPU, Memory: req.DeclaredMemory, GPU: req.DeclaredGPU}
+	}
+
 	// Get master info to send to worker
 	masterID, masterAddress := h.masterServer.GetMasterInfo()
 	if masterID == "" || masterAddress == "" {
-		http.Error(w, "Master info not set. Cannot register worker.", http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, "Master info not set. Cannot register worker.")
 		return
 	}
 
 	// Register worker and notify it - this will trigger the worker to connect back with its resources
 	ctx := context.Background()
-	if err := h.masterServer.ManualRegisterAndNotify(ctx, req.WorkerID, req.WorkerIP, masterID, masterAddress); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to register worker: %v", err), http.StatusInternalServerError)
+	warning, err := h.masterServer.ManualRegisterAndNotify(ctx, req.WorkerID, req.WorkerIP, declared, masterID, masterAddress)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to register worker: %v", err))
 		return
 	}
 
@@ -350,8 +610,13 @@ func (h *WorkerAPIHandler) HandleRegisterWorker(w http.ResponseWriter, r *http.R
 			"worker_id": req.WorkerID,
 			"worker_ip": req.WorkerIP,
 			"is_active": false, // Will become active when worker connects
+			"reachable": warning == "",
+			"declared":  declaredCapacityJSON(declared),
 		},
 	}
+	if warning != "" {
+		response["warning"] = warning
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
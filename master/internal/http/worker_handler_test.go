@@ -0,0 +1,231 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	"master/internal/server"
+	"master/internal/telemetry"
+	pb "master/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"google.golang.org/grpc"
+)
+
+// cancelRecordingWorker is a stand-in worker that records the tasks it is asked to stop
+type cancelRecordingWorker struct {
+	pb.UnimplementedMasterWorkerServer
+	mu        sync.Mutex
+	cancelled []string
+}
+
+func (w *cancelRecordingWorker) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.TaskAck, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cancelled = append(w.cancelled, taskID.TaskId)
+	return &pb.TaskAck{Success: true, Message: "stopped"}, nil
+}
+
+// TestHandleExpireWorker tests that POST /api/workers/{id}/expire deactivates a heartbeating worker at once
+func TestHandleExpireWorker(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("expires and requeues running tasks", func(mt *mtest.T) {
+		telemetryMgr := telemetry.NewTelemetryManager(30 * time.Second)
+		defer telemetryMgr.Shutdown()
+
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		stub := &cancelRecordingWorker{}
+		grpcServer := grpc.NewServer()
+		pb.RegisterMasterWorkerServer(grpcServer, stub)
+		go grpcServer.Serve(lis)
+		defer grpcServer.Stop()
+
+		taskDB := db.NewTaskDBFromClient(mt.Client, "cloudai")
+		ms := server.NewMasterServer(nil, taskDB, nil, nil, nil, nil, telemetryMgr)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+		telemetryMgr.RegisterWorker("worker-1")
+
+		// A recent heartbeat keeps the worker active until the staleness sweep
+		if _, err := ms.SendHeartbeat(context.Background(), &pb.Heartbeat{WorkerId: "worker-1"}); err != nil {
+			t.Fatalf("Failed to send heartbeat: %v", err)
+		}
+		// Let the telemetry thread take the heartbeat, so it cannot land after the worker is unregistered
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			if data, ok := telemetryMgr.GetWorkerTelemetry("worker-1"); ok && data.IsActive {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Expected the heartbeat to reach the telemetry manager")
+			}
+		}
+
+		worker, _ := ms.GetWorkerStats("worker-1")
+		worker.RunningTasks = map[string]bool{"task-1": true}
+		worker.AllocatedCPU = 1.0
+		worker.AvailableCPU = 3.0
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, bson.D{
+				{Key: "task_id", Value: "task-1"},
+				{Key: "docker_image", Value: "alpine:latest"},
+				{Key: "status", Value: "running"},
+				{Key: "req_cpu", Value: 1.0},
+				{Key: "req_memory", Value: 0.0},
+				{Key: "req_storage", Value: 0.0},
+				{Key: "req_gpu", Value: 0.0},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		handler := NewWorkerAPIHandler(ms, nil, nil, telemetryMgr)
+		req := httptest.NewRequest(http.MethodPost, "/api/workers/worker-1/expire?requeue=true", nil)
+		rec := httptest.NewRecorder()
+		handler.HandleExpireWorker(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp struct {
+			RequeuedTasks []string `json:"requeued_tasks"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(resp.RequeuedTasks) != 1 || resp.RequeuedTasks[0] != "task-1" {
+			t.Errorf("Expected task-1 to be requeued, got %v", resp.RequeuedTasks)
+		}
+		if len(stub.cancelled) != 1 || stub.cancelled[0] != "task-1" {
+			t.Errorf("Expected task-1 to be stopped on the expired worker before requeueing, got %v", stub.cancelled)
+		}
+
+		for _, w := range ms.GetClusterSnapshot().Workers {
+			if w.WorkerID == "worker-1" && w.Status != "inactive" {
+				t.Errorf("Expected worker-1 to be inactive in the cluster snapshot, got %s", w.Status)
+			}
+		}
+		if _, exists := telemetryMgr.GetWorkerTelemetry("worker-1"); exists {
+			t.Error("Expected worker-1 to be unregistered from the telemetry manager")
+		}
+
+		queued := ms.GetQueuedTasks()
+		if len(queued) != 1 || queued[0].Task.TaskId != "task-1" {
+			t.Fatalf("Expected task-1 on the queue, got %d queued task(s)", len(queued))
+		}
+		if worker.AllocatedCPU != 0.0 || len(worker.RunningTasks) != 0 {
+			t.Errorf("Expected expired worker's allocation released, got CPU %.1f with %d task(s)",
+				worker.AllocatedCPU, len(worker.RunningTasks))
+		}
+	})
+
+	mt.Run("unknown worker", func(mt *mtest.T) {
+		handler := NewWorkerAPIHandler(server.NewMasterServer(nil, nil, nil, nil, nil, nil, nil), nil, nil, nil)
+		req := httptest.NewRequest(http.MethodPost, "/api/workers/missing/expire", nil)
+		rec := httptest.NewRecorder()
+		handler.HandleExpireWorker(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rec.Code)
+		}
+	})
+
+	mt.Run("database failure", func(mt *mtest.T) {
+		workerDB := db.NewWorkerDBFromClient(mt.Client, "cloudai")
+		ms := server.NewMasterServer(workerDB, nil, nil, nil, nil, nil, nil)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.WORKER_REGISTRY", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "write failed"}))
+		handler := NewWorkerAPIHandler(ms, nil, nil, nil)
+		req := httptest.NewRequest(http.MethodPost, "/api/workers/worker-1/expire", nil)
+		rec := httptest.NewRecorder()
+		handler.HandleExpireWorker(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status 500 when the database update fails, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+// TestHandleGetWorkerTimeseries tests that GET /api/workers/{id}/timeseries returns the last N samples of one metric in order
+func TestHandleGetWorkerTimeseries(t *testing.T) {
+	telemetryMgr := telemetry.NewTelemetryManager(30 * time.Second)
+	defer telemetryMgr.Shutdown()
+
+	for i := 1; i <= 5; i++ {
+		telemetryMgr.RecordSample("worker-1", telemetry.TelemetrySample{
+			Timestamp:   int64(1000 + i),
+			CpuUsage:    float64(i * 10),
+			MemoryUsage: float64(i),
+		})
+	}
+	handler := NewWorkerAPIHandler(nil, nil, nil, telemetryMgr)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/workers/worker-1/timeseries?metric=memory&points=3", nil)
+	rec := httptest.NewRecorder()
+	handler.HandleGetWorkerTimeseries(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Metric string `json:"metric"`
+		Points []struct {
+			Timestamp int64   `json:"timestamp"`
+			Value     float64 `json:"value"`
+		} `json:"points"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Metric != "memory" {
+		t.Errorf("Expected metric memory, got %s", resp.Metric)
+	}
+	if len(resp.Points) != 3 {
+		t.Fatalf("Expected 3 points, got %d", len(resp.Points))
+	}
+	// The newest three samples, oldest first
+	for i, point := range resp.Points {
+		expected := i + 3
+		if point.Timestamp != int64(1000+expected) || point.Value != float64(expected) {
+			t.Errorf("Point %d: expected (%d, %d), got (%d, %.0f)", i, 1000+expected, expected, point.Timestamp, point.Value)
+		}
+	}
+
+	// Unknown metrics are rejected
+	req = httptest.NewRequest(http.MethodGet, "/api/workers/worker-1/timeseries?metric=disk", nil)
+	rec = httptest.NewRecorder()
+	handler.HandleGetWorkerTimeseries(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid metric, got %d", rec.Code)
+	}
+
+	// Unknown workers are not found
+	req = httptest.NewRequest(http.MethodGet, "/api/workers/missing/timeseries?metric=cpu", nil)
+	rec = httptest.NewRecorder()
+	handler.HandleGetWorkerTimeseries(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown worker, got %d", rec.Code)
+	}
+}
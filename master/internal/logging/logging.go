@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the minimum severity a log line needs to be written
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// level is the process-wide threshold, INFO by default
+var level atomic.Int32
+
+func init() {
+	level.Store(int32(LevelInfo))
+}
+
+// ParseLevel converts a LOG_LEVEL value (debug, info, warn, error) into a Level
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// String returns the lowercase name of the level
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// SetLevel sets the minimum level that is written
+func SetLevel(l Level) {
+	level.Store(int32(l))
+}
+
+// GetLevel returns the current minimum level
+func GetLevel() Level {
+	return Level(level.Load())
+}
+
+// Enabled reports whether lines at l are currently written
+func Enabled(l Level) bool {
+	return l >= GetLevel()
+}
+
+// output writes through the standard logger so its flags and output destination still apply
+func output(l Level, s string) {
+	if Enabled(l) {
+		log.Output(3, s)
+	}
+}
+
+// Debugf logs verbose detail such as banners and per-field breakdowns
+func Debugf(format string, v ...interface{}) { output(LevelDebug, fmt.Sprintf(format, v...)) }
+
+// Infof logs routine events
+func Infof(format string, v ...interface{}) { output(LevelInfo, fmt.Sprintf(format, v...)) }
+
+// Warnf logs recoverable problems
+func Warnf(format string, v ...interface{}) { output(LevelWarn, fmt.Sprintf(format, v...)) }
+
+// Errorf logs failures
+func Errorf(format string, v ...interface{}) { output(LevelError, fmt.Sprintf(format, v...)) }
+
+// Debug logs its operands like log.Println at DEBUG level
+func Debug(v ...interface{}) { output(LevelDebug, fmt.Sprintln(v...)) }
+
+// Info logs its operands like log.Println at INFO level
+func Info(v ...interface{}) { output(LevelInfo, fmt.Sprintln(v...)) }
+
+// Warn logs its operands like log.Println at WARN level
+func Warn(v ...interface{}) { output(LevelWarn, fmt.Sprintln(v...)) }
+
+// Error logs its operands like log.Println at ERROR level
+func Error(v ...interface{}) { output(LevelError, fmt.Sprintln(v...)) }
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestParseLevel tests that LOG_LEVEL values map to levels and unknown values are rejected
+func TestParseLevel(t *testing.T) {
+	cases := map[string]Level{"debug": LevelDebug, "INFO": LevelInfo, "": LevelInfo, "warn": LevelWarn, "warning": LevelWarn, " error ": LevelError}
+	for input, want := range cases {
+		got, err := ParseLevel(input)
+		if err != nil {
+			t.Errorf("Expected %q to parse, got %v", input, err)
+		}
+		if got != want {
+			t.Errorf("Expected %q to parse as %s, got %s", input, want, got)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected an unknown level to be rejected")
+	}
+}
+
+// TestLevelFiltersOutput tests that lines below the configured level are dropped
+func TestLevelFiltersOutput(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		SetLevel(LevelInfo)
+	})
+
+	SetLevel(LevelInfo)
+	Debugf("debug %d", 1)
+	Info("info", 2)
+	Warnf("warn %d", 3)
+	Error("error", 4)
+
+	out := buf.String()
+	if strings.Contains(out, "debug 1") {
+		t.Errorf("Expected DEBUG output to be dropped at INFO level, got %q", out)
+	}
+	for _, want := range []string{"info 2", "warn 3", "error 4"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"master/internal/logging"
+)
+
+// TaskEvent is the payload sent when a task reaches a terminal state
+type TaskEvent struct {
+	TaskID          string  `json:"task_id"`
+	UserID          string  `json:"user_id"`
+	WorkerID        string  `json:"worker_id,omitempty"`
+	Status          string  `json:"status"` // completed, failed, crashloop, cancelled
+	DurationSeconds float64 `json:"duration_seconds"`
+	Timestamp       int64   `json:"timestamp"`
+}
+
+// NotificationSink receives terminal task events; implementations must not block the caller
+type NotificationSink interface {
+	Notify(event TaskEvent)
+}
+
+const (
+	// DefaultWebhookAttempts is how many times a webhook delivery is tried before giving up
+	DefaultWebhookAttempts = 3
+	// DefaultWebhookBackoff is the delay before the first retry, doubled on each further retry
+	DefaultWebhookBackoff = time.Second
+)
+
+// WebhookSink posts task events as JSON to one or more webhook URLs (Slack incoming webhooks, alerting endpoints)
+type WebhookSink struct {
+	urls     []string
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// NewWebhookSink creates a sink that posts to every URL in urls
+func NewWebhookSink(urls []string) *WebhookSink {
+	return &WebhookSink{
+		urls:     urls,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		attempts: DefaultWebhookAttempts,
+		backoff:  DefaultWebhookBackoff,
+	}
+}
+
+// SetRetry changes how many times a delivery is tried and the initial backoff between tries
+func (w *WebhookSink) SetRetry(attempts int, backoff time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	w.attempts = attempts
+	w.backoff = backoff
+}
+
+// Notify delivers the event to every webhook in the background
+func (w *WebhookSink) Notify(event TaskEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		logging.Warnf("⚠️  Failed to encode notification for task %s: %v", event.TaskID, err)
+		return
+	}
+	for _, url := range w.urls {
+		go w.deliver(url, event.TaskID, body)
+	}
+}
+
+// deliver posts body to url, retrying on transport errors and non-2xx responses
+func (w *WebhookSink) deliver(url, taskID string, body []byte) {
+	backoff := w.backoff
+	var lastErr error
+	for attempt := 1; attempt <= w.attempts; attempt++ {
+		if lastErr = w.post(url, body); lastErr == nil {
+			return
+		}
+		if attempt < w.attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	logging.Warnf("⚠️  Notification for task %s to %s failed after %d attempt(s): %v", taskID, url, w.attempts, lastErr)
+}
+
+func (w *WebhookSink) post(url string, body []byte) error {
+	resp, err := w.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
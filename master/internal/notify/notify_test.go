@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWebhookSinkRetriesUntilDelivered tests that a failed delivery is retried and the payload arrives intact
+func TestWebhookSinkRetriesUntilDelivered(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan TaskEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event TaskEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		received <- event
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink([]string{srv.URL})
+	sink.SetRetry(3, time.Millisecond)
+	sink.Notify(TaskEvent{TaskID: "task-1", UserID: "alice", Status: "failed", DurationSeconds: 12.5})
+
+	select {
+	case event := <-received:
+		if event.TaskID != "task-1" || event.UserID != "alice" || event.Status != "failed" || event.DurationSeconds != 12.5 {
+			t.Errorf("Unexpected payload: %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for webhook delivery")
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts (one retry), got %d", got)
+	}
+}
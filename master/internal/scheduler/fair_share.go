@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	pb "master/proto"
+)
+
+// DefaultFairShareHalfLife is the age at which consumed resource-seconds count half as much toward a user's share
+const DefaultFairShareHalfLife = time.Hour
+
+// FairShareScheduler wraps another scheduler and favours users who consumed the least recently
+// Worker selection is left to the wrapped scheduler; the queue is ordered so under-served users' tasks are
+// placed first. Consumption is tracked in resource-seconds ((CPU + memory GB + GPU) x runtime) and decays
+// exponentially, so a user who flooded the cluster an hour ago is not held back forever
+type FairShareScheduler struct {
+	inner    Scheduler
+	halfLife time.Duration
+	usage    map[string]float64   // user_id -> decayed resource-seconds as of updated[user_id]
+	updated  map[string]time.Time // user_id -> when usage was last decayed
+	now      func() time.Time     // Clock (replaceable in tests)
+	mu       sync.Mutex
+}
+
+// NewFairShareScheduler creates a fair-share wrapper over inner (halfLife <= 0 uses DefaultFairShareHalfLife)
+func NewFairShareScheduler(inner Scheduler, halfLife time.Duration) *FairShareScheduler {
+	if halfLife <= 0 {
+		halfLife = DefaultFairShareHalfLife
+	}
+	return &FairShareScheduler{
+		inner:    inner,
+		halfLife: halfLife,
+		usage:    make(map[string]float64),
+		updated:  make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// SelectWorker delegates worker selection to the wrapped scheduler
+func (f *FairShareScheduler) SelectWorker(task *pb.Task, workers map[string]*WorkerInfo) string {
+	return f.inner.SelectWorker(task, workers)
+}
+
+// GetName returns the name of the scheduling algorithm
+func (f *FairShareScheduler) GetName() string {
+	return "FairShare(" + f.inner.GetName() + ")"
+}
+
+// Reset forgets all recorded consumption and resets the wrapped scheduler
+func (f *FairShareScheduler) Reset() {
+	f.mu.Lock()
+	f.usage = make(map[string]float64)
+	f.updated = make(map[string]time.Time)
+	f.mu.Unlock()
+	f.inner.Reset()
+}
+
+// SetOvercommitRatios passes over-commit ratios through to the wrapped scheduler when it honours them
+func (f *FairShareScheduler) SetOvercommitRatios(ratios OvercommitRatios) {
+	if oc, ok := f.inner.(interface{ SetOvercommitRatios(OvercommitRatios) }); ok {
+		oc.SetOvercommitRatios(ratios)
+	}
+}
+
+// ResourceSeconds is what a task holding the given resources for runtime consumed
+func ResourceSeconds(cpu, memory, gpu float64, runtime time.Duration) float64 {
+	if runtime <= 0 {
+		return 0
+	}
+	return (cpu + memory + gpu) * runtime.Seconds()
+}
+
+// RecordUsage charges resource-seconds consumed by a finished task to its user
+func (f *FairShareScheduler) RecordUsage(userID string, resourceSeconds float64) {
+	if resourceSeconds <= 0 {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.usage[userID] = f.decayedLocked(userID, f.now()) + resourceSeconds
+	f.updated[userID] = f.now()
+}
+
+// UserUsage returns a user's decayed resource-seconds (0 for users with no recorded consumption)
+func (f *FairShareScheduler) UserUsage(userID string) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.decayedLocked(userID, f.now())
+}
+
+// decayedLocked returns a user's consumption aged to now
+// Caller must hold f.mu
+func (f *FairShareScheduler) decayedLocked(userID string, now time.Time) float64 {
+	usage, exists := f.usage[userID]
+	if !exists {
+		return 0
+	}
+	age := now.Sub(f.updated[userID])
+	if age <= 0 {
+		return usage
+	}
+	return usage * math.Pow(0.5, age.Seconds()/f.halfLife.Seconds())
+}
+
+// OrderByShare returns the indexes of tasks ordered so users with the least recent consumption come first
+// Tasks of users with equal consumption keep their queue order
+func (f *FairShareScheduler) OrderByShare(tasks []*pb.Task) []int {
+	f.mu.Lock()
+	now := f.now()
+	usage := make(map[string]float64)
+	for _, task := range tasks {
+		if _, seen := usage[task.UserId]; !seen {
+			usage[task.UserId] = f.decayedLocked(task.UserId, now)
+		}
+	}
+	f.mu.Unlock()
+
+	order := make([]int, len(tasks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return usage[tasks[order[a]].UserId] < usage[tasks[order[b]].UserId]
+	})
+	return order
+}
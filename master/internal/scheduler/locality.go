@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultLocalityCapacity is the number of locality keys remembered before the oldest is evicted
+const DefaultLocalityCapacity = 1024
+
+// LocalityRiskBonus is subtracted from a worker's risk when it recently ran a task with the same locality key
+// It is small relative to deadline penalties, so locality only breaks ties between comparable workers
+const LocalityRiskBonus = 0.5
+
+// LocalityTracker remembers which worker most recently ran tasks for each locality key
+// Entries are kept in recency order and the least recently used key is evicted at capacity
+type LocalityTracker struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // Front = most recently used
+	mu       sync.Mutex
+}
+
+// localityEntry is a single key -> worker mapping
+type localityEntry struct {
+	key      string
+	workerID string
+}
+
+// NewLocalityTracker creates a tracker that remembers up to capacity locality keys
+func NewLocalityTracker(capacity int) *LocalityTracker {
+	if capacity <= 0 {
+		capacity = DefaultLocalityCapacity
+	}
+	return &LocalityTracker{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Record notes that a task with the given locality key was placed on workerID
+func (t *LocalityTracker) Record(key, workerID string) {
+	if key == "" || workerID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, exists := t.entries[key]; exists {
+		elem.Value.(*localityEntry).workerID = workerID
+		t.order.MoveToFront(elem)
+		return
+	}
+
+	t.entries[key] = t.order.PushFront(&localityEntry{key: key, workerID: workerID})
+
+	if t.order.Len() > t.capacity {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.entries, oldest.Value.(*localityEntry).key)
+	}
+}
+
+// PreferredWorker returns the worker that most recently ran a task with the given locality key
+func (t *LocalityTracker) PreferredWorker(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elem, exists := t.entries[key]
+	if !exists {
+		return "", false
+	}
+	return elem.Value.(*localityEntry).workerID, true
+}
+
+// Len returns the number of locality keys currently tracked
+func (t *LocalityTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Histogram bucket upper bounds, in seconds
+var (
+	queueWaitBuckets         = []float64{1, 5, 15, 30, 60, 300, 900, 3600}
+	assignmentLatencyBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+// histogram is a cumulative-bucket histogram in the Prometheus style
+type histogram struct {
+	bounds []float64
+	counts []uint64 // counts[i] = observations <= bounds[i]
+	sum    float64
+	count  uint64
+}
+
+// newHistogram creates a histogram with the given bucket upper bounds
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// observe records a single value
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// mean returns the average observed value (0 with no observations)
+func (h *histogram) mean() float64 {
+	if h.count == 0 {
+		return 0.0
+	}
+	return h.sum / float64(h.count)
+}
+
+// writePrometheus writes the histogram in Prometheus text exposition format
+func (h *histogram) writePrometheus(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for i, bound := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, bound, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, h.sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// SchedulerStats is a point-in-time summary of scheduler performance
+type SchedulerStats struct {
+	TasksScheduled         uint64
+	SchedulingAttempts     uint64 // Every placement attempt, successful or not
+	FailedAttempts         uint64
+	AvgAttemptsBeforeOK    float64 // Average attempts needed per scheduled task, including the successful one
+	AvgQueueWait           time.Duration
+	AssignmentRPCs         uint64
+	AssignmentRPCFailures  uint64
+	AvgAssignmentLatency   time.Duration
+	LastScheduledTimestamp int64
+}
+
+// SchedulerMetrics accumulates counters and histograms about task placement
+type SchedulerMetrics struct {
+	mu                 sync.Mutex
+	tasksScheduled     uint64
+	failedAttempts     uint64
+	attemptsToSchedule uint64 // Sum of attempts over scheduled tasks
+	assignmentRPCs     uint64
+	assignmentFailures uint64
+	lastScheduled      time.Time
+	queueWait          *histogram
+	assignmentLatency  *histogram
+}
+
+// NewSchedulerMetrics creates an empty set of scheduler metrics
+func NewSchedulerMetrics() *SchedulerMetrics {
+	return &SchedulerMetrics{
+		queueWait:         newHistogram(queueWaitBuckets),
+		assignmentLatency: newHistogram(assignmentLatencyBuckets),
+	}
+}
+
+// RecordFailedAttempt notes a placement attempt that left the task queued
+func (m *SchedulerMetrics) RecordFailedAttempt() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failedAttempts++
+}
+
+// RecordScheduled notes a task that was placed after attempts tries and queueWait in the queue
+func (m *SchedulerMetrics) RecordScheduled(attempts int, queueWait time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tasksScheduled++
+	m.attemptsToSchedule += uint64(attempts)
+	m.lastScheduled = time.Now()
+	m.queueWait.observe(queueWait.Seconds())
+}
+
+// RecordAssignmentRPC notes the latency of an AssignTask call and whether it succeeded
+func (m *SchedulerMetrics) RecordAssignmentRPC(latency time.Duration, success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.assignmentRPCs++
+	if !success {
+		m.assignmentFailures++
+	}
+	m.assignmentLatency.observe(latency.Seconds())
+}
+
+// Stats returns a snapshot of the accumulated metrics
+func (m *SchedulerMetrics) Stats() SchedulerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := SchedulerStats{
+		TasksScheduled:        m.tasksScheduled,
+		SchedulingAttempts:    m.tasksScheduled + m.failedAttempts,
+		FailedAttempts:        m.failedAttempts,
+		AvgQueueWait:          time.Duration(m.queueWait.mean() * float64(time.Second)),
+		AssignmentRPCs:        m.assignmentRPCs,
+		AssignmentRPCFailures: m.assignmentFailures,
+		AvgAssignmentLatency:  time.Duration(m.assignmentLatency.mean() * float64(time.Second)),
+	}
+	if m.tasksScheduled > 0 {
+		stats.AvgAttemptsBeforeOK = float64(m.attemptsToSchedule) / float64(m.tasksScheduled)
+	}
+	if !m.lastScheduled.IsZero() {
+		stats.LastScheduledTimestamp = m.lastScheduled.Unix()
+	}
+	return stats
+}
+
+// WritePrometheus writes all scheduler metrics in Prometheus text exposition format
+func (m *SchedulerMetrics) WritePrometheus(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeCounter(w, "scheduler_tasks_scheduled_total", "Tasks successfully assigned to a worker", m.tasksScheduled)
+	writeCounter(w, "scheduler_failed_attempts_total", "Placement attempts that left the task queued", m.failedAttempts)
+	writeCounter(w, "scheduler_attempts_before_success_total", "Sum of attempts needed per scheduled task", m.attemptsToSchedule)
+	writeCounter(w, "scheduler_assignment_rpcs_total", "AssignTask calls made to workers", m.assignmentRPCs)
+	writeCounter(w, "scheduler_assignment_rpc_failures_total", "AssignTask calls that failed or were rejected", m.assignmentFailures)
+	m.queueWait.writePrometheus(w, "scheduler_queue_wait_seconds", "Time tasks spent queued before being scheduled")
+	m.assignmentLatency.writePrometheus(w, "scheduler_assignment_rpc_latency_seconds", "Latency of AssignTask calls to workers")
+}
+
+// writeCounter writes a single counter in Prometheus text exposition format
+func writeCounter(w io.Writer, name, help string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
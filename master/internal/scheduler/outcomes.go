@@ -0,0 +1,72 @@
+package scheduler
+
+import "sync"
+
+// DefaultOutcomeWindow is the number of recent completions remembered per worker
+const DefaultOutcomeWindow = 20
+
+// riskTieEpsilon is the largest risk difference still treated as a tie between workers
+const riskTieEpsilon = 1e-9
+
+// OutcomeTracker keeps a rolling window of task outcomes per worker
+// Schedulers use the resulting failure rate to break ties between otherwise equal workers
+type OutcomeTracker struct {
+	window   int
+	outcomes map[string][]bool // worker_id -> recent outcomes (true = failed), oldest first
+	mu       sync.Mutex
+}
+
+// NewOutcomeTracker creates a tracker that remembers the last window outcomes per worker
+func NewOutcomeTracker(window int) *OutcomeTracker {
+	if window <= 0 {
+		window = DefaultOutcomeWindow
+	}
+	return &OutcomeTracker{
+		window:   window,
+		outcomes: make(map[string][]bool),
+	}
+}
+
+// Record notes whether a task completed on workerID succeeded
+func (t *OutcomeTracker) Record(workerID string, success bool) {
+	if workerID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	recent := append(t.outcomes[workerID], !success)
+	if len(recent) > t.window {
+		recent = recent[len(recent)-t.window:]
+	}
+	t.outcomes[workerID] = recent
+}
+
+// FailureRate returns the fraction of recent tasks on workerID that failed (0 with no history)
+func (t *OutcomeTracker) FailureRate(workerID string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	recent := t.outcomes[workerID]
+	if len(recent) == 0 {
+		return 0.0
+	}
+
+	failures := 0
+	for _, failed := range recent {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(recent))
+}
+
+// preferHealthier reports whether worker a should win a tie against worker b
+// Lower recent failure rate wins; equal rates fall back to worker ID for determinism
+func preferHealthier(aID string, aRate float64, bID string, bRate float64) bool {
+	if aRate != bRate {
+		return aRate < bRate
+	}
+	return aID < bID
+}
@@ -0,0 +1,40 @@
+package scheduler
+
+// OvercommitRatios holds per-resource multipliers applied to worker capacity when checking fit
+// A ratio of 1.0 is strict; 1.5 lets a worker accept 50% more than its physical capacity
+type OvercommitRatios struct {
+	CPU     float64
+	Memory  float64
+	Storage float64
+	GPU     float64
+}
+
+// overcommitSetter is implemented by schedulers whose capacity checks honour over-commit ratios
+type overcommitSetter interface {
+	SetOvercommitRatios(ratios OvercommitRatios)
+}
+
+// DefaultOvercommitRatios returns strict (1.0) ratios for every resource
+func DefaultOvercommitRatios() OvercommitRatios {
+	return OvercommitRatios{CPU: 1.0, Memory: 1.0, Storage: 1.0, GPU: 1.0}
+}
+
+// Normalized returns a copy with non-positive ratios replaced by 1.0
+func (r OvercommitRatios) Normalized() OvercommitRatios {
+	fix := func(v float64) float64 {
+		if v <= 0 {
+			return 1.0
+		}
+		return v
+	}
+	return OvercommitRatios{CPU: fix(r.CPU), Memory: fix(r.Memory), Storage: fix(r.Storage), GPU: fix(r.GPU)}
+}
+
+// Headroom returns how much of a resource can still be allocated once total capacity is scaled by ratio
+// available is total minus allocated and may be negative on an already over-committed worker
+func Headroom(available, total, ratio float64) float64 {
+	if ratio <= 0 {
+		ratio = 1.0
+	}
+	return available + total*(ratio-1)
+}
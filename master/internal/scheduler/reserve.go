@@ -0,0 +1,21 @@
+package scheduler
+
+// SystemReserve is the slice of each worker resource held back for the OS and the worker agent
+// Tasks are only ever placed on capacity beyond the reserve
+type SystemReserve struct {
+	CPU     float64 // CPU cores
+	Memory  float64 // Memory (GB)
+	Storage float64 // Storage (GB)
+	GPU     float64 // GPU units
+}
+
+// Usable returns the capacity left for tasks once reserve is held back from total
+func Usable(total, reserve float64) float64 {
+	if reserve <= 0 {
+		return total
+	}
+	if reserve >= total {
+		return 0
+	}
+	return total - reserve
+}
@@ -1,9 +1,10 @@
 package scheduler
 
 import (
-	"log"
+	"sort"
 	"sync"
 
+	"master/internal/logging"
 	pb "master/proto"
 )
 
@@ -16,21 +17,42 @@ type WorkerInfo struct {
 	AvailableMemory  float64
 	AvailableStorage float64
 	AvailableGPU     float64
+	// Usable capacity (total minus the system reserve), scaled by over-commit ratios when checking fit
+	TotalCPU     float64
+	TotalMemory  float64
+	TotalStorage float64
+	TotalGPU     float64
+	// RecentFailureRate is the fraction of recent tasks on this worker that failed (tie-breaker)
+	RecentFailureRate float64
+	// Zone is an optional topology label (rack, AZ) used to spread anti-affine tasks
+	Zone string
 }
 
 // RoundRobinScheduler implements a simple round-robin scheduling algorithm
 type RoundRobinScheduler struct {
-	lastWorkerIndex int
-	mu              sync.Mutex
+	lastWorkerID string             // Worker picked by the last rotation; the next one starts after it
+	locality     *LocalityTracker   // Recent placements per locality key
+	zones        *ZoneSpreadTracker // Running tasks per zone for each anti-affinity key
+	overcommit   OvercommitRatios   // Per-resource over-commit ratios for the capacity check
+	mu           sync.Mutex
 }
 
 // NewRoundRobinScheduler creates a new round-robin scheduler
 func NewRoundRobinScheduler() *RoundRobinScheduler {
 	return &RoundRobinScheduler{
-		lastWorkerIndex: -1,
+		locality:   NewLocalityTracker(DefaultLocalityCapacity),
+		zones:      NewZoneSpreadTracker(DefaultZoneSpreadCapacity),
+		overcommit: DefaultOvercommitRatios(),
 	}
 }
 
+// SetOvercommitRatios sets the per-resource over-commit ratios used by the capacity check
+func (s *RoundRobinScheduler) SetOvercommitRatios(ratios OvercommitRatios) {
+	s.mu.Lock()
+	s.overcommit = ratios.Normalized()
+	s.mu.Unlock()
+}
+
 // SelectWorker selects the next available worker using round-robin algorithm
 // Returns the worker ID or empty string if no suitable worker is found
 func (s *RoundRobinScheduler) SelectWorker(task *pb.Task, workers map[string]*WorkerInfo) string {
@@ -38,21 +60,57 @@ func (s *RoundRobinScheduler) SelectWorker(task *pb.Task, workers map[string]*Wo
 	defer s.mu.Unlock()
 
 	if len(workers) == 0 {
-		log.Printf("⚠️ Scheduler: No workers available")
+		logging.Warnf("⚠️ Scheduler: No workers available")
 		return ""
 	}
 
-	// Create a sorted list of worker IDs for consistent ordering
+	// Anti-affine tasks are limited to suitable workers in the least-used zones (nil = no limit)
+	spread := s.spreadCandidates(task, workers)
+
+	// A preferred worker that can take the task wins; otherwise selection falls through as normal
+	if preferred := task.PreferredWorkerId; preferred != "" {
+		if worker, exists := workers[preferred]; exists && s.isWorkerSuitable(worker, task) &&
+			(spread == nil || spread[preferred]) {
+			s.recordPlacement(task, preferred)
+			logging.Debugf("🔄 Scheduler: Round-robin selected preferred worker %s", preferred)
+			return preferred
+		}
+	}
+
+	// Prefer the worker that last ran a task with the same locality key (rotation is left untouched)
+	if preferred, ok := s.locality.PreferredWorker(task.LocalityKey); ok {
+		if worker, exists := workers[preferred]; exists && s.isWorkerSuitable(worker, task) &&
+			(spread == nil || spread[preferred]) {
+			s.recordPlacement(task, preferred)
+			logging.Debugf("🔄 Scheduler: Round-robin selected %s (locality key %s)", preferred, task.LocalityKey)
+			return preferred
+		}
+	}
+
+	// Rotate through workers by ID so the order does not change as failure rates do
 	workerIDs := make([]string, 0, len(workers))
 	for id := range workers {
 		workerIDs = append(workerIDs, id)
 	}
-
-	// Sort for deterministic behavior
-	sortWorkerIDs(workerIDs)
-
-	// Start from next worker after last selected
-	startIndex := (s.lastWorkerIndex + 1) % len(workerIDs)
+	sort.Strings(workerIDs)
+
+	// Start from the worker after the last one selected, so workers joining or leaving do not skip or repeat one;
+	// a fresh rotation starts on the healthiest worker
+	startIndex := 0
+	if s.lastWorkerID != "" {
+		startIndex = sort.SearchStrings(workerIDs, s.lastWorkerID)
+		if startIndex < len(workerIDs) && workerIDs[startIndex] == s.lastWorkerID {
+			startIndex++
+		}
+		startIndex %= len(workerIDs)
+	} else {
+		for i, id := range workerIDs {
+			best := workerIDs[startIndex]
+			if preferHealthier(id, workers[id].RecentFailureRate, best, workers[best].RecentFailureRate) {
+				startIndex = i
+			}
+		}
+	}
 
 	// Try each worker in round-robin order
 	for i := 0; i < len(workerIDs); i++ {
@@ -60,21 +118,28 @@ func (s *RoundRobinScheduler) SelectWorker(task *pb.Task, workers map[string]*Wo
 		workerID := workerIDs[currentIndex]
 		worker := workers[workerID]
 
+		// Skip workers in zones that already hold more tasks with the same anti-affinity key
+		if spread != nil && !spread[workerID] {
+			continue
+		}
+
 		// Check if worker is suitable
 		if s.isWorkerSuitable(worker, task) {
-			s.lastWorkerIndex = currentIndex
-			log.Printf("🔄 Scheduler: Round-robin selected %s (index %d/%d)",
+			s.lastWorkerID = workerID
+			s.recordPlacement(task, workerID)
+			logging.Debugf("🔄 Scheduler: Round-robin selected %s (index %d/%d)",
 				workerID, currentIndex+1, len(workerIDs))
 			return workerID
 		}
 	}
 
-	log.Printf("⚠️ Scheduler: No suitable worker found for task %s (checked %d workers)",
+	logging.Warnf("⚠️ Scheduler: No suitable worker found for task %s (checked %d workers)",
 		task.TaskId, len(workerIDs))
 	return ""
 }
 
 // isWorkerSuitable checks if a worker can handle the task
+// Caller must hold s.mu
 func (s *RoundRobinScheduler) isWorkerSuitable(worker *WorkerInfo, task *pb.Task) bool {
 	// Skip inactive workers
 	if !worker.IsActive {
@@ -86,44 +151,68 @@ func (s *RoundRobinScheduler) isWorkerSuitable(worker *WorkerInfo, task *pb.Task
 		return false
 	}
 
-	// Check resource availability
-	if worker.AvailableCPU < task.ReqCpu {
+	// Check resource availability, with capacity scaled by the over-commit ratios
+	ratios := s.overcommit
+	if Headroom(worker.AvailableCPU, worker.TotalCPU, ratios.CPU) < task.ReqCpu {
 		return false
 	}
-	if worker.AvailableMemory < task.ReqMemory {
+	if Headroom(worker.AvailableMemory, worker.TotalMemory, ratios.Memory) < task.ReqMemory {
 		return false
 	}
-	if worker.AvailableStorage < task.ReqStorage {
+	if Headroom(worker.AvailableStorage, worker.TotalStorage, ratios.Storage) < task.ReqStorage {
 		return false
 	}
-	if worker.AvailableGPU < task.ReqGpu {
+	if Headroom(worker.AvailableGPU, worker.TotalGPU, ratios.GPU) < task.ReqGpu {
 		return false
 	}
 
 	return true
 }
 
+// spreadCandidates returns the suitable workers allowed for an anti-affine task, or nil for no limit
+func (s *RoundRobinScheduler) spreadCandidates(task *pb.Task, workers map[string]*WorkerInfo) map[string]bool {
+	if task.AntiAffinityKey == "" {
+		return nil
+	}
+
+	suitable := make([]string, 0, len(workers))
+	for id, worker := range workers {
+		if s.isWorkerSuitable(worker, task) {
+			suitable = append(suitable, id)
+		}
+	}
+	return s.zones.LeastUsed(task.AntiAffinityKey, suitable, workers)
+}
+
+// recordPlacement remembers the placement for locality; zone spreading counts it once the master confirms it
+func (s *RoundRobinScheduler) recordPlacement(task *pb.Task, workerID string) {
+	s.locality.Record(task.LocalityKey, workerID)
+}
+
+// RecordZonePlacement counts a task with the given anti-affinity key as running in zone
+func (s *RoundRobinScheduler) RecordZonePlacement(key, zone string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zones.Record(key, zone)
+}
+
+// ReleaseZonePlacement stops counting a task with the given anti-affinity key in zone
+func (s *RoundRobinScheduler) ReleaseZonePlacement(key, zone string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zones.Release(key, zone)
+}
+
 // Reset resets the scheduler state (useful for testing)
 func (s *RoundRobinScheduler) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.lastWorkerIndex = -1
+	s.lastWorkerID = ""
+	s.locality = NewLocalityTracker(DefaultLocalityCapacity)
+	s.zones = NewZoneSpreadTracker(DefaultZoneSpreadCapacity)
 }
 
 // GetName returns the scheduler name
 func (s *RoundRobinScheduler) GetName() string {
 	return "Round-Robin"
 }
-
-// sortWorkerIDs sorts worker IDs alphabetically for deterministic ordering
-func sortWorkerIDs(ids []string) {
-	// Simple bubble sort (sufficient for small number of workers)
-	n := len(ids)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if ids[j] > ids[j+1] {
-				ids[j], ids[j+1] = ids[j+1], ids[j]
-			}
-		}
-	}
-}
@@ -27,17 +27,21 @@ type TaskView struct {
 	Storage     float64   // Required storage
 	ArrivalTime time.Time // When task was submitted
 	Tau         float64   // Base runtime estimate (seconds)
-	Deadline    time.Time // Computed deadline: ArrivalTime + k * Tau
+	Deadline    time.Time // ArrivalTime + k * Tau, or the task's absolute deadline when set
 	UserID      string    // User who submitted the task
 }
 
 // WorkerView represents a scheduler-level view of a worker's current state
 type WorkerView struct {
 	ID           string  // Worker identifier
-	CPUAvail     float64 // Available CPU cores
+	CPUAvail     float64 // Available CPU cores (negative when over-committed)
 	MemAvail     float64 // Available memory (GB)
 	GPUAvail     float64 // Available GPU units
 	StorageAvail float64 // Available storage (GB)
+	CPUTotal     float64 // Total CPU cores usable by tasks (excludes system reserve)
+	MemTotal     float64 // Total memory usable by tasks (GB)
+	GPUTotal     float64 // Total GPU units usable by tasks
+	StorageTotal float64 // Total storage usable by tasks (GB)
 	Load         float64 // Normalized load (may exceed 1.0 due to oversubscription)
 }
 
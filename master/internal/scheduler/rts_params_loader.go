@@ -2,8 +2,12 @@ package scheduler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+
+	"master/internal/logging"
 )
 
 // LoadGAParams loads GAParams from a JSON file
@@ -22,7 +26,7 @@ func LoadGAParams(filePath string) (*GAParams, error) {
 	}
 
 	// Validate parameters
-	if err := validateGAParams(&params); err != nil {
+	if err := params.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid GA params: %w", err)
 	}
 
@@ -74,28 +78,37 @@ func GetDefaultGAParams() *GAParams {
 	}
 }
 
-// validateGAParams checks if GAParams contains reasonable values
-func validateGAParams(params *GAParams) error {
+// ValidateRisk checks that risk weights are finite and within the ranges accepted from GA output
+func ValidateRisk(risk Risk) error {
+	if !isFinite(risk.Alpha) || risk.Alpha < 0 || risk.Alpha > 1000 {
+		return fmt.Errorf("Alpha out of range [0, 1000]: %.2f", risk.Alpha)
+	}
+	if !isFinite(risk.Beta) || risk.Beta < 0 || risk.Beta > 100 {
+		return fmt.Errorf("Beta out of range [0, 100]: %.2f", risk.Beta)
+	}
+	return nil
+}
+
+// Validate checks that every parameter is finite and within a reasonable range
+// NaN and infinities would otherwise pass the range checks and poison every score they touch
+func (params *GAParams) Validate() error {
 	// Validate Theta parameters (should be reasonable multipliers)
-	if params.Theta.Theta1 < 0 || params.Theta.Theta1 > 10 {
+	if !isFinite(params.Theta.Theta1) || params.Theta.Theta1 < 0 || params.Theta.Theta1 > 10 {
 		return fmt.Errorf("Theta1 out of range [0, 10]: %.2f", params.Theta.Theta1)
 	}
-	if params.Theta.Theta2 < 0 || params.Theta.Theta2 > 10 {
+	if !isFinite(params.Theta.Theta2) || params.Theta.Theta2 < 0 || params.Theta.Theta2 > 10 {
 		return fmt.Errorf("Theta2 out of range [0, 10]: %.2f", params.Theta.Theta2)
 	}
-	if params.Theta.Theta3 < 0 || params.Theta.Theta3 > 10 {
+	if !isFinite(params.Theta.Theta3) || params.Theta.Theta3 < 0 || params.Theta.Theta3 > 10 {
 		return fmt.Errorf("Theta3 out of range [0, 10]: %.2f", params.Theta.Theta3)
 	}
-	if params.Theta.Theta4 < 0 || params.Theta.Theta4 > 10 {
+	if !isFinite(params.Theta.Theta4) || params.Theta.Theta4 < 0 || params.Theta.Theta4 > 10 {
 		return fmt.Errorf("Theta4 out of range [0, 10]: %.2f", params.Theta.Theta4)
 	}
 
 	// Validate Risk parameters (should be positive)
-	if params.Risk.Alpha < 0 || params.Risk.Alpha > 1000 {
-		return fmt.Errorf("Alpha out of range [0, 1000]: %.2f", params.Risk.Alpha)
-	}
-	if params.Risk.Beta < 0 || params.Risk.Beta > 100 {
-		return fmt.Errorf("Beta out of range [0, 100]: %.2f", params.Risk.Beta)
+	if err := ValidateRisk(params.Risk); err != nil {
+		return err
 	}
 
 	// Validate Affinity matrix structure
@@ -117,7 +130,7 @@ func validateGAParams(params *GAParams) error {
 
 			// Check affinity values are in reasonable range
 			for workerID, affinity := range params.AffinityMatrix[taskType] {
-				if affinity < -10 || affinity > 10 {
+				if !isFinite(affinity) || affinity < -10 || affinity > 10 {
 					return fmt.Errorf("affinity out of range [-10, 10] for task %s, worker %s: %.2f",
 						taskType, workerID, affinity)
 				}
@@ -128,7 +141,7 @@ func validateGAParams(params *GAParams) error {
 	// Validate Penalty vector
 	if params.PenaltyVector != nil {
 		for workerID, penalty := range params.PenaltyVector {
-			if penalty < 0 || penalty > 100 {
+			if !isFinite(penalty) || penalty < 0 || penalty > 100 {
 				return fmt.Errorf("penalty out of range [0, 100] for worker %s: %.2f",
 					workerID, penalty)
 			}
@@ -143,9 +156,17 @@ func validateGAParams(params *GAParams) error {
 func LoadGAParamsOrDefault(filePath string) *GAParams {
 	params, err := LoadGAParams(filePath)
 	if err != nil {
-		// Log the error but don't fail - use defaults
+		// Don't fail - use defaults
 		// This allows the system to start even without trained parameters
+		if !errors.Is(err, os.ErrNotExist) {
+			logging.Warnf("⚠️  RTS: Ignoring GA params from %s, using defaults: %v", filePath, err)
+		}
 		return GetDefaultGAParams()
 	}
 	return params
 }
+
+// isFinite reports whether v is neither NaN nor infinite
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
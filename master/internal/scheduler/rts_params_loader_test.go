@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadGAParamsOrDefaultRejectsInvalidFiles tests that params files with NaN or out-of-range values fall back to defaults
+func TestLoadGAParamsOrDefaultRejectsInvalidFiles(t *testing.T) {
+	defaults := GetDefaultGAParams()
+	files := map[string]string{
+		"nan-theta":     `{"Theta": {"Theta1": NaN, "Theta2": 0.1, "Theta3": 0.3, "Theta4": 0.2}, "Risk": {"Alpha": 10, "Beta": 1}}`,
+		"large-theta":   `{"Theta": {"Theta1": 50, "Theta2": 0.1, "Theta3": 0.3, "Theta4": 0.2}, "Risk": {"Alpha": 10, "Beta": 1}}`,
+		"negative-beta": `{"Theta": {"Theta1": 0.5, "Theta2": 0.1, "Theta3": 0.3, "Theta4": 0.2}, "Risk": {"Alpha": 10, "Beta": -1}}`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(t.TempDir(), name+".json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write params file: %v", err)
+		}
+
+		params := LoadGAParamsOrDefault(path)
+		if params.Theta != defaults.Theta || params.Risk != defaults.Risk {
+			t.Errorf("%s: expected default params, got theta=%+v risk=%+v", name, params.Theta, params.Risk)
+		}
+	}
+}
+
+// TestGAParamsValidateRejectsNonFinite tests that NaN and infinite values are rejected even though they pass range comparisons
+func TestGAParamsValidateRejectsNonFinite(t *testing.T) {
+	nanTheta := GetDefaultGAParams()
+	nanTheta.Theta.Theta2 = math.NaN()
+	if err := nanTheta.Validate(); err == nil {
+		t.Error("Expected a NaN Theta2 to be rejected")
+	}
+
+	infAffinity := GetDefaultGAParams()
+	infAffinity.AffinityMatrix[TaskTypeCPULight] = map[string]float64{"worker-1": math.Inf(1)}
+	if err := infAffinity.Validate(); err == nil {
+		t.Error("Expected an infinite affinity to be rejected")
+	}
+
+	if err := ValidateRisk(Risk{Alpha: math.NaN(), Beta: 1}); err == nil {
+		t.Error("Expected a NaN Alpha to be rejected")
+	}
+
+	if err := GetDefaultGAParams().Validate(); err != nil {
+		t.Errorf("Expected default params to be valid, got %v", err)
+	}
+}
+
+// TestReloadParamsKeepsPreviousOnInvalidFile tests that a hot reload of an invalid params file keeps the last good parameters
+func TestReloadParamsKeepsPreviousOnInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ga_output.json")
+	good := `{"Theta": {"Theta1": 0.7, "Theta2": 0.1, "Theta3": 0.3, "Theta4": 0.2}, "Risk": {"Alpha": 10, "Beta": 1}}`
+	if err := os.WriteFile(path, []byte(good), 0644); err != nil {
+		t.Fatalf("Failed to write params file: %v", err)
+	}
+	s := &RTSScheduler{paramsPath: path, params: GetDefaultGAParams()}
+
+	s.reloadParams()
+	if theta1 := s.getGAParamsSafe().Theta.Theta1; theta1 != 0.7 {
+		t.Fatalf("Expected Theta1 0.7 after reloading a valid file, got %v", theta1)
+	}
+
+	bad := `{"Theta": {"Theta1": 50, "Theta2": 0.1, "Theta3": 0.3, "Theta4": 0.2}, "Risk": {"Alpha": 10, "Beta": 1}}`
+	if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+		t.Fatalf("Failed to write params file: %v", err)
+	}
+	s.reloadParams()
+	if theta1 := s.getGAParamsSafe().Theta.Theta1; theta1 != 0.7 {
+		t.Errorf("Expected the previous Theta1 0.7 to be kept after an invalid reload, got %v", theta1)
+	}
+}
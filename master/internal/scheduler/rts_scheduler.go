@@ -2,11 +2,13 @@ package scheduler
 
 import (
 	"context"
-	"log"
+	"errors"
 	"math"
+	"os"
 	"sync"
 	"time"
 
+	"master/internal/logging"
 	"master/internal/telemetry"
 	pb "master/proto"
 )
@@ -27,9 +29,21 @@ type RTSScheduler struct {
 	paramsMu   sync.RWMutex
 	paramsPath string
 
+	// Live Risk weights set via the API; survive parameter hot-reloads (guarded by paramsMu)
+	riskOverride *Risk
+
 	// SLA multiplier (k factor)
 	slaMultiplier float64
 
+	// Recent placements per locality key (data locality bonus)
+	locality *LocalityTracker
+
+	// Placements per zone for each anti-affinity key (zone spreading)
+	zones *ZoneSpreadTracker
+
+	// Per-resource over-commit ratios for the feasibility filter (guarded by paramsMu)
+	overcommit OvercommitRatios
+
 	// Context for background tasks
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -51,13 +65,16 @@ func NewRTSScheduler(
 		telemetrySource: telemetrySource,
 		paramsPath:      paramsPath,
 		slaMultiplier:   slaMultiplier,
+		locality:        NewLocalityTracker(DefaultLocalityCapacity),
+		zones:           NewZoneSpreadTracker(DefaultZoneSpreadCapacity),
+		overcommit:      DefaultOvercommitRatios(),
 		ctx:             ctx,
 		cancel:          cancel,
 	}
 
 	// Load initial parameters (or defaults if file doesn't exist)
 	s.params = LoadGAParamsOrDefault(paramsPath)
-	log.Printf("✓ RTS Scheduler initialized with params from %s", paramsPath)
+	logging.Infof("✓ RTS Scheduler initialized with params from %s", paramsPath)
 
 	// Start background params reloader
 	s.startParamsReloader()
@@ -76,13 +93,79 @@ func (s *RTSScheduler) Reset() {
 	s.rrScheduler.Reset()
 }
 
+// SetOvercommitRatios sets the per-resource over-commit ratios used by the feasibility filter and the fallback
+func (s *RTSScheduler) SetOvercommitRatios(ratios OvercommitRatios) {
+	s.paramsMu.Lock()
+	s.overcommit = ratios.Normalized()
+	s.paramsMu.Unlock()
+	if rr, ok := s.rrScheduler.(overcommitSetter); ok {
+		rr.SetOvercommitRatios(ratios)
+	}
+}
+
+// GetRisk returns the Risk weights currently used for scheduling and whether they were set live
+func (s *RTSScheduler) GetRisk() (Risk, bool) {
+	s.paramsMu.RLock()
+	defer s.paramsMu.RUnlock()
+	return s.params.Risk, s.riskOverride != nil
+}
+
+// SetRisk replaces the live Risk weights without waiting for a GA epoch
+// The override is re-applied after every parameter reload
+func (s *RTSScheduler) SetRisk(risk Risk) error {
+	if err := ValidateRisk(risk); err != nil {
+		return err
+	}
+
+	s.paramsMu.Lock()
+	defer s.paramsMu.Unlock()
+
+	s.riskOverride = &risk
+	s.params = withRisk(s.params, risk)
+	logging.Infof("✓ RTS: Risk weights set live (alpha=%.2f, beta=%.2f)", risk.Alpha, risk.Beta)
+	return nil
+}
+
+// withRisk returns a copy of params with the given Risk weights
+// Readers holding the old pointer keep a consistent snapshot
+func withRisk(params *GAParams, risk Risk) *GAParams {
+	updated := *params
+	updated.Risk = risk
+	return &updated
+}
+
 // Shutdown gracefully stops the scheduler
 func (s *RTSScheduler) Shutdown() {
 	s.cancel()
 }
 
 // SelectWorker implements the RTS scheduling algorithm (EDD §3.9)
+// Placements are recorded against the task's locality key so related tasks can follow; anti-affinity
+// zones are counted only once the master confirms the placement (RecordZonePlacement)
 func (s *RTSScheduler) SelectWorker(task *pb.Task, workers map[string]*WorkerInfo) string {
+	workerID := s.selectWorker(task, workers)
+	if workerID != "" && s.locality != nil {
+		s.locality.Record(task.LocalityKey, workerID)
+	}
+	return workerID
+}
+
+// RecordZonePlacement counts a task with the given anti-affinity key as running in zone
+func (s *RTSScheduler) RecordZonePlacement(key, zone string) {
+	if s.zones != nil {
+		s.zones.Record(key, zone)
+	}
+}
+
+// ReleaseZonePlacement stops counting a task with the given anti-affinity key in zone
+func (s *RTSScheduler) ReleaseZonePlacement(key, zone string) {
+	if s.zones != nil {
+		s.zones.Release(key, zone)
+	}
+}
+
+// selectWorker picks the lowest-risk feasible worker, falling back to Round-Robin
+func (s *RTSScheduler) selectWorker(task *pb.Task, workers map[string]*WorkerInfo) string {
 	// Step 1: Build TaskView from pb.Task
 	now := time.Now()
 	taskView := s.buildTaskView(task, now)
@@ -94,15 +177,36 @@ func (s *RTSScheduler) SelectWorker(task *pb.Task, workers map[string]*WorkerInf
 	feasibleWorkers := s.filterFeasible(taskView, workerViews)
 
 	if len(feasibleWorkers) == 0 {
-		log.Printf("⚠️ RTS: No feasible workers for task %s (type=%s), falling back to Round-Robin",
+		logging.Warnf("⚠️ RTS: No feasible workers for task %s (type=%s), falling back to Round-Robin",
 			task.TaskId, taskView.Type)
 		return s.rrScheduler.SelectWorker(task, workers)
 	}
 
+	// Anti-affine tasks only consider feasible workers in the least-used zones
+	feasibleWorkers = s.spreadFeasible(task, feasibleWorkers, workers)
+
+	// A feasible preferred worker is taken outright; otherwise the task is placed on risk as usual
+	if preferred := task.PreferredWorkerId; preferred != "" {
+		for _, workerView := range feasibleWorkers {
+			if workerView.ID == preferred {
+				logging.Debugf("✓ RTS: Selected preferred worker %s for task %s", preferred, task.TaskId)
+				return preferred
+			}
+		}
+		logging.Debugf("RTS: Preferred worker %s cannot take task %s, selecting another", preferred, task.TaskId)
+	}
+
 	// Step 4: Load GA parameters (thread-safe)
 	params := s.getGAParamsSafe()
 
+	// Workers that recently ran tasks with the same locality key get a small risk bonus
+	localWorker := ""
+	if s.locality != nil {
+		localWorker, _ = s.locality.PreferredWorker(task.LocalityKey)
+	}
+
 	// Step 5: Compute risk for each feasible worker and select best
+	// Equal risks are broken by recent failure rate, then worker ID
 	bestWorkerID := ""
 	bestRisk := math.Inf(1) // Start with positive infinity
 
@@ -115,9 +219,18 @@ func (s *RTSScheduler) SelectWorker(task *pb.Task, workers map[string]*WorkerInf
 
 		// Compute final risk with affinity and penalty (EDD §3.8)
 		finalRisk := s.computeFinalRisk(baseRisk, taskView.Type, workerView.ID, params)
+		if workerView.ID == localWorker {
+			finalRisk -= LocalityRiskBonus
+		}
+
+		if math.IsInf(finalRisk, 0) || math.IsNaN(finalRisk) {
+			continue
+		}
 
 		// Track best worker (lowest risk)
-		if finalRisk < bestRisk && !math.IsInf(finalRisk, 0) && !math.IsNaN(finalRisk) {
+		if finalRisk < bestRisk-riskTieEpsilon ||
+			(math.Abs(finalRisk-bestRisk) <= riskTieEpsilon &&
+				preferHealthier(workerView.ID, failureRate(workers, workerView.ID), bestWorkerID, failureRate(workers, bestWorkerID))) {
 			bestRisk = finalRisk
 			bestWorkerID = workerView.ID
 		}
@@ -125,16 +238,48 @@ func (s *RTSScheduler) SelectWorker(task *pb.Task, workers map[string]*WorkerInf
 
 	// Step 6: Validate result and fallback if needed
 	if bestWorkerID == "" || math.IsInf(bestRisk, 0) || math.IsNaN(bestRisk) {
-		log.Printf("⚠️ RTS: Invalid risk scores for task %s, falling back to Round-Robin", task.TaskId)
+		logging.Warnf("⚠️ RTS: Invalid risk scores for task %s, falling back to Round-Robin", task.TaskId)
 		return s.rrScheduler.SelectWorker(task, workers)
 	}
 
-	log.Printf("✓ RTS: Selected worker %s for task %s (type=%s, risk=%.2f)",
+	logging.Debugf("✓ RTS: Selected worker %s for task %s (type=%s, risk=%.2f)",
 		bestWorkerID, task.TaskId, taskView.Type, bestRisk)
 
 	return bestWorkerID
 }
 
+// spreadFeasible narrows feasible workers to the zones holding the fewest tasks with the task's anti-affinity key
+func (s *RTSScheduler) spreadFeasible(task *pb.Task, feasible []WorkerView, workers map[string]*WorkerInfo) []WorkerView {
+	if s.zones == nil || task.AntiAffinityKey == "" {
+		return feasible
+	}
+
+	ids := make([]string, 0, len(feasible))
+	for _, view := range feasible {
+		ids = append(ids, view.ID)
+	}
+	allowed := s.zones.LeastUsed(task.AntiAffinityKey, ids, workers)
+	if allowed == nil {
+		return feasible
+	}
+
+	spread := make([]WorkerView, 0, len(allowed))
+	for _, view := range feasible {
+		if allowed[view.ID] {
+			spread = append(spread, view)
+		}
+	}
+	return spread
+}
+
+// failureRate returns the recent failure rate of a worker in the scheduling map (0 if unknown)
+func failureRate(workers map[string]*WorkerInfo, workerID string) float64 {
+	if worker, exists := workers[workerID]; exists {
+		return worker.RecentFailureRate
+	}
+	return 0.0
+}
+
 // buildTaskView constructs a TaskView from a protobuf Task
 func (s *RTSScheduler) buildTaskView(task *pb.Task, now time.Time) TaskView {
 	// Use NewTaskViewFromProto which handles task type inference
@@ -145,7 +290,14 @@ func (s *RTSScheduler) buildTaskView(task *pb.Task, now time.Time) TaskView {
 		tau = s.tauStore.GetTau(taskType)
 	}
 
-	return NewTaskViewFromProto(task, now, tau, s.slaMultiplier)
+	view := NewTaskViewFromProto(task, now, tau, s.slaMultiplier)
+
+	// A user-supplied wall-clock deadline takes precedence over arrival + k*tau
+	if task.Deadline > 0 {
+		view.Deadline = time.Unix(task.Deadline, 0)
+	}
+
+	return view
 }
 
 // buildWorkerViews constructs WorkerViews from available workers and telemetry
@@ -153,7 +305,7 @@ func (s *RTSScheduler) buildWorkerViews(workers map[string]*WorkerInfo) []Worker
 	// Get all worker views from telemetry source
 	allViews, err := s.telemetrySource.GetWorkerViews(context.Background())
 	if err != nil {
-		log.Printf("⚠️ RTS: Failed to get worker views: %v", err)
+		logging.Warnf("⚠️ RTS: Failed to get worker views: %v", err)
 		return []WorkerView{}
 	}
 
@@ -169,15 +321,20 @@ func (s *RTSScheduler) buildWorkerViews(workers map[string]*WorkerInfo) []Worker
 }
 
 // filterFeasible filters workers that can accommodate the task (EDD §3.3)
+// Capacity is scaled by the configured over-commit ratio for each resource
 func (s *RTSScheduler) filterFeasible(task TaskView, workers []WorkerView) []WorkerView {
+	s.paramsMu.RLock()
+	ratios := s.overcommit
+	s.paramsMu.RUnlock()
+
 	feasible := make([]WorkerView, 0, len(workers))
 
 	for _, worker := range workers {
 		// Check resource constraints
-		if worker.CPUAvail >= task.CPU &&
-			worker.MemAvail >= task.Mem &&
-			worker.GPUAvail >= task.GPU &&
-			worker.StorageAvail >= task.Storage {
+		if Headroom(worker.CPUAvail, worker.CPUTotal, ratios.CPU) >= task.CPU &&
+			Headroom(worker.MemAvail, worker.MemTotal, ratios.Memory) >= task.Mem &&
+			Headroom(worker.GPUAvail, worker.GPUTotal, ratios.GPU) >= task.GPU &&
+			Headroom(worker.StorageAvail, worker.StorageTotal, ratios.Storage) >= task.Storage {
 			feasible = append(feasible, worker)
 		}
 	}
@@ -292,15 +449,7 @@ func (s *RTSScheduler) startParamsReloader() {
 		for {
 			select {
 			case <-ticker.C:
-				// Reload parameters from file
-				newParams := LoadGAParamsOrDefault(s.paramsPath)
-
-				// Update with write lock
-				s.paramsMu.Lock()
-				s.params = newParams
-				s.paramsMu.Unlock()
-
-				log.Printf("✓ RTS: Reloaded GA parameters from %s", s.paramsPath)
+				s.reloadParams()
 
 			case <-s.ctx.Done():
 				return
@@ -308,3 +457,25 @@ func (s *RTSScheduler) startParamsReloader() {
 		}
 	}()
 }
+
+// reloadParams replaces the GA parameters with those in the params file
+// An unreadable or invalid file keeps the previous parameters rather than falling back to defaults
+func (s *RTSScheduler) reloadParams() {
+	newParams, err := LoadGAParams(s.paramsPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logging.Warnf("⚠️  RTS: Keeping previous GA parameters, reload from %s failed: %v", s.paramsPath, err)
+		}
+		return
+	}
+
+	// Update with write lock
+	s.paramsMu.Lock()
+	if s.riskOverride != nil {
+		newParams = withRisk(newParams, *s.riskOverride)
+	}
+	s.params = newParams
+	s.paramsMu.Unlock()
+
+	logging.Infof("✓ RTS: Reloaded GA parameters from %s", s.paramsPath)
+}
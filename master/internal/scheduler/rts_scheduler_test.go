@@ -0,0 +1,397 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"master/internal/telemetry"
+	pb "master/proto"
+)
+
+// TestBuildTaskViewAbsoluteDeadline tests that a task's absolute deadline overrides arrival + k*tau
+func TestBuildTaskViewAbsoluteDeadline(t *testing.T) {
+	s := &RTSScheduler{tauStore: telemetry.NewInMemoryTauStore(), slaMultiplier: 2.0}
+	now := time.Unix(1700000000, 0)
+	deadline := now.Add(90 * time.Minute)
+
+	view := s.buildTaskView(&pb.Task{
+		TaskId:   "task-1",
+		TaskType: "cpu-light",
+		ReqCpu:   1.0,
+		Deadline: deadline.Unix(),
+	}, now)
+
+	if !view.Deadline.Equal(deadline) {
+		t.Errorf("Expected deadline %s, got %s", deadline, view.Deadline)
+	}
+}
+
+// TestBuildTaskViewComputedDeadline tests that without an absolute deadline the k*tau deadline is used
+func TestBuildTaskViewComputedDeadline(t *testing.T) {
+	tauStore := telemetry.NewInMemoryTauStore()
+	s := &RTSScheduler{tauStore: tauStore, slaMultiplier: 2.0}
+	now := time.Unix(1700000000, 0)
+
+	view := s.buildTaskView(&pb.Task{TaskId: "task-1", TaskType: "cpu-light", ReqCpu: 1.0}, now)
+
+	expected := now.Add(time.Duration(2.0 * tauStore.GetTau("cpu-light") * float64(time.Second)))
+	if !view.Deadline.Equal(expected) {
+		t.Errorf("Expected computed deadline %s, got %s", expected, view.Deadline)
+	}
+}
+
+// stubTelemetrySource serves fixed worker views
+type stubTelemetrySource struct {
+	views []WorkerView
+}
+
+func (s *stubTelemetrySource) GetWorkerViews(ctx context.Context) ([]WorkerView, error) {
+	return s.views, nil
+}
+
+func (s *stubTelemetrySource) GetWorkerLoad(workerID string) float64 {
+	for _, v := range s.views {
+		if v.ID == workerID {
+			return v.Load
+		}
+	}
+	return 0.0
+}
+
+// TestRTSLocalityKeyPrefersPreviousWorker tests that a task with a seen locality key follows the earlier task
+func TestRTSLocalityKeyPrefersPreviousWorker(t *testing.T) {
+	s := &RTSScheduler{
+		rrScheduler: NewRoundRobinScheduler(),
+		tauStore:    telemetry.NewInMemoryTauStore(),
+		telemetrySource: &stubTelemetrySource{views: []WorkerView{
+			{ID: "worker-a", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.1},
+			{ID: "worker-b", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.3},
+		}},
+		params:        GetDefaultGAParams(),
+		slaMultiplier: 2.0,
+		locality:      NewLocalityTracker(DefaultLocalityCapacity),
+	}
+	both := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052"},
+		"worker-b": {WorkerID: "worker-b", IsActive: true, WorkerIP: "10.0.0.2:50052"},
+	}
+
+	// The first task of the pipeline can only run on worker-b
+	first := &pb.Task{TaskId: "task-1", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1, LocalityKey: "pipeline-1"}
+	if got := s.SelectWorker(first, map[string]*WorkerInfo{"worker-b": both["worker-b"]}); got != "worker-b" {
+		t.Fatalf("Expected first task on worker-b, got %s", got)
+	}
+
+	// worker-a is less loaded, but the second task should follow its pipeline to worker-b
+	second := &pb.Task{TaskId: "task-2", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1, LocalityKey: "pipeline-1"}
+	if got := s.SelectWorker(second, both); got != "worker-b" {
+		t.Errorf("Expected second task with same locality key on worker-b, got %s", got)
+	}
+
+	// A task without the key is placed purely on risk
+	other := &pb.Task{TaskId: "task-3", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1, LocalityKey: "pipeline-2"}
+	if got := s.SelectWorker(other, both); got != "worker-a" {
+		t.Errorf("Expected unrelated task on least-loaded worker-a, got %s", got)
+	}
+}
+
+// TestRTSPreferredWorkerFallsBackWhenFull tests that a preferred worker is used when it fits and skipped when it is full
+func TestRTSPreferredWorkerFallsBackWhenFull(t *testing.T) {
+	s := &RTSScheduler{
+		rrScheduler: NewRoundRobinScheduler(),
+		tauStore:    telemetry.NewInMemoryTauStore(),
+		telemetrySource: &stubTelemetrySource{views: []WorkerView{
+			{ID: "worker-a", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.1},
+			{ID: "worker-b", CPUAvail: 2, MemAvail: 16, StorageAvail: 100, Load: 0.5},
+		}},
+		params:        GetDefaultGAParams(),
+		slaMultiplier: 2.0,
+	}
+	workers := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052"},
+		"worker-b": {WorkerID: "worker-b", IsActive: true, WorkerIP: "10.0.0.2:50052"},
+	}
+
+	// worker-a has the lower risk, but worker-b fits the task and is preferred
+	small := &pb.Task{TaskId: "task-1", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1, PreferredWorkerId: "worker-b"}
+	if got := s.SelectWorker(small, workers); got != "worker-b" {
+		t.Errorf("Expected the preferred worker-b, got %s", got)
+	}
+
+	// worker-b cannot fit 4 CPUs, so the task falls back to worker-a instead of failing
+	large := &pb.Task{TaskId: "task-2", TaskType: "cpu-light", ReqCpu: 4, ReqMemory: 1, PreferredWorkerId: "worker-b"}
+	if got := s.SelectWorker(large, workers); got != "worker-a" {
+		t.Errorf("Expected fallback to worker-a when the preferred worker is full, got %s", got)
+	}
+}
+
+// TestRoundRobinLocalityKeyPrefersPreviousWorker tests that round-robin keeps related tasks on one worker
+func TestRoundRobinLocalityKeyPrefersPreviousWorker(t *testing.T) {
+	rr := NewRoundRobinScheduler()
+	workers := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052", AvailableCPU: 4, AvailableMemory: 8, AvailableStorage: 10},
+		"worker-b": {WorkerID: "worker-b", IsActive: true, WorkerIP: "10.0.0.2:50052", AvailableCPU: 4, AvailableMemory: 8, AvailableStorage: 10},
+	}
+
+	first := rr.SelectWorker(&pb.Task{TaskId: "task-1", ReqCpu: 1, LocalityKey: "job-1"}, workers)
+	second := rr.SelectWorker(&pb.Task{TaskId: "task-2", ReqCpu: 1, LocalityKey: "job-1"}, workers)
+	if first != second {
+		t.Errorf("Expected same worker for same locality key, got %s then %s", first, second)
+	}
+
+	// Tasks without a key still rotate
+	third := rr.SelectWorker(&pb.Task{TaskId: "task-3", ReqCpu: 1}, workers)
+	if third == first {
+		t.Errorf("Expected rotation to move past %s for a task without a locality key", first)
+	}
+}
+
+// TestRTSAntiAffinitySpreadsAcrossZones tests that anti-affine tasks land in different zones
+func TestRTSAntiAffinitySpreadsAcrossZones(t *testing.T) {
+	s := &RTSScheduler{
+		rrScheduler: NewRoundRobinScheduler(),
+		tauStore:    telemetry.NewInMemoryTauStore(),
+		telemetrySource: &stubTelemetrySource{views: []WorkerView{
+			{ID: "worker-a", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.1},
+			{ID: "worker-b", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.3},
+		}},
+		params:        GetDefaultGAParams(),
+		slaMultiplier: 2.0,
+		zones:         NewZoneSpreadTracker(DefaultZoneSpreadCapacity),
+	}
+	workers := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052", Zone: "zone-1"},
+		"worker-b": {WorkerID: "worker-b", IsActive: true, WorkerIP: "10.0.0.2:50052", Zone: "zone-2"},
+	}
+
+	// worker-a is less loaded, so the first replica goes there
+	first := s.SelectWorker(&pb.Task{TaskId: "task-1", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1, AntiAffinityKey: "replicas-1"}, workers)
+	if first != "worker-a" {
+		t.Fatalf("Expected first replica on least-loaded worker-a, got %s", first)
+	}
+	s.RecordZonePlacement("replicas-1", WorkerZone(workers[first]))
+
+	// The second replica must avoid zone-1 even though worker-a still has the lowest risk
+	second := s.SelectWorker(&pb.Task{TaskId: "task-2", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1, AntiAffinityKey: "replicas-1"}, workers)
+	if second == "" || workers[second].Zone == workers[first].Zone {
+		t.Errorf("Expected anti-affine replicas in different zones, got %s then %s", first, second)
+	}
+	s.RecordZonePlacement("replicas-1", WorkerZone(workers[second]))
+
+	// A task with a different key is placed purely on risk
+	other := s.SelectWorker(&pb.Task{TaskId: "task-3", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1, AntiAffinityKey: "replicas-2"}, workers)
+	if other != "worker-a" {
+		t.Errorf("Expected unrelated task on least-loaded worker-a, got %s", other)
+	}
+
+	// With a single zone there is no alternative, so replicas still get placed
+	single := map[string]*WorkerInfo{"worker-a": workers["worker-a"]}
+	if got := s.SelectWorker(&pb.Task{TaskId: "task-4", TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1, AntiAffinityKey: "replicas-1"}, single); got != "worker-a" {
+		t.Errorf("Expected replica on worker-a when no other zone exists, got %s", got)
+	}
+}
+
+// TestRoundRobinAntiAffinitySpreadsAcrossZones tests that round-robin skips zones already used by the key
+func TestRoundRobinAntiAffinitySpreadsAcrossZones(t *testing.T) {
+	rr := NewRoundRobinScheduler()
+	workers := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052", AvailableCPU: 4, AvailableMemory: 8, AvailableStorage: 10, Zone: "zone-1"},
+		"worker-b": {WorkerID: "worker-b", IsActive: true, WorkerIP: "10.0.0.2:50052", AvailableCPU: 4, AvailableMemory: 8, AvailableStorage: 10, Zone: "zone-1"},
+		"worker-c": {WorkerID: "worker-c", IsActive: true, WorkerIP: "10.0.0.3:50052", AvailableCPU: 4, AvailableMemory: 8, AvailableStorage: 10, Zone: "zone-2"},
+	}
+
+	// Rotation alone would pick worker-a then worker-b, both in zone-1
+	first := rr.SelectWorker(&pb.Task{TaskId: "task-1", ReqCpu: 1, AntiAffinityKey: "replicas-1"}, workers)
+	rr.RecordZonePlacement("replicas-1", WorkerZone(workers[first]))
+	second := rr.SelectWorker(&pb.Task{TaskId: "task-2", ReqCpu: 1, AntiAffinityKey: "replicas-1"}, workers)
+	if workers[first].Zone == workers[second].Zone {
+		t.Errorf("Expected anti-affine replicas in different zones, got %s then %s", first, second)
+	}
+}
+
+// TestZoneSpreadCountsOnlyConfirmedRunningTasks tests that selections alone are not counted and finished tasks are released
+func TestZoneSpreadCountsOnlyConfirmedRunningTasks(t *testing.T) {
+	rr := NewRoundRobinScheduler()
+	workers := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052", AvailableCPU: 4, AvailableMemory: 8, AvailableStorage: 10, Zone: "zone-1"},
+		"worker-b": {WorkerID: "worker-b", IsActive: true, WorkerIP: "10.0.0.2:50052", AvailableCPU: 4, AvailableMemory: 8, AvailableStorage: 10, Zone: "zone-2"},
+	}
+	task := &pb.Task{TaskId: "task-1", ReqCpu: 1, AntiAffinityKey: "replicas-1"}
+
+	// A selection whose assignment never happened leaves no trace
+	rr.SelectWorker(task, workers)
+	if got := rr.zones.Count("replicas-1", "zone-1") + rr.zones.Count("replicas-1", "zone-2"); got != 0 {
+		t.Errorf("Expected an unconfirmed selection not to be counted, got %d", got)
+	}
+
+	rr.RecordZonePlacement("replicas-1", "zone-1")
+	rr.RecordZonePlacement("replicas-1", "zone-1")
+	rr.ReleaseZonePlacement("replicas-1", "zone-1")
+	if got := rr.zones.Count("replicas-1", "zone-1"); got != 1 {
+		t.Errorf("Expected 1 running replica in zone-1 after a release, got %d", got)
+	}
+
+	// Once the last replica finishes the key is forgotten
+	rr.ReleaseZonePlacement("replicas-1", "zone-1")
+	if rr.zones.Len() != 0 {
+		t.Errorf("Expected the key to be forgotten with no replicas running, got %d keys", rr.zones.Len())
+	}
+}
+
+// TestLocalityTrackerEvictsOldest tests that the recency map stays bounded
+func TestLocalityTrackerEvictsOldest(t *testing.T) {
+	tracker := NewLocalityTracker(2)
+	tracker.Record("k1", "worker-a")
+	tracker.Record("k2", "worker-b")
+	tracker.PreferredWorker("k1") // Lookups do not refresh recency
+	tracker.Record("k3", "worker-c")
+
+	if tracker.Len() != 2 {
+		t.Errorf("Expected 2 tracked keys, got %d", tracker.Len())
+	}
+	if _, ok := tracker.PreferredWorker("k1"); ok {
+		t.Error("Expected oldest key k1 to be evicted")
+	}
+	if w, ok := tracker.PreferredWorker("k3"); !ok || w != "worker-c" {
+		t.Errorf("Expected k3 -> worker-c, got %s (found: %v)", w, ok)
+	}
+}
+
+// TestFilterFeasibleAppliesOvercommitRatios tests that over-committed CPU admits a worker while memory stays strict
+func TestFilterFeasibleAppliesOvercommitRatios(t *testing.T) {
+	s := &RTSScheduler{overcommit: DefaultOvercommitRatios()}
+	workers := []WorkerView{
+		{ID: "worker-a", CPUAvail: 2, CPUTotal: 4, MemAvail: 4, MemTotal: 8, StorageAvail: 100, StorageTotal: 100},
+	}
+	cpuHeavy := TaskView{ID: "task-cpu", CPU: 3, Mem: 2}
+	memHeavy := TaskView{ID: "task-mem", CPU: 1, Mem: 6}
+
+	if got := s.filterFeasible(cpuHeavy, workers); len(got) != 0 {
+		t.Fatalf("Expected strict filter to reject 3 CPUs with 2 available, got %d workers", len(got))
+	}
+
+	s.SetOvercommitRatios(OvercommitRatios{CPU: 2.0, Memory: 1.0})
+
+	if got := s.filterFeasible(cpuHeavy, workers); len(got) != 1 {
+		t.Errorf("Expected CPU over-commit of 2.0 to admit worker-a, got %d workers", len(got))
+	}
+	if got := s.filterFeasible(memHeavy, workers); len(got) != 0 {
+		t.Errorf("Expected memory to stay strict, got %d workers", len(got))
+	}
+}
+
+// TestRoundRobinRotatesByIDWithUnequalFailureRates tests that failure rates pick where a rotation starts but not its order
+func TestRoundRobinRotatesByIDWithUnequalFailureRates(t *testing.T) {
+	workers := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052", AvailableCPU: 8, AvailableMemory: 16, AvailableStorage: 100, RecentFailureRate: 0.5},
+		"worker-b": {WorkerID: "worker-b", IsActive: true, WorkerIP: "10.0.0.2:50052", AvailableCPU: 8, AvailableMemory: 16, AvailableStorage: 100, RecentFailureRate: 0.0},
+		"worker-c": {WorkerID: "worker-c", IsActive: true, WorkerIP: "10.0.0.3:50052", AvailableCPU: 8, AvailableMemory: 16, AvailableStorage: 100, RecentFailureRate: 0.2},
+	}
+	rr := NewRoundRobinScheduler()
+
+	expected := []string{"worker-b", "worker-c", "worker-a", "worker-b", "worker-c", "worker-a"}
+	for i, want := range expected {
+		task := &pb.Task{TaskId: fmt.Sprintf("task-%d", i), ReqCpu: 1, ReqMemory: 1}
+		if got := rr.SelectWorker(task, workers); got != want {
+			t.Errorf("Expected task %d on %s, got %s", i, want, got)
+		}
+	}
+
+	// A worker leaving mid-rotation hands its turn to the next worker by ID
+	delete(workers, "worker-b")
+	if got := rr.SelectWorker(&pb.Task{TaskId: "task-6", ReqCpu: 1, ReqMemory: 1}, workers); got != "worker-c" {
+		t.Errorf("Expected worker-c after worker-a's turn, got %s", got)
+	}
+}
+
+// TestRoundRobinAppliesOvercommitRatios tests that round-robin admits over-committed CPU like RTS, while memory stays strict
+func TestRoundRobinAppliesOvercommitRatios(t *testing.T) {
+	workers := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052",
+			AvailableCPU: 2, TotalCPU: 4, AvailableMemory: 4, TotalMemory: 8, AvailableStorage: 100, TotalStorage: 100},
+	}
+	cpuHeavy := &pb.Task{TaskId: "task-cpu", ReqCpu: 3, ReqMemory: 2}
+	memHeavy := &pb.Task{TaskId: "task-mem", ReqCpu: 1, ReqMemory: 6}
+
+	rr := NewRoundRobinScheduler()
+	if got := rr.SelectWorker(cpuHeavy, workers); got != "" {
+		t.Fatalf("Expected strict check to reject 3 CPUs with 2 available, got %s", got)
+	}
+
+	// Ratios set on RTS reach its round-robin fallback
+	rts := &RTSScheduler{rrScheduler: rr}
+	rts.SetOvercommitRatios(OvercommitRatios{CPU: 2.0, Memory: 1.0})
+
+	if got := rr.SelectWorker(cpuHeavy, workers); got != "worker-a" {
+		t.Errorf("Expected CPU over-commit of 2.0 to admit worker-a, got %q", got)
+	}
+	if got := rr.SelectWorker(memHeavy, workers); got != "" {
+		t.Errorf("Expected memory to stay strict, got %s", got)
+	}
+}
+
+// TestSchedulersBreakTiesByRecentFailureRate tests that the worker with fewer recent failures wins otherwise-equal choices
+func TestSchedulersBreakTiesByRecentFailureRate(t *testing.T) {
+	outcomes := NewOutcomeTracker(DefaultOutcomeWindow)
+	for i := 0; i < 4; i++ {
+		outcomes.Record("worker-a", i%2 == 0) // 50% failures
+		outcomes.Record("worker-b", true)
+	}
+	outcomes.Record("worker-b", false) // 20% failures
+
+	if rate := outcomes.FailureRate("worker-b"); rate != 0.2 {
+		t.Fatalf("Expected worker-b failure rate 0.2, got %.2f", rate)
+	}
+
+	// worker-a sorts first by ID, so only the failure rate can put worker-b ahead
+	workers := map[string]*WorkerInfo{
+		"worker-a": {WorkerID: "worker-a", IsActive: true, WorkerIP: "10.0.0.1:50052",
+			AvailableCPU: 8, AvailableMemory: 16, AvailableStorage: 100, RecentFailureRate: outcomes.FailureRate("worker-a")},
+		"worker-b": {WorkerID: "worker-b", IsActive: true, WorkerIP: "10.0.0.2:50052",
+			AvailableCPU: 8, AvailableMemory: 16, AvailableStorage: 100, RecentFailureRate: outcomes.FailureRate("worker-b")},
+	}
+
+	rts := &RTSScheduler{
+		rrScheduler: NewRoundRobinScheduler(),
+		tauStore:    telemetry.NewInMemoryTauStore(),
+		telemetrySource: &stubTelemetrySource{views: []WorkerView{
+			{ID: "worker-a", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.2},
+			{ID: "worker-b", CPUAvail: 8, MemAvail: 16, StorageAvail: 100, Load: 0.2},
+		}},
+		params:        GetDefaultGAParams(),
+		slaMultiplier: 2.0,
+	}
+	rr := NewRoundRobinScheduler()
+
+	for i := 0; i < 5; i++ {
+		task := &pb.Task{TaskId: fmt.Sprintf("task-%d", i), TaskType: "cpu-light", ReqCpu: 1, ReqMemory: 1}
+		if got := rts.SelectWorker(task, workers); got != "worker-b" {
+			t.Errorf("RTS attempt %d: expected lower-failure worker-b, got %s", i, got)
+		}
+
+		// Round-robin starts each fresh rotation on the healthier worker
+		rr.Reset()
+		if got := rr.SelectWorker(task, workers); got != "worker-b" {
+			t.Errorf("Round-robin attempt %d: expected lower-failure worker-b, got %s", i, got)
+		}
+	}
+}
+
+// TestOutcomeTrackerKeepsRollingWindow tests that old outcomes fall out of the window
+func TestOutcomeTrackerKeepsRollingWindow(t *testing.T) {
+	outcomes := NewOutcomeTracker(3)
+	outcomes.Record("worker-a", false)
+	outcomes.Record("worker-a", false)
+	outcomes.Record("worker-a", true)
+	outcomes.Record("worker-a", true)
+	outcomes.Record("worker-a", true)
+
+	if rate := outcomes.FailureRate("worker-a"); rate != 0.0 {
+		t.Errorf("Expected early failures to age out of the window, got rate %.2f", rate)
+	}
+	if rate := outcomes.FailureRate("unknown"); rate != 0.0 {
+		t.Errorf("Expected 0 failure rate for a worker with no history, got %.2f", rate)
+	}
+}
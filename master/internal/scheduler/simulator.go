@@ -0,0 +1,270 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"master/internal/db"
+	pb "master/proto"
+)
+
+// SimWorker is the capacity of a worker in a simulated cluster
+type SimWorker struct {
+	ID      string
+	CPU     float64
+	Memory  float64
+	Storage float64
+	GPU     float64
+}
+
+// SimulationReport summarizes how a scheduler handled a replayed history
+type SimulationReport struct {
+	Scheduler string
+	Tasks     int // Arrivals replayed
+	Placed    int // Tasks the scheduler placed
+	Unplaced  int // Tasks that never fit any worker, even an idle one
+	SLAMet    int // Placed tasks that finished by their recorded deadline
+	// SLASuccessRate is SLAMet / Placed (0 when nothing was placed)
+	SLASuccessRate float64
+	AvgWait        time.Duration // Mean time from arrival to placement
+	Makespan       time.Duration // First arrival to last finish
+	// Share of the cluster's capacity held by tasks over the makespan
+	CPUUtilization    float64
+	MemoryUtilization float64
+	GPUUtilization    float64
+}
+
+// simRun is a task occupying a simulated worker until finish
+type simRun struct {
+	workerID string
+	task     db.TaskHistory
+	finish   time.Time
+}
+
+// Simulator replays historical task arrivals against a scheduler on a simulated cluster without running anything
+// Each task keeps its recorded resources and runtime; only where and when it starts is decided by the scheduler.
+// The simulator is the scheduler's TelemetrySource, so RTS sees the simulated cluster rather than the live one
+type Simulator struct {
+	workers   []SimWorker           // Sorted by ID
+	allocated map[string]*SimWorker // worker_id -> resources held by running tasks
+}
+
+// NewSimulator creates a simulator for a cluster of the given workers
+func NewSimulator(workers []SimWorker) *Simulator {
+	sorted := append([]SimWorker(nil), workers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return &Simulator{workers: sorted, allocated: make(map[string]*SimWorker)}
+}
+
+// GetWorkerViews returns the simulated workers with what is currently free on them
+func (s *Simulator) GetWorkerViews(ctx context.Context) ([]WorkerView, error) {
+	views := make([]WorkerView, 0, len(s.workers))
+	for _, w := range s.workers {
+		used := s.used(w.ID)
+		views = append(views, WorkerView{
+			ID:           w.ID,
+			CPUAvail:     w.CPU - used.CPU,
+			MemAvail:     w.Memory - used.Memory,
+			GPUAvail:     w.GPU - used.GPU,
+			StorageAvail: w.Storage - used.Storage,
+			CPUTotal:     w.CPU,
+			MemTotal:     w.Memory,
+			GPUTotal:     w.GPU,
+			StorageTotal: w.Storage,
+			Load:         s.GetWorkerLoad(w.ID),
+		})
+	}
+	return views, nil
+}
+
+// GetWorkerLoad returns the share of a simulated worker's CPU held by running tasks
+func (s *Simulator) GetWorkerLoad(workerID string) float64 {
+	for _, w := range s.workers {
+		if w.ID == workerID && w.CPU > 0 {
+			return s.used(workerID).CPU / w.CPU
+		}
+	}
+	return 0.0
+}
+
+// used returns the resources running tasks hold on a worker
+func (s *Simulator) used(workerID string) SimWorker {
+	if used, exists := s.allocated[workerID]; exists {
+		return *used
+	}
+	return SimWorker{ID: workerID}
+}
+
+// Run replays tasks in arrival order against sched and reports SLA success and utilization
+// Queued tasks are offered to the scheduler first-come first-served whenever a task arrives or finishes
+func (s *Simulator) Run(sched Scheduler, history []db.TaskHistory) *SimulationReport {
+	s.allocated = make(map[string]*SimWorker)
+	sched.Reset()
+
+	tasks := append([]db.TaskHistory(nil), history...)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if !tasks[i].ArrivalTime.Equal(tasks[j].ArrivalTime) {
+			return tasks[i].ArrivalTime.Before(tasks[j].ArrivalTime)
+		}
+		return tasks[i].TaskID < tasks[j].TaskID
+	})
+
+	report := &SimulationReport{Scheduler: sched.GetName(), Tasks: len(tasks)}
+	if len(tasks) == 0 {
+		return report
+	}
+
+	var queue []db.TaskHistory
+	var running []simRun
+	var totalWait time.Duration
+	var held SimWorker // Resource-seconds held by placed tasks
+	start, end := tasks[0].ArrivalTime, tasks[0].ArrivalTime
+	next := 0
+
+	for next < len(tasks) || len(queue) > 0 {
+		// Nothing left to free capacity or bring new work: what is still queued can never be placed
+		if len(running) == 0 && next >= len(tasks) {
+			report.Unplaced += len(queue)
+			break
+		}
+
+		// Advance to the next arrival or completion, whichever comes first
+		now := time.Time{}
+		if next < len(tasks) {
+			now = tasks[next].ArrivalTime
+		}
+		for _, run := range running {
+			if now.IsZero() || run.finish.Before(now) {
+				now = run.finish
+			}
+		}
+
+		stillRunning := running[:0]
+		for _, run := range running {
+			if run.finish.After(now) {
+				stillRunning = append(stillRunning, run)
+				continue
+			}
+			s.release(run)
+		}
+		running = stillRunning
+
+		for next < len(tasks) && !tasks[next].ArrivalTime.After(now) {
+			queue = append(queue, tasks[next])
+			next++
+		}
+
+		waiting := queue[:0]
+		for _, task := range queue {
+			workerID := s.place(sched, task)
+			if workerID == "" {
+				waiting = append(waiting, task)
+				continue
+			}
+
+			runtime := time.Duration(task.ActualRuntime * float64(time.Second))
+			run := simRun{workerID: workerID, task: task, finish: now.Add(runtime)}
+			running = append(running, run)
+
+			report.Placed++
+			totalWait += now.Sub(task.ArrivalTime)
+			if task.Deadline.IsZero() || !run.finish.After(task.Deadline) {
+				report.SLAMet++
+			}
+			held.CPU += task.CPUUsed * runtime.Seconds()
+			held.Memory += task.MemUsed * runtime.Seconds()
+			held.GPU += task.GPUUsed * runtime.Seconds()
+			if run.finish.After(end) {
+				end = run.finish
+			}
+		}
+		queue = waiting
+	}
+
+	if report.Placed > 0 {
+		report.SLASuccessRate = float64(report.SLAMet) / float64(report.Placed)
+		report.AvgWait = totalWait / time.Duration(report.Placed)
+	}
+	report.Makespan = end.Sub(start)
+	if seconds := report.Makespan.Seconds(); seconds > 0 {
+		var capacity SimWorker
+		for _, w := range s.workers {
+			capacity.CPU += w.CPU
+			capacity.Memory += w.Memory
+			capacity.GPU += w.GPU
+		}
+		report.CPUUtilization = share(held.CPU, capacity.CPU*seconds)
+		report.MemoryUtilization = share(held.Memory, capacity.Memory*seconds)
+		report.GPUUtilization = share(held.GPU, capacity.GPU*seconds)
+	}
+	return report
+}
+
+// place asks the scheduler for a worker and holds the task's resources there
+// Returns "" when no worker has room; the scheduler is not consulted then, so it does not log a failed selection
+func (s *Simulator) place(sched Scheduler, task db.TaskHistory) string {
+	workers := make(map[string]*WorkerInfo, len(s.workers))
+	anyFits := false
+	for _, w := range s.workers {
+		used := s.used(w.ID)
+		info := &WorkerInfo{
+			WorkerID:         w.ID,
+			IsActive:         true,
+			WorkerIP:         "simulated",
+			AvailableCPU:     w.CPU - used.CPU,
+			AvailableMemory:  w.Memory - used.Memory,
+			AvailableStorage: w.Storage - used.Storage,
+			AvailableGPU:     w.GPU - used.GPU,
+		}
+		workers[w.ID] = info
+		anyFits = anyFits || fitsSimulated(info, task)
+	}
+	if !anyFits {
+		return ""
+	}
+
+	workerID := sched.SelectWorker(&pb.Task{
+		TaskId:     task.TaskID,
+		TaskType:   task.Type,
+		ReqCpu:     task.CPUUsed,
+		ReqMemory:  task.MemUsed,
+		ReqStorage: task.StorageUsed,
+		ReqGpu:     task.GPUUsed,
+	}, workers)
+	if info, exists := workers[workerID]; !exists || !fitsSimulated(info, task) {
+		return ""
+	}
+
+	used := s.used(workerID)
+	used.CPU += task.CPUUsed
+	used.Memory += task.MemUsed
+	used.Storage += task.StorageUsed
+	used.GPU += task.GPUUsed
+	s.allocated[workerID] = &used
+	return workerID
+}
+
+// release frees the resources a finished task held
+func (s *Simulator) release(run simRun) {
+	used := s.used(run.workerID)
+	used.CPU -= run.task.CPUUsed
+	used.Memory -= run.task.MemUsed
+	used.Storage -= run.task.StorageUsed
+	used.GPU -= run.task.GPUUsed
+	s.allocated[run.workerID] = &used
+}
+
+// fitsSimulated reports whether a task's recorded resources fit in what is free on a worker
+func fitsSimulated(worker *WorkerInfo, task db.TaskHistory) bool {
+	return worker.AvailableCPU >= task.CPUUsed && worker.AvailableMemory >= task.MemUsed &&
+		worker.AvailableStorage >= task.StorageUsed && worker.AvailableGPU >= task.GPUUsed
+}
+
+// share returns part/whole, or 0 when whole is 0
+func share(part, whole float64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return part / whole
+}
@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	"master/internal/telemetry"
+)
+
+// simulationHistory is a fixed history: two tasks fill the cluster, a third waits for one to finish and misses
+// its deadline, and a fourth never fits any worker
+func simulationHistory() []db.TaskHistory {
+	t0 := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	task := func(id string, arrival, runtime, deadline int, cpu float64) db.TaskHistory {
+		return db.TaskHistory{
+			TaskID:        id,
+			Type:          TaskTypeCPUHeavy,
+			ArrivalTime:   t0.Add(time.Duration(arrival) * time.Second),
+			Deadline:      t0.Add(time.Duration(deadline) * time.Second),
+			ActualRuntime: float64(runtime),
+			CPUUsed:       cpu,
+			MemUsed:       1.0,
+		}
+	}
+	return []db.TaskHistory{
+		task("task-3", 10, 50, 110, 2.0),
+		task("task-1", 0, 100, 150, 2.0),
+		task("task-2", 0, 100, 150, 2.0),
+		task("task-4", 20, 10, 60, 8.0),
+	}
+}
+
+// TestSimulatorDeterministicMetrics tests that replaying a fixed history yields the same metrics on every run
+func TestSimulatorDeterministicMetrics(t *testing.T) {
+	sim := NewSimulator([]SimWorker{
+		{ID: "worker-2", CPU: 2.0, Memory: 4.0, Storage: 10.0},
+		{ID: "worker-1", CPU: 2.0, Memory: 4.0, Storage: 10.0},
+	})
+
+	report := sim.Run(NewRoundRobinScheduler(), simulationHistory())
+	want := &SimulationReport{
+		Scheduler:         "Round-Robin",
+		Tasks:             4,
+		Placed:            3,
+		Unplaced:          1,
+		SLAMet:            2,
+		SLASuccessRate:    2.0 / 3.0,
+		AvgWait:           30 * time.Second,
+		Makespan:          150 * time.Second,
+		CPUUtilization:    500.0 / 600.0,
+		MemoryUtilization: 250.0 / 1200.0,
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("Expected %+v, got %+v", want, report)
+	}
+	if again := sim.Run(NewRoundRobinScheduler(), simulationHistory()); !reflect.DeepEqual(again, report) {
+		t.Errorf("Expected a second run to match, got %+v", again)
+	}
+
+	// RTS sees the simulated cluster through the simulator and is just as repeatable
+	missing := filepath.Join(t.TempDir(), "ga_output.json")
+	rts := NewRTSScheduler(NewRoundRobinScheduler(), telemetry.NewInMemoryTauStore(), sim, missing, 2.0)
+	defer rts.Shutdown()
+	first := sim.Run(rts, simulationHistory())
+	second := sim.Run(rts, simulationHistory())
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected repeated RTS runs to match, got %+v and %+v", first, second)
+	}
+	if first.Placed != 3 || first.SLAMet != 2 {
+		t.Errorf("Expected RTS to place 3 tasks with 2 meeting SLA, got %d placed and %d met", first.Placed, first.SLAMet)
+	}
+}
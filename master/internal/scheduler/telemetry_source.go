@@ -19,7 +19,7 @@ type WorkerDBInterface interface {
 type TelemetrySource interface {
 	// GetWorkerViews returns the current state of all active workers
 	// Each WorkerView contains:
-	//   - Available resources (Total - Allocated from DB)
+	//   - Available resources (Total - Reserve - Allocated from DB)
 	//   - Current load (normalized usage from telemetry)
 	GetWorkerViews(ctx context.Context) ([]WorkerView, error)
 
@@ -32,6 +32,7 @@ type TelemetrySource interface {
 type MasterTelemetrySource struct {
 	telemetryMgr *telemetry.TelemetryManager
 	workerDB     WorkerDBInterface
+	reserve      SystemReserve // Capacity held back from tasks on every worker
 }
 
 // NewMasterTelemetrySource creates a new telemetry source for the RTS scheduler
@@ -42,6 +43,11 @@ func NewMasterTelemetrySource(telemetryMgr *telemetry.TelemetryManager, workerDB
 	}
 }
 
+// SetSystemReserve sets the per-resource capacity subtracted from worker totals
+func (mts *MasterTelemetrySource) SetSystemReserve(reserve SystemReserve) {
+	mts.reserve = reserve
+}
+
 // GetWorkerViews returns the current state of all active workers
 func (mts *MasterTelemetrySource) GetWorkerViews(ctx context.Context) ([]WorkerView, error) {
 	// Get all workers from DB (for capacity information)
@@ -61,25 +67,19 @@ func (mts *MasterTelemetrySource) GetWorkerViews(ctx context.Context) ([]WorkerV
 			continue
 		}
 
-		// Compute available resources (Total - Allocated)
-		cpuAvail := worker.TotalCPU - worker.AllocatedCPU
-		memAvail := worker.TotalMemory - worker.AllocatedMemory
-		gpuAvail := worker.TotalGPU - worker.AllocatedGPU
-		storageAvail := worker.TotalStorage - worker.AllocatedStorage
+		// Usable capacity excludes the system reserve
+		cpuTotal := Usable(worker.TotalCPU, mts.reserve.CPU)
+		memTotal := Usable(worker.TotalMemory, mts.reserve.Memory)
+		gpuTotal := Usable(worker.TotalGPU, mts.reserve.GPU)
+		storageTotal := Usable(worker.TotalStorage, mts.reserve.Storage)
 
-		// Ensure non-negative values (in case of oversubscription)
-		if cpuAvail < 0 {
-			cpuAvail = 0
-		}
-		if memAvail < 0 {
-			memAvail = 0
-		}
-		if gpuAvail < 0 {
-			gpuAvail = 0
-		}
-		if storageAvail < 0 {
-			storageAvail = 0
-		}
+		// Compute available resources (Usable - Allocated)
+		// Values may be negative when a worker is over-committed; the feasibility
+		// filter adds over-commit headroom on top and the predictor treats <= 0 as saturated
+		cpuAvail := cpuTotal - worker.AllocatedCPU
+		memAvail := memTotal - worker.AllocatedMemory
+		gpuAvail := gpuTotal - worker.AllocatedGPU
+		storageAvail := storageTotal - worker.AllocatedStorage
 
 		// Compute normalized load from telemetry data
 		load := mts.computeNormalizedLoad(worker.WorkerID, telemetryData, &worker)
@@ -90,6 +90,10 @@ func (mts *MasterTelemetrySource) GetWorkerViews(ctx context.Context) ([]WorkerV
 			MemAvail:     memAvail,
 			GPUAvail:     gpuAvail,
 			StorageAvail: storageAvail,
+			CPUTotal:     cpuTotal,
+			MemTotal:     memTotal,
+			GPUTotal:     gpuTotal,
+			StorageTotal: storageTotal,
 			Load:         load,
 		}
 
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	"master/internal/telemetry"
+)
+
+// stubWorkerDB serves a fixed set of worker documents
+type stubWorkerDB struct {
+	workers []db.WorkerDocument
+}
+
+func (s *stubWorkerDB) GetWorker(ctx context.Context, workerID string) (*db.WorkerDocument, error) {
+	for i := range s.workers {
+		if s.workers[i].WorkerID == workerID {
+			return &s.workers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *stubWorkerDB) GetAllWorkers(ctx context.Context) ([]db.WorkerDocument, error) {
+	return s.workers, nil
+}
+
+// TestWorkerViewsExcludeSystemReserve tests that RTS sees capacity net of the system reserve
+func TestWorkerViewsExcludeSystemReserve(t *testing.T) {
+	telemetryMgr := telemetry.NewTelemetryManager(30 * time.Second)
+	defer telemetryMgr.Shutdown()
+
+	source := NewMasterTelemetrySource(telemetryMgr, &stubWorkerDB{workers: []db.WorkerDocument{
+		{WorkerID: "worker-1", IsActive: true, TotalCPU: 8, TotalMemory: 16, TotalStorage: 100, AllocatedCPU: 2},
+	}})
+	source.SetSystemReserve(SystemReserve{CPU: 1, Memory: 2})
+
+	views, err := source.GetWorkerViews(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(views) != 1 {
+		t.Fatalf("Expected 1 view, got %d", len(views))
+	}
+	if views[0].CPUTotal != 7 || views[0].CPUAvail != 5 {
+		t.Errorf("Expected usable CPU 7 with 5 available, got %.1f/%.1f", views[0].CPUTotal, views[0].CPUAvail)
+	}
+	if views[0].MemAvail != 14 {
+		t.Errorf("Expected 14 GB memory available after a 2 GB reserve, got %.1f", views[0].MemAvail)
+	}
+}
@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultZoneSpreadCapacity is the number of anti-affinity keys remembered before the oldest is evicted
+const DefaultZoneSpreadCapacity = 1024
+
+// ZoneSpreadTracker counts running tasks per zone for each anti-affinity key
+// Entries are kept in recency order and the least recently used key is evicted at capacity
+type ZoneSpreadTracker struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // Front = most recently used
+	mu       sync.Mutex
+}
+
+// zoneSpreadEntry holds the per-zone placement counts for one anti-affinity key
+type zoneSpreadEntry struct {
+	key   string
+	zones map[string]int
+}
+
+// NewZoneSpreadTracker creates a tracker that remembers up to capacity anti-affinity keys
+func NewZoneSpreadTracker(capacity int) *ZoneSpreadTracker {
+	if capacity <= 0 {
+		capacity = DefaultZoneSpreadCapacity
+	}
+	return &ZoneSpreadTracker{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// WorkerZone returns the zone used for spreading; unlabelled workers count as their own zone
+func WorkerZone(worker *WorkerInfo) string {
+	if worker.Zone != "" {
+		return worker.Zone
+	}
+	return "worker:" + worker.WorkerID
+}
+
+// Record notes that a task with the given anti-affinity key was placed in zone
+func (t *ZoneSpreadTracker) Record(key, zone string) {
+	if key == "" || zone == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, exists := t.entries[key]; exists {
+		elem.Value.(*zoneSpreadEntry).zones[zone]++
+		t.order.MoveToFront(elem)
+		return
+	}
+
+	t.entries[key] = t.order.PushFront(&zoneSpreadEntry{key: key, zones: map[string]int{zone: 1}})
+
+	if t.order.Len() > t.capacity {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.entries, oldest.Value.(*zoneSpreadEntry).key)
+	}
+}
+
+// Release notes that a task with the given anti-affinity key no longer runs in zone
+// A key with no tasks left in any zone is forgotten
+func (t *ZoneSpreadTracker) Release(key, zone string) {
+	if key == "" || zone == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elem, exists := t.entries[key]
+	if !exists {
+		return
+	}
+	entry := elem.Value.(*zoneSpreadEntry)
+	if entry.zones[zone] <= 1 {
+		delete(entry.zones, zone)
+	} else {
+		entry.zones[zone]--
+	}
+	if len(entry.zones) == 0 {
+		t.order.Remove(elem)
+		delete(t.entries, key)
+	}
+}
+
+// Count returns how many tasks with the given anti-affinity key were placed in zone
+func (t *ZoneSpreadTracker) Count(key, zone string) int {
+	if key == "" {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elem, exists := t.entries[key]
+	if !exists {
+		return 0
+	}
+	return elem.Value.(*zoneSpreadEntry).zones[zone]
+}
+
+// LeastUsed returns the candidate IDs whose zone holds the fewest tasks with the given key
+// A nil result means no restriction (no key, or fewer than two candidates)
+func (t *ZoneSpreadTracker) LeastUsed(key string, candidates []string, workers map[string]*WorkerInfo) map[string]bool {
+	if key == "" || len(candidates) < 2 {
+		return nil
+	}
+
+	counts := make(map[string]int, len(candidates))
+	fewest := -1
+	for _, id := range candidates {
+		worker, exists := workers[id]
+		if !exists {
+			continue
+		}
+		counts[id] = t.Count(key, WorkerZone(worker))
+		if fewest < 0 || counts[id] < fewest {
+			fewest = counts[id]
+		}
+	}
+
+	allowed := make(map[string]bool, len(counts))
+	for id, count := range counts {
+		if count == fewest {
+			allowed[id] = true
+		}
+	}
+	return allowed
+}
+
+// Len returns the number of anti-affinity keys currently tracked
+func (t *ZoneSpreadTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
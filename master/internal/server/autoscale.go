@@ -0,0 +1,156 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Autoscale recommendations returned by GetAutoscaleRecommendation
+const (
+	AutoscaleScaleUp   = "scale_up"
+	AutoscaleHold      = "hold"
+	AutoscaleScaleDown = "scale_down"
+)
+
+// DefaultAutoscaleWindow is how long a condition must hold before a scale recommendation is made
+const DefaultAutoscaleWindow = 2 * time.Minute
+
+// ScaleDownUtilization is the CPU and memory utilization (percent) under which an idle cluster may shrink
+const ScaleDownUtilization = 30.0
+
+// autoscaleSample is the queue and capacity of the cluster at the end of one queue pass
+type autoscaleSample struct {
+	at                                                time.Time
+	queueLength                                       int
+	queuedCPU, queuedMemory, queuedGPU                float64 // Resources requested by the tasks still queued
+	availableCPU, availableMemory, availableGPU       float64 // Free resources on active workers
+	cpuUtilization, memoryUtilization, gpuUtilization float64
+	activeWorkers                                     int
+}
+
+// AutoscaleRecommendation is a hint for an external autoscaler, based on the samples in the window
+// The shortfall is what the queued tasks request beyond the free capacity of active workers
+type AutoscaleRecommendation struct {
+	Action               string
+	Reason               string
+	Window               time.Duration
+	Samples              int
+	AvgQueueLength       float64
+	AvgCPUUtilization    float64
+	AvgMemoryUtilization float64
+	AvgGPUUtilization    float64
+	ActiveWorkers        int
+	ShortfallCPU         float64
+	ShortfallMemory      float64
+	ShortfallGPU         float64
+}
+
+// SetAutoscaleWindow sets how long the queue must stay backed up (or the cluster idle) before scaling is recommended
+func (s *MasterServer) SetAutoscaleWindow(window time.Duration) {
+	if window <= 0 {
+		window = DefaultAutoscaleWindow
+	}
+	s.mu.Lock()
+	s.autoscaleWindow = window
+	s.mu.Unlock()
+}
+
+// recordAutoscaleSample samples the queue and worker capacity for autoscale recommendations
+// Samples older than the window are dropped, except the newest of them, which marks the window as fully covered
+// This function assumes s.queueMu is already locked by the caller
+func (s *MasterServer) recordAutoscaleSample(now time.Time) {
+	sample := autoscaleSample{at: now, queueLength: len(s.taskQueue)}
+	for _, qt := range s.taskQueue {
+		sample.queuedCPU += qt.Task.ReqCpu
+		sample.queuedMemory += qt.Task.ReqMemory
+		sample.queuedGPU += qt.Task.ReqGpu
+	}
+
+	snapshot := s.GetClusterSnapshot()
+	for _, worker := range snapshot.Workers {
+		if worker.Status != "active" {
+			continue
+		}
+		sample.availableCPU += worker.AvailableCPU
+		sample.availableMemory += worker.AvailableMemory
+		sample.availableGPU += worker.AvailableGPU
+	}
+	sample.cpuUtilization = snapshot.CPUUtilization
+	sample.memoryUtilization = snapshot.MemoryUtilization
+	sample.gpuUtilization = snapshot.GPUUtilization
+	sample.activeWorkers = snapshot.ActiveWorkers
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.autoscaleSamples = append(s.autoscaleSamples, sample)
+
+	cutoff := now.Add(-s.autoscaleWindow)
+	drop := 0
+	for drop+1 < len(s.autoscaleSamples) && !s.autoscaleSamples[drop+1].at.After(cutoff) {
+		drop++
+	}
+	s.autoscaleSamples = s.autoscaleSamples[drop:]
+}
+
+// GetAutoscaleRecommendation recommends scaling up when the queue stayed backed up for the whole window,
+// scaling down when it stayed empty with low utilization and more than one active worker, and holding otherwise
+func (s *MasterServer) GetAutoscaleRecommendation(now time.Time) *AutoscaleRecommendation {
+	s.mu.RLock()
+	window := s.autoscaleWindow
+	samples := make([]autoscaleSample, 0, len(s.autoscaleSamples))
+	for _, sample := range s.autoscaleSamples {
+		if !sample.at.After(now) {
+			samples = append(samples, sample)
+		}
+	}
+	s.mu.RUnlock()
+
+	rec := &AutoscaleRecommendation{Action: AutoscaleHold, Window: window, Samples: len(samples)}
+	if len(samples) == 0 {
+		rec.Reason = "No samples collected yet"
+		return rec
+	}
+
+	backedUp, idle := true, true
+	for _, sample := range samples {
+		rec.AvgQueueLength += float64(sample.queueLength)
+		rec.AvgCPUUtilization += sample.cpuUtilization
+		rec.AvgMemoryUtilization += sample.memoryUtilization
+		rec.AvgGPUUtilization += sample.gpuUtilization
+		if sample.queueLength == 0 {
+			backedUp = false
+		} else {
+			idle = false
+		}
+	}
+	n := float64(len(samples))
+	rec.AvgQueueLength /= n
+	rec.AvgCPUUtilization /= n
+	rec.AvgMemoryUtilization /= n
+	rec.AvgGPUUtilization /= n
+
+	latest := samples[len(samples)-1]
+	rec.ActiveWorkers = latest.activeWorkers
+	rec.ShortfallCPU = math.Max(0, latest.queuedCPU-latest.availableCPU)
+	rec.ShortfallMemory = math.Max(0, latest.queuedMemory-latest.availableMemory)
+	rec.ShortfallGPU = math.Max(0, latest.queuedGPU-latest.availableGPU)
+
+	if now.Sub(samples[0].at) < window {
+		rec.Reason = fmt.Sprintf("Collecting samples (%s of %s window covered)", now.Sub(samples[0].at).Round(time.Second), window)
+		return rec
+	}
+
+	switch {
+	case backedUp:
+		rec.Action = AutoscaleScaleUp
+		rec.Reason = fmt.Sprintf("Queue backed up for %s (avg %.1f tasks)", window, rec.AvgQueueLength)
+	case idle && rec.ActiveWorkers > 1 &&
+		rec.AvgCPUUtilization < ScaleDownUtilization && rec.AvgMemoryUtilization < ScaleDownUtilization:
+		rec.Action = AutoscaleScaleDown
+		rec.Reason = fmt.Sprintf("Queue empty and utilization under %.0f%% for %s", ScaleDownUtilization, window)
+	default:
+		rec.Reason = "Load within capacity"
+	}
+	return rec
+}
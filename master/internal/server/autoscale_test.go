@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "master/proto"
+)
+
+// TestAutoscaleRecommendsScaleUpForSustainedBacklog tests that a queue backed up for the whole window yields scale_up with the shortfall
+func TestAutoscaleRecommendsScaleUpForSustainedBacklog(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.SetAutoscaleWindow(time.Minute)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+	worker, _ := ms.GetWorkerStats("worker-1")
+	worker.IsActive = true
+
+	// 10 CPU, 12 GB and 1 GPU queued against 4 CPU and 8 GB free
+	ms.EnqueueTask(&pb.Task{TaskId: "task-big", ReqCpu: 8.0, ReqMemory: 6.0}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "task-gpu", ReqCpu: 2.0, ReqMemory: 6.0, ReqGpu: 1.0}, "test")
+
+	start := time.Now()
+	for i := 0; i <= 6; i++ {
+		ms.recordAutoscaleSample(start.Add(time.Duration(i) * 10 * time.Second))
+	}
+
+	early := ms.GetAutoscaleRecommendation(start.Add(30 * time.Second))
+	if early.Action != AutoscaleHold {
+		t.Errorf("Expected hold before the window is covered, got %s (%s)", early.Action, early.Reason)
+	}
+
+	rec := ms.GetAutoscaleRecommendation(start.Add(time.Minute))
+	if rec.Action != AutoscaleScaleUp {
+		t.Fatalf("Expected scale_up after a full window of backlog, got %s (%s)", rec.Action, rec.Reason)
+	}
+	if rec.ShortfallCPU != 6.0 || rec.ShortfallMemory != 4.0 || rec.ShortfallGPU != 1.0 {
+		t.Errorf("Expected shortfall of 6 CPU, 4 GB, 1 GPU, got %.1f CPU, %.1f GB, %.1f GPU",
+			rec.ShortfallCPU, rec.ShortfallMemory, rec.ShortfallGPU)
+	}
+	if rec.AvgQueueLength != 2.0 {
+		t.Errorf("Expected average queue length 2, got %.1f", rec.AvgQueueLength)
+	}
+}
+
+// TestAutoscaleHoldsWhenBacklogClears tests that a backlog that drained during the window does not yield scale_up
+func TestAutoscaleHoldsWhenBacklogClears(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.SetAutoscaleWindow(time.Minute)
+	ms.EnqueueTask(&pb.Task{TaskId: "task-1", ReqCpu: 1.0}, "test")
+
+	start := time.Now()
+	ms.recordAutoscaleSample(start)
+	ms.queueMu.Lock()
+	ms.taskQueue = nil
+	ms.queueMu.Unlock()
+	ms.recordAutoscaleSample(start.Add(30 * time.Second))
+	ms.recordAutoscaleSample(start.Add(time.Minute))
+
+	if rec := ms.GetAutoscaleRecommendation(start.Add(time.Minute)); rec.Action != AutoscaleHold {
+		t.Errorf("Expected hold once the queue drained, got %s (%s)", rec.Action, rec.Reason)
+	}
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"master/internal/db"
+	"master/internal/logging"
+	pb "master/proto"
+)
+
+// cancelCandidate is an unfinished task a batch cancellation can match
+type cancelCandidate struct {
+	taskID      string
+	status      string // queued, retrying, held or running
+	userID      string
+	image       string
+	annotations map[string]string
+	stored      bool // Only known from the database (not queued, held or running in memory)
+}
+
+// matches reports whether the candidate matches every field set in the filter
+// A "queued" filter also matches retrying tasks, which are queued too
+func (c *cancelCandidate) matches(filter *pb.CancelFilter) bool {
+	if filter.Status != "" && filter.Status != c.status && !(filter.Status == "queued" && c.status == "retrying") {
+		return false
+	}
+	if filter.UserId != "" && filter.UserId != c.userID {
+		return false
+	}
+	if filter.DockerImage != "" && filter.DockerImage != c.image {
+		return false
+	}
+	if filter.Label != "" {
+		key, value, hasValue := strings.Cut(filter.Label, "=")
+		got, ok := c.annotations[key]
+		if !ok || (hasValue && got != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// batchCancelConcurrency is how many matched tasks a batch cancellation stops at once
+const batchCancelConcurrency = 8
+
+// CancelTasks cancels the requesting user's queued, held or running tasks matching a filter
+// Other users' tasks are never matched; see CancelTasksAsAdmin
+func (s *MasterServer) CancelTasks(ctx context.Context, filter *pb.CancelFilter) (*pb.CancelTasksAck, error) {
+	if filter.RequestingUser == "" {
+		return &pb.CancelTasksAck{Success: false, Message: "requesting_user is required"}, nil
+	}
+	if filter.UserId != "" && filter.UserId != filter.RequestingUser {
+		return &pb.CancelTasksAck{
+			Success: false,
+			Message: fmt.Sprintf("User %s cannot cancel tasks of user %s", filter.RequestingUser, filter.UserId),
+		}, nil
+	}
+	return s.CancelTasksAsAdmin(ctx, &pb.CancelFilter{
+		Status:         filter.Status,
+		UserId:         filter.RequestingUser,
+		DockerImage:    filter.DockerImage,
+		Label:          filter.Label,
+		RequestingUser: filter.RequestingUser,
+	})
+}
+
+// CancelTasksAsAdmin cancels every queued, held or running task matching a filter, whoever owns it, and reports how many were cancelled
+// Matches are resolved from the queue, held and running tasks and (for labels and tasks not in memory) the database
+func (s *MasterServer) CancelTasksAsAdmin(ctx context.Context, filter *pb.CancelFilter) (*pb.CancelTasksAck, error) {
+	if filter.Status == "" && filter.UserId == "" && filter.DockerImage == "" && filter.Label == "" {
+		return &pb.CancelTasksAck{Success: false, Message: "At least one filter (status, user, image or label) is required"}, nil
+	}
+	switch filter.Status {
+	case "", "queued", "retrying", "held", "running":
+	default:
+		return &pb.CancelTasksAck{
+			Success: false,
+			Message: fmt.Sprintf("Unknown status %q: expected queued, retrying, held or running", filter.Status),
+		}, nil
+	}
+
+	var matched []*cancelCandidate
+	for _, c := range s.cancelCandidates(ctx, filter) {
+		if c.matches(filter) {
+			matched = append(matched, c)
+		}
+	}
+
+	// Running tasks are stopped over RPC, so a few are cancelled at once rather than one slow worker at a time
+	cancelled := make([]bool, len(matched))
+	sem := make(chan struct{}, batchCancelConcurrency)
+	var wg sync.WaitGroup
+	for i, c := range matched {
+		wg.Add(1)
+		sem <- struct{}{} // Blocks while batchCancelConcurrency cancellations are in flight
+		go func(i int, c *cancelCandidate) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			cancelled[i] = s.cancelMatchedTask(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	ack := &pb.CancelTasksAck{Success: true, Matched: int32(len(matched))}
+	for i, c := range matched {
+		if cancelled[i] {
+			ack.Cancelled++
+			ack.TaskIds = append(ack.TaskIds, c.taskID)
+		} else {
+			ack.Failed++
+		}
+	}
+
+	ack.Message = fmt.Sprintf("Cancelled %d of %d matching tasks", ack.Cancelled, ack.Matched)
+	if ack.Failed > 0 {
+		ack.Message += fmt.Sprintf(" (%d could not be cancelled)", ack.Failed)
+	}
+	logging.Infof("🛑 Batch cancel [status=%q user=%q image=%q label=%q]: %s",
+		filter.Status, filter.UserId, filter.DockerImage, filter.Label, ack.Message)
+	return ack, nil
+}
+
+// cancelCandidates lists the unfinished tasks: queued and held tasks, tasks running on workers and,
+// when a database is configured, unfinished tasks only it knows about; database annotations are attached to all
+// Only stored tasks matching the filter's user, image and label are loaded from the database
+func (s *MasterServer) cancelCandidates(ctx context.Context, filter *pb.CancelFilter) []*cancelCandidate {
+	var candidates []*cancelCandidate
+	byID := make(map[string]*cancelCandidate)
+	add := func(c *cancelCandidate) {
+		if _, exists := byID[c.taskID]; !exists {
+			byID[c.taskID] = c
+			candidates = append(candidates, c)
+		}
+	}
+
+	s.queueMu.RLock()
+	for _, qt := range s.taskQueue {
+		status := "queued"
+		if qt.Retries > 0 {
+			status = "retrying"
+		}
+		add(&cancelCandidate{taskID: qt.Task.TaskId, status: status, userID: qt.Task.UserId, image: qt.Task.DockerImage})
+	}
+	for _, task := range s.heldTasks {
+		add(&cancelCandidate{taskID: task.TaskId, status: "held", userID: task.UserId, image: task.DockerImage})
+	}
+	s.queueMu.RUnlock()
+
+	s.mu.RLock()
+	for _, worker := range s.workers {
+		for taskID := range worker.RunningTasks {
+			c := &cancelCandidate{taskID: taskID, status: "running"}
+			if spec := s.runningSpecs[taskID]; spec != nil {
+				c.userID, c.image = spec.UserId, spec.DockerImage
+			}
+			add(c)
+		}
+	}
+	s.mu.RUnlock()
+
+	if s.taskDB == nil {
+		return candidates
+	}
+	records, err := s.taskDB.GetUnfinishedTasks(ctx, filter.UserId, filter.DockerImage, filter.Label)
+	if err != nil {
+		logging.Warnf("⚠ Batch cancel: failed to load tasks from database, matching in-memory tasks only: %v", err)
+		return candidates
+	}
+	for _, record := range records {
+		if c, exists := byID[record.TaskID]; exists {
+			c.annotations = record.Annotations
+			if c.userID == "" && c.image == "" {
+				c.userID, c.image = record.UserID, record.DockerImage
+			}
+			continue
+		}
+		if status := unfinishedStatus(record); status != "" {
+			add(&cancelCandidate{
+				taskID:      record.TaskID,
+				status:      status,
+				userID:      record.UserID,
+				image:       record.DockerImage,
+				annotations: record.Annotations,
+				stored:      true,
+			})
+		}
+	}
+	return candidates
+}
+
+// unfinishedStatus maps a stored task's status to a batch cancel status, or "" for a finished task
+func unfinishedStatus(record *db.Task) string {
+	switch record.Status {
+	case "queued", "pending":
+		return "queued"
+	case "held", "running":
+		return record.Status
+	default:
+		return ""
+	}
+}
+
+// cancelMatchedTask cancels one matched task, reporting whether it was cancelled
+// Queued and held tasks are dropped before they reach a worker; running tasks are stopped via CancelTask
+func (s *MasterServer) cancelMatchedTask(ctx context.Context, c *cancelCandidate) bool {
+	if c.status != "running" {
+		if s.dropPendingTask(c.taskID) || c.stored {
+			return s.markPendingTaskCancelled(ctx, c.taskID)
+		}
+		// Assigned to a worker since the candidates were listed
+	}
+
+	ack, err := s.CancelTask(ctx, &pb.TaskID{TaskId: c.taskID})
+	if err != nil {
+		logging.Warnf("  ⚠ Batch cancel: task %s not cancelled: %v", c.taskID, err)
+		return false
+	}
+	if !ack.Success {
+		logging.Warnf("  ⚠ Batch cancel: task %s not cancelled: %s", c.taskID, ack.Message)
+		return false
+	}
+	return true
+}
+
+// dropPendingTask removes a task from the queue or the held tasks, reporting whether it was there
+func (s *MasterServer) dropPendingTask(taskID string) bool {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	if _, held := s.heldTasks[taskID]; held {
+		delete(s.heldTasks, taskID)
+		return true
+	}
+	for i, qt := range s.taskQueue {
+		if qt.Task.TaskId == taskID {
+			s.taskQueue = append(s.taskQueue[:i:i], s.taskQueue[i+1:]...)
+			s.publishQueuePositions()
+			return true
+		}
+	}
+	return false
+}
+
+// markPendingTaskCancelled records a task that never reached a worker as cancelled
+func (s *MasterServer) markPendingTaskCancelled(ctx context.Context, taskID string) bool {
+	if s.taskDB != nil {
+		if err := s.taskDB.UpdateTaskStatus(ctx, taskID, "cancelled"); err != nil {
+			logging.Warnf("  ⚠ Batch cancel: failed to mark task %s as cancelled: %v", taskID, err)
+			return false
+		}
+	}
+	s.notifyTaskTerminal(ctx, taskID, "", "cancelled", nil)
+	logging.Infof("🛑 Task %s cancelled before reaching a worker", taskID)
+	return true
+}
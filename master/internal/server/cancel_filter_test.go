@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "master/proto"
+
+	"google.golang.org/grpc"
+)
+
+// TestCancelTasksByImageRemovesMatchingQueuedTasks tests that cancelling by image drops every queued task using it and keeps the rest
+func TestCancelTasksByImageRemovesMatchingQueuedTasks(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.EnqueueTask(&pb.Task{TaskId: "task-bad-1", DockerImage: "bad/image:1"}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "task-good", DockerImage: "alpine:3.20"}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "task-bad-2", DockerImage: "bad/image:1", UserId: "bob"}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "task-other-tag", DockerImage: "bad/image:2"}, "test")
+
+	ack, err := ms.CancelTasksAsAdmin(context.Background(), &pb.CancelFilter{DockerImage: "bad/image:1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !ack.Success || ack.Matched != 2 || ack.Cancelled != 2 || ack.Failed != 0 {
+		t.Fatalf("Expected 2 matched and cancelled, got %+v", ack)
+	}
+	if len(ack.TaskIds) != 2 || ack.TaskIds[0] != "task-bad-1" || ack.TaskIds[1] != "task-bad-2" {
+		t.Errorf("Expected task-bad-1 and task-bad-2 cancelled, got %v", ack.TaskIds)
+	}
+
+	queue := ms.GetQueuedTasks()
+	if len(queue) != 2 || queue[0].Task.TaskId != "task-good" || queue[1].Task.TaskId != "task-other-tag" {
+		ids := make([]string, len(queue))
+		for i, qt := range queue {
+			ids[i] = qt.Task.TaskId
+		}
+		t.Errorf("Expected task-good and task-other-tag to stay queued, got %v", ids)
+	}
+
+	// An empty filter would cancel everything and is refused
+	ack, _ = ms.CancelTasksAsAdmin(context.Background(), &pb.CancelFilter{})
+	if ack.Success || len(ms.GetQueuedTasks()) != 2 {
+		t.Errorf("Expected an empty filter to be rejected, got %+v", ack)
+	}
+}
+
+// TestCancelTasksOnlyCancelsRequestingUsersTasks tests that a batch cancel without admin rights leaves other users' tasks alone
+func TestCancelTasksOnlyCancelsRequestingUsersTasks(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.EnqueueTask(&pb.Task{TaskId: "task-alice", DockerImage: "bad/image:1", UserId: "alice"}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "task-bob", DockerImage: "bad/image:1", UserId: "bob"}, "test")
+
+	ack, err := ms.CancelTasks(context.Background(), &pb.CancelFilter{DockerImage: "bad/image:1", RequestingUser: "alice"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !ack.Success || ack.Cancelled != 1 || len(ack.TaskIds) != 1 || ack.TaskIds[0] != "task-alice" {
+		t.Fatalf("Expected only task-alice cancelled, got %+v", ack)
+	}
+	if queue := ms.GetQueuedTasks(); len(queue) != 1 || queue[0].Task.TaskId != "task-bob" {
+		t.Errorf("Expected task-bob to stay queued, got %d queued tasks", len(queue))
+	}
+
+	// Naming another user, or no user at all, is refused
+	if ack, _ := ms.CancelTasks(context.Background(), &pb.CancelFilter{UserId: "bob", RequestingUser: "alice"}); ack.Success {
+		t.Errorf("Expected alice to be refused cancelling bob's tasks, got %+v", ack)
+	}
+	if ack, _ := ms.CancelTasks(context.Background(), &pb.CancelFilter{DockerImage: "bad/image:1"}); ack.Success {
+		t.Errorf("Expected a cancel without requesting_user to be refused, got %+v", ack)
+	}
+	if len(ms.GetQueuedTasks()) != 1 {
+		t.Error("Expected task-bob to survive the refused cancellations")
+	}
+}
+
+// overlapStoppingWorker is a worker stub whose CancelTask waits until another cancel is in flight too
+type overlapStoppingWorker struct {
+	pb.UnimplementedMasterWorkerServer
+
+	mu       sync.Mutex
+	inFlight int
+	overlap  chan struct{}
+}
+
+func (w *overlapStoppingWorker) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.TaskAck, error) {
+	w.mu.Lock()
+	w.inFlight++
+	if w.inFlight == 2 {
+		close(w.overlap)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.overlap:
+		return &pb.TaskAck{Success: true, Message: "Task cancelled"}, nil
+	case <-time.After(2 * time.Second):
+		return &pb.TaskAck{Success: false, Message: "no other cancel in flight"}, nil
+	}
+}
+
+// TestCancelTasksStopsRunningTasksConcurrently tests that a batch cancel stops two running tasks at once rather than one after the other
+func TestCancelTasksStopsRunningTasksConcurrently(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	stub := &overlapStoppingWorker{overlap: make(chan struct{})}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, stub)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	worker, _ := ms.GetWorkerStats("worker-1")
+	ms.mu.Lock()
+	for _, taskID := range []string{"task-1", "task-2"} {
+		worker.RunningTasks[taskID] = true
+		ms.runningSpecs[taskID] = &pb.Task{TaskId: taskID, DockerImage: "bad/image:1"}
+	}
+	ms.mu.Unlock()
+
+	ack, err := ms.CancelTasksAsAdmin(context.Background(), &pb.CancelFilter{DockerImage: "bad/image:1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Each worker cancel only succeeds once the other is in flight, so both succeed only if they overlapped
+	if !ack.Success || ack.Matched != 2 || ack.Cancelled != 2 {
+		t.Fatalf("Expected both running tasks to be cancelled concurrently, got %+v", ack)
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if len(worker.RunningTasks) != 0 {
+		t.Errorf("Expected no running tasks left, got %v", worker.RunningTasks)
+	}
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"master/internal/logging"
+	pb "master/proto"
+)
+
+// DefaultCapacityReservationTTL is how long a capacity reservation holds resources when no TTL is given
+const DefaultCapacityReservationTTL = 10 * time.Minute
+
+// Capacity is an amount of CPU, memory (GB) and GPU
+type Capacity struct {
+	CPU    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+	GPU    float64 `json:"gpu"`
+}
+
+// asTask wraps the amount in a task so it can be applied with adjustAllocation
+func (c Capacity) asTask() *pb.Task {
+	return &pb.Task{ReqCpu: c.CPU, ReqMemory: c.Memory, ReqGpu: c.GPU}
+}
+
+// CapacityReservation holds a block of cluster capacity for a batch of tasks until it expires or is released
+// The block is spread over workers as holds. Tasks submitted with its ID draw down Remaining, and when one is
+// placed on a worker holding part of the block, that part of the hold becomes the task's own allocation
+type CapacityReservation struct {
+	ID        string              `json:"reservation_id"`
+	Reserved  Capacity            `json:"reserved"`
+	Remaining Capacity            `json:"remaining"`
+	Holds     map[string]Capacity `json:"holds"` // Worker ID -> capacity still held there
+	CreatedAt time.Time           `json:"created_at"`
+	ExpiresAt time.Time           `json:"expires_at"`
+}
+
+// snapshot returns a copy that is safe to use without holding s.mu
+func (r *CapacityReservation) snapshot() *CapacityReservation {
+	copied := *r
+	copied.Holds = make(map[string]Capacity, len(r.Holds))
+	for workerID, hold := range r.Holds {
+		copied.Holds[workerID] = hold
+	}
+	return &copied
+}
+
+// ReserveCapacity holds the requested capacity across active workers for ttl
+// The block is taken whole or not at all; it fails when the free capacity of the cluster is too small
+func (s *MasterServer) ReserveCapacity(want Capacity, ttl time.Duration, now time.Time) (*CapacityReservation, error) {
+	if ttl <= 0 {
+		ttl = DefaultCapacityReservationTTL
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workerIDs := make([]string, 0, len(s.workers))
+	var free Capacity
+	for id, worker := range s.workers {
+		if !worker.IsActive || worker.Cordoned || worker.Info.WorkerIp == "" {
+			continue
+		}
+		workerIDs = append(workerIDs, id)
+		free.CPU += math.Max(worker.AvailableCPU, 0)
+		free.Memory += math.Max(worker.AvailableMemory, 0)
+		free.GPU += math.Max(worker.AvailableGPU, 0)
+	}
+	if free.CPU < want.CPU || free.Memory < want.Memory || free.GPU < want.GPU {
+		return nil, fmt.Errorf("insufficient free capacity: cluster has %.2f CPU, %.2f GB memory, %.2f GPU free",
+			free.CPU, free.Memory, free.GPU)
+	}
+	sort.Strings(workerIDs)
+
+	reservation := &CapacityReservation{
+		ID:        fmt.Sprintf("res-%d", now.UnixNano()),
+		Reserved:  want,
+		Remaining: want,
+		Holds:     make(map[string]Capacity),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	need := want
+	for _, id := range workerIDs {
+		worker := s.workers[id]
+		hold := Capacity{
+			CPU:    math.Min(need.CPU, math.Max(worker.AvailableCPU, 0)),
+			Memory: math.Min(need.Memory, math.Max(worker.AvailableMemory, 0)),
+			GPU:    math.Min(need.GPU, math.Max(worker.AvailableGPU, 0)),
+		}
+		if hold == (Capacity{}) {
+			continue
+		}
+		adjustAllocation(worker, hold.asTask(), 1)
+		reservation.Holds[id] = hold
+		need.CPU -= hold.CPU
+		need.Memory -= hold.Memory
+		need.GPU -= hold.GPU
+	}
+	s.capacityReservations[reservation.ID] = reservation
+
+	logging.Infof("📦 Capacity reservation %s: %.2f CPU, %.2f GB memory, %.2f GPU on %d worker(s) until %s",
+		reservation.ID, want.CPU, want.Memory, want.GPU, len(reservation.Holds), reservation.ExpiresAt.Format(time.RFC3339))
+	return reservation.snapshot(), nil
+}
+
+// GetCapacityReservation returns a copy of a live capacity reservation
+func (s *MasterServer) GetCapacityReservation(id string) (*CapacityReservation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reservation, exists := s.capacityReservations[id]
+	if !exists {
+		return nil, false
+	}
+	return reservation.snapshot(), true
+}
+
+// ReleaseCapacityReservation returns the capacity a reservation still holds to its workers
+// The returned copy lists the holds that were released
+func (s *MasterServer) ReleaseCapacityReservation(id string) (*CapacityReservation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, exists := s.capacityReservations[id]
+	if !exists {
+		return nil, false
+	}
+	released := reservation.snapshot()
+	s.dropCapacityReservation(reservation)
+	logging.Infof("📦 Capacity reservation %s released", id)
+	return released, true
+}
+
+// releaseExpiredCapacityReservations drops capacity reservations that outlived their TTL
+// Returns the number of reservations released
+func (s *MasterServer) releaseExpiredCapacityReservations(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	released := 0
+	for id, reservation := range s.capacityReservations {
+		if now.Before(reservation.ExpiresAt) {
+			continue
+		}
+		s.dropCapacityReservation(reservation)
+		released++
+		logging.Infof("⏰ Capacity reservation %s expired - released its remaining holds", id)
+	}
+	return released
+}
+
+// dropCapacityReservation returns every hold of a reservation to its worker and forgets it
+// Caller must hold s.mu
+func (s *MasterServer) dropCapacityReservation(reservation *CapacityReservation) {
+	for workerID, hold := range reservation.Holds {
+		if worker, exists := s.workers[workerID]; exists {
+			adjustAllocation(worker, hold.asTask(), -1)
+		}
+	}
+	delete(s.capacityReservations, reservation.ID)
+}
+
+// checkCapacityReservation checks at submission that a task's reservation exists and still has room for it
+// Nothing is drawn until the task is placed, so queued, held and cancelled tasks never tie up the reservation
+func (s *MasterServer) checkCapacityReservation(task *pb.Task, now time.Time) *pb.TaskAck {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reservation, exists := s.capacityReservations[task.ReservationId]
+	if !exists || !now.Before(reservation.ExpiresAt) {
+		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Capacity reservation %s not found or expired", task.ReservationId)}
+	}
+	return reservationShortfall(reservation, task)
+}
+
+// reservationShortfall returns a failed ack when a reservation has too little capacity left for a task
+func reservationShortfall(reservation *CapacityReservation, task *pb.Task) *pb.TaskAck {
+	remaining := reservation.Remaining
+	switch {
+	case task.ReqCpu > remaining.CPU:
+		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Reservation %s has %.2f CPU left, task requires %.2f", reservation.ID, remaining.CPU, task.ReqCpu), ErrorCode: pb.ErrorCode_INSUFFICIENT_CPU}
+	case task.ReqMemory > remaining.Memory:
+		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Reservation %s has %.2f GB memory left, task requires %.2f GB", reservation.ID, remaining.Memory, task.ReqMemory), ErrorCode: pb.ErrorCode_INSUFFICIENT_MEMORY}
+	case task.ReqGpu > remaining.GPU:
+		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Reservation %s has %.2f GPU left, task requires %.2f", reservation.ID, remaining.GPU, task.ReqGpu), ErrorCode: pb.ErrorCode_INSUFFICIENT_GPU}
+	}
+	return nil
+}
+
+// drawCapacityReservation charges a task being placed against its reservation's remaining capacity
+// A task whose reservation has since expired or been released is placed like any other task
+// Returns whether anything was drawn, or a failed ack when the reservation is too small for the task
+// Caller must hold s.mu
+func (s *MasterServer) drawCapacityReservation(task *pb.Task) (bool, *pb.TaskAck) {
+	reservation, exists := s.capacityReservations[task.ReservationId]
+	if task.ReservationId == "" || !exists {
+		return false, nil
+	}
+	if nack := reservationShortfall(reservation, task); nack != nil {
+		return false, nack
+	}
+	reservation.Remaining.CPU -= task.ReqCpu
+	reservation.Remaining.Memory -= task.ReqMemory
+	reservation.Remaining.GPU -= task.ReqGpu
+	return true, nil
+}
+
+// refundCapacityReservation gives back what drawCapacityReservation charged when the task was not placed after all
+// Caller must hold s.mu
+func (s *MasterServer) refundCapacityReservation(task *pb.Task) {
+	reservation, exists := s.capacityReservations[task.ReservationId]
+	if !exists {
+		return
+	}
+	reservation.Remaining.CPU += task.ReqCpu
+	reservation.Remaining.Memory += task.ReqMemory
+	reservation.Remaining.GPU += task.ReqGpu
+}
+
+// reservationWorkers returns the workers a task's live reservation holds capacity on, or nil when placement is unconstrained
+// Caller must hold s.mu
+func (s *MasterServer) reservationWorkers(task *pb.Task) map[string]Capacity {
+	if task.ReservationId == "" {
+		return nil
+	}
+	reservation, exists := s.capacityReservations[task.ReservationId]
+	if !exists {
+		return nil
+	}
+	return reservation.Holds
+}
+
+// reservedFor returns what a task's reservation still holds on a worker
+// Caller must hold s.mu
+func (s *MasterServer) reservedFor(task *pb.Task, workerID string) Capacity {
+	if task.ReservationId == "" {
+		return Capacity{}
+	}
+	reservation, exists := s.capacityReservations[task.ReservationId]
+	if !exists {
+		return Capacity{}
+	}
+	return reservation.Holds[workerID]
+}
+
+// claimReservedCapacity hands the part of a task's reservation held on its worker back to the worker,
+// so the task's own allocation takes its place instead of competing with the hold; returns what was claimed
+// Caller must hold s.mu
+func (s *MasterServer) claimReservedCapacity(task *pb.Task, workerID string, worker *WorkerState) Capacity {
+	hold := s.reservedFor(task, workerID)
+	claimed := Capacity{
+		CPU:    math.Min(hold.CPU, task.ReqCpu),
+		Memory: math.Min(hold.Memory, task.ReqMemory),
+		GPU:    math.Min(hold.GPU, task.ReqGpu),
+	}
+	if claimed == (Capacity{}) {
+		return claimed
+	}
+	s.moveHold(task.ReservationId, workerID, worker, claimed, -1)
+	return claimed
+}
+
+// restoreReservedCapacity puts a claim back into the reservation when the task could not be placed after all
+// Caller must hold s.mu
+func (s *MasterServer) restoreReservedCapacity(task *pb.Task, workerID string, worker *WorkerState, claimed Capacity) {
+	if claimed == (Capacity{}) {
+		return
+	}
+	if _, exists := s.capacityReservations[task.ReservationId]; exists {
+		s.moveHold(task.ReservationId, workerID, worker, claimed, 1)
+	}
+}
+
+// heldCapacity returns what live capacity reservations hold on a worker
+// Holds exist only in memory, so reconciling a worker against the database must add them back
+// Caller must hold s.mu
+func (s *MasterServer) heldCapacity(workerID string) Capacity {
+	var held Capacity
+	for _, reservation := range s.capacityReservations {
+		hold := reservation.Holds[workerID]
+		held.CPU += hold.CPU
+		held.Memory += hold.Memory
+		held.GPU += hold.GPU
+	}
+	return held
+}
+
+// moveHold grows (sign 1) or shrinks (sign -1) a reservation's hold on a worker, allocating or freeing the difference
+// Caller must hold s.mu
+func (s *MasterServer) moveHold(reservationID, workerID string, worker *WorkerState, amount Capacity, sign float64) {
+	reservation := s.capacityReservations[reservationID]
+	adjustAllocation(worker, amount.asTask(), sign)
+
+	hold := reservation.Holds[workerID]
+	hold.CPU += sign * amount.CPU
+	hold.Memory += sign * amount.Memory
+	hold.GPU += sign * amount.GPU
+	if hold == (Capacity{}) {
+		delete(reservation.Holds, workerID)
+	} else {
+		reservation.Holds[workerID] = hold
+	}
+}
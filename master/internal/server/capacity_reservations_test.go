@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	pb "master/proto"
+
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"google.golang.org/grpc"
+)
+
+// TestCapacityReservationIsClaimedOnPlacement tests that reserved capacity is kept from other tasks but usable by tasks drawing on it
+func TestCapacityReservationIsClaimedOnPlacement(t *testing.T) {
+	ms := newReservationTestServer(t, 4.0)
+
+	reservation, err := ms.ReserveCapacity(Capacity{CPU: 4.0, Memory: 4.0}, time.Minute, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to reserve capacity: %v", err)
+	}
+
+	// A task outside the reservation cannot use the held CPU
+	ack, err := ms.assignTaskToWorker(context.Background(), &pb.Task{TaskId: "task-other", ReqCpu: 1.0, ReqMemory: 1.0}, "worker-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ack.Success || ack.ErrorCode != pb.ErrorCode_INSUFFICIENT_CPU {
+		t.Fatalf("Expected INSUFFICIENT_CPU for a task outside the reservation, got success=%v code=%s", ack.Success, ack.ErrorCode)
+	}
+
+	// A task drawing on the reservation takes over part of the hold
+	task := &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0, ReservationId: reservation.ID}
+	if nack := ms.checkCapacityReservation(task, time.Now()); nack != nil {
+		t.Fatalf("Expected the task to fit the reservation, got %s", nack.Message)
+	}
+	if held, _ := ms.GetCapacityReservation(reservation.ID); held.Remaining.CPU != 4.0 {
+		t.Errorf("Expected nothing to be drawn before placement, got %.1f CPU remaining", held.Remaining.CPU)
+	}
+	ack, err = ms.assignTaskToWorker(context.Background(), task, "worker-1")
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected the reserved task to be assigned, got ack=%v err=%v", ack, err)
+	}
+
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if worker.AllocatedCPU != 4.0 || worker.AvailableCPU != 0 {
+		t.Errorf("Expected 4 CPU allocated (3 held + 1 running) and none available, got %.1f allocated and %.1f available", worker.AllocatedCPU, worker.AvailableCPU)
+	}
+	held, _ := ms.GetCapacityReservation(reservation.ID)
+	if hold := held.Holds["worker-1"]; hold.CPU != 3.0 || hold.Memory != 3.0 {
+		t.Errorf("Expected the hold to shrink to 3 CPU and 3 GB, got %+v", hold)
+	}
+	if held.Remaining.CPU != 3.0 {
+		t.Errorf("Expected placement to draw 1 CPU from the reservation, got %.1f CPU remaining", held.Remaining.CPU)
+	}
+
+	// Expiry returns the rest of the hold
+	if released := ms.releaseExpiredCapacityReservations(time.Now().Add(2 * time.Minute)); released != 1 {
+		t.Fatalf("Expected 1 expired reservation, got %d", released)
+	}
+	if worker.AllocatedCPU != 1.0 || worker.AvailableCPU != 3.0 {
+		t.Errorf("Expected only the running task to stay allocated, got %.1f allocated and %.1f available", worker.AllocatedCPU, worker.AvailableCPU)
+	}
+}
+
+// TestCapacityReservationIsRestoredWhenAssignmentFails tests that a task the worker never received gives back its draw and claim
+func TestCapacityReservationIsRestoredWhenAssignmentFails(t *testing.T) {
+	ms := newReservationTestServer(t, 4.0)
+	reservation, err := ms.ReserveCapacity(Capacity{CPU: 4.0, Memory: 4.0}, time.Minute, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to reserve capacity: %v", err)
+	}
+	ms.dialWorker = func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	task := &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0, ReservationId: reservation.ID}
+	ack, _ := ms.assignTaskToWorker(context.Background(), task, "worker-1")
+	if ack.Success {
+		t.Fatal("Expected the assignment to fail")
+	}
+
+	held, _ := ms.GetCapacityReservation(reservation.ID)
+	if held.Remaining.CPU != 4.0 || held.Remaining.Memory != 4.0 {
+		t.Errorf("Expected the draw to be refunded, got %+v remaining", held.Remaining)
+	}
+	if hold := held.Holds["worker-1"]; hold.CPU != 4.0 || hold.Memory != 4.0 {
+		t.Errorf("Expected the hold to be restored to 4 CPU and 4 GB, got %+v", hold)
+	}
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if worker.AllocatedCPU != 4.0 {
+		t.Errorf("Expected only the hold to stay allocated (4 CPU), got %.1f", worker.AllocatedCPU)
+	}
+}
+
+// TestCapacityReservationConstrainsPlacement tests that a task drawing on a reservation only runs where the reservation holds capacity
+func TestCapacityReservationConstrainsPlacement(t *testing.T) {
+	ms := newReservationTestServer(t, 4.0)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-2", "127.0.0.1:1"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-2", 16.0, 32.0, 100.0, 0.0)
+
+	// The whole block fits on worker-1, which comes first
+	reservation, err := ms.ReserveCapacity(Capacity{CPU: 2.0, Memory: 2.0}, time.Minute, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to reserve capacity: %v", err)
+	}
+	task := &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0, ReservationId: reservation.ID}
+
+	if selected := ms.selectWorkerForTask(task); selected != "worker-1" {
+		t.Errorf("Expected the reserved task to be placed on worker-1, got %q", selected)
+	}
+	ack, _ := ms.assignTaskToWorker(context.Background(), task, "worker-2")
+	if ack.Success {
+		t.Error("Expected the reserved task to be refused on worker-2, which holds none of the reservation")
+	}
+	if held, _ := ms.GetCapacityReservation(reservation.ID); held.Remaining.CPU != 2.0 {
+		t.Errorf("Expected a refused placement to draw nothing, got %.1f CPU remaining", held.Remaining.CPU)
+	}
+}
+
+// TestReconcileKeepsCapacityReservationHolds tests that reconciling a worker while a reservation holds capacity on it
+// keeps the hold, so releasing the reservation afterwards returns the allocation exactly to zero
+func TestReconcileKeepsCapacityReservationHolds(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("live reservation", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), db.NewAssignmentDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+
+		reservation, err := ms.ReserveCapacity(Capacity{CPU: 3.0, Memory: 2.0}, time.Minute, time.Now())
+		if err != nil {
+			t.Fatalf("Failed to reserve capacity: %v", err)
+		}
+
+		// No tasks are running, once for the single-worker and once for the full reconcile
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch))
+		if _, err := ms.ReconcileWorker(context.Background(), "worker-1"); err != nil {
+			t.Fatalf("Failed to reconcile worker: %v", err)
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch))
+		summary, err := ms.ReconcileWorkerResourcesWithSummary(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to reconcile workers: %v", err)
+		}
+		if summary.WorkersFixed != 0 {
+			t.Errorf("Expected no worker to need fixing, got %d", summary.WorkersFixed)
+		}
+
+		worker, _ := ms.GetWorkerStats("worker-1")
+		if worker.AllocatedCPU != 3.0 || worker.AllocatedMemory != 2.0 || worker.AvailableCPU != 1.0 {
+			t.Errorf("Expected the 3 CPU / 2 GB hold to survive reconciliation, got %.1f CPU and %.1f GB allocated, %.1f CPU available",
+				worker.AllocatedCPU, worker.AllocatedMemory, worker.AvailableCPU)
+		}
+
+		if _, ok := ms.ReleaseCapacityReservation(reservation.ID); !ok {
+			t.Fatal("Expected the reservation to be released")
+		}
+		if worker.AllocatedCPU != 0 || worker.AllocatedMemory != 0 || worker.AvailableCPU != 4.0 || worker.AvailableMemory != 8.0 {
+			t.Errorf("Expected allocation back at zero after release, got %.1f CPU and %.1f GB allocated, %.1f CPU and %.1f GB available",
+				worker.AllocatedCPU, worker.AllocatedMemory, worker.AvailableCPU, worker.AvailableMemory)
+		}
+	})
+}
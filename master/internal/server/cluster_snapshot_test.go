@@ -0,0 +1,210 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	pb "master/proto"
+)
+
+// newSnapshotTestServer builds a master with n in-memory workers in a mix of states
+func newSnapshotTestServer(n int) *MasterServer {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		workerID := fmt.Sprintf("worker-%04d", i)
+		worker := &WorkerState{
+			Info: &pb.WorkerInfo{
+				WorkerId:     workerID,
+				WorkerIp:     fmt.Sprintf("10.0.%d.%d:50052", i/256, i%256),
+				TotalCpu:     float64(8 + i%4),
+				TotalMemory:  float64(16 + i%8),
+				TotalStorage: 100,
+				TotalGpu:     float64(i % 2),
+			},
+			IsActive:         i%5 != 0,
+			RunningTasks:     map[string]bool{},
+			LatestCPU:        float64(i % 100),
+			LatestMemory:     float64((i * 7) % 100),
+			AllocatedCPU:     float64(i % 4),
+			AllocatedMemory:  float64(i % 8),
+			AllocatedStorage: 10,
+			AllocatedGPU:     float64(i % 2),
+			AvailableCPU:     8,
+			AvailableMemory:  16,
+			AvailableStorage: 90,
+			DisplayName:      fmt.Sprintf("node %d", i),
+			Annotations:      map[string]string{"rack": fmt.Sprintf("r%d", i%10)},
+		}
+		// Heartbeats 30 minutes old, plus a few workers that never sent one
+		if i%7 != 0 {
+			worker.LastHeartbeat = now.Add(-30 * time.Minute).Unix()
+		}
+		for t := 0; t < i%4; t++ {
+			worker.RunningTasks[fmt.Sprintf("task-%d-%d", i, t)] = true
+		}
+		ms.workers[workerID] = worker
+	}
+	return ms
+}
+
+// legacyClusterSnapshot is GetClusterSnapshot as it was before the lock was narrowed: everything is built under s.mu
+func legacyClusterSnapshot(s *MasterServer) *ClusterSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := &ClusterSnapshot{
+		Timestamp: time.Now(),
+		Workers:   []WorkerStateSnapshot{},
+	}
+
+	for workerID, worker := range s.workers {
+		heartbeatAgo := "never"
+		if worker.LastHeartbeat > 0 {
+			duration := time.Since(time.Unix(worker.LastHeartbeat, 0))
+			if duration < 60*time.Second {
+				heartbeatAgo = fmt.Sprintf("%ds ago", int(duration.Seconds()))
+			} else if duration < 60*time.Minute {
+				heartbeatAgo = fmt.Sprintf("%dm ago", int(duration.Minutes()))
+			} else {
+				heartbeatAgo = fmt.Sprintf("%dh ago", int(duration.Hours()))
+			}
+		}
+
+		status := "active"
+		if !worker.IsActive {
+			status = "inactive"
+		}
+
+		runningTasks := []string{}
+		if worker.RunningTasks != nil {
+			for taskID := range worker.RunningTasks {
+				runningTasks = append(runningTasks, taskID)
+			}
+		}
+
+		var totalCPU, totalMemory, totalStorage, totalGPU float64
+		var workerIP string
+		if worker.Info != nil {
+			totalCPU = worker.Info.TotalCpu
+			totalMemory = worker.Info.TotalMemory
+			totalStorage = worker.Info.TotalStorage
+			totalGPU = worker.Info.TotalGpu
+			workerIP = worker.Info.WorkerIp
+		}
+
+		snapshot.Workers = append(snapshot.Workers, WorkerStateSnapshot{
+			WorkerID:         workerID,
+			WorkerIP:         workerIP,
+			Status:           status,
+			LastHeartbeat:    worker.LastHeartbeat,
+			HeartbeatAgo:     heartbeatAgo,
+			CPUUsage:         worker.LatestCPU,
+			MemoryUsage:      worker.LatestMemory,
+			GPUUsage:         worker.LatestGPU,
+			TotalCPU:         totalCPU,
+			TotalMemory:      totalMemory,
+			TotalStorage:     totalStorage,
+			TotalGPU:         totalGPU,
+			AllocatedCPU:     worker.AllocatedCPU,
+			AllocatedMemory:  worker.AllocatedMemory,
+			AllocatedStorage: worker.AllocatedStorage,
+			AllocatedGPU:     worker.AllocatedGPU,
+			AvailableCPU:     worker.AvailableCPU,
+			AvailableMemory:  worker.AvailableMemory,
+			AvailableStorage: worker.AvailableStorage,
+			AvailableGPU:     worker.AvailableGPU,
+			RunningTasks:     runningTasks,
+			TaskCount:        len(runningTasks),
+			DisplayName:      worker.DisplayName,
+			Annotations:      copyAnnotations(worker.Annotations),
+		})
+
+		snapshot.TotalWorkers++
+		if worker.IsActive {
+			snapshot.ActiveWorkers++
+		}
+		if worker.RunningTasks != nil {
+			snapshot.TotalTasks += len(worker.RunningTasks)
+		}
+		snapshot.TotalCPU += totalCPU
+		snapshot.TotalMemory += totalMemory
+		snapshot.TotalGPU += totalGPU
+		snapshot.AllocatedCPU += worker.AllocatedCPU
+		snapshot.AllocatedMemory += worker.AllocatedMemory
+		snapshot.AllocatedGPU += worker.AllocatedGPU
+		snapshot.AvailableCPU += worker.AvailableCPU
+		snapshot.AvailableMemory += worker.AvailableMemory
+		snapshot.AvailableGPU += worker.AvailableGPU
+	}
+
+	snapshot.InactiveWorkers = snapshot.TotalWorkers - snapshot.ActiveWorkers
+	if snapshot.TotalCPU > 0 {
+		snapshot.CPUUtilization = (snapshot.AllocatedCPU / snapshot.TotalCPU) * 100
+	}
+	if snapshot.TotalMemory > 0 {
+		snapshot.MemoryUtilization = (snapshot.AllocatedMemory / snapshot.TotalMemory) * 100
+	}
+	if snapshot.TotalGPU > 0 {
+		snapshot.GPUUtilization = (snapshot.AllocatedGPU / snapshot.TotalGPU) * 100
+	}
+
+	return snapshot
+}
+
+// normalizeSnapshot orders workers and their tasks so snapshots built from map iteration can be compared
+func normalizeSnapshot(snapshot *ClusterSnapshot) {
+	snapshot.Timestamp = time.Time{}
+	sort.Slice(snapshot.Workers, func(i, j int) bool {
+		return snapshot.Workers[i].WorkerID < snapshot.Workers[j].WorkerID
+	})
+	for _, worker := range snapshot.Workers {
+		sort.Strings(worker.RunningTasks)
+	}
+}
+
+// TestClusterSnapshotMatchesLegacyAggregation tests that the narrowed-lock snapshot equals the one built entirely under the lock
+func TestClusterSnapshotMatchesLegacyAggregation(t *testing.T) {
+	for _, n := range []int{0, 1, 250} {
+		ms := newSnapshotTestServer(n)
+
+		got := ms.GetClusterSnapshot()
+		want := legacyClusterSnapshot(ms)
+		normalizeSnapshot(got)
+		normalizeSnapshot(want)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("With %d workers expected snapshot %+v, got %+v", n, want, got)
+		}
+	}
+}
+
+// BenchmarkClusterSnapshotLockHold measures how long a 1,000-worker snapshot holds s.mu, blocking heartbeats
+// The legacy snapshot holds the lock for the whole build; the narrowed one only while copying worker state
+func BenchmarkClusterSnapshotLockHold(b *testing.B) {
+	ms := newSnapshotTestServer(1000)
+
+	b.Run("legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyClusterSnapshot(ms)
+		}
+	})
+	b.Run("narrowed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ms.copyWorkerStates()
+		}
+	})
+}
+
+// BenchmarkGetClusterSnapshot measures building a snapshot of 1,000 workers
+func BenchmarkGetClusterSnapshot(b *testing.B) {
+	ms := newSnapshotTestServer(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ms.GetClusterSnapshot()
+	}
+}
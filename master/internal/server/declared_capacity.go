@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"master/internal/db"
+	"master/internal/logging"
+	pb "master/proto"
+)
+
+// DefaultCapacityTolerance is how many times its declared capacity a worker may claim before it is flagged
+const DefaultCapacityTolerance = 2.0
+
+// SetCapacityVerification sets how far a registering worker's claimed capacity may exceed its declared
+// capacity (a multiple, e.g. 2.0) and whether a worker beyond that is rejected instead of only flagged
+func (s *MasterServer) SetCapacityVerification(tolerance float64, reject bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tolerance < 1 {
+		tolerance = DefaultCapacityTolerance
+	}
+	s.capacityTolerance = tolerance
+	s.rejectCapacityMismatch = reject
+}
+
+// DeclareWorkerCapacity records the capacity an admin expects a registered worker to have
+// When the worker connects, claims above the declared capacity times the tolerance are flagged; nil clears it
+func (s *MasterServer) DeclareWorkerCapacity(ctx context.Context, workerID string, declared *db.DeclaredCapacity) error {
+	if declared != nil {
+		if declared.CPU < 0 || declared.Memory < 0 || declared.GPU < 0 {
+			return fmt.Errorf("declared capacity cannot be negative")
+		}
+		if *declared == (db.DeclaredCapacity{}) {
+			declared = nil
+		}
+	}
+
+	s.mu.Lock()
+	worker, exists := s.workers[workerID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("worker %s not found", workerID)
+	}
+	worker.Declared = declared
+	s.mu.Unlock()
+
+	if s.workerDB != nil {
+		if err := s.workerDB.SetDeclaredCapacity(ctx, workerID, declared); err != nil {
+			return fmt.Errorf("persist declared capacity: %w", err)
+		}
+	}
+	return nil
+}
+
+// WorkerCapacityWarning returns why a worker's claimed capacity was flagged at registration ("" if it was not)
+func (s *MasterServer) WorkerCapacityWarning(workerID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if worker, exists := s.workers[workerID]; exists {
+		return worker.CapacityWarning
+	}
+	return ""
+}
+
+// checkDeclaredCapacity compares a worker's claimed capacity with what was declared for it
+// It describes each resource claimed above tolerance times its declared amount, or returns "" when the claim is plausible
+func checkDeclaredCapacity(declared *db.DeclaredCapacity, info *pb.WorkerInfo, tolerance float64) string {
+	if declared == nil {
+		return ""
+	}
+
+	var problems []string
+	check := func(resource string, claimed, expected float64, unit string) {
+		if expected > 0 && claimed > expected*tolerance {
+			problems = append(problems, fmt.Sprintf("%s: claims %.1f %s, declared %.1f (%.1fx)",
+				resource, claimed, unit, expected, claimed/expected))
+		}
+	}
+	check("CPU", info.TotalCpu, declared.CPU, "cores")
+	check("Memory", info.TotalMemory, declared.Memory, "GB")
+	check("GPU", info.TotalGpu, declared.GPU, "GPUs")
+	return strings.Join(problems, "; ")
+}
+
+// verifyClaimedCapacity checks a registering worker against its declared capacity, flagging a worker that
+// claims implausibly more; it returns a rejection when REJECT_CAPACITY_MISMATCH is set
+// This function assumes s.mu is already locked by the caller
+func (s *MasterServer) verifyClaimedCapacity(worker *WorkerState, info *pb.WorkerInfo) *pb.RegisterAck {
+	tolerance := s.capacityTolerance
+	if tolerance < 1 {
+		tolerance = DefaultCapacityTolerance
+	}
+
+	mismatch := checkDeclaredCapacity(worker.Declared, info, tolerance)
+	worker.CapacityWarning = mismatch
+	if mismatch == "" {
+		return nil
+	}
+
+	if s.rejectCapacityMismatch {
+		logging.Errorf("❌ Rejected worker %s: claimed capacity exceeds its declared capacity (%s)", info.WorkerId, mismatch)
+		return &pb.RegisterAck{
+			Success:   false,
+			Message:   fmt.Sprintf("Worker %s claims more capacity than declared for it: %s", info.WorkerId, mismatch),
+			ErrorCode: pb.ErrorCode_NOT_AUTHORIZED,
+		}
+	}
+	logging.Warnf("⚠️  Worker %s claims more capacity than declared for it: %s", info.WorkerId, mismatch)
+	return nil
+}
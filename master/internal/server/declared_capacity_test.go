@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"master/internal/db"
+	pb "master/proto"
+)
+
+// registerDeclared pre-registers a worker with a declared capacity of 4 CPUs and 16 GB
+func registerDeclared(t *testing.T, ms *MasterServer, workerID string) {
+	t.Helper()
+	if err := ms.ManualRegisterWorker(context.Background(), workerID, "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register %s: %v", workerID, err)
+	}
+	if err := ms.DeclareWorkerCapacity(context.Background(), workerID, &db.DeclaredCapacity{CPU: 4, Memory: 16}); err != nil {
+		t.Fatalf("Failed to declare capacity for %s: %v", workerID, err)
+	}
+}
+
+// TestWorkerClaimingTenTimesDeclaredCPUIsFlagged tests that a worker claiming 10x its declared CPU is flagged,
+// and rejected when mismatches are configured to be rejected
+func TestWorkerClaimingTenTimesDeclaredCPUIsFlagged(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+
+	// Within the tolerance: no warning
+	registerDeclared(t, ms, "worker-honest")
+	ack, err := ms.RegisterWorker(context.Background(), &pb.WorkerInfo{WorkerId: "worker-honest", TotalCpu: 6, TotalMemory: 16})
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected a plausible worker to register, got %+v (err %v)", ack, err)
+	}
+	if warning := ms.WorkerCapacityWarning("worker-honest"); warning != "" {
+		t.Errorf("Expected no capacity warning for 6 of 4 declared CPUs, got %q", warning)
+	}
+
+	// 10x the declared CPU is flagged but still registered by default
+	registerDeclared(t, ms, "worker-inflated")
+	ack, err = ms.RegisterWorker(context.Background(), &pb.WorkerInfo{WorkerId: "worker-inflated", TotalCpu: 40, TotalMemory: 16})
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected the flagged worker to register in warn mode, got %+v (err %v)", ack, err)
+	}
+	warning := ms.WorkerCapacityWarning("worker-inflated")
+	if !strings.Contains(warning, "CPU: claims 40.0 cores, declared 4.0 (10.0x)") {
+		t.Errorf("Expected a warning describing the CPU claim, got %q", warning)
+	}
+	if strings.Contains(warning, "Memory") {
+		t.Errorf("Expected memory within its declaration not to be flagged, got %q", warning)
+	}
+
+	// With rejection enabled the worker is refused and its claimed capacity never applied
+	ms.SetCapacityVerification(DefaultCapacityTolerance, true)
+	registerDeclared(t, ms, "worker-rejected")
+	ack, err = ms.RegisterWorker(context.Background(), &pb.WorkerInfo{WorkerId: "worker-rejected", TotalCpu: 40, TotalMemory: 16})
+	if err == nil || ack.Success || ack.ErrorCode != pb.ErrorCode_NOT_AUTHORIZED {
+		t.Fatalf("Expected the inflated worker to be rejected, got %+v (err %v)", ack, err)
+	}
+	worker, _ := ms.GetWorkerStats("worker-rejected")
+	if worker.IsActive || worker.Info.TotalCpu != 0 {
+		t.Errorf("Expected the rejected worker to stay inactive without capacity, got active=%v cpu=%.1f",
+			worker.IsActive, worker.Info.TotalCpu)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"master/internal/logging"
+)
+
+// Cluster event types published on the event bus
+const (
+	EventWorkerJoined = "worker_joined" // A worker became active (registered, reconnected or heartbeating again)
+	EventWorkerLeft   = "worker_left"   // A worker was unregistered, expired or stopped heartbeating
+	EventTaskQueued   = "task_queued"
+	EventTaskStarted  = "task_started"
+	EventTaskFinished = "task_finished" // Status holds the final state (completed, failed, cancelled, expired, ...)
+)
+
+// clusterEventBuffer is the number of events buffered per subscriber; a subscriber that falls further behind misses events
+const clusterEventBuffer = 64
+
+// ClusterEvent is a cluster-wide state change streamed to dashboards
+type ClusterEvent struct {
+	Type      string    `json:"type"`
+	WorkerID  string    `json:"worker_id,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// eventBus fans cluster events out to every subscriber
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan ClusterEvent]struct{}
+}
+
+// newEventBus creates an event bus with no subscribers
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[chan ClusterEvent]struct{})}
+}
+
+// subscribe registers a channel that receives every event published from now on
+func (b *eventBus) subscribe() chan ClusterEvent {
+	ch := make(chan ClusterEvent, clusterEventBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes a channel registered with subscribe
+func (b *eventBus) unsubscribe(ch chan ClusterEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, ch)
+}
+
+// publish delivers an event to every subscriber without blocking
+func (b *eventBus) publish(event ClusterEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			logging.Warnf("⚠ Cluster event subscriber is not keeping up, dropping %s event", event.Type)
+		}
+	}
+}
+
+// SubscribeEvents returns a channel of cluster events and a function that ends the subscription
+func (s *MasterServer) SubscribeEvents() (<-chan ClusterEvent, func()) {
+	ch := s.events.subscribe()
+	return ch, func() { s.events.unsubscribe(ch) }
+}
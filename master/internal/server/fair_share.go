@@ -0,0 +1,46 @@
+package server
+
+import (
+	"time"
+
+	"master/internal/db"
+	"master/internal/scheduler"
+	pb "master/proto"
+)
+
+// fairShareScheduler is implemented by schedulers that order the queue by each user's recent consumption
+type fairShareScheduler interface {
+	RecordUsage(userID string, resourceSeconds float64)
+	OrderByShare(tasks []*pb.Task) []int
+}
+
+// orderQueueByFairShare moves queued tasks of under-served users ahead when the scheduler is fair-share
+// Caller must hold s.queueMu
+func (s *MasterServer) orderQueueByFairShare() {
+	s.mu.RLock()
+	fs, ok := s.scheduler.(fairShareScheduler)
+	s.mu.RUnlock()
+	if !ok || len(s.taskQueue) < 2 {
+		return
+	}
+
+	tasks := make([]*pb.Task, len(s.taskQueue))
+	for i, qt := range s.taskQueue {
+		tasks[i] = qt.Task
+	}
+	ordered := make([]*QueuedTask, len(s.taskQueue))
+	for i, idx := range fs.OrderByShare(tasks) {
+		ordered[i] = s.taskQueue[idx]
+	}
+	s.taskQueue = ordered
+}
+
+// recordFairShareUsage charges a finished task's resource-seconds to its user when the scheduler is fair-share
+// Caller must hold s.mu
+func (s *MasterServer) recordFairShareUsage(task *db.Task, finishedAt time.Time) {
+	fs, ok := s.scheduler.(fairShareScheduler)
+	if !ok || task == nil || task.StartedAt.IsZero() {
+		return
+	}
+	fs.RecordUsage(task.UserID, scheduler.ResourceSeconds(task.ReqCPU, task.ReqMemory, task.ReqGPU, finishedAt.Sub(task.StartedAt)))
+}
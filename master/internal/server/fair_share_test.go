@@ -0,0 +1,37 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"master/internal/db"
+	"master/internal/scheduler"
+	pb "master/proto"
+)
+
+// TestFairShareDeprioritizesHeavyUser tests that of two equal queued tasks the idle user's is placed before the heavy user's
+func TestFairShareDeprioritizesHeavyUser(t *testing.T) {
+	ms := newReservationTestServer(t, 1.0)
+	fs := scheduler.NewFairShareScheduler(scheduler.NewRoundRobinScheduler(), time.Hour)
+	ms.SetScheduler(fs)
+
+	// alice just finished an hour-long 1 CPU / 1 GB task; bob has run nothing
+	now := time.Now()
+	ms.recordFairShareUsage(&db.Task{UserID: "alice", ReqCPU: 1.0, ReqMemory: 1.0, StartedAt: now.Add(-time.Hour)}, now)
+	if usage := fs.UserUsage("alice"); usage < 7000 {
+		t.Fatalf("Expected about 7200 resource-seconds charged to alice, got %.0f", usage)
+	}
+
+	// alice queued first, but only one task fits
+	ms.EnqueueTask(&pb.Task{TaskId: "task-alice", UserId: "alice", ReqCpu: 1.0, ReqMemory: 1.0}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "task-bob", UserId: "bob", ReqCpu: 1.0, ReqMemory: 1.0}, "test")
+	ms.processQueueOnce(now)
+
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if len(worker.RunningTasks) != 1 || !worker.RunningTasks["task-bob"] {
+		t.Fatalf("Expected only bob's task to run, got %v", worker.RunningTasks)
+	}
+	if queued := ms.GetQueuedTasks(); len(queued) != 1 || queued[0].Task.TaskId != "task-alice" {
+		t.Errorf("Expected alice's task to stay queued, got %d queued", len(queued))
+	}
+}
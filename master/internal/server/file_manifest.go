@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"master/internal/logging"
+	pb "master/proto"
+)
+
+// GetTaskManifest lists a task's stored result files with their sizes and checksums, without downloading them
+// Only the files' owner (or an admin) may list them
+func (s *MasterServer) GetTaskManifest(ctx context.Context, req *pb.TaskManifestRequest) (*pb.TaskManifest, error) {
+	if req.TaskId == "" || req.UserId == "" || req.RequestingUser == "" {
+		return &pb.TaskManifest{Success: false, Message: "task_id, user_id and requesting_user are required"}, nil
+	}
+	if s.fileMetadataDB == nil || s.fileStorage == nil {
+		return &pb.TaskManifest{Success: false, Message: "File metadata not available"}, nil
+	}
+	if err := s.fileStorage.GetAccessControl().CanAccessFiles(req.RequestingUser, req.UserId); err != nil {
+		return &pb.TaskManifest{Success: false, Message: err.Error()}, nil
+	}
+
+	metadata, err := s.fileMetadataDB.GetFileMetadataByTask(ctx, req.TaskId)
+	if err != nil {
+		logging.Errorf("Error loading file manifest for task %s: %v", req.TaskId, err)
+		return &pb.TaskManifest{Success: false, Message: fmt.Sprintf("Failed to load file metadata: %v", err)}, nil
+	}
+	// Files of another user's task are reported as missing rather than revealing they exist
+	if metadata == nil || metadata.UserID != req.UserId {
+		return &pb.TaskManifest{Success: false, Message: fmt.Sprintf("No files found for task %s", req.TaskId)}, nil
+	}
+
+	manifest := &pb.TaskManifest{
+		Success:  true,
+		TaskId:   metadata.TaskID,
+		TaskName: metadata.TaskName,
+		UserId:   metadata.UserID,
+	}
+	for _, f := range metadata.Manifest() {
+		manifest.Files = append(manifest.Files, &pb.ManifestFile{Path: f.Path, Size: f.Size, Sha256: f.SHA256})
+		manifest.TotalSize += f.Size
+	}
+	manifest.Message = fmt.Sprintf("%d file(s)", len(manifest.Files))
+	return manifest, nil
+}
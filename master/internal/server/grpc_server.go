@@ -0,0 +1,35 @@
+package server
+
+import (
+	"master/internal/logging"
+	pb "master/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewGRPCServer creates the master's gRPC server with the MasterWorker service registered
+// When enableReflection is set the reflection service is also registered so tools like grpcurl
+// can discover services without local proto files (keep it off in production)
+// Keepalive pings keep idle worker connections from being dropped by NAT or firewalls
+// creds secures the listener with TLS; nil serves plaintext
+func NewGRPCServer(ms *MasterServer, enableReflection bool, keepalive KeepaliveConfig, creds credentials.TransportCredentials) *grpc.Server {
+	opts := keepalive.ServerOptions()
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+	// Worker RPCs must come from the worker named in them (checked against its client certificate)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(ms.workerIdentityUnaryInterceptor),
+		grpc.ChainStreamInterceptor(ms.workerIdentityStreamInterceptor))
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterMasterWorkerServer(grpcServer, ms)
+
+	if enableReflection {
+		reflection.Register(grpcServer)
+		logging.Info("✓ gRPC reflection enabled (GRPC_REFLECTION=true)")
+	}
+
+	return grpcServer
+}
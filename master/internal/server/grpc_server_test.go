@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// listServices lists services through the reflection API of a server built with NewGRPCServer
+func listServices(t *testing.T, enableReflection bool) ([]string, error) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := NewGRPCServer(NewMasterServer(nil, nil, nil, nil, nil, nil, nil), enableReflection, DefaultKeepaliveConfig(), nil)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		names = append(names, svc.GetName())
+	}
+	return names, nil
+}
+
+// TestGRPCReflectionEnabled tests that the reflection service lists MasterWorker when enabled
+func TestGRPCReflectionEnabled(t *testing.T) {
+	names, err := listServices(t, true)
+	if err != nil {
+		t.Fatalf("Expected reflection to be available, got %v", err)
+	}
+
+	found := false
+	for _, name := range names {
+		if name == "cluster.MasterWorker" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected cluster.MasterWorker in reflected services, got %v", names)
+	}
+}
+
+// TestGRPCReflectionDisabled tests that reflection is not exposed by default
+func TestGRPCReflectionDisabled(t *testing.T) {
+	if _, err := listServices(t, false); err == nil {
+		t.Error("Expected reflection request to fail when reflection is disabled")
+	}
+}
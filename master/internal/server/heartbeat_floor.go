@@ -0,0 +1,34 @@
+package server
+
+import (
+	"time"
+
+	"master/internal/logging"
+)
+
+// SetMinHeartbeatInterval ignores heartbeats arriving less than interval after a worker's last processed one
+// so a misbehaving worker cannot flood the telemetry manager; 0 processes every heartbeat
+func (s *MasterServer) SetMinHeartbeatInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if interval < 0 {
+		interval = 0
+	}
+	s.minHeartbeatInterval = interval
+}
+
+// heartbeatTooSoon reports whether a heartbeat arriving at now should be ignored, recording it otherwise
+// The first ignored heartbeat of a worker logs a warning; later ones are dropped silently (caller holds s.mu)
+func (s *MasterServer) heartbeatTooSoon(workerID string, worker *WorkerState, now time.Time) bool {
+	if s.minHeartbeatInterval > 0 && !worker.lastHeartbeatAt.IsZero() &&
+		now.Sub(worker.lastHeartbeatAt) < s.minHeartbeatInterval {
+		if !worker.heartbeatFloodWarned {
+			worker.heartbeatFloodWarned = true
+			logging.Warnf("⚠️  Worker %s is sending heartbeats faster than every %s; ignoring the extra ones",
+				workerID, s.minHeartbeatInterval)
+		}
+		return true
+	}
+	worker.lastHeartbeatAt = now
+	return false
+}
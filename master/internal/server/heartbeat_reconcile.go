@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+
+	"master/internal/logging"
+	pb "master/proto"
+)
+
+// droppedFromHeartbeat records the tasks a worker reports and returns the ones it has stopped reporting
+// A task counts as dropped when it was in the previous heartbeat, is missing from this one and the master
+// still holds it on the worker; tasks assigned since the last heartbeat are never dropped. Caller holds s.mu.
+func (w *WorkerState) droppedFromHeartbeat(reported []*pb.RunningTask) []string {
+	current := make(map[string]bool, len(reported))
+	for _, rt := range reported {
+		current[rt.TaskId] = true
+	}
+
+	var dropped []string
+	for taskID := range w.ReportedTasks {
+		if !current[taskID] && w.RunningTasks[taskID] {
+			dropped = append(dropped, taskID)
+		}
+	}
+	w.ReportedTasks = current
+	return dropped
+}
+
+// readyFromHeartbeat records the tasks a worker reports ready and returns the ones that were not ready in the
+// previous heartbeat; a restarted container reports running again and becomes newly ready once healthy. Caller holds s.mu.
+func (w *WorkerState) readyFromHeartbeat(reported []*pb.RunningTask) []string {
+	current := make(map[string]bool)
+	var ready []string
+	for _, rt := range reported {
+		if rt.Status != "ready" {
+			continue
+		}
+		current[rt.TaskId] = true
+		if !w.ReadyTasks[rt.TaskId] {
+			ready = append(ready, rt.TaskId)
+		}
+	}
+	w.ReadyTasks = current
+	return ready
+}
+
+// markTasksReady records when tasks' health checks passed and tells their watchers the tasks are ready
+func (s *MasterServer) markTasksReady(ctx context.Context, workerID string, taskIDs []string) {
+	for _, taskID := range taskIDs {
+		logging.Infof("💚 Task %s is ready on worker %s", taskID, workerID)
+		s.watchers.publish(&pb.TaskStatusUpdate{TaskId: taskID, Status: "ready", WorkerId: workerID})
+		if s.taskDB == nil {
+			continue
+		}
+		if err := s.taskDB.MarkTaskReady(ctx, taskID); err != nil {
+			logging.Warnf("Warning: failed to record task %s as ready: %v", taskID, err)
+		}
+	}
+}
+
+// releaseDroppedTasks releases the resources of tasks a worker no longer reports and fails them
+// Only tasks the database still shows as running are touched; a completion report may already have settled the rest
+func (s *MasterServer) releaseDroppedTasks(ctx context.Context, workerID string, taskIDs []string) {
+	if s.taskDB == nil {
+		return
+	}
+
+	for _, taskID := range taskIDs {
+		record, err := s.taskDB.GetTask(ctx, taskID)
+		if err != nil {
+			logging.Warnf("Warning: failed to look up task %s dropped by worker %s: %v", taskID, workerID, err)
+			continue
+		}
+		if record.Status != "running" {
+			continue
+		}
+
+		logging.Infof("🧹 Worker %s no longer reports task %s - releasing its resources", workerID, taskID)
+		s.releaseTaskFromWorker(ctx, workerID, record)
+
+		if err := s.taskDB.UpdateTaskStatus(ctx, taskID, "failed"); err != nil {
+			logging.Warnf("Warning: failed to mark task %s failed: %v", taskID, err)
+			continue
+		}
+		s.mu.RLock()
+		s.notifyTaskTerminal(ctx, taskID, workerID, "failed", record)
+		s.mu.RUnlock()
+	}
+}
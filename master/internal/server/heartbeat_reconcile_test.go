@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"master/internal/db"
+	pb "master/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestHeartbeatReleasesDroppedTask tests that a task missing from a worker's heartbeat has its resources released
+func TestHeartbeatReleasesDroppedTask(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("dropped", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+		worker, _ := ms.GetWorkerStats("worker-1")
+		worker.RunningTasks["task-1"] = true
+		worker.AllocatedCPU, worker.AvailableCPU = 2.0, 2.0
+		worker.AllocatedMemory, worker.AvailableMemory = 1.0, 7.0
+
+		// The task is reported while it runs, so nothing is released
+		running := &pb.Heartbeat{WorkerId: "worker-1", RunningTasks: []*pb.RunningTask{{TaskId: "task-1"}}}
+		if _, err := ms.SendHeartbeat(context.Background(), running); err != nil {
+			t.Fatalf("Heartbeat failed: %v", err)
+		}
+		if !worker.RunningTasks["task-1"] || worker.AllocatedCPU != 2.0 {
+			t.Fatalf("Expected task-1 to stay allocated while reported, got allocated %.1f", worker.AllocatedCPU)
+		}
+
+		// The next heartbeat no longer lists it while the database still shows it running
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, bson.D{
+				{Key: "task_id", Value: "task-1"},
+				{Key: "status", Value: "running"},
+				{Key: "req_cpu", Value: 2.0},
+				{Key: "req_memory", Value: 1.0},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		if _, err := ms.SendHeartbeat(context.Background(), &pb.Heartbeat{WorkerId: "worker-1"}); err != nil {
+			t.Fatalf("Heartbeat failed: %v", err)
+		}
+
+		if worker.RunningTasks["task-1"] {
+			t.Error("Expected task-1 to be removed from the worker's running tasks")
+		}
+		if worker.AllocatedCPU != 0 || worker.AvailableCPU != 4.0 {
+			t.Errorf("Expected CPU to be released, got allocated %.1f available %.1f", worker.AllocatedCPU, worker.AvailableCPU)
+		}
+		if worker.AllocatedMemory != 0 || worker.AvailableMemory != 8.0 {
+			t.Errorf("Expected memory to be released, got allocated %.1f available %.1f", worker.AllocatedMemory, worker.AvailableMemory)
+		}
+	})
+}
+
+// TestHeartbeatKeepsNewlyAssignedTask tests that a task assigned since the last heartbeat is not released for being unreported
+func TestHeartbeatKeepsNewlyAssignedTask(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	worker, _ := ms.GetWorkerStats("worker-1")
+	worker.RunningTasks["task-1"] = true
+
+	for i := 0; i < 2; i++ {
+		if _, err := ms.SendHeartbeat(context.Background(), &pb.Heartbeat{WorkerId: "worker-1"}); err != nil {
+			t.Fatalf("Heartbeat failed: %v", err)
+		}
+	}
+	if !worker.RunningTasks["task-1"] {
+		t.Error("Expected task-1 to stay on the worker")
+	}
+}
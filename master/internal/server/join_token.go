@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"master/internal/logging"
+	pb "master/proto"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// JoinTokenMetadataKey is the gRPC metadata key a worker sends its cluster join token under
+const JoinTokenMetadataKey = "x-join-token"
+
+// DefaultJoinTokenTTL is how long a join token from NewJoinToken stays valid when no TTL is given
+const DefaultJoinTokenTTL = 24 * time.Hour
+
+// SignJoinToken creates a join token "<expiry unix>.<hex HMAC-SHA256 of the expiry>" signed with secret
+func SignJoinToken(secret []byte, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + joinTokenSignature(secret, expiry)
+}
+
+// VerifyJoinToken checks a join token's signature against secret and that it has not expired at now
+func VerifyJoinToken(secret []byte, token string, now time.Time) error {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok || expiry == "" || signature == "" {
+		return fmt.Errorf("malformed join token")
+	}
+	if !hmac.Equal([]byte(signature), []byte(joinTokenSignature(secret, expiry))) {
+		return fmt.Errorf("invalid join token signature")
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed join token expiry")
+	}
+	if now.Unix() > expiresAt {
+		return fmt.Errorf("join token expired at %s", time.Unix(expiresAt, 0).Format(time.RFC3339))
+	}
+	return nil
+}
+
+func joinTokenSignature(secret []byte, expiry string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EnableAutoRegister lets unknown workers register themselves with a join token signed by secret
+// An empty secret keeps the default strict mode where every worker must be pre-registered
+func (s *MasterServer) EnableAutoRegister(secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if secret == "" {
+		s.joinTokenSecret = nil
+		return
+	}
+	s.joinTokenSecret = []byte(secret)
+}
+
+// NewJoinToken issues a join token valid for ttl (DefaultJoinTokenTTL when ttl <= 0)
+func (s *MasterServer) NewJoinToken(ttl time.Duration) (string, time.Time, error) {
+	s.mu.RLock()
+	secret := s.joinTokenSecret
+	s.mu.RUnlock()
+
+	if secret == nil {
+		return "", time.Time{}, fmt.Errorf("auto-registration is disabled (set AUTO_REGISTER=true and JOIN_TOKEN_SECRET)")
+	}
+	if ttl <= 0 {
+		ttl = DefaultJoinTokenTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+	return SignJoinToken(secret, expiresAt), expiresAt, nil
+}
+
+// autoRegisterWorker adds an unknown worker that presented a valid join token in its request metadata
+// The worker must advertise its own address since the master has never been told it
+// This function assumes s.mu is already locked by the caller
+func (s *MasterServer) autoRegisterWorker(ctx context.Context, info *pb.WorkerInfo) error {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(JoinTokenMetadataKey); len(values) > 0 {
+			token = values[0]
+		}
+	}
+	if token == "" {
+		return fmt.Errorf("worker is not pre-registered and presented no join token")
+	}
+	if err := VerifyJoinToken(s.joinTokenSecret, token, time.Now()); err != nil {
+		return err
+	}
+	if info.WorkerIp == "" {
+		return fmt.Errorf("worker must advertise its address to auto-register")
+	}
+
+	if err := s.addWorker(ctx, info.WorkerId, info.WorkerIp); err != nil {
+		return err
+	}
+	logging.Infof("🔑 Auto-registered worker with join token: %s (Address: %s)", info.WorkerId, info.WorkerIp)
+	return nil
+}
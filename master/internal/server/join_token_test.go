@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	pb "master/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"google.golang.org/grpc/metadata"
+)
+
+// joinContext returns an incoming gRPC context carrying token as the worker's join token
+func joinContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(JoinTokenMetadataKey, token))
+}
+
+// TestRegisterWorkerAutoRegistersWithValidJoinToken tests that an unknown worker with a valid join token is added and persisted
+func TestRegisterWorkerAutoRegistersWithValidJoinToken(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("valid token", func(mt *mtest.T) {
+		ms := NewMasterServer(db.NewWorkerDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil, nil)
+		ms.EnableAutoRegister("cluster-secret")
+		token, _, err := ms.NewJoinToken(time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to issue join token: %v", err)
+		}
+
+		// Existence check, insert, then the worker info update on connect
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.WORKERS", mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, "cloudai.WORKERS", mtest.FirstBatch, bson.D{{Key: "worker_id", Value: "worker-auto"}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		ack, err := ms.RegisterWorker(joinContext(token), &pb.WorkerInfo{
+			WorkerId: "worker-auto",
+			WorkerIp: "10.0.0.7:50052",
+			TotalCpu: 4.0,
+		})
+		if err != nil || !ack.Success {
+			t.Fatalf("Expected auto-registration to succeed, got ack=%v err=%v", ack, err)
+		}
+
+		worker, exists := ms.GetWorkerStats("worker-auto")
+		if !exists || !worker.IsActive {
+			t.Fatal("Expected the worker to be registered and active")
+		}
+		if worker.Info.WorkerIp != "10.0.0.7:50052" {
+			t.Errorf("Expected advertised address 10.0.0.7:50052, got %q", worker.Info.WorkerIp)
+		}
+
+		inserted := false
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" {
+				inserted = true
+			}
+		}
+		if !inserted {
+			t.Error("Expected the worker to be persisted to the worker database")
+		}
+	})
+}
+
+// TestRegisterWorkerRejectsInvalidJoinToken tests that forged, expired or missing join tokens are rejected
+func TestRegisterWorkerRejectsInvalidJoinToken(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.EnableAutoRegister("cluster-secret")
+
+	info := &pb.WorkerInfo{WorkerId: "worker-rogue", WorkerIp: "10.0.0.9:50052"}
+	tokens := map[string]context.Context{
+		"forged":  joinContext(SignJoinToken([]byte("other-secret"), time.Now().Add(time.Hour))),
+		"expired": joinContext(SignJoinToken([]byte("cluster-secret"), time.Now().Add(-time.Minute))),
+		"garbage": joinContext("not-a-token"),
+		"missing": context.Background(),
+	}
+	for name, ctx := range tokens {
+		ack, err := ms.RegisterWorker(ctx, info)
+		if err == nil || ack.Success {
+			t.Errorf("Expected %s token to be rejected", name)
+		}
+		if ack.ErrorCode != pb.ErrorCode_NOT_AUTHORIZED {
+			t.Errorf("Expected NOT_AUTHORIZED for %s token, got %s", name, ack.ErrorCode)
+		}
+	}
+	if _, exists := ms.GetWorkerStats("worker-rogue"); exists {
+		t.Error("Expected the worker not to be registered")
+	}
+
+	// Strict mode (the default) never auto-registers, even with a valid token
+	strict := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	valid := SignJoinToken([]byte("cluster-secret"), time.Now().Add(time.Hour))
+	if ack, _ := strict.RegisterWorker(joinContext(valid), info); ack.Success {
+		t.Error("Expected strict mode to reject an unknown worker")
+	}
+}
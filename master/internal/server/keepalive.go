@@ -0,0 +1,81 @@
+package server
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// minClientPingInterval is the most frequent client keepalive ping the server accepts
+// gRPC clients never ping more often than every 10s, so any worker keepalive setting is allowed
+const minClientPingInterval = 5 * time.Second
+
+// KeepaliveConfig tunes gRPC keepalive pings so idle worker connections are not silently dropped by NAT or firewalls
+type KeepaliveConfig struct {
+	Time                time.Duration // Ping the peer after this long without activity
+	Timeout             time.Duration // Close the connection if a ping is not acknowledged within this
+	PermitWithoutStream bool          // Keep pinging while no RPC is in flight
+}
+
+// DefaultKeepaliveConfig returns keepalive settings that stay below common NAT idle timeouts
+func DefaultKeepaliveConfig() KeepaliveConfig {
+	return KeepaliveConfig{
+		Time:                30 * time.Second,
+		Timeout:             10 * time.Second,
+		PermitWithoutStream: true,
+	}
+}
+
+// ServerParameters returns the keepalive parameters for the gRPC server
+func (k KeepaliveConfig) ServerParameters() keepalive.ServerParameters {
+	return keepalive.ServerParameters{
+		Time:    k.Time,
+		Timeout: k.Timeout,
+	}
+}
+
+// EnforcementPolicy returns the policy the gRPC server applies to client pings
+func (k KeepaliveConfig) EnforcementPolicy() keepalive.EnforcementPolicy {
+	return keepalive.EnforcementPolicy{
+		MinTime:             minClientPingInterval,
+		PermitWithoutStream: k.PermitWithoutStream,
+	}
+}
+
+// ClientParameters returns the keepalive parameters for dials to workers
+func (k KeepaliveConfig) ClientParameters() keepalive.ClientParameters {
+	return keepalive.ClientParameters{
+		Time:                k.Time,
+		Timeout:             k.Timeout,
+		PermitWithoutStream: k.PermitWithoutStream,
+	}
+}
+
+// ServerOptions returns the gRPC server options applying this keepalive configuration
+func (k KeepaliveConfig) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.KeepaliveParams(k.ServerParameters()),
+		grpc.KeepaliveEnforcementPolicy(k.EnforcementPolicy()),
+	}
+}
+
+// DialOptions returns the gRPC dial options applying this keepalive configuration
+func (k KeepaliveConfig) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{grpc.WithKeepaliveParams(k.ClientParameters())}
+}
+
+// SetKeepalive sets the keepalive used on long-lived connections to workers (log streams)
+func (s *MasterServer) SetKeepalive(k KeepaliveConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keepalive = k
+}
+
+// streamDialOptions returns the dial options for long-lived streams to a worker
+func (s *MasterServer) streamDialOptions() []grpc.DialOption {
+	s.mu.RLock()
+	k := s.keepalive
+	s.mu.RUnlock()
+	return append([]grpc.DialOption{s.dialCredentials()}, k.DialOptions()...)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"master/internal/config"
+)
+
+// TestKeepaliveOptionsFromConfig tests that keepalive settings from the environment reach the server and dial parameters
+func TestKeepaliveOptionsFromConfig(t *testing.T) {
+	t.Setenv("GRPC_KEEPALIVE_TIME", "45s")
+	t.Setenv("GRPC_KEEPALIVE_TIMEOUT", "15")
+	t.Setenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "false")
+
+	cfg := config.LoadConfig()
+	k := KeepaliveConfig{
+		Time:                cfg.GRPCKeepaliveTime,
+		Timeout:             cfg.GRPCKeepaliveTimeout,
+		PermitWithoutStream: cfg.GRPCKeepalivePermitWithoutStream,
+	}
+
+	server := k.ServerParameters()
+	if server.Time != 45*time.Second || server.Timeout != 15*time.Second {
+		t.Errorf("Expected server keepalive 45s/15s, got %s/%s", server.Time, server.Timeout)
+	}
+
+	policy := k.EnforcementPolicy()
+	if policy.PermitWithoutStream {
+		t.Error("Expected the server to reject pings without an active stream")
+	}
+	if policy.MinTime > 10*time.Second {
+		t.Errorf("Expected the server to accept client pings every 10s, got minimum %s", policy.MinTime)
+	}
+
+	client := k.ClientParameters()
+	if client.Time != 45*time.Second || client.Timeout != 15*time.Second || client.PermitWithoutStream {
+		t.Errorf("Expected client keepalive 45s/15s without streams, got %s/%s permit=%v", client.Time, client.Timeout, client.PermitWithoutStream)
+	}
+	if len(k.ServerOptions()) != 2 || len(k.DialOptions()) != 1 {
+		t.Errorf("Expected 2 server options and 1 dial option, got %d and %d", len(k.ServerOptions()), len(k.DialOptions()))
+	}
+
+	// Defaults apply when nothing is configured
+	t.Setenv("GRPC_KEEPALIVE_TIME", "")
+	t.Setenv("GRPC_KEEPALIVE_TIMEOUT", "")
+	t.Setenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "")
+	cfg = config.LoadConfig()
+	defaults := DefaultKeepaliveConfig()
+	if cfg.GRPCKeepaliveTime != defaults.Time || cfg.GRPCKeepaliveTimeout != defaults.Timeout || cfg.GRPCKeepalivePermitWithoutStream != defaults.PermitWithoutStream {
+		t.Errorf("Expected config defaults to match DefaultKeepaliveConfig, got %s/%s permit=%v",
+			cfg.GRPCKeepaliveTime, cfg.GRPCKeepaliveTimeout, cfg.GRPCKeepalivePermitWithoutStream)
+	}
+}
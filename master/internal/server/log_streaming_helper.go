@@ -3,20 +3,20 @@ package server
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
+	"master/internal/logging"
 	pb "master/proto"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // LogStreamHandler is a function type that handles incoming log lines
 // logLine: the log content
+// stream: stdout or stderr for live lines; empty for stored logs, where both streams are merged
 // isComplete: true if this is the final log (task completed)
 // status: current task status (running, success, failed, etc.)
-type LogStreamHandler func(logLine string, isComplete bool, status string) error
+type LogStreamHandler func(logLine, stream string, isComplete bool, status string) error
 
 // StreamTaskLogsUnified is a unified function to stream logs from a worker
 // This can be used by both CLI and web interface
@@ -39,7 +39,7 @@ func (s *MasterServer) StreamTaskLogsUnified(ctx context.Context, taskID, userID
 			lines := splitLogLines(result.Logs)
 			for i, line := range lines {
 				isLastLine := i == len(lines)-1
-				if err := handler(line, isLastLine, result.Status); err != nil {
+				if err := handler(line, "", isLastLine, result.Status); err != nil {
 					return err
 				}
 
@@ -80,7 +80,7 @@ func (s *MasterServer) StreamTaskLogsUnified(ctx context.Context, taskID, userID
 	s.mu.RUnlock()
 
 	// Connect to worker
-	conn, err := grpc.Dial(workerIP, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(workerIP, s.streamDialOptions()...)
 	if err != nil {
 		return fmt.Errorf("failed to connect to worker: %w", err)
 	}
@@ -98,13 +98,13 @@ func (s *MasterServer) StreamTaskLogsUnified(ctx context.Context, taskID, userID
 		return fmt.Errorf("failed to start log stream: %w", err)
 	}
 
-	log.Printf("[StreamLogs] Started streaming logs for task %s from worker %s", taskID, workerID)
+	logging.Infof("[StreamLogs] Started streaming logs for task %s from worker %s", taskID, workerID)
 
 	// Stream logs
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("[StreamLogs] Context cancelled for task %s", taskID)
+			logging.Debugf("[StreamLogs] Context cancelled for task %s", taskID)
 			return ctx.Err()
 		default:
 		}
@@ -112,24 +112,24 @@ func (s *MasterServer) StreamTaskLogsUnified(ctx context.Context, taskID, userID
 		chunk, err := stream.Recv()
 		if err != nil {
 			if err.Error() == "EOF" {
-				log.Printf("[StreamLogs] Stream ended (EOF) for task %s", taskID)
+				logging.Debugf("[StreamLogs] Stream ended (EOF) for task %s", taskID)
 				return nil
 			}
 			return fmt.Errorf("error receiving log chunk: %w", err)
 		}
 
 		// Call handler with log content
-		if err := handler(chunk.Content, chunk.IsComplete, chunk.Status); err != nil {
+		if err := handler(chunk.Content, chunk.Stream, chunk.IsComplete, chunk.Status); err != nil {
 			return fmt.Errorf("handler error: %w", err)
 		}
 
 		if chunk.IsComplete {
-			log.Printf("[StreamLogs] Task %s completed with status: %s", taskID, chunk.Status)
+			logging.Infof("[StreamLogs] Task %s completed with status: %s", taskID, chunk.Status)
 
 			// Update task status in database if completed
 			if s.taskDB != nil && chunk.Status != "running" {
 				if err := s.taskDB.UpdateTaskStatus(ctx, taskID, chunk.Status); err != nil {
-					log.Printf("[StreamLogs] Warning: failed to update task status: %v", err)
+					logging.Warnf("[StreamLogs] Warning: failed to update task status: %v", err)
 				}
 			}
 			return nil
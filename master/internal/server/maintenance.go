@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"master/internal/db"
+	"master/internal/logging"
+)
+
+// DefaultMaintenanceCheckInterval is how often workers are checked against their maintenance windows
+const DefaultMaintenanceCheckInterval = 30 * time.Second
+
+// MaxMaintenanceDuration bounds how long a single maintenance window may last
+const MaxMaintenanceDuration = 7 * 24 * time.Hour
+
+// cronSchedule is a parsed five-field cron expression
+type cronSchedule struct {
+	minute, hour, dom, month, dow []bool
+	domAny, dowAny                bool // "*" fields, for the cron rule that a restricted day-of-month OR day-of-week matches
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week"
+// Each field accepts *, a value, a range (a-b), a step (*/n or a-b/n) or a comma-separated list of these
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	c := &cronSchedule{domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day-of-month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	// 7 is accepted as Sunday
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day-of-week: %w", err)
+	}
+	if c.dow[7] {
+		c.dow[0] = true
+	}
+	return c, nil
+}
+
+// parseCronField returns which values in [min, max] a cron field selects, indexed by value
+func parseCronField(field string, min, max int) ([]bool, error) {
+	selected := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 1 && step > 1 {
+				hi = max // "a/n" steps from a to the end of the range
+			}
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			selected[v] = true
+		}
+	}
+	return selected, nil
+}
+
+// matches reports whether the schedule fires in the minute containing t
+func (c *cronSchedule) matches(t time.Time) bool {
+	return c.matchesDay(t) && c.hour[t.Hour()] && c.minute[t.Minute()]
+}
+
+// matchesDay reports whether the schedule fires at some time on t's day
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	if !c.month[int(t.Month())] {
+		return false
+	}
+	domMatch, dowMatch := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	if !c.domAny && !c.dowAny {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// firedWithin reports whether the schedule fired in a minute that started within d before now
+// It steps back over whole days and hours that cannot match, so a week-long window takes a few hundred steps at most
+func (c *cronSchedule) firedWithin(now time.Time, d time.Duration) bool {
+	for t := now.Truncate(time.Minute); now.Sub(t) < d; {
+		year, month, day := t.Date()
+		switch {
+		case !c.matchesDay(t):
+			t = time.Date(year, month, day, 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+		case !c.hour[t.Hour()]:
+			t = time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location()).Add(-time.Minute)
+		case !c.minute[t.Minute()]:
+			t = t.Add(-time.Minute)
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateMaintenanceWindow checks a maintenance window's cron expression and duration
+func ValidateMaintenanceWindow(window *db.MaintenanceWindow) error {
+	if _, err := parseCron(window.Cron); err != nil {
+		return err
+	}
+	if window.Duration < time.Minute || window.Duration > MaxMaintenanceDuration {
+		return fmt.Errorf("duration must be between 1m and %s", MaxMaintenanceDuration)
+	}
+	return nil
+}
+
+// inMaintenanceWindow reports whether now falls within a window that opened in the last window.Duration
+func inMaintenanceWindow(window *db.MaintenanceWindow, now time.Time) bool {
+	schedule, err := parseCron(window.Cron)
+	if err != nil {
+		return false
+	}
+	return schedule.firedWithin(now, window.Duration)
+}
+
+// SetWorkerMaintenance sets a worker's recurring maintenance window (nil removes it)
+// The worker is cordoned or uncordoned right away if that changes whether it is in a window
+func (s *MasterServer) SetWorkerMaintenance(ctx context.Context, workerID string, window *db.MaintenanceWindow) error {
+	if window != nil {
+		if err := ValidateMaintenanceWindow(window); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	worker, exists := s.workers[workerID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("worker %s not found", workerID)
+	}
+	worker.Maintenance = window
+	s.mu.Unlock()
+
+	if s.workerDB != nil {
+		if err := s.workerDB.SetMaintenanceWindow(ctx, workerID, window); err != nil {
+			return fmt.Errorf("persist maintenance window: %w", err)
+		}
+	}
+
+	s.checkMaintenanceWindows(time.Now())
+	return nil
+}
+
+// IsWorkerCordoned reports whether a worker is currently cordoned by its maintenance window
+func (s *MasterServer) IsWorkerCordoned(workerID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	worker, exists := s.workers[workerID]
+	return exists && worker.Cordoned
+}
+
+// checkMaintenanceWindows cordons workers entering their maintenance window and uncordons workers leaving it
+// Returns the IDs of the workers cordoned and uncordoned by this check
+func (s *MasterServer) checkMaintenanceWindows(now time.Time) (cordoned, uncordoned []string) {
+	// Evaluate the windows without holding s.mu, so scheduling is not held up by the cron matching
+	s.mu.RLock()
+	windows := make(map[string]*db.MaintenanceWindow, len(s.workers))
+	for workerID, worker := range s.workers {
+		windows[workerID] = worker.Maintenance
+	}
+	s.mu.RUnlock()
+
+	inWindows := make(map[string]bool, len(windows))
+	for workerID, window := range windows {
+		inWindows[workerID] = window != nil && inMaintenanceWindow(window, now)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for workerID, worker := range s.workers {
+		// A worker that joined or had its window changed meanwhile is left to the next check
+		window, checked := windows[workerID]
+		if !checked || worker.Maintenance != window {
+			continue
+		}
+		inWindow := inWindows[workerID]
+		switch {
+		case inWindow && !worker.Cordoned:
+			worker.Cordoned = true
+			cordoned = append(cordoned, workerID)
+			logging.Infof("🚧 Worker %s entered its maintenance window (%s for %s) - cordoned",
+				workerID, worker.Maintenance.Cron, worker.Maintenance.Duration)
+		case !inWindow && worker.Cordoned:
+			worker.Cordoned = false
+			uncordoned = append(uncordoned, workerID)
+			logging.Infof("✓ Worker %s left its maintenance window - uncordoned", workerID)
+		}
+	}
+
+	sort.Strings(cordoned)
+	sort.Strings(uncordoned)
+	return cordoned, uncordoned
+}
+
+// StartMaintenanceChecker periodically cordons and uncordons workers by their maintenance windows until StopMaintenanceChecker is called
+func (s *MasterServer) StartMaintenanceChecker(interval time.Duration) {
+	s.maintenanceTicker = time.NewTicker(interval)
+	s.maintenanceStop = make(chan bool)
+
+	go func() {
+		logging.Infof("🚧 Maintenance window checker started (interval: %s)", interval)
+		s.checkMaintenanceWindows(time.Now())
+		for {
+			select {
+			case <-s.maintenanceTicker.C:
+				s.checkMaintenanceWindows(time.Now())
+			case <-s.maintenanceStop:
+				logging.Info("🛑 Maintenance window checker stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopMaintenanceChecker stops the periodic maintenance window checker
+func (s *MasterServer) StopMaintenanceChecker() {
+	if s.maintenanceTicker != nil {
+		s.maintenanceTicker.Stop()
+	}
+	if s.maintenanceStop != nil {
+		close(s.maintenanceStop)
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	pb "master/proto"
+)
+
+// TestMaintenanceWindowCordonsWorker tests that a worker is unschedulable inside its maintenance window and schedulable outside it
+func TestMaintenanceWindowCordonsWorker(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+	worker, _ := ms.GetWorkerStats("worker-1")
+	worker.IsActive = true
+
+	// A daily one-hour window opening at the current minute
+	now := time.Now()
+	window := &db.MaintenanceWindow{Cron: fmt.Sprintf("%d %d * * *", now.Minute(), now.Hour()), Duration: time.Hour}
+	if err := ms.SetWorkerMaintenance(context.Background(), "worker-1", window); err != nil {
+		t.Fatalf("Failed to set maintenance window: %v", err)
+	}
+
+	task := &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0}
+	if !ms.IsWorkerCordoned("worker-1") {
+		t.Fatal("Expected worker-1 to be cordoned inside its maintenance window")
+	}
+	if selected := ms.selectWorkerForTask(task); selected != "" {
+		t.Errorf("Expected no worker to be selected while worker-1 is cordoned, got %s", selected)
+	}
+	if _, _, nack := ms.reserveTaskOnWorker(task, "worker-1"); nack == nil {
+		t.Error("Expected a direct assignment to a cordoned worker to be rejected")
+	}
+
+	// Two hours later the window has closed
+	cordoned, uncordoned := ms.checkMaintenanceWindows(now.Add(2 * time.Hour))
+	if len(cordoned) != 0 || len(uncordoned) != 1 || uncordoned[0] != "worker-1" {
+		t.Fatalf("Expected worker-1 to be uncordoned, got cordoned=%v uncordoned=%v", cordoned, uncordoned)
+	}
+	if selected := ms.selectWorkerForTask(task); selected != "worker-1" {
+		t.Errorf("Expected worker-1 to be selected outside its maintenance window, got %q", selected)
+	}
+
+	// The next day's window cordons it again
+	if cordoned, _ := ms.checkMaintenanceWindows(now.Add(24 * time.Hour)); len(cordoned) != 1 {
+		t.Errorf("Expected worker-1 to be cordoned again in the next window, got %v", cordoned)
+	}
+}
+
+// TestParseCron tests cron field parsing and matching
+func TestParseCron(t *testing.T) {
+	saturday2am := time.Date(2026, time.March, 7, 2, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"30 2 * * 6", true},
+		{"*/15 1-3 * * 6", true},
+		{"0 2 * * 6", false},
+		{"30 2 7 * 1", true}, // Day-of-month OR day-of-week when both are restricted
+		{"30 2 1 * 1", false},
+		{"30 2 * * 0,6", true},
+	}
+
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected parse error: %v", tt.expr, err)
+			continue
+		}
+		if got := schedule.matches(saturday2am); got != tt.expected {
+			t.Errorf("%q: expected match=%v, got %v", tt.expr, tt.expected, got)
+		}
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := parseCron(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+// TestMaintenanceWindowLongDuration tests that week-long windows match exactly the minutes a minute-by-minute walk would
+func TestMaintenanceWindowLongDuration(t *testing.T) {
+	exprs := []string{"0 0 1 1 *", "30 2 * * 6", "*/20 9-17 * * 1-5", "0 3 15 * 0", "45 23 31 * *"}
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, expr := range exprs {
+		schedule, err := parseCron(expr)
+		if err != nil {
+			t.Fatalf("%q: unexpected parse error: %v", expr, err)
+		}
+		window := &db.MaintenanceWindow{Cron: expr, Duration: MaxMaintenanceDuration}
+		for now := start; now.Before(start.AddDate(0, 2, 0)); now = now.Add(7*time.Hour + 13*time.Minute) {
+			expected := false
+			for m := now.Truncate(time.Minute); now.Sub(m) < window.Duration; m = m.Add(-time.Minute) {
+				if schedule.matches(m) {
+					expected = true
+					break
+				}
+			}
+			if got := inMaintenanceWindow(window, now); got != expected {
+				t.Errorf("%q at %s: expected in window=%v, got %v", expr, now.Format(time.RFC3339), expected, got)
+			}
+		}
+	}
+}
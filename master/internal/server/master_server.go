@@ -2,20 +2,27 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"master/internal/db"
+	"master/internal/logging"
+	"master/internal/notify"
 	"master/internal/scheduler"
 	"master/internal/storage"
 	"master/internal/telemetry"
 	pb "master/proto"
 
+	"go.mongodb.org/mongo-driver/mongo"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
 )
 
 // QueuedTask represents a task waiting to be scheduled and assigned
@@ -24,6 +31,7 @@ type QueuedTask struct {
 	QueuedAt  time.Time
 	Retries   int
 	LastError string
+	Position  int // Last 1-based queue position reported to status watchers
 }
 
 // TaskSubmission represents the result of submitting a task to the system
@@ -53,20 +61,104 @@ type MasterServer struct {
 
 	// Task queue for tasks waiting for resources
 	taskQueue   []*QueuedTask
+	heldTasks   map[string]*pb.Task // Staged tasks kept out of the queue until released (guarded by queueMu)
 	queueMu     sync.RWMutex
 	queueTicker *time.Ticker
 
 	// Task scheduler
 	scheduler scheduler.Scheduler
 
+	// Per-resource over-commit ratios for assignment checks
+	overcommit scheduler.OvercommitRatios
+
+	// Per-resource capacity held back from tasks on every worker
+	systemReserve scheduler.SystemReserve
+
+	// Rolling task outcomes per worker, used as a scheduling tie-breaker
+	outcomes *scheduler.OutcomeTracker
+	// Placement counters and latency histograms, exposed via /metrics and the CLI
+	schedMetrics *scheduler.SchedulerMetrics
+	// WatchTaskStatus subscribers, notified on queue position and status changes
+	watchers *taskWatchers
+	// Cluster-wide event subscribers (worker joins/leaves, task lifecycle), streamed over SSE
+	events *eventBus
+	// Receives terminal task events (webhooks); nil disables notifications
+	notifier notify.NotificationSink
+
 	// Telemetry manager for handling worker telemetry in separate threads
 	telemetryManager *telemetry.TelemetryManager
 
 	// Worker reconnection
-	reconnectTicker *time.Ticker
-	reconnectStop   chan bool
+	reconnectTicker      *time.Ticker
+	reconnectStop        chan bool
+	reconnectConcurrency int                                                              // Max concurrent reconnection dials
+	reconnectInFlight    atomic.Bool                                                      // A reconnection cycle is still dialing
+	dialWorker           func(ctx context.Context, addr string) (*grpc.ClientConn, error) // Dials a worker (replaceable in tests)
+	transportCreds       credentials.TransportCredentials                                 // Credentials for dials to workers (nil = plaintext)
+
+	// Worker RPCs from peers without a verified client certificate are refused (mutual TLS)
+	requireWorkerCert bool
+
+	// Max concurrent assignment attempts in one queue processing pass
+	queueConcurrency int
+	// New submissions are rejected while this many tasks are queued (0 = unbounded)
+	maxQueueLength int
+
+	// Keepalive pings on long-lived connections to workers
+	keepalive KeepaliveConfig
+
+	// Signs cluster join tokens; when set, unknown workers presenting a valid token are auto-registered
+	joinTokenSecret []byte
+
+	// Registering workers claiming more than capacityTolerance times their declared capacity are flagged (or rejected)
+	capacityTolerance      float64
+	rejectCapacityMismatch bool
+
+	// Queued tasks at or above preemptPriority may evict lower-priority running tasks (0 = disabled)
+	preemptPriority int32
+	preemptCooldown time.Duration        // Minimum time between preemptions on the same worker
+	lastPreemption  map[string]time.Time // Worker ID -> last preemption attempt there (guarded by mu)
+	preempted       map[string]string    // Evicted task ID -> worker it was evicted from (guarded by mu)
+	runningSpecs    map[string]*pb.Task  // Specs of tasks this master assigned, for picking preemption victims (guarded by mu)
+	placedZones     map[string]string    // Task ID -> zone its anti-affinity key is counted in by the scheduler (guarded by mu)
+	// Host ports running tasks' ports were published on, from heartbeats (guarded by mu)
+	publishedPorts map[string][]*pb.PortMapping
+	// Network modes tasks may use (guarded by mu)
+	allowedNetworks map[string]bool
+
+	// Tentative reservations awaiting a worker's ack, keyed by task ID (guarded by mu)
+	pendingReservations map[string]*pendingReservation
+	reservationTTL      time.Duration
+
+	// Blocks of capacity held for batches of tasks, keyed by reservation ID (guarded by mu)
+	capacityReservations map[string]*CapacityReservation
+
+	// Queue and capacity samples for autoscale recommendations, oldest first (guarded by mu)
+	autoscaleSamples []autoscaleSample
+	autoscaleWindow  time.Duration
+
+	// Periodic collection of running tasks whose worker is gone
+	taskGCTicker *time.Ticker
+	taskGCStop   chan bool
+
+	// Periodic cordoning of workers by their maintenance windows
+	maintenanceTicker *time.Ticker
+	maintenanceStop   chan bool
+
+	// Periodic deletion of finished tasks past their retention
+	retentionTicker *time.Ticker
+	retentionStop   chan bool
+
+	// Heartbeats closer together than this are ignored (0 = no floor)
+	minHeartbeatInterval time.Duration
 }
 
+// DefaultReconnectConcurrency is the default limit on concurrent reconnection dials
+const DefaultReconnectConcurrency = 8
+
+// DefaultQueueConcurrency is the default limit on concurrent assignment attempts per queue pass
+const DefaultQueueConcurrency = 8
+
 // WorkerState tracks the current state of a worker
 type WorkerState struct {
 	Info          *pb.WorkerInfo
@@ -77,6 +169,10 @@ type WorkerState struct {
 	LatestMemory  float64 // Latest memory usage from heartbeat
 	LatestGPU     float64 // Latest GPU usage from heartbeat
 	TaskCount     int     // Number of running tasks from latest heartbeat
+	// Task IDs in the latest heartbeat, to spot tasks the worker stops reporting
+	ReportedTasks map[string]bool
+	// Tasks the latest heartbeat reported ready (health check passed), to spot newly ready ones
+	ReadyTasks map[string]bool
 	// Resource tracking
 	AllocatedCPU     float64
 	AllocatedMemory  float64
@@ -86,6 +182,20 @@ type WorkerState struct {
 	AvailableMemory  float64
 	AvailableStorage float64
 	AvailableGPU     float64
+	// Unreachable is set when the registration-time probe could not reach the worker's address
+	Unreachable bool
+	// User-editable labels
+	DisplayName string
+	Annotations map[string]string
+	// Recurring maintenance window; Cordoned is set while the worker is inside it and takes no new tasks
+	Maintenance *db.MaintenanceWindow
+	Cordoned    bool
+	// Capacity declared by the admin at registration; CapacityWarning is set when the worker claimed implausibly more
+	Declared        *db.DeclaredCapacity
+	CapacityWarning string
+	// When the last processed heartbeat arrived, and whether heartbeats arriving too fast were reported
+	lastHeartbeatAt      time.Time
+	heartbeatFloodWarned bool
 }
 
 // TaskAssignment represents a task to be sent to a worker
@@ -96,7 +206,7 @@ type TaskAssignment struct {
 
 // NewMasterServer creates a new master server instance
 func NewMasterServer(workerDB *db.WorkerDB, taskDB *db.TaskDB, assignmentDB *db.AssignmentDB, resultDB *db.ResultDB, fileMetadataDB *db.FileMetadataDB, fileStorage *storage.FileStorageService, telemetryMgr *telemetry.TelemetryManager) *MasterServer {
-	return &MasterServer{
+	s := &MasterServer{
 		workers:          make(map[string]*WorkerState),
 		workerDB:         workerDB,
 		taskDB:           taskDB,
@@ -108,9 +218,134 @@ func NewMasterServer(workerDB *db.WorkerDB, taskDB *db.TaskDB, assignmentDB *db.
 		masterAddress:    "",
 		taskChan:         make(chan *TaskAssignment, 100),
 		taskQueue:        make([]*QueuedTask, 0),
+		heldTasks:        make(map[string]*pb.Task),
 		scheduler:        scheduler.NewRoundRobinScheduler(), // Use Round-Robin as default
+		overcommit:       scheduler.DefaultOvercommitRatios(),
+		outcomes:         scheduler.NewOutcomeTracker(scheduler.DefaultOutcomeWindow),
+		schedMetrics:     scheduler.NewSchedulerMetrics(),
+		watchers:         newTaskWatchers(),
+		events:           newEventBus(),
 		telemetryManager: telemetryMgr,
+
+		reconnectConcurrency: DefaultReconnectConcurrency,
+		queueConcurrency:     DefaultQueueConcurrency,
+		keepalive:            DefaultKeepaliveConfig(),
+
+		preemptCooldown: DefaultPreemptionCooldown,
+		lastPreemption:  make(map[string]time.Time),
+		preempted:       make(map[string]string),
+		runningSpecs:    make(map[string]*pb.Task),
+		placedZones:     make(map[string]string),
+		publishedPorts:  make(map[string][]*pb.PortMapping),
+
+		pendingReservations: make(map[string]*pendingReservation),
+		reservationTTL:      DefaultReservationTTL,
+
+		capacityReservations: make(map[string]*CapacityReservation),
+
+		autoscaleWindow: DefaultAutoscaleWindow,
+
+		capacityTolerance: DefaultCapacityTolerance,
+	}
+	s.dialWorker = s.dialWorkerBlocking
+	s.SetAllowedTaskNetworks(nil)
+	return s
+}
+
+// SetNotificationSink sets where terminal task events are sent; nil disables notifications
+func (s *MasterServer) SetNotificationSink(sink notify.NotificationSink) {
+	s.mu.Lock()
+	s.notifier = sink
+	s.mu.Unlock()
+}
+
+// notifyTaskTerminal sends a terminal-state event to task watchers and to the notification sink, if one is configured
+// It may be called with or without s.mu held; record may be nil, in which case the task is looked up for its user and start time
+func (s *MasterServer) notifyTaskTerminal(ctx context.Context, taskID, workerID, status string, record *db.Task) {
+	s.watchers.publish(&pb.TaskStatusUpdate{TaskId: taskID, Status: status, WorkerId: workerID})
+	s.events.publish(ClusterEvent{Type: EventTaskFinished, TaskID: taskID, WorkerID: workerID, Status: status})
+
+	if s.notifier == nil {
+		return
+	}
+	if record == nil && s.taskDB != nil {
+		if task, err := s.taskDB.GetTask(ctx, taskID); err == nil {
+			record = task
+		}
+	}
+
+	event := notify.TaskEvent{
+		TaskID:    taskID,
+		WorkerID:  workerID,
+		Status:    status,
+		Timestamp: time.Now().Unix(),
+	}
+	if record != nil {
+		event.UserID = record.UserID
+		started := record.StartedAt
+		if started.IsZero() {
+			started = record.CreatedAt
+		}
+		if !started.IsZero() {
+			event.DurationSeconds = time.Since(started).Seconds()
+		}
+	}
+	s.notifier.Notify(event)
+}
+
+// terminalStatus maps a worker-reported result status to the task's stored terminal status
+func terminalStatus(resultStatus string) string {
+	switch resultStatus {
+	case "success":
+		return "completed"
+	case "cancelled", "crashloop":
+		return resultStatus
+	default:
+		return "failed"
+	}
+}
+
+// SetReconnectConcurrency sets the maximum number of concurrent reconnection dials
+func (s *MasterServer) SetReconnectConcurrency(n int) {
+	if n <= 0 {
+		n = DefaultReconnectConcurrency
+	}
+	s.mu.Lock()
+	s.reconnectConcurrency = n
+	s.mu.Unlock()
+}
+
+// SetQueueConcurrency sets the maximum number of concurrent assignment attempts per queue pass
+func (s *MasterServer) SetQueueConcurrency(n int) {
+	if n <= 0 {
+		n = DefaultQueueConcurrency
+	}
+	s.mu.Lock()
+	s.queueConcurrency = n
+	s.mu.Unlock()
+}
+
+// SetMaxQueueLength sets how many tasks may wait in the queue before new submissions are shed (0 = unbounded)
+// Requeued and released tasks are always accepted so work already admitted is never dropped
+func (s *MasterServer) SetMaxQueueLength(n int) {
+	if n < 0 {
+		n = 0
 	}
+	s.queueMu.Lock()
+	s.maxQueueLength = n
+	s.queueMu.Unlock()
+}
+
+// queueFull reports whether the queue has reached its maximum length
+func (s *MasterServer) queueFull() (bool, int) {
+	s.queueMu.RLock()
+	defer s.queueMu.RUnlock()
+	return s.maxQueueLength > 0 && len(s.taskQueue) >= s.maxQueueLength, s.maxQueueLength
+}
+
+// dialWorkerBlocking dials a worker and waits for the connection to be established
+func (s *MasterServer) dialWorkerBlocking(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+	return grpc.DialContext(ctx, addr, s.dialCredentials(), grpc.WithBlock())
 }
 
 // SetMasterInfo sets the master ID and address
@@ -119,7 +354,7 @@ func (s *MasterServer) SetMasterInfo(masterID, masterAddress string) {
 	defer s.mu.Unlock()
 	s.masterID = masterID
 	s.masterAddress = masterAddress
-	log.Printf("Master info set: ID=%s, Address=%s", masterID, masterAddress)
+	logging.Infof("Master info set: ID=%s, Address=%s", masterID, masterAddress)
 }
 
 // GetMasterInfo returns the master ID and address
@@ -134,7 +369,90 @@ func (s *MasterServer) SetScheduler(sched scheduler.Scheduler) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.scheduler = sched
-	log.Printf("Scheduler set: %s", sched.GetName())
+	if oc, ok := sched.(overcommitScheduler); ok {
+		oc.SetOvercommitRatios(s.overcommit)
+	}
+	logging.Infof("Scheduler set: %s", sched.GetName())
+}
+
+// overcommitScheduler is implemented by schedulers whose feasibility checks honour over-commit ratios
+type overcommitScheduler interface {
+	SetOvercommitRatios(ratios scheduler.OvercommitRatios)
+}
+
+// zoneSpreadScheduler is implemented by schedulers that spread tasks sharing an anti-affinity key across zones
+// The master reports placements once a worker accepts the task, and releases them when the task stops running
+type zoneSpreadScheduler interface {
+	RecordZonePlacement(key, zone string)
+	ReleaseZonePlacement(key, zone string)
+}
+
+// trackRunningTask remembers the spec of a task a worker accepted and, for an anti-affine task,
+// has the scheduler count it in the worker's zone
+// Caller must hold s.mu
+func (s *MasterServer) trackRunningTask(task *pb.Task, worker *WorkerState) {
+	if _, tracked := s.runningSpecs[task.TaskId]; tracked {
+		s.forgetRunningTask(task.TaskId)
+	}
+	s.runningSpecs[task.TaskId] = task
+
+	zs, ok := s.scheduler.(zoneSpreadScheduler)
+	if !ok || task.AntiAffinityKey == "" {
+		return
+	}
+	zone := scheduler.WorkerZone(&scheduler.WorkerInfo{WorkerID: worker.Info.WorkerId, Zone: worker.Info.Zone})
+	zs.RecordZonePlacement(task.AntiAffinityKey, zone)
+	s.placedZones[task.TaskId] = zone
+}
+
+// forgetRunningTask drops the spec of a task that stopped running (completed, failed, cancelled or
+// released from its worker) and releases its anti-affinity zone placement
+// Caller must hold s.mu
+func (s *MasterServer) forgetRunningTask(taskID string) {
+	// A task that ends before it was stored as running no longer needs its committed reservation tracked
+	if r, ok := s.pendingReservations[taskID]; ok && r.committed {
+		delete(s.pendingReservations, taskID)
+	}
+	spec := s.runningSpecs[taskID]
+	delete(s.runningSpecs, taskID)
+	zone, placed := s.placedZones[taskID]
+	delete(s.placedZones, taskID)
+	if zs, ok := s.scheduler.(zoneSpreadScheduler); ok && placed && spec != nil {
+		zs.ReleaseZonePlacement(spec.AntiAffinityKey, zone)
+	}
+}
+
+// SetOvercommitRatios sets the per-resource over-commit ratios for assignment and scheduling
+func (s *MasterServer) SetOvercommitRatios(ratios scheduler.OvercommitRatios) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overcommit = ratios.Normalized()
+	if oc, ok := s.scheduler.(overcommitScheduler); ok {
+		oc.SetOvercommitRatios(s.overcommit)
+	}
+}
+
+// SetSystemReserve sets the per-resource capacity held back from tasks on every worker
+// Available resources of known workers are recomputed immediately
+func (s *MasterServer) SetSystemReserve(reserve scheduler.SystemReserve) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.systemReserve = reserve
+	for _, worker := range s.workers {
+		s.recomputeAvailable(worker)
+	}
+}
+
+// recomputeAvailable sets a worker's available resources to usable capacity (total - reserve) minus allocated
+// Caller must hold s.mu
+func (s *MasterServer) recomputeAvailable(worker *WorkerState) {
+	if worker.Info == nil {
+		return
+	}
+	worker.AvailableCPU = scheduler.Usable(worker.Info.TotalCpu, s.systemReserve.CPU) - worker.AllocatedCPU
+	worker.AvailableMemory = scheduler.Usable(worker.Info.TotalMemory, s.systemReserve.Memory) - worker.AllocatedMemory
+	worker.AvailableStorage = scheduler.Usable(worker.Info.TotalStorage, s.systemReserve.Storage) - worker.AllocatedStorage
+	worker.AvailableGPU = scheduler.Usable(worker.Info.TotalGpu, s.systemReserve.GPU) - worker.AllocatedGPU
 }
 
 // LoadWorkersFromDB loads registered workers from database into memory
@@ -160,6 +478,7 @@ func (s *MasterServer) LoadWorkersFromDB(ctx context.Context) error {
 				TotalMemory:  w.TotalMemory,
 				TotalStorage: w.TotalStorage,
 				TotalGpu:     w.TotalGPU,
+				Zone:         w.Zone,
 			},
 			LastHeartbeat:    w.LastHeartbeat,
 			IsActive:         w.IsActive,
@@ -172,13 +491,18 @@ func (s *MasterServer) LoadWorkersFromDB(ctx context.Context) error {
 			AvailableMemory:  w.AvailableMemory,
 			AvailableStorage: w.AvailableStorage,
 			AvailableGPU:     w.AvailableGPU,
+			DisplayName:      w.DisplayName,
+			Annotations:      w.Annotations,
+			Maintenance:      w.Maintenance,
+			Declared:         w.Declared,
 		}
+		s.recomputeAvailable(s.workers[w.WorkerID])
 	}
 
 	// Reconcile resources based on actual running tasks
 	s.ReconcileWorkerResources(ctx)
 
-	log.Printf("Loaded %d workers from database", len(workers))
+	logging.Infof("Loaded %d workers from database", len(workers))
 	return nil
 }
 
@@ -187,6 +511,17 @@ func (s *MasterServer) ManualRegisterWorker(ctx context.Context, workerID, worke
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.addWorker(ctx, workerID, workerIP); err != nil {
+		return err
+	}
+
+	logging.Infof("Manually registered worker: %s (Address: %s)", workerID, workerIP)
+	return nil
+}
+
+// addWorker persists a new worker and adds it to memory, inactive until it connects
+// This function assumes s.mu is already locked by the caller
+func (s *MasterServer) addWorker(ctx context.Context, workerID, workerIP string) error {
 	// Check if already exists
 	if _, exists := s.workers[workerID]; exists {
 		return fmt.Errorf("worker %s already registered", workerID)
@@ -226,8 +561,6 @@ func (s *MasterServer) ManualRegisterWorker(ctx context.Context, workerID, worke
 		AvailableStorage: 0.0,
 		AvailableGPU:     0.0,
 	}
-
-	log.Printf("Manually registered worker: %s (Address: %s)", workerID, workerIP)
 	return nil
 }
 
@@ -238,7 +571,7 @@ func (s *MasterServer) UpdateWorkerResourcesInMemory(workerID string, totalCPU,
 
 	worker, exists := s.workers[workerID]
 	if !exists {
-		log.Printf("Warning: Cannot update resources for non-existent worker: %s", workerID)
+		logging.Warnf("Warning: Cannot update resources for non-existent worker: %s", workerID)
 		return
 	}
 
@@ -248,37 +581,77 @@ func (s *MasterServer) UpdateWorkerResourcesInMemory(workerID string, totalCPU,
 	worker.Info.TotalStorage = totalStorage
 	worker.Info.TotalGpu = totalGPU
 
-	// Calculate available resources (total - allocated)
-	worker.AvailableCPU = totalCPU - worker.AllocatedCPU
-	worker.AvailableMemory = totalMemory - worker.AllocatedMemory
-	worker.AvailableStorage = totalStorage - worker.AllocatedStorage
-	worker.AvailableGPU = totalGPU - worker.AllocatedGPU
+	// Calculate available resources (total - reserve - allocated)
+	s.recomputeAvailable(worker)
 
 	// Mark worker as active since it has been configured
+	if !worker.IsActive {
+		s.events.publish(ClusterEvent{Type: EventWorkerJoined, WorkerID: workerID})
+	}
 	worker.IsActive = true
 
-	log.Printf("Updated worker %s resources: CPU=%.2f, Memory=%.2f, Storage=%.2f, GPU=%.2f",
+	logging.Infof("Updated worker %s resources: CPU=%.2f, Memory=%.2f, Storage=%.2f, GPU=%.2f",
 		workerID, totalCPU, totalMemory, totalStorage, totalGPU)
 }
 
+// ResourceAllocation is a snapshot of the resources allocated on a worker
+type ResourceAllocation struct {
+	CPU     float64
+	Memory  float64
+	Storage float64
+	GPU     float64
+}
+
+// WorkerReconcileChange describes the correction applied to a single worker
+type WorkerReconcileChange struct {
+	WorkerID    string
+	Before      ResourceAllocation
+	After       ResourceAllocation
+	TasksBefore int
+	TasksAfter  int
+}
+
+// ReconcileSummary reports the outcome of a resource reconciliation run
+type ReconcileSummary struct {
+	Skipped        bool // True when the databases are unavailable
+	WorkersChecked int
+	WorkersFixed   int
+	Changes        []WorkerReconcileChange
+	StartedAt      time.Time
+	CompletedAt    time.Time
+}
+
 // ReconcileWorkerResources reconciles allocated resources based on actual running tasks
 // This fixes stale resource allocations from completed tasks
 // Should be called: 1) On startup after loading workers, 2) Periodically, 3) After crashes
 func (s *MasterServer) ReconcileWorkerResources(ctx context.Context) error {
 	// This function assumes s.mu is already locked by the caller
+	_, err := s.reconcileWorkerResources(ctx)
+	return err
+}
+
+// reconcileWorkerResources performs the reconciliation and records every correction made
+// This function assumes s.mu is already locked by the caller
+func (s *MasterServer) reconcileWorkerResources(ctx context.Context) (*ReconcileSummary, error) {
+	summary := &ReconcileSummary{
+		StartedAt: time.Now(),
+		Changes:   make([]WorkerReconcileChange, 0),
+	}
 
 	if s.taskDB == nil || s.assignmentDB == nil {
-		log.Printf("⚠ Resource reconciliation skipped: databases not available")
-		return nil
+		logging.Warnf("⚠ Resource reconciliation skipped: databases not available")
+		summary.Skipped = true
+		summary.CompletedAt = time.Now()
+		return summary, nil
 	}
 
-	log.Printf("🔄 Starting resource reconciliation...")
+	logging.Infof("🔄 Starting resource reconciliation...")
 
 	// Get all running tasks from database
 	tasks, err := s.taskDB.GetTasksByStatus(ctx, "running")
 	if err != nil {
-		log.Printf("⚠ Failed to get running tasks for reconciliation: %v", err)
-		return err
+		logging.Warnf("⚠ Failed to get running tasks for reconciliation: %v", err)
+		return nil, err
 	}
 
 	// Build map of actual allocations per worker
@@ -291,7 +664,7 @@ func (s *MasterServer) ReconcileWorkerResources(ctx context.Context) error {
 		// Get assignment to find which worker
 		assignment, err := s.assignmentDB.GetAssignmentByTaskID(ctx, task.TaskID)
 		if err != nil {
-			log.Printf("⚠ Task %s has no assignment, skipping", task.TaskID)
+			logging.Warnf("⚠ Task %s has no assignment, skipping", task.TaskID)
 			continue
 		}
 
@@ -316,6 +689,20 @@ func (s *MasterServer) ReconcileWorkerResources(ctx context.Context) error {
 	fixedCount := 0
 	for workerID, worker := range s.workers {
 		actual := actualAllocations[workerID]
+		summary.WorkersChecked++
+
+		// Capacity reservations and in-flight assignments hold resources the database does not know about
+		tasksCPU, tasksMemory, tasksStorage, tasksGPU := actual.CPU, actual.Memory, actual.Storage, actual.GPU
+		held := s.heldCapacity(workerID)
+		inFlight := ResourceAllocation{CPU: held.CPU, Memory: held.Memory, GPU: held.GPU}
+		if actual.TaskIDs == nil {
+			actual.TaskIDs = make(map[string]bool)
+		}
+		s.addInFlightReservations(workerID, &inFlight, actual.TaskIDs)
+		actual.CPU += inFlight.CPU
+		actual.Memory += inFlight.Memory
+		actual.Storage += inFlight.Storage
+		actual.GPU += inFlight.GPU
 
 		// Check if resources are out of sync
 		if worker.AllocatedCPU != actual.CPU ||
@@ -325,6 +712,23 @@ func (s *MasterServer) ReconcileWorkerResources(ctx context.Context) error {
 
 			oldCPU := worker.AllocatedCPU
 			oldMem := worker.AllocatedMemory
+			change := WorkerReconcileChange{
+				WorkerID: workerID,
+				Before: ResourceAllocation{
+					CPU:     worker.AllocatedCPU,
+					Memory:  worker.AllocatedMemory,
+					Storage: worker.AllocatedStorage,
+					GPU:     worker.AllocatedGPU,
+				},
+				After: ResourceAllocation{
+					CPU:     actual.CPU,
+					Memory:  actual.Memory,
+					Storage: actual.Storage,
+					GPU:     actual.GPU,
+				},
+				TasksBefore: len(worker.RunningTasks),
+				TasksAfter:  len(actual.TaskIDs),
+			}
 
 			// Fix the allocations
 			worker.AllocatedCPU = actual.CPU
@@ -332,11 +736,8 @@ func (s *MasterServer) ReconcileWorkerResources(ctx context.Context) error {
 			worker.AllocatedStorage = actual.Storage
 			worker.AllocatedGPU = actual.GPU
 
-			// Recalculate available resources
-			worker.AvailableCPU = worker.Info.TotalCpu - actual.CPU
-			worker.AvailableMemory = worker.Info.TotalMemory - actual.Memory
-			worker.AvailableStorage = worker.Info.TotalStorage - actual.Storage
-			worker.AvailableGPU = worker.Info.TotalGpu - actual.GPU
+			// Recalculate available resources (total - reserve - allocated)
+			s.recomputeAvailable(worker)
 
 			// Update running tasks map
 			worker.RunningTasks = actual.TaskIDs
@@ -346,31 +747,39 @@ func (s *MasterServer) ReconcileWorkerResources(ctx context.Context) error {
 			if s.workerDB != nil && oldCPU > 0 {
 				if err := s.workerDB.ReleaseResources(ctx, workerID,
 					oldCPU, oldMem, worker.AllocatedStorage, worker.AllocatedGPU); err != nil {
-					log.Printf("⚠ Failed to release old resources for %s in DB: %v", workerID, err)
+					logging.Warnf("⚠ Failed to release old resources for %s in DB: %v", workerID, err)
 				}
 			}
 
-			// Now allocate the correct amount
-			if s.workerDB != nil && actual.CPU > 0 {
+			// Now allocate the correct amount (the database tracks tasks only, not reservation holds)
+			if s.workerDB != nil && tasksCPU > 0 {
 				if err := s.workerDB.AllocateResources(ctx, workerID,
-					actual.CPU, actual.Memory, actual.Storage, actual.GPU); err != nil {
-					log.Printf("⚠ Failed to allocate resources for %s in DB: %v", workerID, err)
+					tasksCPU, tasksMemory, tasksStorage, tasksGPU); err != nil {
+					logging.Warnf("⚠ Failed to allocate resources for %s in DB: %v", workerID, err)
 				}
 			}
 
-			log.Printf("  ✓ Fixed %s: CPU %.1f→%.1f, Memory %.1f→%.1f, Tasks: %d",
+			logging.Debugf("  ✓ Fixed %s: CPU %.1f→%.1f, Memory %.1f→%.1f, Tasks: %d",
 				workerID, oldCPU, actual.CPU, oldMem, actual.Memory, len(actual.TaskIDs))
+			summary.Changes = append(summary.Changes, change)
 			fixedCount++
 		}
 	}
 
 	if fixedCount > 0 {
-		log.Printf("✓ Resource reconciliation complete: fixed %d workers", fixedCount)
+		logging.Infof("✓ Resource reconciliation complete: fixed %d workers", fixedCount)
 	} else {
-		log.Printf("✓ Resource reconciliation complete: all workers correct")
+		logging.Infof("✓ Resource reconciliation complete: all workers correct")
 	}
 
-	return nil
+	// Keep the report stable regardless of map iteration order
+	sort.Slice(summary.Changes, func(i, j int) bool {
+		return summary.Changes[i].WorkerID < summary.Changes[j].WorkerID
+	})
+	summary.WorkersFixed = fixedCount
+	summary.CompletedAt = time.Now()
+
+	return summary, nil
 }
 
 // ReconcileWorkerResourcesPublic is a public wrapper that acquires the lock
@@ -380,22 +789,70 @@ func (s *MasterServer) ReconcileWorkerResourcesPublic(ctx context.Context) error
 	return s.ReconcileWorkerResources(ctx)
 }
 
+// ReconcileWorkerResourcesWithSummary acquires the lock, reconciles resources and
+// returns a summary of the workers that were corrected (used by the HTTP admin API)
+func (s *MasterServer) ReconcileWorkerResourcesWithSummary(ctx context.Context) (*ReconcileSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reconcileWorkerResources(ctx)
+}
+
+// ReconcileWorker acquires the lock and reconciles a single worker's allocations against its running tasks
+// Other workers are left untouched; the returned change reports the allocation before and after
+func (s *MasterServer) ReconcileWorker(ctx context.Context, workerID string) (*WorkerReconcileChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	worker, exists := s.workers[workerID]
+	if !exists {
+		return nil, fmt.Errorf("worker %s not found", workerID)
+	}
+	if s.taskDB == nil || s.assignmentDB == nil {
+		return nil, fmt.Errorf("databases not available")
+	}
+
+	change := &WorkerReconcileChange{
+		WorkerID: workerID,
+		Before: ResourceAllocation{
+			CPU:     worker.AllocatedCPU,
+			Memory:  worker.AllocatedMemory,
+			Storage: worker.AllocatedStorage,
+			GPU:     worker.AllocatedGPU,
+		},
+		TasksBefore: len(worker.RunningTasks),
+	}
+
+	if err := s.reconcileSingleWorker(ctx, workerID, worker); err != nil {
+		return nil, err
+	}
+
+	change.After = ResourceAllocation{
+		CPU:     worker.AllocatedCPU,
+		Memory:  worker.AllocatedMemory,
+		Storage: worker.AllocatedStorage,
+		GPU:     worker.AllocatedGPU,
+	}
+	change.TasksAfter = len(worker.RunningTasks)
+
+	return change, nil
+}
+
 // reconcileSingleWorker reconciles resources for a specific worker based on actual running tasks
 // This function assumes s.mu is already locked by the caller
-func (s *MasterServer) reconcileSingleWorker(ctx context.Context, workerID string, worker *WorkerState) {
+func (s *MasterServer) reconcileSingleWorker(ctx context.Context, workerID string, worker *WorkerState) error {
 	if s.taskDB == nil || s.assignmentDB == nil {
-		log.Printf("⚠ Resource reconciliation skipped for %s: databases not available", workerID)
-		return
+		logging.Warnf("⚠ Resource reconciliation skipped for %s: databases not available", workerID)
+		return nil
 	}
 
 	// Get all running tasks assigned to this worker
 	tasks, err := s.taskDB.GetTasksByStatus(ctx, "running")
 	if err != nil {
-		log.Printf("⚠ Failed to get running tasks for reconciliation: %v", err)
-		return
+		logging.Warnf("⚠ Failed to get running tasks for reconciliation: %v", err)
+		return fmt.Errorf("get running tasks: %w", err)
 	}
 
-	log.Printf("  🔍 Reconciliation: Found %d tasks with 'running' status in database", len(tasks))
+	logging.Debugf("  🔍 Reconciliation: Found %d tasks with 'running' status in database", len(tasks))
 
 	// Calculate actual resource usage from running tasks
 	var actualCPU, actualMemory, actualStorage, actualGPU float64
@@ -405,12 +862,12 @@ func (s *MasterServer) reconcileSingleWorker(ctx context.Context, workerID strin
 		// Get assignment to find which worker
 		assignment, err := s.assignmentDB.GetAssignmentByTaskID(ctx, task.TaskID)
 		if err != nil {
-			log.Printf("  ⚠ Task %s has no assignment, skipping", task.TaskID)
+			logging.Warnf("  ⚠ Task %s has no assignment, skipping", task.TaskID)
 			continue
 		}
 
 		if assignment.WorkerID == workerID {
-			log.Printf("  📋 Found task %s assigned to %s (CPU=%.1f, Mem=%.1f, Storage=%.1f, GPU=%.1f)",
+			logging.Debugf("  📋 Found task %s assigned to %s (CPU=%.1f, Mem=%.1f, Storage=%.1f, GPU=%.1f)",
 				task.TaskID, workerID, task.ReqCPU, task.ReqMemory, task.ReqStorage, task.ReqGPU)
 			actualCPU += task.ReqCPU
 			actualMemory += task.ReqMemory
@@ -420,38 +877,62 @@ func (s *MasterServer) reconcileSingleWorker(ctx context.Context, workerID strin
 		}
 	}
 
-	// Update worker's allocated resources
-	worker.AllocatedCPU = actualCPU
-	worker.AllocatedMemory = actualMemory
-	worker.AllocatedStorage = actualStorage
-	worker.AllocatedGPU = actualGPU
+	// Update worker's allocated resources, adding back what capacity reservations and in-flight assignments hold there
+	held := s.heldCapacity(workerID)
+	inFlight := ResourceAllocation{CPU: held.CPU, Memory: held.Memory, GPU: held.GPU}
+	s.addInFlightReservations(workerID, &inFlight, actualTaskIDs)
+	worker.AllocatedCPU = actualCPU + inFlight.CPU
+	worker.AllocatedMemory = actualMemory + inFlight.Memory
+	worker.AllocatedStorage = actualStorage + inFlight.Storage
+	worker.AllocatedGPU = actualGPU + inFlight.GPU
 
-	// Recalculate available resources
-	worker.AvailableCPU = worker.Info.TotalCpu - actualCPU
-	worker.AvailableMemory = worker.Info.TotalMemory - actualMemory
-	worker.AvailableStorage = worker.Info.TotalStorage - actualStorage
-	worker.AvailableGPU = worker.Info.TotalGpu - actualGPU
+	// Recalculate available resources (total - reserve - allocated)
+	s.recomputeAvailable(worker)
 
 	// Update running tasks map
 	worker.RunningTasks = actualTaskIDs
 
-	// Update database with correct allocations
+	// Update database with correct allocations (it tracks tasks only, not reservation holds)
 	if s.workerDB != nil {
 		if err := s.workerDB.SetWorkerResources(ctx, workerID,
 			actualCPU, actualMemory, actualStorage, actualGPU,
-			worker.AvailableCPU, worker.AvailableMemory, worker.AvailableStorage, worker.AvailableGPU); err != nil {
-			log.Printf("⚠ Failed to update resources for %s in DB: %v", workerID, err)
+			worker.AvailableCPU+inFlight.CPU, worker.AvailableMemory+inFlight.Memory, worker.AvailableStorage+inFlight.Storage, worker.AvailableGPU+inFlight.GPU); err != nil {
+			logging.Warnf("⚠ Failed to update resources for %s in DB: %v", workerID, err)
 		}
 	}
 
-	log.Printf("  ✓ Reconciled %s: CPU=%.1f, Memory=%.1f, Storage=%.1f, GPU=%.1f, Tasks=%d",
+	logging.Debugf("  ✓ Reconciled %s: CPU=%.1f, Memory=%.1f, Storage=%.1f, GPU=%.1f, Tasks=%d",
 		workerID, actualCPU, actualMemory, actualStorage, actualGPU, len(actualTaskIDs))
+	return nil
 }
 
+// workerProbeTimeout bounds the reachability probe run when a worker is registered
+const workerProbeTimeout = 2 * time.Second
+
 // ManualRegisterAndNotify registers a worker and immediately tries to notify it of the master's address
-func (s *MasterServer) ManualRegisterAndNotify(ctx context.Context, workerID, workerIP, masterID, masterAddress string) error {
+// declared (optional) is the capacity the worker is expected to have, checked when it connects
+// The worker's address is probed first; an unreachable address is still registered but flagged,
+// and the returned warning describes the problem (empty when the worker was reachable)
+func (s *MasterServer) ManualRegisterAndNotify(ctx context.Context, workerID, workerIP string, declared *db.DeclaredCapacity, masterID, masterAddress string) (string, error) {
 	if err := s.ManualRegisterWorker(ctx, workerID, workerIP); err != nil {
-		return err
+		return "", err
+	}
+	if declared != nil {
+		if err := s.DeclareWorkerCapacity(ctx, workerID, declared); err != nil {
+			return "", err
+		}
+	}
+
+	warning := ""
+	if err := s.probeWorkerAddress(ctx, workerIP); err != nil {
+		warning = fmt.Sprintf("worker %s is not reachable at %s: %v", workerID, workerIP, err)
+		logging.Warnf("⚠ Registered %s but its address failed the reachability probe: %v", workerID, err)
+
+		s.mu.Lock()
+		if worker, exists := s.workers[workerID]; exists {
+			worker.Unreachable = true
+		}
+		s.mu.Unlock()
 	}
 
 	// Attempt to contact worker and send MasterRegister
@@ -459,9 +940,9 @@ func (s *MasterServer) ManualRegisterAndNotify(ctx context.Context, workerID, wo
 		cctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		conn, err := grpc.DialContext(cctx, workerIP, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		conn, err := grpc.DialContext(cctx, workerIP, s.dialCredentials(), grpc.WithBlock())
 		if err != nil {
-			log.Printf("Failed to connect to worker %s (%s) for MasterRegister: %v", workerID, workerIP, err)
+			logging.Errorf("Failed to connect to worker %s (%s) for MasterRegister: %v", workerID, workerIP, err)
 			return
 		}
 		defer conn.Close()
@@ -470,16 +951,31 @@ func (s *MasterServer) ManualRegisterAndNotify(ctx context.Context, workerID, wo
 		mi := &pb.MasterInfo{MasterId: masterID, MasterAddress: masterAddress}
 		ack, err := client.MasterRegister(cctx, mi)
 		if err != nil {
-			log.Printf("MasterRegister RPC to worker %s (%s) failed: %v", workerID, workerIP, err)
+			logging.Warnf("MasterRegister RPC to worker %s (%s) failed: %v", workerID, workerIP, err)
 			return
 		}
 		if ack != nil && !ack.Success {
-			log.Printf("MasterRegister rejected by worker %s: %s", workerID, ack.Message)
+			logging.Warnf("MasterRegister rejected by worker %s: %s", workerID, ack.Message)
 		}
 		// Success case: no log to keep CLI clean
 	}()
 
-	return nil
+	return warning, nil
+}
+
+// probeWorkerAddress checks that a gRPC connection to addr can be established
+func (s *MasterServer) probeWorkerAddress(ctx context.Context, addr string) error {
+	pctx, cancel := context.WithTimeout(ctx, workerProbeTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(pctx, addr,
+		s.dialCredentials(),
+		grpc.WithBlock(),
+		grpc.FailOnNonTempDialError(true))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
 }
 
 // StartWorkerReconnectionMonitor starts a background process that periodically attempts
@@ -489,14 +985,14 @@ func (s *MasterServer) StartWorkerReconnectionMonitor() {
 	s.reconnectStop = make(chan bool)
 
 	go func() {
-		log.Println("🔄 Worker reconnection monitor started")
+		logging.Info("🔄 Worker reconnection monitor started")
 		for {
 			select {
 			case <-s.reconnectTicker.C:
 				s.checkAndMarkInactiveWorkers() // Check for inactive workers first
 				s.attemptWorkerReconnections()
 			case <-s.reconnectStop:
-				log.Println("🛑 Worker reconnection monitor stopped")
+				logging.Info("🛑 Worker reconnection monitor stopped")
 				return
 			}
 		}
@@ -525,8 +1021,9 @@ func (s *MasterServer) checkAndMarkInactiveWorkers() {
 		if worker.IsActive && worker.LastHeartbeat > 0 {
 			timeSinceLastHeartbeat := now - worker.LastHeartbeat
 			if timeSinceLastHeartbeat > heartbeatTimeout {
-				log.Printf("⚠️ Worker %s marked as inactive (no heartbeat for %d seconds)", workerID, timeSinceLastHeartbeat)
+				logging.Warnf("⚠️ Worker %s marked as inactive (no heartbeat for %d seconds)", workerID, timeSinceLastHeartbeat)
 				worker.IsActive = false
+				s.events.publish(ClusterEvent{Type: EventWorkerLeft, WorkerID: workerID, Message: "Heartbeat timed out"})
 			}
 		}
 	}
@@ -537,6 +1034,7 @@ func (s *MasterServer) attemptWorkerReconnections() {
 	s.mu.RLock()
 	masterID := s.masterID
 	masterAddress := s.masterAddress
+	limit := s.reconnectConcurrency
 
 	// Collect inactive workers
 	inactiveWorkers := make(map[string]string) // workerID -> workerIP
@@ -547,15 +1045,44 @@ func (s *MasterServer) attemptWorkerReconnections() {
 	}
 	s.mu.RUnlock()
 
-	// If there are inactive workers, attempt to reconnect
-	if len(inactiveWorkers) > 0 {
-		log.Printf("🔄 Attempting to reconnect to %d inactive worker(s)...", len(inactiveWorkers))
+	if len(inactiveWorkers) == 0 {
+		return
+	}
+
+	// Skip this cycle if the previous one is still dialing, so slow dials never pile up
+	if !s.reconnectInFlight.CompareAndSwap(false, true) {
+		return
+	}
 
-		for workerID, workerIP := range inactiveWorkers {
-			// Launch reconnection attempt in goroutine (non-blocking)
-			go s.attemptSingleWorkerReconnection(workerID, workerIP, masterID, masterAddress)
-		}
+	logging.Infof("🔄 Attempting to reconnect to %d inactive worker(s)...", len(inactiveWorkers))
+
+	// Run the cycle in the background so the monitor loop is never blocked
+	go func() {
+		defer s.reconnectInFlight.Store(false)
+		s.reconnectWorkers(inactiveWorkers, masterID, masterAddress, limit)
+	}()
+}
+
+// reconnectWorkers attempts to reconnect to each worker with at most limit dials in flight
+func (s *MasterServer) reconnectWorkers(workers map[string]string, masterID, masterAddress string, limit int) {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for workerID, workerIP := range workers {
+		sem <- struct{}{} // Blocks while limit dials are running
+		wg.Add(1)
+		go func(workerID, workerIP string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.attemptSingleWorkerReconnection(workerID, workerIP, masterID, masterAddress)
+		}(workerID, workerIP)
 	}
+
+	wg.Wait()
 }
 
 // attemptSingleWorkerReconnection attempts to reconnect to a single worker
@@ -563,9 +1090,7 @@ func (s *MasterServer) attemptSingleWorkerReconnection(workerID, workerIP, maste
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, workerIP,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock())
+	conn, err := s.dialWorker(ctx, workerIP)
 	if err != nil {
 		// Worker still offline, silently skip (don't spam logs)
 		return
@@ -581,7 +1106,7 @@ func (s *MasterServer) attemptSingleWorkerReconnection(workerID, workerIP, maste
 	}
 
 	if ack != nil && ack.Success {
-		log.Printf("✓ Successfully reconnected to worker %s (%s)", workerID, workerIP)
+		logging.Infof("✓ Successfully reconnected to worker %s (%s)", workerID, workerIP)
 	}
 }
 
@@ -609,108 +1134,402 @@ func (s *MasterServer) UnregisterWorker(ctx context.Context, workerID string) er
 
 	// Remove from memory
 	delete(s.workers, workerID)
+	s.forgetPreemptionsOn(workerID)
 
-	log.Printf("Unregistered worker: %s", workerID)
+	s.events.publish(ClusterEvent{Type: EventWorkerLeft, WorkerID: workerID, Message: "Unregistered"})
+	logging.Infof("Unregistered worker: %s", workerID)
 	return nil
 }
 
-// RegisterWorker handles worker registration requests
-// Workers can ONLY register if they have been manually pre-registered by admin
-func (s *MasterServer) RegisterWorker(ctx context.Context, info *pb.WorkerInfo) (*pb.RegisterAck, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// WorkerExpiry reports the outcome of force-expiring a worker
+type WorkerExpiry struct {
+	WorkerID      string
+	RequeuedTasks []string // Running tasks put back on the queue
+	SkippedTasks  []string // Running tasks that could not be requeued (left on the worker)
+}
 
-	// Check if worker was manually pre-registered by admin
-	existingWorker, exists := s.workers[info.WorkerId]
-	if !exists {
-		// Worker NOT pre-registered - reject the connection
-		log.Printf("❌ Rejected unauthorized worker registration attempt: %s (Address: %s)",
-			info.WorkerId, info.WorkerIp)
-		return &pb.RegisterAck{
-			Success: false,
-			Message: fmt.Sprintf("Worker %s is not authorized. Admin must register it first using: register %s <ip:port>",
-				info.WorkerId, info.WorkerId),
-		}, fmt.Errorf("worker %s not authorized - must be pre-registered by admin", info.WorkerId)
+// SetWorkerLabels updates a worker's display name and merges its annotations
+// A nil display name is left untouched; an empty annotation value removes that key
+func (s *MasterServer) SetWorkerLabels(ctx context.Context, workerID string, displayName *string, annotations map[string]string) error {
+	if err := db.ValidateAnnotations(annotations); err != nil {
+		return err
 	}
 
-	// Check if this is a new registration (worker connecting for the first time or reconnecting with new specs)
-	isNewConnection := existingWorker.Info.TotalCpu == 0 || !existingWorker.IsActive
-
-	// Ensure RunningTasks map is initialized (defensive programming)
-	if existingWorker.RunningTasks == nil {
-		existingWorker.RunningTasks = make(map[string]bool)
+	s.mu.Lock()
+	worker, exists := s.workers[workerID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("worker %s not found", workerID)
+	}
+	if displayName != nil {
+		worker.DisplayName = *displayName
 	}
+	for key, value := range annotations {
+		if value == "" {
+			delete(worker.Annotations, key)
+			continue
+		}
+		if worker.Annotations == nil {
+			worker.Annotations = make(map[string]string)
+		}
+		worker.Annotations[key] = value
+	}
+	s.mu.Unlock()
 
-	// Worker IS pre-registered - update with full specs but preserve the IP from manual registration
-	preservedIP := existingWorker.Info.WorkerIp
-	existingWorker.Info = info
+	if s.workerDB != nil {
+		if err := s.workerDB.UpdateWorkerLabels(ctx, workerID, displayName, annotations); err != nil {
+			return fmt.Errorf("persist worker labels: %w", err)
+		}
+	}
+	return nil
+}
 
-	// If worker didn't provide IP or provided empty IP, use the one from manual registration
-	if existingWorker.Info.WorkerIp == "" {
-		existingWorker.Info.WorkerIp = preservedIP
-		log.Printf("✓ Worker %s registered - using pre-configured address: %s", info.WorkerId, preservedIP)
+// copyAnnotations returns a copy so snapshots don't share the live map
+func copyAnnotations(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		out[k] = v
 	}
+	return out
+}
 
-	existingWorker.IsActive = true
-	existingWorker.LastHeartbeat = time.Now().Unix()
+// ErrWorkerNotFound is returned by ExpireWorker for a worker that is not registered
+var ErrWorkerNotFound = errors.New("worker not found")
 
-	// If this is a new connection or reconnection, reconcile resources for this worker
-	// to ensure allocated resources match actual running tasks
-	if isNewConnection {
-		log.Printf("🔄 Worker %s connected with new specs, reconciling resources...", info.WorkerId)
+// expiredWorkerDialTimeout bounds the connection attempt to a force-expired worker, which is usually gone
+const expiredWorkerDialTimeout = 5 * time.Second
 
-		// Initialize allocated resources to 0 first, reconciliation will fix them
-		existingWorker.AllocatedCPU = 0.0
-		existingWorker.AllocatedMemory = 0.0
-		existingWorker.AllocatedStorage = 0.0
-		existingWorker.AllocatedGPU = 0.0
+// ExpireWorker immediately marks a worker inactive and drops its telemetry registration
+// without waiting for the heartbeat staleness sweep. When requeue is set, its running
+// tasks are stopped on the worker (if it can still be reached), released from it and
+// queued for rescheduling; later reports from the worker for those tasks are ignored.
+// A worker that is actually alive becomes active again on its next heartbeat.
+func (s *MasterServer) ExpireWorker(ctx context.Context, workerID string, requeue bool) (*WorkerExpiry, error) {
+	s.mu.Lock()
+	worker, exists := s.workers[workerID]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrWorkerNotFound, workerID)
+	}
+	workerIP := worker.Info.WorkerIp
 
-		// Initialize available resources to total
-		existingWorker.AvailableCPU = info.TotalCpu
-		existingWorker.AvailableMemory = info.TotalMemory
-		existingWorker.AvailableStorage = info.TotalStorage
-		existingWorker.AvailableGPU = info.TotalGpu
+	if worker.IsActive {
+		s.events.publish(ClusterEvent{Type: EventWorkerLeft, WorkerID: workerID, Message: "Force-expired"})
+	}
+	worker.IsActive = false
+	runningTasks := make([]string, 0, len(worker.RunningTasks))
+	for taskID := range worker.RunningTasks {
+		runningTasks = append(runningTasks, taskID)
+	}
+	s.mu.Unlock()
 
-		// Trigger reconciliation for this specific worker to fix resources based on actual running tasks
-		s.reconcileSingleWorker(ctx, info.WorkerId, existingWorker)
-	} else {
-		// Worker is already connected with same specs, just update available resources
-		existingWorker.AvailableCPU = info.TotalCpu - existingWorker.AllocatedCPU
-		existingWorker.AvailableMemory = info.TotalMemory - existingWorker.AllocatedMemory
-		existingWorker.AvailableStorage = info.TotalStorage - existingWorker.AllocatedStorage
-		existingWorker.AvailableGPU = info.TotalGpu - existingWorker.AllocatedGPU
+	if s.telemetryManager != nil {
+		s.telemetryManager.UnregisterWorker(workerID)
 	}
 
-	// Update in database
 	if s.workerDB != nil {
-		if err := s.workerDB.UpdateWorkerInfo(ctx, existingWorker.Info); err != nil {
-			log.Printf("Warning: failed to update worker in db: %v", err)
+		if err := s.workerDB.MarkInactive(ctx, workerID); err != nil {
+			return nil, fmt.Errorf("mark worker %s inactive: %w", workerID, err)
 		}
 	}
 
-	// Register worker with telemetry manager
-	if s.telemetryManager != nil {
-		s.telemetryManager.RegisterWorker(info.WorkerId)
+	expiry := &WorkerExpiry{WorkerID: workerID, RequeuedTasks: []string{}, SkippedTasks: []string{}}
+	logging.Warnf("⚠️ Worker %s force-expired (%d running task(s), requeue=%v)", workerID, len(runningTasks), requeue)
+
+	if !requeue {
+		return expiry, nil
 	}
 
-	return &pb.RegisterAck{
-		Success: true,
-		Message: "Worker registered successfully",
-	}, nil
-}
+	records := make([]*db.Task, 0, len(runningTasks))
+	for _, taskID := range runningTasks {
+		if s.taskDB == nil {
+			expiry.SkippedTasks = append(expiry.SkippedTasks, taskID)
+			continue
+		}
+		record, err := s.taskDB.GetTask(ctx, taskID)
+		if err != nil {
+			logging.Warnf("Warning: cannot requeue task %s from expired worker %s: %v", taskID, workerID, err)
+			expiry.SkippedTasks = append(expiry.SkippedTasks, taskID)
+			continue
+		}
+		records = append(records, record)
+	}
 
-// SendHeartbeat processes heartbeat messages from workers
-func (s *MasterServer) SendHeartbeat(ctx context.Context, hb *pb.Heartbeat) (*pb.HeartbeatAck, error) {
+	// The worker may still be running the tasks, so stop them there before they run anywhere else;
+	// the cancelled reports this produces are stale once the tasks are requeued
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	for _, record := range records {
+		s.preempted[record.TaskID] = workerID
+	}
+	s.mu.Unlock()
+	s.stopTasksOnExpiredWorker(ctx, workerID, workerIP, records)
+
+	for _, record := range records {
+		// Release the task's allocation from the expired worker
+		s.releaseTaskFromWorker(ctx, workerID, record)
+
+		if err := s.taskDB.UpdateTaskStatus(ctx, record.TaskID, "pending"); err != nil {
+			logging.Warnf("Warning: failed to reset task %s to pending: %v", record.TaskID, err)
+		}
+
+		s.EnqueueTask(taskFromRecord(record), fmt.Sprintf("worker %s expired", workerID))
+		expiry.RequeuedTasks = append(expiry.RequeuedTasks, record.TaskID)
+	}
+
+	return expiry, nil
+}
+
+// stopTasksOnExpiredWorker asks a force-expired worker to stop tasks that are about to be requeued
+// Failures are only logged: an unreachable worker is most likely gone, and the tasks are requeued either way
+func (s *MasterServer) stopTasksOnExpiredWorker(ctx context.Context, workerID, workerIP string, records []*db.Task) {
+	if len(records) == 0 {
+		return
+	}
+
+	dialCtx, cancelDial := context.WithTimeout(ctx, expiredWorkerDialTimeout)
+	conn, err := s.dialWorker(dialCtx, workerIP)
+	cancelDial()
+	if err != nil {
+		logging.Warnf("Warning: could not reach expired worker %s to stop its tasks: %v", workerID, err)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMasterWorkerClient(conn)
+	var wg sync.WaitGroup
+	for _, record := range records {
+		wg.Add(1)
+		go func(record *db.Task) {
+			defer wg.Done()
+			// The worker may wait the task's whole stop grace before the container is killed
+			stopCtx, cancel := context.WithTimeout(ctx, cancelTimeout(taskFromRecord(record)))
+			defer cancel()
+
+			ack, err := client.CancelTask(stopCtx, &pb.TaskID{TaskId: record.TaskID})
+			switch {
+			case err != nil:
+				logging.Warnf("Warning: failed to stop task %s on expired worker %s: %v", record.TaskID, workerID, err)
+			case !ack.Success:
+				logging.Warnf("Warning: expired worker %s did not stop task %s: %s", workerID, record.TaskID, ack.Message)
+			default:
+				logging.Infof("🛑 Stopped task %s on expired worker %s before requeueing it", record.TaskID, workerID)
+			}
+		}(record)
+	}
+	wg.Wait()
+}
 
+// taskRecord builds the database record of a submitted task; taskFromRecord turns it back into the task
+func taskRecord(task *pb.Task, status string) *db.Task {
+	return &db.Task{
+		TaskID:         task.TaskId,
+		UserID:         task.UserId,
+		TaskName:       task.TaskName,
+		SubmittedAt:    task.SubmittedAt,
+		DockerImage:    task.DockerImage,
+		Command:        task.Command,
+		ReqCPU:         task.ReqCpu,
+		ReqMemory:      task.ReqMemory,
+		ReqStorage:     task.ReqStorage,
+		ReqGPU:         task.ReqGpu,
+		TaskType:       task.TaskType,      // NEW: Save task type for training
+		SLAMultiplier:  task.SlaMultiplier, // NEW: Save SLA multiplier
+		Priority:       task.Priority,
+		OriginalTaskID: task.OriginalTaskId,
+		ExternalRef:    task.ExternalRef,
+		NetworkMode:    task.NetworkMode,
+		PortBindings:   task.PortBindings,
+		WorkingDir:     task.WorkingDir,
+		RunAsUser:      task.RunAsUser,
+		ReadOnlyRootFS: task.ReadOnlyRootfs,
+		HealthCheck:    task.HealthCheck,
+		HealthTimeout:  task.HealthCheckTimeoutSec,
+		Status:         status,
+
+		PinCPUs:            task.PinCpus,
+		Cacheable:          task.Cacheable,
+		LocalityKey:        task.LocalityKey,
+		AbsoluteDeadline:   task.Deadline,
+		MaxRestarts:        task.MaxRestarts,
+		MemLimit:           task.MemLimit,
+		StopGracePeriodSec: task.StopGracePeriodSec,
+		AntiAffinityKey:    task.AntiAffinityKey,
+		PreferredWorkerID:  task.PreferredWorkerId,
+		ReservationID:      task.ReservationId,
+	}
+}
+
+// taskFromRecord rebuilds a schedulable task from its database record
+func taskFromRecord(t *db.Task) *pb.Task {
+	return &pb.Task{
+		TaskId:         t.TaskID,
+		DockerImage:    t.DockerImage,
+		Command:        t.Command,
+		ReqCpu:         t.ReqCPU,
+		ReqMemory:      t.ReqMemory,
+		ReqStorage:     t.ReqStorage,
+		ReqGpu:         t.ReqGPU,
+		UserId:         t.UserID,
+		SlaMultiplier:  t.SLAMultiplier,
+		TaskType:       t.TaskType,
+		TaskName:       t.TaskName,
+		SubmittedAt:    t.SubmittedAt,
+		Priority:       t.Priority,
+		OriginalTaskId: t.OriginalTaskID,
+		ExternalRef:    t.ExternalRef,
+		NetworkMode:    t.NetworkMode,
+		PortBindings:   t.PortBindings,
+		WorkingDir:     t.WorkingDir,
+		RunAsUser:      t.RunAsUser,
+		ReadOnlyRootfs: t.ReadOnlyRootFS,
+
+		HealthCheck:           t.HealthCheck,
+		HealthCheckTimeoutSec: t.HealthTimeout,
+
+		PinCpus:            t.PinCPUs,
+		Cacheable:          t.Cacheable,
+		LocalityKey:        t.LocalityKey,
+		Deadline:           t.AbsoluteDeadline,
+		MaxRestarts:        t.MaxRestarts,
+		MemLimit:           t.MemLimit,
+		StopGracePeriodSec: t.StopGracePeriodSec,
+		AntiAffinityKey:    t.AntiAffinityKey,
+		PreferredWorkerId:  t.PreferredWorkerID,
+		ReservationId:      t.ReservationID,
+	}
+}
+
+// RegisterWorker handles worker registration requests
+// Workers can ONLY register if they have been manually pre-registered by admin
+func (s *MasterServer) RegisterWorker(ctx context.Context, info *pb.WorkerInfo) (*pb.RegisterAck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check if worker was manually pre-registered by admin
+	existingWorker, exists := s.workers[info.WorkerId]
+	if !exists && s.joinTokenSecret != nil {
+		// Auto-registration: an unknown worker may join with a valid cluster join token
+		if err := s.autoRegisterWorker(ctx, info); err != nil {
+			logging.Errorf("❌ Rejected worker auto-registration: %s (Address: %s): %v", info.WorkerId, info.WorkerIp, err)
+			return &pb.RegisterAck{
+				Success:   false,
+				Message:   fmt.Sprintf("Worker %s could not auto-register: %v", info.WorkerId, err),
+				ErrorCode: pb.ErrorCode_NOT_AUTHORIZED,
+			}, fmt.Errorf("worker %s auto-registration rejected: %w", info.WorkerId, err)
+		}
+		existingWorker, exists = s.workers[info.WorkerId]
+	}
+	if !exists {
+		// Worker NOT pre-registered - reject the connection
+		logging.Errorf("❌ Rejected unauthorized worker registration attempt: %s (Address: %s)",
+			info.WorkerId, info.WorkerIp)
+		return &pb.RegisterAck{
+			Success:   false,
+			Message:   fmt.Sprintf("Worker %s is not authorized. Admin must register it first using: register %s <ip:port>", info.WorkerId, info.WorkerId),
+			ErrorCode: pb.ErrorCode_NOT_AUTHORIZED,
+		}, fmt.Errorf("worker %s not authorized - must be pre-registered by admin", info.WorkerId)
+	}
+
+	// A worker claiming far more than the capacity declared for it is flagged, or rejected
+	if nack := s.verifyClaimedCapacity(existingWorker, info); nack != nil {
+		return nack, fmt.Errorf("worker %s rejected: %s", info.WorkerId, nack.Message)
+	}
+
+	// Check if this is a new registration (worker connecting for the first time or reconnecting with new specs)
+	isNewConnection := existingWorker.Info.TotalCpu == 0 || !existingWorker.IsActive
+
+	// Ensure RunningTasks map is initialized (defensive programming)
+	if existingWorker.RunningTasks == nil {
+		existingWorker.RunningTasks = make(map[string]bool)
+	}
+
+	// The worker reached us, so any registration-time probe failure is stale
+	existingWorker.Unreachable = false
+
+	// Worker IS pre-registered - update with full specs but preserve the IP from manual registration
+	preservedIP := existingWorker.Info.WorkerIp
+	preservedZone := existingWorker.Info.Zone
+	existingWorker.Info = info
+
+	// A worker that reports no zone keeps the one it was last registered with
+	if existingWorker.Info.Zone == "" {
+		existingWorker.Info.Zone = preservedZone
+	}
+
+	// If worker didn't provide IP or provided empty IP, use the one from manual registration
+	if existingWorker.Info.WorkerIp == "" {
+		existingWorker.Info.WorkerIp = preservedIP
+		logging.Infof("✓ Worker %s registered - using pre-configured address: %s", info.WorkerId, preservedIP)
+	}
+
+	if !existingWorker.IsActive {
+		s.events.publish(ClusterEvent{Type: EventWorkerJoined, WorkerID: info.WorkerId})
+	}
+	existingWorker.IsActive = true
+	existingWorker.LastHeartbeat = time.Now().Unix()
+
+	// If this is a new connection or reconnection, reconcile resources for this worker
+	// to ensure allocated resources match actual running tasks
+	if isNewConnection {
+		logging.Infof("🔄 Worker %s connected with new specs, reconciling resources...", info.WorkerId)
+
+		// Initialize allocated resources to 0 first, reconciliation will fix them
+		existingWorker.AllocatedCPU = 0.0
+		existingWorker.AllocatedMemory = 0.0
+		existingWorker.AllocatedStorage = 0.0
+		existingWorker.AllocatedGPU = 0.0
+
+		// Initialize available resources to usable capacity (total - reserve)
+		s.recomputeAvailable(existingWorker)
+
+		// Trigger reconciliation for this specific worker to fix resources based on actual running tasks
+		s.reconcileSingleWorker(ctx, info.WorkerId, existingWorker)
+	} else {
+		// Worker is already connected with same specs, just update available resources
+		s.recomputeAvailable(existingWorker)
+	}
+
+	// Update in database
+	if s.workerDB != nil {
+		if err := s.workerDB.UpdateWorkerInfo(ctx, existingWorker.Info); err != nil {
+			logging.Warnf("Warning: failed to update worker in db: %v", err)
+		}
+	}
+
+	// Register worker with telemetry manager
+	if s.telemetryManager != nil {
+		s.telemetryManager.RegisterWorker(info.WorkerId)
+	}
+
+	return &pb.RegisterAck{
+		Success: true,
+		Message: "Worker registered successfully",
+	}, nil
+}
+
+// SendHeartbeat processes heartbeat messages from workers
+func (s *MasterServer) SendHeartbeat(ctx context.Context, hb *pb.Heartbeat) (*pb.HeartbeatAck, error) {
+	s.mu.Lock()
 	worker, exists := s.workers[hb.WorkerId]
 	if !exists {
+		s.mu.Unlock()
 		return &pb.HeartbeatAck{Success: false}, fmt.Errorf("worker %s not registered", hb.WorkerId)
 	}
 
+	// Heartbeats arriving faster than the floor are not processed (nor forwarded to telemetry)
+	if s.heartbeatTooSoon(hb.WorkerId, worker, time.Now()) {
+		s.mu.Unlock()
+		return &pb.HeartbeatAck{Success: false}, nil
+	}
+
 	timestamp := time.Now().Unix()
 	worker.LastHeartbeat = timestamp
+	if !worker.IsActive {
+		s.events.publish(ClusterEvent{Type: EventWorkerJoined, WorkerID: hb.WorkerId})
+	}
 	worker.IsActive = true
 
 	// Store latest heartbeat metrics (keep minimal data in main thread)
@@ -719,18 +1538,31 @@ func (s *MasterServer) SendHeartbeat(ctx context.Context, hb *pb.Heartbeat) (*pb
 	worker.LatestGPU = hb.GpuUsage
 	worker.TaskCount = len(hb.RunningTasks)
 
+	// The worker's running-task list is authoritative: tasks it stopped reporting are released below
+	dropped := worker.droppedFromHeartbeat(hb.RunningTasks)
+	ready := worker.readyFromHeartbeat(hb.RunningTasks)
+	s.recordPublishedPorts(worker, hb.RunningTasks)
+
 	// Update heartbeat in database
 	if s.workerDB != nil {
 		if err := s.workerDB.UpdateHeartbeat(ctx, hb.WorkerId, timestamp); err != nil {
-			log.Printf("Warning: failed to update heartbeat in db: %v", err)
+			logging.Warnf("Warning: failed to update heartbeat in db: %v", err)
 		}
 	}
+	s.mu.Unlock()
+
+	if len(dropped) > 0 {
+		s.releaseDroppedTasks(ctx, hb.WorkerId, dropped)
+	}
+	if len(ready) > 0 {
+		s.markTasksReady(ctx, hb.WorkerId, ready)
+	}
 
 	// Offload telemetry processing to dedicated thread
 	// This is non-blocking and won't slow down the RPC handler
 	if s.telemetryManager != nil {
 		if err := s.telemetryManager.ProcessHeartbeat(hb); err != nil {
-			log.Printf("Warning: failed to process telemetry: %v", err)
+			logging.Warnf("Warning: failed to process telemetry: %v", err)
 		}
 	}
 
@@ -742,24 +1574,50 @@ func (s *MasterServer) ReportTaskCompletion(ctx context.Context, result *pb.Task
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	log.Printf("📥 Task completion report received: %s from %s [Status: %s]", result.TaskId, result.WorkerId, result.Status)
+	logging.Infof("📥 Task completion report received: %s from %s [Status: %s]", result.TaskId, result.WorkerId, result.Status)
+	if result.CacheHit {
+		logging.Debugf("  ℹ Result served from worker cache (no container was started)")
+	}
+	if result.FailureReason != "" {
+		logging.Debugf("  ℹ Failure reason: %s", result.FailureReason)
+	}
+	if result.ErrorMessage != "" {
+		logging.Debugf("  ℹ Worker error (exit code %d): %s", result.ExitCode, result.ErrorMessage)
+	}
+
+	// A preempted task was already released and re-queued; the worker's report for it is stale
+	if workerID, ok := s.preempted[result.TaskId]; ok && workerID == result.WorkerId {
+		delete(s.preempted, result.TaskId)
+		logging.Debugf("  ℹ Ignoring report for preempted task %s (already re-queued)", result.TaskId)
+		return &pb.Ack{Success: true, Message: "Task was preempted and re-queued"}, nil
+	}
+
+	// Cancellations say nothing about worker health, so they are not counted
+	if result.Status != "cancelled" {
+		s.outcomes.Record(result.WorkerId, result.Status == "success")
+	}
 
 	// Get task info to retrieve resource requirements
 	var taskResources *db.Task
 	if s.taskDB != nil {
 		task, err := s.taskDB.GetTask(context.Background(), result.TaskId)
 		if err != nil {
-			log.Printf("  ⚠ Warning: Failed to get task info for resource release: %v", err)
+			logging.Warnf("  ⚠ Warning: Failed to get task info for resource release: %v", err)
 		} else {
 			taskResources = task
 		}
 	}
 
+	// Charge what the task consumed to its user's fair share
+	s.recordFairShareUsage(taskResources, time.Now())
+
 	// Remove task from worker's running tasks and release resources
 	if worker, exists := s.workers[result.WorkerId]; exists {
 		if worker.RunningTasks != nil {
 			delete(worker.RunningTasks, result.TaskId)
 		}
+		s.forgetRunningTask(result.TaskId)
+		delete(s.publishedPorts, result.TaskId)
 
 		// 🚨 RELEASE RESOURCES - Update both in-memory and database
 		if taskResources != nil {
@@ -791,9 +1649,9 @@ func (s *MasterServer) ReportTaskCompletion(ctx context.Context, result *pb.Task
 				if err := s.workerDB.ReleaseResources(ctx, result.WorkerId,
 					taskResources.ReqCPU, taskResources.ReqMemory,
 					taskResources.ReqStorage, taskResources.ReqGPU); err != nil {
-					log.Printf("  ⚠ Warning: Failed to release resources in database: %v", err)
+					logging.Warnf("  ⚠ Warning: Failed to release resources in database: %v", err)
 				} else {
-					log.Printf("  ✓ Released resources: CPU=%.2f, Memory=%.2f, Storage=%.2f, GPU=%.2f",
+					logging.Debugf("  ✓ Released resources: CPU=%.2f, Memory=%.2f, Storage=%.2f, GPU=%.2f",
 						taskResources.ReqCPU, taskResources.ReqMemory, taskResources.ReqStorage, taskResources.ReqGPU)
 				}
 			}
@@ -807,31 +1665,38 @@ func (s *MasterServer) ReportTaskCompletion(ctx context.Context, result *pb.Task
 		// Check if task is already cancelled - do not overwrite cancelled status
 		existingTask, err := s.taskDB.GetTask(context.Background(), result.TaskId)
 		if err != nil {
-			log.Printf("  ⚠ Warning: Failed to get task status from database: %v", err)
+			logging.Warnf("  ⚠ Warning: Failed to get task status from database: %v", err)
 		} else if existingTask != nil && existingTask.Status == "cancelled" {
-			log.Printf("  ℹ Task %s is already cancelled - preserving status", result.TaskId)
+			logging.Debugf("  ℹ Task %s is already cancelled - preserving status", result.TaskId)
 			// Check if result already exists - don't store duplicate
 			if s.resultDB != nil {
 				existingResult, err := s.resultDB.GetResult(context.Background(), result.TaskId)
 				if err == nil && existingResult != nil {
-					log.Printf("  ℹ Result already stored for cancelled task - ignoring worker's confirmation report")
+					logging.Debugf("  ℹ Result already stored for cancelled task - ignoring worker's confirmation report")
 					return &pb.Ack{
 						Success: true,
 						Message: "Task result received (status preserved as cancelled, result already stored)",
 					}, nil
 				}
 				// No existing result, store this one (first report with actual logs)
-				log.Printf("  ℹ Storing first result for cancelled task")
+				logging.Debugf("  ℹ Storing first result for cancelled task")
 				taskResult := &db.TaskResult{
-					TaskID:   result.TaskId,
-					WorkerID: result.WorkerId,
-					Status:   "cancelled",
-					Logs:     result.Logs,
+					TaskID:         result.TaskId,
+					WorkerID:       result.WorkerId,
+					Status:         "cancelled",
+					Logs:           result.Logs,
+					CacheHit:       result.CacheHit,
+					FailureReason:  result.FailureReason,
+					ExitCode:       result.ExitCode,
+					ErrorMessage:   result.ErrorMessage,
+					Usage:          db.ResourceUsageFromProto(result.Usage),
+					PublishedPorts: db.PublishedPortsFromProto(result.PublishedPorts),
+					ImageDigest:    result.ImageDigest,
 				}
 				if err := s.resultDB.CreateResult(context.Background(), taskResult); err != nil {
-					log.Printf("  ⚠ Warning: Failed to store task result: %v", err)
+					logging.Warnf("  ⚠ Warning: Failed to store task result: %v", err)
 				} else {
-					log.Printf("  ✓ Task result stored with 'cancelled' status")
+					logging.Debugf("  ✓ Task result stored with 'cancelled' status")
 				}
 			}
 			return &pb.Ack{
@@ -840,59 +1705,120 @@ func (s *MasterServer) ReportTaskCompletion(ctx context.Context, result *pb.Task
 			}, nil
 		}
 
-		status := "completed"
-		if result.Status == "cancelled" {
-			status = "cancelled"
-			log.Printf("  ℹ Confirming task %s 'cancelled' status (already set by master)", result.TaskId)
-		} else if result.Status != "success" {
-			status = "failed"
+		status := terminalStatus(result.Status)
+		switch status {
+		case "cancelled":
+			logging.Debugf("  ℹ Confirming task %s 'cancelled' status (already set by master)", result.TaskId)
+		case "crashloop":
+			logging.Debugf("  🔁 Task %s stopped restarting: container is crash-looping", result.TaskId)
 		}
 
 		// Idempotent update - safe to call even if already cancelled
 		if err := s.taskDB.UpdateTaskStatus(context.Background(), result.TaskId, status); err != nil {
-			log.Printf("  ⚠ Warning: Failed to update task status in database: %v", err)
+			logging.Warnf("  ⚠ Warning: Failed to update task status in database: %v", err)
 			// For cancelled tasks this is not critical since master already updated
 			if result.Status != "cancelled" {
 				return &pb.Ack{
-					Success: false,
-					Message: fmt.Sprintf("Failed to update task status: %v", err),
+					Success:   false,
+					Message:   fmt.Sprintf("Failed to update task status: %v", err),
+					ErrorCode: pb.ErrorCode_DATABASE_ERROR,
 				}, nil
 			}
 		} else {
-			log.Printf("  ✓ Task status confirmed as '%s' in database", status)
+			logging.Debugf("  ✓ Task status confirmed as '%s' in database", status)
 		}
 	}
 
 	// Store result with logs in RESULTS collection
 	if s.resultDB != nil {
 		taskResult := &db.TaskResult{
-			TaskID:   result.TaskId,
-			WorkerID: result.WorkerId,
-			Status:   result.Status,
-			Logs:     result.Logs,
+			TaskID:         result.TaskId,
+			WorkerID:       result.WorkerId,
+			Status:         result.Status,
+			Logs:           result.Logs,
+			CacheHit:       result.CacheHit,
+			FailureReason:  result.FailureReason,
+			ExitCode:       result.ExitCode,
+			ErrorMessage:   result.ErrorMessage,
+			Usage:          db.ResourceUsageFromProto(result.Usage),
+			PublishedPorts: db.PublishedPortsFromProto(result.PublishedPorts),
+			ImageDigest:    result.ImageDigest,
 		}
 		if err := s.resultDB.CreateResult(context.Background(), taskResult); err != nil {
-			log.Printf("  ⚠ Warning: Failed to store task result: %v", err)
+			logging.Warnf("  ⚠ Warning: Failed to store task result: %v", err)
 			// Don't fail here - status update is more critical
 		} else {
-			log.Printf("  ✓ Task result stored in RESULTS collection")
+			logging.Debugf("  ✓ Task result stored in RESULTS collection")
 		}
 	}
 
+	// Cancellations were already notified by CancelTask
+	if status := terminalStatus(result.Status); status != "cancelled" {
+		s.notifyTaskTerminal(ctx, result.TaskId, result.WorkerId, status, taskResources)
+	}
+
 	return &pb.Ack{
 		Success: true,
 		Message: "Task result received and processed",
 	}, nil
 }
 
+// ReleaseTask handles a worker handing back a running task it cannot finish (e.g. on graceful shutdown)
+// The task's reservation is released and it is re-queued fresh instead of being marked failed
+func (s *MasterServer) ReleaseTask(ctx context.Context, req *pb.TaskRelease) (*pb.Ack, error) {
+	logging.Infof("↩ Task release received: %s from %s [Reason: %s]", req.TaskId, req.WorkerId, req.Reason)
+
+	s.mu.RLock()
+	worker, exists := s.workers[req.WorkerId]
+	running := exists && worker.RunningTasks[req.TaskId]
+	s.mu.RUnlock()
+	if !running {
+		return &pb.Ack{
+			Success:   false,
+			Message:   fmt.Sprintf("Task %s is not running on worker %s", req.TaskId, req.WorkerId),
+			ErrorCode: pb.ErrorCode_TASK_NOT_FOUND,
+		}, nil
+	}
+
+	if s.taskDB == nil {
+		return &pb.Ack{Success: false, Message: "Task database not available", ErrorCode: pb.ErrorCode_DATABASE_ERROR}, nil
+	}
+	record, err := s.taskDB.GetTask(ctx, req.TaskId)
+	if err != nil {
+		return &pb.Ack{
+			Success:   false,
+			Message:   fmt.Sprintf("Failed to load task %s: %v", req.TaskId, err),
+			ErrorCode: pb.ErrorCode_TASK_NOT_FOUND,
+		}, nil
+	}
+
+	s.releaseTaskFromWorker(ctx, req.WorkerId, record)
+
+	if err := s.taskDB.UpdateTaskStatus(ctx, req.TaskId, "pending"); err != nil {
+		logging.Warnf("  ⚠ Warning: Failed to reset released task %s to pending: %v", req.TaskId, err)
+	}
+
+	reason := fmt.Sprintf("released by worker %s", req.WorkerId)
+	if req.Reason != "" {
+		reason = fmt.Sprintf("%s (%s)", reason, req.Reason)
+	}
+	s.EnqueueTask(taskFromRecord(record), reason)
+	logging.Debugf("  ✓ Task %s re-queued", req.TaskId)
+
+	return &pb.Ack{
+		Success: true,
+		Message: fmt.Sprintf("Task %s released and re-queued", req.TaskId),
+	}, nil
+}
+
 // UploadTaskFiles handles file uploads from workers via streaming RPC
 func (s *MasterServer) UploadTaskFiles(stream pb.MasterWorker_UploadTaskFilesServer) error {
-	log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	log.Printf("  📤 FILE UPLOAD REQUEST")
-	log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logging.Debugf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logging.Debugf("  📤 FILE UPLOAD REQUEST")
+	logging.Debugf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 	if s.fileStorage == nil {
-		log.Printf("  ✗ File storage service not initialized")
+		logging.Errorf("  ✗ File storage service not initialized")
 		return stream.SendAndClose(&pb.FileUploadAck{
 			Success:       false,
 			Message:       "File storage service not available",
@@ -900,19 +1826,20 @@ func (s *MasterServer) UploadTaskFiles(stream pb.MasterWorker_UploadTaskFilesSer
 		})
 	}
 
-	// Receive file stream and store files
+	// Receive file stream and store files; each file is committed on its own
 	metadata, err := s.fileStorage.ReceiveFileStream(stream)
-	if err != nil {
-		log.Printf("  ✗ Failed to receive files: %v", err)
+	if metadata == nil || (err != nil && len(metadata.FilePaths) == 0) {
+		logging.Errorf("  ✗ Failed to receive files: %v", err)
 		return stream.SendAndClose(&pb.FileUploadAck{
 			Success:       false,
 			Message:       fmt.Sprintf("Failed to receive files: %v", err),
 			FilesReceived: 0,
+			Results:       fileUploadResults(metadata),
 		})
 	}
 
-	// Store metadata in database
-	if s.fileMetadataDB != nil {
+	// Store metadata in database (committed files only)
+	if s.fileMetadataDB != nil && len(metadata.FilePaths) > 0 {
 		dbMetadata := &db.FileMetadata{
 			UserID:      metadata.UserID,
 			TaskID:      metadata.TaskID,
@@ -921,27 +1848,64 @@ func (s *MasterServer) UploadTaskFiles(stream pb.MasterWorker_UploadTaskFilesSer
 			FilePaths:   metadata.FilePaths,
 			StoragePath: metadata.StoragePath,
 		}
+		for _, f := range metadata.Files {
+			dbMetadata.Files = append(dbMetadata.Files, db.FileEntry{Path: f.Path, Size: f.Size, SHA256: f.SHA256})
+		}
 
 		if err := s.fileMetadataDB.CreateFileMetadata(context.Background(), dbMetadata); err != nil {
-			log.Printf("  ⚠ Warning: Failed to store file metadata in database: %v", err)
+			logging.Warnf("  ⚠ Warning: Failed to store file metadata in database: %v", err)
 		} else {
-			log.Printf("  ✓ File metadata stored in database")
+			logging.Debugf("  ✓ File metadata stored in database")
 		}
 	}
 
-	log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	log.Printf("  ✓ FILE UPLOAD COMPLETE")
-	log.Printf("  Task: %s | User: %s | Files: %d", metadata.TaskID, metadata.UserID, len(metadata.FilePaths))
-	log.Printf("  Storage Path: %s", metadata.StoragePath)
-	log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	results := fileUploadResults(metadata)
+	failed := len(results) - len(metadata.FilePaths)
+
+	logging.Infof("📤 Received %d file(s) for task %s (%d failed)", len(metadata.FilePaths), metadata.TaskID, failed)
+	logging.Debugf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if failed > 0 || err != nil {
+		logging.Warnf("  ⚠ FILE UPLOAD PARTIALLY COMPLETE")
+	} else {
+		logging.Debugf("  ✓ FILE UPLOAD COMPLETE")
+	}
+	logging.Debugf("  Task: %s | User: %s | Files: %d stored, %d failed", metadata.TaskID, metadata.UserID, len(metadata.FilePaths), failed)
+	logging.Debugf("  Storage Path: %s", metadata.StoragePath)
+	logging.Debugf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	message := "Files uploaded successfully"
+	if err != nil {
+		message = fmt.Sprintf("Upload interrupted after %d file(s): %v", len(metadata.FilePaths), err)
+	} else if failed > 0 {
+		message = fmt.Sprintf("%d of %d file(s) failed to upload", failed, len(results))
+	}
 
 	return stream.SendAndClose(&pb.FileUploadAck{
-		Success:       true,
-		Message:       "Files uploaded successfully",
+		Success:       failed == 0 && err == nil,
+		Message:       message,
 		FilesReceived: int32(len(metadata.FilePaths)),
+		Results:       results,
+		FilesFailed:   int32(failed),
 	})
 }
 
+// fileUploadResults converts per-file storage outcomes into upload ack results
+func fileUploadResults(metadata *storage.FileMetadata) []*pb.FileUploadResult {
+	if metadata == nil {
+		return nil
+	}
+
+	results := make([]*pb.FileUploadResult, 0, len(metadata.Results))
+	for _, result := range metadata.Results {
+		results = append(results, &pb.FileUploadResult{
+			FilePath: result.Path,
+			Success:  result.Error == "",
+			Error:    result.Error,
+		})
+	}
+	return results
+}
+
 // GetWorkers returns current worker states (for CLI)
 func (s *MasterServer) GetWorkers() map[string]*WorkerState {
 	s.mu.RLock()
@@ -1017,6 +1981,9 @@ type WorkerStateSnapshot struct {
 	AvailableGPU     float64
 	RunningTasks     []string
 	TaskCount        int
+	DisplayName      string
+	Annotations      map[string]string
+	Cordoned         bool // Inside its maintenance window, so no new tasks are placed on it
 }
 
 // ClusterSnapshot represents a point-in-time snapshot of the entire cluster
@@ -1042,67 +2009,76 @@ type ClusterSnapshot struct {
 }
 
 // GetClusterSnapshot returns a structured snapshot of the cluster state
+// Worker state is copied under the read lock; formatting and aggregation happen after it is released,
+// so building a snapshot of a large cluster does not hold up heartbeats and task reports
 func (s *MasterServer) GetClusterSnapshot() *ClusterSnapshot {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	snapshot := &ClusterSnapshot{
 		Timestamp: time.Now(),
-		Workers:   []WorkerStateSnapshot{},
+		Workers:   s.copyWorkerStates(),
 	}
 
-	for workerID, worker := range s.workers {
-		// Calculate heartbeat ago
-		heartbeatAgo := "never"
-		if worker.LastHeartbeat > 0 {
-			duration := time.Since(time.Unix(worker.LastHeartbeat, 0))
-			if duration < 60*time.Second {
-				heartbeatAgo = fmt.Sprintf("%ds ago", int(duration.Seconds()))
-			} else if duration < 60*time.Minute {
-				heartbeatAgo = fmt.Sprintf("%dm ago", int(duration.Minutes()))
-			} else {
-				heartbeatAgo = fmt.Sprintf("%dh ago", int(duration.Hours()))
-			}
+	for i := range snapshot.Workers {
+		worker := &snapshot.Workers[i]
+		worker.HeartbeatAgo = formatHeartbeatAgo(worker.LastHeartbeat, snapshot.Timestamp)
+
+		// Aggregate cluster stats
+		snapshot.TotalWorkers++
+		if worker.Status == "active" {
+			snapshot.ActiveWorkers++
 		}
+		snapshot.TotalTasks += worker.TaskCount
+		snapshot.TotalCPU += worker.TotalCPU
+		snapshot.TotalMemory += worker.TotalMemory
+		snapshot.TotalGPU += worker.TotalGPU
+		snapshot.AllocatedCPU += worker.AllocatedCPU
+		snapshot.AllocatedMemory += worker.AllocatedMemory
+		snapshot.AllocatedGPU += worker.AllocatedGPU
+		snapshot.AvailableCPU += worker.AvailableCPU
+		snapshot.AvailableMemory += worker.AvailableMemory
+		snapshot.AvailableGPU += worker.AvailableGPU
+	}
 
-		// Status
+	snapshot.InactiveWorkers = snapshot.TotalWorkers - snapshot.ActiveWorkers
+
+	// Calculate utilization percentages
+	if snapshot.TotalCPU > 0 {
+		snapshot.CPUUtilization = (snapshot.AllocatedCPU / snapshot.TotalCPU) * 100
+	}
+	if snapshot.TotalMemory > 0 {
+		snapshot.MemoryUtilization = (snapshot.AllocatedMemory / snapshot.TotalMemory) * 100
+	}
+	if snapshot.TotalGPU > 0 {
+		snapshot.GPUUtilization = (snapshot.AllocatedGPU / snapshot.TotalGPU) * 100
+	}
+
+	return snapshot
+}
+
+// copyWorkerStates copies every worker's raw state under the read lock
+// Only plain copies are made here; HeartbeatAgo is left for the caller to fill in
+func (s *MasterServer) copyWorkerStates() []WorkerStateSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workers := make([]WorkerStateSnapshot, 0, len(s.workers))
+	for workerID, worker := range s.workers {
 		status := "active"
 		if !worker.IsActive {
 			status = "inactive"
 		}
 
-		// Extract running tasks
-		runningTasks := []string{}
-		if worker.RunningTasks != nil {
-			for taskID := range worker.RunningTasks {
-				runningTasks = append(runningTasks, taskID)
-			}
-		}
-
-		// Get resource totals
-		var totalCPU, totalMemory, totalStorage, totalGPU float64
-		var workerIP string
-		if worker.Info != nil {
-			totalCPU = worker.Info.TotalCpu
-			totalMemory = worker.Info.TotalMemory
-			totalStorage = worker.Info.TotalStorage
-			totalGPU = worker.Info.TotalGpu
-			workerIP = worker.Info.WorkerIp
+		runningTasks := make([]string, 0, len(worker.RunningTasks))
+		for taskID := range worker.RunningTasks {
+			runningTasks = append(runningTasks, taskID)
 		}
 
 		workerSnapshot := WorkerStateSnapshot{
 			WorkerID:         workerID,
-			WorkerIP:         workerIP,
 			Status:           status,
 			LastHeartbeat:    worker.LastHeartbeat,
-			HeartbeatAgo:     heartbeatAgo,
 			CPUUsage:         worker.LatestCPU,
 			MemoryUsage:      worker.LatestMemory,
 			GPUUsage:         worker.LatestGPU,
-			TotalCPU:         totalCPU,
-			TotalMemory:      totalMemory,
-			TotalStorage:     totalStorage,
-			TotalGPU:         totalGPU,
 			AllocatedCPU:     worker.AllocatedCPU,
 			AllocatedMemory:  worker.AllocatedMemory,
 			AllocatedStorage: worker.AllocatedStorage,
@@ -1113,43 +2089,36 @@ func (s *MasterServer) GetClusterSnapshot() *ClusterSnapshot {
 			AvailableGPU:     worker.AvailableGPU,
 			RunningTasks:     runningTasks,
 			TaskCount:        len(runningTasks),
+			DisplayName:      worker.DisplayName,
+			Annotations:      copyAnnotations(worker.Annotations),
+			Cordoned:         worker.Cordoned,
 		}
-
-		snapshot.Workers = append(snapshot.Workers, workerSnapshot)
-
-		// Aggregate cluster stats
-		snapshot.TotalWorkers++
-		if worker.IsActive {
-			snapshot.ActiveWorkers++
-		}
-		if worker.RunningTasks != nil {
-			snapshot.TotalTasks += len(worker.RunningTasks)
+		if worker.Info != nil {
+			workerSnapshot.WorkerIP = worker.Info.WorkerIp
+			workerSnapshot.TotalCPU = worker.Info.TotalCpu
+			workerSnapshot.TotalMemory = worker.Info.TotalMemory
+			workerSnapshot.TotalStorage = worker.Info.TotalStorage
+			workerSnapshot.TotalGPU = worker.Info.TotalGpu
 		}
-		snapshot.TotalCPU += totalCPU
-		snapshot.TotalMemory += totalMemory
-		snapshot.TotalGPU += totalGPU
-		snapshot.AllocatedCPU += worker.AllocatedCPU
-		snapshot.AllocatedMemory += worker.AllocatedMemory
-		snapshot.AllocatedGPU += worker.AllocatedGPU
-		snapshot.AvailableCPU += worker.AvailableCPU
-		snapshot.AvailableMemory += worker.AvailableMemory
-		snapshot.AvailableGPU += worker.AvailableGPU
-	}
 
-	snapshot.InactiveWorkers = snapshot.TotalWorkers - snapshot.ActiveWorkers
-
-	// Calculate utilization percentages
-	if snapshot.TotalCPU > 0 {
-		snapshot.CPUUtilization = (snapshot.AllocatedCPU / snapshot.TotalCPU) * 100
-	}
-	if snapshot.TotalMemory > 0 {
-		snapshot.MemoryUtilization = (snapshot.AllocatedMemory / snapshot.TotalMemory) * 100
+		workers = append(workers, workerSnapshot)
 	}
-	if snapshot.TotalGPU > 0 {
-		snapshot.GPUUtilization = (snapshot.AllocatedGPU / snapshot.TotalGPU) * 100
+	return workers
+}
+
+// formatHeartbeatAgo renders the time since a heartbeat as "5s ago", "2m ago" or "3h ago" ("never" if none)
+func formatHeartbeatAgo(lastHeartbeat int64, now time.Time) string {
+	if lastHeartbeat <= 0 {
+		return "never"
 	}
 
-	return snapshot
+	duration := now.Sub(time.Unix(lastHeartbeat, 0))
+	if duration < 60*time.Second {
+		return fmt.Sprintf("%ds ago", int(duration.Seconds()))
+	} else if duration < 60*time.Minute {
+		return fmt.Sprintf("%dm ago", int(duration.Minutes()))
+	}
+	return fmt.Sprintf("%dh ago", int(duration.Hours()))
 }
 
 // DumpInMemoryState returns a formatted string of the complete in-memory state
@@ -1218,60 +2187,300 @@ func (s *MasterServer) DumpInMemoryState() string {
 		snapshot.AllocatedCPU, snapshot.TotalCPU, snapshot.CPUUtilization,
 		snapshot.AllocatedMemory, snapshot.TotalMemory, snapshot.MemoryUtilization)
 
-	return output
-}
+	return output
+}
+
+// joinTasks joins task IDs with commas
+func joinTasks(tasks []string) string {
+	result := ""
+	for i, t := range tasks {
+		if i > 0 {
+			result += ","
+		}
+		result += t
+	}
+	return result
+}
+
+// maxTaskIDAttempts bounds how many fresh IDs are tried when a task ID is already taken
+const maxTaskIDAttempts = 5
+
+// NewTaskID returns a collision-resistant task ID: task-<unix-nanos>-<random hex>
+func NewTaskID() string {
+	var suffix [4]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return fmt.Sprintf("task-%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("task-%d-%s", time.Now().UnixNano(), hex.EncodeToString(suffix[:]))
+}
+
+// ensureUniqueTaskID assigns an ID to tasks that have none and replaces one already stored in the database
+func (s *MasterServer) ensureUniqueTaskID(ctx context.Context, task *pb.Task) error {
+	if task.TaskId == "" {
+		task.TaskId = NewTaskID()
+	}
+	if s.taskDB == nil {
+		return nil
+	}
+
+	for attempt := 0; attempt < maxTaskIDAttempts; attempt++ {
+		exists, err := s.taskDB.TaskExists(ctx, task.TaskId)
+		if err != nil {
+			logging.Warnf("Warning: could not check task ID %s for collisions: %v", task.TaskId, err)
+			return nil
+		}
+		if !exists {
+			return nil
+		}
+		newID := NewTaskID()
+		logging.Warnf("⚠️  Task ID %s already exists, reassigning as %s", task.TaskId, newID)
+		task.TaskId = newID
+	}
+	return fmt.Errorf("could not allocate a unique task ID after %d attempts", maxTaskIDAttempts)
+}
+
+// SubmitTask submits a task to the system for scheduling
+// ALL tasks go through the queue first, then the scheduler assigns them to workers
+// Tasks submitted with Hold are stored as "held" and wait for ReleaseHeldTask instead
+func (s *MasterServer) SubmitTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	if err := s.ensureUniqueTaskID(ctx, task); err != nil {
+		return nil, err
+	}
+
+	if err := s.ValidateTaskNetwork(task.NetworkMode, task.PortBindings); err != nil {
+		logging.Infof("🚫 Task %s rejected: %v", task.TaskId, err)
+		return &pb.TaskAck{Success: false, Message: err.Error(), ErrorCode: pb.ErrorCode_INVALID_TASK_SPEC}, nil
+	}
+	if task.StopGracePeriodSec < 0 || task.StopGracePeriodSec > MaxStopGracePeriodSec {
+		logging.Infof("🚫 Task %s rejected: stop grace period %ds out of range", task.TaskId, task.StopGracePeriodSec)
+		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("stop_grace_period_sec must be between 0 and %d", MaxStopGracePeriodSec), ErrorCode: pb.ErrorCode_INVALID_TASK_SPEC}, nil
+	}
+
+	status := "queued"
+	if task.Hold {
+		status = "held"
+	}
+
+	// Shed load rather than letting the queue grow without bound (held tasks do not join the queue)
+	if full, limit := s.queueFull(); full && !task.Hold {
+		logging.Infof("🚫 Task %s rejected: queue is full (%d tasks)", task.TaskId, limit)
+		return &pb.TaskAck{
+			Success:   false,
+			Message:   fmt.Sprintf("Cluster at capacity: %d tasks already queued, retry later", limit),
+			ErrorCode: pb.ErrorCode_CLUSTER_AT_CAPACITY,
+		}, nil
+	}
+
+	// Check the task fits its capacity reservation, if it names one; it is charged when placed
+	if task.ReservationId != "" {
+		if nack := s.checkCapacityReservation(task, time.Now()); nack != nil {
+			return nack, nil
+		}
+	}
+
+	// Store task in database as queued (or held)
+	if s.taskDB != nil {
+		dbTask := taskRecord(task, status)
+		if err := s.taskDB.CreateTask(ctx, dbTask); err != nil {
+			logging.Warnf("Warning: Failed to store task in database: %v", err)
+		}
+	}
+
+	if task.Hold {
+		s.queueMu.Lock()
+		s.heldTasks[task.TaskId] = task
+		s.queueMu.Unlock()
+
+		logging.Infof("⏸️  Task %s submitted on hold", task.TaskId)
+		return &pb.TaskAck{
+			Success: true,
+			Message: fmt.Sprintf("Task submitted on hold. Run 'release %s' to queue it for scheduling.", task.TaskId),
+		}, nil
+	}
+
+	// Enqueue the task for scheduling
+	s.EnqueueTask(task, "Task submitted to queue for scheduling")
+
+	// Get queue position
+	s.queueMu.RLock()
+	position := len(s.taskQueue)
+	s.queueMu.RUnlock()
+
+	logging.Infof("📋 Task %s submitted and queued (position: %d)", task.TaskId, position)
+
+	return &pb.TaskAck{
+		Success: true,
+		Message: fmt.Sprintf("Task submitted successfully. Queue position: %d. Scheduler will assign it to an available worker.", position),
+	}, nil
+}
+
+// ReleaseHeldTask moves a held task into the queue so the scheduler can assign it
+// The database status moves from held to queued only once, so releasing a task twice never queues it twice
+func (s *MasterServer) ReleaseHeldTask(ctx context.Context, taskID *pb.TaskID) (*pb.TaskAck, error) {
+	notHeld := &pb.TaskAck{
+		Success:   false,
+		Message:   fmt.Sprintf("Task %s is not on hold", taskID.TaskId),
+		ErrorCode: pb.ErrorCode_TASK_NOT_FOUND,
+	}
+
+	s.queueMu.Lock()
+	task, held := s.heldTasks[taskID.TaskId]
+	delete(s.heldTasks, taskID.TaskId)
+	s.queueMu.Unlock()
+
+	if s.taskDB != nil {
+		// A held task missing from memory (e.g. one that failed to reload) is rebuilt from the database
+		if !held {
+			record, err := s.taskDB.GetTask(ctx, taskID.TaskId)
+			if err != nil || record.Status != "held" {
+				return notHeld, nil
+			}
+			task = taskFromRecord(record)
+		}
+
+		swapped, err := s.taskDB.SwapTaskStatus(ctx, taskID.TaskId, "held", "queued")
+		if err != nil {
+			if held {
+				s.queueMu.Lock()
+				s.heldTasks[taskID.TaskId] = task
+				s.queueMu.Unlock()
+			}
+			logging.Warnf("Warning: Failed to mark released task %s as queued: %v", taskID.TaskId, err)
+			return &pb.TaskAck{
+				Success:   false,
+				Message:   fmt.Sprintf("Failed to release task %s: %v", taskID.TaskId, err),
+				ErrorCode: pb.ErrorCode_DATABASE_ERROR,
+			}, nil
+		}
+		if !swapped {
+			return notHeld, nil
+		}
+	} else if !held {
+		return notHeld, nil
+	}
+
+	task.Hold = false
+	s.EnqueueTask(task, "Task released from hold")
+
+	return &pb.TaskAck{
+		Success: true,
+		Message: fmt.Sprintf("Task %s released and queued for scheduling", taskID.TaskId),
+	}, nil
+}
+
+// LoadHeldTasksFromDB restores the tasks left on hold by a previous master, so they can be listed and released
+func (s *MasterServer) LoadHeldTasksFromDB(ctx context.Context) error {
+	if s.taskDB == nil {
+		return nil // DB not configured, skip
+	}
+
+	records, err := s.taskDB.GetTasksByStatus(ctx, "held")
+	if err != nil {
+		return fmt.Errorf("load held tasks from db: %w", err)
+	}
+
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	for _, record := range records {
+		task := taskFromRecord(record)
+		task.Hold = true
+		s.heldTasks[task.TaskId] = task
+	}
+	if len(records) > 0 {
+		logging.Infof("⏸️  Restored %d held task(s) from database", len(records))
+	}
+	return nil
+}
+
+// GetHeldTasks returns the tasks currently on hold
+func (s *MasterServer) GetHeldTasks() []*pb.Task {
+	s.queueMu.RLock()
+	defer s.queueMu.RUnlock()
+
+	held := make([]*pb.Task, 0, len(s.heldTasks))
+	for _, task := range s.heldTasks {
+		held = append(held, task)
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].TaskId < held[j].TaskId })
+	return held
+}
+
+// RequeueTask submits a copy of a finished task (same image, command, resources and user) under a new ID
+// The copy records the task it came from in OriginalTaskId; tasks still pending or running cannot be requeued
+// Returns the new task (nil when nothing was submitted) and the submission ack
+func (s *MasterServer) RequeueTask(ctx context.Context, taskID string) (*pb.Task, *pb.TaskAck, error) {
+	task, failure := s.copyFinishedTask(ctx, taskID, "requeued")
+	if failure != nil {
+		return nil, failure, nil
+	}
+
+	ack, err := s.SubmitTask(ctx, task)
+	if err != nil || !ack.Success {
+		return nil, ack, err
+	}
+	logging.Infof("🔁 Task %s requeued as %s", taskID, task.TaskId)
+	return task, ack, nil
+}
+
+// RetryTaskOnWorker runs a copy of a finished (typically failed) task under a new ID on the chosen worker,
+// bypassing the scheduler, e.g. to move it off a worker suspected to be faulty
+// The copy records the task it came from in OriginalTaskId; returns the new task (nil when nothing was dispatched)
+func (s *MasterServer) RetryTaskOnWorker(ctx context.Context, taskID, workerID string) (*pb.Task, *pb.TaskAck, error) {
+	s.mu.RLock()
+	_, exists := s.workers[workerID]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, &pb.TaskAck{
+			Success:   false,
+			Message:   fmt.Sprintf("Worker %s not found", workerID),
+			ErrorCode: pb.ErrorCode_WORKER_NOT_FOUND,
+		}, nil
+	}
+
+	task, failure := s.copyFinishedTask(ctx, taskID, "retried")
+	if failure != nil {
+		return nil, failure, nil
+	}
+	task.TargetWorkerId = workerID
 
-// joinTasks joins task IDs with commas
-func joinTasks(tasks []string) string {
-	result := ""
-	for i, t := range tasks {
-		if i > 0 {
-			result += ","
-		}
-		result += t
+	ack, err := s.DispatchTaskToWorker(ctx, task, workerID)
+	if err != nil || !ack.Success {
+		return nil, ack, err
 	}
-	return result
+	logging.Infof("🔁 Task %s retried as %s on worker %s", taskID, task.TaskId, workerID)
+	return task, ack, nil
 }
 
-// SubmitTask submits a task to the system for scheduling
-// ALL tasks go through the queue first, then the scheduler assigns them to workers
-func (s *MasterServer) SubmitTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
-	// Store task in database as queued
-	if s.taskDB != nil {
-		dbTask := &db.Task{
-			TaskID:        task.TaskId,
-			UserID:        task.UserId,
-			TaskName:      task.TaskName,
-			SubmittedAt:   task.SubmittedAt,
-			DockerImage:   task.DockerImage,
-			Command:       task.Command,
-			ReqCPU:        task.ReqCpu,
-			ReqMemory:     task.ReqMemory,
-			ReqStorage:    task.ReqStorage,
-			ReqGPU:        task.ReqGpu,
-			TaskType:      task.TaskType,      // NEW: Save task type for training
-			SLAMultiplier: task.SlaMultiplier, // NEW: Save SLA multiplier
-			Status:        "queued",
-		}
-		if err := s.taskDB.CreateTask(ctx, dbTask); err != nil {
-			log.Printf("Warning: Failed to store task in database: %v", err)
-		}
+// copyFinishedTask loads a finished task and returns a copy with a new ID linked to it through OriginalTaskId
+// A failure ack is returned instead when the task is unknown or not finished; action names the operation in it
+func (s *MasterServer) copyFinishedTask(ctx context.Context, taskID, action string) (*pb.Task, *pb.TaskAck) {
+	if s.taskDB == nil {
+		return nil, &pb.TaskAck{Success: false, Message: "Task database not available", ErrorCode: pb.ErrorCode_DATABASE_ERROR}
 	}
 
-	// Enqueue the task for scheduling
-	s.EnqueueTask(task, "Task submitted to queue for scheduling")
-
-	// Get queue position
-	s.queueMu.RLock()
-	position := len(s.taskQueue)
-	s.queueMu.RUnlock()
+	record, err := s.taskDB.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, &pb.TaskAck{
+			Success:   false,
+			Message:   fmt.Sprintf("Task %s not found: %v", taskID, err),
+			ErrorCode: pb.ErrorCode_TASK_NOT_FOUND,
+		}
+	}
 
-	log.Printf("📋 Task %s submitted and queued (position: %d)", task.TaskId, position)
+	switch record.Status {
+	case "completed", "failed", "cancelled", "crashloop", "expired":
+	default:
+		return nil, &pb.TaskAck{
+			Success: false,
+			Message: fmt.Sprintf("Task %s is %s; only finished tasks can be %s", taskID, record.Status, action),
+		}
+	}
 
-	return &pb.TaskAck{
-		Success: true,
-		Message: fmt.Sprintf("Task submitted successfully. Queue position: %d. Scheduler will assign it to an available worker.", position),
-	}, nil
+	task := taskFromRecord(record)
+	task.TaskId = NewTaskID()
+	task.SubmittedAt = time.Now().Unix()
+	task.OriginalTaskId = record.TaskID
+	return task, nil
 }
 
 // AssignTask is kept for backward compatibility but now redirects to SubmitTask
@@ -1280,47 +2489,59 @@ func (s *MasterServer) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskA
 	return s.SubmitTask(ctx, task)
 }
 
+// SubmitTaskAuto queues a task for the scheduler to place on the best worker
+// Any TargetWorkerId the client set is ignored (use DispatchTaskToWorker to bypass the scheduler);
+// the ack message names the task, whose ID is generated when the client leaves it empty
+func (s *MasterServer) SubmitTaskAuto(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	if task.TargetWorkerId != "" {
+		logging.Infof("📋 Ignoring target worker %s for task %s: the scheduler places it", task.TargetWorkerId, task.TaskId)
+		task.TargetWorkerId = ""
+	}
+	if task.SubmittedAt == 0 {
+		task.SubmittedAt = time.Now().Unix()
+	}
+
+	ack, err := s.SubmitTask(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	ack.Message = fmt.Sprintf("Task %s: %s", task.TaskId, ack.Message)
+	return ack, nil
+}
+
 // DispatchTaskToWorker directly dispatches a task to a specific worker, bypassing the scheduler
 // This is useful for testing and debugging purposes
 func (s *MasterServer) DispatchTaskToWorker(ctx context.Context, task *pb.Task, workerID string) (*pb.TaskAck, error) {
-	log.Printf("🎯 Direct dispatch request: Task %s -> Worker %s", task.TaskId, workerID)
+	if err := s.ensureUniqueTaskID(ctx, task); err != nil {
+		return nil, err
+	}
+	logging.Infof("🎯 Direct dispatch request: Task %s -> Worker %s", task.TaskId, workerID)
 
 	// Store task in database as queued first
 	if s.taskDB != nil {
-		dbTask := &db.Task{
-			TaskID:        task.TaskId,
-			UserID:        task.UserId,
-			TaskName:      task.TaskName,
-			SubmittedAt:   task.SubmittedAt,
-			DockerImage:   task.DockerImage,
-			Command:       task.Command,
-			ReqCPU:        task.ReqCpu,
-			ReqMemory:     task.ReqMemory,
-			ReqStorage:    task.ReqStorage,
-			ReqGPU:        task.ReqGpu,
-			TaskType:      task.TaskType,      // NEW: Save task type for training
-			SLAMultiplier: task.SlaMultiplier, // NEW: Save SLA multiplier
-			Status:        "queued",
-		}
+		dbTask := taskRecord(task, "queued")
 		if err := s.taskDB.CreateTask(ctx, dbTask); err != nil {
-			log.Printf("Warning: Failed to store task in database: %v", err)
+			logging.Warnf("Warning: Failed to store task in database: %v", err)
 		}
 	}
 
 	// Directly assign to the specified worker (bypassing queue and scheduler)
 	ack, err := s.assignTaskToWorker(ctx, task, workerID)
 	if err != nil {
+		s.discardUndispatchedTask(ctx, task.TaskId)
 		return &pb.TaskAck{
-			Success: false,
-			Message: fmt.Sprintf("Failed to dispatch task to worker %s: %v", workerID, err),
+			Success:   false,
+			Message:   fmt.Sprintf("Failed to dispatch task to worker %s: %v", workerID, err),
+			ErrorCode: pb.ErrorCode_WORKER_UNREACHABLE,
 		}, nil
 	}
 
 	if !ack.Success {
+		s.discardUndispatchedTask(ctx, task.TaskId)
 		return ack, nil
 	}
 
-	log.Printf("✅ Task %s dispatched directly to worker %s", task.TaskId, workerID)
+	logging.Infof("✅ Task %s dispatched directly to worker %s", task.TaskId, workerID)
 
 	return &pb.TaskAck{
 		Success: true,
@@ -1328,6 +2549,16 @@ func (s *MasterServer) DispatchTaskToWorker(ctx context.Context, task *pb.Task,
 	}, nil
 }
 
+// discardUndispatchedTask deletes the record of a directly dispatched task no worker took, so it is not left queued forever
+func (s *MasterServer) discardUndispatchedTask(ctx context.Context, taskID string) {
+	if s.taskDB == nil {
+		return
+	}
+	if err := s.taskDB.DeleteTask(ctx, taskID); err != nil {
+		logging.Warnf("Warning: failed to delete undispatched task %s: %v", taskID, err)
+	}
+}
+
 // StreamTaskLogs handles gRPC streaming of task logs (called by master CLI)
 func (s *MasterServer) StreamTaskLogs(req *pb.TaskLogRequest, stream pb.MasterWorker_StreamTaskLogsServer) error {
 	// This is a stub - the master doesn't receive this call from workers
@@ -1336,7 +2567,8 @@ func (s *MasterServer) StreamTaskLogs(req *pb.TaskLogRequest, stream pb.MasterWo
 }
 
 // StreamTaskLogsFromWorker streams logs for a task from the worker (helper method for CLI)
-func (s *MasterServer) StreamTaskLogsFromWorker(ctx context.Context, taskID, userID string, logHandler func(string, bool)) error {
+// logHandler gets each line with its stream (stdout or stderr; empty for stored logs) and whether it is the last
+func (s *MasterServer) StreamTaskLogsFromWorker(ctx context.Context, taskID, userID string, logHandler func(line, stream string, isComplete bool)) error {
 	s.mu.RLock()
 
 	// First, check if task is completed and logs are in database
@@ -1352,7 +2584,7 @@ func (s *MasterServer) StreamTaskLogsFromWorker(ctx context.Context, taskID, use
 				// Send each line with a small delay to simulate streaming
 				time.Sleep(10 * time.Millisecond)
 				isLastLine := i == len(lines)-1
-				logHandler(line, isLastLine)
+				logHandler(line, "", isLastLine)
 			}
 			return nil
 		}
@@ -1384,7 +2616,7 @@ func (s *MasterServer) StreamTaskLogsFromWorker(ctx context.Context, taskID, use
 	s.mu.RUnlock()
 
 	// Connect to worker
-	conn, err := grpc.Dial(workerIP, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(workerIP, s.streamDialOptions()...)
 	if err != nil {
 		return fmt.Errorf("failed to connect to worker: %w", err)
 	}
@@ -1413,7 +2645,7 @@ func (s *MasterServer) StreamTaskLogsFromWorker(ctx context.Context, taskID, use
 		}
 
 		// Pass log content to handler
-		logHandler(chunk.Content, chunk.IsComplete)
+		logHandler(chunk.Content, chunk.Stream, chunk.IsComplete)
 
 		if chunk.IsComplete {
 			// Update task status in database if completed
@@ -1471,6 +2703,31 @@ func (s *MasterServer) GetAssignmentByTaskID(ctx context.Context, taskID string)
 	return assignment, nil
 }
 
+// GetTaskUsage returns a task together with its stored result, for comparing requested and used resources
+func (s *MasterServer) GetTaskUsage(ctx context.Context, taskID string) (*db.Task, *db.TaskResult, error) {
+	if s.taskDB == nil || s.resultDB == nil {
+		return nil, nil, fmt.Errorf("task database not available")
+	}
+
+	task, err := s.taskDB.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if task == nil {
+		return nil, nil, fmt.Errorf("task not found")
+	}
+
+	result, err := s.resultDB.GetResult(ctx, taskID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get result: %w", err)
+	}
+	if result == nil {
+		return nil, nil, fmt.Errorf("task has no result yet")
+	}
+
+	return task, result, nil
+}
+
 // BroadcastMasterRegistration calls MasterRegister on all pre-registered workers
 // so the master can announce its address and allow workers to connect back.
 func (s *MasterServer) BroadcastMasterRegistration(masterID, masterAddress string) {
@@ -1487,9 +2744,9 @@ func (s *MasterServer) BroadcastMasterRegistration(masterID, masterAddress strin
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
-			conn, err := grpc.DialContext(ctx, workerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+			conn, err := grpc.DialContext(ctx, workerAddr, s.dialCredentials(), grpc.WithBlock())
 			if err != nil {
-				log.Printf("Failed to connect to worker %s (%s) for MasterRegister: %v", workerID, workerAddr, err)
+				logging.Errorf("Failed to connect to worker %s (%s) for MasterRegister: %v", workerID, workerAddr, err)
 				return
 			}
 			defer conn.Close()
@@ -1498,32 +2755,51 @@ func (s *MasterServer) BroadcastMasterRegistration(masterID, masterAddress strin
 			mi := &pb.MasterInfo{MasterId: masterID, MasterAddress: masterAddress}
 			ack, err := client.MasterRegister(ctx, mi)
 			if err != nil {
-				log.Printf("MasterRegister RPC to worker %s (%s) failed: %v", workerID, workerAddr, err)
+				logging.Warnf("MasterRegister RPC to worker %s (%s) failed: %v", workerID, workerAddr, err)
 				return
 			}
 			if ack != nil && ack.Success {
-				log.Printf("MasterRegister acknowledged by worker %s: %s", workerID, ack.Message)
+				logging.Infof("MasterRegister acknowledged by worker %s: %s", workerID, ack.Message)
 			} else if ack != nil {
-				log.Printf("MasterRegister rejected by worker %s: %s", workerID, ack.Message)
+				logging.Warnf("MasterRegister rejected by worker %s: %s", workerID, ack.Message)
 			}
 		}(id, workerAddr)
 	}
 }
 
-func (s *MasterServer) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.TaskAck, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// MaxStopGracePeriodSec is the longest SIGTERM-to-SIGKILL grace a task may ask for
+const MaxStopGracePeriodSec = 300
+
+// defaultStopGracePeriod is the worker's grace for tasks that do not set one
+const defaultStopGracePeriod = 10 * time.Second
 
-	log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	log.Printf("  🛑 CANCELLING TASK")
-	log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	log.Printf("  Task ID: %s", taskID.TaskId)
+// cancelMargin is the time a cancel RPC gets on top of the task's stop grace
+const cancelMargin = 20 * time.Second
+
+// cancelTimeout bounds a CancelTask RPC for a task: its stop grace plus cancelMargin (spec may be nil)
+func cancelTimeout(spec *pb.Task) time.Duration {
+	grace := defaultStopGracePeriod
+	if spec != nil && spec.StopGracePeriodSec > 0 {
+		grace = time.Duration(spec.StopGracePeriodSec) * time.Second
+	}
+	return grace + cancelMargin
+}
+
+// CancelTask stops a running task on its worker and marks it cancelled
+// s.mu is held only to find the task's worker and to forget the task afterwards: the worker's cancel
+// waits out the task's stop grace, and heartbeats, scheduling and other cancels must go on meanwhile
+func (s *MasterServer) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.TaskAck, error) {
+	logging.Debugf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logging.Debugf("  🛑 CANCELLING TASK")
+	logging.Debugf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logging.Debugf("  Task ID: %s", taskID.TaskId)
 
 	// Find which worker has this task
 	var targetWorkerID string
 	var targetWorker *WorkerState
 
 	// First check in-memory running tasks
+	s.mu.RLock()
 	for workerID, worker := range s.workers {
 		if worker.RunningTasks != nil && worker.RunningTasks[taskID.TaskId] {
 			targetWorkerID = workerID
@@ -1531,64 +2807,77 @@ func (s *MasterServer) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.T
 			break
 		}
 	}
+	s.mu.RUnlock()
 
 	// If not found in memory, check database
 	if targetWorkerID == "" && s.assignmentDB != nil {
 		workerID, err := s.assignmentDB.GetWorkerForTask(ctx, taskID.TaskId)
 		if err != nil {
-			log.Printf("  ✗ Task %s not found on any worker", taskID.TaskId)
+			logging.Warnf("  ✗ Task %s not found on any worker", taskID.TaskId)
 			return &pb.TaskAck{
-				Success: false,
-				Message: fmt.Sprintf("Task not found or not assigned to any worker: %v", err),
+				Success:   false,
+				Message:   fmt.Sprintf("Task not found or not assigned to any worker: %v", err),
+				ErrorCode: pb.ErrorCode_TASK_NOT_FOUND,
 			}, nil
 		}
 		targetWorkerID = workerID
+		s.mu.RLock()
 		targetWorker = s.workers[workerID]
+		s.mu.RUnlock()
 		if targetWorker == nil {
-			log.Printf("  ✗ Worker %s not found", workerID)
+			logging.Warnf("  ✗ Worker %s not found", workerID)
 			return &pb.TaskAck{
-				Success: false,
-				Message: fmt.Sprintf("Worker %s not found", workerID),
+				Success:   false,
+				Message:   fmt.Sprintf("Worker %s not found", workerID),
+				ErrorCode: pb.ErrorCode_WORKER_NOT_FOUND,
 			}, nil
 		}
 	}
 
 	if targetWorkerID == "" {
-		log.Printf("  ✗ Task not found")
+		logging.Warnf("  ✗ Task not found")
 		return &pb.TaskAck{
-			Success: false,
-			Message: "Task not found or not running",
+			Success:   false,
+			Message:   "Task not found or not running",
+			ErrorCode: pb.ErrorCode_TASK_NOT_FOUND,
 		}, nil
 	}
 
-	log.Printf("  Target Worker: %s (%s)", targetWorkerID, targetWorker.Info.WorkerIp)
-	log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	s.mu.RLock()
+	workerIP := targetWorker.Info.WorkerIp
+	timeout := cancelTimeout(s.runningSpecs[taskID.TaskId])
+	s.mu.RUnlock()
+
+	logging.Debugf("  Target Worker: %s (%s)", targetWorkerID, workerIP)
+	logging.Debugf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 	// Update task status in database FIRST (optimistic update)
 	// This ensures database is always updated even if worker communication fails
 	if s.taskDB != nil {
 		if err := s.taskDB.UpdateTaskStatus(ctx, taskID.TaskId, "cancelled"); err != nil {
-			log.Printf("  ✗ CRITICAL: Failed to update task status in database: %v", err)
+			logging.Errorf("  ✗ CRITICAL: Failed to update task status in database: %v", err)
 			return &pb.TaskAck{
-				Success: false,
-				Message: fmt.Sprintf("Failed to update database: %v", err),
+				Success:   false,
+				Message:   fmt.Sprintf("Failed to update database: %v", err),
+				ErrorCode: pb.ErrorCode_DATABASE_ERROR,
 			}, nil
 		} else {
-			log.Printf("  ✓ Task status updated to 'cancelled' in database")
+			logging.Debugf("  ✓ Task status updated to 'cancelled' in database")
 		}
 	} else {
-		log.Printf("  ⚠ Warning: No database configured, task status not persisted")
+		logging.Warnf("  ⚠ Warning: No database configured, task status not persisted")
 	}
+	s.notifyTaskTerminal(ctx, taskID.TaskId, targetWorkerID, "cancelled", nil)
 
 	// Connect to worker and send cancel request with extended timeout
-	// Use a longer timeout for cancellation as it may involve stopping containers
-	cancelCtx, cancelFunc := context.WithTimeout(context.Background(), 30*time.Second)
+	// The worker may wait the task's whole stop grace before the container is killed
+	cancelCtx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 	defer cancelFunc()
 
-	conn, err := grpc.Dial(targetWorker.Info.WorkerIp, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(workerIP, s.dialCredentials())
 	if err != nil {
-		log.Printf("  ✗ Failed to connect to worker: %v", err)
-		log.Printf("  ⚠ Database updated but worker not reachable")
+		logging.Errorf("  ✗ Failed to connect to worker: %v", err)
+		logging.Warnf("  ⚠ Database updated but worker not reachable")
 		// This is not a critical failure - DB is updated, worker will see it
 		return &pb.TaskAck{
 			Success: true,
@@ -1600,8 +2889,8 @@ func (s *MasterServer) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.T
 	client := pb.NewMasterWorkerClient(conn)
 	ack, err := client.CancelTask(cancelCtx, taskID)
 	if err != nil {
-		log.Printf("  ✗ Failed to cancel task on worker: %v", err)
-		log.Printf("  ⚠ Database updated but worker communication failed")
+		logging.Errorf("  ✗ Failed to cancel task on worker: %v", err)
+		logging.Warnf("  ⚠ Database updated but worker communication failed")
 		// This is not a critical failure - DB is updated correctly
 		return &pb.TaskAck{
 			Success: true,
@@ -1610,19 +2899,24 @@ func (s *MasterServer) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.T
 	}
 
 	if !ack.Success {
-		log.Printf("  ✗ Worker rejected cancellation: %s", ack.Message)
-		log.Printf("  ⚠ Database marked as cancelled but worker could not stop task")
+		logging.Errorf("  ✗ Worker rejected cancellation: %s", ack.Message)
+		logging.Warnf("  ⚠ Database marked as cancelled but worker could not stop task")
 		return ack, nil
 	}
 
 	// Remove task from worker's running tasks
+	s.mu.Lock()
 	if targetWorker.RunningTasks != nil {
 		delete(targetWorker.RunningTasks, taskID.TaskId)
 	}
+	s.forgetRunningTask(taskID.TaskId)
+	delete(s.publishedPorts, taskID.TaskId)
+	s.mu.Unlock()
 
-	log.Printf("  ✓ Task cancelled successfully on worker")
-	log.Printf("  ✓ Container stopped and database updated")
-	log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logging.Infof("🛑 Task %s cancelled on worker %s", taskID.TaskId, targetWorkerID)
+	logging.Debugf("  ✓ Task cancelled successfully on worker")
+	logging.Debugf("  ✓ Container stopped and database updated")
+	logging.Debugf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 	return &pb.TaskAck{
 		Success: true,
@@ -1630,18 +2924,107 @@ func (s *MasterServer) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.T
 	}, nil
 }
 
+// CancelByExternalRef cancels a task identified by the client's own job ID instead of the master's task ID
+func (s *MasterServer) CancelByExternalRef(ctx context.Context, ref *pb.ExternalRef) (*pb.TaskAck, error) {
+	if ref.Ref == "" {
+		return &pb.TaskAck{Success: false, Message: "External reference required"}, nil
+	}
+	if s.taskDB == nil {
+		return &pb.TaskAck{Success: false, Message: "Task database not available", ErrorCode: pb.ErrorCode_DATABASE_ERROR}, nil
+	}
+
+	record, err := s.taskDB.GetTaskByExternalRef(ctx, ref.Ref)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &pb.TaskAck{
+			Success:   false,
+			Message:   fmt.Sprintf("Task with external ref %s not found", ref.Ref),
+			ErrorCode: pb.ErrorCode_TASK_NOT_FOUND,
+		}, nil
+	}
+	if err != nil {
+		return &pb.TaskAck{
+			Success:   false,
+			Message:   fmt.Sprintf("Failed to look up external ref %s: %v", ref.Ref, err),
+			ErrorCode: pb.ErrorCode_DATABASE_ERROR,
+		}, nil
+	}
+
+	logging.Infof("🔎 External ref %s resolved to task %s", ref.Ref, record.TaskID)
+	return s.CancelTask(ctx, &pb.TaskID{TaskId: record.TaskID})
+}
+
+// PrewarmWorkerImages asks a worker to pull images ahead of time so tasks skip the pull on startup
+func (s *MasterServer) PrewarmWorkerImages(ctx context.Context, workerID string, images []string) (*pb.PrewarmAck, error) {
+	s.mu.RLock()
+	worker, exists := s.workers[workerID]
+	var workerIP string
+	if exists {
+		workerIP = worker.Info.WorkerIp
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worker %s not found", workerID)
+	}
+
+	conn, err := grpc.Dial(workerIP, s.dialCredentials())
+	if err != nil {
+		return nil, fmt.Errorf("connect to worker %s: %w", workerID, err)
+	}
+	defer conn.Close()
+
+	client := pb.NewMasterWorkerClient(conn)
+	ack, err := client.PrewarmImages(ctx, &pb.PrewarmRequest{Images: images})
+	if err != nil {
+		return nil, fmt.Errorf("prewarm images on worker %s: %w", workerID, err)
+	}
+
+	logging.Infof("🔥 Prewarm on %s: %s", workerID, ack.Message)
+	return ack, nil
+}
+
+// ValidateWorkerImage asks a worker to check that an image exists and can be pulled, without running it
+func (s *MasterServer) ValidateWorkerImage(ctx context.Context, workerID string, image string) (*pb.ValidateImageAck, error) {
+	s.mu.RLock()
+	worker, exists := s.workers[workerID]
+	var workerIP string
+	if exists {
+		workerIP = worker.Info.WorkerIp
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worker %s not found", workerID)
+	}
+
+	conn, err := grpc.Dial(workerIP, s.dialCredentials())
+	if err != nil {
+		return nil, fmt.Errorf("connect to worker %s: %w", workerID, err)
+	}
+	defer conn.Close()
+
+	client := pb.NewMasterWorkerClient(conn)
+	ack, err := client.ValidateImage(ctx, &pb.ValidateImageRequest{Image: image})
+	if err != nil {
+		return nil, fmt.Errorf("validate image on worker %s: %w", workerID, err)
+	}
+
+	logging.Infof("🔍 Image %s on %s: %s", image, workerID, ack.Message)
+	return ack, nil
+}
+
 // StartQueueProcessor starts the background task queue processor
 func (s *MasterServer) StartQueueProcessor() {
 	s.queueTicker = time.NewTicker(5 * time.Second) // Check queue every 5 seconds
 	go s.processQueue()
-	log.Printf("✓ Task queue processor started (checking every 5s)")
+	logging.Infof("✓ Task queue processor started (checking every 5s)")
 }
 
 // StopQueueProcessor stops the background task queue processor
 func (s *MasterServer) StopQueueProcessor() {
 	if s.queueTicker != nil {
 		s.queueTicker.Stop()
-		log.Printf("✓ Task queue processor stopped")
+		logging.Infof("✓ Task queue processor stopped")
 	}
 }
 
@@ -1649,62 +3032,165 @@ func (s *MasterServer) StopQueueProcessor() {
 // This is the main scheduler that selects workers for tasks
 func (s *MasterServer) processQueue() {
 	for range s.queueTicker.C {
-		s.queueMu.Lock()
-		if len(s.taskQueue) == 0 {
-			s.queueMu.Unlock()
+		s.processQueueOnce(time.Now())
+	}
+}
+
+// processQueueOnce runs a single scheduling pass over the queue
+// Workers are selected and reserved one task at a time, then the assignment RPCs run concurrently
+// (bounded by queueConcurrency) so one slow worker does not stall the whole pass
+// Tasks whose absolute deadline has already passed are dropped and marked expired
+func (s *MasterServer) processQueueOnce(now time.Time) {
+	// Evictions wait for workers to stop tasks, so they run once the queue is unlocked
+	preemptions := s.scheduleQueue(now)
+	s.carryOutPreemptions(preemptions, now)
+}
+
+// scheduleQueue places what it can from the queue and returns the preemptions it claimed for tasks that did not fit
+func (s *MasterServer) scheduleQueue(now time.Time) []pendingPreemption {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	// Return holds left behind by assignments that never reported back, and by lapsed capacity reservations
+	s.releaseExpiredReservations(now)
+	s.releaseExpiredCapacityReservations(now)
+
+	// Sample what is still queued once the pass is done
+	defer s.recordAutoscaleSample(now)
+
+	if len(s.taskQueue) == 0 {
+		return nil
+	}
+
+	// Under fair-share scheduling, users who consumed the least recently get first pick of free capacity
+	s.orderQueueByFairShare()
+
+	s.mu.RLock()
+	limit := s.queueConcurrency
+	s.mu.RUnlock()
+	if limit <= 0 {
+		limit = 1
+	}
+
+	// keep[i] is set for tasks that stay queued; each goroutine only writes its own index
+	keep := make([]bool, len(s.taskQueue))
+	var preemptions []pendingPreemption
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	// Try to schedule and assign tasks from the queue
+	for i, qt := range s.taskQueue {
+		// Drop tasks that can no longer meet their wall-clock deadline
+		if qt.Task.Deadline > 0 && now.Unix() > qt.Task.Deadline {
+			s.expireQueuedTask(qt, now)
 			continue
 		}
 
-		// Try to schedule and assign tasks from the queue
-		remainingTasks := make([]*QueuedTask, 0)
-		for _, qt := range s.taskQueue {
-			// Find the best worker for this task using the scheduler
-			selectedWorker := s.selectWorkerForTask(qt.Task)
+		// Find the best worker for this task using the scheduler
+		selectedWorker := s.selectWorkerForTask(qt.Task)
 
-			if selectedWorker == "" {
-				// No suitable worker available, keep in queue
-				qt.Retries++
-				qt.LastError = "No suitable worker available with sufficient resources"
-				remainingTasks = append(remainingTasks, qt)
-
-				// Log only on first retry and every 10th retry to avoid spam
-				if qt.Retries == 1 || qt.Retries%10 == 0 {
-					log.Printf("📋 Queue: Task %s still waiting (attempt %d): %s",
-						qt.Task.TaskId, qt.Retries, qt.LastError)
-				}
+		if selectedWorker == "" {
+			// A high-priority task may evict a lower-priority running task instead of waiting
+			if victim := s.claimPreemptionVictim(qt.Task, now); victim != nil {
+				preemptions = append(preemptions, pendingPreemption{qt: qt, victim: victim})
+				keep[i] = true
 				continue
 			}
+		}
+
+		if selectedWorker == "" {
+			// No suitable worker available, keep in queue
+			qt.Retries++
+			qt.LastError = "No suitable worker available with sufficient resources"
+			s.schedMetrics.RecordFailedAttempt()
+			keep[i] = true
+
+			// Log only on first retry and every 10th retry to avoid spam
+			if qt.Retries == 1 || qt.Retries%10 == 0 {
+				logging.Debugf("📋 Queue: Task %s still waiting (attempt %d): %s",
+					qt.Task.TaskId, qt.Retries, qt.LastError)
+			}
+			continue
+		}
+
+		// Set the selected worker as the target
+		qt.Task.TargetWorkerId = selectedWorker
+
+		// Reserve the task's resources now so the next selection sees them as taken
+		worker, workerIP, nack := s.reserveTaskOnWorker(qt.Task, selectedWorker)
+		if nack != nil {
+			s.recordQueueAssignmentFailure(qt, selectedWorker, nack.Message)
+			keep[i] = true
+			continue
+		}
 
-			// Set the selected worker as the target
-			qt.Task.TargetWorkerId = selectedWorker
+		sem <- struct{}{} // Blocks while limit assignments are in flight
+		wg.Add(1)
+		go func(i int, qt *QueuedTask, worker *WorkerState, workerID, workerIP string) {
+			defer wg.Done()
+			defer func() { <-sem }()
 
 			// Try to assign the task to the selected worker
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			ack, err := s.assignTaskToWorker(ctx, qt.Task, selectedWorker)
+			ack, err := s.sendTaskToWorker(ctx, qt.Task, worker, workerID, workerIP)
 			cancel()
 
 			if err != nil || !ack.Success {
 				// Assignment failed, keep in queue and try again later
-				qt.Retries++
+				msg := ack.GetMessage()
 				if err != nil {
-					qt.LastError = err.Error()
-				} else {
-					qt.LastError = ack.Message
-				}
-				remainingTasks = append(remainingTasks, qt)
-
-				if qt.Retries == 1 || qt.Retries%10 == 0 {
-					log.Printf("📋 Queue: Task %s assignment to %s failed (attempt %d): %s",
-						qt.Task.TaskId, selectedWorker, qt.Retries, qt.LastError)
+					msg = err.Error()
 				}
-			} else {
-				log.Printf("✓ Queue: Task %s successfully assigned to %s after %d attempts",
-					qt.Task.TaskId, selectedWorker, qt.Retries)
+				s.recordQueueAssignmentFailure(qt, workerID, msg)
+				keep[i] = true
+				return
 			}
+			logging.Infof("✓ Queue: Task %s successfully assigned to %s after %d attempts",
+				qt.Task.TaskId, workerID, qt.Retries)
+			s.schedMetrics.RecordScheduled(qt.Retries+1, now.Sub(qt.QueuedAt))
+		}(i, qt, worker, selectedWorker, workerIP)
+	}
+
+	wg.Wait()
+
+	remainingTasks := make([]*QueuedTask, 0)
+	for i, qt := range s.taskQueue {
+		if keep[i] {
+			remainingTasks = append(remainingTasks, qt)
 		}
+	}
+	s.taskQueue = remainingTasks
+	s.publishQueuePositions()
+	return preemptions
+}
 
-		s.taskQueue = remainingTasks
-		s.queueMu.Unlock()
+// recordQueueAssignmentFailure notes a failed assignment attempt for a task that stays queued
+func (s *MasterServer) recordQueueAssignmentFailure(qt *QueuedTask, workerID, msg string) {
+	qt.Retries++
+	qt.LastError = msg
+	s.schedMetrics.RecordFailedAttempt()
+
+	if qt.Retries == 1 || qt.Retries%10 == 0 {
+		logging.Infof("📋 Queue: Task %s assignment to %s failed (attempt %d): %s",
+			qt.Task.TaskId, workerID, qt.Retries, qt.LastError)
+	}
+}
+
+// expireQueuedTask drops a queued task that missed its absolute deadline
+// This function assumes s.queueMu is already locked by the caller
+func (s *MasterServer) expireQueuedTask(qt *QueuedTask, now time.Time) {
+	deadline := time.Unix(qt.Task.Deadline, 0)
+	logging.Infof("⏰ Queue: Task %s expired (deadline %s passed %s ago) - removing from queue",
+		qt.Task.TaskId, deadline.Format(time.RFC3339), now.Sub(deadline).Round(time.Second))
+	s.watchers.publish(&pb.TaskStatusUpdate{TaskId: qt.Task.TaskId, Status: "expired", Message: "Deadline passed while queued"})
+	s.events.publish(ClusterEvent{Type: EventTaskFinished, TaskID: qt.Task.TaskId, Status: "expired", Message: "Deadline passed while queued"})
+
+	if s.taskDB != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.taskDB.UpdateTaskStatus(ctx, qt.Task.TaskId, "expired"); err != nil {
+			logging.Warnf("  ⚠ Warning: Failed to mark task %s as expired: %v", qt.Task.TaskId, err)
+		}
 	}
 }
 
@@ -1714,8 +3200,17 @@ func (s *MasterServer) selectWorkerForTask(task *pb.Task) string {
 	s.mu.RLock()
 
 	// Convert WorkerState map to scheduler.WorkerInfo map
+	holds := s.reservationWorkers(task)
 	workerInfos := make(map[string]*scheduler.WorkerInfo)
 	for id, worker := range s.workers {
+		// Cordoned workers keep their running tasks but take no new ones
+		if worker.Cordoned {
+			continue
+		}
+		// A task drawing on a reservation is placed only on the workers holding part of it
+		if _, held := holds[id]; holds != nil && !held {
+			continue
+		}
 		workerInfos[id] = &scheduler.WorkerInfo{
 			WorkerID:         id,
 			IsActive:         worker.IsActive,
@@ -1724,6 +3219,20 @@ func (s *MasterServer) selectWorkerForTask(task *pb.Task) string {
 			AvailableMemory:  worker.AvailableMemory,
 			AvailableStorage: worker.AvailableStorage,
 			AvailableGPU:     worker.AvailableGPU,
+			TotalCPU:         scheduler.Usable(worker.Info.TotalCpu, s.systemReserve.CPU),
+			TotalMemory:      scheduler.Usable(worker.Info.TotalMemory, s.systemReserve.Memory),
+			TotalStorage:     scheduler.Usable(worker.Info.TotalStorage, s.systemReserve.Storage),
+			TotalGPU:         scheduler.Usable(worker.Info.TotalGpu, s.systemReserve.GPU),
+
+			RecentFailureRate: s.outcomes.FailureRate(id),
+			Zone:              worker.Info.Zone,
+		}
+
+		// Capacity held by the task's own reservation is available to it
+		if hold := s.reservedFor(task, id); hold != (Capacity{}) {
+			workerInfos[id].AvailableCPU += hold.CPU
+			workerInfos[id].AvailableMemory += hold.Memory
+			workerInfos[id].AvailableGPU += hold.GPU
 		}
 	}
 
@@ -1734,6 +3243,11 @@ func (s *MasterServer) selectWorkerForTask(task *pb.Task) string {
 	return selectedWorker
 }
 
+// SchedulerMetrics returns the scheduler's placement counters and latency histograms
+func (s *MasterServer) SchedulerMetrics() *scheduler.SchedulerMetrics {
+	return s.schedMetrics
+}
+
 // EnqueueTask adds a task to the queue
 func (s *MasterServer) EnqueueTask(task *pb.Task, reason string) {
 	s.queueMu.Lock()
@@ -1746,8 +3260,11 @@ func (s *MasterServer) EnqueueTask(task *pb.Task, reason string) {
 		LastError: reason,
 	}
 	s.taskQueue = append(s.taskQueue, qt)
+	qt.Position = len(s.taskQueue)
+	s.watchers.publish(&pb.TaskStatusUpdate{TaskId: task.TaskId, Status: "queued", QueuePosition: int32(qt.Position), Message: reason})
+	s.events.publish(ClusterEvent{Type: EventTaskQueued, TaskID: task.TaskId, Message: reason})
 
-	log.Printf("📋 Task %s queued: %s", task.TaskId, reason)
+	logging.Infof("📋 Task %s queued: %s", task.TaskId, reason)
 }
 
 // GetQueuedTasks returns a copy of the current task queue
@@ -1764,113 +3281,214 @@ func (s *MasterServer) GetQueuedTasks() []*QueuedTask {
 // assignTaskToWorker assigns a task to a specific worker
 // This is called by the scheduler after selecting an appropriate worker
 func (s *MasterServer) assignTaskToWorker(ctx context.Context, task *pb.Task, workerID string) (*pb.TaskAck, error) {
+	worker, workerIP, nack := s.reserveTaskOnWorker(task, workerID)
+	if nack != nil {
+		return nack, nil
+	}
+	return s.sendTaskToWorker(ctx, task, worker, workerID, workerIP)
+}
+
+// reserveTaskOnWorker checks that a worker can take a task and allocates its resources in memory
+// The check and the allocation happen under one lock, so concurrent assignments cannot oversubscribe a worker
+// Returns a failed ack (and allocates nothing) when the worker cannot take the task
+func (s *MasterServer) reserveTaskOnWorker(task *pb.Task, workerID string) (*WorkerState, string, *pb.TaskAck) {
 	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	// Find the specified worker
 	worker, exists := s.workers[workerID]
 	if !exists {
-		s.mu.Unlock()
-		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Worker %s not found", workerID)}, nil
+		return nil, "", &pb.TaskAck{Success: false, Message: fmt.Sprintf("Worker %s not found", workerID), ErrorCode: pb.ErrorCode_WORKER_NOT_FOUND}
+	}
+
+	// A task drawing on a capacity reservation is charged for it now, and may only run where the reservation holds capacity
+	drawn, nack := s.drawCapacityReservation(task)
+	if nack != nil {
+		return nil, "", nack
+	}
+	if _, held := s.reservationWorkers(task)[workerID]; drawn && !held {
+		s.refundCapacityReservation(task)
+		return nil, "", &pb.TaskAck{Success: false, Message: fmt.Sprintf("Reservation %s holds no capacity on worker %s", task.ReservationId, workerID)}
 	}
+
+	// Capacity the task's reservation holds on this worker is freed for the task first
+	claimed := s.claimReservedCapacity(task, workerID, worker)
+	if rejections := s.workerRejections(task, workerID, worker); len(rejections) > 0 {
+		s.restoreReservedCapacity(task, workerID, worker, claimed)
+		if drawn {
+			s.refundCapacityReservation(task)
+		}
+		return nil, "", rejections[0]
+	}
+
+	adjustAllocation(worker, task, 1)
+	s.trackReservation(task, worker, workerID, time.Now(), drawn, claimed)
+
+	return worker, worker.Info.WorkerIp, nil
+}
+
+// workerRejections returns every reason a worker cannot take a task right now (none if it can)
+// An unavailable worker reports only that; an available one reports each resource it is short of
+// Caller must hold s.mu
+func (s *MasterServer) workerRejections(task *pb.Task, workerID string, worker *WorkerState) []*pb.TaskAck {
 	if !worker.IsActive {
-		s.mu.Unlock()
-		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Worker %s is not active", workerID)}, nil
+		return []*pb.TaskAck{{Success: false, Message: fmt.Sprintf("Worker %s is not active", workerID), ErrorCode: pb.ErrorCode_WORKER_INACTIVE}}
+	}
+	if worker.Cordoned {
+		return []*pb.TaskAck{{Success: false, Message: fmt.Sprintf("Worker %s is cordoned for maintenance", workerID), ErrorCode: pb.ErrorCode_WORKER_INACTIVE}}
 	}
 
 	// Validate worker IP is set
 	if worker.Info.WorkerIp == "" {
-		s.mu.Unlock()
-		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Worker %s has no IP address configured", workerID)}, nil
+		return []*pb.TaskAck{{Success: false, Message: fmt.Sprintf("Worker %s has no IP address configured", workerID), ErrorCode: pb.ErrorCode_WORKER_NO_ADDRESS}}
 	}
 
-	// CHECK RESOURCE AVAILABILITY - Prevent Oversubscription
-	if worker.AvailableCPU < task.ReqCpu {
-		s.mu.Unlock()
-		return &pb.TaskAck{
+	// CHECK RESOURCE AVAILABILITY - Prevent Oversubscription beyond the over-commit ratios
+	cpuHeadroom := scheduler.Headroom(worker.AvailableCPU, scheduler.Usable(worker.Info.TotalCpu, s.systemReserve.CPU), s.overcommit.CPU)
+	memHeadroom := scheduler.Headroom(worker.AvailableMemory, scheduler.Usable(worker.Info.TotalMemory, s.systemReserve.Memory), s.overcommit.Memory)
+	storageHeadroom := scheduler.Headroom(worker.AvailableStorage, scheduler.Usable(worker.Info.TotalStorage, s.systemReserve.Storage), s.overcommit.Storage)
+	gpuHeadroom := scheduler.Headroom(worker.AvailableGPU, scheduler.Usable(worker.Info.TotalGpu, s.systemReserve.GPU), s.overcommit.GPU)
+
+	var rejections []*pb.TaskAck
+	if cpuHeadroom < task.ReqCpu {
+		rejections = append(rejections, &pb.TaskAck{
 			Success: false,
 			Message: fmt.Sprintf("Insufficient CPU: worker has %.2f available, task requires %.2f",
-				worker.AvailableCPU, task.ReqCpu),
-		}, nil
+				cpuHeadroom, task.ReqCpu),
+			ErrorCode: pb.ErrorCode_INSUFFICIENT_CPU,
+		})
 	}
-	if worker.AvailableMemory < task.ReqMemory {
-		s.mu.Unlock()
-		return &pb.TaskAck{
+	if memHeadroom < task.ReqMemory {
+		rejections = append(rejections, &pb.TaskAck{
 			Success: false,
 			Message: fmt.Sprintf("Insufficient Memory: worker has %.2f GB available, task requires %.2f GB",
-				worker.AvailableMemory, task.ReqMemory),
-		}, nil
+				memHeadroom, task.ReqMemory),
+			ErrorCode: pb.ErrorCode_INSUFFICIENT_MEMORY,
+		})
 	}
-	if worker.AvailableStorage < task.ReqStorage {
-		s.mu.Unlock()
-		return &pb.TaskAck{
+	if storageHeadroom < task.ReqStorage {
+		rejections = append(rejections, &pb.TaskAck{
 			Success: false,
 			Message: fmt.Sprintf("Insufficient Storage: worker has %.2f GB available, task requires %.2f GB",
-				worker.AvailableStorage, task.ReqStorage),
-		}, nil
+				storageHeadroom, task.ReqStorage),
+			ErrorCode: pb.ErrorCode_INSUFFICIENT_STORAGE,
+		})
 	}
-	if worker.AvailableGPU < task.ReqGpu {
-		s.mu.Unlock()
-		return &pb.TaskAck{
+	if gpuHeadroom < task.ReqGpu {
+		rejections = append(rejections, &pb.TaskAck{
 			Success: false,
 			Message: fmt.Sprintf("Insufficient GPU: worker has %.2f available, task requires %.2f",
-				worker.AvailableGPU, task.ReqGpu),
-		}, nil
+				gpuHeadroom, task.ReqGpu),
+			ErrorCode: pb.ErrorCode_INSUFFICIENT_GPU,
+		})
+	}
+	return rejections
+}
+
+// unreserveTaskOnWorker returns the in-memory resources reserved by reserveTaskOnWorker
+// Nothing is returned if the reservation already expired and was released
+func (s *MasterServer) unreserveTaskOnWorker(task *pb.Task, worker *WorkerState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.pendingReservations[task.TaskId]; ok && !r.committed {
+		s.releaseReservation(r)
 	}
+}
 
-	workerIP := worker.Info.WorkerIp
-	s.mu.Unlock()
+// checkWorkerStillRegistered returns a failed ack, releasing the task's reservation, if worker is no longer
+// the registered state for workerID (unregistered, or unregistered and registered again)
+func (s *MasterServer) checkWorkerStillRegistered(task *pb.Task, worker *WorkerState, workerID string) *pb.TaskAck {
+	s.mu.RLock()
+	current := s.workers[workerID]
+	s.mu.RUnlock()
+	if current == worker {
+		return nil
+	}
+
+	s.unreserveTaskOnWorker(task, worker)
+	logging.Warnf("⚠ Worker %s was unregistered while task %s was being assigned; not sending it", workerID, task.TaskId)
+	return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Worker %s was unregistered during assignment", workerID), ErrorCode: pb.ErrorCode_WORKER_NOT_FOUND}
+}
+
+// sendTaskToWorker sends a task to a worker that already holds its reservation
+// The reservation is released again if the worker does not accept the task
+func (s *MasterServer) sendTaskToWorker(ctx context.Context, task *pb.Task, worker *WorkerState, workerID, workerIP string) (*pb.TaskAck, error) {
+	// The worker may have been unregistered since the reservation was taken
+	if nack := s.checkWorkerStillRegistered(task, worker, workerID); nack != nil {
+		return nack, nil
+	}
 
 	// Connect to worker and assign task
-	conn, err := grpc.Dial(workerIP, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := s.dialWorker(ctx, workerIP)
 	if err != nil {
-		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Failed to connect to worker: %v", err)}, nil
+		s.unreserveTaskOnWorker(task, worker)
+		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Failed to connect to worker: %v", err), ErrorCode: pb.ErrorCode_WORKER_UNREACHABLE}, nil
 	}
 	defer conn.Close()
 
+	// ...or while the dial was in flight, which can take seconds
+	if nack := s.checkWorkerStillRegistered(task, worker, workerID); nack != nil {
+		return nack, nil
+	}
+
 	client := pb.NewMasterWorkerClient(conn)
+	rpcStart := time.Now()
 	ack, err := client.AssignTask(ctx, task)
+	s.schedMetrics.RecordAssignmentRPC(time.Since(rpcStart), err == nil && ack.Success)
 	if err != nil {
+		s.unreserveTaskOnWorker(task, worker)
 		// Update task status to failed if assignment fails
 		if s.taskDB != nil {
 			s.taskDB.UpdateTaskStatus(ctx, task.TaskId, "failed")
 		}
-		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Failed to assign task: %v", err)}, nil
+		return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Failed to assign task: %v", err), ErrorCode: pb.ErrorCode_WORKER_UNREACHABLE}, nil
 	}
 
-	if ack.Success {
+	if !ack.Success {
+		s.unreserveTaskOnWorker(task, worker)
+	} else {
 		s.mu.Lock()
+		// An ack arriving after the reservation expired is too late: its resources were already released
+		// and may have gone to another task, so the worker is told to stop the task and it is retried
+		if !s.commitReservation(task) {
+			s.mu.Unlock()
+			logging.Warnf("⚠ Task %s was accepted by %s after its reservation expired - stopping it there", task.TaskId, workerID)
+			if cancelAck, err := client.CancelTask(ctx, &pb.TaskID{TaskId: task.TaskId}); err != nil || !cancelAck.Success {
+				logging.Warnf("Warning: Failed to stop task %s on worker %s: %v", task.TaskId, workerID, err)
+			}
+			return &pb.TaskAck{Success: false, Message: fmt.Sprintf("Worker %s accepted the task after its reservation expired", workerID), ErrorCode: pb.ErrorCode_WORKER_UNREACHABLE}, nil
+		}
 		// Ensure RunningTasks map is initialized (defensive programming)
 		if worker.RunningTasks == nil {
 			worker.RunningTasks = make(map[string]bool)
 		}
-		// Mark task as running on worker
+		// Mark task as running on worker (its resources were reserved in memory before the RPC)
 		worker.RunningTasks[task.TaskId] = true
-
-		// 🚨 ALLOCATE RESOURCES - Update both in-memory and database
-		worker.AllocatedCPU += task.ReqCpu
-		worker.AllocatedMemory += task.ReqMemory
-		worker.AllocatedStorage += task.ReqStorage
-		worker.AllocatedGPU += task.ReqGpu
-		worker.AvailableCPU -= task.ReqCpu
-		worker.AvailableMemory -= task.ReqMemory
-		worker.AvailableStorage -= task.ReqStorage
-		worker.AvailableGPU -= task.ReqGpu
+		s.trackRunningTask(task, worker)
+		// A task preempted from this worker and now placed back on it reports as normal again
+		if s.preempted[task.TaskId] == workerID {
+			delete(s.preempted, task.TaskId)
+		}
 		s.mu.Unlock()
 
-		// Update database
+		s.watchers.publish(&pb.TaskStatusUpdate{TaskId: task.TaskId, Status: "running", WorkerId: workerID})
+		s.events.publish(ClusterEvent{Type: EventTaskStarted, TaskID: task.TaskId, WorkerID: workerID})
+
+		// 🚨 ALLOCATE RESOURCES - Update database
 		if s.workerDB != nil {
 			if err := s.workerDB.AllocateResources(ctx, workerID,
 				task.ReqCpu, task.ReqMemory, task.ReqStorage, task.ReqGpu); err != nil {
-				log.Printf("Warning: Failed to allocate resources in database: %v", err)
+				logging.Warnf("Warning: Failed to allocate resources in database: %v", err)
 			}
 		}
 
 		// Update task status to running
 		if s.taskDB != nil {
 			if err := s.taskDB.UpdateTaskStatus(ctx, task.TaskId, "running"); err != nil {
-				log.Printf("Warning: Failed to update task status: %v", err)
+				logging.Warnf("Warning: Failed to update task status: %v", err)
 			}
 		}
+		s.settleReservation(task)
 
 		// Store assignment in database
 		if s.assignmentDB != nil {
@@ -1880,25 +3498,26 @@ func (s *MasterServer) assignTaskToWorker(ctx context.Context, task *pb.Task, wo
 				WorkerID:     workerID,
 			}
 			if err := s.assignmentDB.CreateAssignment(ctx, assignment); err != nil {
-				log.Printf("Warning: Failed to store assignment in database: %v", err)
+				logging.Warnf("Warning: Failed to store assignment in database: %v", err)
 			}
 		}
 
-		log.Println("\n═══════════════════════════════════════════════════════")
-		log.Println("  📤 TASK ASSIGNED TO WORKER")
-		log.Println("═══════════════════════════════════════════════════════")
-		log.Printf("  Task ID:           %s", task.TaskId)
-		log.Printf("  User ID:           %s", task.UserId)
-		log.Printf("  Assigned Worker:   %s", workerID)
-		log.Printf("  Docker Image:      %s", task.DockerImage)
-		log.Println("───────────────────────────────────────────────────────")
-		log.Println("  Resource Requirements:")
-		log.Printf("    • CPU Cores:     %.2f cores", task.ReqCpu)
-		log.Printf("    • Memory:        %.2f GB", task.ReqMemory)
-		log.Printf("    • Storage:       %.2f GB", task.ReqStorage)
-		log.Printf("    • GPU Cores:     %.2f cores", task.ReqGpu)
-		log.Println("═══════════════════════════════════════════════════════")
-		log.Println("")
+		logging.Infof("📤 Task %s assigned to worker %s", task.TaskId, workerID)
+		logging.Debug("\n═══════════════════════════════════════════════════════")
+		logging.Debug("  📤 TASK ASSIGNED TO WORKER")
+		logging.Debug("═══════════════════════════════════════════════════════")
+		logging.Debugf("  Task ID:           %s", task.TaskId)
+		logging.Debugf("  User ID:           %s", task.UserId)
+		logging.Debugf("  Assigned Worker:   %s", workerID)
+		logging.Debugf("  Docker Image:      %s", task.DockerImage)
+		logging.Debug("───────────────────────────────────────────────────────")
+		logging.Debug("  Resource Requirements:")
+		logging.Debugf("    • CPU Cores:     %.2f cores", task.ReqCpu)
+		logging.Debugf("    • Memory:        %.2f GB", task.ReqMemory)
+		logging.Debugf("    • Storage:       %.2f GB", task.ReqStorage)
+		logging.Debugf("    • GPU Cores:     %.2f cores", task.ReqGpu)
+		logging.Debug("═══════════════════════════════════════════════════════")
+		logging.Debug("")
 	}
 
 	return ack, err
@@ -0,0 +1,1458 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	"master/internal/logging"
+	"master/internal/notify"
+	"master/internal/scheduler"
+	"master/internal/storage"
+	"master/internal/telemetry"
+	pb "master/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"google.golang.org/grpc"
+)
+
+// TestAssignTaskInsufficientMemoryErrorCode tests that a memory shortfall is reported as INSUFFICIENT_MEMORY
+func TestAssignTaskInsufficientMemoryErrorCode(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 8.0, 4.0, 100.0, 0.0)
+
+	task := &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 16.0}
+	ack, err := ms.assignTaskToWorker(context.Background(), task, "worker-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if ack.Success {
+		t.Fatal("Expected assignment to fail")
+	}
+	if ack.ErrorCode != pb.ErrorCode_INSUFFICIENT_MEMORY {
+		t.Errorf("Expected error code INSUFFICIENT_MEMORY, got %s", ack.ErrorCode)
+	}
+	if ack.Message == "" {
+		t.Error("Expected a human-readable message alongside the error code")
+	}
+}
+
+// TestAssignTaskWorkerNotFoundErrorCode tests that an unknown worker is reported as WORKER_NOT_FOUND
+func TestAssignTaskWorkerNotFoundErrorCode(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+
+	task := &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0}
+	ack, err := ms.assignTaskToWorker(context.Background(), task, "missing-worker")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if ack.Success {
+		t.Fatal("Expected assignment to fail")
+	}
+	if ack.ErrorCode != pb.ErrorCode_WORKER_NOT_FOUND {
+		t.Errorf("Expected error code WORKER_NOT_FOUND, got %s", ack.ErrorCode)
+	}
+}
+
+// TestManualRegisterFlagsUnreachableWorker tests that a bad address is still registered but flagged
+func TestManualRegisterFlagsUnreachableWorker(t *testing.T) {
+	// Reserve a port and close it so nothing is listening there
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	deadAddr := lis.Addr().String()
+	lis.Close()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	warning, err := ms.ManualRegisterAndNotify(context.Background(), "worker-1", deadAddr, nil, "master-1", "127.0.0.1:50051")
+	if err != nil {
+		t.Fatalf("Expected registration to succeed, got %v", err)
+	}
+	if warning == "" {
+		t.Error("Expected a reachability warning for an unreachable address")
+	}
+
+	worker, exists := ms.GetWorkerStats("worker-1")
+	if !exists {
+		t.Fatal("Expected unreachable worker to still be registered")
+	}
+	if !worker.Unreachable {
+		t.Error("Expected worker to be flagged as unreachable")
+	}
+}
+
+// TestManualRegisterReachableWorker tests that a reachable address registers without a warning
+func TestManualRegisterReachableWorker(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	warning, err := ms.ManualRegisterAndNotify(context.Background(), "worker-1", lis.Addr().String(), nil, "master-1", "127.0.0.1:50051")
+	if err != nil {
+		t.Fatalf("Expected registration to succeed, got %v", err)
+	}
+	if warning != "" {
+		t.Errorf("Expected no warning for a reachable address, got %q", warning)
+	}
+
+	worker, exists := ms.GetWorkerStats("worker-1")
+	if !exists {
+		t.Fatal("Expected worker to be registered")
+	}
+	if worker.Unreachable {
+		t.Error("Expected reachable worker not to be flagged")
+	}
+}
+
+// TestReconnectionDialsAreBounded tests that reconnection never runs more than the configured number of dials at once
+func TestReconnectionDialsAreBounded(t *testing.T) {
+	const workers = 50
+	const limit = 4
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.SetMasterInfo("master-1", "127.0.0.1:50051")
+	ms.SetReconnectConcurrency(limit)
+	for i := 0; i < workers; i++ {
+		if err := ms.ManualRegisterWorker(context.Background(), fmt.Sprintf("worker-%d", i), fmt.Sprintf("10.0.0.%d:50052", i)); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+	}
+
+	// Counting stub dialer: tracks in-flight dials and always fails like an offline worker
+	var mu sync.Mutex
+	inFlight, maxInFlight, total := 0, 0, 0
+	ms.dialWorker = func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+		mu.Lock()
+		inFlight++
+		total++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil, errors.New("worker offline")
+	}
+
+	ms.attemptWorkerReconnections()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ms.reconnectInFlight.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("Reconnection cycle did not finish in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if total != workers {
+		t.Errorf("Expected %d dials, got %d", workers, total)
+	}
+	if maxInFlight > limit {
+		t.Errorf("Expected at most %d concurrent dials, got %d", limit, maxInFlight)
+	}
+}
+
+// TestReconcileWorkerOnlyFixesTargetWorker tests that reconciling one worker leaves other workers untouched
+func TestReconcileWorkerOnlyFixesTargetWorker(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("single worker", func(mt *mtest.T) {
+		taskDB := db.NewTaskDBFromClient(mt.Client, "cloudai")
+		assignmentDB := db.NewAssignmentDBFromClient(mt.Client, "cloudai")
+		ms := NewMasterServer(nil, taskDB, assignmentDB, nil, nil, nil, nil)
+
+		for _, id := range []string{"worker-1", "worker-2"} {
+			if err := ms.ManualRegisterWorker(context.Background(), id, "10.0.0.1:50052"); err != nil {
+				t.Fatalf("Failed to register %s: %v", id, err)
+			}
+			ms.UpdateWorkerResourcesInMemory(id, 8.0, 16.0, 100.0, 0.0)
+		}
+
+		// Both workers have drifted allocations
+		worker1, _ := ms.GetWorkerStats("worker-1")
+		worker1.AllocatedCPU = 6.0
+		worker2, _ := ms.GetWorkerStats("worker-2")
+		worker2.AllocatedCPU = 7.0
+
+		// One running 2-core task, assigned to worker-1
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, bson.D{
+				{Key: "task_id", Value: "task-1"},
+				{Key: "status", Value: "running"},
+				{Key: "req_cpu", Value: 2.0},
+				{Key: "req_memory", Value: 1.0},
+				{Key: "req_storage", Value: 0.0},
+				{Key: "req_gpu", Value: 0.0},
+			}),
+			mtest.CreateCursorResponse(0, "cloudai.ASSIGNMENTS", mtest.FirstBatch, bson.D{
+				{Key: "ass_id", Value: "ass-task-1"},
+				{Key: "task_id", Value: "task-1"},
+				{Key: "worker_id", Value: "worker-1"},
+			}),
+		)
+
+		change, err := ms.ReconcileWorker(context.Background(), "worker-1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if change.Before.CPU != 6.0 || change.After.CPU != 2.0 {
+			t.Errorf("Expected worker-1 CPU 6.0→2.0, got %.1f→%.1f", change.Before.CPU, change.After.CPU)
+		}
+		if worker1.AvailableCPU != 6.0 {
+			t.Errorf("Expected worker-1 available CPU 6.0, got %.1f", worker1.AvailableCPU)
+		}
+		if worker2.AllocatedCPU != 7.0 {
+			t.Errorf("Expected worker-2 to be untouched at 7.0 CPU, got %.1f", worker2.AllocatedCPU)
+		}
+	})
+
+	mt.Run("unknown worker", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), db.NewAssignmentDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil)
+		if _, err := ms.ReconcileWorker(context.Background(), "missing"); err == nil {
+			t.Error("Expected error for unknown worker")
+		}
+	})
+}
+
+// TestProcessQueueExpiresPastDeadline tests that queued tasks past their absolute deadline are dropped
+func TestProcessQueueExpiresPastDeadline(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	now := time.Now()
+
+	ms.EnqueueTask(&pb.Task{TaskId: "expired", ReqCpu: 1.0, Deadline: now.Add(-time.Minute).Unix()}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "future", ReqCpu: 1.0, Deadline: now.Add(time.Hour).Unix()}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "no-deadline", ReqCpu: 1.0}, "test")
+
+	// No workers are registered, so tasks that are not expired stay queued
+	ms.processQueueOnce(now)
+
+	queue := ms.GetQueuedTasks()
+	remaining := make(map[string]bool)
+	for _, qt := range queue {
+		remaining[qt.Task.TaskId] = true
+	}
+
+	if remaining["expired"] {
+		t.Error("Expected expired task to be removed from the queue")
+	}
+	if !remaining["future"] || !remaining["no-deadline"] {
+		t.Errorf("Expected future and no-deadline tasks to remain queued, got %v", remaining)
+	}
+}
+
+// acceptingWorker is a worker stub that accepts every task assignment
+type acceptingWorker struct {
+	pb.UnimplementedMasterWorkerServer
+}
+
+func (acceptingWorker) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	return &pb.TaskAck{Success: true, Message: "accepted"}, nil
+}
+
+// TestAssignTaskHonoursCPUOvercommit tests that a CPU ratio of 2.0 admits a task strict checking rejects, while memory stays strict
+func TestAssignTaskHonoursCPUOvercommit(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, acceptingWorker{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 2.0, 4.0, 100.0, 0.0)
+
+	cpuHeavy := &pb.Task{TaskId: "task-cpu", ReqCpu: 3.0, ReqMemory: 1.0}
+
+	// Strict checking rejects 3 CPUs on a 2-core worker
+	ack, err := ms.assignTaskToWorker(context.Background(), cpuHeavy, "worker-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ack.Success || ack.ErrorCode != pb.ErrorCode_INSUFFICIENT_CPU {
+		t.Fatalf("Expected strict check to fail with INSUFFICIENT_CPU, got success=%v code=%s", ack.Success, ack.ErrorCode)
+	}
+
+	ms.SetOvercommitRatios(scheduler.OvercommitRatios{CPU: 2.0, Memory: 1.0})
+
+	ack, err = ms.assignTaskToWorker(context.Background(), cpuHeavy, "worker-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !ack.Success {
+		t.Fatalf("Expected CPU over-commit to admit the task, got %s: %s", ack.ErrorCode, ack.Message)
+	}
+
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if worker.AvailableCPU != -1.0 {
+		t.Errorf("Expected available CPU -1.0 after over-committing, got %.1f", worker.AvailableCPU)
+	}
+
+	// Memory is not over-committed: 8 GB on a 4 GB worker is still rejected
+	memHeavy := &pb.Task{TaskId: "task-mem", ReqCpu: 0.5, ReqMemory: 8.0}
+	ack, err = ms.assignTaskToWorker(context.Background(), memHeavy, "worker-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ack.Success || ack.ErrorCode != pb.ErrorCode_INSUFFICIENT_MEMORY {
+		t.Errorf("Expected memory to stay strict with INSUFFICIENT_MEMORY, got success=%v code=%s", ack.Success, ack.ErrorCode)
+	}
+}
+
+// TestWarnLevelSilencesAssignmentLogs tests that at WARN level an assignment logs nothing while an error is still logged
+func TestWarnLevelSilencesAssignmentLogs(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	logging.SetLevel(logging.LevelWarn)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		logging.SetLevel(logging.LevelInfo)
+	})
+
+	ms := newReservationTestServer(t, 4.0)
+	ack, err := ms.assignTaskToWorker(context.Background(), &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0}, "worker-1")
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected the assignment to succeed, got ack=%v err=%v", ack, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no log output for an assignment at WARN level, got %q", buf.String())
+	}
+
+	// The accepting worker does not implement CancelTask, so cancelling logs an error
+	buf.Reset()
+	if _, err := ms.CancelTask(context.Background(), &pb.TaskID{TaskId: "task-1"}); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Failed to cancel task on worker") {
+		t.Errorf("Expected the cancellation error to be logged, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "CANCELLING TASK") {
+		t.Errorf("Expected the cancellation banner to be suppressed, got %q", buf.String())
+	}
+}
+
+// flakyWorker is a worker stub that rejects a fixed number of assignments, then accepts the rest
+type flakyWorker struct {
+	pb.UnimplementedMasterWorkerServer
+	mu         sync.Mutex
+	rejections int
+}
+
+func (f *flakyWorker) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rejections > 0 {
+		f.rejections--
+		return &pb.TaskAck{Success: false, Message: "busy"}, nil
+	}
+	return &pb.TaskAck{Success: true, Message: "accepted"}, nil
+}
+
+// TestSchedulerMetricsRecordAttemptsBeforeSuccess tests that a task placed after two failed attempts records 3 attempts
+func TestSchedulerMetricsRecordAttemptsBeforeSuccess(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, &flakyWorker{rejections: 2})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+
+	ms.EnqueueTask(&pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0}, "test")
+	for i := 0; i < 3; i++ {
+		ms.processQueueOnce(time.Now())
+	}
+
+	if queued := ms.GetQueuedTasks(); len(queued) != 0 {
+		t.Fatalf("Expected the task to be scheduled on the third pass, %d still queued", len(queued))
+	}
+
+	stats := ms.SchedulerMetrics().Stats()
+	if stats.TasksScheduled != 1 {
+		t.Errorf("Expected 1 task scheduled, got %d", stats.TasksScheduled)
+	}
+	if stats.AvgAttemptsBeforeOK != 3.0 {
+		t.Errorf("Expected 3 attempts before success, got %.2f", stats.AvgAttemptsBeforeOK)
+	}
+	if stats.FailedAttempts != 2 {
+		t.Errorf("Expected 2 failed attempts, got %d", stats.FailedAttempts)
+	}
+	if stats.AssignmentRPCs != 3 || stats.AssignmentRPCFailures != 2 {
+		t.Errorf("Expected 3 assignment RPCs with 2 failures, got %d with %d", stats.AssignmentRPCs, stats.AssignmentRPCFailures)
+	}
+}
+
+// TestProcessQueueAssignsConcurrently tests that tasks bound for different workers are assigned in parallel within one pass
+func TestProcessQueueAssignsConcurrently(t *testing.T) {
+	const tasks = 4
+	const dialLatency = 200 * time.Millisecond
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, acceptingWorker{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.SetQueueConcurrency(tasks)
+	// Each worker has room for exactly one task, so every task needs a different worker
+	for i := 0; i < tasks; i++ {
+		workerID := fmt.Sprintf("worker-%d", i)
+		if err := ms.ManualRegisterWorker(context.Background(), workerID, lis.Addr().String()); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory(workerID, 1.0, 1.0, 10.0, 0.0)
+		ms.EnqueueTask(&pb.Task{TaskId: fmt.Sprintf("task-%d", i), ReqCpu: 1.0, ReqMemory: 1.0}, "test")
+	}
+
+	// Slow stub dialer: tracks in-flight dials, then connects to the accepting worker
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	ms.dialWorker = func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(dialLatency)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return ms.dialWorkerBlocking(ctx, addr)
+	}
+
+	start := time.Now()
+	ms.processQueueOnce(time.Now())
+	elapsed := time.Since(start)
+
+	if queued := ms.GetQueuedTasks(); len(queued) != 0 {
+		t.Fatalf("Expected every task to be assigned in one pass, %d still queued", len(queued))
+	}
+	if elapsed >= 2*dialLatency {
+		t.Errorf("Expected the pass to take about one dial (%s), took %s", dialLatency, elapsed)
+	}
+	mu.Lock()
+	if maxInFlight != tasks {
+		t.Errorf("Expected %d dials in flight at once, got %d", tasks, maxInFlight)
+	}
+	mu.Unlock()
+
+	// Reservations kept the workers from being oversubscribed
+	for i := 0; i < tasks; i++ {
+		worker, _ := ms.GetWorkerStats(fmt.Sprintf("worker-%d", i))
+		if len(worker.RunningTasks) != 1 || worker.AvailableCPU != 0 {
+			t.Errorf("Expected worker-%d to run exactly one task, got %d running with %.1f CPU available", i, len(worker.RunningTasks), worker.AvailableCPU)
+		}
+	}
+}
+
+// TestSystemReserveReducesAdvertisedCapacity tests that an 8-CPU worker with a 1-CPU reserve only advertises 7 available
+func TestSystemReserveReducesAdvertisedCapacity(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.SetSystemReserve(scheduler.SystemReserve{CPU: 1.0})
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+
+	ack, err := ms.RegisterWorker(context.Background(), &pb.WorkerInfo{
+		WorkerId:     "worker-1",
+		TotalCpu:     8.0,
+		TotalMemory:  16.0,
+		TotalStorage: 100.0,
+	})
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected registration to succeed, got ack=%v err=%v", ack, err)
+	}
+
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if worker.AvailableCPU != 7.0 {
+		t.Errorf("Expected 7.0 available CPU after a 1-CPU reserve, got %.1f", worker.AvailableCPU)
+	}
+	if worker.AvailableMemory != 16.0 {
+		t.Errorf("Expected memory without a reserve to stay at 16.0, got %.1f", worker.AvailableMemory)
+	}
+
+	// The reserved core is never handed to a task
+	task := &pb.Task{TaskId: "task-1", ReqCpu: 8.0, ReqMemory: 1.0}
+	ack2, err := ms.assignTaskToWorker(context.Background(), task, "worker-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ack2.Success || ack2.ErrorCode != pb.ErrorCode_INSUFFICIENT_CPU {
+		t.Errorf("Expected an 8-CPU task to be rejected with INSUFFICIENT_CPU, got success=%v code=%s", ack2.Success, ack2.ErrorCode)
+	}
+}
+
+// TestHeldTaskWaitsForRelease tests that a held task is never assigned until released, then schedules normally
+func TestHeldTaskWaitsForRelease(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, acceptingWorker{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+
+	ack, err := ms.SubmitTask(context.Background(), &pb.Task{TaskId: "task-held", ReqCpu: 1.0, ReqMemory: 1.0, Hold: true})
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected held submission to succeed, got ack=%v err=%v", ack, err)
+	}
+
+	// Scheduling passes must leave the held task alone
+	for i := 0; i < 3; i++ {
+		ms.processQueueOnce(time.Now())
+	}
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if worker.RunningTasks["task-held"] {
+		t.Fatal("Expected held task not to be assigned before release")
+	}
+	if len(ms.GetQueuedTasks()) != 0 {
+		t.Errorf("Expected held task to stay out of the queue, got %d queued", len(ms.GetQueuedTasks()))
+	}
+	if held := ms.GetHeldTasks(); len(held) != 1 || held[0].TaskId != "task-held" {
+		t.Fatalf("Expected task-held to be on hold, got %v", held)
+	}
+
+	ack, err = ms.ReleaseHeldTask(context.Background(), &pb.TaskID{TaskId: "task-held"})
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected release to succeed, got ack=%v err=%v", ack, err)
+	}
+	if len(ms.GetHeldTasks()) != 0 {
+		t.Error("Expected no held tasks after release")
+	}
+
+	ms.processQueueOnce(time.Now())
+
+	if !worker.RunningTasks["task-held"] {
+		t.Error("Expected released task to be assigned to worker-1")
+	}
+	if len(ms.GetQueuedTasks()) != 0 {
+		t.Errorf("Expected queue to be empty after assignment, got %d queued", len(ms.GetQueuedTasks()))
+	}
+
+	// A task that is not held cannot be released
+	ack, _ = ms.ReleaseHeldTask(context.Background(), &pb.TaskID{TaskId: "task-held"})
+	if ack.Success || ack.ErrorCode != pb.ErrorCode_TASK_NOT_FOUND {
+		t.Errorf("Expected TASK_NOT_FOUND releasing a task twice, got success=%v code=%s", ack.Success, ack.ErrorCode)
+	}
+}
+
+// TestHeldTasksSurviveRestartAndReleaseOnce tests that held tasks are reloaded from the database and a repeated release queues them once
+func TestHeldTasksSurviveRestartAndReleaseOnce(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("reload and release", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+		heldDoc := bson.D{{Key: "task_id", Value: "task-held"}, {Key: "status", Value: "held"},
+			{Key: "docker_image", Value: "alpine"}, {Key: "req_cpu", Value: 1.0}}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, heldDoc))
+
+		if err := ms.LoadHeldTasksFromDB(context.Background()); err != nil {
+			t.Fatalf("Failed to load held tasks: %v", err)
+		}
+		if held := ms.GetHeldTasks(); len(held) != 1 || held[0].TaskId != "task-held" || held[0].DockerImage != "alpine" {
+			t.Fatalf("Expected task-held to be restored on hold, got %v", held)
+		}
+
+		// The status moves from held to queued
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		ack, err := ms.ReleaseHeldTask(context.Background(), &pb.TaskID{TaskId: "task-held"})
+		if err != nil || !ack.Success {
+			t.Fatalf("Expected release to succeed, got ack=%v err=%v", ack, err)
+		}
+
+		// A second release still reads the task as held, but another release already moved its status
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, heldDoc),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+		)
+		ack, _ = ms.ReleaseHeldTask(context.Background(), &pb.TaskID{TaskId: "task-held"})
+		if ack.Success || ack.ErrorCode != pb.ErrorCode_TASK_NOT_FOUND {
+			t.Errorf("Expected TASK_NOT_FOUND releasing a task twice, got success=%v code=%s", ack.Success, ack.ErrorCode)
+		}
+		if queued := ms.GetQueuedTasks(); len(queued) != 1 {
+			t.Errorf("Expected the task to be queued once, got %d queued", len(queued))
+		}
+	})
+}
+
+// TestSubmitTaskShedsLoadWhenQueueFull tests that submissions beyond the queue limit are rejected until the queue drains
+func TestSubmitTaskShedsLoadWhenQueueFull(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, acceptingWorker{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.SetMaxQueueLength(2)
+
+	for i := 1; i <= 2; i++ {
+		ack, err := ms.SubmitTask(context.Background(), &pb.Task{TaskId: fmt.Sprintf("task-%d", i), ReqCpu: 1.0, ReqMemory: 1.0})
+		if err != nil || !ack.Success {
+			t.Fatalf("Expected submission %d within the limit to succeed, got ack=%v err=%v", i, ack, err)
+		}
+	}
+
+	ack, err := ms.SubmitTask(context.Background(), &pb.Task{TaskId: "task-3", ReqCpu: 1.0, ReqMemory: 1.0})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ack.Success || ack.ErrorCode != pb.ErrorCode_CLUSTER_AT_CAPACITY {
+		t.Fatalf("Expected CLUSTER_AT_CAPACITY beyond the limit, got success=%v code=%s", ack.Success, ack.ErrorCode)
+	}
+	if queued := ms.GetQueuedTasks(); len(queued) != 2 {
+		t.Fatalf("Expected the rejected task not to be queued, got %d queued", len(queued))
+	}
+
+	// A worker joins and the queue drains
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+	ms.processQueueOnce(time.Now())
+	if queued := ms.GetQueuedTasks(); len(queued) != 0 {
+		t.Fatalf("Expected the queue to drain, got %d queued", len(queued))
+	}
+
+	ack, err = ms.SubmitTask(context.Background(), &pb.Task{TaskId: "task-3", ReqCpu: 1.0, ReqMemory: 1.0})
+	if err != nil || !ack.Success {
+		t.Errorf("Expected submission to be accepted after the queue drained, got ack=%v err=%v", ack, err)
+	}
+}
+
+// TestSubmitTaskGeneratesUniqueIDs tests that tasks submitted in a tight loop all get distinct IDs
+func TestSubmitTaskGeneratesUniqueIDs(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+
+	const count = 1000
+	for i := 0; i < count; i++ {
+		if _, err := ms.SubmitTask(context.Background(), &pb.Task{DockerImage: "alpine", ReqCpu: 1.0}); err != nil {
+			t.Fatalf("SubmitTask failed: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool, count)
+	for _, qt := range ms.GetQueuedTasks() {
+		if qt.Task.TaskId == "" {
+			t.Fatal("Expected every submitted task to get an ID")
+		}
+		if seen[qt.Task.TaskId] {
+			t.Fatalf("Duplicate task ID %s", qt.Task.TaskId)
+		}
+		seen[qt.Task.TaskId] = true
+	}
+	if len(seen) != count {
+		t.Errorf("Expected %d unique IDs, got %d", count, len(seen))
+	}
+}
+
+// TestSubmitTaskReassignsTakenID tests that an ID already stored in the database is replaced before insert
+func TestSubmitTaskReassignsTakenID(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("taken id", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+
+		mt.AddMockResponses(
+			// task-1 exists, the regenerated ID does not, then the insert succeeds
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}),
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		task := &pb.Task{TaskId: "task-1", DockerImage: "alpine", ReqCpu: 1.0}
+		if _, err := ms.SubmitTask(context.Background(), task); err != nil {
+			t.Fatalf("SubmitTask failed: %v", err)
+		}
+		if task.TaskId == "task-1" {
+			t.Error("Expected the colliding ID to be reassigned")
+		}
+
+		queued := ms.GetQueuedTasks()
+		if len(queued) != 1 || queued[0].Task.TaskId != task.TaskId {
+			t.Errorf("Expected the reassigned task to be queued, got %+v", queued)
+		}
+	})
+}
+
+// TestSubmitTaskAutoPlacesOnSchedulerChoice tests that a task submitted without a usable target is placed by the scheduler
+// on the only worker with room for it, ignoring the target the client named
+func TestSubmitTaskAutoPlacesOnSchedulerChoice(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, acceptingWorker{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	for _, workerID := range []string{"worker-small", "worker-large"} {
+		if err := ms.ManualRegisterWorker(context.Background(), workerID, lis.Addr().String()); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-small", 1.0, 1.0, 10.0, 0.0)
+	ms.UpdateWorkerResourcesInMemory("worker-large", 8.0, 16.0, 100.0, 0.0)
+
+	task := &pb.Task{DockerImage: "alpine", ReqCpu: 4.0, ReqMemory: 4.0, TargetWorkerId: "worker-small"}
+	ack, err := ms.SubmitTaskAuto(context.Background(), task)
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected the task to be queued, got %v / %+v", err, ack)
+	}
+	if task.TaskId == "" || !strings.HasPrefix(ack.Message, "Task "+task.TaskId) {
+		t.Errorf("Expected a generated task ID named in the ack, got %q / %q", task.TaskId, ack.Message)
+	}
+
+	ms.processQueueOnce(time.Now())
+
+	if queued := ms.GetQueuedTasks(); len(queued) != 0 {
+		t.Fatalf("Expected the task to be placed, %d still queued", len(queued))
+	}
+	large, _ := ms.GetWorkerStats("worker-large")
+	if !large.RunningTasks[task.TaskId] {
+		t.Errorf("Expected the task on worker-large, got target %q", task.TargetWorkerId)
+	}
+	small, _ := ms.GetWorkerStats("worker-small")
+	if len(small.RunningTasks) != 0 {
+		t.Errorf("Expected nothing on worker-small, got %v", small.RunningTasks)
+	}
+}
+
+// TestRoundRobinRotatesThroughWorkers tests that tasks submitted to an unchanging set of workers rotate strictly by worker ID
+func TestRoundRobinRotatesThroughWorkers(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, acceptingWorker{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	// Registered out of order: rotation follows worker IDs, not registration or map order
+	for _, workerID := range []string{"worker-c", "worker-a", "worker-b"} {
+		if err := ms.ManualRegisterWorker(context.Background(), workerID, lis.Addr().String()); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory(workerID, 16.0, 64.0, 500.0, 0.0)
+	}
+
+	expected := []string{"worker-a", "worker-b", "worker-c", "worker-a", "worker-b", "worker-c", "worker-a"}
+	for i, want := range expected {
+		task := &pb.Task{DockerImage: "alpine", ReqCpu: 1.0, ReqMemory: 1.0}
+		if ack, err := ms.SubmitTaskAuto(context.Background(), task); err != nil || !ack.Success {
+			t.Fatalf("Expected task %d to be queued, got %v / %+v", i, err, ack)
+		}
+		ms.processQueueOnce(time.Now())
+
+		if task.TargetWorkerId != want {
+			t.Errorf("Expected task %d on %s, got %q", i, want, task.TargetWorkerId)
+		}
+	}
+}
+
+// TestSendHeartbeatIgnoresHeartbeatsBelowFloor tests that rapid heartbeats are rate-limited before reaching the telemetry manager
+func TestSendHeartbeatIgnoresHeartbeatsBelowFloor(t *testing.T) {
+	tm := telemetry.NewTelemetryManager(time.Minute)
+	defer tm.Shutdown()
+	var processed atomic.Int32
+	tm.SetUpdateCallback(func(workerID string, data *telemetry.WorkerTelemetryData) {
+		processed.Add(1)
+	})
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, tm)
+	ms.SetMinHeartbeatInterval(200 * time.Millisecond)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+
+	// Two bursts separated by more than the floor: only the first heartbeat of each is processed
+	accepted := 0
+	for burst := 0; burst < 2; burst++ {
+		for i := 0; i < 10; i++ {
+			ack, err := ms.SendHeartbeat(context.Background(), &pb.Heartbeat{WorkerId: "worker-1", CpuUsage: float64(i)})
+			if err != nil {
+				t.Fatalf("Expected rapid heartbeats to be ignored without an error, got %v", err)
+			}
+			if ack.Success {
+				accepted++
+			}
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	if accepted != 2 {
+		t.Errorf("Expected 2 of 20 heartbeats accepted, got %d", accepted)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for processed.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := processed.Load(); got != 2 {
+		t.Errorf("Expected the telemetry manager to process 2 heartbeats, got %d", got)
+	}
+
+	// Without a floor every heartbeat is processed
+	ms.SetMinHeartbeatInterval(0)
+	for i := 0; i < 3; i++ {
+		if ack, _ := ms.SendHeartbeat(context.Background(), &pb.Heartbeat{WorkerId: "worker-1"}); !ack.Success {
+			t.Errorf("Expected heartbeat %d to be accepted without a floor", i)
+		}
+	}
+}
+
+// TestRetryTaskOnWorkerDispatchesCopyToNamedWorker tests that retrying a failed task creates a new linked task dispatched to the named worker
+func TestRetryTaskOnWorkerDispatchesCopyToNamedWorker(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, acceptingWorker{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("retry", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+		for _, workerID := range []string{"worker-faulty", "worker-good"} {
+			if err := ms.ManualRegisterWorker(context.Background(), workerID, lis.Addr().String()); err != nil {
+				t.Fatalf("Failed to register worker: %v", err)
+			}
+			ms.UpdateWorkerResourcesInMemory(workerID, 8.0, 16.0, 100.0, 0.0)
+		}
+
+		// Unknown workers are refused before the task is looked up
+		if task, ack, _ := ms.RetryTaskOnWorker(context.Background(), "task-1", "worker-missing"); task != nil || ack.ErrorCode != pb.ErrorCode_WORKER_NOT_FOUND {
+			t.Errorf("Expected WORKER_NOT_FOUND for an unknown worker, got %+v", ack)
+		}
+
+		// The original task lookup, the new ID collision check, then the insert
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, bson.D{
+				{Key: "task_id", Value: "task-1"},
+				{Key: "user_id", Value: "alice@example.com"},
+				{Key: "docker_image", Value: "trainer:latest"},
+				{Key: "command", Value: "python train.py"},
+				{Key: "req_cpu", Value: 2.0},
+				{Key: "req_memory", Value: 4.0},
+				{Key: "status", Value: "failed"},
+			}),
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+		task, ack, err := ms.RetryTaskOnWorker(context.Background(), "task-1", "worker-good")
+		if err != nil || !ack.Success {
+			t.Fatalf("Expected the retry to be dispatched, got %v / %+v", err, ack)
+		}
+
+		if task.TaskId == "" || task.TaskId == "task-1" || task.OriginalTaskId != "task-1" {
+			t.Errorf("Expected a new task linked to task-1, got %s linked to %q", task.TaskId, task.OriginalTaskId)
+		}
+		if task.DockerImage != "trainer:latest" || task.Command != "python train.py" || task.ReqCpu != 2.0 {
+			t.Errorf("Expected the original spec, got %q %q %.1f CPU", task.DockerImage, task.Command, task.ReqCpu)
+		}
+		good, _ := ms.GetWorkerStats("worker-good")
+		if !good.RunningTasks[task.TaskId] {
+			t.Errorf("Expected %s to run on worker-good, got %v", task.TaskId, good.RunningTasks)
+		}
+		faulty, _ := ms.GetWorkerStats("worker-faulty")
+		if len(faulty.RunningTasks) != 0 {
+			t.Errorf("Expected nothing on worker-faulty, got %v", faulty.RunningTasks)
+		}
+		if queued := ms.GetQueuedTasks(); len(queued) != 0 {
+			t.Errorf("Expected the retry to bypass the queue, got %d queued", len(queued))
+		}
+	})
+}
+
+// rejectingWorker is a worker stub that refuses every task assignment
+type rejectingWorker struct {
+	pb.UnimplementedMasterWorkerServer
+}
+
+func (rejectingWorker) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	return &pb.TaskAck{Success: false, Message: "refused", ErrorCode: pb.ErrorCode_EXECUTION_FAILED}, nil
+}
+
+// zoneRecordingScheduler is a round-robin scheduler that records the anti-affinity placements the master reports
+type zoneRecordingScheduler struct {
+	*scheduler.RoundRobinScheduler
+	running map[string]int // "key/zone" -> tasks counted there
+}
+
+func (s *zoneRecordingScheduler) RecordZonePlacement(key, zone string) {
+	s.running[key+"/"+zone]++
+}
+
+func (s *zoneRecordingScheduler) ReleaseZonePlacement(key, zone string) {
+	s.running[key+"/"+zone]--
+}
+
+// TestZonePlacementCountsOnlyAcceptedTasks tests that an anti-affine task counts toward its zone from acceptance until it finishes
+func TestZonePlacementCountsOnlyAcceptedTasks(t *testing.T) {
+	startWorker := func(worker pb.MasterWorkerServer) string {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		grpcServer := grpc.NewServer()
+		pb.RegisterMasterWorkerServer(grpcServer, worker)
+		go grpcServer.Serve(lis)
+		t.Cleanup(grpcServer.Stop)
+		return lis.Addr().String()
+	}
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	sched := &zoneRecordingScheduler{RoundRobinScheduler: scheduler.NewRoundRobinScheduler(), running: make(map[string]int)}
+	ms.SetScheduler(sched)
+	for id, addr := range map[string]string{"worker-1": startWorker(acceptingWorker{}), "worker-2": startWorker(rejectingWorker{})} {
+		if err := ms.ManualRegisterWorker(context.Background(), id, addr); err != nil {
+			t.Fatalf("Failed to register %s: %v", id, err)
+		}
+		ms.UpdateWorkerResourcesInMemory(id, 4.0, 8.0, 100.0, 0.0)
+		ms.workers[id].Info.Zone = "zone-" + strings.TrimPrefix(id, "worker-")
+	}
+
+	// A worker that refuses the task does not count it in its zone
+	refused := &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0, AntiAffinityKey: "shard"}
+	if ack, _ := ms.assignTaskToWorker(context.Background(), refused, "worker-2"); ack.Success {
+		t.Fatal("Expected worker-2 to refuse the task")
+	}
+	if got := sched.running["shard/zone-2"]; got != 0 {
+		t.Errorf("Expected no placement counted for a refused task, got %d", got)
+	}
+
+	accepted := &pb.Task{TaskId: "task-2", ReqCpu: 1.0, ReqMemory: 1.0, AntiAffinityKey: "shard"}
+	if ack, _ := ms.assignTaskToWorker(context.Background(), accepted, "worker-1"); !ack.Success {
+		t.Fatalf("Expected worker-1 to accept the task, got %s", ack.Message)
+	}
+	if got := sched.running["shard/zone-1"]; got != 1 {
+		t.Errorf("Expected 1 placement counted in zone-1, got %d", got)
+	}
+
+	if _, err := ms.ReportTaskCompletion(context.Background(), &pb.TaskResult{TaskId: "task-2", WorkerId: "worker-1", Status: "failed"}); err != nil {
+		t.Fatalf("Failed to report completion: %v", err)
+	}
+	if got := sched.running["shard/zone-1"]; got != 0 {
+		t.Errorf("Expected the placement released once the task failed, got %d", got)
+	}
+}
+
+// TestDispatchTaskToWorkerDeletesRecordWhenRefused tests that a directly dispatched task no worker took is not left queued in the database
+func TestDispatchTaskToWorkerDeletesRecordWhenRefused(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, rejectingWorker{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("refused", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 8.0, 16.0, 100.0, 0.0)
+
+		// The ID collision check, the insert, then the delete
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		ack, err := ms.DispatchTaskToWorker(context.Background(), &pb.Task{TaskId: "task-1", DockerImage: "alpine", ReqCpu: 1, ReqMemory: 1}, "worker-1")
+		if err != nil || ack.Success {
+			t.Fatalf("Expected the dispatch to fail, got %v / %+v", err, ack)
+		}
+
+		var deleted bool
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "delete" {
+				deleted = true
+			}
+		}
+		if !deleted {
+			t.Error("Expected the refused task's record to be deleted")
+		}
+	})
+}
+
+// TestTaskRecordRoundTripKeepsSpec tests that a task stored in the database comes back with the spec it was submitted with
+func TestTaskRecordRoundTripKeepsSpec(t *testing.T) {
+	task := &pb.Task{
+		TaskId:             "task-1",
+		DockerImage:        "trainer:latest",
+		ReqCpu:             2,
+		ReqMemory:          4,
+		PinCpus:            true,
+		Cacheable:          true,
+		LocalityKey:        "dataset-7",
+		Deadline:           1900000000,
+		MaxRestarts:        3,
+		MemLimit:           8,
+		StopGracePeriodSec: 30,
+		AntiAffinityKey:    "shard",
+		PreferredWorkerId:  "worker-2",
+		ReservationId:      "res-1",
+	}
+	got := taskFromRecord(taskRecord(task, "queued"))
+	if !got.PinCpus || !got.Cacheable || got.LocalityKey != "dataset-7" {
+		t.Errorf("Expected pinning, caching and locality to survive, got %+v", got)
+	}
+	if got.Deadline != 1900000000 {
+		t.Errorf("Expected Deadline to survive, got %+v", got.Deadline)
+	}
+	if got.MaxRestarts != 3 {
+		t.Errorf("Expected MaxRestarts to survive, got %+v", got.MaxRestarts)
+	}
+	if got.MemLimit != 8 {
+		t.Errorf("Expected MemLimit to survive, got %+v", got.MemLimit)
+	}
+	if got.StopGracePeriodSec != 30 {
+		t.Errorf("Expected StopGracePeriodSec to survive, got %+v", got.StopGracePeriodSec)
+	}
+	if got.AntiAffinityKey != "shard" {
+		t.Errorf("Expected AntiAffinityKey to survive, got %+v", got.AntiAffinityKey)
+	}
+	if got.PreferredWorkerId != "worker-2" {
+		t.Errorf("Expected PreferredWorkerId to survive, got %+v", got.PreferredWorkerId)
+	}
+	if got.ReservationId != "res-1" {
+		t.Errorf("Expected ReservationId to survive, got %+v", got.ReservationId)
+	}
+}
+
+// TestCancelTimeoutCoversStopGrace tests that a cancel RPC waits longer than the grace the worker gives the task
+func TestCancelTimeoutCoversStopGrace(t *testing.T) {
+	if got := cancelTimeout(nil); got != defaultStopGracePeriod+cancelMargin {
+		t.Errorf("Expected the default grace plus margin for an unknown task, got %v", got)
+	}
+	if got := cancelTimeout(&pb.Task{StopGracePeriodSec: 120}); got != 120*time.Second+cancelMargin {
+		t.Errorf("Expected 120s plus margin, got %v", got)
+	}
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ack, err := ms.SubmitTask(context.Background(), &pb.Task{TaskId: "task-1", DockerImage: "busybox", ReqCpu: 1, ReqMemory: 1, StopGracePeriodSec: MaxStopGracePeriodSec + 1})
+	if err != nil || ack.Success || ack.ErrorCode != pb.ErrorCode_INVALID_TASK_SPEC {
+		t.Errorf("Expected a grace above the maximum to be rejected with INVALID_TASK_SPEC, got %+v (%v)", ack, err)
+	}
+}
+
+// TestHeartbeatHandledWhileCancelInFlight tests that a worker's heartbeat is processed while a long-grace cancel waits on its worker
+func TestHeartbeatHandledWhileCancelInFlight(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	stub := &slowStoppingWorker{stopping: make(chan struct{}), release: make(chan struct{})}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, stub)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	worker, _ := ms.GetWorkerStats("worker-1")
+	ms.mu.Lock()
+	worker.RunningTasks["task-1"] = true
+	ms.runningSpecs["task-1"] = &pb.Task{TaskId: "task-1", StopGracePeriodSec: MaxStopGracePeriodSec}
+	ms.mu.Unlock()
+
+	cancelled := make(chan *pb.TaskAck, 1)
+	go func() {
+		ack, _ := ms.CancelTask(context.Background(), &pb.TaskID{TaskId: "task-1"})
+		cancelled <- ack
+	}()
+	<-stub.stopping
+
+	// The worker is still waiting out the task's grace; its heartbeat must not queue behind the cancel
+	heartbeat := make(chan error, 1)
+	go func() {
+		_, err := ms.SendHeartbeat(context.Background(), &pb.Heartbeat{WorkerId: "worker-1"})
+		heartbeat <- err
+	}()
+	select {
+	case err := <-heartbeat:
+		if err != nil {
+			t.Errorf("Expected the heartbeat to be accepted, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the heartbeat to be handled while the cancel was in flight")
+	}
+
+	close(stub.release)
+	if ack := <-cancelled; ack == nil || !ack.Success {
+		t.Fatalf("Expected the cancel to succeed, got %v", ack)
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if worker.RunningTasks["task-1"] || ms.runningSpecs["task-1"] != nil {
+		t.Error("Expected task-1 to be forgotten once cancelled")
+	}
+}
+
+// TestReportTaskCompletionNotifiesWebhookOnFailure tests that a failed task posts its ID, user, status and duration
+func TestReportTaskCompletionNotifiesWebhookOnFailure(t *testing.T) {
+	received := make(chan notify.TaskEvent, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.TaskEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode webhook payload: %v", err)
+		}
+		received <- event
+	}))
+	defer webhook.Close()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("failed task", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+		ms.SetNotificationSink(notify.NewWebhookSink([]string{webhook.URL}))
+
+		taskDoc := bson.D{
+			{Key: "task_id", Value: "task-1"},
+			{Key: "user_id", Value: "alice"},
+			{Key: "status", Value: "running"},
+			{Key: "started_at", Value: time.Now().Add(-30 * time.Second)},
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, taskDoc),
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, taskDoc),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		ack, err := ms.ReportTaskCompletion(context.Background(), &pb.TaskResult{
+			TaskId:   "task-1",
+			WorkerId: "worker-1",
+			Status:   "failed",
+		})
+		if err != nil || !ack.Success {
+			t.Fatalf("ReportTaskCompletion failed: %v %+v", err, ack)
+		}
+
+		select {
+		case event := <-received:
+			if event.TaskID != "task-1" {
+				t.Errorf("Expected task_id task-1, got %q", event.TaskID)
+			}
+			if event.UserID != "alice" {
+				t.Errorf("Expected user_id alice, got %q", event.UserID)
+			}
+			if event.Status != "failed" {
+				t.Errorf("Expected status failed, got %q", event.Status)
+			}
+			if event.WorkerID != "worker-1" {
+				t.Errorf("Expected worker_id worker-1, got %q", event.WorkerID)
+			}
+			if event.DurationSeconds < 29 || event.DurationSeconds > 60 {
+				t.Errorf("Expected a duration of about 30s, got %.1f", event.DurationSeconds)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for the webhook notification")
+		}
+	})
+}
+
+// TestReleaseTaskRequeuesTask tests that a task handed back by a shutting-down worker is re-queued instead of failed
+func TestReleaseTaskRequeuesTask(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("released task", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), db.NewAssignmentDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+		worker, _ := ms.GetWorkerStats("worker-1")
+		worker.RunningTasks["task-1"] = true
+		worker.AllocatedCPU, worker.AvailableCPU = 2.0, 2.0
+		worker.AllocatedMemory, worker.AvailableMemory = 1.0, 7.0
+
+		taskDoc := bson.D{
+			{Key: "task_id", Value: "task-1"},
+			{Key: "docker_image", Value: "alpine"},
+			{Key: "status", Value: "running"},
+			{Key: "req_cpu", Value: 2.0},
+			{Key: "req_memory", Value: 1.0},
+		}
+		// The task lookup, the assignment delete, then the status update
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, taskDoc),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		ack, err := ms.ReleaseTask(context.Background(), &pb.TaskRelease{TaskId: "task-1", WorkerId: "worker-1", Reason: "worker shutting down"})
+		if err != nil || !ack.Success {
+			t.Fatalf("ReleaseTask failed: %v %+v", err, ack)
+		}
+		if worker.RunningTasks["task-1"] || worker.AllocatedCPU != 0 || worker.AvailableCPU != 4.0 {
+			t.Errorf("Expected the reservation to be released, got allocated %.1f available %.1f", worker.AllocatedCPU, worker.AvailableCPU)
+		}
+		queued := ms.GetQueuedTasks()
+		if len(queued) != 1 || queued[0].Task.TaskId != "task-1" {
+			t.Errorf("Expected task-1 to be queued, got %+v", queued)
+		}
+
+		// A task the worker is not running cannot be released
+		ack, _ = ms.ReleaseTask(context.Background(), &pb.TaskRelease{TaskId: "task-2", WorkerId: "worker-1"})
+		if ack.Success {
+			t.Error("Expected releasing a task the worker is not running to fail")
+		}
+	})
+}
+
+// fakeUploadStream replays file chunks to UploadTaskFiles and captures the ack
+type fakeUploadStream struct {
+	grpc.ServerStream
+	chunks []*pb.FileChunk
+	ack    *pb.FileUploadAck
+}
+
+func (f *fakeUploadStream) Recv() (*pb.FileChunk, error) {
+	if len(f.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := f.chunks[0]
+	f.chunks = f.chunks[1:]
+	return chunk, nil
+}
+
+func (f *fakeUploadStream) SendAndClose(ack *pb.FileUploadAck) error {
+	f.ack = ack
+	return nil
+}
+
+// TestUploadTaskFilesCommitsFilesIndividually tests that a corrupt file is discarded while the rest are stored
+func TestUploadTaskFilesCommitsFilesIndividually(t *testing.T) {
+	baseDir := t.TempDir()
+	fileStorage, err := storage.NewFileStorageService(baseDir)
+	if err != nil {
+		t.Fatalf("Failed to create file storage: %v", err)
+	}
+	ms := NewMasterServer(nil, nil, nil, nil, nil, fileStorage, nil)
+
+	checksum := func(data string) string {
+		sum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+	chunk := func(path, data, sum string, lastFile bool) *pb.FileChunk {
+		return &pb.FileChunk{
+			TaskId: "task-1", UserId: "alice", TaskName: "job", Timestamp: 1700000000,
+			FilePath: path, Data: []byte(data), IsLastChunk: true, IsLastFile: lastFile, Sha256: sum,
+		}
+	}
+
+	stream := &fakeUploadStream{chunks: []*pb.FileChunk{
+		chunk("a.txt", "alpha", checksum("alpha"), false),
+		chunk("b.txt", "corrupted", checksum("bravo"), false),
+		chunk("out/c.txt", "charlie", checksum("charlie"), true),
+	}}
+	if err := ms.UploadTaskFiles(stream); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ack := stream.ack
+	if ack == nil {
+		t.Fatal("Expected an ack")
+	}
+	if ack.Success {
+		t.Error("Expected the upload to be reported as not fully successful")
+	}
+	if ack.FilesReceived != 2 || ack.FilesFailed != 1 {
+		t.Errorf("Expected 2 stored and 1 failed, got %d stored and %d failed", ack.FilesReceived, ack.FilesFailed)
+	}
+
+	expected := []struct {
+		path    string
+		success bool
+	}{{"a.txt", true}, {"b.txt", false}, {"out/c.txt", true}}
+	if len(ack.Results) != len(expected) {
+		t.Fatalf("Expected %d per-file results, got %d", len(expected), len(ack.Results))
+	}
+	for i, want := range expected {
+		got := ack.Results[i]
+		if got.FilePath != want.path || got.Success != want.success {
+			t.Errorf("Result %d: expected %s success=%v, got %s success=%v", i, want.path, want.success, got.FilePath, got.Success)
+		}
+		if !got.Success && got.Error == "" {
+			t.Errorf("Result %d: expected an error for the failed file", i)
+		}
+	}
+
+	taskDir := fileStorage.GetTaskStoragePath("alice", "job", 1700000000, "task-1")
+	for _, path := range []string{"a.txt", "out/c.txt"} {
+		if _, err := os.Stat(filepath.Join(taskDir, path)); err != nil {
+			t.Errorf("Expected %s to be stored: %v", path, err)
+		}
+	}
+	for _, path := range []string{"b.txt", "b.txt.part"} {
+		if _, err := os.Stat(filepath.Join(taskDir, path)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be discarded, stat returned %v", path, err)
+		}
+	}
+}
+
+// TestCancelByExternalRef tests that a task submitted with an external ref can be cancelled by that ref
+func TestCancelByExternalRef(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("running task", func(mt *mtest.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		stub := &preemptibleWorker{}
+		grpcServer := grpc.NewServer()
+		pb.RegisterMasterWorkerServer(grpcServer, stub)
+		go grpcServer.Serve(lis)
+		defer grpcServer.Stop()
+
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+
+		// The ID collision check, then the insert
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+		ack, err := ms.SubmitTask(context.Background(), &pb.Task{TaskId: "task-1", DockerImage: "alpine", ExternalRef: "job-42"})
+		if err != nil || !ack.Success {
+			t.Fatalf("Expected the task to be submitted, got %v (%v)", ack, err)
+		}
+		mt.GetStartedEvent()
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		if ref := inserted.Lookup("external_ref").StringValue(); ref != "job-42" {
+			t.Fatalf("Expected external_ref job-42 to be stored, got %q", ref)
+		}
+
+		// Pretend the scheduler placed the task on worker-1
+		worker, _ := ms.GetWorkerStats("worker-1")
+		worker.RunningTasks["task-1"] = true
+
+		// The external ref lookup, then the status update
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, bson.D{
+				{Key: "task_id", Value: "task-1"},
+				{Key: "external_ref", Value: "job-42"},
+				{Key: "status", Value: "running"},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		ack, err = ms.CancelByExternalRef(context.Background(), &pb.ExternalRef{Ref: "job-42"})
+		if err != nil || !ack.Success {
+			t.Fatalf("Expected cancellation to succeed, got %v (%v)", ack, err)
+		}
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		if len(stub.cancelled) != 1 || stub.cancelled[0] != "task-1" {
+			t.Errorf("Expected the worker to stop task-1, got cancellations %v", stub.cancelled)
+		}
+		if worker.RunningTasks["task-1"] {
+			t.Errorf("Expected task-1 to no longer be running on worker-1")
+		}
+	})
+
+	mt.Run("unknown ref", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch))
+
+		ack, err := ms.CancelByExternalRef(context.Background(), &pb.ExternalRef{Ref: "job-missing"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if ack.Success || ack.ErrorCode != pb.ErrorCode_TASK_NOT_FOUND {
+			t.Errorf("Expected TASK_NOT_FOUND, got %v", ack)
+		}
+	})
+
+	mt.Run("database error", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil)
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted at shutdown"}))
+
+		ack, err := ms.CancelByExternalRef(context.Background(), &pb.ExternalRef{Ref: "job-42"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if ack.Success || ack.ErrorCode != pb.ErrorCode_DATABASE_ERROR {
+			t.Errorf("Expected DATABASE_ERROR, got %v", ack)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	pb "master/proto"
+)
+
+// baseTaskNetworks are the network modes every task may use ("" is Docker's default, bridge)
+var baseTaskNetworks = []string{"", "bridge", "none"}
+
+// SetAllowedTaskNetworks lets tasks also use these network modes or user-defined networks, e.g. host (call before serving)
+// container:<id> modes are never allowed, since they would share another container's network namespace
+func (s *MasterServer) SetAllowedTaskNetworks(networks []string) {
+	allowed := make(map[string]bool)
+	for _, network := range append(baseTaskNetworks, networks...) {
+		allowed[network] = true
+	}
+	s.mu.Lock()
+	s.allowedNetworks = allowed
+	s.mu.Unlock()
+}
+
+// ValidateTaskNetwork checks a task's network mode against the allowed networks and its port bindings for valid ports
+func (s *MasterServer) ValidateTaskNetwork(mode string, ports []string) error {
+	if strings.HasPrefix(mode, "container:") {
+		return fmt.Errorf("network mode %q is not allowed: tasks cannot join another container's network", mode)
+	}
+	s.mu.RLock()
+	allowed := s.allowedNetworks[mode]
+	s.mu.RUnlock()
+	if !allowed {
+		return fmt.Errorf("network mode %q is not allowed (allowed: bridge, none and the networks in TASK_NETWORKS)", mode)
+	}
+
+	if len(ports) > 0 && (mode == "host" || mode == "none") {
+		return fmt.Errorf("ports cannot be published with network mode %q", mode)
+	}
+	for _, spec := range ports {
+		if err := validatePortSpec(spec); err != nil {
+			return fmt.Errorf("invalid port binding %q: %w", spec, err)
+		}
+	}
+	return nil
+}
+
+// validatePortSpec checks a Docker port binding: [ip:][host_port:]container_port[/protocol], where ports may be ranges (8000-8010)
+func validatePortSpec(spec string) error {
+	rest, protocol, hasProtocol := strings.Cut(spec, "/")
+	if hasProtocol && protocol != "tcp" && protocol != "udp" && protocol != "sctp" {
+		return fmt.Errorf("protocol must be tcp, udp or sctp, not %q", protocol)
+	}
+
+	// An IPv6 host address is bracketed: [::1]:9000:8080
+	var ip string
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]:")
+		if end < 0 {
+			return fmt.Errorf("unterminated IPv6 address")
+		}
+		ip, rest = rest[1:end], rest[end+2:]
+	}
+
+	parts := strings.Split(rest, ":")
+	var hostPort, containerPort string
+	switch {
+	case len(parts) == 1 && ip == "":
+		containerPort = parts[0]
+	case len(parts) == 2 && ip == "":
+		hostPort, containerPort = parts[0], parts[1]
+		if hostPort == "" {
+			return fmt.Errorf("host port is empty")
+		}
+	case len(parts) == 3 && ip == "":
+		ip, hostPort, containerPort = parts[0], parts[1], parts[2]
+	case len(parts) == 2:
+		hostPort, containerPort = parts[0], parts[1]
+	default:
+		return fmt.Errorf("expected [ip:][host_port:]container_port[/protocol]")
+	}
+
+	if ip != "" && net.ParseIP(ip) == nil {
+		return fmt.Errorf("%q is not an IP address", ip)
+	}
+	// An empty host port with an IP lets Docker pick one
+	if hostPort != "" {
+		if err := validatePortRange(hostPort); err != nil {
+			return fmt.Errorf("host port: %w", err)
+		}
+	}
+	if err := validatePortRange(containerPort); err != nil {
+		return fmt.Errorf("container port: %w", err)
+	}
+	return nil
+}
+
+// validatePortRange checks a port number or low-high range within 1-65535
+func validatePortRange(value string) error {
+	low, high, isRange := strings.Cut(value, "-")
+	if !isRange {
+		high = low
+	}
+	lo, err := strconv.Atoi(low)
+	if err != nil || lo < 1 || lo > 65535 {
+		return fmt.Errorf("%q is not a port between 1 and 65535", value)
+	}
+	hi, err := strconv.Atoi(high)
+	if err != nil || hi < lo || hi > 65535 {
+		return fmt.Errorf("%q is not a port range between 1 and 65535", value)
+	}
+	return nil
+}
+
+// recordPublishedPorts keeps the host ports a running task's ports were published on, as reported in heartbeats
+// Caller must hold s.mu; only tasks running on the reporting worker are recorded
+func (s *MasterServer) recordPublishedPorts(worker *WorkerState, tasks []*pb.RunningTask) {
+	for _, task := range tasks {
+		if len(task.PublishedPorts) > 0 && worker.RunningTasks[task.TaskId] {
+			s.publishedPorts[task.TaskId] = task.PublishedPorts
+		}
+	}
+}
+
+// GetPublishedPorts returns where a running task's ports were published on its worker (nil once it finishes)
+func (s *MasterServer) GetPublishedPorts(taskID string) []*pb.PortMapping {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.publishedPorts[taskID]
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	pb "master/proto"
+)
+
+// TestValidateTaskNetwork tests that only allowed network modes and in-range port bindings are accepted
+func TestValidateTaskNetwork(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+
+	cases := []struct {
+		mode  string
+		ports []string
+		valid bool
+	}{
+		{"", []string{"8080"}, true},
+		{"bridge", []string{"9000:8080", "127.0.0.1:9001:8081/udp", "127.0.0.1::8082", "[::1]:9002:8083", "8000-8010"}, true},
+		{"none", nil, true},
+		{"host", nil, false},    // Not allowed until an admin lists it
+		{"backend", nil, false}, // Nor are user-defined networks
+		{"container:db", nil, false},
+		{"none", []string{"8080"}, false},
+		{"bridge", []string{"0"}, false},
+		{"bridge", []string{"70000"}, false},
+		{"bridge", []string{"9000:65536"}, false},
+		{"bridge", []string{"8010-8000"}, false},
+		{"bridge", []string{"8080/icmp"}, false},
+		{"bridge", []string{"not-an-ip:9000:8080"}, false},
+		{"bridge", []string{":8080"}, false},
+	}
+	for _, c := range cases {
+		err := ms.ValidateTaskNetwork(c.mode, c.ports)
+		if (err == nil) != c.valid {
+			t.Errorf("Mode %q ports %v: expected valid=%v, got %v", c.mode, c.ports, c.valid, err)
+		}
+	}
+
+	// Networks an admin allows can be used; container: modes never can
+	ms.SetAllowedTaskNetworks([]string{"host", "backend", "container:db"})
+	for _, mode := range []string{"host", "backend"} {
+		if err := ms.ValidateTaskNetwork(mode, nil); err != nil {
+			t.Errorf("Expected allowed network %q to be accepted, got %v", mode, err)
+		}
+	}
+	if err := ms.ValidateTaskNetwork("container:db", nil); err == nil {
+		t.Error("Expected a container: network mode to be rejected even when listed")
+	}
+	if err := ms.ValidateTaskNetwork("host", []string{"8080"}); err == nil {
+		t.Error("Expected ports to be rejected with host networking")
+	}
+}
+
+// TestSubmitTaskRejectsDisallowedNetwork tests that a task asking for an unlisted network mode is refused at submission
+func TestSubmitTaskRejectsDisallowedNetwork(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+
+	ack, err := ms.SubmitTask(context.Background(), &pb.Task{TaskId: "task-1", DockerImage: "nginx", ReqCpu: 1, ReqMemory: 1, NetworkMode: "host"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ack.Success || ack.ErrorCode != pb.ErrorCode_INVALID_TASK_SPEC {
+		t.Errorf("Expected INVALID_TASK_SPEC, got %+v", ack)
+	}
+	if len(ms.GetQueuedTasks()) != 0 {
+		t.Error("Expected the rejected task not to be queued")
+	}
+}
+
+// TestHeartbeatReportsPublishedPortsWhileRunning tests that the ports a running task published are known before it finishes
+func TestHeartbeatReportsPublishedPortsWhileRunning(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	ms.SetMinHeartbeatInterval(0)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.mu.Lock()
+	ms.workers["worker-1"].RunningTasks["task-1"] = true
+	ms.mu.Unlock()
+
+	ports := []*pb.PortMapping{{ContainerPort: "8080/tcp", HostIp: "0.0.0.0", HostPort: 32768}}
+	hb := &pb.Heartbeat{WorkerId: "worker-1", RunningTasks: []*pb.RunningTask{
+		{TaskId: "task-1", Status: "running", PublishedPorts: ports},
+		{TaskId: "task-other", Status: "running", PublishedPorts: ports}, // Not assigned to this worker
+	}}
+	if _, err := ms.SendHeartbeat(context.Background(), hb); err != nil {
+		t.Fatalf("Failed to send heartbeat: %v", err)
+	}
+
+	got := ms.GetPublishedPorts("task-1")
+	if len(got) != 1 || got[0].HostPort != 32768 {
+		t.Errorf("Expected task-1's port published on 32768, got %v", got)
+	}
+	if got := ms.GetPublishedPorts("task-other"); got != nil {
+		t.Errorf("Expected no ports for a task not running on the worker, got %v", got)
+	}
+
+	// Once the task finishes the ports are gone
+	if _, err := ms.ReportTaskCompletion(context.Background(), &pb.TaskResult{TaskId: "task-1", WorkerId: "worker-1", Status: "success"}); err != nil {
+		t.Fatalf("Failed to report completion: %v", err)
+	}
+	if got := ms.GetPublishedPorts("task-1"); got != nil {
+		t.Errorf("Expected no ports after completion, got %v", got)
+	}
+}
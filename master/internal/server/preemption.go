@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"master/internal/db"
+	"master/internal/logging"
+	"master/internal/scheduler"
+	pb "master/proto"
+)
+
+// DefaultPreemptionCooldown is the default minimum time between preemptions on the same worker
+const DefaultPreemptionCooldown = time.Minute
+
+// preemptionVictim is a running task whose eviction would let a higher-priority task fit on its worker
+type preemptionVictim struct {
+	task     *pb.Task
+	workerID string
+	workerIP string
+}
+
+// SetPreemption lets queued tasks with at least the given priority evict lower-priority running tasks (0 disables it)
+// After a preemption the worker is left alone for cooldown, so tasks are not evicted back and forth
+func (s *MasterServer) SetPreemption(priority int32, cooldown time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cooldown < 0 {
+		cooldown = 0
+	}
+	s.preemptPriority = priority
+	s.preemptCooldown = cooldown
+}
+
+// pendingPreemption is a queued task waiting for the running task evicted on its behalf to stop
+type pendingPreemption struct {
+	qt     *QueuedTask
+	victim *preemptionVictim
+}
+
+// claimPreemptionVictim picks a lower-priority running task whose eviction would let a high-priority task that
+// could not be placed fit, and marks it preempted; the caller stops it with evictForTask once queueMu is released
+// Returns nil when preemption is disabled, the task's priority is too low, or no running task qualifies
+func (s *MasterServer) claimPreemptionVictim(task *pb.Task, now time.Time) *preemptionVictim {
+	s.mu.RLock()
+	threshold := s.preemptPriority
+	s.mu.RUnlock()
+	if threshold <= 0 || task.Priority < threshold {
+		return nil
+	}
+
+	victim := s.findPreemptionVictim(task, now)
+	if victim == nil {
+		return nil
+	}
+
+	// Mark the victim before stopping it so the worker's cancellation report is not taken as a real cancellation
+	s.mu.Lock()
+	s.preempted[victim.task.TaskId] = victim.workerID
+	s.lastPreemption[victim.workerID] = now
+	s.mu.Unlock()
+	return victim
+}
+
+// evictForTask stops a claimed victim on its worker and releases its resources so task fits there
+// It makes an RPC to the worker, so it must not be called with queueMu held; the caller re-queues the victim
+// Returns false (and unmarks the victim) when the worker could not stop it
+func (s *MasterServer) evictForTask(ctx context.Context, victim *preemptionVictim, task *pb.Task) bool {
+	logging.Infof("⚡ Preempting task %s (priority %d) on %s for task %s (priority %d)",
+		victim.task.TaskId, victim.task.Priority, victim.workerID, task.TaskId, task.Priority)
+
+	if err := s.stopTaskOnWorker(ctx, victim.workerIP, victim.task.TaskId); err != nil {
+		logging.Errorf("  ✗ Failed to preempt task %s: %v", victim.task.TaskId, err)
+		s.mu.Lock()
+		delete(s.preempted, victim.task.TaskId)
+		s.mu.Unlock()
+		return false
+	}
+
+	s.releaseTaskFromWorker(ctx, victim.workerID, &db.Task{
+		TaskID:     victim.task.TaskId,
+		ReqCPU:     victim.task.ReqCpu,
+		ReqMemory:  victim.task.ReqMemory,
+		ReqStorage: victim.task.ReqStorage,
+		ReqGPU:     victim.task.ReqGpu,
+	})
+	if s.taskDB != nil {
+		if err := s.taskDB.UpdateTaskStatus(ctx, victim.task.TaskId, "pending"); err != nil {
+			logging.Warnf("  ⚠ Warning: Failed to reset preempted task %s to pending: %v", victim.task.TaskId, err)
+		}
+	}
+	s.watchers.publish(&pb.TaskStatusUpdate{
+		TaskId:   victim.task.TaskId,
+		Status:   "preempted",
+		WorkerId: victim.workerID,
+		Message:  fmt.Sprintf("Preempted by higher-priority task %s", task.TaskId),
+	})
+
+	logging.Debugf("  ✓ Task %s stopped and released from %s", victim.task.TaskId, victim.workerID)
+	return true
+}
+
+// carryOutPreemptions stops the victims claimed during a queue pass, re-queues them, and assigns each
+// high-priority task to the worker its victim freed; a task whose assignment fails stays queued
+// This function must be called without queueMu held, since it makes RPCs to the workers
+func (s *MasterServer) carryOutPreemptions(preemptions []pendingPreemption, now time.Time) {
+	for _, p := range preemptions {
+		ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout(p.victim.task))
+		if !s.evictForTask(ctx, p.victim, p.qt.Task) {
+			cancel()
+			continue
+		}
+
+		s.queueMu.Lock()
+		s.taskQueue = append(s.taskQueue, &QueuedTask{Task: p.victim.task, QueuedAt: now, LastError: "Preempted by a higher-priority task"})
+		s.publishQueuePositions()
+		s.queueMu.Unlock()
+
+		p.qt.Task.TargetWorkerId = p.victim.workerID
+		ack, err := s.assignTaskToWorker(ctx, p.qt.Task, p.victim.workerID)
+		cancel()
+		if err != nil || !ack.Success {
+			msg := ack.GetMessage()
+			if err != nil {
+				msg = err.Error()
+			}
+			s.queueMu.Lock()
+			s.recordQueueAssignmentFailure(p.qt, p.victim.workerID, msg)
+			s.queueMu.Unlock()
+			continue
+		}
+
+		logging.Infof("✓ Queue: Task %s assigned to %s after preemption", p.qt.Task.TaskId, p.victim.workerID)
+		s.schedMetrics.RecordScheduled(p.qt.Retries+1, now.Sub(p.qt.QueuedAt))
+		s.queueMu.Lock()
+		s.removeQueuedTask(p.qt)
+		s.publishQueuePositions()
+		s.queueMu.Unlock()
+	}
+}
+
+// removeQueuedTask drops a task from the queue if it is still there
+// This function assumes s.queueMu is already locked by the caller
+func (s *MasterServer) removeQueuedTask(qt *QueuedTask) {
+	for i, queued := range s.taskQueue {
+		if queued == qt {
+			s.taskQueue = append(s.taskQueue[:i], s.taskQueue[i+1:]...)
+			return
+		}
+	}
+}
+
+// forgetPreemptionsOn drops the preemption bookkeeping of a worker that left the cluster
+// This function assumes s.mu is already locked by the caller
+func (s *MasterServer) forgetPreemptionsOn(workerID string) {
+	for taskID, evictedFrom := range s.preempted {
+		if evictedFrom == workerID {
+			delete(s.preempted, taskID)
+		}
+	}
+	delete(s.lastPreemption, workerID)
+}
+
+// findPreemptionVictim picks the lowest-priority running task whose resources would let task fit on its worker
+// Only tasks with strictly lower priority, on active workers outside their preemption cooldown, are considered;
+// tasks this master has no spec for (e.g. running since before a restart) are never preempted
+func (s *MasterServer) findPreemptionVictim(task *pb.Task, now time.Time) *preemptionVictim {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var best *preemptionVictim
+	for workerID, worker := range s.workers {
+		if !worker.IsActive || worker.Cordoned || worker.Info.WorkerIp == "" {
+			continue
+		}
+		if last, ok := s.lastPreemption[workerID]; ok && now.Sub(last) < s.preemptCooldown {
+			continue
+		}
+
+		for taskID := range worker.RunningTasks {
+			// A task already claimed for another preemption is on its way out
+			if _, claimed := s.preempted[taskID]; claimed {
+				continue
+			}
+			spec, ok := s.runningSpecs[taskID]
+			if !ok || spec.Priority >= task.Priority || !s.fitsAfterEvicting(worker, spec, task) {
+				continue
+			}
+			// Lowest priority wins; ties are broken by task ID so the choice is deterministic
+			if best == nil || spec.Priority < best.task.Priority ||
+				(spec.Priority == best.task.Priority && spec.TaskId < best.task.TaskId) {
+				best = &preemptionVictim{task: spec, workerID: workerID, workerIP: worker.Info.WorkerIp}
+			}
+		}
+	}
+	return best
+}
+
+// fitsAfterEvicting reports whether task would fit on worker once victim's resources were released
+// This function assumes s.mu is already locked by the caller
+func (s *MasterServer) fitsAfterEvicting(worker *WorkerState, victim, task *pb.Task) bool {
+	cpu := scheduler.Headroom(worker.AvailableCPU+victim.ReqCpu, scheduler.Usable(worker.Info.TotalCpu, s.systemReserve.CPU), s.overcommit.CPU)
+	mem := scheduler.Headroom(worker.AvailableMemory+victim.ReqMemory, scheduler.Usable(worker.Info.TotalMemory, s.systemReserve.Memory), s.overcommit.Memory)
+	storage := scheduler.Headroom(worker.AvailableStorage+victim.ReqStorage, scheduler.Usable(worker.Info.TotalStorage, s.systemReserve.Storage), s.overcommit.Storage)
+	gpu := scheduler.Headroom(worker.AvailableGPU+victim.ReqGpu, scheduler.Usable(worker.Info.TotalGpu, s.systemReserve.GPU), s.overcommit.GPU)
+
+	return cpu >= task.ReqCpu && mem >= task.ReqMemory && storage >= task.ReqStorage && gpu >= task.ReqGpu
+}
+
+// stopTaskOnWorker asks a worker to stop a running task
+func (s *MasterServer) stopTaskOnWorker(ctx context.Context, workerIP, taskID string) error {
+	conn, err := s.dialWorker(ctx, workerIP)
+	if err != nil {
+		return fmt.Errorf("connect to worker: %w", err)
+	}
+	defer conn.Close()
+
+	ack, err := pb.NewMasterWorkerClient(conn).CancelTask(ctx, &pb.TaskID{TaskId: taskID})
+	if err != nil {
+		return fmt.Errorf("cancel task: %w", err)
+	}
+	if !ack.Success {
+		return fmt.Errorf("worker refused to stop task: %s", ack.Message)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "master/proto"
+
+	"google.golang.org/grpc"
+)
+
+// preemptibleWorker is a worker stub that accepts every assignment and records cancelled tasks
+type preemptibleWorker struct {
+	pb.UnimplementedMasterWorkerServer
+
+	mu        sync.Mutex
+	cancelled []string
+}
+
+func (w *preemptibleWorker) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	return &pb.TaskAck{Success: true, Message: "accepted"}, nil
+}
+
+func (w *preemptibleWorker) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.TaskAck, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cancelled = append(w.cancelled, taskID.TaskId)
+	return &pb.TaskAck{Success: true, Message: "Task cancelled"}, nil
+}
+
+// slowStoppingWorker is a worker stub whose CancelTask blocks until release is closed
+type slowStoppingWorker struct {
+	pb.UnimplementedMasterWorkerServer
+
+	stopping chan struct{}
+	release  chan struct{}
+}
+
+func (w *slowStoppingWorker) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	return &pb.TaskAck{Success: true, Message: "accepted"}, nil
+}
+
+func (w *slowStoppingWorker) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.TaskAck, error) {
+	close(w.stopping)
+	<-w.release
+	return &pb.TaskAck{Success: true, Message: "Task cancelled"}, nil
+}
+
+// TestPreemptionDoesNotHoldQueueWhileStopping tests that the queue stays usable while a preempted task is being stopped
+func TestPreemptionDoesNotHoldQueueWhileStopping(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	stub := &slowStoppingWorker{stopping: make(chan struct{}), release: make(chan struct{})}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, stub)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 2.0, 4.0, 10.0, 0.0)
+	ms.SetPreemption(10, time.Minute)
+
+	now := time.Now()
+	ms.EnqueueTask(&pb.Task{TaskId: "task-low", ReqCpu: 2.0, ReqMemory: 1.0, Priority: 1}, "test")
+	ms.processQueueOnce(now)
+	ms.EnqueueTask(&pb.Task{TaskId: "task-high", ReqCpu: 2.0, ReqMemory: 1.0, Priority: 10}, "test")
+
+	done := make(chan struct{})
+	go func() {
+		ms.processQueueOnce(now)
+		close(done)
+	}()
+	<-stub.stopping
+
+	// Submissions must not wait for the worker to finish stopping the victim
+	enqueued := make(chan struct{})
+	go func() {
+		ms.EnqueueTask(&pb.Task{TaskId: "task-other", ReqCpu: 1.0, ReqMemory: 1.0}, "test")
+		close(enqueued)
+	}()
+	select {
+	case <-enqueued:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected EnqueueTask to proceed while the victim was being stopped")
+	}
+
+	close(stub.release)
+	<-done
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if !worker.RunningTasks["task-high"] {
+		t.Errorf("Expected task-high to run after the preemption, got running tasks %v", worker.RunningTasks)
+	}
+}
+
+// TestUnregisterWorkerForgetsPreemptions tests that removing a worker drops the preemption records that name it
+func TestUnregisterWorkerForgetsPreemptions(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "127.0.0.1:1"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.mu.Lock()
+	ms.preempted["task-low"] = "worker-1"
+	ms.preempted["task-other"] = "worker-2"
+	ms.lastPreemption["worker-1"] = time.Now()
+	ms.mu.Unlock()
+
+	if err := ms.UnregisterWorker(context.Background(), "worker-1"); err != nil {
+		t.Fatalf("Failed to unregister worker: %v", err)
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if _, ok := ms.preempted["task-low"]; ok {
+		t.Error("Expected the preemption record for worker-1 to be dropped")
+	}
+	if _, ok := ms.preempted["task-other"]; !ok {
+		t.Error("Expected the preemption record for worker-2 to be kept")
+	}
+	if _, ok := ms.lastPreemption["worker-1"]; ok {
+		t.Error("Expected worker-1's preemption cooldown to be dropped")
+	}
+}
+
+// TestHighPriorityTaskPreemptsLowPriorityTask tests that a high-priority task evicts a low-priority one on a full worker
+func TestHighPriorityTaskPreemptsLowPriorityTask(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	stub := &preemptibleWorker{}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, stub)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 2.0, 4.0, 10.0, 0.0)
+	ms.SetPreemption(10, time.Minute)
+
+	// The low-priority task fills the worker
+	now := time.Now()
+	ms.EnqueueTask(&pb.Task{TaskId: "task-low", ReqCpu: 2.0, ReqMemory: 1.0, Priority: 1}, "test")
+	ms.processQueueOnce(now)
+
+	ms.EnqueueTask(&pb.Task{TaskId: "task-high", ReqCpu: 2.0, ReqMemory: 1.0, Priority: 10}, "test")
+	ms.processQueueOnce(now)
+
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if !worker.RunningTasks["task-high"] || worker.RunningTasks["task-low"] {
+		t.Fatalf("Expected task-high to replace task-low on worker-1, got running tasks %v", worker.RunningTasks)
+	}
+	if worker.AllocatedCPU != 2.0 {
+		t.Errorf("Expected 2.0 CPU allocated after preemption, got %.1f", worker.AllocatedCPU)
+	}
+	stub.mu.Lock()
+	if len(stub.cancelled) != 1 || stub.cancelled[0] != "task-low" {
+		t.Errorf("Expected the worker to stop task-low, got cancellations %v", stub.cancelled)
+	}
+	stub.mu.Unlock()
+
+	queued := ms.GetQueuedTasks()
+	if len(queued) != 1 || queued[0].Task.TaskId != "task-low" {
+		t.Fatalf("Expected task-low to be re-queued, got %d queued tasks", len(queued))
+	}
+
+	// The worker's cancellation report for the evicted task must not release task-high's resources
+	ack, err := ms.ReportTaskCompletion(context.Background(), &pb.TaskResult{TaskId: "task-low", WorkerId: "worker-1", Status: "cancelled"})
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected the stale report to be acknowledged, got %v (%v)", ack, err)
+	}
+	if worker.AllocatedCPU != 2.0 {
+		t.Errorf("Expected the stale report to leave 2.0 CPU allocated, got %.1f", worker.AllocatedCPU)
+	}
+
+	// Within the cooldown an even higher-priority task waits instead of evicting task-high
+	ms.EnqueueTask(&pb.Task{TaskId: "task-urgent", ReqCpu: 2.0, ReqMemory: 1.0, Priority: 20}, "test")
+	ms.processQueueOnce(now.Add(time.Second))
+	if !worker.RunningTasks["task-high"] {
+		t.Errorf("Expected task-high to keep running during the preemption cooldown")
+	}
+	if len(ms.GetQueuedTasks()) != 2 {
+		t.Errorf("Expected task-low and task-urgent to stay queued, got %d queued tasks", len(ms.GetQueuedTasks()))
+	}
+}
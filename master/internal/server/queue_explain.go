@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	pb "master/proto"
+)
+
+// WorkerFit is whether one worker could take a queued task right now, and why not
+type WorkerFit struct {
+	WorkerID string
+	Fits     bool
+	Reasons  []string // Each problem found by the same checks an assignment runs
+}
+
+// QueueExplanation describes why a queued task is still waiting
+type QueueExplanation struct {
+	Task      *pb.Task
+	QueuedAt  time.Time
+	Position  int // 1-based position in the queue
+	Retries   int
+	LastError string
+	Workers   []WorkerFit // Sorted by worker ID
+}
+
+// ExplainQueuedTask reports a queued task's retries and last error, and checks it against every
+// registered worker's current state with the same rules as an assignment
+func (s *MasterServer) ExplainQueuedTask(taskID string) (*QueueExplanation, error) {
+	s.queueMu.RLock()
+	var explanation *QueueExplanation
+	for i, qt := range s.taskQueue {
+		if qt.Task.TaskId == taskID {
+			explanation = &QueueExplanation{
+				Task:      qt.Task,
+				QueuedAt:  qt.QueuedAt,
+				Position:  i + 1,
+				Retries:   qt.Retries,
+				LastError: qt.LastError,
+			}
+			break
+		}
+	}
+	s.queueMu.RUnlock()
+	if explanation == nil {
+		return nil, fmt.Errorf("task %s is not queued", taskID)
+	}
+
+	s.mu.RLock()
+	for workerID, worker := range s.workers {
+		fit := WorkerFit{WorkerID: workerID, Fits: true}
+		for _, rejection := range s.workerRejections(explanation.Task, workerID, worker) {
+			fit.Fits = false
+			fit.Reasons = append(fit.Reasons, rejection.Message)
+		}
+		explanation.Workers = append(explanation.Workers, fit)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(explanation.Workers, func(i, j int) bool {
+		return explanation.Workers[i].WorkerID < explanation.Workers[j].WorkerID
+	})
+	return explanation, nil
+}
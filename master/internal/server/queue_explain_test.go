@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	pb "master/proto"
+)
+
+// TestExplainQueuedTaskReportsPerWorkerShortfall tests that a task too big for every worker reports what each worker lacks
+func TestExplainQueuedTaskReportsPerWorkerShortfall(t *testing.T) {
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	for id, addr := range map[string]string{"worker-1": "10.0.0.1:50052", "worker-2": "10.0.0.2:50052"} {
+		if err := ms.ManualRegisterWorker(context.Background(), id, addr); err != nil {
+			t.Fatalf("Failed to register %s: %v", id, err)
+		}
+		worker, _ := ms.GetWorkerStats(id)
+		worker.IsActive = true
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 32.0, 100.0, 0.0)
+	ms.UpdateWorkerResourcesInMemory("worker-2", 16.0, 8.0, 100.0, 1.0)
+
+	ms.EnqueueTask(&pb.Task{TaskId: "task-big", ReqCpu: 8.0, ReqMemory: 16.0, ReqGpu: 1.0}, "No suitable worker")
+
+	explanation, err := ms.ExplainQueuedTask("task-big")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if explanation.Position != 1 || explanation.LastError != "No suitable worker" {
+		t.Errorf("Expected position 1 and the queue reason, got %d and %q", explanation.Position, explanation.LastError)
+	}
+
+	expected := map[string][]string{
+		"worker-1": {
+			"Insufficient CPU: worker has 4.00 available, task requires 8.00",
+			"Insufficient GPU: worker has 0.00 available, task requires 1.00",
+		},
+		"worker-2": {
+			"Insufficient Memory: worker has 8.00 GB available, task requires 16.00 GB",
+		},
+	}
+	if len(explanation.Workers) != len(expected) {
+		t.Fatalf("Expected %d workers, got %d", len(expected), len(explanation.Workers))
+	}
+	for _, fit := range explanation.Workers {
+		if fit.Fits {
+			t.Errorf("Expected %s not to fit", fit.WorkerID)
+		}
+		want := expected[fit.WorkerID]
+		if len(fit.Reasons) != len(want) {
+			t.Errorf("Expected %s to report %v, got %v", fit.WorkerID, want, fit.Reasons)
+			continue
+		}
+		for i := range want {
+			if fit.Reasons[i] != want[i] {
+				t.Errorf("Expected %s reason %q, got %q", fit.WorkerID, want[i], fit.Reasons[i])
+			}
+		}
+	}
+
+	if _, err := ms.ExplainQueuedTask("task-missing"); err == nil {
+		t.Error("Expected an error for a task that is not queued")
+	}
+}
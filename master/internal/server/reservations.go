@@ -0,0 +1,126 @@
+package server
+
+import (
+	"time"
+
+	"master/internal/logging"
+	pb "master/proto"
+)
+
+// DefaultReservationTTL bounds how long a tentative reservation may hold a worker's resources
+// It outlives the assignment RPC timeout, so it only fires when an assignment never reports back
+const DefaultReservationTTL = 30 * time.Second
+
+// pendingReservation is a resource hold taken when a worker is selected for a task, before the worker acks it
+// While pending, later selections see the resources as taken; it is committed on ack and released on failure or expiry
+// A committed reservation is kept until the task is stored as running, so a reconcile in between still counts it
+type pendingReservation struct {
+	task      *pb.Task
+	worker    *WorkerState
+	workerID  string
+	expiresAt time.Time
+	committed bool // The worker accepted the task; its allocation is final
+
+	// What the task took from its capacity reservation, given back if the assignment fails
+	drawn   bool     // Charged against the reservation's remaining capacity
+	claimed Capacity // Taken over from the reservation's hold on the worker
+}
+
+// trackReservation records a tentative reservation made by reserveTaskOnWorker
+// Caller must hold s.mu
+func (s *MasterServer) trackReservation(task *pb.Task, worker *WorkerState, workerID string, now time.Time, drawn bool, claimed Capacity) {
+	s.pendingReservations[task.TaskId] = &pendingReservation{
+		task:      task,
+		worker:    worker,
+		workerID:  workerID,
+		expiresAt: now.Add(s.reservationTTL),
+		drawn:     drawn,
+		claimed:   claimed,
+	}
+}
+
+// commitReservation turns a task's tentative reservation into a regular allocation once the worker accepted it
+// Returns false if the reservation already expired: its resources were released and are not allocated again
+// The reservation stays tracked until settleReservation, once the task is stored as running
+// Caller must hold s.mu
+func (s *MasterServer) commitReservation(task *pb.Task) bool {
+	r, ok := s.pendingReservations[task.TaskId]
+	if !ok {
+		return false
+	}
+	r.committed = true
+	return true
+}
+
+// settleReservation stops tracking a committed reservation once the database shows its task as running
+func (s *MasterServer) settleReservation(task *pb.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.pendingReservations[task.TaskId]; ok && r.committed {
+		delete(s.pendingReservations, task.TaskId)
+	}
+}
+
+// addInFlightReservations adds the assignments to a worker that the database does not show as running yet:
+// their resources to alloc, and the tasks the worker already accepted to taskIDs
+// Tasks already in taskIDs were counted from the database and are skipped
+// Caller must hold s.mu
+func (s *MasterServer) addInFlightReservations(workerID string, alloc *ResourceAllocation, taskIDs map[string]bool) {
+	for taskID, r := range s.pendingReservations {
+		if r.workerID != workerID || taskIDs[taskID] {
+			continue
+		}
+		alloc.CPU += r.task.ReqCpu
+		alloc.Memory += r.task.ReqMemory
+		alloc.Storage += r.task.ReqStorage
+		alloc.GPU += r.task.ReqGpu
+		if r.committed {
+			taskIDs[taskID] = true
+		}
+	}
+}
+
+// releaseReservation returns a tentative reservation's resources to its worker and its capacity reservation
+// Caller must hold s.mu
+func (s *MasterServer) releaseReservation(r *pendingReservation) {
+	delete(s.pendingReservations, r.task.TaskId)
+	adjustAllocation(r.worker, r.task, -1)
+
+	// Give back what the task took from its capacity reservation
+	s.restoreReservedCapacity(r.task, r.workerID, r.worker, r.claimed)
+	if r.drawn {
+		s.refundCapacityReservation(r.task)
+	}
+}
+
+// releaseExpiredReservations returns the resources of tentative reservations that outlived their TTL
+// Returns the number of reservations released
+func (s *MasterServer) releaseExpiredReservations(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	released := 0
+	for taskID, r := range s.pendingReservations {
+		if r.committed || now.Before(r.expiresAt) {
+			continue
+		}
+		s.releaseReservation(r)
+		released++
+		logging.Infof("⏰ Reservation for task %s on %s expired - released %.2f CPU, %.2f GB memory",
+			taskID, r.workerID, r.task.ReqCpu, r.task.ReqMemory)
+	}
+	return released
+}
+
+// adjustAllocation adds (sign 1) or returns (sign -1) a task's resources on a worker
+// Caller must hold s.mu
+func adjustAllocation(worker *WorkerState, task *pb.Task, sign float64) {
+	worker.AllocatedCPU += sign * task.ReqCpu
+	worker.AllocatedMemory += sign * task.ReqMemory
+	worker.AllocatedStorage += sign * task.ReqStorage
+	worker.AllocatedGPU += sign * task.ReqGpu
+	worker.AvailableCPU -= sign * task.ReqCpu
+	worker.AvailableMemory -= sign * task.ReqMemory
+	worker.AvailableStorage -= sign * task.ReqStorage
+	worker.AvailableGPU -= sign * task.ReqGpu
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"master/internal/db"
+	pb "master/proto"
+
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"google.golang.org/grpc"
+)
+
+// newReservationTestServer registers one accepting worker with the given CPU and returns the master
+func newReservationTestServer(t *testing.T, cpu float64) *MasterServer {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, acceptingWorker{})
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", cpu, 8.0, 100.0, 0.0)
+	return ms
+}
+
+// TestProcessQueueDoesNotDoubleBookWorker tests that two tasks in one pass are not both assigned to a worker that fits one
+func TestProcessQueueDoesNotDoubleBookWorker(t *testing.T) {
+	ms := newReservationTestServer(t, 1.0)
+	ms.SetQueueConcurrency(2)
+
+	// A slow dial keeps the first assignment in flight while the second task is selected
+	ms.dialWorker = func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+		time.Sleep(100 * time.Millisecond)
+		return ms.dialWorkerBlocking(ctx, addr)
+	}
+
+	ms.EnqueueTask(&pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0}, "test")
+	ms.EnqueueTask(&pb.Task{TaskId: "task-2", ReqCpu: 1.0, ReqMemory: 1.0}, "test")
+	ms.processQueueOnce(time.Now())
+
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if len(worker.RunningTasks) != 1 || !worker.RunningTasks["task-1"] {
+		t.Fatalf("Expected only task-1 to run on worker-1, got %v", worker.RunningTasks)
+	}
+	if worker.AllocatedCPU != 1.0 || worker.AvailableCPU != 0 {
+		t.Errorf("Expected 1.0 CPU allocated and 0 available, got %.1f allocated and %.1f available", worker.AllocatedCPU, worker.AvailableCPU)
+	}
+	if queued := ms.GetQueuedTasks(); len(queued) != 1 || queued[0].Task.TaskId != "task-2" {
+		t.Fatalf("Expected task-2 to stay queued, got %d queued", len(queued))
+	}
+	if len(ms.pendingReservations) != 0 {
+		t.Errorf("Expected no pending reservations after the pass, got %d", len(ms.pendingReservations))
+	}
+}
+
+// TestExpiredReservationIsReleasedOnce tests that a reservation past its TTL returns its resources exactly once
+func TestExpiredReservationIsReleasedOnce(t *testing.T) {
+	ms := newReservationTestServer(t, 2.0)
+	task := &pb.Task{TaskId: "task-stuck", ReqCpu: 1.5, ReqMemory: 1.0}
+
+	worker, _, nack := ms.reserveTaskOnWorker(task, "worker-1")
+	if nack != nil {
+		t.Fatalf("Expected reservation to succeed, got %s", nack.Message)
+	}
+	if worker.AvailableCPU != 0.5 {
+		t.Fatalf("Expected 0.5 CPU available while reserved, got %.1f", worker.AvailableCPU)
+	}
+
+	// Not yet expired
+	if released := ms.releaseExpiredReservations(time.Now()); released != 0 {
+		t.Errorf("Expected no reservation released before the TTL, got %d", released)
+	}
+
+	if released := ms.releaseExpiredReservations(time.Now().Add(ms.reservationTTL)); released != 1 {
+		t.Fatalf("Expected 1 reservation released after the TTL, got %d", released)
+	}
+	if worker.AvailableCPU != 2.0 || worker.AllocatedCPU != 0 {
+		t.Errorf("Expected 2.0 CPU available after expiry, got %.1f available and %.1f allocated", worker.AvailableCPU, worker.AllocatedCPU)
+	}
+
+	// The late failure path must not return the resources a second time
+	ms.unreserveTaskOnWorker(task, worker)
+	if worker.AvailableCPU != 2.0 {
+		t.Errorf("Expected 2.0 CPU available after a late unreserve, got %.1f", worker.AvailableCPU)
+	}
+}
+
+// countingWorker is a worker stub that accepts every task and counts the assignments it received
+type countingWorker struct {
+	pb.UnimplementedMasterWorkerServer
+	assigned *atomic.Int32
+}
+
+func (w countingWorker) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	w.assigned.Add(1)
+	return &pb.TaskAck{Success: true, Message: "accepted"}, nil
+}
+
+// TestUnregisterDuringAssignmentRequeuesTask tests that a worker unregistered while its assignment is dialing never receives the task
+func TestUnregisterDuringAssignmentRequeuesTask(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	var assigned atomic.Int32
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, countingWorker{assigned: &assigned})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 2.0, 8.0, 100.0, 0.0)
+
+	// The worker is unregistered after it was selected and reserved, while the dial is in flight
+	ms.dialWorker = func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+		if err := ms.UnregisterWorker(ctx, "worker-1"); err != nil {
+			t.Errorf("Failed to unregister worker: %v", err)
+		}
+		return ms.dialWorkerBlocking(ctx, addr)
+	}
+
+	ms.EnqueueTask(&pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0}, "test")
+	ms.processQueueOnce(time.Now())
+
+	if n := assigned.Load(); n != 0 {
+		t.Fatalf("Expected the unregistered worker to receive no task, got %d", n)
+	}
+	queued := ms.GetQueuedTasks()
+	if len(queued) != 1 || queued[0].Task.TaskId != "task-1" {
+		t.Fatalf("Expected task-1 to be re-queued, got %d queued", len(queued))
+	}
+	if queued[0].Retries != 1 {
+		t.Errorf("Expected the aborted assignment to count as 1 failed attempt, got %d", queued[0].Retries)
+	}
+	if len(ms.pendingReservations) != 0 {
+		t.Errorf("Expected the reservation to be released, got %d pending", len(ms.pendingReservations))
+	}
+}
+
+// lateAckWorker is a worker stub that lets the task's reservation expire before accepting it, and records cancellations
+type lateAckWorker struct {
+	pb.UnimplementedMasterWorkerServer
+	ms        *MasterServer
+	cancelled chan string
+}
+
+func (w lateAckWorker) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskAck, error) {
+	w.ms.releaseExpiredReservations(time.Now().Add(w.ms.reservationTTL))
+	return &pb.TaskAck{Success: true, Message: "accepted"}, nil
+}
+
+func (w lateAckWorker) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.TaskAck, error) {
+	w.cancelled <- taskID.TaskId
+	return &pb.TaskAck{Success: true}, nil
+}
+
+// TestAckAfterReservationExpiredIsNotReallocated tests that a worker accepting a task after its reservation expired
+// is told to stop it, and the released resources are not allocated again
+func TestAckAfterReservationExpiredIsNotReallocated(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	stub := lateAckWorker{ms: ms, cancelled: make(chan string, 1)}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, stub)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", lis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 2.0, 8.0, 100.0, 0.0)
+
+	ms.EnqueueTask(&pb.Task{TaskId: "task-1", ReqCpu: 1.5, ReqMemory: 1.0}, "test")
+	ms.processQueueOnce(time.Now())
+
+	select {
+	case taskID := <-stub.cancelled:
+		if taskID != "task-1" {
+			t.Errorf("Expected task-1 to be stopped on the worker, got %s", taskID)
+		}
+	default:
+		t.Fatal("Expected the late-accepted task to be stopped on the worker")
+	}
+	worker, _ := ms.GetWorkerStats("worker-1")
+	if worker.RunningTasks["task-1"] {
+		t.Error("Expected task-1 not to be tracked as running")
+	}
+	if worker.AvailableCPU != 2.0 || worker.AllocatedCPU != 0 {
+		t.Errorf("Expected 2.0 CPU available and none allocated, got %.1f available and %.1f allocated", worker.AvailableCPU, worker.AllocatedCPU)
+	}
+	if queued := ms.GetQueuedTasks(); len(queued) != 1 || queued[0].Task.TaskId != "task-1" {
+		t.Fatalf("Expected task-1 to be re-queued, got %d queued", len(queued))
+	}
+}
+
+// TestReconcileDuringAssignmentKeepsReservations tests that reconciling a worker while a task is being assigned to it
+// keeps the task's resources, and keeps the task running once the worker accepted it, so nothing is released twice
+func TestReconcileDuringAssignmentKeepsReservations(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("in flight", func(mt *mtest.T) {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), db.NewAssignmentDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+		reconcile := func() {
+			t.Helper()
+			// The database shows no running tasks: the assignment has not been stored yet
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch))
+			if _, err := ms.ReconcileWorker(context.Background(), "worker-1"); err != nil {
+				t.Fatalf("Failed to reconcile worker: %v", err)
+			}
+		}
+
+		task := &pb.Task{TaskId: "task-1", ReqCpu: 1.5, ReqMemory: 1.0}
+		worker, _, nack := ms.reserveTaskOnWorker(task, "worker-1")
+		if nack != nil {
+			t.Fatalf("Expected reservation to succeed, got %s", nack.Message)
+		}
+
+		// Selected but not yet acked: the resources stay reserved
+		reconcile()
+		if worker.AllocatedCPU != 1.5 || worker.AvailableCPU != 2.5 {
+			t.Fatalf("Expected 1.5 CPU to stay reserved, got %.1f allocated and %.1f available", worker.AllocatedCPU, worker.AvailableCPU)
+		}
+
+		// Acked but not yet stored as running: the task stays on the worker
+		ms.mu.Lock()
+		if !ms.commitReservation(task) {
+			t.Fatal("Expected the reservation to be committed")
+		}
+		worker.RunningTasks[task.TaskId] = true
+		ms.mu.Unlock()
+		reconcile()
+		if !worker.RunningTasks[task.TaskId] || worker.AllocatedCPU != 1.5 {
+			t.Fatalf("Expected task-1 to stay running with 1.5 CPU allocated, got running=%v and %.1f allocated",
+				worker.RunningTasks[task.TaskId], worker.AllocatedCPU)
+		}
+
+		// Neither expiry nor a late unreserve hands back the committed allocation
+		ms.settleReservation(task)
+		ms.unreserveTaskOnWorker(task, worker)
+		if released := ms.releaseExpiredReservations(time.Now().Add(ms.reservationTTL)); released != 0 {
+			t.Errorf("Expected no reservation to expire after commit, got %d", released)
+		}
+		if worker.AllocatedCPU != 1.5 || len(ms.pendingReservations) != 0 {
+			t.Errorf("Expected 1.5 CPU allocated and no pending reservations, got %.1f and %d", worker.AllocatedCPU, len(ms.pendingReservations))
+		}
+	})
+}
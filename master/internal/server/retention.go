@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"master/internal/db"
+	"master/internal/logging"
+)
+
+// DefaultRetentionSweepInterval is how often finished tasks are checked against the retention period
+const DefaultRetentionSweepInterval = time.Hour
+
+// retentionStatuses are the finished task statuses the retention policy deletes
+var retentionStatuses = []string{"completed", "failed", "cancelled", "crashloop", "expired"}
+
+// RetentionReport reports the outcome of a retention sweep
+type RetentionReport struct {
+	Cutoff  time.Time
+	DryRun  bool
+	Checked int      // Finished tasks created before the cutoff
+	Expired []string // Tasks that finished before the cutoff
+	Deleted []string // Expired tasks deleted with their results, assignments and files
+	Failed  []string // Expired tasks that could not be (fully) deleted; retried on the next sweep
+}
+
+// taskFinishedAt returns when a task finished; cancelled and expired tasks have no completion time and fall back to their start or creation
+func taskFinishedAt(task *db.Task) time.Time {
+	for _, t := range []time.Time{task.CompletedAt, task.StartedAt} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return task.CreatedAt
+}
+
+// expiredTask reports whether a task is finished and finished before cutoff
+func expiredTask(task *db.Task, cutoff time.Time) bool {
+	for _, status := range retentionStatuses {
+		if task.Status == status {
+			return taskFinishedAt(task).Before(cutoff)
+		}
+	}
+	return false
+}
+
+// SweepExpiredTasks deletes finished tasks that finished more than retention ago, with their results, assignments and files
+// In dry-run mode nothing is deleted; the expired tasks are only logged and reported
+func (s *MasterServer) SweepExpiredTasks(ctx context.Context, retention time.Duration, dryRun bool) (*RetentionReport, error) {
+	if s.taskDB == nil {
+		return nil, fmt.Errorf("task database not available")
+	}
+
+	cutoff := time.Now().Add(-retention)
+	tasks, err := s.taskDB.GetTasksCreatedBefore(ctx, retentionStatuses, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("get finished tasks: %w", err)
+	}
+
+	report := &RetentionReport{Cutoff: cutoff, DryRun: dryRun, Checked: len(tasks), Expired: []string{}, Deleted: []string{}, Failed: []string{}}
+	for _, task := range tasks {
+		if !expiredTask(task, cutoff) {
+			continue
+		}
+		report.Expired = append(report.Expired, task.TaskID)
+		if dryRun {
+			logging.Infof("🗑 [dry run] Would delete task %s (%s, finished %s)",
+				task.TaskID, task.Status, taskFinishedAt(task).Format(time.RFC3339))
+			continue
+		}
+
+		if err := s.deleteExpiredTask(ctx, task); err != nil {
+			logging.Warnf("⚠ Failed to delete expired task %s: %v", task.TaskID, err)
+			report.Failed = append(report.Failed, task.TaskID)
+			continue
+		}
+		report.Deleted = append(report.Deleted, task.TaskID)
+	}
+
+	return report, nil
+}
+
+// deleteExpiredTask deletes a task's files, results and assignment, then the task itself
+// The task record goes last so a partly deleted task is found again by the next sweep
+func (s *MasterServer) deleteExpiredTask(ctx context.Context, task *db.Task) error {
+	if s.fileMetadataDB != nil {
+		metadata, err := s.fileMetadataDB.GetFileMetadataByTask(ctx, task.TaskID)
+		if err != nil {
+			return fmt.Errorf("get file metadata: %w", err)
+		}
+		if metadata != nil {
+			if s.fileStorage != nil {
+				if err := s.fileStorage.DeleteTaskFiles(metadata.UserID, task.TaskID); err != nil {
+					return fmt.Errorf("delete files: %w", err)
+				}
+			}
+			if err := s.fileMetadataDB.DeleteFileMetadata(ctx, task.TaskID); err != nil {
+				return fmt.Errorf("delete file metadata: %w", err)
+			}
+		}
+	}
+
+	if s.resultDB != nil {
+		if err := s.resultDB.DeleteResults(ctx, task.TaskID); err != nil {
+			return fmt.Errorf("delete results: %w", err)
+		}
+	}
+
+	// Most finished tasks have had their assignment removed already
+	if s.assignmentDB != nil {
+		if err := s.assignmentDB.DeleteAssignment(ctx, task.TaskID); err != nil {
+			logging.Debugf("No assignment deleted for task %s: %v", task.TaskID, err)
+		}
+	}
+
+	if err := s.taskDB.DeleteTask(ctx, task.TaskID); err != nil {
+		return fmt.Errorf("delete task: %w", err)
+	}
+	return nil
+}
+
+// StartRetentionSweeper periodically deletes finished tasks older than retention until StopRetentionSweeper is called
+func (s *MasterServer) StartRetentionSweeper(interval, retention time.Duration, dryRun bool) {
+	s.retentionTicker = time.NewTicker(interval)
+	s.retentionStop = make(chan bool)
+
+	sweep := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		report, err := s.SweepExpiredTasks(ctx, retention, dryRun)
+		if err != nil {
+			logging.Warnf("⚠ Retention sweep failed: %v", err)
+		} else if dryRun && len(report.Expired) > 0 {
+			logging.Infof("🗑 [dry run] %d finished task(s) older than %s would be deleted", len(report.Expired), retention)
+		} else if len(report.Deleted)+len(report.Failed) > 0 {
+			logging.Infof("🗑 Retention sweep: deleted %d task(s), %d failed", len(report.Deleted), len(report.Failed))
+		}
+	}
+
+	go func() {
+		logging.Infof("🗑 Retention sweeper started (retention: %s, interval: %s, dry run: %v)", retention, interval, dryRun)
+		sweep()
+		for {
+			select {
+			case <-s.retentionTicker.C:
+				sweep()
+			case <-s.retentionStop:
+				logging.Info("🛑 Retention sweeper stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopRetentionSweeper stops the periodic retention sweeper
+func (s *MasterServer) StopRetentionSweeper() {
+	if s.retentionTicker != nil {
+		s.retentionTicker.Stop()
+	}
+	if s.retentionStop != nil {
+		close(s.retentionStop)
+	}
+}
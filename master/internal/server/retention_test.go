@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"master/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestSweepExpiredTasks tests that only finished tasks older than the retention are deleted and running tasks are kept
+func TestSweepExpiredTasks(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	now := time.Now()
+	daysAgo := func(days int) time.Time { return now.Add(-time.Duration(days) * 24 * time.Hour) }
+	taskDocs := []bson.D{
+		bson.D{{Key: "task_id", Value: "old-completed"}, {Key: "status", Value: "completed"},
+			{Key: "created_at", Value: daysAgo(40)}, {Key: "completed_at", Value: daysAgo(35)}},
+		bson.D{{Key: "task_id", Value: "old-running"}, {Key: "status", Value: "running"},
+			{Key: "created_at", Value: daysAgo(40)}, {Key: "started_at", Value: daysAgo(40)}},
+		bson.D{{Key: "task_id", Value: "recent-failed"}, {Key: "status", Value: "failed"},
+			{Key: "created_at", Value: daysAgo(40)}, {Key: "completed_at", Value: daysAgo(1)}},
+		bson.D{{Key: "task_id", Value: "old-cancelled"}, {Key: "status", Value: "cancelled"},
+			{Key: "created_at", Value: daysAgo(40)}},
+		bson.D{{Key: "task_id", Value: "old-expired"}, {Key: "status", Value: "expired"},
+			{Key: "created_at", Value: daysAgo(40)}},
+	}
+	newServer := func(mt *mtest.T) *MasterServer {
+		return NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), db.NewAssignmentDBFromClient(mt.Client, "cloudai"),
+			db.NewResultDBFromClient(mt.Client, "cloudai"), nil, nil, nil)
+	}
+	retention := 30 * 24 * time.Hour
+
+	mt.Run("delete", func(mt *mtest.T) {
+		ms := newServer(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, taskDocs...))
+		// Results, assignment and task deletes for each of the three expired tasks
+		for i := 0; i < 3*3; i++ {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		}
+
+		report, err := ms.SweepExpiredTasks(context.Background(), retention, false)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(report.Deleted) != 3 || report.Deleted[0] != "old-completed" || report.Deleted[1] != "old-cancelled" ||
+			report.Deleted[2] != "old-expired" {
+			t.Errorf("Expected old-completed, old-cancelled and old-expired to be deleted, got %v", report.Deleted)
+		}
+		if len(report.Failed) != 0 {
+			t.Errorf("Expected no failures, got %v", report.Failed)
+		}
+	})
+
+	mt.Run("dry run", func(mt *mtest.T) {
+		ms := newServer(mt)
+		// No delete responses: a delete in dry-run mode would fail the task
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, taskDocs...))
+
+		report, err := ms.SweepExpiredTasks(context.Background(), retention, true)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(report.Expired) != 3 || len(report.Deleted) != 0 || len(report.Failed) != 0 {
+			t.Errorf("Expected 3 expired and nothing deleted, got %+v", report)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// stateDiffTolerance absorbs float rounding between in-memory and stored resource values
+const stateDiffTolerance = 1e-6
+
+// WorkerStateDiff is one place where a worker's in-memory state disagrees with the database
+type WorkerStateDiff struct {
+	WorkerID string
+	Field    string  // Resource field (e.g. "allocated_cpu"), or "worker" when one side is missing the worker
+	Memory   float64 // In-memory value
+	Database float64 // Stored value
+	Detail   string  // Set for "worker" diffs: which side is missing it
+}
+
+// DiffWorkerState compares each worker's allocated and available resources in memory against the database
+// Discrepancies are only reported; use reconcile or fix-resources to correct them
+func (s *MasterServer) DiffWorkerState(ctx context.Context) ([]WorkerStateDiff, error) {
+	if s.workerDB == nil {
+		return nil, fmt.Errorf("worker database not available")
+	}
+
+	docs, err := s.workerDB.GetAllWorkersUncached(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load workers from database: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	diffs := []WorkerStateDiff{}
+	stored := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		stored[doc.WorkerID] = true
+
+		worker, exists := s.workers[doc.WorkerID]
+		if !exists {
+			diffs = append(diffs, WorkerStateDiff{WorkerID: doc.WorkerID, Field: "worker", Detail: "in database but not in memory"})
+			continue
+		}
+
+		fields := []struct {
+			name         string
+			memory, disk float64
+		}{
+			{"allocated_cpu", worker.AllocatedCPU, doc.AllocatedCPU},
+			{"allocated_memory", worker.AllocatedMemory, doc.AllocatedMemory},
+			{"allocated_storage", worker.AllocatedStorage, doc.AllocatedStorage},
+			{"allocated_gpu", worker.AllocatedGPU, doc.AllocatedGPU},
+			{"available_cpu", worker.AvailableCPU, doc.AvailableCPU},
+			{"available_memory", worker.AvailableMemory, doc.AvailableMemory},
+			{"available_storage", worker.AvailableStorage, doc.AvailableStorage},
+			{"available_gpu", worker.AvailableGPU, doc.AvailableGPU},
+		}
+		for _, f := range fields {
+			if math.Abs(f.memory-f.disk) > stateDiffTolerance {
+				diffs = append(diffs, WorkerStateDiff{WorkerID: doc.WorkerID, Field: f.name, Memory: f.memory, Database: f.disk})
+			}
+		}
+	}
+
+	for workerID := range s.workers {
+		if !stored[workerID] {
+			diffs = append(diffs, WorkerStateDiff{WorkerID: workerID, Field: "worker", Detail: "in memory but not in database"})
+		}
+	}
+
+	// Keep each worker's fields in the order above
+	sort.SliceStable(diffs, func(i, j int) bool { return diffs[i].WorkerID < diffs[j].WorkerID })
+	return diffs, nil
+}
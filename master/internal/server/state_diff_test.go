@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"master/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestDiffWorkerStateReportsDivergentAllocation tests that a stored allocation differing from memory is reported without being fixed
+func TestDiffWorkerStateReportsDivergentAllocation(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("divergent allocation", func(mt *mtest.T) {
+		ms := NewMasterServer(db.NewWorkerDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil, nil)
+		ms.workers["worker-1"] = &WorkerState{
+			AllocatedCPU:    2.0,
+			AvailableCPU:    2.0,
+			AllocatedMemory: 1.0,
+			AvailableMemory: 7.0,
+			RunningTasks:    make(map[string]bool),
+		}
+
+		// The database still thinks 3 CPUs are allocated; memory agrees
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "cloudai.WORKERS", mtest.FirstBatch, bson.D{
+			{Key: "worker_id", Value: "worker-1"},
+			{Key: "allocated_cpu", Value: 3.0},
+			{Key: "available_cpu", Value: 1.0},
+			{Key: "allocated_memory", Value: 1.0},
+			{Key: "available_memory", Value: 7.0},
+		}))
+
+		diffs, err := ms.DiffWorkerState(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(diffs) != 2 {
+			t.Fatalf("Expected 2 discrepancies, got %d: %+v", len(diffs), diffs)
+		}
+		if d := diffs[0]; d.WorkerID != "worker-1" || d.Field != "allocated_cpu" || d.Memory != 2.0 || d.Database != 3.0 {
+			t.Errorf("Expected allocated_cpu 2.0 in memory vs 3.0 in database, got %+v", d)
+		}
+		if d := diffs[1]; d.Field != "available_cpu" || d.Memory != 2.0 || d.Database != 1.0 {
+			t.Errorf("Expected available_cpu 2.0 in memory vs 1.0 in database, got %+v", d)
+		}
+
+		// Reporting leaves the in-memory state untouched
+		if worker := ms.workers["worker-1"]; worker.AllocatedCPU != 2.0 {
+			t.Errorf("Expected in-memory allocation to stay 2.0, got %.1f", worker.AllocatedCPU)
+		}
+	})
+}
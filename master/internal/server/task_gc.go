@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"master/internal/db"
+	"master/internal/logging"
+)
+
+// Policies for running tasks whose worker is gone
+const (
+	TaskGCPolicyFail    = "fail"    // Mark the task failed
+	TaskGCPolicyRequeue = "requeue" // Put the task back on the queue
+)
+
+// TaskGCReport reports the outcome of a stuck-task collection
+type TaskGCReport struct {
+	Policy   string
+	Checked  int      // Running tasks examined
+	Failed   []string // Stuck tasks marked failed
+	Requeued []string // Stuck tasks put back on the queue
+	Skipped  []string // Stuck tasks that could not be cleaned up
+}
+
+// ValidTaskGCPolicy reports whether policy is a known stuck-task policy
+func ValidTaskGCPolicy(policy string) bool {
+	return policy == TaskGCPolicyFail || policy == TaskGCPolicyRequeue
+}
+
+// CollectStuckTasks finds tasks still "running" whose assigned worker is inactive or no longer registered.
+// Each one has its phantom reservation released and is then failed or requeued according to policy.
+func (s *MasterServer) CollectStuckTasks(ctx context.Context, policy string) (*TaskGCReport, error) {
+	if !ValidTaskGCPolicy(policy) {
+		return nil, fmt.Errorf("unknown policy %q (use %s or %s)", policy, TaskGCPolicyFail, TaskGCPolicyRequeue)
+	}
+	if s.taskDB == nil || s.assignmentDB == nil {
+		return nil, fmt.Errorf("databases not available")
+	}
+
+	tasks, err := s.taskDB.GetTasksByStatus(ctx, "running")
+	if err != nil {
+		return nil, fmt.Errorf("get running tasks: %w", err)
+	}
+
+	report := &TaskGCReport{Policy: policy, Checked: len(tasks), Failed: []string{}, Requeued: []string{}, Skipped: []string{}}
+	for _, task := range tasks {
+		assignment, err := s.assignmentDB.GetAssignmentByTaskID(ctx, task.TaskID)
+		if err != nil {
+			logging.Warnf("⚠ Task %s has no assignment, skipping", task.TaskID)
+			continue
+		}
+
+		workerID := assignment.WorkerID
+		s.mu.RLock()
+		worker, registered := s.workers[workerID]
+		active := registered && worker.IsActive
+		s.mu.RUnlock()
+		if active {
+			continue
+		}
+
+		reason := fmt.Sprintf("worker %s is no longer registered", workerID)
+		if registered {
+			reason = fmt.Sprintf("worker %s is inactive", workerID)
+		}
+		logging.Infof("🧹 Task %s is stuck running: %s (policy=%s)", task.TaskID, reason, policy)
+
+		s.releaseTaskFromWorker(ctx, workerID, task)
+
+		if policy == TaskGCPolicyRequeue {
+			if err := s.taskDB.UpdateTaskStatus(ctx, task.TaskID, "pending"); err != nil {
+				logging.Warnf("Warning: failed to reset task %s to pending: %v", task.TaskID, err)
+				report.Skipped = append(report.Skipped, task.TaskID)
+				continue
+			}
+			s.EnqueueTask(taskFromRecord(task), reason)
+			report.Requeued = append(report.Requeued, task.TaskID)
+			continue
+		}
+
+		if err := s.taskDB.UpdateTaskStatus(ctx, task.TaskID, "failed"); err != nil {
+			logging.Warnf("Warning: failed to mark task %s failed: %v", task.TaskID, err)
+			report.Skipped = append(report.Skipped, task.TaskID)
+			continue
+		}
+		s.mu.RLock()
+		s.notifyTaskTerminal(ctx, task.TaskID, workerID, "failed", task)
+		s.mu.RUnlock()
+		report.Failed = append(report.Failed, task.TaskID)
+	}
+
+	return report, nil
+}
+
+// releaseTaskFromWorker drops a task's allocation and assignment from a worker that will not report it
+// The allocation is released in memory and in the database only if the worker still holds the task,
+// so a task already released (e.g. by a late completion report) is not released twice
+func (s *MasterServer) releaseTaskFromWorker(ctx context.Context, workerID string, record *db.Task) {
+	s.mu.Lock()
+	worker, exists := s.workers[workerID]
+	s.forgetRunningTask(record.TaskID)
+	delete(s.publishedPorts, record.TaskID)
+	released := exists && worker.RunningTasks[record.TaskID]
+	if released {
+		delete(worker.RunningTasks, record.TaskID)
+		worker.AllocatedCPU -= record.ReqCPU
+		worker.AllocatedMemory -= record.ReqMemory
+		worker.AllocatedStorage -= record.ReqStorage
+		worker.AllocatedGPU -= record.ReqGPU
+		worker.AvailableCPU += record.ReqCPU
+		worker.AvailableMemory += record.ReqMemory
+		worker.AvailableStorage += record.ReqStorage
+		worker.AvailableGPU += record.ReqGPU
+	}
+	s.mu.Unlock()
+
+	if released && s.workerDB != nil {
+		if err := s.workerDB.ReleaseResources(ctx, workerID,
+			record.ReqCPU, record.ReqMemory, record.ReqStorage, record.ReqGPU); err != nil {
+			logging.Warnf("Warning: failed to release resources in database: %v", err)
+		}
+	}
+	if s.assignmentDB != nil {
+		if err := s.assignmentDB.DeleteAssignment(ctx, record.TaskID); err != nil {
+			logging.Warnf("Warning: failed to delete assignment for task %s: %v", record.TaskID, err)
+		}
+	}
+}
+
+// StartTaskGC periodically collects stuck running tasks with the given policy until StopTaskGC is called
+func (s *MasterServer) StartTaskGC(interval time.Duration, policy string) {
+	s.taskGCTicker = time.NewTicker(interval)
+	s.taskGCStop = make(chan bool)
+
+	go func() {
+		logging.Infof("🧹 Stuck task collector started (interval: %s, policy: %s)", interval, policy)
+		for {
+			select {
+			case <-s.taskGCTicker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				report, err := s.CollectStuckTasks(ctx, policy)
+				cancel()
+				if err != nil {
+					logging.Warnf("⚠ Stuck task collection failed: %v", err)
+				} else if len(report.Failed)+len(report.Requeued) > 0 {
+					logging.Infof("🧹 Collected stuck tasks: %d failed, %d requeued", len(report.Failed), len(report.Requeued))
+				}
+			case <-s.taskGCStop:
+				logging.Info("🛑 Stuck task collector stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopTaskGC stops the periodic stuck task collector
+func (s *MasterServer) StopTaskGC() {
+	if s.taskGCTicker != nil {
+		s.taskGCTicker.Stop()
+	}
+	if s.taskGCStop != nil {
+		close(s.taskGCStop)
+	}
+}
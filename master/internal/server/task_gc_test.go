@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"master/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestCollectStuckTasksCleansUpUnregisteredWorker tests that a running task whose worker was unregistered is failed or requeued
+func TestCollectStuckTasksCleansUpUnregisteredWorker(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	taskDoc := bson.D{
+		{Key: "task_id", Value: "task-1"},
+		{Key: "docker_image", Value: "alpine"},
+		{Key: "status", Value: "running"},
+		{Key: "req_cpu", Value: 2.0},
+		{Key: "req_memory", Value: 1.0},
+	}
+	assignmentDoc := bson.D{
+		{Key: "ass_id", Value: "ass-task-1"},
+		{Key: "task_id", Value: "task-1"},
+		{Key: "worker_id", Value: "worker-1"},
+	}
+	// Running tasks, the task's assignment, the assignment delete, then the status update
+	addResponses := func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.TASKS", mtest.FirstBatch, taskDoc),
+			mtest.CreateCursorResponse(0, "cloudai.ASSIGNMENTS", mtest.FirstBatch, assignmentDoc),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+	}
+	newServer := func(mt *mtest.T) *MasterServer {
+		ms := NewMasterServer(nil, db.NewTaskDBFromClient(mt.Client, "cloudai"), db.NewAssignmentDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+		return ms
+	}
+
+	mt.Run("fail", func(mt *mtest.T) {
+		ms := newServer(mt)
+		if err := ms.UnregisterWorker(context.Background(), "worker-1"); err != nil {
+			t.Fatalf("Failed to unregister worker: %v", err)
+		}
+		addResponses(mt)
+
+		report, err := ms.CollectStuckTasks(context.Background(), TaskGCPolicyFail)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(report.Failed) != 1 || report.Failed[0] != "task-1" {
+			t.Errorf("Expected task-1 to be failed, got %+v", report)
+		}
+		if len(ms.GetQueuedTasks()) != 0 {
+			t.Errorf("Expected nothing to be requeued, got %d queued", len(ms.GetQueuedTasks()))
+		}
+	})
+
+	mt.Run("requeue", func(mt *mtest.T) {
+		ms := newServer(mt)
+		// The worker is still registered but inactive, holding a phantom reservation for the task
+		worker, _ := ms.GetWorkerStats("worker-1")
+		worker.IsActive = false
+		worker.RunningTasks["task-1"] = true
+		worker.AllocatedCPU, worker.AvailableCPU = 2.0, 2.0
+		worker.AllocatedMemory, worker.AvailableMemory = 1.0, 7.0
+		addResponses(mt)
+
+		report, err := ms.CollectStuckTasks(context.Background(), TaskGCPolicyRequeue)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(report.Requeued) != 1 || report.Requeued[0] != "task-1" {
+			t.Errorf("Expected task-1 to be requeued, got %+v", report)
+		}
+		if worker.RunningTasks["task-1"] || worker.AllocatedCPU != 0 || worker.AvailableCPU != 4.0 {
+			t.Errorf("Expected the reservation to be released, got allocated %.1f available %.1f", worker.AllocatedCPU, worker.AvailableCPU)
+		}
+		queued := ms.GetQueuedTasks()
+		if len(queued) != 1 || queued[0].Task.TaskId != "task-1" {
+			t.Errorf("Expected task-1 to be queued, got %+v", queued)
+		}
+	})
+}
+
+// TestReleaseTaskFromWorkerReleasesOnce tests that the database allocation is released only while the worker still holds the task
+func TestReleaseTaskFromWorkerReleasesOnce(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("release", func(mt *mtest.T) {
+		ms := NewMasterServer(db.NewWorkerDBFromClient(mt.Client, "cloudai"), nil, nil, nil, nil, nil, nil)
+		// The existence check, then the insert
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "cloudai.WORKER_REGISTRY", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+		if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register worker: %v", err)
+		}
+		ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+		worker, _ := ms.GetWorkerStats("worker-1")
+		worker.RunningTasks["task-1"] = true
+		worker.AllocatedCPU, worker.AvailableCPU = 2.0, 2.0
+
+		releases := func() int {
+			count := 0
+			for _, event := range mt.GetAllStartedEvents() {
+				if event.CommandName == "update" {
+					count++
+				}
+			}
+			return count
+		}
+
+		record := &db.Task{TaskID: "task-1", ReqCPU: 2.0}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		ms.releaseTaskFromWorker(context.Background(), "worker-1", record)
+		if got := releases(); got != 1 {
+			t.Fatalf("Expected 1 database release, got %d", got)
+		}
+
+		// The worker no longer holds the task, so a second release leaves the database alone
+		ms.releaseTaskFromWorker(context.Background(), "worker-1", record)
+		if got := releases(); got != 1 {
+			t.Errorf("Expected no further database release, got %d in total", got)
+		}
+		if worker.AllocatedCPU != 0 || worker.AvailableCPU != 4.0 {
+			t.Errorf("Expected the allocation released once, got allocated %.1f available %.1f", worker.AllocatedCPU, worker.AvailableCPU)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	pb "master/proto"
+
+	"google.golang.org/grpc"
+)
+
+// TaskStatsHandler receives each live resource sample of a task; the last one has IsComplete set
+type TaskStatsHandler func(stats *pb.TaskStats) error
+
+// StreamTaskStatsFromWorker relays a running task's live CPU and memory samples from the worker running it
+// It returns once the task stops running, the handler fails or ctx is cancelled
+func (s *MasterServer) StreamTaskStatsFromWorker(ctx context.Context, taskID string, handler TaskStatsHandler) error {
+	s.mu.RLock()
+	var workerID, workerIP string
+	for id, worker := range s.workers {
+		if worker.RunningTasks[taskID] {
+			workerID, workerIP = id, worker.Info.WorkerIp
+			break
+		}
+	}
+	s.mu.RUnlock()
+	if workerID == "" {
+		return fmt.Errorf("task %s is not running on any worker", taskID)
+	}
+
+	conn, err := grpc.Dial(workerIP, s.streamDialOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to worker %s: %w", workerID, err)
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewMasterWorkerClient(conn).StreamTaskStats(ctx, &pb.TaskID{TaskId: taskID})
+	if err != nil {
+		return fmt.Errorf("failed to start stats stream: %w", err)
+	}
+
+	for {
+		stats, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error receiving task stats: %w", err)
+		}
+		if err := handler(stats); err != nil {
+			return fmt.Errorf("handler error: %w", err)
+		}
+		if stats.IsComplete {
+			return nil
+		}
+	}
+}
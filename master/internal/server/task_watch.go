@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"master/internal/logging"
+	pb "master/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// taskWatchBuffer is the number of updates buffered per watcher; a watcher that falls further behind misses intermediate updates
+const taskWatchBuffer = 32
+
+// taskWatchers fans task status updates out to WatchTaskStatus streams
+type taskWatchers struct {
+	mu       sync.Mutex
+	watchers map[string]map[chan *pb.TaskStatusUpdate]struct{} // task_id -> subscribed channels
+}
+
+// newTaskWatchers creates an empty watcher registry
+func newTaskWatchers() *taskWatchers {
+	return &taskWatchers{watchers: make(map[string]map[chan *pb.TaskStatusUpdate]struct{})}
+}
+
+// subscribe registers a channel that receives every update for taskID
+func (w *taskWatchers) subscribe(taskID string) chan *pb.TaskStatusUpdate {
+	ch := make(chan *pb.TaskStatusUpdate, taskWatchBuffer)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watchers[taskID] == nil {
+		w.watchers[taskID] = make(map[chan *pb.TaskStatusUpdate]struct{})
+	}
+	w.watchers[taskID][ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes a channel registered with subscribe
+func (w *taskWatchers) unsubscribe(taskID string, ch chan *pb.TaskStatusUpdate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.watchers[taskID], ch)
+	if len(w.watchers[taskID]) == 0 {
+		delete(w.watchers, taskID)
+	}
+}
+
+// publish delivers an update to every watcher of its task without blocking
+// A final status is never dropped: it replaces the oldest buffered update of a watcher that is not keeping up
+func (w *taskWatchers) publish(update *pb.TaskStatusUpdate) {
+	if update.Timestamp == 0 {
+		update.Timestamp = time.Now().Unix()
+	}
+	final := isFinalTaskStatus(update.Status)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.watchers[update.TaskId] {
+		select {
+		case ch <- update:
+			continue
+		default:
+		}
+		if !final {
+			logging.Warnf("⚠ Task watcher for %s is not keeping up, dropping %s update", update.TaskId, update.Status)
+			continue
+		}
+		// Only publishers send, under w.mu, so freeing a slot guarantees the send succeeds
+		select {
+		case dropped := <-ch:
+			logging.Warnf("⚠ Task watcher for %s is not keeping up, dropping %s update", update.TaskId, dropped.Status)
+		default:
+		}
+		ch <- update
+	}
+}
+
+// isFinalTaskStatus reports whether a task in this status will not change again
+func isFinalTaskStatus(status string) bool {
+	switch status {
+	case "completed", "failed", "cancelled", "crashloop", "expired":
+		return true
+	default:
+		return false
+	}
+}
+
+// publishQueuePositions reports queue position changes to watchers of queued tasks
+// This function assumes s.queueMu is already locked by the caller
+func (s *MasterServer) publishQueuePositions() {
+	for i, qt := range s.taskQueue {
+		position := i + 1
+		if qt.Position == position {
+			continue
+		}
+		qt.Position = position
+		s.watchers.publish(&pb.TaskStatusUpdate{
+			TaskId:        qt.Task.TaskId,
+			Status:        "queued",
+			QueuePosition: int32(position),
+			Message:       qt.LastError,
+		})
+	}
+}
+
+// currentTaskStatus returns a task's status as the first update for a new watcher, or nil if the task is unknown
+func (s *MasterServer) currentTaskStatus(ctx context.Context, taskID string) *pb.TaskStatusUpdate {
+	s.queueMu.RLock()
+	for i, qt := range s.taskQueue {
+		if qt.Task.TaskId == taskID {
+			s.queueMu.RUnlock()
+			return &pb.TaskStatusUpdate{TaskId: taskID, Status: "queued", QueuePosition: int32(i + 1), Message: qt.LastError}
+		}
+	}
+	_, held := s.heldTasks[taskID]
+	s.queueMu.RUnlock()
+	if held {
+		return &pb.TaskStatusUpdate{TaskId: taskID, Status: "held", Message: "Waiting to be released"}
+	}
+
+	s.mu.RLock()
+	for workerID, worker := range s.workers {
+		if worker.RunningTasks[taskID] {
+			s.mu.RUnlock()
+			return &pb.TaskStatusUpdate{TaskId: taskID, Status: "running", WorkerId: workerID}
+		}
+	}
+	s.mu.RUnlock()
+
+	if s.taskDB != nil {
+		if task, err := s.taskDB.GetTask(ctx, taskID); err == nil {
+			return &pb.TaskStatusUpdate{TaskId: taskID, Status: task.Status}
+		}
+	}
+	return nil
+}
+
+// WatchTaskStatus streams a task's status as it moves from queued (with position changes) to running to a final state
+// The first message is the task's current status; the stream ends after a final status or when the client disconnects
+func (s *MasterServer) WatchTaskStatus(req *pb.TaskID, stream pb.MasterWorker_WatchTaskStatusServer) error {
+	// Subscribe before reading the current status so no transition is missed in between
+	updates := s.watchers.subscribe(req.TaskId)
+	defer s.watchers.unsubscribe(req.TaskId, updates)
+
+	current := s.currentTaskStatus(stream.Context(), req.TaskId)
+	if current == nil {
+		return status.Errorf(codes.NotFound, "task %s not found", req.TaskId)
+	}
+	current.Timestamp = time.Now().Unix()
+	if err := stream.Send(current); err != nil {
+		return err
+	}
+	if isFinalTaskStatus(current.Status) {
+		return nil
+	}
+
+	last := current
+	for {
+		select {
+		case update := <-updates:
+			// A transition published while the current status was read may repeat it
+			if update.Status == last.Status && update.QueuePosition == last.QueuePosition && update.WorkerId == last.WorkerId {
+				continue
+			}
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+			last = update
+			if isFinalTaskStatus(update.Status) {
+				return nil
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
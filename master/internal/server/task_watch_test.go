@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	pb "master/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TestWatchTaskStatusQueuedThenRunning tests that a task submitted with no workers reports queued, then running once a worker joins
+func TestWatchTaskStatusQueuedThenRunning(t *testing.T) {
+	// Worker stub that accepts the assignment once it is registered
+	workerLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	workerServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(workerServer, acceptingWorker{})
+	go workerServer.Serve(workerLis)
+	defer workerServer.Stop()
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	masterLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	masterServer := NewGRPCServer(ms, false, DefaultKeepaliveConfig(), nil)
+	go masterServer.Serve(masterLis)
+	defer masterServer.Stop()
+
+	if _, err := ms.SubmitTask(context.Background(), &pb.Task{TaskId: "task-1", ReqCpu: 1.0, ReqMemory: 1.0}); err != nil {
+		t.Fatalf("Failed to submit task: %v", err)
+	}
+
+	conn, err := grpc.NewClient(masterLis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := pb.NewMasterWorkerClient(conn).WatchTaskStatus(ctx, &pb.TaskID{TaskId: "task-1"})
+	if err != nil {
+		t.Fatalf("Failed to watch task: %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected an initial update, got %v", err)
+	}
+	if first.Status != "queued" || first.QueuePosition != 1 {
+		t.Fatalf("Expected queued at position 1, got %s at %d", first.Status, first.QueuePosition)
+	}
+
+	// No workers yet: a scheduling pass leaves the task queued at the same position
+	ms.processQueueOnce(time.Now())
+
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", workerLis.Addr().String()); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	ms.UpdateWorkerResourcesInMemory("worker-1", 4.0, 8.0, 100.0, 0.0)
+	ms.processQueueOnce(time.Now())
+
+	second, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected a running update, got %v", err)
+	}
+	if second.Status != "running" || second.WorkerId != "worker-1" {
+		t.Fatalf("Expected running on worker-1, got %s on %q", second.Status, second.WorkerId)
+	}
+
+	if _, err := ms.ReportTaskCompletion(context.Background(), &pb.TaskResult{TaskId: "task-1", WorkerId: "worker-1", Status: "success"}); err != nil {
+		t.Fatalf("Failed to report completion: %v", err)
+	}
+
+	final, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected a final update, got %v", err)
+	}
+	if final.Status != "completed" {
+		t.Errorf("Expected completed, got %s", final.Status)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected the stream to end after the final status, got %v", err)
+	}
+}
+
+// TestTaskWatchersNeverDropFinalStatus tests that a final status reaches a watcher whose buffer is full
+func TestTaskWatchersNeverDropFinalStatus(t *testing.T) {
+	watchers := newTaskWatchers()
+	updates := watchers.subscribe("task-1")
+	defer watchers.unsubscribe("task-1", updates)
+
+	for i := 0; i <= taskWatchBuffer; i++ {
+		watchers.publish(&pb.TaskStatusUpdate{TaskId: "task-1", Status: "queued", QueuePosition: int32(i + 1)})
+	}
+	watchers.publish(&pb.TaskStatusUpdate{TaskId: "task-1", Status: "completed"})
+
+	if len(updates) != taskWatchBuffer {
+		t.Fatalf("Expected %d buffered updates, got %d", taskWatchBuffer, len(updates))
+	}
+	var last *pb.TaskStatusUpdate
+	for len(updates) > 0 {
+		last = <-updates
+	}
+	if last.Status != "completed" {
+		t.Errorf("Expected the final update to be completed, got %s", last.Status)
+	}
+}
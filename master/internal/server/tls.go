@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"master/internal/logging"
+	pb "master/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TLSConfig holds the PEM files used to serve gRPC and the HTTP API over TLS and to verify workers
+type TLSConfig struct {
+	CertFile string // Certificate presented by the master
+	KeyFile  string // Private key for CertFile
+	CAFile   string // CA bundle used to verify worker certificates when dialing ("" = system roots)
+	// RequireClientCert turns on mutual TLS for worker RPCs: workers must present a certificate signed by CAFile
+	// whose common name is their worker ID (other clients, e.g. task submitters, may connect without one)
+	RequireClientCert bool
+}
+
+// Enabled reports whether a certificate and key are configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// ServerTLS loads the certificate and key into a server-side TLS configuration
+func (c TLSConfig) ServerTLS() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// ClientTLS returns the TLS configuration for dials to workers, trusting CAFile when set
+// The certificate is presented too, so peers requiring client certificates can authenticate this process
+func (c TLSConfig) ClientTLS() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		if config.RootCAs, err = c.caPool(); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// caPool reads CAFile into a certificate pool
+func (c TLSConfig) caPool() (*x509.CertPool, error) {
+	pem, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read TLS CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+	}
+	return pool, nil
+}
+
+// ServerCredentials returns the gRPC server credentials (nil when TLS is not configured, meaning plaintext)
+// With RequireClientCert, a certificate a peer presents must be signed by CAFile; peers without one still connect,
+// and worker RPCs are refused per call (see SetRequireWorkerCert)
+func (c TLSConfig) ServerCredentials() (credentials.TransportCredentials, error) {
+	if !c.Enabled() {
+		if c.RequireClientCert {
+			return nil, fmt.Errorf("client certificates require TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	config, err := c.ServerTLS()
+	if err != nil {
+		return nil, err
+	}
+	if c.RequireClientCert {
+		if c.CAFile == "" {
+			return nil, fmt.Errorf("client certificates require TLS_CA_FILE")
+		}
+		if config.ClientCAs, err = c.caPool(); err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// ClientCredentials returns the credentials for dials to workers (plaintext when TLS is not configured)
+func (c TLSConfig) ClientCredentials() (credentials.TransportCredentials, error) {
+	if !c.Enabled() {
+		return insecure.NewCredentials(), nil
+	}
+	config, err := c.ClientTLS()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// workerRPCs are the RPCs only workers call; each is checked against the caller's client certificate
+var workerRPCs = map[string]bool{
+	pb.MasterWorker_RegisterWorker_FullMethodName:       true,
+	pb.MasterWorker_SendHeartbeat_FullMethodName:        true,
+	pb.MasterWorker_ReportTaskCompletion_FullMethodName: true,
+	pb.MasterWorker_ReleaseTask_FullMethodName:          true,
+	pb.MasterWorker_UploadTaskFiles_FullMethodName:      true,
+}
+
+// SetRequireWorkerCert refuses worker RPCs from peers without a verified client certificate (call before serving)
+func (s *MasterServer) SetRequireWorkerCert(require bool) {
+	s.requireWorkerCert = require
+}
+
+// peerWorkerID returns the common name of the peer's verified client certificate, or "" when it presented none
+func peerWorkerID(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return ""
+	}
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+}
+
+// verifyPeerWorkerID checks that a worker authenticated by client certificate claims the ID its certificate was issued to
+// Peers without a verified client certificate are refused when worker certificates are required, and let through otherwise
+func (s *MasterServer) verifyPeerWorkerID(ctx context.Context, workerID string) error {
+	cn := peerWorkerID(ctx)
+	if cn == "" {
+		if s.requireWorkerCert {
+			return status.Error(codes.Unauthenticated, "worker RPCs require a client certificate")
+		}
+		return nil
+	}
+	if cn != workerID {
+		return status.Errorf(codes.PermissionDenied, "client certificate was issued to %q, not %q", cn, workerID)
+	}
+	return nil
+}
+
+// verifyPeerUploader checks that files for a task come from the worker its client certificate names and that the task runs there
+func (s *MasterServer) verifyPeerUploader(ctx context.Context, taskID string) error {
+	cn := peerWorkerID(ctx)
+	if cn == "" {
+		if s.requireWorkerCert {
+			return status.Error(codes.Unauthenticated, "worker RPCs require a client certificate")
+		}
+		return nil
+	}
+
+	s.mu.RLock()
+	worker, exists := s.workers[cn]
+	running := exists && worker.RunningTasks[taskID]
+	s.mu.RUnlock()
+	if !running && s.assignmentDB != nil {
+		if assigned, err := s.assignmentDB.GetWorkerForTask(ctx, taskID); err == nil && assigned == cn {
+			running = true
+		}
+	}
+	if !running {
+		return status.Errorf(codes.PermissionDenied, "task %s is not assigned to worker %s", taskID, cn)
+	}
+	return nil
+}
+
+// workerIdentityUnaryInterceptor checks the worker ID claimed by every unary worker RPC against the caller's certificate
+func (s *MasterServer) workerIdentityUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if workerRPCs[info.FullMethod] {
+		claimed, ok := req.(interface{ GetWorkerId() string })
+		if !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "%s carries no worker ID", info.FullMethod)
+		}
+		if err := s.verifyPeerWorkerID(ctx, claimed.GetWorkerId()); err != nil {
+			logging.Warnf("⚠️  Rejected %s from worker %q: %v", info.FullMethod, claimed.GetWorkerId(), err)
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// workerIdentityStreamInterceptor checks each task a worker uploads files for against the caller's certificate
+func (s *MasterServer) workerIdentityStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !workerRPCs[info.FullMethod] {
+		return handler(srv, ss)
+	}
+	return handler(srv, &uploaderStream{ServerStream: ss, ms: s, verified: make(map[string]bool)})
+}
+
+// uploaderStream verifies the uploading worker for every task its file chunks belong to
+type uploaderStream struct {
+	grpc.ServerStream
+	ms       *MasterServer
+	verified map[string]bool // Task IDs already checked on this stream
+}
+
+func (u *uploaderStream) RecvMsg(m interface{}) error {
+	if err := u.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	chunk, ok := m.(*pb.FileChunk)
+	if !ok || u.verified[chunk.TaskId] {
+		return nil
+	}
+	if err := u.ms.verifyPeerUploader(u.Context(), chunk.TaskId); err != nil {
+		logging.Warnf("⚠️  Rejected file upload for task %s: %v", chunk.TaskId, err)
+		return err
+	}
+	u.verified[chunk.TaskId] = true
+	return nil
+}
+
+// SetTransportCredentials sets the credentials used for every dial to a worker (call before serving)
+func (s *MasterServer) SetTransportCredentials(creds credentials.TransportCredentials) {
+	s.transportCreds = creds
+}
+
+// dialCredentials returns the dial option carrying the worker transport credentials (plaintext when unset)
+func (s *MasterServer) dialCredentials() grpc.DialOption {
+	if s.transportCreds == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	return grpc.WithTransportCredentials(s.transportCreds)
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"master/internal/storage"
+	pb "master/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// testCA is a throwaway certificate authority for TLS tests
+type testCA struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+}
+
+// newTestCA creates a certificate authority and writes its certificate to a temp file
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cloudai-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+
+	certFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write CA certificate: %v", err)
+	}
+	return &testCA{cert: cert, key: key, certFile: certFile}
+}
+
+// issue writes a certificate for 127.0.0.1 with the given common name, signed by the CA, and its key
+// The certificate is valid for both server and client authentication
+func (ca *testCA) issue(t *testing.T, commonName string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// serveTLS starts the master's gRPC server with the given TLS configuration, returning its address
+func serveTLS(t *testing.T, ms *MasterServer, tlsConfig TLSConfig) string {
+	t.Helper()
+
+	serverCreds, err := tlsConfig.ServerCredentials()
+	if err != nil {
+		t.Fatalf("Failed to load server credentials: %v", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ms.SetRequireWorkerCert(tlsConfig.RequireClientCert)
+	grpcServer := NewGRPCServer(ms, false, DefaultKeepaliveConfig(), serverCreds)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+	return lis.Addr().String()
+}
+
+// masterClient connects to addr with the given credentials
+func masterClient(t *testing.T, addr string, opt grpc.DialOption) pb.MasterWorkerClient {
+	t.Helper()
+
+	conn, err := grpc.NewClient(addr, opt)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMasterWorkerClient(conn)
+}
+
+// TestTLSRejectsInsecureClient tests that a TLS-enabled gRPC server rejects a plaintext client and accepts a TLS client
+func TestTLSRejectsInsecureClient(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "master-1")
+	tlsConfig := TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: ca.certFile}
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	addr := serveTLS(t, ms, tlsConfig)
+
+	heartbeat := func(opt grpc.DialOption) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := masterClient(t, addr, opt).SendHeartbeat(ctx, &pb.Heartbeat{WorkerId: "worker-1"})
+		return err
+	}
+
+	if err := heartbeat(grpc.WithTransportCredentials(insecure.NewCredentials())); err == nil {
+		t.Error("Expected a plaintext client to be rejected by the TLS server")
+	}
+
+	clientCreds, err := tlsConfig.ClientCredentials()
+	if err != nil {
+		t.Fatalf("Failed to load client credentials: %v", err)
+	}
+	if err := heartbeat(grpc.WithTransportCredentials(clientCreds)); err != nil {
+		t.Errorf("Expected a TLS client to connect, got %v", err)
+	}
+}
+
+// TestMutualTLSRejectsMismatchedWorkerID tests that a worker whose client certificate CN differs from its claimed ID cannot register
+func TestMutualTLSRejectsMismatchedWorkerID(t *testing.T) {
+	ca := newTestCA(t)
+	masterCert, masterKey := ca.issue(t, "master-1")
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	for _, id := range []string{"worker-1", "worker-2"} {
+		if err := ms.ManualRegisterWorker(context.Background(), id, "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register %s: %v", id, err)
+		}
+	}
+	addr := serveTLS(t, ms, TLSConfig{CertFile: masterCert, KeyFile: masterKey, CAFile: ca.certFile, RequireClientCert: true})
+
+	register := func(certCN, claimedID string) (*pb.RegisterAck, error) {
+		certFile, keyFile := ca.issue(t, certCN)
+		creds, err := TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: ca.certFile}.ClientCredentials()
+		if err != nil {
+			t.Fatalf("Failed to load client credentials: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return masterClient(t, addr, grpc.WithTransportCredentials(creds)).RegisterWorker(ctx,
+			&pb.WorkerInfo{WorkerId: claimedID, TotalCpu: 4, TotalMemory: 8})
+	}
+
+	if _, err := register("worker-2", "worker-1"); err == nil {
+		t.Error("Expected worker-2's certificate to be rejected when claiming to be worker-1")
+	}
+	if worker, _ := ms.GetWorkerStats("worker-1"); worker.IsActive {
+		t.Error("Expected worker-1 to stay inactive after the rejected registration")
+	}
+
+	ack, err := register("worker-1", "worker-1")
+	if err != nil || !ack.Success {
+		t.Fatalf("Expected worker-1 to register with its own certificate, got ack=%v err=%v", ack, err)
+	}
+}
+
+// workerCreds returns client credentials for a certificate issued to commonName
+func workerCreds(t *testing.T, ca *testCA, commonName string) grpc.DialOption {
+	t.Helper()
+
+	certFile, keyFile := ca.issue(t, commonName)
+	creds, err := TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: ca.certFile}.ClientCredentials()
+	if err != nil {
+		t.Fatalf("Failed to load client credentials: %v", err)
+	}
+	return grpc.WithTransportCredentials(creds)
+}
+
+// TestMutualTLSChecksWorkerIDOnEveryWorkerRPC tests that heartbeats, completions and releases must come from the worker they name
+func TestMutualTLSChecksWorkerIDOnEveryWorkerRPC(t *testing.T) {
+	ca := newTestCA(t)
+	masterCert, masterKey := ca.issue(t, "master-1")
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	addr := serveTLS(t, ms, TLSConfig{CertFile: masterCert, KeyFile: masterKey, CAFile: ca.certFile, RequireClientCert: true})
+
+	impostor := masterClient(t, addr, workerCreds(t, ca, "worker-2"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := impostor.SendHeartbeat(ctx, &pb.Heartbeat{WorkerId: "worker-1"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected heartbeat to be denied, got %v", err)
+	}
+	if _, err := impostor.ReportTaskCompletion(ctx, &pb.TaskResult{WorkerId: "worker-1", TaskId: "task-1"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected completion report to be denied, got %v", err)
+	}
+	if _, err := impostor.ReleaseTask(ctx, &pb.TaskRelease{WorkerId: "worker-1", TaskId: "task-1"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected release to be denied, got %v", err)
+	}
+
+	owner := masterClient(t, addr, workerCreds(t, ca, "worker-1"))
+	if _, err := owner.SendHeartbeat(ctx, &pb.Heartbeat{WorkerId: "worker-1"}); err != nil {
+		t.Errorf("Expected worker-1 to send its own heartbeat, got %v", err)
+	}
+}
+
+// TestMutualTLSAllowsClientsWithoutCertificate tests that only worker RPCs need a client certificate
+func TestMutualTLSAllowsClientsWithoutCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	masterCert, masterKey := ca.issue(t, "master-1")
+
+	ms := NewMasterServer(nil, nil, nil, nil, nil, nil, nil)
+	if err := ms.ManualRegisterWorker(context.Background(), "worker-1", "10.0.0.1:50052"); err != nil {
+		t.Fatalf("Failed to register worker: %v", err)
+	}
+	addr := serveTLS(t, ms, TLSConfig{CertFile: masterCert, KeyFile: masterKey, CAFile: ca.certFile, RequireClientCert: true})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := masterClient(t, addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: roots})))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.GetTaskManifest(ctx, &pb.TaskManifestRequest{}); err != nil {
+		t.Errorf("Expected a client without a certificate to call GetTaskManifest, got %v", err)
+	}
+	if _, err := client.SendHeartbeat(ctx, &pb.Heartbeat{WorkerId: "worker-1"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected a heartbeat without a certificate to be unauthenticated, got %v", err)
+	}
+}
+
+// TestMutualTLSRejectsUploadForAnotherWorkersTask tests that a worker can only upload files for tasks running on it
+func TestMutualTLSRejectsUploadForAnotherWorkersTask(t *testing.T) {
+	ca := newTestCA(t)
+	masterCert, masterKey := ca.issue(t, "master-1")
+
+	fileStorage, err := storage.NewFileStorageService(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file storage: %v", err)
+	}
+	ms := NewMasterServer(nil, nil, nil, nil, nil, fileStorage, nil)
+	for _, id := range []string{"worker-1", "worker-2"} {
+		if err := ms.ManualRegisterWorker(context.Background(), id, "10.0.0.1:50052"); err != nil {
+			t.Fatalf("Failed to register %s: %v", id, err)
+		}
+	}
+	ms.mu.Lock()
+	ms.workers["worker-1"].RunningTasks["task-1"] = true
+	ms.mu.Unlock()
+	addr := serveTLS(t, ms, TLSConfig{CertFile: masterCert, KeyFile: masterKey, CAFile: ca.certFile, RequireClientCert: true})
+
+	upload := func(commonName string) *pb.FileUploadAck {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := masterClient(t, addr, workerCreds(t, ca, commonName)).UploadTaskFiles(ctx)
+		if err != nil {
+			t.Fatalf("Failed to open upload stream: %v", err)
+		}
+		if err := stream.Send(&pb.FileChunk{
+			TaskId: "task-1", UserId: "alice", TaskName: "job", Timestamp: 1700000000,
+			FilePath: "out.txt", Data: []byte("result"), IsLastChunk: true, IsLastFile: true,
+		}); err != nil {
+			t.Fatalf("Failed to send chunk: %v", err)
+		}
+		ack, err := stream.CloseAndRecv()
+		if err != nil {
+			t.Fatalf("Failed to close upload stream: %v", err)
+		}
+		return ack
+	}
+
+	if ack := upload("worker-2"); ack.Success || ack.FilesReceived != 0 {
+		t.Errorf("Expected worker-2's upload for task-1 to be rejected, got %v", ack)
+	}
+	if ack := upload("worker-1"); !ack.Success || ack.FilesReceived != 1 {
+		t.Errorf("Expected worker-1's upload for task-1 to be stored, got %v", ack)
+	}
+}
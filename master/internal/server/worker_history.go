@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"master/internal/db"
+	"master/internal/logging"
+)
+
+// workerSnapshotRecorder stores worker snapshots for simulations to replay against (implemented by db.HistoryDB)
+type workerSnapshotRecorder interface {
+	RecordWorkerSnapshot(ctx context.Context, snapshot db.WorkerSnapshot) error
+}
+
+// StartWorkerSnapshotRecorder records each worker's capacity whenever it joins or leaves the cluster
+// Runs for the life of the master; snapshots missed while the recorder falls behind are only logged
+func (s *MasterServer) StartWorkerSnapshotRecorder(recorder workerSnapshotRecorder) {
+	events, _ := s.SubscribeEvents()
+
+	go func() {
+		logging.Info("📸 Worker snapshot recorder started")
+		for event := range events {
+			if event.Type != EventWorkerJoined && event.Type != EventWorkerLeft {
+				continue
+			}
+			snapshot := s.workerSnapshot(event.WorkerID, event.Type == EventWorkerJoined, event.Timestamp)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := recorder.RecordWorkerSnapshot(ctx, snapshot); err != nil {
+				logging.Warnf("⚠ Failed to record snapshot of worker %s: %v", event.WorkerID, err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// workerSnapshot captures a worker's current capacity as it joined (active) or left the cluster
+func (s *MasterServer) workerSnapshot(workerID string, active bool, at time.Time) db.WorkerSnapshot {
+	snapshot := db.WorkerSnapshot{WorkerID: workerID, IsActive: active, RecordedAt: at}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if worker, exists := s.workers[workerID]; exists && worker.Info != nil {
+		snapshot.TotalCPU = worker.Info.TotalCpu
+		snapshot.TotalMemory = worker.Info.TotalMemory
+		snapshot.TotalStorage = worker.Info.TotalStorage
+		snapshot.TotalGPU = worker.Info.TotalGpu
+	}
+	return snapshot
+}
@@ -3,10 +3,11 @@ package storage
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"master/internal/logging"
 )
 
 // AccessControl enforces user-level file access policies
@@ -137,6 +138,6 @@ func (ac *AccessControl) AuditFileAccess(userID, action, resource string, succes
 	if !success {
 		status = "❌ DENIED"
 	}
-	log.Printf("[AUDIT] %s | User=%s | Action=%s | Resource=%s",
+	logging.Infof("[AUDIT] %s | User=%s | Action=%s | Resource=%s",
 		status, userID, action, resource)
 }
@@ -1,41 +1,53 @@
 package storage
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"master/internal/logging"
 	pb "master/proto"
 )
 
+// objectUploadTimeout bounds the upload of one committed file to object storage
+const objectUploadTimeout = 5 * time.Minute
+
 // FileStorageService handles file uploads and storage organization
 type FileStorageService struct {
-	baseDir       string // Base directory for all file storage (e.g., /var/cloudai/files)
+	baseDir       string      // Base directory for all file storage (e.g., /var/cloudai/files)
+	objects       objectStore // Object storage backend; nil keeps files on local disk under baseDir
 	accessControl *AccessControl
 	mu            sync.RWMutex
 }
 
 // FileInfo represents individual file information
 type FileInfo struct {
-	Path string // Relative path from task directory
-	Size int64  // File size in bytes
+	Path   string // Relative path from task directory
+	Size   int64  // File size in bytes
+	SHA256 string // Hex SHA-256 of the content (uploads only)
 }
 
 // FileMetadata represents metadata for stored files
 type FileMetadata struct {
-	UserID      string
-	TaskID      string
-	TaskName    string
-	Timestamp   time.Time
-	FilePaths   []string   // Relative paths from task directory (deprecated, use Files)
-	Files       []FileInfo // Detailed file information with sizes
-	StoragePath string     // Absolute path to task directory
-	TotalSize   int64      // Total size of all files in bytes
+	UserID       string
+	TaskID       string
+	TaskName     string
+	Timestamp    time.Time
+	FilePaths    []string     // Relative paths from task directory (deprecated, use Files)
+	Files        []FileInfo   // Detailed file information with sizes
+	StoragePath  string       // Absolute path to task directory (s3:// location with object storage)
+	ObjectPrefix string       // Key prefix of the task's objects (object storage only)
+	TotalSize    int64        // Total size of all files in bytes
+	Results      []FileResult // Per-file upload outcome, in stream order (uploads only)
 }
 
 // NewFileStorageService creates a new file storage service
@@ -52,11 +64,35 @@ func NewFileStorageService(baseDir string) (*FileStorageService, error) {
 	// Initialize access control
 	fs.accessControl = NewAccessControl(fs)
 
-	log.Printf("✓ FileStorageService initialized with access control")
+	logging.Infof("✓ FileStorageService initialized with access control")
 
 	return fs, nil
 }
 
+// NewS3FileStorageService creates a file storage service that keeps task files in an S3-compatible bucket
+// stagingDir holds uploads while they are received; files are moved to the bucket once committed
+func NewS3FileStorageService(stagingDir string, cfg S3Config) (*FileStorageService, error) {
+	store, err := NewS3Store(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := store.CheckBucket(ctx); err != nil {
+		return nil, err
+	}
+
+	fs, err := NewFileStorageService(stagingDir)
+	if err != nil {
+		return nil, err
+	}
+	fs.objects = store
+
+	logging.Infof("✓ FileStorageService using object storage: %s", store.Location(""))
+	return fs, nil
+}
+
 // GetAccessControl returns the access control instance
 func (s *FileStorageService) GetAccessControl() *AccessControl {
 	return s.accessControl
@@ -76,31 +112,68 @@ func (s *FileStorageService) GetTaskStoragePath(userID, taskName string, timesta
 	return filepath.Join(userDir, taskName, timestampStr, taskID)
 }
 
+// taskObjectPrefix returns the object key prefix for a task, mirroring the local directory layout
+// Key format: <user_id>/<task_name>/<timestamp>/<task_id>
+func taskObjectPrefix(userID, taskName string, timestamp int64, taskID string) string {
+	timestampStr := time.Unix(timestamp, 0).Format("2006-01-02_15-04-05")
+	return strings.Join([]string{userID, taskName, timestampStr, taskID}, "/")
+}
+
+// FileChunkReceiver is the receiving side of a file upload stream
+type FileChunkReceiver interface {
+	Recv() (*pb.FileChunk, error)
+}
+
+// FileResult is the outcome of storing a single uploaded file
+type FileResult struct {
+	Path  string // Relative path as sent by the worker
+	Error string // Why the file was discarded (empty when it was committed)
+}
+
+// pendingFile is an uploaded file being written to a temporary path until it is committed
+type pendingFile struct {
+	path      string // Relative path as sent by the worker
+	finalPath string // Destination once committed
+	key       string // Object key once committed (object storage only)
+	tmp       *os.File
+	hash      hash.Hash
+	size      int64
+	err       error // First failure; later chunks of the file are discarded
+}
+
 // ReceiveFileStream handles streaming file uploads from workers
-func (s *FileStorageService) ReceiveFileStream(stream pb.MasterWorker_UploadTaskFilesServer) (*FileMetadata, error) {
+// Each file is committed on its own: a failed file is discarded and reported in the
+// returned metadata's Results while the others are kept. Metadata lists only committed files.
+// An error means the stream itself broke; metadata is still returned for files committed before it.
+func (s *FileStorageService) ReceiveFileStream(stream FileChunkReceiver) (*FileMetadata, error) {
 	var metadata FileMetadata
-	var currentFile *os.File
-	var currentFilePath string
-	filesReceived := 0
+	var current *pendingFile
+	var stagingPath string // Local task directory; files are staged here before object upload
+
+	// Only local storage is serialised: with object storage each task is staged in its own directory
+	// and uploaded over the network, which must not hold up other uploads, listings and deletes
+	if s.objects == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// With object storage every staged file has been uploaded or discarded once the stream ends
+	defer func() {
+		if s.objects != nil && stagingPath != "" {
+			os.RemoveAll(stagingPath)
+		}
+	}()
 
 	for {
 		chunk, err := stream.Recv()
 		if err == io.EOF {
-			// Close last file if open
-			if currentFile != nil {
-				currentFile.Close()
-			}
 			break
 		}
 		if err != nil {
-			// Clean up on error
-			if currentFile != nil {
-				currentFile.Close()
+			if current != nil {
+				s.finishFile(&metadata, current, "", fmt.Errorf("upload interrupted"))
 			}
-			return nil, fmt.Errorf("error receiving file chunk: %w", err)
+			return &metadata, fmt.Errorf("error receiving file chunk: %w", err)
 		}
 
 		// First chunk initializes metadata
@@ -109,72 +182,156 @@ func (s *FileStorageService) ReceiveFileStream(stream pb.MasterWorker_UploadTask
 			metadata.TaskID = chunk.TaskId
 			metadata.TaskName = chunk.TaskName
 			metadata.Timestamp = time.Unix(chunk.Timestamp, 0)
-			metadata.StoragePath = s.GetTaskStoragePath(chunk.UserId, chunk.TaskName, chunk.Timestamp, chunk.TaskId)
+			stagingPath = s.GetTaskStoragePath(chunk.UserId, chunk.TaskName, chunk.Timestamp, chunk.TaskId)
+			metadata.StoragePath = stagingPath
 			metadata.FilePaths = []string{}
+			if s.objects != nil {
+				metadata.ObjectPrefix = taskObjectPrefix(chunk.UserId, chunk.TaskName, chunk.Timestamp, chunk.TaskId)
+				metadata.StoragePath = s.objects.Location(metadata.ObjectPrefix)
+			}
 
 			// Create storage directory with secure permissions (drwx------)
-			if err := os.MkdirAll(metadata.StoragePath, 0700); err != nil {
+			if err := os.MkdirAll(stagingPath, 0700); err != nil {
 				return nil, fmt.Errorf("failed to create storage directory: %w", err)
 			}
 
-			log.Printf("[FileStorage] 🔒 Receiving files for task %s (user: %s, secure storage)",
+			logging.Infof("[FileStorage] 🔒 Receiving files for task %s (user: %s, secure storage)",
 				chunk.TaskId, chunk.UserId)
 		}
 
 		// New file in the stream
-		if currentFilePath != chunk.FilePath {
-			// Close previous file
-			if currentFile != nil {
-				currentFile.Close()
-				filesReceived++
-			}
-
-			// Open new file
-			currentFilePath = chunk.FilePath
-			fullPath := filepath.Join(metadata.StoragePath, chunk.FilePath)
-
-			// Create parent directories with secure permissions
-			if err := os.MkdirAll(filepath.Dir(fullPath), 0700); err != nil {
-				return nil, fmt.Errorf("failed to create directory for %s: %w", chunk.FilePath, err)
+		if current == nil || current.path != chunk.FilePath {
+			if current != nil {
+				s.finishFile(&metadata, current, "", fmt.Errorf("file ended without its last chunk"))
 			}
-
-			// Create file with secure permissions (rw-------)
-			currentFile, err = os.OpenFile(fullPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create file %s: %w", fullPath, err)
+			current = s.beginFile(stagingPath, chunk.FilePath)
+			if s.objects != nil {
+				current.key = metadata.ObjectPrefix + "/" + chunk.FilePath
 			}
-
-			metadata.FilePaths = append(metadata.FilePaths, chunk.FilePath)
-			log.Printf("[FileStorage] 📄 Receiving file: %s (secure)", chunk.FilePath)
 		}
 
-		// Write chunk data
-		if _, err := currentFile.Write(chunk.Data); err != nil {
-			currentFile.Close()
-			return nil, fmt.Errorf("failed to write to file %s: %w", currentFilePath, err)
+		// Write chunk data (discarded once the file has failed)
+		if current.err == nil {
+			if _, err := current.tmp.Write(chunk.Data); err != nil {
+				current.err = fmt.Errorf("failed to write: %w", err)
+			} else {
+				current.hash.Write(chunk.Data)
+				current.size += int64(len(chunk.Data))
+			}
 		}
 
-		// Close file if this is the last chunk
+		// Commit or discard the file on its last chunk
 		if chunk.IsLastChunk {
-			currentFile.Close()
-			filesReceived++
-			log.Printf("[FileStorage] ✓ File complete: %s", chunk.FilePath)
-			currentFile = nil
-			currentFilePath = ""
+			s.finishFile(&metadata, current, chunk.Sha256, nil)
+			current = nil
 		}
 
 		// All files received
 		if chunk.IsLastFile {
-			log.Printf("[FileStorage] ✓ All files received (%d files) for task %s", filesReceived, chunk.TaskId)
 			break
 		}
 	}
 
+	if current != nil {
+		s.finishFile(&metadata, current, "", fmt.Errorf("file ended without its last chunk"))
+	}
+
+	logging.Infof("[FileStorage] ✓ Upload finished for task %s: %d stored, %d failed",
+		metadata.TaskID, len(metadata.FilePaths), len(metadata.Results)-len(metadata.FilePaths))
 	return &metadata, nil
 }
 
+// beginFile opens a temporary file for an incoming upload; failures are kept on the pending file
+func (s *FileStorageService) beginFile(storagePath, relPath string) *pendingFile {
+	file := &pendingFile{path: relPath, hash: sha256.New()}
+
+	if err := s.accessControl.ValidateFilePath(relPath); err != nil {
+		file.err = err
+		return file
+	}
+	file.finalPath = filepath.Join(storagePath, relPath)
+
+	// Create parent directories with secure permissions
+	if err := os.MkdirAll(filepath.Dir(file.finalPath), 0700); err != nil {
+		file.err = fmt.Errorf("failed to create directory: %w", err)
+		return file
+	}
+
+	// Create temporary file with secure permissions (rw-------); renamed into place on commit
+	tmp, err := os.OpenFile(file.finalPath+".part", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		file.err = fmt.Errorf("failed to create file: %w", err)
+		return file
+	}
+	file.tmp = tmp
+
+	logging.Debugf("[FileStorage] 📄 Receiving file: %s (secure)", relPath)
+	return file
+}
+
+// finishFile commits a pending file, or discards it when it failed or its checksum does not match
+// The outcome is appended to metadata; only committed files are added to its file lists
+func (s *FileStorageService) finishFile(metadata *FileMetadata, file *pendingFile, expectedSHA256 string, failure error) {
+	if file.err == nil {
+		file.err = failure
+	}
+	if file.err == nil && expectedSHA256 != "" {
+		if actual := hex.EncodeToString(file.hash.Sum(nil)); !strings.EqualFold(actual, expectedSHA256) {
+			file.err = fmt.Errorf("checksum mismatch (expected %s, got %s)", expectedSHA256, actual)
+		}
+	}
+
+	if file.tmp != nil {
+		if err := file.tmp.Close(); err != nil && file.err == nil {
+			file.err = fmt.Errorf("failed to close: %w", err)
+		}
+		if file.err == nil {
+			if s.objects != nil {
+				file.err = s.uploadObject(file)
+			} else if err := os.Rename(file.tmp.Name(), file.finalPath); err != nil {
+				file.err = fmt.Errorf("failed to commit: %w", err)
+			}
+		}
+		if file.err != nil || s.objects != nil {
+			os.Remove(file.tmp.Name())
+		}
+	}
+
+	if file.err != nil {
+		logging.Errorf("[FileStorage] ✗ File discarded: %s: %v", file.path, file.err)
+		metadata.Results = append(metadata.Results, FileResult{Path: file.path, Error: file.err.Error()})
+		return
+	}
+
+	metadata.FilePaths = append(metadata.FilePaths, file.path)
+	metadata.Files = append(metadata.Files, FileInfo{Path: file.path, Size: file.size, SHA256: hex.EncodeToString(file.hash.Sum(nil))})
+	metadata.TotalSize += file.size
+	metadata.Results = append(metadata.Results, FileResult{Path: file.path})
+	logging.Debugf("[FileStorage] ✓ File complete: %s", file.path)
+}
+
+// uploadObject copies a staged file to its object key
+func (s *FileStorageService) uploadObject(file *pendingFile) error {
+	staged, err := os.Open(file.tmp.Name())
+	if err != nil {
+		return fmt.Errorf("failed to reopen staged file: %w", err)
+	}
+	defer staged.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectUploadTimeout)
+	defer cancel()
+	if err := s.objects.PutObject(ctx, file.key, staged, file.size); err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	return nil
+}
+
 // ListUserFiles returns all files for a specific user
 func (s *FileStorageService) ListUserFiles(userID string) ([]FileMetadata, error) {
+	if s.objects != nil {
+		return s.listUserObjects(userID)
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -211,7 +368,7 @@ func (s *FileStorageService) ListUserFiles(userID string) ([]FileMetadata, error
 			// Parse timestamp
 			timestamp, err := time.Parse("2006-01-02_15-04-05", timestampStr)
 			if err != nil {
-				log.Printf("Warning: failed to parse timestamp %s: %v", timestampStr, err)
+				logging.Warnf("Warning: failed to parse timestamp %s: %v", timestampStr, err)
 				return nil
 			}
 
@@ -252,6 +409,54 @@ func (s *FileStorageService) ListUserFiles(userID string) ([]FileMetadata, error
 	return metadataList, err
 }
 
+// listUserObjects groups a user's objects into per-task metadata
+func (s *FileStorageService) listUserObjects(userID string) ([]FileMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	objects, err := s.objects.ListObjects(ctx, userID+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var metadataList []FileMetadata
+	index := make(map[string]int) // task prefix -> position in metadataList
+	for _, obj := range objects {
+		// Key format: <user_id>/<task_name>/<timestamp>/<task_id>/<relative path>
+		parts := strings.SplitN(obj.Key, "/", 5)
+		if len(parts) != 5 || parts[4] == "" {
+			continue
+		}
+		prefix := strings.Join(parts[:4], "/")
+
+		i, exists := index[prefix]
+		if !exists {
+			timestamp, err := time.Parse("2006-01-02_15-04-05", parts[2])
+			if err != nil {
+				logging.Warnf("Warning: failed to parse timestamp %s: %v", parts[2], err)
+				continue
+			}
+			metadataList = append(metadataList, FileMetadata{
+				UserID:       userID,
+				TaskID:       parts[3],
+				TaskName:     parts[1],
+				Timestamp:    timestamp,
+				StoragePath:  s.objects.Location(prefix),
+				ObjectPrefix: prefix,
+			})
+			i = len(metadataList) - 1
+			index[prefix] = i
+		}
+
+		metadata := &metadataList[i]
+		metadata.FilePaths = append(metadata.FilePaths, parts[4])
+		metadata.Files = append(metadata.Files, FileInfo{Path: parts[4], Size: obj.Size})
+		metadata.TotalSize += obj.Size
+	}
+
+	return metadataList, nil
+}
+
 // GetTaskFiles returns files for a specific task
 func (s *FileStorageService) GetTaskFiles(userID, taskID string) (*FileMetadata, error) {
 	s.mu.RLock()
@@ -274,14 +479,26 @@ func (s *FileStorageService) GetTaskFiles(userID, taskID string) (*FileMetadata,
 
 // DeleteTaskFiles removes all files for a specific task
 func (s *FileStorageService) DeleteTaskFiles(userID, taskID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
+	// Look the task up before taking the write lock; GetTaskFiles takes the read lock itself
 	metadata, err := s.GetTaskFiles(userID, taskID)
 	if err != nil {
 		return err
 	}
 
+	// Object deletes go over the network, so they run without the storage lock
+	if s.objects != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, filePath := range metadata.FilePaths {
+			if err := s.objects.DeleteObject(ctx, metadata.ObjectPrefix+"/"+filePath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return os.RemoveAll(metadata.StoragePath)
 }
 
@@ -308,6 +525,96 @@ func (s *FileStorageService) GetFilePath(userID, taskID, relativeFilePath string
 	return filepath.Join(metadata.StoragePath, relativeFilePath), nil
 }
 
+// readTaskFile returns the contents of a stored task file from disk or object storage
+func (s *FileStorageService) readTaskFile(userID, taskID, relativeFilePath string) ([]byte, error) {
+	if s.objects == nil {
+		fullPath, err := s.GetFilePath(userID, taskID, relativeFilePath)
+		if err != nil {
+			return nil, err
+		}
+		return os.ReadFile(fullPath)
+	}
+
+	metadata, err := s.GetTaskFiles(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !containsPath(metadata.FilePaths, relativeFilePath) {
+		return nil, fmt.Errorf("file %s not found in task %s", relativeFilePath, taskID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectUploadTimeout)
+	defer cancel()
+	body, err := s.objects.GetObject(ctx, metadata.ObjectPrefix+"/"+relativeFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// openTaskFile opens a stored task file for serving, with its modification time
+// Object storage has no seekable body, so the object is read into memory and stamped with the task's upload time
+func (s *FileStorageService) openTaskFile(userID, taskID, relativeFilePath string) (io.ReadSeekCloser, time.Time, error) {
+	if s.objects == nil {
+		fullPath, err := s.GetFilePath(userID, taskID, relativeFilePath)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		file, err := os.Open(fullPath)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, time.Time{}, err
+		}
+		return file, info.ModTime(), nil
+	}
+
+	metadata, err := s.GetTaskFiles(userID, taskID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := s.readTaskFile(userID, taskID, relativeFilePath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return nopSeekCloser{bytes.NewReader(data)}, metadata.Timestamp, nil
+}
+
+// nopSeekCloser adds a no-op Close to an in-memory reader
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// DownloadURL returns a time-limited direct download URL for a task file
+// Returns "" for local storage, where files are served through the file API instead
+func (s *FileStorageService) DownloadURL(metadata *FileMetadata, relativeFilePath string) string {
+	if s.objects == nil || metadata.ObjectPrefix == "" {
+		return ""
+	}
+	url, err := s.objects.PresignGetURL(metadata.ObjectPrefix+"/"+relativeFilePath, DefaultDownloadURLExpiry)
+	if err != nil {
+		logging.Warnf("Warning: failed to presign download URL for %s: %v", relativeFilePath, err)
+		return ""
+	}
+	return url
+}
+
+// containsPath reports whether paths contains p
+func containsPath(paths []string, p string) bool {
+	for _, candidate := range paths {
+		if candidate == p {
+			return true
+		}
+	}
+	return false
+}
+
 // Close cleans up resources (currently no-op, but useful for future extensions)
 func (s *FileStorageService) Close() error {
 	return nil
@@ -332,7 +639,7 @@ func (s *FileStorageService) ListUserFilesWithAccess(requestingUserID, targetUse
 
 	s.accessControl.AuditFileAccess(requestingUserID, "list_files", targetUserID, true)
 
-	log.Printf("🔐 [Access Control] User %s accessed file list for user %s (%d files)",
+	logging.Infof("🔐 [Access Control] User %s accessed file list for user %s (%d files)",
 		requestingUserID, targetUserID, len(files))
 
 	// Convert []FileMetadata to []*FileMetadata
@@ -357,7 +664,7 @@ func (s *FileStorageService) GetTaskFilesWithAccess(requestingUserID, targetUser
 	s.accessControl.AuditFileAccess(requestingUserID, "get_task", taskID, err == nil)
 
 	if err == nil {
-		log.Printf("🔐 [Access Control] User %s accessed task %s files (user: %s)",
+		logging.Infof("🔐 [Access Control] User %s accessed task %s files (user: %s)",
 			requestingUserID, taskID, targetUserID)
 	}
 
@@ -378,23 +685,42 @@ func (s *FileStorageService) ReadFileWithAccess(requestingUserID, targetUserID,
 		return nil, err
 	}
 
-	// Get full file path
-	fullPath, err := s.GetFilePath(targetUserID, taskID, filePath)
-	if err != nil {
+	// Read file from disk or object storage
+	data, err := s.readTaskFile(targetUserID, taskID, filePath)
+	s.accessControl.AuditFileAccess(requestingUserID, "read_file", filePath, err == nil)
+
+	if err == nil {
+		logging.Infof("🔐 [Access Control] User %s read file %s (task: %s, user: %s, size: %d bytes)",
+			requestingUserID, filePath, taskID, targetUserID, len(data))
+	}
+
+	return data, err
+}
+
+// OpenFileWithAccess opens a file for streaming with access control, returning its modification time
+// The caller must close the returned file
+func (s *FileStorageService) OpenFileWithAccess(requestingUserID, targetUserID, taskID, filePath string) (io.ReadSeekCloser, time.Time, error) {
+	// Check access permission
+	if err := s.accessControl.CanAccessFiles(requestingUserID, targetUserID); err != nil {
 		s.accessControl.AuditFileAccess(requestingUserID, "read_file", filePath, false)
-		return nil, err
+		return nil, time.Time{}, err
+	}
+
+	// Sanitize file path to prevent directory traversal
+	if err := s.accessControl.ValidateFilePath(filePath); err != nil {
+		s.accessControl.AuditFileAccess(requestingUserID, "read_file", filePath, false)
+		return nil, time.Time{}, err
 	}
 
-	// Read file
-	data, err := os.ReadFile(fullPath)
+	file, modTime, err := s.openTaskFile(targetUserID, taskID, filePath)
 	s.accessControl.AuditFileAccess(requestingUserID, "read_file", filePath, err == nil)
 
 	if err == nil {
-		log.Printf("🔐 [Access Control] User %s read file %s (task: %s, user: %s, size: %d bytes)",
-			requestingUserID, filePath, taskID, targetUserID, len(data))
+		logging.Infof("🔐 [Access Control] User %s opened file %s (task: %s, user: %s)",
+			requestingUserID, filePath, taskID, targetUserID)
 	}
 
-	return data, err
+	return file, modTime, err
 }
 
 // DeleteTaskFilesWithAccess deletes task files with access control
@@ -410,7 +736,7 @@ func (s *FileStorageService) DeleteTaskFilesWithAccess(requestingUserID, targetU
 	s.accessControl.AuditFileAccess(requestingUserID, "delete_task", taskID, err == nil)
 
 	if err == nil {
-		log.Printf("🔐 [Access Control] User %s deleted task %s files (user: %s)",
+		logging.Infof("🔐 [Access Control] User %s deleted task %s files (user: %s)",
 			requestingUserID, taskID, targetUserID)
 	}
 
@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDownloadURLExpiry is how long presigned object download URLs stay valid
+const DefaultDownloadURLExpiry = time.Hour
+
+// unsignedPayload skips payload hashing so uploads can be streamed from disk
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Config configures an S3-compatible object store (AWS S3, MinIO, ...)
+type S3Config struct {
+	Endpoint  string // Base URL, e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	Bucket    string
+	Region    string // Defaults to us-east-1
+	AccessKey string
+	SecretKey string
+}
+
+// ObjectInfo describes a single stored object
+type ObjectInfo struct {
+	Key  string
+	Size int64
+}
+
+// objectStore is the object storage backend used by FileStorageService
+type objectStore interface {
+	PutObject(ctx context.Context, key string, body io.Reader, size int64) error
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+	ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	DeleteObject(ctx context.Context, key string) error
+	PresignGetURL(key string, expiry time.Duration) (string, error)
+	Location(key string) string
+}
+
+// S3Store reads and writes objects in an S3-compatible bucket
+// Requests use path-style addressing and AWS Signature Version 4, so MinIO works without DNS setup
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time // Signing clock (replaceable in tests)
+}
+
+// NewS3Store creates a store for the configured bucket
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 endpoint and bucket are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+
+	return &S3Store{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+// CheckBucket verifies the bucket exists and the credentials can reach it
+func (s *S3Store) CheckBucket(ctx context.Context) error {
+	resp, err := s.do(ctx, http.MethodHead, "", nil, nil, -1)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bucket %s not accessible: %s", s.cfg.Bucket, resp.Status)
+	}
+	return nil
+}
+
+// PutObject uploads size bytes from body to key
+func (s *S3Store) PutObject(ctx context.Context, key string, body io.Reader, size int64) error {
+	resp, err := s.do(ctx, http.MethodPut, key, nil, body, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error("put", key, resp)
+	}
+	return nil
+}
+
+// GetObject opens the object at key; the caller closes the returned reader
+func (s *S3Store) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("object %s not found", key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error("get", key, resp)
+	}
+	return resp.Body, nil
+}
+
+// listBucketResult is the subset of a ListObjectsV2 response used here
+type listBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListObjects returns every object whose key starts with prefix, following pagination
+func (s *S3Store) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, -1)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return nil, s3Error("list", prefix, resp)
+		}
+
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode object list: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			objects = append(objects, ObjectInfo{Key: obj.Key, Size: obj.Size})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// DeleteObject removes the object at key (deleting a missing object is not an error)
+func (s *S3Store) DeleteObject(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, -1)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return s3Error("delete", key, resp)
+	}
+	return nil
+}
+
+// PresignGetURL returns a URL that downloads key without credentials until expiry passes
+func (s *S3Store) PresignGetURL(key string, expiry time.Duration) (string, error) {
+	if expiry <= 0 {
+		expiry = DefaultDownloadURLExpiry
+	}
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", now.Format("20060102"), s.cfg.Region)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	u := s.objectURL(key, query)
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	query.Set("X-Amz-Signature", s.signature(now, scope, canonical))
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// Location returns a human-readable s3:// URI for key
+func (s *S3Store) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.cfg.Bucket, key)
+}
+
+// do sends a signed request for key (the bucket itself when key is empty)
+// size is the body length, or -1 when there is no body
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	u := s.objectURL(key, query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("build S3 request: %w", err)
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	s.sign(req, query)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s %s: %w", strings.ToLower(method), key, err)
+	}
+	return resp, nil
+}
+
+// objectURL builds the path-style URL /<bucket>/<key>
+func (s *S3Store) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimRight(s.endpoint.Path, "/") + "/" + s.cfg.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (s *S3Store) sign(req *http.Request, query url.Values) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", now.Format("20060102"), s.cfg.Region)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", req.URL.Host, unsignedPayload, amzDate)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(query),
+		canonicalHeaders,
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, s.signature(now, scope, canonical)))
+}
+
+// signature computes the hex SigV4 signature of a canonical request
+func (s *S3Store) signature(now time.Time, scope, canonicalRequest string) string {
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// hmacSHA256 returns HMAC-SHA256(key, data)
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by key, as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath URI-encodes every path segment, keeping the slashes between them
+func escapePath(p string) string {
+	return uriEncode(p, false)
+}
+
+// uriEncode percent-encodes everything except unreserved characters (and '/' unless encodeSlash)
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3Error turns an unexpected S3 response into an error including the response body
+func s3Error(op, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 %s %s failed: %s: %s", op, key, resp.Status, strings.TrimSpace(string(body)))
+}
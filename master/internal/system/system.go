@@ -2,11 +2,12 @@ package system
 
 import (
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"runtime"
 	"syscall"
+
+	"master/internal/logging"
 )
 
 // SystemInfo holds system information collected at runtime
@@ -50,7 +51,7 @@ func CollectSystemInfo() (*SystemInfo, error) {
 			}
 		}
 	} else {
-		log.Printf("Warning: Failed to get IP addresses: %v", err)
+		logging.Warnf("Warning: Failed to get IP addresses: %v", err)
 	}
 
 	return info, nil
@@ -73,18 +74,18 @@ func (s *SystemInfo) GetMasterAddress() string {
 
 // LogSystemInfo logs the collected system information
 func (s *SystemInfo) LogSystemInfo() {
-	log.Printf("=== System Information ===")
-	log.Printf("Hostname: %s", s.Hostname)
-	log.Printf("IP Addresses: %v", s.IPAddresses)
-	log.Printf("OS: %s", s.OS)
-	log.Printf("Architecture: %s", s.Arch)
-	log.Printf("CPU Cores: %d", s.NumCPU)
-	log.Printf("Process ID: %d", s.PID)
-	log.Printf("User ID: %d", s.UID)
-	log.Printf("Group ID: %d", s.GID)
-	log.Printf("Master Address: %s", s.GetMasterAddress())
-	log.Printf("Master Port: %s", s.GetMasterPort())
-	log.Printf("==========================")
+	logging.Infof("=== System Information ===")
+	logging.Infof("Hostname: %s", s.Hostname)
+	logging.Infof("IP Addresses: %v", s.IPAddresses)
+	logging.Infof("OS: %s", s.OS)
+	logging.Infof("Architecture: %s", s.Arch)
+	logging.Infof("CPU Cores: %d", s.NumCPU)
+	logging.Infof("Process ID: %d", s.PID)
+	logging.Infof("User ID: %d", s.UID)
+	logging.Infof("Group ID: %d", s.GID)
+	logging.Infof("Master Address: %s", s.GetMasterAddress())
+	logging.Infof("Master Port: %s", s.GetMasterPort())
+	logging.Infof("==========================")
 }
 
 // SetMasterPort sets the master's communication port
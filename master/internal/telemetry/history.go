@@ -0,0 +1,81 @@
+package telemetry
+
+// DefaultHistorySize is the number of telemetry samples kept per worker (30 minutes at a 5s heartbeat)
+const DefaultHistorySize = 360
+
+// TelemetrySample is a single point in a worker's telemetry history
+type TelemetrySample struct {
+	Timestamp   int64
+	CpuUsage    float64
+	MemoryUsage float64
+	GpuUsage    float64
+}
+
+// sampleRing is a fixed-size ring buffer of telemetry samples; the oldest sample is overwritten when full
+type sampleRing struct {
+	samples []TelemetrySample
+	next    int // Index the next sample is written to
+	count   int
+}
+
+// newSampleRing creates a ring buffer holding up to size samples
+func newSampleRing(size int) *sampleRing {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &sampleRing{samples: make([]TelemetrySample, size)}
+}
+
+// add appends a sample, overwriting the oldest one when the buffer is full
+func (r *sampleRing) add(sample TelemetrySample) {
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.count < len(r.samples) {
+		r.count++
+	}
+}
+
+// last returns up to n of the most recent samples, oldest first
+func (r *sampleRing) last(n int) []TelemetrySample {
+	if n <= 0 || n > r.count {
+		n = r.count
+	}
+
+	result := make([]TelemetrySample, n)
+	start := (r.next - n + len(r.samples)) % len(r.samples)
+	for i := 0; i < n; i++ {
+		result[i] = r.samples[(start+i)%len(r.samples)]
+	}
+	return result
+}
+
+// RecordSample appends a sample to a worker's telemetry history
+func (tm *TelemetryManager) RecordSample(workerID string, sample TelemetrySample) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.recordSampleLocked(workerID, sample)
+}
+
+// recordSampleLocked appends a sample to a worker's history; the caller must hold tm.mu
+func (tm *TelemetryManager) recordSampleLocked(workerID string, sample TelemetrySample) {
+	ring, exists := tm.history[workerID]
+	if !exists {
+		ring = newSampleRing(DefaultHistorySize)
+		tm.history[workerID] = ring
+	}
+	ring.add(sample)
+}
+
+// GetWorkerHistory returns up to points of a worker's most recent telemetry samples, oldest first
+// points <= 0 returns the full history; the bool is false when the worker is unknown
+func (tm *TelemetryManager) GetWorkerHistory(workerID string, points int) ([]TelemetrySample, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	ring, exists := tm.history[workerID]
+	if !exists {
+		_, registered := tm.workerData[workerID]
+		return []TelemetrySample{}, registered
+	}
+	return ring.last(points), true
+}
@@ -2,10 +2,11 @@ package telemetry
 
 import (
 	"context"
-	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"master/internal/logging"
 	pb "master/proto"
 )
 
@@ -20,6 +21,9 @@ type WorkerTelemetryData struct {
 	IsActive     bool
 }
 
+// HeartbeatBufferSize is the number of heartbeats buffered per worker before stale samples are dropped
+const HeartbeatBufferSize = 10
+
 // TelemetryManager manages telemetry reception from multiple workers
 // Each worker gets its own goroutine to process heartbeats
 type TelemetryManager struct {
@@ -27,10 +31,18 @@ type TelemetryManager struct {
 	workerData map[string]*WorkerTelemetryData
 	mu         sync.RWMutex
 
+	// Recent samples per worker for time-series queries (guarded by mu)
+	history map[string]*sampleRing
+
 	// Map of worker ID to their heartbeat channel
 	workerChannels map[string]chan *pb.Heartbeat
 	channelMu      sync.RWMutex
 
+	// Heartbeats discarded because a worker's thread fell behind (oldest samples go first)
+	droppedSamples map[string]uint64
+	totalDropped   atomic.Uint64
+	dropMu         sync.Mutex
+
 	// Callback to notify when telemetry is updated (optional)
 	onUpdate func(workerID string, data *WorkerTelemetryData)
 
@@ -51,7 +63,9 @@ func NewTelemetryManager(inactivityTimeout time.Duration) *TelemetryManager {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &TelemetryManager{
 		workerData:        make(map[string]*WorkerTelemetryData),
+		history:           make(map[string]*sampleRing),
 		workerChannels:    make(map[string]chan *pb.Heartbeat),
+		droppedSamples:    make(map[string]uint64),
 		ctx:               ctx,
 		cancel:            cancel,
 		inactivityTimeout: inactivityTimeout,
@@ -78,12 +92,12 @@ func (tm *TelemetryManager) RegisterWorker(workerID string) {
 
 	// Check if worker already has a channel
 	if _, exists := tm.workerChannels[workerID]; exists {
-		log.Printf("Worker %s already registered for telemetry", workerID)
+		logging.Infof("Worker %s already registered for telemetry", workerID)
 		return
 	}
 
 	// Create channel for this worker's heartbeats
-	heartbeatChan := make(chan *pb.Heartbeat, 10) // Buffered channel
+	heartbeatChan := make(chan *pb.Heartbeat, HeartbeatBufferSize) // Buffered channel
 	tm.workerChannels[workerID] = heartbeatChan
 
 	// Initialize worker data
@@ -100,7 +114,7 @@ func (tm *TelemetryManager) RegisterWorker(workerID string) {
 	go tm.processWorkerTelemetry(workerID, heartbeatChan)
 
 	if !tm.quietMode {
-		log.Printf("Telemetry manager: Registered worker %s with dedicated thread", workerID)
+		logging.Infof("Telemetry manager: Registered worker %s with dedicated thread", workerID)
 	}
 }
 
@@ -113,29 +127,36 @@ func (tm *TelemetryManager) UnregisterWorker(workerID string) {
 	if ch, exists := tm.workerChannels[workerID]; exists {
 		close(ch)
 		delete(tm.workerChannels, workerID)
-		log.Printf("Telemetry manager: Unregistered worker %s", workerID)
+		logging.Infof("Telemetry manager: Unregistered worker %s", workerID)
 	}
 
-	// Remove worker data
+	// Remove worker data and history
 	tm.mu.Lock()
 	delete(tm.workerData, workerID)
+	delete(tm.history, workerID)
 	tm.mu.Unlock()
 }
 
 // ProcessHeartbeat receives a heartbeat and forwards it to the worker's dedicated thread
+// It never blocks: when the worker's buffer is full the oldest queued sample is dropped
+// so the newest telemetry still gets through, and the drop is counted
 func (tm *TelemetryManager) ProcessHeartbeat(hb *pb.Heartbeat) error {
 	tm.channelMu.RLock()
-	ch, exists := tm.workerChannels[hb.WorkerId]
+	_, exists := tm.workerChannels[hb.WorkerId]
 	tm.channelMu.RUnlock()
 
 	if !exists {
 		// Worker not registered, auto-register it
 		tm.RegisterWorker(hb.WorkerId)
+	}
+
+	// Hold the read lock while sending so the channel cannot be closed underneath us
+	tm.channelMu.RLock()
+	defer tm.channelMu.RUnlock()
 
-		// Get the channel again after registration
-		tm.channelMu.RLock()
-		ch = tm.workerChannels[hb.WorkerId]
-		tm.channelMu.RUnlock()
+	ch, exists := tm.workerChannels[hb.WorkerId]
+	if !exists {
+		return nil // Unregistered concurrently
 	}
 
 	// Send heartbeat to worker's dedicated thread (non-blocking)
@@ -143,16 +164,55 @@ func (tm *TelemetryManager) ProcessHeartbeat(hb *pb.Heartbeat) error {
 	case ch <- hb:
 		return nil
 	default:
-		log.Printf("Warning: Heartbeat channel full for worker %s, dropping heartbeat", hb.WorkerId)
-		return nil
 	}
+
+	// Buffer full: discard the oldest (stale) sample and retry once
+	select {
+	case <-ch:
+		tm.recordDrop(hb.WorkerId)
+	default:
+	}
+
+	select {
+	case ch <- hb:
+	default:
+		// Another heartbeat took the freed slot; it is at least as fresh as this one
+		tm.recordDrop(hb.WorkerId)
+	}
+	return nil
+}
+
+// recordDrop counts a discarded heartbeat, logging the first and every 100th drop per worker
+func (tm *TelemetryManager) recordDrop(workerID string) {
+	tm.totalDropped.Add(1)
+
+	tm.dropMu.Lock()
+	tm.droppedSamples[workerID]++
+	dropped := tm.droppedSamples[workerID]
+	tm.dropMu.Unlock()
+
+	if dropped == 1 || dropped%100 == 0 {
+		logging.Warnf("Warning: Telemetry thread for worker %s is falling behind, dropped %d stale heartbeat(s)", workerID, dropped)
+	}
+}
+
+// DroppedSamples returns the number of heartbeats dropped for a worker
+func (tm *TelemetryManager) DroppedSamples(workerID string) uint64 {
+	tm.dropMu.Lock()
+	defer tm.dropMu.Unlock()
+	return tm.droppedSamples[workerID]
+}
+
+// TotalDroppedSamples returns the number of heartbeats dropped across all workers
+func (tm *TelemetryManager) TotalDroppedSamples() uint64 {
+	return tm.totalDropped.Load()
 }
 
 // processWorkerTelemetry is the dedicated goroutine for processing a single worker's telemetry
 func (tm *TelemetryManager) processWorkerTelemetry(workerID string, heartbeatChan <-chan *pb.Heartbeat) {
 	defer tm.wg.Done()
 	if !tm.quietMode {
-		log.Printf("Started telemetry processing thread for worker %s", workerID)
+		logging.Debugf("Started telemetry processing thread for worker %s", workerID)
 	}
 
 	for {
@@ -161,7 +221,7 @@ func (tm *TelemetryManager) processWorkerTelemetry(workerID string, heartbeatCha
 			if !ok {
 				// Channel closed, worker unregistered
 				if !tm.quietMode {
-					log.Printf("Telemetry thread for worker %s shutting down", workerID)
+					logging.Debugf("Telemetry thread for worker %s shutting down", workerID)
 				}
 				return
 			}
@@ -172,7 +232,7 @@ func (tm *TelemetryManager) processWorkerTelemetry(workerID string, heartbeatCha
 		case <-tm.ctx.Done():
 			// Manager shutting down
 			if !tm.quietMode {
-				log.Printf("Telemetry thread for worker %s shutting down (manager closed)", workerID)
+				logging.Debugf("Telemetry thread for worker %s shutting down (manager closed)", workerID)
 			}
 			return
 		}
@@ -200,6 +260,13 @@ func (tm *TelemetryManager) updateWorkerTelemetry(hb *pb.Heartbeat) {
 	data.LastUpdate = time.Now().Unix()
 	data.IsActive = true
 
+	tm.recordSampleLocked(hb.WorkerId, TelemetrySample{
+		Timestamp:   data.LastUpdate,
+		CpuUsage:    hb.CpuUsage,
+		MemoryUsage: hb.MemoryUsage,
+		GpuUsage:    hb.GpuUsage,
+	})
+
 	// Call callback if set
 	if tm.onUpdate != nil {
 		// Call callback without holding lock to avoid deadlocks
@@ -260,7 +327,7 @@ func (tm *TelemetryManager) GetAllWorkerTelemetry() map[string]*WorkerTelemetryD
 func (tm *TelemetryManager) Start() {
 	tm.wg.Add(1)
 	go tm.checkInactivity()
-	log.Println("Telemetry manager started")
+	logging.Info("Telemetry manager started")
 }
 
 // checkInactivity periodically checks for inactive workers
@@ -290,7 +357,7 @@ func (tm *TelemetryManager) markInactiveWorkers() {
 		if now-data.LastUpdate > int64(tm.inactivityTimeout.Seconds()) {
 			if data.IsActive {
 				data.IsActive = false
-				log.Printf("Worker %s marked as inactive (no heartbeat for %v)", id, tm.inactivityTimeout)
+				logging.Infof("Worker %s marked as inactive (no heartbeat for %v)", id, tm.inactivityTimeout)
 			}
 		}
 	}
@@ -298,7 +365,7 @@ func (tm *TelemetryManager) markInactiveWorkers() {
 
 // Shutdown gracefully shuts down the telemetry manager
 func (tm *TelemetryManager) Shutdown() {
-	log.Println("Shutting down telemetry manager...")
+	logging.Info("Shutting down telemetry manager...")
 
 	// Cancel context to stop all goroutines
 	tm.cancel()
@@ -314,7 +381,7 @@ func (tm *TelemetryManager) Shutdown() {
 	// Wait for all goroutines to finish
 	tm.wg.Wait()
 
-	log.Println("Telemetry manager shutdown complete")
+	logging.Info("Telemetry manager shutdown complete")
 }
 
 // GetWorkerCount returns the number of registered workers
@@ -0,0 +1,72 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	pb "master/proto"
+)
+
+// TestProcessHeartbeatDropsStaleSamplesWithoutBlocking tests that a stalled worker thread never blocks the RPC path
+func TestProcessHeartbeatDropsStaleSamplesWithoutBlocking(t *testing.T) {
+	tm := NewTelemetryManager(30 * time.Second)
+
+	// A channel nobody reads stands in for a worker thread that has stalled
+	stalled := make(chan *pb.Heartbeat, HeartbeatBufferSize)
+	tm.workerChannels["worker-1"] = stalled
+
+	const flood = 1000
+	var slowest time.Duration
+	for i := 0; i < flood; i++ {
+		start := time.Now()
+		if err := tm.ProcessHeartbeat(&pb.Heartbeat{WorkerId: "worker-1", CpuUsage: float64(i)}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > slowest {
+			slowest = elapsed
+		}
+	}
+
+	if slowest > 50*time.Millisecond {
+		t.Errorf("Expected ProcessHeartbeat never to block, slowest call took %s", slowest)
+	}
+
+	expectedDrops := uint64(flood - HeartbeatBufferSize)
+	if got := tm.DroppedSamples("worker-1"); got != expectedDrops {
+		t.Errorf("Expected %d dropped samples, got %d", expectedDrops, got)
+	}
+	if got := tm.TotalDroppedSamples(); got != expectedDrops {
+		t.Errorf("Expected %d total dropped samples, got %d", expectedDrops, got)
+	}
+
+	// Stale samples were dropped, so the buffer holds the newest heartbeats in order
+	if len(stalled) != HeartbeatBufferSize {
+		t.Fatalf("Expected a full buffer of %d, got %d", HeartbeatBufferSize, len(stalled))
+	}
+	first := <-stalled
+	if first.CpuUsage != float64(flood-HeartbeatBufferSize) {
+		t.Errorf("Expected oldest buffered sample to be %d, got %.0f", flood-HeartbeatBufferSize, first.CpuUsage)
+	}
+}
+
+// TestSampleRingOverwritesOldest tests that the history ring keeps the newest samples in order once full
+func TestSampleRingOverwritesOldest(t *testing.T) {
+	ring := newSampleRing(3)
+	for i := 1; i <= 5; i++ {
+		ring.add(TelemetrySample{Timestamp: int64(i)})
+	}
+
+	samples := ring.last(0)
+	if len(samples) != 3 {
+		t.Fatalf("Expected 3 samples, got %d", len(samples))
+	}
+	for i, sample := range samples {
+		if sample.Timestamp != int64(i+3) {
+			t.Errorf("Sample %d: expected timestamp %d, got %d", i, i+3, sample.Timestamp)
+		}
+	}
+
+	if got := ring.last(10); len(got) != 3 {
+		t.Errorf("Expected requests beyond the buffer to return 3 samples, got %d", len(got))
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,25 +19,35 @@ import (
 	"master/internal/config"
 	"master/internal/db"
 	httpserver "master/internal/http"
+	"master/internal/logging"
+	"master/internal/notify"
 	"master/internal/scheduler"
 	"master/internal/server"
 	"master/internal/storage"
 	"master/internal/system"
 	"master/internal/telemetry"
-	pb "master/proto"
 
 	"google.golang.org/grpc"
 )
 
 func main() {
+	userFlag := flag.String("user", "", "User that CLI-submitted tasks belong to (overrides CLOUDAI_USER)")
+	flag.Parse()
+
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	logLevel, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logging.Warnf("⚠️  %v, using info", err)
+	}
+	logging.SetLevel(logLevel)
+
 	// Determine file storage base directory with fallback
 	fileStorageBaseDir := "/var/cloudai/files"
 	if err := os.MkdirAll(fileStorageBaseDir, 0700); err != nil {
 		// If /var/cloudai/files fails (permission denied), fallback to ~/.cloudai/files
-		log.Printf("Warning: Cannot create %s: %v", fileStorageBaseDir, err)
+		logging.Warnf("Warning: Cannot create %s: %v", fileStorageBaseDir, err)
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
 			log.Fatalf("Failed to get home directory: %v", err)
@@ -44,9 +56,9 @@ func main() {
 		if err := os.MkdirAll(fileStorageBaseDir, 0700); err != nil {
 			log.Fatalf("Failed to create fallback directory %s: %v", fileStorageBaseDir, err)
 		}
-		log.Printf("✓ Using fallback file storage directory: %s", fileStorageBaseDir)
+		logging.Infof("✓ Using fallback file storage directory: %s", fileStorageBaseDir)
 	} else {
-		log.Printf("✓ File storage directory ready (secure): %s", fileStorageBaseDir)
+		logging.Infof("✓ File storage directory ready (secure): %s", fileStorageBaseDir)
 	}
 
 	// Set environment variable for file storage components
@@ -74,82 +86,92 @@ func main() {
 	var fileStorage *storage.FileStorageService
 
 	if err := db.EnsureCollections(ctx, cfg); err != nil {
-		log.Printf("Warning: MongoDB initialization failed: %v", err)
-		log.Println("Continuing without database persistence...")
+		logging.Warnf("Warning: MongoDB initialization failed: %v", err)
+		logging.Info("Continuing without database persistence...")
 	} else {
-		log.Println("✓ MongoDB collections ensured")
+		logging.Info("✓ MongoDB collections ensured")
 
 		// Create worker database handler
 		var err error
 		workerDB, err = db.NewWorkerDB(ctx, cfg)
 		if err != nil {
-			log.Printf("Warning: Failed to create WorkerDB: %v", err)
-			log.Println("Continuing without database persistence...")
+			logging.Warnf("Warning: Failed to create WorkerDB: %v", err)
+			logging.Info("Continuing without database persistence...")
 			workerDB = nil
 		} else {
-			log.Println("✓ WorkerDB initialized")
+			logging.Info("✓ WorkerDB initialized")
 			defer workerDB.Close(context.Background())
 		}
 
 		// Create task database handler
 		taskDB, err = db.NewTaskDB(ctx, cfg)
 		if err != nil {
-			log.Printf("Warning: Failed to create TaskDB: %v", err)
+			logging.Warnf("Warning: Failed to create TaskDB: %v", err)
 			taskDB = nil
 		} else {
-			log.Println("✓ TaskDB initialized")
+			logging.Info("✓ TaskDB initialized")
 			defer taskDB.Close(context.Background())
 		}
 
 		// Create assignment database handler
 		assignmentDB, err = db.NewAssignmentDB(ctx, cfg)
 		if err != nil {
-			log.Printf("Warning: Failed to create AssignmentDB: %v", err)
+			logging.Warnf("Warning: Failed to create AssignmentDB: %v", err)
 			assignmentDB = nil
 		} else {
-			log.Println("✓ AssignmentDB initialized")
+			logging.Info("✓ AssignmentDB initialized")
 			defer assignmentDB.Close(context.Background())
 		}
 
 		// Create result database handler
 		resultDB, err = db.NewResultDB(ctx, cfg)
 		if err != nil {
-			log.Printf("Warning: Failed to create ResultDB: %v", err)
+			logging.Warnf("Warning: Failed to create ResultDB: %v", err)
 			resultDB = nil
 		} else {
-			log.Println("✓ ResultDB initialized")
+			logging.Info("✓ ResultDB initialized")
 			defer resultDB.Close(context.Background())
 		}
 
 		// Create user database handler
 		userDB, err = db.NewUserDB(ctx, cfg)
 		if err != nil {
-			log.Printf("Warning: Failed to create UserDB: %v", err)
+			logging.Warnf("Warning: Failed to create UserDB: %v", err)
 			userDB = nil
 		} else {
-			log.Println("✓ UserDB initialized")
+			logging.Info("✓ UserDB initialized")
 			defer userDB.Close(context.Background())
 		}
 
 		// Create file metadata database handler
 		fileMetadataDB, err = db.NewFileMetadataDB(ctx, cfg)
 		if err != nil {
-			log.Printf("Warning: Failed to create FileMetadataDB: %v", err)
+			logging.Warnf("Warning: Failed to create FileMetadataDB: %v", err)
 			fileMetadataDB = nil
 		} else {
-			log.Println("✓ FileMetadataDB initialized")
+			logging.Info("✓ FileMetadataDB initialized")
 			defer fileMetadataDB.Close(context.Background())
 		}
 	}
 
-	// Initialize file storage service
-	fileStorage, err = storage.NewFileStorageService(fileStorageBaseDir)
+	// Initialize file storage service (local disk, or an S3-compatible bucket)
+	if cfg.FileStorageBackend == "s3" {
+		fileStorage, err = storage.NewS3FileStorageService(fileStorageBaseDir, storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+	} else {
+		fileStorage, err = storage.NewFileStorageService(fileStorageBaseDir)
+	}
 	if err != nil {
-		log.Printf("Warning: Failed to create FileStorageService: %v", err)
-		log.Println("Continuing without file storage...")
+		logging.Warnf("Warning: Failed to create FileStorageService: %v", err)
+		logging.Info("Continuing without file storage...")
 		fileStorage = nil
 	} else {
-		log.Printf("✓ FileStorageService initialized (base: %s)", fileStorageBaseDir)
+		logging.Infof("✓ FileStorageService initialized (base: %s)", fileStorageBaseDir)
 		defer fileStorage.Close()
 	}
 
@@ -157,13 +179,13 @@ func main() {
 	// Initialize telemetry manager (30 second inactivity timeout)
 	telemetryMgr := telemetry.NewTelemetryManager(30 * time.Second)
 	telemetryMgr.Start()
-	log.Println("✓ Telemetry manager started")
+	logging.Info("✓ Telemetry manager started")
 
 	// Initialize tau store for runtime learning
 	tauStore := telemetry.NewInMemoryTauStore()
-	log.Println("✓ Tau store initialized with default values:")
+	logging.Info("✓ Tau store initialized with default values:")
 	for taskType, tau := range tauStore.GetAllTau() {
-		log.Printf("  - %s: %.1fs", taskType, tau)
+		logging.Debugf("  - %s: %.1fs", taskType, tau)
 	}
 
 	// Load SLA multiplier from environment or use default
@@ -171,28 +193,88 @@ func main() {
 	if cfg.SLAMultiplier > 0 {
 		slaMultiplier = cfg.SLAMultiplier
 	}
-	log.Printf("✓ SLA multiplier (k): %.1f", slaMultiplier)
+	logging.Infof("✓ SLA multiplier (k): %.1f", slaMultiplier)
 
 	// Create scheduler with RTS (Risk-aware Task Scheduling)
 	// Create Round-Robin as fallback
 	rrScheduler := scheduler.NewRoundRobinScheduler()
-	log.Println("✓ Round-Robin scheduler created (fallback)")
+	logging.Info("✓ Round-Robin scheduler created (fallback)")
 
 	// Create telemetry source adapter for RTS
+	systemReserve := scheduler.SystemReserve{
+		CPU:     cfg.ReserveCPU,
+		Memory:  cfg.ReserveMemory,
+		Storage: cfg.ReserveStorage,
+		GPU:     cfg.ReserveGPU,
+	}
 	telemetrySource := scheduler.NewMasterTelemetrySource(telemetryMgr, workerDB)
-	log.Println("✓ Telemetry source adapter created")
+	telemetrySource.SetSystemReserve(systemReserve)
+	logging.Info("✓ Telemetry source adapter created")
 
 	// Create RTS scheduler with Round-Robin fallback
 	paramsPath := "config/ga_output.json"
 	rtsScheduler := scheduler.NewRTSScheduler(rrScheduler, tauStore, telemetrySource, paramsPath, slaMultiplier)
-	log.Printf("✓ RTS scheduler initialized (params: %s)", paramsPath)
-	log.Printf("  - Scheduler: %s", rtsScheduler.GetName())
-	log.Printf("  - Fallback: Round-Robin")
-	log.Printf("  - Parameter hot-reload: enabled (every 30s)")
+	logging.Infof("✓ RTS scheduler initialized (params: %s)", paramsPath)
+	logging.Debugf("  - Scheduler: %s", rtsScheduler.GetName())
+	logging.Debugf("  - Fallback: Round-Robin")
+	logging.Debugf("  - Parameter hot-reload: enabled (every 30s)")
+
+	// FAIR_SHARE wraps RTS so users who consumed the least recently are scheduled first
+	var activeScheduler scheduler.Scheduler = rtsScheduler
+	if cfg.FairShare {
+		activeScheduler = scheduler.NewFairShareScheduler(rtsScheduler, cfg.FairShareHalfLife)
+		logging.Infof("✓ Fair-share scheduling enabled (usage half-life %s)", cfg.FairShareHalfLife)
+	}
 
 	masterServer := server.NewMasterServer(workerDB, taskDB, assignmentDB, resultDB, fileMetadataDB, fileStorage, telemetryMgr)
-	masterServer.SetScheduler(rtsScheduler)
-	log.Printf("✓ Master server configured with %s scheduler", rtsScheduler.GetName())
+	masterServer.SetScheduler(activeScheduler)
+	logging.Infof("✓ Master server configured with %s scheduler", activeScheduler.GetName())
+
+	masterServer.SetOvercommitRatios(scheduler.OvercommitRatios{
+		CPU:     cfg.OvercommitCPU,
+		Memory:  cfg.OvercommitMemory,
+		Storage: cfg.OvercommitStorage,
+		GPU:     cfg.OvercommitGPU,
+	})
+	logging.Infof("✓ Over-commit ratios: CPU %.2fx, memory %.2fx, storage %.2fx, GPU %.2fx",
+		cfg.OvercommitCPU, cfg.OvercommitMemory, cfg.OvercommitStorage, cfg.OvercommitGPU)
+	masterServer.SetSystemReserve(systemReserve)
+	logging.Infof("✓ System reserve per worker: CPU %.2f, memory %.2f GB, storage %.2f GB, GPU %.2f",
+		cfg.ReserveCPU, cfg.ReserveMemory, cfg.ReserveStorage, cfg.ReserveGPU)
+
+	if len(cfg.NotifyWebhookURLs) > 0 {
+		masterServer.SetNotificationSink(notify.NewWebhookSink(cfg.NotifyWebhookURLs))
+		logging.Infof("✓ Task notifications: %d webhook(s)", len(cfg.NotifyWebhookURLs))
+	}
+
+	// TLS for gRPC and the HTTP API; plaintext only when explicitly allowed for development
+	tlsConfig := server.TLSConfig{
+		CertFile:          cfg.TLSCertFile,
+		KeyFile:           cfg.TLSKeyFile,
+		CAFile:            cfg.TLSCAFile,
+		RequireClientCert: cfg.TLSRequireClientCert,
+	}
+	if !tlsConfig.Enabled() && !cfg.AllowInsecure {
+		log.Fatalf("TLS is not configured: set TLS_CERT_FILE and TLS_KEY_FILE, or ALLOW_INSECURE=true for development")
+	}
+	grpcCreds, err := tlsConfig.ServerCredentials()
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+	dialCreds, err := tlsConfig.ClientCredentials()
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+	masterServer.SetTransportCredentials(dialCreds)
+	masterServer.SetRequireWorkerCert(tlsConfig.RequireClientCert)
+	if tlsConfig.Enabled() {
+		logging.Infof("✓ TLS enabled for gRPC and the HTTP API (cert: %s)", cfg.TLSCertFile)
+		if tlsConfig.RequireClientCert {
+			logging.Info("✓ Mutual TLS: workers must present a client certificate issued to their worker ID")
+		}
+	} else {
+		logging.Warn("⚠️  TLS disabled (ALLOW_INSECURE=true) - do not use outside development")
+	}
 
 	// Set master info
 	masterID := "master-1"
@@ -200,23 +282,41 @@ func main() {
 	masterServer.SetMasterInfo(masterID, masterAddress)
 
 	// Start task queue processor
+	masterServer.SetQueueConcurrency(cfg.QueueConcurrency)
+	masterServer.SetMaxQueueLength(cfg.MaxQueueLength)
+	masterServer.SetAutoscaleWindow(cfg.AutoscaleWindow)
+	if cfg.PreemptionPriority > 0 {
+		masterServer.SetPreemption(int32(cfg.PreemptionPriority), cfg.PreemptionCooldown)
+		logging.Infof("✓ Preemption enabled for tasks with priority >= %d (cooldown %s per worker)", cfg.PreemptionPriority, cfg.PreemptionCooldown)
+	}
 	masterServer.StartQueueProcessor()
-	log.Println("✓ Task queue processor started")
+	logging.Infof("✓ Task queue processor started (max %d concurrent assignments)", cfg.QueueConcurrency)
 
 	// Initialize HistoryDB for AOD/GA training
 	var historyDB *db.HistoryDB
 	if cfg.MongoDBURI != "" {
 		historyDB, err = db.NewHistoryDB(ctx, cfg)
 		if err != nil {
-			log.Printf("Warning: Failed to create HistoryDB: %v", err)
-			log.Println("AOD/GA training will be disabled")
+			logging.Warnf("Warning: Failed to create HistoryDB: %v", err)
+			logging.Info("AOD/GA training will be disabled")
 			historyDB = nil
 		} else {
-			log.Println("✓ HistoryDB initialized for AOD/GA training")
+			logging.Info("✓ HistoryDB initialized for AOD/GA training")
 			defer historyDB.Close(context.Background())
 		}
 	}
 
+	// Seed tau from historical runtimes so early estimates are not just the defaults
+	if historyDB != nil && cfg.TauBootstrap {
+		logging.Infof("✓ Bootstrapping tau from task history (window: %s, min samples: %d)", cfg.TauBootstrapWindow, cfg.TauBootstrapMinSamples)
+		bootstrapped, err := aod.BootstrapTau(ctx, historyDB, tauStore, cfg.TauBootstrapWindow, cfg.TauBootstrapMinSamples)
+		if err != nil {
+			logging.Warnf("Warning: Failed to bootstrap tau: %v", err)
+		} else if len(bootstrapped) == 0 {
+			logging.Debug("  - Not enough history yet, keeping default tau values")
+		}
+	}
+
 	// Start AOD training ticker for parameter optimization
 	if historyDB != nil {
 		// Start AOD training ticker (runs every 60 seconds)
@@ -225,40 +325,95 @@ func main() {
 			ticker := time.NewTicker(aodTrainingInterval)
 			defer ticker.Stop()
 
-			log.Printf("✓ AOD training ticker started (interval: %s)", aodTrainingInterval)
-			log.Printf("  - Training method: Linear regression (Theta) + Direct computation (Affinity/Penalty)")
-			log.Printf("  - Training data window: 24 hours")
-			log.Printf("  - Output: %s", paramsPath)
-			log.Printf("  - RTS hot-reload: every 30s")
+			logging.Infof("✓ AOD training ticker started (interval: %s)", aodTrainingInterval)
+			logging.Debugf("  - Training method: Linear regression (Theta) + Direct computation (Affinity/Penalty)")
+			logging.Debugf("  - Training data window: 24 hours")
+			logging.Debugf("  - Affinity half-life: %s", cfg.AffinityHalfLife)
+			logging.Debugf("  - Output: %s", paramsPath)
+			logging.Debugf("  - RTS hot-reload: every 30s")
 
 			for range ticker.C {
-				log.Println("🧬 Starting AOD training cycle...")
-				if err := aod.RunTraining(context.Background(), historyDB, paramsPath); err != nil {
-					log.Printf("❌ AOD training error: %v", err)
+				logging.Info("🧬 Starting AOD training cycle...")
+				if err := aod.RunTraining(context.Background(), historyDB, paramsPath, cfg.AffinityHalfLife); err != nil {
+					logging.Errorf("❌ AOD training error: %v", err)
 				} else {
-					log.Println("✅ AOD training cycle completed successfully")
+					logging.Info("✅ AOD training cycle completed successfully")
 				}
 			}
 		}()
 	} else {
-		log.Println("⚠️  AOD training disabled (HistoryDB not available)")
-		log.Println("  - RTS will use default parameters from config/ga_output.json")
+		logging.Warn("⚠️  AOD training disabled (HistoryDB not available)")
+		logging.Debug("  - RTS will use default parameters from config/ga_output.json")
 	}
 
 	// Load workers from database
 	if workerDB != nil {
 		if err := masterServer.LoadWorkersFromDB(ctx); err != nil {
-			log.Printf("Warning: Failed to load workers from DB: %v", err)
+			logging.Warnf("Warning: Failed to load workers from DB: %v", err)
+		}
+	}
+
+	// Restore tasks left on hold, which wait for an explicit release
+	if taskDB != nil {
+		if err := masterServer.LoadHeldTasksFromDB(ctx); err != nil {
+			logging.Warnf("Warning: Failed to load held tasks from DB: %v", err)
 		}
 	}
 
+	// Record worker capacity as workers join and leave, so simulations replay the cluster of the time
+	if historyDB != nil {
+		masterServer.StartWorkerSnapshotRecorder(historyDB)
+	}
+
 	// Start worker reconnection monitor
+	masterServer.SetReconnectConcurrency(cfg.ReconnectConcurrency)
 	masterServer.StartWorkerReconnectionMonitor()
-	log.Println("✓ Worker reconnection monitor started")
+	logging.Infof("✓ Worker reconnection monitor started (max %d concurrent dials)", cfg.ReconnectConcurrency)
+
+	// Start stuck task collector (disabled unless TASK_GC_INTERVAL is set)
+	if cfg.TaskGCInterval > 0 {
+		masterServer.StartTaskGC(cfg.TaskGCInterval, cfg.TaskGCPolicy)
+	}
+
+	// Delete finished tasks past their retention (disabled unless TASK_RETENTION_DAYS is set)
+	if cfg.TaskRetentionDays > 0 {
+		masterServer.StartRetentionSweeper(server.DefaultRetentionSweepInterval,
+			time.Duration(cfg.TaskRetentionDays)*24*time.Hour, cfg.TaskRetentionDryRun)
+	}
+
+	// Cordon workers during their recurring maintenance windows
+	masterServer.StartMaintenanceChecker(server.DefaultMaintenanceCheckInterval)
+
+	// Accept unknown workers that present a valid join token (strict pre-registration otherwise)
+	if cfg.AutoRegister {
+		if cfg.JoinTokenSecret == "" {
+			logging.Warn("⚠️  AUTO_REGISTER=true but JOIN_TOKEN_SECRET is not set - workers must still be pre-registered")
+		} else {
+			masterServer.EnableAutoRegister(cfg.JoinTokenSecret)
+			logging.Info("✓ Worker auto-registration enabled (generate tokens with: join-token [ttl])")
+		}
+	}
+
+	// Workers claiming far more than the capacity declared at registration are flagged (or rejected)
+	masterServer.SetCapacityVerification(cfg.CapacityTolerance, cfg.RejectCapacityMismatch)
+
+	// Heartbeats arriving faster than the floor (a flapping or misconfigured worker) are ignored
+	masterServer.SetMinHeartbeatInterval(cfg.MinHeartbeatInterval)
+	masterServer.SetAllowedTaskNetworks(cfg.TaskNetworks)
+	if len(cfg.TaskNetworks) > 0 {
+		logging.Infof("✓ Tasks may also use networks: %s", strings.Join(cfg.TaskNetworks, ", "))
+	}
+
+	// Keepalive pings stop NAT and firewalls from silently dropping idle worker connections
+	keepalive := server.KeepaliveConfig{
+		Time:                cfg.GRPCKeepaliveTime,
+		Timeout:             cfg.GRPCKeepaliveTimeout,
+		PermitWithoutStream: cfg.GRPCKeepalivePermitWithoutStream,
+	}
+	masterServer.SetKeepalive(keepalive)
 
 	// Start gRPC server in background
-	grpcServer := grpc.NewServer()
-	pb.RegisterMasterWorkerServer(grpcServer, masterServer)
+	grpcServer := server.NewGRPCServer(masterServer, cfg.GRPCReflection, keepalive, grpcCreds)
 	go startGRPCServer(grpcServer, masterAddress)
 
 	// Start HTTP telemetry server (optional, configurable via HTTP_PORT env var)
@@ -273,43 +428,102 @@ func main() {
 
 		// Create telemetry server with WebSocket support
 		httpTelemetryServer = httpserver.NewTelemetryServer(port, telemetryMgr)
+		httpTelemetryServer.SetShutdownGracePeriod(cfg.HTTPShutdownGracePeriod)
+		if tlsConfig.Enabled() {
+			httpTLS, err := tlsConfig.ServerTLS()
+			if err != nil {
+				log.Fatalf("Failed to load TLS credentials: %v", err)
+			}
+			httpTelemetryServer.SetTLS(httpTLS)
+		}
 
-		// Create task and worker API handlers
+		// Create task, worker, admin, scheduler, capacity, metrics and event API handlers
 		taskHandler := httpserver.NewTaskAPIHandler(masterServer, taskDB, assignmentDB, resultDB)
 		workerHandler := httpserver.NewWorkerAPIHandler(masterServer, workerDB, assignmentDB, telemetryMgr)
+		adminHandler := httpserver.NewAdminAPIHandler(masterServer)
+		schedulerHandler := httpserver.NewSchedulerAPIHandler(rtsScheduler)
+		capacityHandler := httpserver.NewCapacityAPIHandler(masterServer)
+		metricsHandler := httpserver.NewMetricsHandler(masterServer.SchedulerMetrics())
+		eventsHandler := httpserver.NewEventsAPIHandler(masterServer)
 
 		// Add API routes
 		httpTelemetryServer.RegisterTaskHandlers(taskHandler)
 		httpTelemetryServer.RegisterWorkerHandlers(workerHandler)
+		httpTelemetryServer.RegisterAdminHandlers(adminHandler)
+		httpTelemetryServer.RegisterSchedulerHandlers(schedulerHandler)
+		httpTelemetryServer.RegisterCapacityHandlers(capacityHandler)
+		httpTelemetryServer.RegisterMetricsHandlers(metricsHandler)
+		httpTelemetryServer.RegisterEventHandlers(eventsHandler)
 
 		// Register file handlers if file storage is available
 		if fileStorage != nil {
 			fileHandler := httpserver.NewFileAPIHandler(fileStorage)
+			if fileMetadataDB != nil {
+				fileHandler.SetFileMetadataDB(fileMetadataDB)
+			}
 			httpTelemetryServer.RegisterFileHandlers(fileHandler)
-			log.Println("✓ File API handlers registered")
+			logging.Info("✓ File API handlers registered")
 		}
 
 		// Register auth handlers if user database is available
+		var authHandler *httpserver.AuthHandler
 		if userDB != nil {
-			authHandler := httpserver.NewAuthHandler(userDB)
+			authHandler = httpserver.NewAuthHandler(userDB)
 			httpTelemetryServer.RegisterAuthHandlers(authHandler)
-			log.Println("✓ Auth API handlers registered")
+			logging.Info("✓ Auth API handlers registered")
+		}
+
+		// Register usage report handlers if task history is available (signed-in users see their own usage)
+		if historyDB != nil {
+			usageHandler := httpserver.NewUsageAPIHandler(historyDB)
+			if authHandler != nil {
+				usageHandler.SetRequestUser(authHandler.RequestUser)
+			}
+			httpTelemetryServer.RegisterUsageHandlers(usageHandler)
+			logging.Info("✓ Usage API handlers registered")
+		}
+
+		// Privileged endpoints (reconcile, worker registration, reservations) require the admin key
+		if cfg.AdminAPIKey != "" {
+			httpTelemetryServer.SetAdminKey(cfg.AdminAPIKey)
+			logging.Info("✓ Admin endpoints require the X-Admin-Key header")
+		} else {
+			logging.Warn("⚠️  ADMIN_API_KEY is not set - admin endpoints are open to every API client")
+		}
+
+		// Rate limit API route groups per client (authenticated user, else IP)
+		if len(cfg.RateLimits) > 0 {
+			limits := make(map[string]httpserver.RateLimit)
+			for group, spec := range cfg.RateLimits {
+				limit, err := httpserver.ParseRateLimit(spec)
+				if err != nil {
+					logging.Warnf("Warning: Ignoring rate limit for %s: %v", group, err)
+					continue
+				}
+				limits[group] = limit
+				logging.Infof("✓ Rate limit for %s API: %.1f req/s (burst %d)", group, limit.PerSecond, limit.Burst)
+			}
+			rateLimiter := httpserver.NewRateLimiter(limits)
+			if authHandler != nil {
+				rateLimiter.SetIdentifier(authHandler.RequestUser)
+			}
+			httpTelemetryServer.SetRateLimiter(rateLimiter)
 		}
 
 		go func() {
 			if err := httpTelemetryServer.Start(); err != nil && err != http.ErrServerClosed {
-				log.Printf("HTTP API server error: %v", err)
+				logging.Errorf("HTTP API server error: %v", err)
 			}
 		}()
-		log.Printf("✓ HTTP API server started on port %d", port)
-		log.Printf("  - Telemetry: GET /health, /telemetry, /workers")
-		log.Printf("  - WebSocket: WS /ws/telemetry, /ws/telemetry/{worker_id}")
-		log.Printf("  - Tasks: POST/GET/DELETE /api/tasks, GET /api/tasks/{id}")
-		log.Printf("  - Workers: GET /api/workers, /api/workers/{id}")
+		logging.Infof("✓ HTTP API server started on port %d", port)
+		logging.Debugf("  - Telemetry: GET /health, /telemetry, /workers")
+		logging.Debugf("  - WebSocket: WS /ws/telemetry, /ws/telemetry/{worker_id}")
+		logging.Debugf("  - Tasks: POST/GET/DELETE /api/tasks, GET /api/tasks/{id}")
+		logging.Debugf("  - Workers: GET /api/workers, /api/workers/{id}")
 		if fileStorage != nil {
-			log.Printf("  - Files: GET /api/files, /api/files/{task_id}")
-			log.Printf("           GET /api/files/{task_id}/download/{file_path}")
-			log.Printf("           DELETE /api/files/{task_id}")
+			logging.Debugf("  - Files: GET /api/files, /api/files/{task_id}")
+			logging.Debugf("           GET /api/files/{task_id}/download/{file_path}")
+			logging.Debugf("           DELETE /api/files/{task_id}")
 		}
 	}
 
@@ -320,7 +534,7 @@ func main() {
 	// Handle shutdown in background
 	go func() {
 		<-sigChan
-		log.Println("\n\nShutting down master node...")
+		logging.Info("\n\nShutting down master node...")
 
 		// Stop queue processor
 		masterServer.StopQueueProcessor()
@@ -328,6 +542,15 @@ func main() {
 		// Stop worker reconnection monitor
 		masterServer.StopWorkerReconnectionMonitor()
 
+		// Stop stuck task collector
+		masterServer.StopTaskGC()
+
+		// Stop retention sweeper
+		masterServer.StopRetentionSweeper()
+
+		// Stop maintenance window checker
+		masterServer.StopMaintenanceChecker()
+
 		// Shutdown HTTP server
 		if httpTelemetryServer != nil {
 			httpTelemetryServer.Shutdown()
@@ -338,7 +561,7 @@ func main() {
 
 		// Shutdown RTS scheduler
 		if rtsScheduler != nil {
-			log.Println("⏹️  Shutting down RTS scheduler...")
+			logging.Info("⏹️  Shutting down RTS scheduler...")
 			rtsScheduler.Shutdown()
 		}
 
@@ -362,7 +585,7 @@ func main() {
 			historyDB.Close(context.Background())
 		}
 
-		log.Println("✓ Master node shutdown complete")
+		logging.Info("✓ Master node shutdown complete")
 		os.Exit(0)
 	}()
 
@@ -373,10 +596,32 @@ func main() {
 	masterServer.BroadcastMasterRegistration(masterID, masterAddress)
 
 	// Start CLI interface
-	log.Println("\n✓ Master node started successfully")
-	log.Printf("✓ Starting gRPC server on %s\n", masterAddress)
+	logging.Info("\n✓ Master node started successfully")
+	logging.Infof("✓ Starting gRPC server on %s\n", masterAddress)
+
+	// Resolve the CLI user, rejecting users unknown to the user database
+	var users cli.UserLookup
+	if userDB != nil {
+		users = userDB
+	}
+	cliUser, err := cli.ResolveUser(*userFlag, cfg.CLIUser, users)
+	if err != nil {
+		log.Fatalf("Invalid CLI user: %v", err)
+	}
+	logging.Infof("✓ CLI tasks will be submitted as user: %s", cliUser)
 
 	cliInterface := cli.NewCLI(masterServer, fileStorage)
+	cliInterface.SetUser(cliUser)
+	cliInterface.SetPrompt(cfg.CLIPrompt)
+	historyFile := cfg.CLIHistoryFile
+	if historyFile == "" {
+		historyFile = cli.DefaultHistoryPath()
+	}
+	cliInterface.SetHistoryFile(historyFile)
+	if historyDB != nil {
+		cliInterface.SetTraining(historyDB, paramsPath, cfg.AffinityHalfLife)
+		cliInterface.SetSimulation(tauStore, slaMultiplier)
+	}
 	cliInterface.Run()
 }
 
@@ -62,6 +62,9 @@ if [ ! -f "masterNode" ]; then
     exit 1
 fi
 
+# Development script: run without TLS unless a certificate is configured (see TLS_CERT_FILE)
+export ALLOW_INSECURE="${ALLOW_INSECURE:-true}"
+
 # Start the master node
 echo "Launching master node..."
 ./masterNode
@@ -34,6 +34,9 @@ if [ ! -f "workerNode" ]; then
     exit 1
 fi
 
+# Development script: run without TLS unless a certificate is configured (see TLS_CERT_FILE)
+export ALLOW_INSECURE="${ALLOW_INSECURE:-true}"
+
 # Start the worker node (no arguments needed - auto-detects everything)
 echo "Launching worker node..."
 echo ""
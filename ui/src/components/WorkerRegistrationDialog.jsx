@@ -43,7 +43,7 @@ const WorkerRegistrationDialog = ({ open, onClose, onSuccess }) => {
       onSuccess();
       handleClose();
     } catch (err) {
-      setError(err.response?.data?.error || err.message || 'Failed to register worker');
+      setError(err.response?.data?.error?.message || err.message || 'Failed to register worker');
     } finally {
       setLoading(false);
     }
@@ -76,6 +76,7 @@ const TaskLogsDialog = ({ open, onClose, taskId }) => {
               {
                 type: 'log',
                 text: data.line,
+                stream: data.stream,
                 timestamp: new Date().toISOString(),
               },
             ]);
@@ -228,7 +229,7 @@ const TaskLogsDialog = ({ open, onClose, taskId }) => {
               sx={{
                 mb: 0.5,
                 color:
-                  log.type === 'error'
+                  log.type === 'error' || log.stream === 'stderr'
                     ? '#f48771'
                     : log.type === 'system'
                     ? '#4ec9b0'
@@ -48,7 +48,7 @@ export const AuthProvider = ({ children }) => {
         throw new Error(response.message || 'Login failed');
       }
     } catch (err) {
-      const errorMessage = err.response?.data?.message || err.message || 'Login failed';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Login failed';
       setError(errorMessage);
       return { success: false, error: errorMessage };
     }
@@ -65,7 +65,7 @@ export const AuthProvider = ({ children }) => {
         throw new Error(response.message || 'Registration failed');
       }
     } catch (err) {
-      const errorMessage = err.response?.data?.message || err.message || 'Registration failed';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Registration failed';
       setError(errorMessage);
       return { success: false, error: errorMessage };
     }
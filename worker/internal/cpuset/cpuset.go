@@ -0,0 +1,122 @@
+package cpuset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Allocator tracks which host CPU cores are pinned to which task
+type Allocator struct {
+	totalCores int
+	owners     []string         // core index -> task_id ("" when free)
+	assigned   map[string][]int // task_id -> pinned core indices
+	mu         sync.Mutex
+}
+
+// NewAllocator creates a new allocator for a host with the given number of cores
+func NewAllocator(totalCores int) *Allocator {
+	if totalCores < 0 {
+		totalCores = 0
+	}
+	return &Allocator{
+		totalCores: totalCores,
+		owners:     make([]string, totalCores),
+		assigned:   make(map[string][]int),
+	}
+}
+
+// CoresFor returns the number of whole cores needed to pin a task requesting reqCPU cores
+func CoresFor(reqCPU float64) int {
+	if reqCPU <= 0 {
+		return 1
+	}
+	return int(math.Ceil(reqCPU))
+}
+
+// Allocate pins count free cores to a task and returns them in Docker cpuset format (e.g. "0,1")
+// Calling Allocate again for a task that already holds cores returns its existing set
+func (a *Allocator) Allocate(taskID string, count int) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if cores, exists := a.assigned[taskID]; exists {
+		return formatCores(cores), nil
+	}
+
+	if count <= 0 {
+		return "", fmt.Errorf("invalid core count: %d", count)
+	}
+
+	free := make([]int, 0, count)
+	for core, owner := range a.owners {
+		if owner == "" {
+			free = append(free, core)
+			if len(free) == count {
+				break
+			}
+		}
+	}
+
+	if len(free) < count {
+		return "", fmt.Errorf("not enough free cores: requested %d, available %d", count, len(free))
+	}
+
+	for _, core := range free {
+		a.owners[core] = taskID
+	}
+	a.assigned[taskID] = free
+
+	return formatCores(free), nil
+}
+
+// Release frees all cores pinned to a task (no-op if the task holds none)
+func (a *Allocator) Release(taskID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, core := range a.assigned[taskID] {
+		a.owners[core] = ""
+	}
+	delete(a.assigned, taskID)
+}
+
+// Assigned returns the cores pinned to a task
+func (a *Allocator) Assigned(taskID string) []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cores := make([]int, len(a.assigned[taskID]))
+	copy(cores, a.assigned[taskID])
+	return cores
+}
+
+// FreeCores returns the number of cores not pinned to any task
+func (a *Allocator) FreeCores() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	free := 0
+	for _, owner := range a.owners {
+		if owner == "" {
+			free++
+		}
+	}
+	return free
+}
+
+// formatCores renders core indices as a Docker cpuset string
+func formatCores(cores []int) string {
+	sorted := make([]int, len(cores))
+	copy(sorted, cores)
+	sort.Ints(sorted)
+
+	parts := make([]string, len(sorted))
+	for i, core := range sorted {
+		parts[i] = strconv.Itoa(core)
+	}
+	return strings.Join(parts, ",")
+}
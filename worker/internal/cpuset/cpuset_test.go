@@ -0,0 +1,71 @@
+package cpuset
+
+import "testing"
+
+// TestPinnedTasksGetDisjointCores tests that two pinned tasks never share a core
+func TestPinnedTasksGetDisjointCores(t *testing.T) {
+	alloc := NewAllocator(4)
+
+	first, err := alloc.Allocate("task-1", 2)
+	if err != nil {
+		t.Fatalf("Expected no error for task-1, got %v", err)
+	}
+	second, err := alloc.Allocate("task-2", 2)
+	if err != nil {
+		t.Fatalf("Expected no error for task-2, got %v", err)
+	}
+
+	if first != "0,1" || second != "2,3" {
+		t.Errorf("Expected cpusets 0,1 and 2,3, got %s and %s", first, second)
+	}
+
+	seen := make(map[int]string)
+	for _, taskID := range []string{"task-1", "task-2"} {
+		for _, core := range alloc.Assigned(taskID) {
+			if owner, taken := seen[core]; taken {
+				t.Errorf("Core %d assigned to both %s and %s", core, owner, taskID)
+			}
+			seen[core] = taskID
+		}
+	}
+
+	if _, err := alloc.Allocate("task-3", 1); err == nil {
+		t.Error("Expected error when no cores are free")
+	}
+}
+
+// TestReleaseFreesCores tests that cores are returned to the pool after completion
+func TestReleaseFreesCores(t *testing.T) {
+	alloc := NewAllocator(2)
+
+	if _, err := alloc.Allocate("task-1", 2); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if alloc.FreeCores() != 0 {
+		t.Errorf("Expected 0 free cores, got %d", alloc.FreeCores())
+	}
+
+	alloc.Release("task-1")
+
+	if alloc.FreeCores() != 2 {
+		t.Errorf("Expected 2 free cores after release, got %d", alloc.FreeCores())
+	}
+	if len(alloc.Assigned("task-1")) != 0 {
+		t.Error("Expected released task to hold no cores")
+	}
+
+	cpus, err := alloc.Allocate("task-2", 2)
+	if err != nil || cpus != "0,1" {
+		t.Errorf("Expected task-2 to reuse cores 0,1, got %q (err: %v)", cpus, err)
+	}
+}
+
+// TestCoresFor tests rounding of fractional CPU requests to whole cores
+func TestCoresFor(t *testing.T) {
+	cases := map[float64]int{0: 1, 0.5: 1, 1.0: 1, 1.5: 2, 4.0: 4}
+	for reqCPU, expected := range cases {
+		if got := CoresFor(reqCPU); got != expected {
+			t.Errorf("CoresFor(%.1f) = %d, expected %d", reqCPU, got, expected)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// SetPinDigests makes the executor refuse images that resolve to the mutable "latest" tag
+// Digests are recorded in task results either way; this only turns on rejection
+func (e *TaskExecutor) SetPinDigests(pin bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pinDigests = pin
+}
+
+// checkDigestPolicy returns an error when digest pinning is enforced and the image uses "latest"
+func (e *TaskExecutor) checkDigestPolicy(imageName string) error {
+	e.mu.RLock()
+	pin := e.pinDigests
+	e.mu.RUnlock()
+
+	if pin && isLatestRef(imageName) {
+		return fmt.Errorf("image %s uses the mutable :latest tag; PIN_DIGESTS requires a version tag or @sha256 digest", imageName)
+	}
+	return nil
+}
+
+// isLatestRef reports whether an image reference, explicitly or implicitly, names the "latest" tag
+func isLatestRef(imageName string) bool {
+	ref := normalizeImageRef(imageName)
+	return !strings.Contains(ref, "@") && strings.HasSuffix(ref, ":latest")
+}
+
+// pinImageDigest resolves a local image to its repo digest and records it in the result
+// Returns the digest reference to create the container from, so the run uses exactly the recorded image;
+// images without a repo digest (built locally, or when inspection fails) run under their original name
+func pinImageDigest(ctx context.Context, api imageInspectAPI, taskID, imageName string, result *TaskResult) string {
+	if strings.Contains(imageName, "@") {
+		result.ImageDigest = imageName
+		return imageName
+	}
+
+	info, err := api.ImageInspect(ctx, normalizeImageRef(imageName))
+	if err != nil {
+		log.Printf("[Task %s] Warning: failed to resolve digest of %s: %v", taskID, imageName, err)
+		return imageName
+	}
+	digest := repoDigestFor(imageName, info.RepoDigests)
+	if digest == "" {
+		return imageName
+	}
+
+	result.ImageDigest = digest
+	log.Printf("[Task %s] 📌 Image %s pinned to %s", taskID, imageName, digest)
+	return digest
+}
+
+// repoDigestFor picks the repo digest belonging to the image's repository, falling back to the first one
+func repoDigestFor(imageName string, repoDigests []string) string {
+	repo := normalizeImageRef(imageName)
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	for _, digest := range repoDigests {
+		if strings.HasPrefix(digest, repo+"@") {
+			return digest
+		}
+	}
+	if len(repoDigests) > 0 {
+		return repoDigests[0]
+	}
+	return ""
+}
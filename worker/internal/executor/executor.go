@@ -1,19 +1,26 @@
 package executor
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"worker/internal/cpuset"
 	"worker/internal/logstream"
+	"worker/internal/resultcache"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
@@ -24,19 +31,101 @@ import (
 type TaskExecutor struct {
 	dockerClient *client.Client
 	logStreamMgr *logstream.LogStreamManager
+	cpuAllocator *cpuset.Allocator  // Tracks cores pinned to latency-sensitive tasks
+	resultCache  *resultcache.Cache // Results of cacheable tasks, keyed by image digest+command
+	runFn        containerRunFunc   // Runs a task in a container (replaceable in tests)
+	resolveFn    imageResolveFunc   // Resolves a task's image to an immutable reference (replaceable in tests)
+	crashLoop    CrashLoopPolicy    // When repeated fast failures stop local restarts
+	noTTY        bool               // Run containers without a TTY so stdout and stderr stay separate
+	pinDigests   bool               // Refuse images that resolve to the mutable "latest" tag
+	pullTimeout  time.Duration      // How long an image pull may take (0 waits indefinitely)
+	sandbox      SandboxPolicy      // Capabilities and security options of every task container
 	mu           sync.RWMutex
-	containers   map[string]string // task_id -> container_id
+	containers   map[string]string     // task_id -> container_id
+	restartable  map[string]bool       // task_id -> cancel requested, for tasks that may be restarted
+	tasks        map[string]*taskState // task_id -> spec and pull progress, while ExecuteTask runs the task
+
+	// Mount every task container's root filesystem read-only
+	readOnlyRootFS bool
+
+	// Told when a service task's health check state changes
+	onReadiness func(taskID, state string)
+
+	// Told where a task's ports were published once its container starts
+	onPortsPublished func(taskID string, ports []PublishedPort)
+}
+
+// DefaultStopGracePeriod is how many seconds a cancelled container gets between SIGTERM and SIGKILL
+const DefaultStopGracePeriod = 10
+
+// stopMargin is the time allowed beyond a task's stop grace to kill and remove its container
+const stopMargin = 10 * time.Second
+
+// containerRunFunc runs a task to completion inside a container
+type containerRunFunc func(ctx context.Context, spec TaskSpec) *TaskResult
+
+// imageResolveFunc pulls a task's image if needed and returns an immutable reference to it
+type imageResolveFunc func(ctx context.Context, spec TaskSpec) (string, error)
+
+// TaskSpec describes a task to run: its image and command, the resources it gets, and how its container is set up
+type TaskSpec struct {
+	TaskID      string
+	DockerImage string
+	Command     string
+
+	ReqCPU    float64
+	ReqMemory float64 // GB; a soft reservation when MemLimit is above it
+	ReqGPU    float64
+	MemLimit  float64 // Hard memory cap in GB (0 = ReqMemory)
+	PinCPUs   bool    // Restrict the container to dedicated cores for its lifetime
+
+	Cacheable    bool // An identical earlier run may be returned from the result cache instead
+	MaxRestarts  int  // Restarts on non-zero exit, unless the task is crash-looping
+	StopGraceSec int  // SIGTERM-to-SIGKILL grace if the task is cancelled (0 = DefaultStopGracePeriod)
+
+	Network TaskNetwork     // Network mode and ports published on the worker host
+	Process TaskProcess     // Working directory and user overrides
+	Health  TaskHealthCheck // When it has a command, must pass after the container starts for the task to be ready
+}
+
+// taskState is what the executor keeps about a task while ExecuteTask runs it
+type taskState struct {
+	spec     TaskSpec
+	pulling  bool     // The task's image is being pulled and it has no container yet
+	progress []string // Image pull progress lines, kept for log streams until the container starts
 }
 
 // TaskResult contains the execution result
 type TaskResult struct {
 	TaskID         string
-	Status         string // success, failed
+	Status         string // success, failed, crashloop
 	Logs           string
 	ExitCode       int64
 	Error          error
-	ResultLocation string   // Path to output directory on worker
-	OutputFiles    []string // List of output files relative to ResultLocation
+	ResultLocation string          // Path to output directory on worker
+	OutputFiles    []string        // List of output files relative to ResultLocation
+	CacheHit       bool            // Result was served from the local result cache
+	Attempts       int             // Number of container runs (1 + restarts)
+	FailureReason  string          // Why a failed task ended (FailureReasonOOM, FailureReasonSignal, FailureReasonExitCode, FailureReasonImagePullTimeout)
+	Usage          *UsageSummary   // Sampled CPU/memory usage of the container (nil if no samples were taken)
+	PublishedPorts []PublishedPort // Host ports the container's ports were published on
+	ImageDigest    string          // Repo digest the image resolved to (empty when the image has none)
+}
+
+// CrashLoopPolicy decides when a restarting task is flapping rather than failing transiently
+type CrashLoopPolicy struct {
+	Window       time.Duration // A non-zero exit sooner than this after start counts as a fast failure
+	MaxFastFails int           // Consecutive fast failures that mark the task as crash-looping
+	Backoff      time.Duration // Delay before the first restart, doubled on each further restart
+}
+
+// DefaultCrashLoopPolicy returns the worker's default crash-loop detection settings
+func DefaultCrashLoopPolicy() CrashLoopPolicy {
+	return CrashLoopPolicy{
+		Window:       5 * time.Second,
+		MaxFastFails: 3,
+		Backoff:      time.Second,
+	}
 }
 
 // getBaseOutputDir returns the base output directory, using CLOUDAI_OUTPUT_DIR env var if set
@@ -47,6 +136,38 @@ func getBaseOutputDir() string {
 	return "/var/cloudai/outputs"
 }
 
+// OutputBaseDir returns the directory under which task output directories are created
+func OutputBaseDir() string {
+	return getBaseOutputDir()
+}
+
+// getCacheDir returns the result cache directory, using CLOUDAI_CACHE_DIR env var if set
+func getCacheDir() string {
+	if dir := os.Getenv("CLOUDAI_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(getBaseOutputDir(), ".cache")
+}
+
+// getCacheMaxEntries returns how many results the result cache keeps, using CLOUDAI_CACHE_MAX_ENTRIES env var if set
+func getCacheMaxEntries() int {
+	if value := os.Getenv("CLOUDAI_CACHE_MAX_ENTRIES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("Warning: invalid CLOUDAI_CACHE_MAX_ENTRIES %q, keeping %d results", value, resultcache.DefaultMaxEntries)
+	}
+	return resultcache.DefaultMaxEntries
+}
+
+// getLogDir returns the directory task logs are teed to, using CLOUDAI_LOG_DIR env var if set
+func getLogDir() string {
+	if dir := os.Getenv("CLOUDAI_LOG_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(getBaseOutputDir(), ".logs")
+}
+
 // NewTaskExecutor creates a new task executor
 func NewTaskExecutor() (*TaskExecutor, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
@@ -54,15 +175,182 @@ func NewTaskExecutor() (*TaskExecutor, error) {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
 
-	return &TaskExecutor{
+	e := &TaskExecutor{
 		dockerClient: cli,
 		logStreamMgr: logstream.NewLogStreamManager(cli),
+		cpuAllocator: cpuset.NewAllocator(runtime.NumCPU()),
+		resultCache:  resultcache.New(getCacheDir(), getCacheMaxEntries()),
+		crashLoop:    DefaultCrashLoopPolicy(),
+		pullTimeout:  DefaultImagePullTimeout,
+		sandbox:      DefaultSandboxPolicy(),
 		containers:   make(map[string]string),
-	}, nil
+	}
+	e.logStreamMgr.SetLogDir(getLogDir())
+	e.runFn = e.runContainer
+	e.resolveFn = e.resolveImage
+	return e, nil
+}
+
+// SetSeparateLogStreams runs task containers without a TTY so their log lines are labelled stdout or stderr
+// With a TTY (the default) Docker merges both streams, but programs flush output line by line
+func (e *TaskExecutor) SetSeparateLogStreams(separate bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.noTTY = separate
 }
 
 // ExecuteTask pulls and runs a Docker container for the task with resource constraints
-func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID, dockerImage, command string, reqCPU, reqMemory, reqGPU float64) *TaskResult {
+// See TaskSpec for how pinning, caching, restarts, the stop grace, networking and health checks apply
+func (e *TaskExecutor) ExecuteTask(ctx context.Context, spec TaskSpec) *TaskResult {
+	e.mu.Lock()
+	if e.tasks == nil {
+		e.tasks = make(map[string]*taskState)
+	}
+	e.tasks[spec.TaskID] = &taskState{spec: spec}
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.tasks, spec.TaskID)
+		e.mu.Unlock()
+	}()
+
+	if !spec.Cacheable {
+		return e.runWithRestarts(ctx, spec)
+	}
+
+	// Key on the image's digest, so a re-pushed tag does not serve results computed with the old image
+	imageRef, err := e.resolveFn(ctx, spec)
+	if err != nil {
+		log.Printf("[Task %s] Warning: failed to resolve image %s, skipping the result cache: %v", spec.TaskID, spec.DockerImage, err)
+		return e.runWithRestarts(ctx, spec)
+	}
+
+	// The working directory and user change what a command reads, writes and may access, so they are part of the key
+	key := resultcache.Key(imageRef, spec.Command, spec.Process.WorkingDir, spec.Process.User)
+	if entry, ok := e.resultCache.Lookup(key); ok {
+		log.Printf("[Task %s] ✓ Result cache hit (key: %s), skipping container execution", spec.TaskID, key[:12])
+		return &TaskResult{
+			TaskID:         spec.TaskID,
+			Status:         "success",
+			Logs:           entry.Logs,
+			ResultLocation: entry.OutputDir,
+			OutputFiles:    entry.OutputFiles,
+			CacheHit:       true,
+		}
+	}
+
+	result := e.runWithRestarts(ctx, spec)
+
+	// Only successful runs are cached; failures may be transient
+	if result.Status == "success" {
+		if err := e.resultCache.Store(key, result.Logs, result.ResultLocation, result.OutputFiles); err != nil {
+			log.Printf("[Task %s] Warning: failed to cache result: %v", spec.TaskID, err)
+		} else {
+			log.Printf("[Task %s] ✓ Result cached (key: %s)", spec.TaskID, key[:12])
+		}
+	}
+
+	return result
+}
+
+// resolveImage pulls the task's image if it is missing and returns its repo digest, or its image ID when it has none
+func (e *TaskExecutor) resolveImage(ctx context.Context, spec TaskSpec) (string, error) {
+	if strings.Contains(spec.DockerImage, "@") {
+		return spec.DockerImage, nil
+	}
+	if err := e.checkDigestPolicy(spec.DockerImage); err != nil {
+		return "", err
+	}
+
+	defer e.clearPullProgress(spec.TaskID)
+	if _, err := ensureImage(ctx, e.dockerClient, spec.DockerImage, e.taskPullOptions(spec.TaskID)); err != nil {
+		return "", fmt.Errorf("failed to pull image: %w", err)
+	}
+	info, err := e.dockerClient.ImageInspect(ctx, normalizeImageRef(spec.DockerImage))
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image: %w", err)
+	}
+	if digest := repoDigestFor(spec.DockerImage, info.RepoDigests); digest != "" {
+		return digest, nil
+	}
+	return info.ID, nil
+}
+
+// runWithRestarts runs the task container, restarting it on non-zero exit up to spec.MaxRestarts times
+// Consecutive exits within the crash-loop window stop restarts early with status "crashloop"
+func (e *TaskExecutor) runWithRestarts(ctx context.Context, spec TaskSpec) *TaskResult {
+	taskID, maxRestarts := spec.TaskID, spec.MaxRestarts
+	policy := e.crashLoop
+	backoff := policy.Backoff
+	fastFails := 0
+
+	if maxRestarts > 0 {
+		e.mu.Lock()
+		if e.restartable == nil {
+			e.restartable = make(map[string]bool)
+		}
+		e.restartable[taskID] = false
+		e.mu.Unlock()
+		defer func() {
+			e.mu.Lock()
+			delete(e.restartable, taskID)
+			e.mu.Unlock()
+		}()
+	}
+
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+		result := e.runFn(ctx, spec)
+		result.Attempts = attempt
+
+		// Only a container that ran and exited non-zero is worth restarting
+		if result.Status != "failed" || result.ExitCode == 0 {
+			return result
+		}
+
+		if time.Since(started) < policy.Window {
+			fastFails++
+		} else {
+			fastFails = 0
+		}
+
+		if policy.MaxFastFails > 0 && fastFails >= policy.MaxFastFails {
+			log.Printf("[Task %s] 🔁 Crash loop detected: %d consecutive exits within %s, giving up",
+				taskID, fastFails, policy.Window)
+			result.Status = "crashloop"
+			result.Error = fmt.Errorf("crash loop: container exited with code %d %d times within %s of starting",
+				result.ExitCode, fastFails, policy.Window)
+			return result
+		}
+
+		if attempt > maxRestarts || ctx.Err() != nil || e.restartCancelled(taskID) {
+			return result
+		}
+
+		log.Printf("[Task %s] 🔄 Exit code %d, restarting in %s (restart %d/%d)",
+			taskID, result.ExitCode, backoff, attempt, maxRestarts)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return result
+		}
+		if e.restartCancelled(taskID) {
+			return result
+		}
+		backoff *= 2
+	}
+}
+
+// restartCancelled reports whether the task was cancelled while it was allowed to restart
+func (e *TaskExecutor) restartCancelled(taskID string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.restartable[taskID]
+}
+
+// runContainer pulls the image and runs the task container to completion
+func (e *TaskExecutor) runContainer(ctx context.Context, spec TaskSpec) *TaskResult {
+	taskID, dockerImage := spec.TaskID, spec.DockerImage
 	result := &TaskResult{
 		TaskID: taskID,
 		Status: "failed",
@@ -70,19 +358,41 @@ func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID, dockerImage, com
 
 	log.Printf("[Task %s] Starting execution...", taskID)
 
-	// Pull the image
-	log.Printf("[Task %s] Pulling image: %s", taskID, dockerImage)
-	if err := e.pullImage(ctx, dockerImage); err != nil {
+	if err := e.checkDigestPolicy(dockerImage); err != nil {
+		result.Error = err
+		result.Logs = fmt.Sprintf("Error: %v", err)
+		return result
+	}
+
+	// Pull the image (skipped when it is already present locally); log streams show the pull's progress
+	defer e.clearPullProgress(taskID)
+	pulled, err := ensureImage(ctx, e.dockerClient, dockerImage, e.taskPullOptions(taskID))
+	if err != nil {
 		result.Error = fmt.Errorf("failed to pull image: %w", err)
 		result.Logs = fmt.Sprintf("Error pulling image: %v", err)
+		if progress, _ := e.PullProgress(taskID); len(progress) > 0 {
+			result.Logs = strings.Join(progress, "\n") + "\n" + result.Logs
+		}
+		if errors.Is(err, ErrImagePullTimeout) {
+			result.FailureReason = FailureReasonImagePullTimeout
+		}
 		return result
 	}
+	if pulled {
+		log.Printf("[Task %s] Pulled image: %s", taskID, dockerImage)
+	} else {
+		log.Printf("[Task %s] ✓ Image already present, skipping pull: %s", taskID, dockerImage)
+	}
+
+	// Run from the digest the tag resolves to now, so the result records exactly which image ran
+	runImage := pinImageDigest(ctx, e.dockerClient, taskID, dockerImage, result)
 
 	// Create container with resource limits
 	log.Printf("[Task %s] Creating container with resource limits (CPU: %.2f, Memory: %.2fGB, GPU: %.2f)...",
-		taskID, reqCPU, reqMemory, reqGPU)
-	containerID, err := e.createContainer(ctx, dockerImage, command, taskID, reqCPU, reqMemory, reqGPU)
+		taskID, spec.ReqCPU, spec.ReqMemory, spec.ReqGPU)
+	containerID, err := e.createContainer(ctx, runImage, spec)
 	if err != nil {
+		e.cpuAllocator.Release(taskID)
 		result.Error = fmt.Errorf("failed to create container: %w", err)
 		result.Logs = fmt.Sprintf("Error creating container: %v", err)
 		return result
@@ -98,6 +408,7 @@ func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID, dockerImage, com
 		e.logStreamMgr.StopTask(taskID)
 
 		e.cleanup(ctx, containerID)
+		e.cpuAllocator.Release(taskID)
 		e.mu.Lock()
 		delete(e.containers, taskID)
 		e.mu.Unlock()
@@ -111,11 +422,49 @@ func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID, dockerImage, com
 		return result
 	}
 
+	// Sample CPU and memory usage until the container exits, for right-sizing recommendations
+	usageCtx, stopUsage := context.WithCancel(ctx)
+	defer stopUsage()
+	usageCh := sampleUsage(usageCtx, e.dockerClient, containerID, DefaultUsageSampleInterval)
+
+	// Report where published ports ended up (host ports may have been picked by Docker)
+	if len(spec.Network.Ports) > 0 {
+		ports, err := publishedPorts(ctx, e.dockerClient, containerID)
+		if err != nil {
+			log.Printf("[Task %s] Warning: failed to read published ports: %v", taskID, err)
+		}
+		for _, p := range ports {
+			log.Printf("[Task %s] 🔌 Port %s published on %s:%d", taskID, p.ContainerPort, p.HostIP, p.HostPort)
+		}
+		result.PublishedPorts = ports
+		e.reportPublishedPorts(taskID, ports)
+	}
+
 	// Start log streaming for this task
 	if err := e.logStreamMgr.StartTask(taskID, containerID); err != nil {
 		log.Printf("[Task %s] Warning: failed to start log streaming: %v", taskID, err)
 	}
 
+	// A service task is only ready once its health check passes; one that never passes is stopped and fails
+	unhealthy := make(chan error, 1)
+	if check := spec.Health; check.Command != "" {
+		healthCtx, stopHealth := context.WithCancel(ctx)
+		defer stopHealth()
+		go func() {
+			err := watchHealth(healthCtx, e.dockerClient, taskID, containerID, check, func(state string) {
+				e.reportReadiness(taskID, state)
+			})
+			if errors.Is(err, ErrUnhealthy) {
+				log.Printf("[Task %s] ✗ %v - stopping container", taskID, err)
+				unhealthy <- err
+				grace := e.stopGracePeriod(taskID)
+				if stopErr := e.dockerClient.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &grace}); stopErr != nil {
+					log.Printf("[Task %s] Warning: failed to stop unhealthy container: %v", taskID, stopErr)
+				}
+			}
+		}()
+	}
+
 	// Collect logs for final result
 	logs, err := e.collectLogs(ctx, containerID)
 	if err != nil {
@@ -141,10 +490,14 @@ func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID, dockerImage, com
 			log.Printf("[Task %s] ✓ Completed successfully", taskID)
 		} else {
 			result.Status = "failed"
-			result.Error = fmt.Errorf("container exited with code %d", status.StatusCode)
-			log.Printf("[Task %s] ✗ Failed with exit code %d", taskID, status.StatusCode)
+			result.FailureReason = exitReason(ctx, e.dockerClient, taskID, containerID, status.StatusCode)
+			result.Error = fmt.Errorf("container exited with code %d (%s)", status.StatusCode, result.FailureReason)
+			log.Printf("[Task %s] ✗ Failed with exit code %d (reason: %s)", taskID, status.StatusCode, result.FailureReason)
 		}
 
+		stopUsage()
+		result.Usage = <-usageCh
+
 		// Print task completion banner
 		log.Println(" ")
 		log.Println("═══════════════════════════════════════════════════════")
@@ -156,17 +509,29 @@ func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID, dockerImage, com
 		log.Println("═══════════════════════════════════════════════════════")
 		log.Printf("  Task ID:           %s", taskID)
 		log.Printf("  Docker Image:      %s", dockerImage)
-		log.Printf("  Command:           %s", command)
+		log.Printf("  Command:           %s", spec.Command)
 		log.Printf("  Exit Code:         %d", status.StatusCode)
+		if result.FailureReason != "" {
+			log.Printf("  Failure Reason:    %s", result.FailureReason)
+		}
 		log.Println("───────────────────────────────────────────────────────")
 		log.Println("  Resources Released:")
-		log.Printf("    • CPU Cores:     %.2f cores", reqCPU)
-		log.Printf("    • Memory:        %.2f GB", reqMemory)
-		log.Printf("    • GPU Cores:     %.2f cores", reqGPU)
+		log.Printf("    • CPU Cores:     %.2f cores", spec.ReqCPU)
+		log.Printf("    • Memory:        %.2f GB", spec.ReqMemory)
+		log.Printf("    • GPU Cores:     %.2f cores", spec.ReqGPU)
 		log.Println("═══════════════════════════════════════════════════════")
 		log.Println("")
 	}
 
+	// The container stopped because it never became healthy, whatever its exit code
+	select {
+	case err := <-unhealthy:
+		result.Status = "failed"
+		result.FailureReason = FailureReasonUnhealthy
+		result.Error = err
+	default:
+	}
+
 	// Collect output files
 	outputDir := filepath.Join(getBaseOutputDir(), taskID)
 	outputFiles, err := e.collectOutputFiles(outputDir)
@@ -183,37 +548,154 @@ func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID, dockerImage, com
 	return result
 }
 
-// pullImage pulls a Docker image from registry
-func (e *TaskExecutor) pullImage(ctx context.Context, imageName string) error {
-	out, err := e.dockerClient.ImagePull(ctx, imageName, image.PullOptions{})
+// imageAPI is the subset of the Docker client used to manage local images
+type imageAPI interface {
+	ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error)
+	ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error)
+}
+
+// EnsureImage pulls an image only if it is not already present locally
+// Returns true if the image had to be pulled from the registry
+func (e *TaskExecutor) EnsureImage(ctx context.Context, imageName string) (bool, error) {
+	return ensureImage(ctx, e.dockerClient, imageName, pullOptions{timeout: e.imagePullTimeout()})
+}
+
+// imagePullTimeout returns how long an image pull may take
+func (e *TaskExecutor) imagePullTimeout() time.Duration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.pullTimeout
+}
+
+// PrewarmImages pulls a list of images ahead of time so later tasks start without a pull
+// Returns the images that were pulled, those already cached, and those that failed
+func (e *TaskExecutor) PrewarmImages(ctx context.Context, images []string) (pulled, cached, failed []string) {
+	for _, img := range images {
+		wasPulled, err := e.EnsureImage(ctx, img)
+		switch {
+		case err != nil:
+			log.Printf("⚠ Failed to prewarm image %s: %v", img, err)
+			failed = append(failed, img)
+		case wasPulled:
+			log.Printf("✓ Prewarmed image: %s", img)
+			pulled = append(pulled, img)
+		default:
+			cached = append(cached, img)
+		}
+	}
+	return pulled, cached, failed
+}
+
+// imageInspectAPI extends imageAPI with image inspection, used to validate images
+type imageInspectAPI interface {
+	imageAPI
+	ImageInspect(ctx context.Context, imageID string, inspectOpts ...client.ImageInspectOption) (image.InspectResponse, error)
+}
+
+// ImageValidation describes an image that was found usable by ValidateImage
+type ImageValidation struct {
+	Digest    string // Repo digest, or the image ID when the image has no digest
+	SizeBytes int64
+	Pulled    bool // True when the image had to be pulled to validate it
+}
+
+// ValidateImage checks that an image exists locally or can be pulled, without running it
+func (e *TaskExecutor) ValidateImage(ctx context.Context, imageName string) (*ImageValidation, error) {
+	return validateImage(ctx, e.dockerClient, imageName, pullOptions{timeout: e.imagePullTimeout()})
+}
+
+// validateImage inspects the image, pulling it first when it is not present locally
+func validateImage(ctx context.Context, api imageInspectAPI, imageName string, opts pullOptions) (*ImageValidation, error) {
+	if strings.TrimSpace(imageName) == "" {
+		return nil, fmt.Errorf("no image specified")
+	}
+	ref := normalizeImageRef(imageName)
+
+	pulled := false
+	info, err := api.ImageInspect(ctx, ref)
 	if err != nil {
-		return err
+		if err := pullImage(ctx, api, ref, opts); err != nil {
+			return nil, fmt.Errorf("image %s not found locally and could not be pulled: %w", imageName, err)
+		}
+		pulled = true
+
+		info, err = api.ImageInspect(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("image %s was pulled but could not be inspected: %w", imageName, err)
+		}
+	}
+
+	digest := info.ID
+	if len(info.RepoDigests) > 0 {
+		digest = info.RepoDigests[0]
 	}
-	defer out.Close()
 
-	// Read pull output (required to complete pull)
-	_, err = io.Copy(io.Discard, out)
-	return err
+	return &ImageValidation{
+		Digest:    digest,
+		SizeBytes: info.Size,
+		Pulled:    pulled,
+	}, nil
+}
+
+// ensureImage checks the local image cache and pulls the image only when missing
+func ensureImage(ctx context.Context, api imageAPI, imageName string, opts pullOptions) (bool, error) {
+	ref := normalizeImageRef(imageName)
+
+	images, err := api.ImageList(ctx, image.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("reference", ref)),
+	})
+	if err == nil && len(images) > 0 {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("Warning: failed to list local images, pulling %s: %v", imageName, err)
+	}
+
+	if err := pullImage(ctx, api, imageName, opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// normalizeImageRef adds the implicit ":latest" tag so local lookups match what a pull would fetch
+func normalizeImageRef(imageName string) string {
+	if strings.Contains(imageName, "@") {
+		return imageName
+	}
+	// A colon after the last slash is a tag; one before it belongs to a registry host:port
+	if strings.LastIndex(imageName, ":") > strings.LastIndex(imageName, "/") {
+		return imageName
+	}
+	return imageName + ":latest"
 }
 
 // createContainer creates a Docker container with resource limits
-func (e *TaskExecutor) createContainer(ctx context.Context, image, command, taskID string, reqCPU, reqMemory, reqGPU float64) (string, error) {
+// image is the reference to run, which may be pinned to a digest and differ from spec.DockerImage
+func (e *TaskExecutor) createContainer(ctx context.Context, image string, spec TaskSpec) (string, error) {
+	taskID := spec.TaskID
+
 	// Prepare container config
 	containerConfig := &container.Config{
 		Image: image,
 	}
 
 	// Use a TTY so many programs flush stdout line-by-line instead of block-buffering
-	// when their stdout is not a TTY. This improves live log streaming behavior.
-	containerConfig.Tty = true
+	// when their stdout is not a TTY. This improves live log streaming behavior,
+	// at the cost of merging stderr into stdout (see SetSeparateLogStreams).
+	e.mu.RLock()
+	containerConfig.Tty = !e.noTTY
+	e.mu.RUnlock()
 	containerConfig.AttachStdout = true
 	containerConfig.AttachStderr = true
 
 	// Add command if provided
-	if command != "" {
-		containerConfig.Cmd = []string{"/bin/sh", "-c", command}
+	if spec.Command != "" {
+		containerConfig.Cmd = []string{"/bin/sh", "-c", spec.Command}
 	}
 
+	// Run from the requested directory and as the requested user
+	applyProcessConfig(containerConfig, spec.Process)
+
 	// Create output directory on host with secure permissions
 	outputDir := filepath.Join(getBaseOutputDir(), taskID)
 	if err := os.MkdirAll(outputDir, 0700); err != nil { // drwx------ (owner only)
@@ -233,18 +715,43 @@ func (e *TaskExecutor) createContainer(ctx context.Context, image, command, task
 		},
 	}
 
+	// Lock the root filesystem if asked; the task can still write its results to /output
+	e.applyRootFSPolicy(hostConfig, spec.Process)
+
+	// Drop capabilities and privilege escalation so arbitrary images run confined
+	if err := e.applySandboxPolicy(hostConfig); err != nil {
+		return "", err
+	}
+
+	// Attach to the requested network and publish ports for service tasks
+	if err := applyNetworkConfig(containerConfig, hostConfig, spec.Network); err != nil {
+		return "", err
+	}
+
 	// Set CPU limit (in nano CPUs: 1 CPU = 1e9 nano CPUs)
-	if reqCPU > 0 {
-		hostConfig.Resources.NanoCPUs = int64(reqCPU * 1e9)
+	if spec.ReqCPU > 0 {
+		hostConfig.Resources.NanoCPUs = int64(spec.ReqCPU * 1e9)
 	}
 
-	// Set Memory limit (convert GB to bytes)
-	if reqMemory > 0 {
-		hostConfig.Resources.Memory = int64(reqMemory * units.GiB)
+	// Pin to dedicated cores if requested (falls back to the NanoCPUs quota when none are free)
+	if spec.PinCPUs {
+		cpus, err := e.cpuAllocator.Allocate(taskID, cpuset.CoresFor(spec.ReqCPU))
+		if err != nil {
+			log.Printf("[Task %s] ⚠ CPU pinning unavailable, running unpinned: %v", taskID, err)
+		} else {
+			hostConfig.Resources.CpusetCpus = cpus
+			log.Printf("[Task %s] ✓ Pinned to cores: %s", taskID, cpus)
+		}
+	}
+
+	// Set Memory limits (soft reservation plus hard cap)
+	applyMemoryLimits(&hostConfig.Resources, spec.ReqMemory, spec.MemLimit)
+	if hostConfig.Resources.MemoryReservation > 0 {
+		log.Printf("[Task %s] ✓ Memory: %.2fGB reserved, %.2fGB hard cap", taskID, spec.ReqMemory, spec.MemLimit)
 	}
 
 	// Set GPU devices (if requested)
-	if reqGPU > 0 {
+	if spec.ReqGPU > 0 {
 		// Note: This is a simplified GPU allocation
 		// In production, you'd use nvidia-docker runtime and proper device requests
 		hostConfig.Runtime = "nvidia"
@@ -273,8 +780,30 @@ func (e *TaskExecutor) createContainer(ctx context.Context, image, command, task
 	return resp.ID, nil
 }
 
+// applyMemoryLimits sets the container memory limits from the task spec (both in GB)
+// With a hard cap above the request, the request becomes a soft MemoryReservation the task
+// may burst past into idle memory up to the cap; otherwise the request is the hard limit
+func applyMemoryLimits(res *container.Resources, reqMemory, memLimit float64) {
+	if memLimit > reqMemory {
+		res.Memory = int64(memLimit * units.GiB)
+		if reqMemory > 0 {
+			res.MemoryReservation = int64(reqMemory * units.GiB)
+		}
+		return
+	}
+
+	if reqMemory > 0 {
+		res.Memory = int64(reqMemory * units.GiB)
+	}
+}
+
 // collectLogs streams container logs
 func (e *TaskExecutor) collectLogs(ctx context.Context, containerID string) (string, error) {
+	inspect, err := e.dockerClient.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", err
+	}
+
 	logReader, err := e.dockerClient.ContainerLogs(ctx, containerID, container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
@@ -286,19 +815,23 @@ func (e *TaskExecutor) collectLogs(ctx context.Context, containerID string) (str
 	}
 	defer logReader.Close()
 
-	var logBuffer bytes.Buffer
-	scanner := bufio.NewScanner(logReader)
+	lines, err := readContainerLogs(logReader, inspect.Config != nil && inspect.Config.Tty)
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Remove Docker log header (first 8 bytes)
-		if len(line) > 8 {
-			line = line[8:]
-		}
-		logBuffer.WriteString(line + "\n")
+	var logBuffer bytes.Buffer
+	for _, line := range lines {
+		logBuffer.WriteString(line.Content + "\n")
 	}
+	return logBuffer.String(), err
+}
 
-	return logBuffer.String(), scanner.Err()
+// readContainerLogs reads a container's log stream into lines labelled stdout or stderr
+// Non-TTY logs carry Docker's 8-byte frame headers and are demultiplexed; TTY logs are raw
+func readContainerLogs(r io.Reader, tty bool) ([]logstream.LogLine, error) {
+	var lines []logstream.LogLine
+	err := logstream.ReadLogLines(r, tty, func(line logstream.LogLine) {
+		lines = append(lines, line)
+	})
+	return lines, err
 }
 
 // collectOutputFiles collects all files from the output directory
@@ -361,10 +894,10 @@ func (e *TaskExecutor) GetContainerID(taskID string) (string, bool) {
 }
 
 // StreamLogs subscribes to live logs from a container via the log stream manager
-// Returns a channel that receives log lines and an error channel
+// Returns a channel that receives log lines (labelled stdout or stderr) and an error channel
 // This uses the broadcaster pattern to support multiple subscribers efficiently
-func (e *TaskExecutor) StreamLogs(ctx context.Context, taskID string) (<-chan string, <-chan error) {
-	logChan := make(chan string, 100)
+func (e *TaskExecutor) StreamLogs(ctx context.Context, taskID string) (<-chan logstream.LogLine, <-chan error) {
+	logChan := make(chan logstream.LogLine, 100)
 	errChan := make(chan error, 1)
 
 	go func() {
@@ -378,7 +911,7 @@ func (e *TaskExecutor) StreamLogs(ctx context.Context, taskID string) (<-chan st
 			return
 		}
 
-		// Forward log lines to the string channel
+		// Forward log lines to the caller's channel
 		for {
 			select {
 			case logLine, ok := <-logLineChan:
@@ -386,9 +919,9 @@ func (e *TaskExecutor) StreamLogs(ctx context.Context, taskID string) (<-chan st
 					// Log stream closed
 					return
 				}
-				// Send the log content
+				// Send the log line
 				select {
-				case logChan <- logLine.Content:
+				case logChan <- logLine:
 				case <-ctx.Done():
 					return
 				}
@@ -422,38 +955,109 @@ func (e *TaskExecutor) GetContainerStatus(ctx context.Context, containerID strin
 
 // CancelTask stops and removes a running task's container
 func (e *TaskExecutor) CancelTask(ctx context.Context, taskID string) error {
-	e.mu.RLock()
+	e.mu.Lock()
 	containerID, exists := e.containers[taskID]
-	e.mu.RUnlock()
+	_, restartable := e.restartable[taskID]
+	if restartable {
+		// Stop any further restarts, including one waiting out its backoff
+		e.restartable[taskID] = true
+	}
+	e.mu.Unlock()
 
 	if !exists {
+		if restartable {
+			log.Printf("[Task %s] ✓ Task cancelled between restarts", taskID)
+			return nil
+		}
 		return fmt.Errorf("task %s not found or not running", taskID)
 	}
 
-	log.Printf("[Task %s] Cancelling task (container: %s)...", taskID, containerID[:12])
+	graceSecs := e.stopGracePeriod(taskID)
+	log.Printf("[Task %s] Cancelling task (container: %s, grace: %ds)...", taskID, containerID[:12], graceSecs)
 
-	// Stop the container with a timeout
-	timeoutSecs := 10
-	if err := e.dockerClient.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeoutSecs}); err != nil {
+	if err := stopContainer(ctx, e.dockerClient, taskID, containerID, graceSecs); err != nil {
+		return err
+	}
+
+	// Remove from tracking and free any pinned cores
+	e.mu.Lock()
+	delete(e.containers, taskID)
+	e.mu.Unlock()
+	e.cpuAllocator.Release(taskID)
+
+	log.Printf("[Task %s] ✓ Task cancelled successfully", taskID)
+	return nil
+}
+
+// stopGracePeriod returns the task's configured SIGTERM-to-SIGKILL grace in seconds
+func (e *TaskExecutor) stopGracePeriod(taskID string) int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if state, ok := e.tasks[taskID]; ok && state.spec.StopGraceSec > 0 {
+		return state.spec.StopGraceSec
+	}
+	return DefaultStopGracePeriod
+}
+
+// StopTimeout is how long stopping the task's container may take: its stop grace plus time to kill and remove it
+func (e *TaskExecutor) StopTimeout(taskID string) time.Duration {
+	return time.Duration(e.stopGracePeriod(taskID))*time.Second + stopMargin
+}
+
+// containerStopAPI is the subset of the Docker client used to stop and remove containers
+type containerStopAPI interface {
+	ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error
+	ContainerKill(ctx context.Context, containerID, signal string) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+}
+
+// stopContainer sends SIGTERM, lets Docker escalate to SIGKILL after graceSecs, then removes the container
+func stopContainer(ctx context.Context, api containerStopAPI, taskID, containerID string, graceSecs int) error {
+	if err := api.ContainerStop(ctx, containerID, container.StopOptions{Signal: "SIGTERM", Timeout: &graceSecs}); err != nil {
 		log.Printf("[Task %s] Warning: failed to stop container gracefully: %v", taskID, err)
 		// Try to kill it forcefully
-		if killErr := e.dockerClient.ContainerKill(ctx, containerID, "SIGKILL"); killErr != nil {
+		if killErr := api.ContainerKill(ctx, containerID, "SIGKILL"); killErr != nil {
 			return fmt.Errorf("failed to kill container: %w", killErr)
 		}
 	}
 
 	// Remove the container
-	if err := e.dockerClient.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
+	if err := api.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
 		log.Printf("[Task %s] Warning: failed to remove container: %v", taskID, err)
 	}
+	return nil
+}
 
-	// Remove from tracking
-	e.mu.Lock()
-	delete(e.containers, taskID)
-	e.mu.Unlock()
+// Reasons a failed task ended, reported to the master as the result's failure_reason
+const (
+	FailureReasonOOM      = "oom"       // Killed by the kernel OOM killer after exceeding its memory limit
+	FailureReasonSignal   = "signal"    // Terminated by a signal (exit code 128+n)
+	FailureReasonExitCode = "exit_code" // The application itself exited with a non-zero code
+	// The image was not pulled within the pull timeout (the container never started)
+	FailureReasonImagePullTimeout = "image_pull_timeout"
+	// The task's health check did not pass within its timeout; the container was stopped
+	FailureReasonUnhealthy = "unhealthy"
+)
 
-	log.Printf("[Task %s] ✓ Task cancelled successfully", taskID)
-	return nil
+// containerInspectAPI is the subset of the Docker client used to inspect containers
+type containerInspectAPI interface {
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+}
+
+// exitReason inspects an exited container to tell an OOM kill or a signal apart from an application error
+func exitReason(ctx context.Context, api containerInspectAPI, taskID, containerID string, exitCode int64) string {
+	inspect, err := api.ContainerInspect(ctx, containerID)
+	if err != nil {
+		log.Printf("[Task %s] Warning: failed to inspect exited container: %v", taskID, err)
+	} else if inspect.ContainerJSONBase != nil && inspect.State != nil && inspect.State.OOMKilled {
+		return FailureReasonOOM
+	}
+
+	if exitCode > 128 {
+		log.Printf("[Task %s] Container was terminated by signal %d", taskID, exitCode-128)
+		return FailureReasonSignal
+	}
+	return FailureReasonExitCode
 }
 
 // GetLogStreamManager returns the log stream manager for direct access
@@ -0,0 +1,784 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"worker/internal/logstream"
+	"worker/internal/resultcache"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+)
+
+// fakeImageAPI records pulls and serves a fixed local image list
+type fakeImageAPI struct {
+	local []image.Summary
+	pulls []string
+}
+
+func (f *fakeImageAPI) ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error) {
+	return f.local, nil
+}
+
+func (f *fakeImageAPI) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	f.pulls = append(f.pulls, refStr)
+	return io.NopCloser(strings.NewReader("{}")), nil
+}
+
+// TestEnsureImageSkipsPullWhenPresent tests that a locally cached image is not pulled again
+func TestEnsureImageSkipsPullWhenPresent(t *testing.T) {
+	api := &fakeImageAPI{
+		local: []image.Summary{{ID: "sha256:abc", RepoTags: []string{"alpine:latest"}}},
+	}
+
+	pulled, err := ensureImage(context.Background(), api, "alpine", pullOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pulled {
+		t.Error("Expected cached image not to be reported as pulled")
+	}
+	if len(api.pulls) != 0 {
+		t.Errorf("Expected no pulls, got %v", api.pulls)
+	}
+}
+
+// TestEnsureImagePullsWhenMissing tests that a missing image goes through the pull path
+func TestEnsureImagePullsWhenMissing(t *testing.T) {
+	api := &fakeImageAPI{}
+
+	pulled, err := ensureImage(context.Background(), api, "alpine:3.20", pullOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !pulled || len(api.pulls) != 1 || api.pulls[0] != "alpine:3.20" {
+		t.Errorf("Expected a single pull of alpine:3.20, got pulled=%v pulls=%v", pulled, api.pulls)
+	}
+}
+
+// stalledPullAPI sends a sample progress stream and then stalls until the pull's context ends
+type stalledPullAPI struct {
+	fakeImageAPI
+	progress string
+}
+
+func (f *stalledPullAPI) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	f.pulls = append(f.pulls, refStr)
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, f.progress)
+		<-ctx.Done()
+		w.CloseWithError(ctx.Err())
+	}()
+	return r, nil
+}
+
+// TestPullImageTimesOutWithProgress tests that a stalled pull fails with the pull timeout after reporting layer progress
+func TestPullImageTimesOutWithProgress(t *testing.T) {
+	api := &stalledPullAPI{progress: `{"status":"Pulling from library/alpine","id":"latest"}
+{"status":"Downloading","id":"layer1","progressDetail":{"current":10,"total":100}}
+{"status":"Downloading","id":"layer1","progressDetail":{"current":30,"total":100}}
+{"status":"Downloading","id":"layer1","progressDetail":{"current":40,"total":100}}
+{"status":"Download complete","id":"layer1"}
+{"status":"Downloading","id":"layer2","progressDetail":{"current":50,"total":100}}
+`}
+
+	var lines []string
+	opts := pullOptions{timeout: 100 * time.Millisecond, progress: func(line string) { lines = append(lines, line) }}
+
+	start := time.Now()
+	err := pullImage(context.Background(), api, "alpine", opts)
+	if !errors.Is(err, ErrImagePullTimeout) {
+		t.Fatalf("Expected ErrImagePullTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the pull to give up after its timeout, took %s", elapsed)
+	}
+
+	expected := []string{
+		"pulling layer layer1: 30% complete",
+		"pulling layer layer1: 100% complete",
+		"pulling layer layer2: 50% complete",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected progress lines %q, got %q", expected, lines)
+	}
+}
+
+// fakeRegistryAPI serves inspections for known images and fails pulls of anything else
+type fakeRegistryAPI struct {
+	fakeImageAPI
+	known map[string]image.InspectResponse // Images the registry can serve
+	local map[string]bool                  // Images already pulled
+}
+
+func (f *fakeRegistryAPI) ImageInspect(ctx context.Context, imageID string, inspectOpts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	if !f.local[imageID] {
+		return image.InspectResponse{}, fmt.Errorf("No such image: %s", imageID)
+	}
+	return f.known[imageID], nil
+}
+
+func (f *fakeRegistryAPI) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	f.pulls = append(f.pulls, refStr)
+	if _, ok := f.known[refStr]; !ok {
+		return nil, fmt.Errorf("manifest for %s not found: manifest unknown", refStr)
+	}
+	f.local[refStr] = true
+	return io.NopCloser(strings.NewReader("{}")), nil
+}
+
+// TestValidateImageMissingImageFails tests that a nonexistent image returns a clear failure
+func TestValidateImageMissingImageFails(t *testing.T) {
+	api := &fakeRegistryAPI{known: map[string]image.InspectResponse{}, local: map[string]bool{}}
+
+	result, err := validateImage(context.Background(), api, "user/does-not-exist", pullOptions{})
+	if err == nil {
+		t.Fatalf("Expected an error for a nonexistent image, got %+v", result)
+	}
+	if !strings.Contains(err.Error(), "user/does-not-exist") || !strings.Contains(err.Error(), "could not be pulled") {
+		t.Errorf("Expected error to name the image and the failed pull, got %q", err.Error())
+	}
+}
+
+// TestValidateImagePullsMissingImage tests that an image missing locally is pulled and then inspected
+func TestValidateImagePullsMissingImage(t *testing.T) {
+	api := &fakeRegistryAPI{
+		known: map[string]image.InspectResponse{
+			"alpine:latest": {ID: "sha256:abc", RepoDigests: []string{"alpine@sha256:def"}, Size: 1024},
+		},
+		local: map[string]bool{},
+	}
+
+	result, err := validateImage(context.Background(), api, "alpine", pullOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Pulled || result.Digest != "alpine@sha256:def" || result.SizeBytes != 1024 {
+		t.Errorf("Expected pulled image with digest alpine@sha256:def and 1024 bytes, got %+v", result)
+	}
+
+	// A second validation finds the image locally
+	result, err = validateImage(context.Background(), api, "alpine", pullOptions{})
+	if err != nil || result.Pulled {
+		t.Errorf("Expected cached image without a pull, got %+v (err %v)", result, err)
+	}
+}
+
+// TestPulledImageDigestRecordedInResult tests that an image pulled for a task is pinned to its repo digest in the result
+func TestPulledImageDigestRecordedInResult(t *testing.T) {
+	api := &fakeRegistryAPI{
+		known: map[string]image.InspectResponse{
+			"alpine": {ID: "sha256:abc"},
+			"alpine:latest": {ID: "sha256:abc", RepoDigests: []string{
+				"mirror.local/alpine@sha256:111",
+				"alpine@sha256:def",
+			}},
+		},
+		local: map[string]bool{},
+	}
+
+	pulled, err := ensureImage(context.Background(), api, "alpine", pullOptions{})
+	if err != nil || !pulled {
+		t.Fatalf("Expected alpine to be pulled, got pulled=%v err=%v", pulled, err)
+	}
+	api.local["alpine:latest"] = true
+
+	result := &TaskResult{TaskID: "task-1"}
+	runImage := pinImageDigest(context.Background(), api, "task-1", "alpine", result)
+	if result.ImageDigest != "alpine@sha256:def" {
+		t.Errorf("Expected digest alpine@sha256:def in the result, got %q", result.ImageDigest)
+	}
+	if runImage != "alpine@sha256:def" {
+		t.Errorf("Expected the container to run from the pinned digest, got %q", runImage)
+	}
+}
+
+// TestPinDigestsRejectsLatest tests that the PIN_DIGESTS policy refuses untagged and :latest images only
+func TestPinDigestsRejectsLatest(t *testing.T) {
+	e := &TaskExecutor{}
+	if err := e.checkDigestPolicy("alpine"); err != nil {
+		t.Errorf("Expected no rejection with pinning off, got %v", err)
+	}
+
+	e.SetPinDigests(true)
+	for _, ref := range []string{"alpine", "alpine:latest", "registry:5000/team/app"} {
+		if err := e.checkDigestPolicy(ref); err == nil {
+			t.Errorf("Expected %s to be rejected", ref)
+		}
+	}
+	for _, ref := range []string{"alpine:3.20", "alpine@sha256:def", "registry:5000/team/app:v2"} {
+		if err := e.checkDigestPolicy(ref); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", ref, err)
+		}
+	}
+}
+
+// TestNormalizeImageRef tests implicit tag handling for local image lookups
+func TestNormalizeImageRef(t *testing.T) {
+	cases := map[string]string{
+		"alpine":                    "alpine:latest",
+		"alpine:3.20":               "alpine:3.20",
+		"registry:5000/team/app":    "registry:5000/team/app:latest",
+		"registry:5000/team/app:v1": "registry:5000/team/app:v1",
+		"alpine@sha256:deadbeef":    "alpine@sha256:deadbeef",
+	}
+	for in, expected := range cases {
+		if got := normalizeImageRef(in); got != expected {
+			t.Errorf("normalizeImageRef(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
+
+// TestCacheableTaskSecondRunUsesCache tests that an identical cacheable task is served from cache without a container
+func TestCacheableTaskSecondRunUsesCache(t *testing.T) {
+	outputBase := t.TempDir()
+	runs := 0
+
+	digest := "alpine@sha256:def"
+	e := &TaskExecutor{
+		resultCache: resultcache.New(t.TempDir(), 0),
+		containers:  make(map[string]string),
+	}
+	// Stand-in for the image the tag currently resolves to
+	e.resolveFn = func(ctx context.Context, spec TaskSpec) (string, error) {
+		return digest, nil
+	}
+	// Stand-in for the Docker run path: counts container starts and writes one output file
+	e.runFn = func(ctx context.Context, spec TaskSpec) *TaskResult {
+		runs++
+		outputDir := filepath.Join(outputBase, spec.TaskID)
+		if err := os.MkdirAll(outputDir, 0700); err != nil {
+			t.Fatalf("Failed to create output dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(outputDir, "result.txt"), []byte("42"), 0600); err != nil {
+			t.Fatalf("Failed to write output: %v", err)
+		}
+		return &TaskResult{
+			TaskID:         spec.TaskID,
+			Status:         "success",
+			Logs:           "computed 42\n",
+			ResultLocation: outputDir,
+			OutputFiles:    []string{"result.txt"},
+		}
+	}
+
+	first := e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-1", DockerImage: "alpine:latest", Command: "echo 42", ReqCPU: 1, ReqMemory: 1, Cacheable: true})
+	if first.CacheHit {
+		t.Error("Expected first run to miss the cache")
+	}
+
+	second := e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-2", DockerImage: "alpine:latest", Command: "echo 42", ReqCPU: 1, ReqMemory: 1, Cacheable: true})
+	if runs != 1 {
+		t.Errorf("Expected 1 container run, got %d", runs)
+	}
+	if !second.CacheHit || second.Status != "success" {
+		t.Errorf("Expected cached success, got status=%s cache_hit=%v", second.Status, second.CacheHit)
+	}
+	if second.Logs != "computed 42\n" {
+		t.Errorf("Expected cached logs, got %q", second.Logs)
+	}
+	if len(second.OutputFiles) != 1 || second.OutputFiles[0] != "result.txt" {
+		t.Fatalf("Expected cached output result.txt, got %v", second.OutputFiles)
+	}
+	data, err := os.ReadFile(filepath.Join(second.ResultLocation, "result.txt"))
+	if err != nil || string(data) != "42" {
+		t.Errorf("Expected cached output contents 42, got %q (err: %v)", data, err)
+	}
+
+	// A different command must not reuse the cached result
+	e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-3", DockerImage: "alpine:latest", Command: "echo 43", ReqCPU: 1, ReqMemory: 1, Cacheable: true})
+	if runs != 2 {
+		t.Errorf("Expected different command to run a container, got %d runs", runs)
+	}
+
+	// A re-pushed tag resolves to a new digest and must not reuse the old image's result
+	digest = "alpine@sha256:fed"
+	repushed := e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-4", DockerImage: "alpine:latest", Command: "echo 42", ReqCPU: 1, ReqMemory: 1, Cacheable: true})
+	if runs != 3 || repushed.CacheHit {
+		t.Errorf("Expected the re-pushed image to run a container, got %d runs (cache_hit=%v)", runs, repushed.CacheHit)
+	}
+
+	// The same command in another working directory or as another user must not reuse the cached result
+	moved := e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-5", DockerImage: "alpine:latest", Command: "echo 42", ReqCPU: 1, ReqMemory: 1, Cacheable: true, Process: TaskProcess{WorkingDir: "/app"}})
+	if runs != 4 || moved.CacheHit {
+		t.Errorf("Expected a different working directory to run a container, got %d runs (cache_hit=%v)", runs, moved.CacheHit)
+	}
+	otherUser := e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-6", DockerImage: "alpine:latest", Command: "echo 42", ReqCPU: 1, ReqMemory: 1, Cacheable: true, Process: TaskProcess{WorkingDir: "/app", User: "1000:1000"}})
+	if runs != 5 || otherUser.CacheHit {
+		t.Errorf("Expected a different user to run a container, got %d runs (cache_hit=%v)", runs, otherUser.CacheHit)
+	}
+}
+
+// TestFastFailingTaskReportsCrashLoop tests that an always-failing fast-exit container stops restarting after N fast failures
+func TestFastFailingTaskReportsCrashLoop(t *testing.T) {
+	runs := 0
+	e := &TaskExecutor{
+		crashLoop:  CrashLoopPolicy{Window: time.Minute, MaxFastFails: 3},
+		containers: make(map[string]string),
+	}
+	// Stand-in for a container whose command exits 1 immediately
+	e.runFn = func(ctx context.Context, spec TaskSpec) *TaskResult {
+		runs++
+		return &TaskResult{TaskID: spec.TaskID, Status: "failed", ExitCode: 1, Logs: "boom\n"}
+	}
+
+	result := e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-1", DockerImage: "alpine:latest", Command: "false", ReqCPU: 1, ReqMemory: 1, MaxRestarts: 10})
+
+	if result.Status != "crashloop" {
+		t.Fatalf("Expected crashloop status, got %s", result.Status)
+	}
+	if runs != 3 || result.Attempts != 3 {
+		t.Errorf("Expected 3 attempts before giving up, got %d runs (attempts=%d)", runs, result.Attempts)
+	}
+	if result.Error == nil {
+		t.Error("Expected a crash-loop error describing the exits")
+	}
+}
+
+// TestFailingTaskWithoutRestartsIsFailed tests that the default of no restarts keeps the plain failed status
+func TestFailingTaskWithoutRestartsIsFailed(t *testing.T) {
+	runs := 0
+	e := &TaskExecutor{
+		crashLoop:  CrashLoopPolicy{Window: time.Minute, MaxFastFails: 3},
+		containers: make(map[string]string),
+	}
+	e.runFn = func(ctx context.Context, spec TaskSpec) *TaskResult {
+		runs++
+		return &TaskResult{TaskID: spec.TaskID, Status: "failed", ExitCode: 1}
+	}
+
+	result := e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-1", DockerImage: "alpine:latest", Command: "false", ReqCPU: 1, ReqMemory: 1})
+
+	if result.Status != "failed" || runs != 1 {
+		t.Errorf("Expected a single failed run, got status=%s runs=%d", result.Status, runs)
+	}
+}
+
+// TestApplyMemoryLimitsSoftAndHard tests that a hard cap above the request sets both Memory and MemoryReservation
+func TestApplyMemoryLimitsSoftAndHard(t *testing.T) {
+	var res container.Resources
+	applyMemoryLimits(&res, 2.0, 4.0)
+
+	if res.MemoryReservation != 2*units.GiB {
+		t.Errorf("Expected MemoryReservation of 2GiB, got %d", res.MemoryReservation)
+	}
+	if res.Memory != 4*units.GiB {
+		t.Errorf("Expected Memory hard cap of 4GiB, got %d", res.Memory)
+	}
+
+	// Without a cap above the request, the request stays a hard limit
+	var strict container.Resources
+	applyMemoryLimits(&strict, 2.0, 0)
+
+	if strict.Memory != 2*units.GiB || strict.MemoryReservation != 0 {
+		t.Errorf("Expected hard 2GiB limit without reservation, got Memory=%d MemoryReservation=%d",
+			strict.Memory, strict.MemoryReservation)
+	}
+}
+
+// fakeStopAPI records how containers were stopped
+type fakeStopAPI struct {
+	stops   []container.StopOptions
+	kills   []string
+	removed []string
+}
+
+func (f *fakeStopAPI) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	f.stops = append(f.stops, options)
+	return nil
+}
+
+func (f *fakeStopAPI) ContainerKill(ctx context.Context, containerID, signal string) error {
+	f.kills = append(f.kills, signal)
+	return nil
+}
+
+func (f *fakeStopAPI) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	f.removed = append(f.removed, containerID)
+	return nil
+}
+
+// TestCancelUsesConfiguredStopGracePeriod tests that a task's grace period reaches ContainerStop with SIGTERM
+func TestCancelUsesConfiguredStopGracePeriod(t *testing.T) {
+	e := &TaskExecutor{containers: make(map[string]string)}
+
+	var graceWhileRunning int
+	e.runFn = func(ctx context.Context, spec TaskSpec) *TaskResult {
+		graceWhileRunning = e.stopGracePeriod(spec.TaskID)
+		return &TaskResult{TaskID: spec.TaskID, Status: "success"}
+	}
+	e.ExecuteTask(context.Background(), TaskSpec{TaskID: "task-1", DockerImage: "alpine:latest", Command: "sleep 60", ReqCPU: 1, ReqMemory: 1, StopGraceSec: 45})
+
+	if graceWhileRunning != 45 {
+		t.Errorf("Expected grace of 45s while the task runs, got %d", graceWhileRunning)
+	}
+	if got := e.stopGracePeriod("task-1"); got != DefaultStopGracePeriod {
+		t.Errorf("Expected grace to reset to the default after the task finished, got %d", got)
+	}
+
+	api := &fakeStopAPI{}
+	if err := stopContainer(context.Background(), api, "task-1", "abcdef1234567890", graceWhileRunning); err != nil {
+		t.Fatalf("stopContainer failed: %v", err)
+	}
+	if len(api.stops) != 1 {
+		t.Fatalf("Expected one ContainerStop call, got %d", len(api.stops))
+	}
+	if api.stops[0].Signal != "SIGTERM" {
+		t.Errorf("Expected SIGTERM first, got %q", api.stops[0].Signal)
+	}
+	if api.stops[0].Timeout == nil || *api.stops[0].Timeout != 45 {
+		t.Errorf("Expected ContainerStop timeout of 45s, got %v", api.stops[0].Timeout)
+	}
+	if len(api.kills) != 0 {
+		t.Errorf("Expected no explicit SIGKILL after a clean stop, got %v", api.kills)
+	}
+	if len(api.removed) != 1 {
+		t.Errorf("Expected the container to be removed, got %v", api.removed)
+	}
+}
+
+// TestReadContainerLogsLabelsStreams tests that a multiplexed log stream is split into labelled stdout and stderr lines
+func TestReadContainerLogsLabelsStreams(t *testing.T) {
+	var muxed bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&muxed, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(&muxed, stdcopy.Stderr)
+	stdout.Write([]byte("starting\n"))
+	stderr.Write([]byte("warning: low memory\n"))
+	stdout.Write([]byte("step 1 "))
+	stdout.Write([]byte("done\n"))
+	stderr.Write([]byte("fatal: disk full"))
+
+	lines, err := readContainerLogs(&muxed, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []logstream.LogLine{
+		{Content: "starting", Stream: logstream.StreamStdout},
+		{Content: "warning: low memory", Stream: logstream.StreamStderr},
+		{Content: "step 1 done", Stream: logstream.StreamStdout},
+		{Content: "fatal: disk full", Stream: logstream.StreamStderr},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d: %+v", len(expected), len(lines), lines)
+	}
+	for i, want := range expected {
+		if lines[i].Content != want.Content || lines[i].Stream != want.Stream {
+			t.Errorf("Expected line %d to be %s %q, got %s %q", i, want.Stream, want.Content, lines[i].Stream, lines[i].Content)
+		}
+	}
+}
+
+// fakeInspectAPI serves a fixed container state
+type fakeInspectAPI struct {
+	state *container.State
+	ports nat.PortMap
+}
+
+func (f *fakeInspectAPI) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: containerID, State: f.state},
+		NetworkSettings:   &container.NetworkSettings{NetworkSettingsBase: container.NetworkSettingsBase{Ports: f.ports}},
+	}, nil
+}
+
+// TestExitReasonDetectsOOMKill tests that an OOM-killed container is reported as oom rather than a plain exit code
+func TestExitReasonDetectsOOMKill(t *testing.T) {
+	oom := &fakeInspectAPI{state: &container.State{Status: "exited", OOMKilled: true, ExitCode: 137}}
+	if got := exitReason(context.Background(), oom, "task-1", "abcdef1234567890", 137); got != FailureReasonOOM {
+		t.Errorf("Expected failure_reason %q, got %q", FailureReasonOOM, got)
+	}
+
+	// The same exit code without the OOM flag is a signal, and a small code is the application's own error
+	killed := &fakeInspectAPI{state: &container.State{Status: "exited", ExitCode: 137}}
+	if got := exitReason(context.Background(), killed, "task-1", "abcdef1234567890", 137); got != FailureReasonSignal {
+		t.Errorf("Expected failure_reason %q, got %q", FailureReasonSignal, got)
+	}
+	appError := &fakeInspectAPI{state: &container.State{Status: "exited", ExitCode: 1}}
+	if got := exitReason(context.Background(), appError, "task-1", "abcdef1234567890", 1); got != FailureReasonExitCode {
+		t.Errorf("Expected failure_reason %q, got %q", FailureReasonExitCode, got)
+	}
+}
+
+// TestApplyNetworkConfigPublishesPorts tests that a requested container port is exposed and bound to a host port
+func TestApplyNetworkConfigPublishesPorts(t *testing.T) {
+	cfg := &container.Config{}
+	hostConfig := &container.HostConfig{}
+	network := TaskNetwork{Mode: "bridge", Ports: []string{"8080", "9000:9090/udp"}}
+	if err := applyNetworkConfig(cfg, hostConfig, network); err != nil {
+		t.Fatalf("Failed to apply network config: %v", err)
+	}
+
+	if hostConfig.NetworkMode != "bridge" {
+		t.Errorf("Expected network mode bridge, got %q", hostConfig.NetworkMode)
+	}
+	for _, port := range []nat.Port{"8080/tcp", "9090/udp"} {
+		if _, ok := cfg.ExposedPorts[port]; !ok {
+			t.Errorf("Expected %s to be exposed, got %v", port, cfg.ExposedPorts)
+		}
+	}
+
+	// A bare container port is bound to a host port Docker picks; an explicit one is kept
+	if bindings := hostConfig.PortBindings["8080/tcp"]; len(bindings) != 1 || bindings[0].HostPort != "" {
+		t.Errorf("Expected 8080/tcp bound to a Docker-assigned host port, got %v", bindings)
+	}
+	if bindings := hostConfig.PortBindings["9090/udp"]; len(bindings) != 1 || bindings[0].HostPort != "9000" {
+		t.Errorf("Expected 9090/udp bound to host port 9000, got %v", bindings)
+	}
+
+	// Ports cannot be published without a network namespace of the container's own
+	if err := applyNetworkConfig(&container.Config{}, &container.HostConfig{}, TaskNetwork{Mode: "host", Ports: []string{"8080"}}); err == nil {
+		t.Error("Expected an error publishing ports with host networking")
+	}
+
+	// Joining another container's network namespace is never allowed
+	if err := applyNetworkConfig(&container.Config{}, &container.HostConfig{}, TaskNetwork{Mode: "container:other"}); err == nil {
+		t.Error("Expected an error for a container: network mode")
+	}
+}
+
+// TestPublishedPortsReportsAssignedHostPort tests that the host port Docker assigned is read back from the container
+func TestPublishedPortsReportsAssignedHostPort(t *testing.T) {
+	api := &fakeInspectAPI{ports: nat.PortMap{
+		"8080/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "49153"}},
+		"9090/udp": nil, // Exposed but not published
+	}}
+
+	ports, err := publishedPorts(context.Background(), api, "abcdef1234567890")
+	if err != nil {
+		t.Fatalf("Failed to read published ports: %v", err)
+	}
+	if len(ports) != 1 {
+		t.Fatalf("Expected 1 published port, got %v", ports)
+	}
+	want := PublishedPort{ContainerPort: "8080/tcp", HostIP: "0.0.0.0", HostPort: 49153}
+	if ports[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, ports[0])
+	}
+}
+
+// newFakeDockerDaemon starts a stand-in Docker daemon serving handlers by request path suffix and returns a client for it
+func newFakeDockerDaemon(t *testing.T, handlers map[string]http.HandlerFunc) *client.Client {
+	t.Helper()
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for suffix, handler := range handlers {
+			if strings.HasSuffix(r.URL.Path, suffix) {
+				handler(w, r)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(daemon.Close)
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(daemon.URL, "http://")), client.WithVersion("1.43"))
+	if err != nil {
+		t.Fatalf("Failed to create docker client: %v", err)
+	}
+	return cli
+}
+
+// recordContainerCreates starts a fake Docker daemon that records each container create request
+func recordContainerCreates(t *testing.T) (*client.Client, <-chan container.CreateRequest) {
+	t.Helper()
+	created := make(chan container.CreateRequest, 1)
+	cli := newFakeDockerDaemon(t, map[string]http.HandlerFunc{
+		"/containers/create": func(w http.ResponseWriter, r *http.Request) {
+			var body container.CreateRequest
+			json.NewDecoder(r.Body).Decode(&body)
+			created <- body
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Id": "abcdef1234567890", "Warnings": []}`))
+		},
+	})
+	return cli, created
+}
+
+// TestCreateContainerSetsWorkingDirAndUser tests that a task's working directory and user end up in the created container config
+func TestCreateContainerSetsWorkingDirAndUser(t *testing.T) {
+	t.Setenv("CLOUDAI_OUTPUT_DIR", t.TempDir())
+
+	cli, created := recordContainerCreates(t)
+	e := &TaskExecutor{dockerClient: cli}
+
+	spec := TaskSpec{TaskID: "task-1", Command: "pwd", ReqCPU: 1, ReqMemory: 1, Process: TaskProcess{WorkingDir: "/app", User: "1000:1000"}}
+	if _, err := e.createContainer(context.Background(), "alpine:latest", spec); err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+	cfg := (<-created).Config
+	if cfg.WorkingDir != "/app" {
+		t.Errorf("Expected working directory /app, got %q", cfg.WorkingDir)
+	}
+	if cfg.User != "1000:1000" {
+		t.Errorf("Expected user 1000:1000, got %q", cfg.User)
+	}
+
+	// Without overrides the image defaults are left alone
+	spec = TaskSpec{TaskID: "task-2", Command: "pwd", ReqCPU: 1, ReqMemory: 1}
+	if _, err := e.createContainer(context.Background(), "alpine:latest", spec); err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+	if cfg := (<-created).Config; cfg.WorkingDir != "" || cfg.User != "" {
+		t.Errorf("Expected no working directory or user override, got %q and %q", cfg.WorkingDir, cfg.User)
+	}
+}
+
+// TestCreateContainerReadOnlyRootFS tests that a read-only root filesystem is requested while /output stays writable
+func TestCreateContainerReadOnlyRootFS(t *testing.T) {
+	t.Setenv("CLOUDAI_OUTPUT_DIR", t.TempDir())
+
+	cli, created := recordContainerCreates(t)
+	e := &TaskExecutor{dockerClient: cli}
+
+	assertReadOnly := func(taskID string, process TaskProcess, want bool) {
+		t.Helper()
+		spec := TaskSpec{TaskID: taskID, Command: "touch /output/ok", ReqCPU: 1, ReqMemory: 1, Process: process}
+		if _, err := e.createContainer(context.Background(), "alpine:latest", spec); err != nil {
+			t.Fatalf("Failed to create container: %v", err)
+		}
+		req := <-created
+		if req.HostConfig == nil {
+			t.Fatal("Expected a host config in the create request")
+		}
+		if req.HostConfig.ReadonlyRootfs != want {
+			t.Errorf("Expected ReadonlyRootfs %v for %s, got %v", want, taskID, req.HostConfig.ReadonlyRootfs)
+		}
+		writable := false
+		for _, m := range req.HostConfig.Mounts {
+			if m.Target == "/output" && !m.ReadOnly {
+				writable = true
+			}
+		}
+		if !writable {
+			t.Errorf("Expected a writable /output mount for %s, got %+v", taskID, req.HostConfig.Mounts)
+		}
+	}
+
+	// The task flag locks the root; a task without it is left writable
+	assertReadOnly("task-1", TaskProcess{ReadOnlyRootFS: true}, true)
+	assertReadOnly("task-2", TaskProcess{}, false)
+
+	// The worker policy forces it on for every task
+	e.SetReadOnlyRootFS(true)
+	assertReadOnly("task-2", TaskProcess{}, true)
+}
+
+// fakeExecAPI runs health checks that fail until the container has been up for healthyAfter
+type fakeExecAPI struct {
+	started      time.Time
+	healthyAfter time.Duration
+	runs         int
+}
+
+func (f *fakeExecAPI) ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error) {
+	f.runs++
+	return container.ExecCreateResponse{ID: fmt.Sprintf("exec-%d", f.runs)}, nil
+}
+
+func (f *fakeExecAPI) ContainerExecStart(ctx context.Context, execID string, config container.ExecStartOptions) error {
+	return nil
+}
+
+func (f *fakeExecAPI) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	exitCode := 1
+	if time.Since(f.started) >= f.healthyAfter {
+		exitCode = 0
+	}
+	return container.ExecInspect{ExecID: execID, Running: false, ExitCode: exitCode}, nil
+}
+
+// TestWatchHealthReportsReadyOnceHealthy tests that a container passing its health check after a delay goes from running to ready
+func TestWatchHealthReportsReadyOnceHealthy(t *testing.T) {
+	api := &fakeExecAPI{started: time.Now(), healthyAfter: 100 * time.Millisecond}
+	check := TaskHealthCheck{Command: "curl -f localhost:8080/health", Timeout: 5 * time.Second, Interval: 20 * time.Millisecond}
+
+	var states []string
+	err := watchHealth(context.Background(), api, "task-1", "container-1", check, func(state string) {
+		states = append(states, state)
+	})
+	if err != nil {
+		t.Fatalf("Expected the task to become healthy, got %v", err)
+	}
+	if len(states) != 2 || states[0] != ReadinessRunning || states[1] != ReadinessReady {
+		t.Errorf("Expected states [running ready], got %v", states)
+	}
+	if api.runs < 2 {
+		t.Errorf("Expected the health check to fail before passing, got %d run(s)", api.runs)
+	}
+}
+
+// TestWatchHealthTimesOut tests that a health check that never passes fails with ErrUnhealthy and never reports ready
+func TestWatchHealthTimesOut(t *testing.T) {
+	api := &fakeExecAPI{started: time.Now(), healthyAfter: time.Hour}
+	check := TaskHealthCheck{Command: "false", Timeout: 100 * time.Millisecond, Interval: 20 * time.Millisecond}
+
+	var states []string
+	err := watchHealth(context.Background(), api, "task-1", "container-1", check, func(state string) {
+		states = append(states, state)
+	})
+	if !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("Expected ErrUnhealthy, got %v", err)
+	}
+	if len(states) != 1 || states[0] != ReadinessRunning {
+		t.Errorf("Expected only the running state, got %v", states)
+	}
+}
+
+// TestCreateContainerAppliesSandboxPolicy tests that the sandbox policy's cap-drop list and security options reach the host config
+func TestCreateContainerAppliesSandboxPolicy(t *testing.T) {
+	t.Setenv("CLOUDAI_OUTPUT_DIR", t.TempDir())
+
+	cli, created := recordContainerCreates(t)
+	profile := filepath.Join(t.TempDir(), "seccomp.json")
+	if err := os.WriteFile(profile, []byte(`{"defaultAction":"SCMP_ACT_ALLOW"}`), 0600); err != nil {
+		t.Fatalf("Failed to write seccomp profile: %v", err)
+	}
+	e := &TaskExecutor{dockerClient: cli}
+	e.SetSandboxPolicy(SandboxPolicy{
+		DropCapabilities: true,
+		AddCapabilities:  ParseCapabilities(" chown, ,NET_BIND_SERVICE,"),
+		NoNewPrivileges:  true,
+		SeccompProfile:   profile,
+	})
+
+	if _, err := e.createContainer(context.Background(), "alpine:latest", TaskSpec{TaskID: "task-1", Command: "id", ReqCPU: 1, ReqMemory: 1}); err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+	req := <-created
+	if req.HostConfig == nil {
+		t.Fatal("Expected a host config in the create request")
+	}
+	if fmt.Sprint(req.HostConfig.CapDrop) != "[ALL]" {
+		t.Errorf("Expected CapDrop [ALL], got %v", req.HostConfig.CapDrop)
+	}
+	if fmt.Sprint(req.HostConfig.CapAdd) != "[CAP_CHOWN CAP_NET_BIND_SERVICE]" {
+		t.Errorf("Expected CapAdd [CAP_CHOWN CAP_NET_BIND_SERVICE], got %v", req.HostConfig.CapAdd)
+	}
+	expectedOpts := []string{"no-new-privileges", `seccomp={"defaultAction":"SCMP_ACT_ALLOW"}`}
+	if fmt.Sprint(req.HostConfig.SecurityOpt) != fmt.Sprint(expectedOpts) {
+		t.Errorf("Expected security options %v, got %v", expectedOpts, req.HostConfig.SecurityOpt)
+	}
+
+	// A missing seccomp profile fails the task instead of running it unconfined
+	e.SetSandboxPolicy(SandboxPolicy{SeccompProfile: filepath.Join(t.TempDir(), "missing.json")})
+	if _, err := e.createContainer(context.Background(), "alpine:latest", TaskSpec{TaskID: "task-2", Command: "id", ReqCPU: 1, ReqMemory: 1}); err == nil {
+		t.Error("Expected an error for a missing seccomp profile")
+	}
+}
@@ -0,0 +1,125 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// DefaultHealthCheckTimeout is how long a task with a health check may take to become ready
+const DefaultHealthCheckTimeout = 60 * time.Second
+
+// DefaultHealthCheckInterval is the time between two runs of a task's health check
+const DefaultHealthCheckInterval = 2 * time.Second
+
+// execPollInterval is how often a running health check is polled for its exit code
+const execPollInterval = 100 * time.Millisecond
+
+// Readiness states reported for a task with a health check
+const (
+	ReadinessRunning = "running" // Container started, health check not passed yet
+	ReadinessReady   = "ready"   // Health check passed
+)
+
+// ErrUnhealthy is returned when a task's health check does not pass within its timeout
+var ErrUnhealthy = errors.New("health check did not pass")
+
+// TaskHealthCheck is a command run inside a started container; the task is ready once it exits 0
+type TaskHealthCheck struct {
+	Command  string        // Run with sh -c inside the container ("" = no health check)
+	Timeout  time.Duration // How long the task may take to become ready (0 = DefaultHealthCheckTimeout)
+	Interval time.Duration // Time between runs (0 = DefaultHealthCheckInterval)
+}
+
+// containerExecAPI is the subset of the Docker client used to run health checks inside a container
+type containerExecAPI interface {
+	ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error)
+	ContainerExecStart(ctx context.Context, execID string, config container.ExecStartOptions) error
+	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+}
+
+// SetReadinessHandler sets the function told when a task with a health check becomes ready (or is running again after a restart)
+func (e *TaskExecutor) SetReadinessHandler(handler func(taskID, state string)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onReadiness = handler
+}
+
+// reportReadiness passes a task's readiness state to the readiness handler, if one is set
+func (e *TaskExecutor) reportReadiness(taskID, state string) {
+	e.mu.RLock()
+	handler := e.onReadiness
+	e.mu.RUnlock()
+	if handler != nil {
+		handler(taskID, state)
+	}
+}
+
+// watchHealth reports the task running, then runs its health check every interval until it passes (reporting
+// the task ready) or the timeout ends (returning ErrUnhealthy); it returns ctx's error if ctx ends first
+func watchHealth(ctx context.Context, api containerExecAPI, taskID, containerID string, check TaskHealthCheck, report func(state string)) error {
+	timeout, interval := check.Timeout, check.Interval
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+
+	report(ReadinessRunning)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		exitCode, err := runHealthCheck(ctx, api, containerID, check.Command)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil && exitCode == 0 {
+			log.Printf("[Task %s] 💚 Health check passed - task is ready", taskID)
+			report(ReadinessReady)
+			return nil
+		}
+		if err != nil {
+			log.Printf("[Task %s] Health check could not run: %v", taskID, err)
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-deadline.C:
+			return fmt.Errorf("%w within %s: %s", ErrUnhealthy, timeout, check.Command)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// runHealthCheck runs command inside the container and waits for its exit code
+func runHealthCheck(ctx context.Context, api containerExecAPI, containerID, command string) (int, error) {
+	exec, err := api.ContainerExecCreate(ctx, containerID, container.ExecOptions{Cmd: []string{"sh", "-c", command}})
+	if err != nil {
+		return 0, fmt.Errorf("create exec: %w", err)
+	}
+	if err := api.ContainerExecStart(ctx, exec.ID, container.ExecStartOptions{Detach: true}); err != nil {
+		return 0, fmt.Errorf("start exec: %w", err)
+	}
+
+	for {
+		inspect, err := api.ContainerExecInspect(ctx, exec.ID)
+		if err != nil {
+			return 0, fmt.Errorf("inspect exec: %w", err)
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-time.After(execPollInterval):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// TaskNetwork is how a task's container is attached to the network
+type TaskNetwork struct {
+	Mode  string   // Docker network mode: bridge, host, none or a user-defined network name ("" = bridge)
+	Ports []string // Ports to publish: "8080" (any host port), "9000:8080" or "127.0.0.1:9000:8080/udp"
+}
+
+// PublishedPort is a container port published on the worker host
+type PublishedPort struct {
+	ContainerPort string // e.g. "8080/tcp"
+	HostIP        string
+	HostPort      int
+}
+
+// applyNetworkConfig sets the container's network mode and exposes and publishes the requested ports
+// A port with no host part is bound to a host port Docker picks, reported back once the container starts
+func applyNetworkConfig(cfg *container.Config, hostConfig *container.HostConfig, network TaskNetwork) error {
+	mode := container.NetworkMode(network.Mode)
+	if mode.IsContainer() {
+		return fmt.Errorf("network mode %q would join another container's network namespace", network.Mode)
+	}
+	if mode != "" {
+		hostConfig.NetworkMode = mode
+	}
+	if len(network.Ports) == 0 {
+		return nil
+	}
+	if mode.IsHost() || mode.IsNone() {
+		return fmt.Errorf("ports cannot be published with network mode %q", network.Mode)
+	}
+
+	exposed, bindings, err := nat.ParsePortSpecs(network.Ports)
+	if err != nil {
+		return fmt.Errorf("invalid port binding: %w", err)
+	}
+	cfg.ExposedPorts = exposed
+	hostConfig.PortBindings = bindings
+	return nil
+}
+
+// publishedPorts inspects a running container for the host ports its ports were published on
+func publishedPorts(ctx context.Context, api containerInspectAPI, containerID string) ([]PublishedPort, error) {
+	inspect, err := api.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	if inspect.NetworkSettings == nil {
+		return nil, nil
+	}
+
+	var ports []PublishedPort
+	for port, bindings := range inspect.NetworkSettings.Ports {
+		for _, binding := range bindings {
+			hostPort, err := strconv.Atoi(binding.HostPort)
+			if err != nil {
+				continue
+			}
+			ports = append(ports, PublishedPort{ContainerPort: string(port), HostIP: binding.HostIP, HostPort: hostPort})
+		}
+	}
+
+	// Keep the report stable regardless of map iteration order
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].ContainerPort != ports[j].ContainerPort {
+			return ports[i].ContainerPort < ports[j].ContainerPort
+		}
+		return ports[i].HostIP < ports[j].HostIP
+	})
+	return ports, nil
+}
+
+// SetPortsHandler sets the function told where a task's ports were published, while the task is still running
+func (e *TaskExecutor) SetPortsHandler(handler func(taskID string, ports []PublishedPort)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onPortsPublished = handler
+}
+
+// reportPublishedPorts passes a started task's published ports to the ports handler, if one is set
+func (e *TaskExecutor) reportPublishedPorts(taskID string, ports []PublishedPort) {
+	e.mu.RLock()
+	handler := e.onPortsPublished
+	e.mu.RUnlock()
+	if handler != nil && len(ports) > 0 {
+		handler(taskID, ports)
+	}
+}
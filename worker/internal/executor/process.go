@@ -0,0 +1,36 @@
+package executor
+
+import "github.com/docker/docker/api/types/container"
+
+// TaskProcess is where and as whom the task's main process runs inside its container
+type TaskProcess struct {
+	WorkingDir     string // Working directory ("" = the image's WORKDIR)
+	User           string // User name, UID or UID:GID ("" = the image's USER)
+	ReadOnlyRootFS bool   // Mount the root filesystem read-only; the /output mount stays writable
+}
+
+// applyProcessConfig sets the container's working directory and user when the task overrides them
+func applyProcessConfig(cfg *container.Config, process TaskProcess) {
+	if process.WorkingDir != "" {
+		cfg.WorkingDir = process.WorkingDir
+	}
+	if process.User != "" {
+		cfg.User = process.User
+	}
+}
+
+// SetReadOnlyRootFS mounts every task container's root filesystem read-only, whether or not the task asked for it
+func (e *TaskExecutor) SetReadOnlyRootFS(readOnly bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.readOnlyRootFS = readOnly
+}
+
+// applyRootFSPolicy makes the container's root filesystem read-only when the task or the worker policy asks for it
+// Only the root is locked: bind mounts such as /output keep their own (writable) mode
+func (e *TaskExecutor) applyRootFSPolicy(hostConfig *container.HostConfig, process TaskProcess) {
+	e.mu.RLock()
+	forced := e.readOnlyRootFS
+	e.mu.RUnlock()
+	hostConfig.ReadonlyRootfs = forced || process.ReadOnlyRootFS
+}
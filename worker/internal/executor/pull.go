@@ -0,0 +1,147 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+// DefaultImagePullTimeout is how long an image pull may take before the task fails with image_pull_timeout
+const DefaultImagePullTimeout = 10 * time.Minute
+
+// pullProgressStep is the layer download progress (percent) between two progress lines
+const pullProgressStep = 25
+
+// ErrImagePullTimeout is returned when an image pull does not finish within the pull timeout
+var ErrImagePullTimeout = errors.New("image pull timed out")
+
+// pullOptions controls how long a pull may take and where its progress goes
+type pullOptions struct {
+	timeout  time.Duration     // 0 waits indefinitely
+	progress func(line string) // Receives "pulling layer ..." lines; nil discards them
+}
+
+// pullMessage is one message of the JSON progress stream Docker sends while pulling
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error string `json:"error"`
+}
+
+// SetImagePullTimeout sets how long an image pull may take; 0 waits indefinitely
+func (e *TaskExecutor) SetImagePullTimeout(timeout time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pullTimeout = timeout
+}
+
+// taskPullOptions returns the pull settings for a task: the configured timeout, with progress
+// logged and kept for log streams until the task's container starts
+func (e *TaskExecutor) taskPullOptions(taskID string) pullOptions {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if state, ok := e.tasks[taskID]; ok {
+		state.pulling = true
+		state.progress = []string{}
+	}
+
+	return pullOptions{
+		timeout: e.pullTimeout,
+		progress: func(line string) {
+			log.Printf("[Task %s] %s", taskID, line)
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			if state, ok := e.tasks[taskID]; ok && state.pulling {
+				state.progress = append(state.progress, line)
+			}
+		},
+	}
+}
+
+// PullProgress returns the image pull progress lines of a task that has no container yet
+// ok is false once the task has finished (or was never started on this worker)
+func (e *TaskExecutor) PullProgress(taskID string) (lines []string, ok bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	state, exists := e.tasks[taskID]
+	if !exists || !state.pulling {
+		return nil, false
+	}
+	return append([]string(nil), state.progress...), true
+}
+
+// clearPullProgress forgets a task's pull progress once it has run
+func (e *TaskExecutor) clearPullProgress(taskID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if state, ok := e.tasks[taskID]; ok {
+		state.pulling = false
+		state.progress = nil
+	}
+}
+
+// pullImage pulls a Docker image from registry, giving up after opts.timeout
+func pullImage(ctx context.Context, api imageAPI, imageName string, opts pullOptions) error {
+	pullCtx := ctx
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		pullCtx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	out, err := api.ImagePull(pullCtx, imageName, image.PullOptions{})
+	if err == nil {
+		// Read pull output (required to complete pull)
+		err = readPullProgress(out, opts.progress)
+		out.Close()
+	}
+
+	// Only our own deadline is a pull timeout; the caller's context ending is not
+	if err != nil && ctx.Err() == nil && errors.Is(pullCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s not pulled within %s", ErrImagePullTimeout, imageName, opts.timeout)
+	}
+	return err
+}
+
+// readPullProgress reads a pull's JSON progress stream to the end, reporting each layer every
+// pullProgressStep percent of its download; an error message in the stream fails the pull
+func readPullProgress(r io.Reader, progress func(line string)) error {
+	reported := make(map[string]int) // layer id -> last step reported
+	decoder := json.NewDecoder(r)
+	for {
+		var msg pullMessage
+		if err := decoder.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+		if progress == nil || msg.ID == "" {
+			continue
+		}
+
+		percent := -1
+		switch {
+		case msg.Status == "Downloading" && msg.ProgressDetail.Total > 0:
+			percent = int(msg.ProgressDetail.Current * 100 / msg.ProgressDetail.Total)
+		case msg.Status == "Download complete":
+			percent = 100
+		}
+		if step := percent / pullProgressStep; percent >= 0 && step > reported[msg.ID] {
+			reported[msg.ID] = step
+			progress(fmt.Sprintf("pulling layer %s: %d%% complete", msg.ID, percent))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// SandboxPolicy restricts what a task container's processes may do, whatever image they run
+type SandboxPolicy struct {
+	DropCapabilities bool     // Drop all Linux capabilities; only AddCapabilities are given back
+	AddCapabilities  []string // Capabilities kept despite DropCapabilities (e.g. CHOWN, NET_BIND_SERVICE)
+	NoNewPrivileges  bool     // Stop setuid binaries and file capabilities from gaining privileges
+	SeccompProfile   string   // Path to a seccomp profile (JSON) on the worker ("" = Docker's default profile)
+}
+
+// DefaultSandboxPolicy drops every capability and forbids gaining new privileges
+func DefaultSandboxPolicy() SandboxPolicy {
+	return SandboxPolicy{DropCapabilities: true, NoNewPrivileges: true}
+}
+
+// ParseCapabilities splits a comma-separated capability list (e.g. TASK_CAP_ADD), ignoring spaces and empty entries
+func ParseCapabilities(list string) []string {
+	var capabilities []string
+	for _, capability := range strings.Split(list, ",") {
+		if capability = strings.ToUpper(strings.TrimSpace(capability)); capability != "" {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	return capabilities
+}
+
+// SetSandboxPolicy sets the security options every task container is created with
+func (e *TaskExecutor) SetSandboxPolicy(policy SandboxPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sandbox = policy
+}
+
+// applySandboxPolicy adds the worker's capability and security options to a container's host config
+// Docker expects the seccomp profile's contents rather than its path, so the profile is read here
+func (e *TaskExecutor) applySandboxPolicy(hostConfig *container.HostConfig) error {
+	e.mu.RLock()
+	policy := e.sandbox
+	e.mu.RUnlock()
+
+	if policy.DropCapabilities {
+		hostConfig.CapDrop = []string{"ALL"}
+		for _, capability := range policy.AddCapabilities {
+			if capability = strings.ToUpper(strings.TrimSpace(capability)); capability != "" {
+				hostConfig.CapAdd = append(hostConfig.CapAdd, capability)
+			}
+		}
+	}
+	if policy.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges")
+	}
+	if policy.SeccompProfile != "" {
+		profile, err := os.ReadFile(policy.SeccompProfile)
+		if err != nil {
+			return fmt.Errorf("failed to read seccomp profile %s: %w", policy.SeccompProfile, err)
+		}
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+string(profile))
+	}
+	return nil
+}
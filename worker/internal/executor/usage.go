@@ -0,0 +1,195 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// DefaultUsageSampleInterval is how often a running container's CPU and memory usage is sampled
+const DefaultUsageSampleInterval = 2 * time.Second
+
+// UsageSummary describes a task's sampled resource usage: CPU in cores, memory in GB
+type UsageSummary struct {
+	CPUMin, CPUAvg, CPUP95, CPUMax             float64
+	MemoryMin, MemoryAvg, MemoryP95, MemoryMax float64
+	Samples                                    int
+}
+
+// UsageSample is one live reading of a task container's usage: CPU in cores, memory in GB
+type UsageSample struct {
+	CPUCores      float64
+	MemoryGB      float64
+	MemoryLimitGB float64
+	Timestamp     time.Time
+}
+
+// containerStatsAPI is the subset of the Docker client used to sample container stats
+type containerStatsAPI interface {
+	ContainerStatsOneShot(ctx context.Context, containerID string) (container.StatsResponseReader, error)
+}
+
+// sampleUsage samples a container's CPU and memory every interval until ctx is done, then summarizes them
+// The result is sent on the returned channel (nil if no sample could be taken)
+func sampleUsage(ctx context.Context, api containerStatsAPI, containerID string, interval time.Duration) <-chan *UsageSummary {
+	done := make(chan *UsageSummary, 1)
+
+	go func() {
+		var cpu, memory []float64
+		var prev *container.CPUStats
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				done <- summarizeUsage(cpu, memory)
+				return
+			case <-ticker.C:
+			}
+
+			stats, err := readStats(ctx, api, containerID)
+			if err != nil {
+				continue
+			}
+			// CPU usage is a rate, so the first sample only sets the baseline
+			if prev != nil {
+				if cores, ok := cpuCores(*prev, stats.CPUStats); ok {
+					cpu = append(cpu, cores)
+				}
+			}
+			prev = &stats.CPUStats
+			memory = append(memory, memoryGB(stats.MemoryStats))
+		}
+	}()
+
+	return done
+}
+
+// StreamUsage samples a running task's container every interval until ctx is done or the task stops running
+// CPU usage is a rate, so the first sample is sent after the second reading; the channel is closed when sampling stops
+func (e *TaskExecutor) StreamUsage(ctx context.Context, taskID string, interval time.Duration) (<-chan UsageSample, error) {
+	containerID, exists := e.GetContainerID(taskID)
+	if !exists {
+		return nil, fmt.Errorf("task %s is not running on this worker", taskID)
+	}
+
+	samples := make(chan UsageSample, 1)
+	go func() {
+		defer close(samples)
+		var prev *container.CPUStats
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if current, running := e.GetContainerID(taskID); !running || current != containerID {
+				return
+			}
+			stats, err := readStats(ctx, e.dockerClient, containerID)
+			if err != nil {
+				return
+			}
+			if prev != nil {
+				cores, _ := cpuCores(*prev, stats.CPUStats)
+				sample := UsageSample{
+					CPUCores:      cores,
+					MemoryGB:      memoryGB(stats.MemoryStats),
+					MemoryLimitGB: float64(stats.MemoryStats.Limit) / (1024 * 1024 * 1024),
+					Timestamp:     time.Now(),
+				}
+				select {
+				case samples <- sample:
+				case <-ctx.Done():
+					return
+				}
+			}
+			prev = &stats.CPUStats
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return samples, nil
+}
+
+// readStats takes one stats snapshot of a container
+func readStats(ctx context.Context, api containerStatsAPI, containerID string) (container.StatsResponse, error) {
+	var stats container.StatsResponse
+	reader, err := api.ContainerStatsOneShot(ctx, containerID)
+	if err != nil {
+		return stats, err
+	}
+	defer reader.Body.Close()
+
+	err = json.NewDecoder(reader.Body).Decode(&stats)
+	return stats, err
+}
+
+// cpuCores converts the CPU time used between two stats snapshots into cores in use
+func cpuCores(prev, cur container.CPUStats) (float64, bool) {
+	if cur.CPUUsage.TotalUsage < prev.CPUUsage.TotalUsage || cur.SystemUsage <= prev.SystemUsage {
+		return 0, false
+	}
+	onlineCPUs := float64(cur.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(cur.CPUUsage.PercpuUsage))
+	}
+	if onlineCPUs == 0 {
+		return 0, false
+	}
+
+	containerDelta := float64(cur.CPUUsage.TotalUsage - prev.CPUUsage.TotalUsage)
+	systemDelta := float64(cur.SystemUsage - prev.SystemUsage)
+	return containerDelta / systemDelta * onlineCPUs, true
+}
+
+// memoryGB returns a container's working-set memory in GB (usage minus reclaimable page cache)
+func memoryGB(stats container.MemoryStats) float64 {
+	usage := stats.Usage
+	// cgroup v2 reports inactive_file, v1 reports total_inactive_file
+	for _, key := range []string{"inactive_file", "total_inactive_file"} {
+		if cache, ok := stats.Stats[key]; ok && cache < usage {
+			usage -= cache
+			break
+		}
+	}
+	return float64(usage) / (1024 * 1024 * 1024)
+}
+
+// summarizeUsage computes min/avg/p95/max of the CPU and memory samples (nil if there are none)
+func summarizeUsage(cpu, memory []float64) *UsageSummary {
+	if len(cpu) == 0 && len(memory) == 0 {
+		return nil
+	}
+
+	summary := &UsageSummary{Samples: len(memory)}
+	summary.CPUMin, summary.CPUAvg, summary.CPUP95, summary.CPUMax = distribution(cpu)
+	summary.MemoryMin, summary.MemoryAvg, summary.MemoryP95, summary.MemoryMax = distribution(memory)
+	return summary
+}
+
+// distribution returns the min, mean, 95th percentile (nearest rank) and max of samples
+func distribution(samples []float64) (min, avg, p95, max float64) {
+	if len(samples) == 0 {
+		return 0, 0, 0, 0
+	}
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	total := 0.0
+	for _, v := range sorted {
+		total += v
+	}
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return sorted[0], total / float64(len(sorted)), sorted[rank], sorted[len(sorted)-1]
+}
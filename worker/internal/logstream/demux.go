@@ -0,0 +1,70 @@
+package logstream
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Output streams a log line can come from
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// ReadLogLines reads container logs and emits one labelled LogLine per line, in output order
+// TTY containers produce a single raw stream (labelled stdout); otherwise the logs are
+// Docker's multiplexed format and are split into stdout and stderr with stdcopy
+func ReadLogLines(r io.Reader, tty bool, emit func(LogLine)) error {
+	if tty {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			emit(LogLine{Content: scanner.Text(), Timestamp: time.Now(), Stream: StreamStdout})
+		}
+		return scanner.Err()
+	}
+
+	stdout := &lineWriter{stream: StreamStdout, emit: emit}
+	stderr := &lineWriter{stream: StreamStderr, emit: emit}
+	_, err := stdcopy.StdCopy(stdout, stderr, r)
+	stdout.flush()
+	stderr.flush()
+	return err
+}
+
+// lineWriter splits one demultiplexed stream into lines, holding back a partial last line
+type lineWriter struct {
+	stream string
+	emit   func(LogLine)
+	buf    bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// No newline yet: keep the partial line for the next frame
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		w.emitLine(line)
+	}
+}
+
+// flush emits a trailing line that had no newline
+func (w *lineWriter) flush() {
+	if w.buf.Len() > 0 {
+		w.emitLine(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+func (w *lineWriter) emitLine(line string) {
+	line = strings.TrimSuffix(line, "\n")
+	w.emit(LogLine{Content: strings.TrimSuffix(line, "\r"), Timestamp: time.Now(), Stream: w.stream})
+}
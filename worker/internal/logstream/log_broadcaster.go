@@ -5,19 +5,20 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
-	"github.com/docker/docker/pkg/stdcopy"
 )
 
 // LogLine represents a single log entry
 type LogLine struct {
 	Content   string
 	Timestamp time.Time
+	Stream    string // StreamStdout or StreamStderr
 }
 
 // Subscriber represents a client listening to logs
@@ -41,8 +42,14 @@ type TaskLogBroadcaster struct {
 	cancel        context.CancelFunc
 	isRunning     bool
 	startOnce     sync.Once
+	done          chan struct{} // Closed once streamLogs returns
+	logDir        string        // Directory of the on-disk log tee ("" if disabled)
+	logFile       *TaskLogFile  // Receives every broadcast line while the task runs
 }
 
+// logDrainTimeout is how long Stop lets the stream reach the end of a finished container's logs before cutting it off
+const logDrainTimeout = 2 * time.Second
+
 // NewTaskLogBroadcaster creates a new log broadcaster for a task
 func NewTaskLogBroadcaster(taskID, containerID string, dockerClient *client.Client) *TaskLogBroadcaster {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -56,6 +63,7 @@ func NewTaskLogBroadcaster(taskID, containerID string, dockerClient *client.Clie
 		ctx:           ctx,
 		cancel:        cancel,
 		isRunning:     false,
+		done:          make(chan struct{}),
 	}
 }
 
@@ -77,14 +85,21 @@ func (b *TaskLogBroadcaster) Subscribe(subscriberID string, subscriberCtx contex
 	b.subscribers[subscriberID] = subscriber
 
 	// Start log streaming if not already started
-	b.startOnce.Do(func() {
-		go b.streamLogs()
-	})
+	b.start()
+
+	// The on-disk tee holds every line so far, including any a disconnected subscriber missed;
+	// reading it under the lock keeps it consistent with the live lines that follow
+	recent := b.recentLogs
+	if sendRecent && b.logFile != nil {
+		if stored, err := ReadLogFile(b.logDir, b.taskID); err == nil {
+			recent = stored
+		}
+	}
 
 	// Send recent logs to new subscriber if requested
-	if sendRecent && len(b.recentLogs) > 0 {
+	if sendRecent && len(recent) > 0 {
 		go func() {
-			for _, logLine := range b.recentLogs {
+			for _, logLine := range recent {
 				select {
 				case subChan <- logLine:
 				case <-subscriberCtx.Done():
@@ -100,6 +115,27 @@ func (b *TaskLogBroadcaster) Subscribe(subscriberID string, subscriberCtx contex
 	return subChan, nil
 }
 
+// start begins reading logs from Docker (only the first call has any effect)
+func (b *TaskLogBroadcaster) start() {
+	b.startOnce.Do(func() {
+		go b.streamLogs()
+	})
+}
+
+// teeTo writes every broadcast line to the task's log file under dir
+func (b *TaskLogBroadcaster) teeTo(dir string) error {
+	logFile, err := OpenTaskLogFile(dir, b.taskID)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.logDir = dir
+	b.logFile = logFile
+	return nil
+}
+
 // Unsubscribe removes a subscriber
 func (b *TaskLogBroadcaster) Unsubscribe(subscriberID string) {
 	b.mu.Lock()
@@ -116,6 +152,8 @@ func (b *TaskLogBroadcaster) Unsubscribe(subscriberID string) {
 
 // streamLogs is the main goroutine that reads from Docker and broadcasts
 func (b *TaskLogBroadcaster) streamLogs() {
+	defer close(b.done)
+
 	b.mu.Lock()
 	b.isRunning = true
 	b.mu.Unlock()
@@ -175,6 +213,7 @@ func (b *TaskLogBroadcaster) streamRawLogs(logReader io.ReadCloser) {
 			logLine := LogLine{
 				Content:   strings.TrimRight(line, "\n"),
 				Timestamp: time.Now(),
+				Stream:    StreamStdout, // A TTY merges stdout and stderr
 			}
 			b.broadcast(logLine)
 		}
@@ -190,49 +229,11 @@ func (b *TaskLogBroadcaster) streamRawLogs(logReader io.ReadCloser) {
 }
 
 // streamMultiplexedLogs handles logs from non-TTY containers (multiplexed stdout/stderr)
+// Lines are broadcast in output order, labelled with the stream they came from
 func (b *TaskLogBroadcaster) streamMultiplexedLogs(logReader io.ReadCloser) {
-	// Create pipes for demultiplexing
-	stdoutReader, stdoutWriter := io.Pipe()
-	stderrReader, stderrWriter := io.Pipe()
-
-	// Demultiplex in background
-	go func() {
-		_, _ = stdcopy.StdCopy(stdoutWriter, stderrWriter, logReader)
-		stdoutWriter.Close()
-		stderrWriter.Close()
-	}()
-
-	// Read from both stdout and stderr
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	// Read stdout
-	go func() {
-		defer wg.Done()
-		scanner := bufio.NewScanner(stdoutReader)
-		for scanner.Scan() {
-			logLine := LogLine{
-				Content:   scanner.Text(),
-				Timestamp: time.Now(),
-			}
-			b.broadcast(logLine)
-		}
-	}()
-
-	// Read stderr
-	go func() {
-		defer wg.Done()
-		scanner := bufio.NewScanner(stderrReader)
-		for scanner.Scan() {
-			logLine := LogLine{
-				Content:   scanner.Text(),
-				Timestamp: time.Now(),
-			}
-			b.broadcast(logLine)
-		}
-	}()
-
-	wg.Wait()
+	if err := ReadLogLines(logReader, false, b.broadcast); err != nil && b.ctx.Err() == nil {
+		b.broadcastError(fmt.Errorf("error reading logs: %w", err))
+	}
 }
 
 // broadcast sends a log line to all subscribers and stores in buffer
@@ -246,6 +247,12 @@ func (b *TaskLogBroadcaster) broadcast(logLine LogLine) {
 	}
 	b.recentLogs = append(b.recentLogs, logLine)
 
+	if b.logFile != nil {
+		if err := b.logFile.Write(logLine); err != nil {
+			log.Printf("[Task %s] Warning: failed to write log file: %v", b.taskID, err)
+		}
+	}
+
 	// Get current subscribers
 	subscribers := make([]*Subscriber, 0, len(b.subscribers))
 	for _, sub := range b.subscribers {
@@ -272,13 +279,27 @@ func (b *TaskLogBroadcaster) broadcastError(err error) {
 	errorLine := LogLine{
 		Content:   fmt.Sprintf("ERROR: %v", err),
 		Timestamp: time.Now(),
+		Stream:    StreamStderr,
 	}
 	b.broadcast(errorLine)
 }
 
 // Stop stops the broadcaster and cleans up
+// The stream goroutine is given a moment to tee the container's last lines and always exits before the log file is closed
 func (b *TaskLogBroadcaster) Stop() {
+	// A broadcaster that never started streaming never will now
+	started := true
+	b.startOnce.Do(func() { started = false })
+	if started {
+		select {
+		case <-b.done:
+		case <-time.After(logDrainTimeout):
+		}
+	}
 	b.cancel()
+	if started {
+		<-b.done
+	}
 
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -287,6 +308,13 @@ func (b *TaskLogBroadcaster) Stop() {
 		close(sub.Channel)
 	}
 	b.subscribers = make(map[string]*Subscriber)
+
+	if b.logFile != nil {
+		if err := b.logFile.Close(); err != nil {
+			log.Printf("[Task %s] Warning: failed to close log file: %v", b.taskID, err)
+		}
+		b.logFile = nil
+	}
 }
 
 // GetSubscriberCount returns the number of active subscribers
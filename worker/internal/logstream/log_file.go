@@ -0,0 +1,95 @@
+package logstream
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TaskLogFile tees a task's log lines to a file on the worker's disk
+// Lines are kept even when no master is subscribed, so a master that disconnects mid-stream
+// can recover what it missed once it reconnects
+type TaskLogFile struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// LogFilePath returns where a task's log file is kept under dir
+func LogFilePath(dir, taskID string) string {
+	return filepath.Join(dir, filepath.Base(taskID)+".log")
+}
+
+// OpenTaskLogFile opens (or creates) a task's log file for appending
+func OpenTaskLogFile(dir, taskID string) (*TaskLogFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(LogFilePath(dir, taskID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+	return &TaskLogFile{file: file}, nil
+}
+
+// Write appends one log line as "<RFC3339Nano timestamp> <stream> <content>"
+func (f *TaskLogFile) Write(line LogLine) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stream := line.Stream
+	if stream == "" {
+		stream = StreamStdout
+	}
+	_, err := fmt.Fprintf(f.file, "%s %s %s\n", line.Timestamp.Format(time.RFC3339Nano), stream, line.Content)
+	return err
+}
+
+// Close closes the log file; the file itself stays on disk
+func (f *TaskLogFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// ReadLogFile returns the log lines stored for a task, in the order they were written
+func ReadLogFile(dir, taskID string) ([]LogLine, error) {
+	file, err := os.Open(LogFilePath(dir, taskID))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []LogLine
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), " ", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+		if err != nil {
+			continue
+		}
+		line := LogLine{Timestamp: timestamp, Stream: parts[1]}
+		if len(parts) == 3 {
+			line.Content = parts[2]
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// RemoveLogFile deletes a task's log file once its logs are safely with the master
+func RemoveLogFile(dir, taskID string) error {
+	err := os.Remove(LogFilePath(dir, taskID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package logstream
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestLogsWrittenDuringDisconnectAreRecoverable tests that lines broadcast while no master is subscribed are kept in the task's log file
+func TestLogsWrittenDuringDisconnectAreRecoverable(t *testing.T) {
+	dir := t.TempDir()
+
+	b := NewTaskLogBroadcaster("task-1", "container-1", nil)
+	// Mark streaming as started (and finished) so subscribing does not read from Docker; lines are broadcast by hand
+	b.startOnce.Do(func() { close(b.done) })
+	// Keep the in-memory buffer tiny so only the file can hold the missed lines
+	b.maxRecentLogs = 1
+	if err := b.teeTo(dir); err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	live, err := b.Subscribe("master", ctx, false)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	b.broadcast(LogLine{Content: "epoch 1", Timestamp: time.Now(), Stream: StreamStdout})
+	if line := <-live; line.Content != "epoch 1" {
+		t.Fatalf("Expected live line %q, got %q", "epoch 1", line.Content)
+	}
+
+	// The master disconnects while the task keeps logging
+	b.Unsubscribe("master")
+	b.broadcast(LogLine{Content: "epoch 2", Timestamp: time.Now(), Stream: StreamStdout})
+	b.broadcast(LogLine{Content: "warning: loss is nan", Timestamp: time.Now(), Stream: StreamStderr})
+
+	want := []LogLine{
+		{Content: "epoch 1", Stream: StreamStdout},
+		{Content: "epoch 2", Stream: StreamStdout},
+		{Content: "warning: loss is nan", Stream: StreamStderr},
+	}
+
+	// On reconnect the master is replayed the on-disk portion before the live tail
+	replay, err := b.Subscribe("master-reconnected", ctx, true)
+	if err != nil {
+		t.Fatalf("Failed to resubscribe: %v", err)
+	}
+	for i, expected := range want {
+		select {
+		case line := <-replay:
+			if line.Content != expected.Content || line.Stream != expected.Stream {
+				t.Errorf("Replayed line %d: expected %s %q, got %s %q", i, expected.Stream, expected.Content, line.Stream, line.Content)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for replayed line %d", i)
+		}
+	}
+
+	// The file outlives the broadcaster once the task has finished
+	b.Stop()
+	stored, err := ReadLogFile(dir, "task-1")
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if len(stored) != len(want) {
+		t.Fatalf("Expected %d stored lines, got %d", len(want), len(stored))
+	}
+	for i, expected := range want {
+		if stored[i].Content != expected.Content || stored[i].Stream != expected.Stream {
+			t.Errorf("Stored line %d: expected %s %q, got %s %q", i, expected.Stream, expected.Content, stored[i].Stream, stored[i].Content)
+		}
+		if stored[i].Timestamp.IsZero() {
+			t.Errorf("Stored line %d has no timestamp", i)
+		}
+	}
+
+	if err := RemoveLogFile(dir, "task-1"); err != nil {
+		t.Fatalf("Failed to remove log file: %v", err)
+	}
+	if _, err := ReadLogFile(dir, "task-1"); err == nil {
+		t.Error("Expected the log file to be gone after removal")
+	}
+}
+
+// TestStopClosesLogFileAfterStreamDrains tests that stopping a task waits for its stream goroutine to tee the last lines before closing the log file
+func TestStopClosesLogFileAfterStreamDrains(t *testing.T) {
+	dir := t.TempDir()
+
+	b := NewTaskLogBroadcaster("task-1", "container-1", nil)
+	if err := b.teeTo(dir); err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	// A stand-in stream that is still reading the exited container's last line when the task ends
+	b.startOnce.Do(func() {
+		go func() {
+			defer close(b.done)
+			time.Sleep(50 * time.Millisecond)
+			b.broadcast(LogLine{Content: "done", Timestamp: time.Now(), Stream: StreamStdout})
+		}()
+	})
+
+	b.Stop()
+	select {
+	case <-b.done:
+	default:
+		t.Fatal("Expected the stream goroutine to have exited once Stop returned")
+	}
+	if b.logFile != nil {
+		t.Error("Expected the log file to be closed")
+	}
+	stored, err := ReadLogFile(dir, "task-1")
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Content != "done" {
+		t.Errorf("Expected the last line to be teed before the file was closed, got %+v", stored)
+	}
+}
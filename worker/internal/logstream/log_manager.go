@@ -13,6 +13,7 @@ import (
 type LogStreamManager struct {
 	broadcasters map[string]*TaskLogBroadcaster // taskID -> broadcaster
 	dockerClient *client.Client
+	logDir       string // Where task logs are teed to disk ("" disables the tee)
 	mu           sync.RWMutex
 }
 
@@ -24,7 +25,15 @@ func NewLogStreamManager(dockerClient *client.Client) *LogStreamManager {
 	}
 }
 
+// SetLogDir tees every task's logs to a file under dir, so they survive a master disconnect
+func (m *LogStreamManager) SetLogDir(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logDir = dir
+}
+
 // StartTask starts log broadcasting for a new task
+// With a log directory set, logs are read and teed to disk right away rather than on the first subscriber
 func (m *LogStreamManager) StartTask(taskID, containerID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -38,9 +47,40 @@ func (m *LogStreamManager) StartTask(taskID, containerID string) error {
 	broadcaster := NewTaskLogBroadcaster(taskID, containerID, m.dockerClient)
 	m.broadcasters[taskID] = broadcaster
 
+	if m.logDir != "" {
+		if err := broadcaster.teeTo(m.logDir); err != nil {
+			return fmt.Errorf("tee logs for task %s: %w", taskID, err)
+		}
+		broadcaster.start()
+	}
+
 	return nil
 }
 
+// StoredLogs returns the log lines teed to disk for a task, including those of a task that has finished
+func (m *LogStreamManager) StoredLogs(taskID string) ([]LogLine, error) {
+	m.mu.RLock()
+	dir := m.logDir
+	m.mu.RUnlock()
+
+	if dir == "" {
+		return nil, fmt.Errorf("log tee is disabled")
+	}
+	return ReadLogFile(dir, taskID)
+}
+
+// RemoveStoredLogs deletes a task's on-disk logs once the master has its result
+func (m *LogStreamManager) RemoveStoredLogs(taskID string) error {
+	m.mu.RLock()
+	dir := m.logDir
+	m.mu.RUnlock()
+
+	if dir == "" {
+		return nil
+	}
+	return RemoveLogFile(dir, taskID)
+}
+
 // Subscribe subscribes to logs for a task
 // Returns a channel that receives log lines
 func (m *LogStreamManager) Subscribe(ctx context.Context, taskID string, sendRecent bool) (<-chan LogLine, error) {
@@ -69,14 +109,16 @@ func (m *LogStreamManager) Unsubscribe(taskID, subscriberID string) {
 	}
 }
 
-// StopTask stops log broadcasting for a task and cleans up
+// StopTask stops log broadcasting for a task and cleans up, closing its log file
+// The broadcaster is stopped outside the lock since it waits for its stream to drain
 func (m *LogStreamManager) StopTask(taskID string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	broadcaster, exists := m.broadcasters[taskID]
+	delete(m.broadcasters, taskID)
+	m.mu.Unlock()
 
-	if broadcaster, exists := m.broadcasters[taskID]; exists {
+	if exists {
 		broadcaster.Stop()
-		delete(m.broadcasters, taskID)
 	}
 }
 
@@ -96,12 +138,13 @@ func (m *LogStreamManager) GetTaskInfo(taskID string) (exists bool, subscriberCo
 // StopAll stops all broadcasters and cleans up
 func (m *LogStreamManager) StopAll() {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	broadcasters := m.broadcasters
+	m.broadcasters = make(map[string]*TaskLogBroadcaster)
+	m.mu.Unlock()
 
-	for _, broadcaster := range m.broadcasters {
+	for _, broadcaster := range broadcasters {
 		broadcaster.Stop()
 	}
-	m.broadcasters = make(map[string]*TaskLogBroadcaster)
 }
 
 // GetActiveTaskIDs returns a list of all task IDs that have active log broadcasters
@@ -0,0 +1,203 @@
+package resultcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	outputSubdir = "output"
+	logsFile     = "logs.txt"
+)
+
+// DefaultMaxEntries is the number of results kept when no limit is configured
+const DefaultMaxEntries = 256
+
+// Cache stores results of deterministic tasks on local disk, keyed by a hash of their inputs
+// Once it holds maxEntries results, storing another evicts the least recently used one
+type Cache struct {
+	baseDir    string
+	maxEntries int
+	mu         sync.Mutex
+}
+
+// Entry is a cached task result
+type Entry struct {
+	Logs        string
+	OutputDir   string   // Directory holding the cached output files
+	OutputFiles []string // Output files relative to OutputDir
+}
+
+// New creates a result cache rooted at baseDir that keeps at most maxEntries results
+func New(baseDir string, maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Cache{baseDir: baseDir, maxEntries: maxEntries}
+}
+
+// Key computes the cache key for a task from its image, command and any extra inputs
+// The image must be an immutable reference (a digest or image ID), or a re-pushed tag would serve stale results
+func Key(dockerImage, command string, inputs ...string) string {
+	h := sha256.New()
+	for _, part := range append([]string{dockerImage, command}, inputs...) {
+		// Length-prefix each part so ("ab", "c") and ("a", "bc") hash differently
+		fmt.Fprintf(h, "%d:%s;", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Lookup returns the cached result for key, if one exists
+func (c *Cache) Lookup(key string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entryDir := filepath.Join(c.baseDir, key)
+
+	logs, err := os.ReadFile(filepath.Join(entryDir, logsFile))
+	if err != nil {
+		return nil, false
+	}
+
+	outputDir := filepath.Join(entryDir, outputSubdir)
+	files, err := listFiles(outputDir)
+	if err != nil {
+		return nil, false
+	}
+
+	// The entry directory's modification time records its last use for eviction
+	now := time.Now()
+	os.Chtimes(entryDir, now, now)
+
+	return &Entry{
+		Logs:        string(logs),
+		OutputDir:   outputDir,
+		OutputFiles: files,
+	}, true
+}
+
+// Store saves a successful result under key, copying output files from sourceDir
+// The entry is written to a temporary directory first so a crash never leaves a partial entry
+func (c *Cache) Store(key, logs, sourceDir string, outputFiles []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.baseDir, 0700); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp(c.baseDir, key+".tmp-")
+	if err != nil {
+		return fmt.Errorf("create cache entry: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, relPath := range outputFiles {
+		dst := filepath.Join(tmpDir, outputSubdir, relPath)
+		if err := copyFile(filepath.Join(sourceDir, relPath), dst); err != nil {
+			return fmt.Errorf("copy output file %s: %w", relPath, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, outputSubdir), 0700); err != nil {
+		return fmt.Errorf("create cache output directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, logsFile), []byte(logs), 0600); err != nil {
+		return fmt.Errorf("write cached logs: %w", err)
+	}
+
+	entryDir := filepath.Join(c.baseDir, key)
+	if err := os.RemoveAll(entryDir); err != nil {
+		return fmt.Errorf("replace cache entry: %w", err)
+	}
+	if err := os.Rename(tmpDir, entryDir); err != nil {
+		return fmt.Errorf("commit cache entry: %w", err)
+	}
+	return c.evict()
+}
+
+// evict removes the least recently used entries beyond maxEntries
+// Caller must hold c.mu
+func (c *Cache) evict() error {
+	dirEntries, err := os.ReadDir(c.baseDir)
+	if err != nil {
+		return fmt.Errorf("list cache entries: %w", err)
+	}
+
+	type cacheEntry struct {
+		name    string
+		lastUse time.Time
+	}
+	var entries []cacheEntry
+	for _, dirEntry := range dirEntries {
+		// Skip files and entries still being written
+		if !dirEntry.IsDir() || strings.Contains(dirEntry.Name(), ".tmp-") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cacheEntry{name: dirEntry.Name(), lastUse: info.ModTime()})
+	}
+	if len(entries) <= c.maxEntries {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastUse.Before(entries[j].lastUse) })
+	for _, entry := range entries[:len(entries)-c.maxEntries] {
+		if err := os.RemoveAll(filepath.Join(c.baseDir, entry.name)); err != nil {
+			return fmt.Errorf("evict cache entry %s: %w", entry.name, err)
+		}
+	}
+	return nil
+}
+
+// copyFile copies a single file, creating parent directories as needed
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// listFiles returns all regular files under dir, relative to dir
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, relPath)
+		}
+		return nil
+	})
+	return files, err
+}
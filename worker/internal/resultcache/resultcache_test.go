@@ -0,0 +1,43 @@
+package resultcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestStoreEvictsLeastRecentlyUsed tests that storing past the entry limit evicts the entry used longest ago
+func TestStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New(t.TempDir(), 2)
+	source := t.TempDir()
+
+	// Space out last uses so they do not depend on the filesystem's timestamp resolution
+	use := func(key string, at time.Time) {
+		if err := os.Chtimes(filepath.Join(c.baseDir, key), at, at); err != nil {
+			t.Fatalf("Failed to set last use of %s: %v", key, err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+
+	for i, key := range []string{"a", "b"} {
+		if err := c.Store(key, "logs "+key, source, nil); err != nil {
+			t.Fatalf("Failed to store %s: %v", key, err)
+		}
+		use(key, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	// Looking a up makes b the least recently used entry
+	if _, ok := c.Lookup("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	if err := c.Store("c", "logs c", source, nil); err != nil {
+		t.Fatalf("Failed to store c: %v", err)
+	}
+
+	for key, expected := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Lookup(key); ok != expected {
+			t.Errorf("Expected %s cached=%v, got %v", key, expected, ok)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"log"
+
+	pb "worker/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewGRPCServer creates the worker's gRPC server with the MasterWorker service registered
+// When enableReflection is set the reflection service is also registered for grpcurl debugging
+// Keepalive pings keep idle master connections from being dropped by NAT or firewalls
+// creds secures the listener with TLS; nil serves plaintext
+func NewGRPCServer(ws *WorkerServer, enableReflection bool, keepalive KeepaliveConfig, creds credentials.TransportCredentials) *grpc.Server {
+	opts := keepalive.ServerOptions()
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterMasterWorkerServer(grpcServer, ws)
+
+	if enableReflection {
+		reflection.Register(grpcServer)
+		log.Println("✓ gRPC reflection enabled (GRPC_REFLECTION=true)")
+	}
+
+	return grpcServer
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// minClientPingInterval is the most frequent client keepalive ping the server accepts
+// gRPC clients never ping more often than every 10s, so any master keepalive setting is allowed
+const minClientPingInterval = 5 * time.Second
+
+// KeepaliveConfig tunes gRPC keepalive pings so idle master connections are not silently dropped by NAT or firewalls
+type KeepaliveConfig struct {
+	Time                time.Duration // Ping the peer after this long without activity
+	Timeout             time.Duration // Close the connection if a ping is not acknowledged within this
+	PermitWithoutStream bool          // Keep pinging while no RPC is in flight
+}
+
+// DefaultKeepaliveConfig returns keepalive settings that stay below common NAT idle timeouts
+func DefaultKeepaliveConfig() KeepaliveConfig {
+	return KeepaliveConfig{
+		Time:                30 * time.Second,
+		Timeout:             10 * time.Second,
+		PermitWithoutStream: true,
+	}
+}
+
+// ServerOptions returns the gRPC server options applying this keepalive configuration
+func (k KeepaliveConfig) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: k.Time, Timeout: k.Timeout}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             minClientPingInterval,
+			PermitWithoutStream: k.PermitWithoutStream,
+		}),
+	}
+}
+
+// DialOptions returns the gRPC dial options applying this keepalive configuration
+func (k KeepaliveConfig) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		Time:                k.Time,
+		Timeout:             k.Timeout,
+		PermitWithoutStream: k.PermitWithoutStream,
+	})}
+}
+
+// SetKeepalive sets the keepalive used on long-lived connections to the master (file uploads)
+func (s *WorkerServer) SetKeepalive(k KeepaliveConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keepalive = k
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"worker/internal/telemetry"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TLSConfig holds the PEM files used to serve gRPC over TLS and to verify the master
+type TLSConfig struct {
+	CertFile string // Certificate presented by the worker
+	KeyFile  string // Private key for CertFile
+	CAFile   string // CA bundle used to verify the master certificate when dialing ("" = system roots)
+	// RequireClientCert turns on mutual TLS for gRPC: the master must present a certificate signed by CAFile
+	RequireClientCert bool
+}
+
+// Enabled reports whether a certificate and key are configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// ServerTLS loads the certificate and key into a server-side TLS configuration
+func (c TLSConfig) ServerTLS() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// ClientTLS returns the TLS configuration for dials to the master, trusting CAFile when set
+// The certificate is presented too, so peers requiring client certificates can authenticate this process
+func (c TLSConfig) ClientTLS() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		if config.RootCAs, err = c.caPool(); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// caPool reads CAFile into a certificate pool
+func (c TLSConfig) caPool() (*x509.CertPool, error) {
+	pem, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read TLS CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+	}
+	return pool, nil
+}
+
+// ServerCredentials returns the gRPC server credentials (nil when TLS is not configured, meaning plaintext)
+// With RequireClientCert, peers without a certificate signed by CAFile are refused during the handshake
+func (c TLSConfig) ServerCredentials() (credentials.TransportCredentials, error) {
+	if !c.Enabled() {
+		if c.RequireClientCert {
+			return nil, fmt.Errorf("client certificates require TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	config, err := c.ServerTLS()
+	if err != nil {
+		return nil, err
+	}
+	if c.RequireClientCert {
+		if c.CAFile == "" {
+			return nil, fmt.Errorf("client certificates require TLS_CA_FILE")
+		}
+		if config.ClientCAs, err = c.caPool(); err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// ClientCredentials returns the credentials for dials to the master (plaintext when TLS is not configured)
+func (c TLSConfig) ClientCredentials() (credentials.TransportCredentials, error) {
+	if !c.Enabled() {
+		return insecure.NewCredentials(), nil
+	}
+	config, err := c.ClientTLS()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// SetTransportCredentials sets the credentials used for every dial to the master, including heartbeats (call before serving)
+func (s *WorkerServer) SetTransportCredentials(creds credentials.TransportCredentials) {
+	s.transportCreds = creds
+	if s.monitor != nil {
+		s.monitor.SetTransportCredentials(creds)
+	}
+}
+
+// dialCredentials returns the dial option carrying the master transport credentials (plaintext when unset)
+func (s *WorkerServer) dialCredentials() grpc.DialOption {
+	return telemetry.DialCredentials(s.transportCreds)
+}
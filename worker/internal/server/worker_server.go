@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
@@ -10,14 +12,25 @@ import (
 	"time"
 
 	"worker/internal/executor"
+	"worker/internal/logstream"
 	"worker/internal/system"
 	"worker/internal/telemetry"
 	pb "worker/proto"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 )
 
+// joinTokenMetadataKey is the gRPC metadata key the master reads the cluster join token from
+const joinTokenMetadataKey = "x-join-token"
+
+// DefaultMinFreeDiskGB is the free space (GB) required under the output directory to accept a task
+const DefaultMinFreeDiskGB = 1.0
+
+// pullProgressPollInterval is how often a log stream checks a pulling task for new progress
+const pullProgressPollInterval = 500 * time.Millisecond
+
 // WorkerServer handles incoming gRPC requests from master
 type WorkerServer struct {
 	pb.UnimplementedMasterWorkerServer
@@ -27,7 +40,30 @@ type WorkerServer struct {
 	monitor          *telemetry.Monitor
 	masterAddr       string
 	masterRegistered bool
+	zone             string // Optional topology label (rack, AZ) reported at registration
 	mu               sync.RWMutex
+
+	// Disk-space guard: tasks are refused when the output disk has less than minFreeDiskGB free
+	outputDir     string
+	minFreeDiskGB float64
+	freeDiskFn    func(path string) (float64, error) // Free GB at path (replaceable in tests)
+
+	runningTasksFn func() []string // IDs of tasks still running (replaceable in tests)
+	// Stops a task's container, honouring its stop grace (replaceable in tests)
+	stopTaskFn func(taskID string) error
+	// Tasks being stopped at shutdown to be released to the master; true once the task's own result was withheld
+	handingBack map[string]bool
+	// Live usage samples of a running task's container (replaceable in tests)
+	taskUsageFn func(ctx context.Context, taskID string, interval time.Duration) (<-chan executor.UsageSample, error)
+
+	// Auto-registration: a join token sent to the master with this worker's advertised address
+	joinToken     string
+	advertiseAddr string
+
+	// Keepalive pings on long-lived connections to the master
+	keepalive KeepaliveConfig
+	// Credentials for dials to the master (nil = plaintext)
+	transportCreds credentials.TransportCredentials
 }
 
 // NewWorkerServer creates a new worker server instance
@@ -37,6 +73,14 @@ func NewWorkerServer(workerID string, monitor *telemetry.Monitor) (*WorkerServer
 		return nil, fmt.Errorf("failed to create executor: %w", err)
 	}
 
+	// Heartbeats report a service task ready once its health check passes, and where its ports were published
+	if monitor != nil {
+		exec.SetReadinessHandler(monitor.SetTaskStatus)
+		exec.SetPortsHandler(func(taskID string, ports []executor.PublishedPort) {
+			monitor.SetTaskPorts(taskID, portMappings(ports))
+		})
+	}
+
 	return &WorkerServer{
 		workerID:         workerID,
 		executor:         exec,
@@ -44,9 +88,102 @@ func NewWorkerServer(workerID string, monitor *telemetry.Monitor) (*WorkerServer
 		masterAddr:       "", // Will be set when master registers
 		masterRegistered: false,
 		mu:               sync.RWMutex{},
+		outputDir:        executor.OutputBaseDir(),
+		minFreeDiskGB:    DefaultMinFreeDiskGB,
+		freeDiskFn:       system.GetAvailableStorageAt,
+		runningTasksFn:   exec.GetRunningTasks,
+		stopTaskFn: func(taskID string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), exec.StopTimeout(taskID))
+			defer cancel()
+			return exec.CancelTask(ctx, taskID)
+		},
+		taskUsageFn: exec.StreamUsage,
+		keepalive:   DefaultKeepaliveConfig(),
 	}, nil
 }
 
+// SetMinFreeDisk sets the free space (GB) required on the output disk before a task is accepted (0 disables the guard)
+func (s *WorkerServer) SetMinFreeDisk(gb float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minFreeDiskGB = gb
+}
+
+// SetZone sets the topology label (rack, AZ) reported to the master at registration
+func (s *WorkerServer) SetZone(zone string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zone = zone
+}
+
+// SetSeparateLogStreams runs task containers without a TTY so streamed log lines carry their stdout/stderr label
+func (s *WorkerServer) SetSeparateLogStreams(separate bool) {
+	s.executor.SetSeparateLogStreams(separate)
+}
+
+// SetPinDigests fails tasks whose image resolves to the mutable "latest" tag
+func (s *WorkerServer) SetPinDigests(pin bool) {
+	s.executor.SetPinDigests(pin)
+}
+
+// SetReadOnlyRootFS runs every task with a read-only root filesystem, even when the task did not ask for one
+func (s *WorkerServer) SetReadOnlyRootFS(readOnly bool) {
+	s.executor.SetReadOnlyRootFS(readOnly)
+}
+
+// SetSandboxPolicy sets the capabilities and security options task containers run with
+func (s *WorkerServer) SetSandboxPolicy(policy executor.SandboxPolicy) {
+	s.executor.SetSandboxPolicy(policy)
+}
+
+// SetImagePullTimeout fails tasks whose image is not pulled within timeout with image_pull_timeout
+func (s *WorkerServer) SetImagePullTimeout(timeout time.Duration) {
+	s.executor.SetImagePullTimeout(timeout)
+}
+
+// SetJoinToken sets the cluster join token (and the address the master should reach this worker at)
+// presented when registering, so a master with AUTO_REGISTER enabled accepts the worker without a manual register
+func (s *WorkerServer) SetJoinToken(token, advertiseAddr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.joinToken = token
+	s.advertiseAddr = advertiseAddr
+}
+
+// JoinMaster registers with a known master directly instead of waiting for the master to register first
+func (s *WorkerServer) JoinMaster(masterAddr string) {
+	s.mu.Lock()
+	s.masterAddr = masterAddr
+	s.masterRegistered = true
+	s.mu.Unlock()
+
+	s.monitor.SetMasterAddress(masterAddr)
+	go s.registerWithMaster()
+}
+
+// checkDiskSpace returns an error when the output disk is below the free-space threshold
+// A failed free-space lookup is logged and does not block the task
+func (s *WorkerServer) checkDiskSpace() error {
+	s.mu.RLock()
+	minFree := s.minFreeDiskGB
+	s.mu.RUnlock()
+
+	if minFree <= 0 || s.freeDiskFn == nil {
+		return nil
+	}
+
+	free, err := s.freeDiskFn(s.outputDir)
+	if err != nil {
+		log.Printf("Warning: Failed to check free space under %s: %v", s.outputDir, err)
+		return nil
+	}
+
+	if free < minFree {
+		return fmt.Errorf("only %.2f GB free under %s (need at least %.2f GB)", free, s.outputDir, minFree)
+	}
+	return nil
+}
+
 // MasterRegister handles master registration from master node
 func (s *WorkerServer) MasterRegister(ctx context.Context, masterInfo *pb.MasterInfo) (*pb.RegisterAck, error) {
 	log.Printf("Master registration request from: %s (%s)", masterInfo.MasterId, masterInfo.MasterAddress)
@@ -72,6 +209,9 @@ func (s *WorkerServer) MasterRegister(ctx context.Context, masterInfo *pb.Master
 func (s *WorkerServer) registerWithMaster() {
 	s.mu.RLock()
 	masterAddr := s.masterAddr
+	zone := s.zone
+	joinToken := s.joinToken
+	advertiseAddr := s.advertiseAddr
 	s.mu.RUnlock()
 
 	if masterAddr == "" {
@@ -85,7 +225,7 @@ func (s *WorkerServer) registerWithMaster() {
 	defer cancel()
 
 	conn, err := grpc.DialContext(ctx, masterAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		s.dialCredentials(),
 		grpc.WithBlock())
 	if err != nil {
 		log.Printf("Failed to connect to master for registration: %v", err)
@@ -121,6 +261,13 @@ func (s *WorkerServer) registerWithMaster() {
 		TotalMemory:  resources.TotalMemory,
 		TotalStorage: resources.TotalStorage,
 		TotalGpu:     resources.TotalGPU,
+		Zone:         zone,
+	}
+
+	// With a join token the worker advertises its own address so the master can auto-register it
+	if joinToken != "" {
+		workerInfo.WorkerIp = advertiseAddr
+		ctx = metadata.AppendToOutgoingContext(ctx, joinTokenMetadataKey, joinToken)
 	}
 
 	ack, err := client.RegisterWorker(ctx, workerInfo)
@@ -144,8 +291,19 @@ func (s *WorkerServer) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskA
 
 	if !registered {
 		return &pb.TaskAck{
-			Success: false,
-			Message: "Master not registered yet",
+			Success:   false,
+			Message:   "Master not registered yet",
+			ErrorCode: pb.ErrorCode_NOT_AUTHORIZED,
+		}, nil
+	}
+
+	// Refuse before doing any work so the master can place the task elsewhere
+	if err := s.checkDiskSpace(); err != nil {
+		log.Printf("❌ Rejecting task %s: output disk is nearly full: %v", task.TaskId, err)
+		return &pb.TaskAck{
+			Success:   false,
+			Message:   fmt.Sprintf("Insufficient disk space on worker %s: %v", s.workerID, err),
+			ErrorCode: pb.ErrorCode_INSUFFICIENT_STORAGE,
 		}, nil
 	}
 
@@ -164,6 +322,18 @@ func (s *WorkerServer) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskA
 	log.Printf("    • Memory:        %.2f GB", task.ReqMemory)
 	log.Printf("    • Storage:       %.2f GB", task.ReqStorage)
 	log.Printf("    • GPU Cores:     %.2f cores", task.ReqGpu)
+	if task.PinCpus {
+		log.Printf("    • CPU Pinning:   dedicated cores requested")
+	}
+	if task.Cacheable {
+		log.Printf("    • Cacheable:     result may be served from cache")
+	}
+	if task.ReadOnlyRootfs {
+		log.Printf("    • Root FS:       read-only (/output writable)")
+	}
+	if task.HealthCheck != "" {
+		log.Printf("    • Health Check:  %s", task.HealthCheck)
+	}
 	log.Println("═══════════════════════════════════════════════════════")
 	log.Printf("  ✓ Task accepted - Starting execution...")
 	log.Println("═══════════════════════════════════════════════════════")
@@ -181,17 +351,57 @@ func (s *WorkerServer) AssignTask(ctx context.Context, task *pb.Task) (*pb.TaskA
 	}, nil
 }
 
+// portMappings converts a task's published ports for reporting to the master
+func portMappings(ports []executor.PublishedPort) []*pb.PortMapping {
+	var mappings []*pb.PortMapping
+	for _, p := range ports {
+		mappings = append(mappings, &pb.PortMapping{
+			ContainerPort: p.ContainerPort,
+			HostIp:        p.HostIP,
+			HostPort:      int32(p.HostPort),
+		})
+	}
+	return mappings
+}
+
 // executeTask runs the task and reports result
 func (s *WorkerServer) executeTask(task *pb.Task) {
 	// Create a new context for task execution (not tied to RPC timeout)
 	ctx := context.Background()
 
 	// Execute the task with resource constraints
-	result := s.executor.ExecuteTask(ctx, task.TaskId, task.DockerImage, task.Command,
-		task.ReqCpu, task.ReqMemory, task.ReqGpu)
-
-	// Remove from monitoring
-	s.monitor.RemoveTask(task.TaskId)
+	result := s.executor.ExecuteTask(ctx, executor.TaskSpec{
+		TaskID:       task.TaskId,
+		DockerImage:  task.DockerImage,
+		Command:      task.Command,
+		ReqCPU:       task.ReqCpu,
+		ReqMemory:    task.ReqMemory,
+		ReqGPU:       task.ReqGpu,
+		MemLimit:     task.MemLimit,
+		PinCPUs:      task.PinCpus,
+		Cacheable:    task.Cacheable,
+		MaxRestarts:  int(task.MaxRestarts),
+		StopGraceSec: int(task.StopGracePeriodSec),
+		Network:      executor.TaskNetwork{Mode: task.NetworkMode, Ports: task.PortBindings},
+		Process:      executor.TaskProcess{WorkingDir: task.WorkingDir, User: task.RunAsUser, ReadOnlyRootFS: task.ReadOnlyRootfs},
+		Health:       executor.TaskHealthCheck{Command: task.HealthCheck, Timeout: time.Duration(task.HealthCheckTimeoutSec) * time.Second},
+	})
+
+	// Keep reporting the task in heartbeats until its result has been sent, so the master
+	// never sees it disappear before the completion report arrives
+	defer s.monitor.RemoveTask(task.TaskId)
+
+	// A task stopped by shutdown is released to the master instead of reported
+	s.mu.Lock()
+	_, handedBack := s.handingBack[task.TaskId]
+	if handedBack {
+		s.handingBack[task.TaskId] = true
+	}
+	s.mu.Unlock()
+	if handedBack {
+		log.Printf("[Task %s] Stopped for shutdown - leaving it to be released", task.TaskId)
+		return
+	}
 
 	// Upload output files to master if any were generated
 	if len(result.OutputFiles) > 0 {
@@ -214,14 +424,41 @@ func (s *WorkerServer) executeTask(task *pb.Task) {
 		Logs:           result.Logs,
 		ResultLocation: result.ResultLocation,
 		OutputFiles:    result.OutputFiles,
+		CacheHit:       result.CacheHit,
+		FailureReason:  result.FailureReason,
+		ExitCode:       int32(result.ExitCode),
+		ImageDigest:    result.ImageDigest,
 	}
+	if result.Error != nil {
+		taskResult.ErrorMessage = result.Error.Error()
+	}
+	if u := result.Usage; u != nil {
+		taskResult.Usage = &pb.ResourceUsage{
+			CpuMin:    u.CPUMin,
+			CpuAvg:    u.CPUAvg,
+			CpuP95:    u.CPUP95,
+			CpuMax:    u.CPUMax,
+			MemoryMin: u.MemoryMin,
+			MemoryAvg: u.MemoryAvg,
+			MemoryP95: u.MemoryP95,
+			MemoryMax: u.MemoryMax,
+			Samples:   int32(u.Samples),
+		}
+	}
+	taskResult.PublishedPorts = portMappings(result.PublishedPorts)
 
 	s.mu.RLock()
 	masterAddr := s.masterAddr
 	s.mu.RUnlock()
 
-	if err := telemetry.ReportTaskResult(reportCtx, masterAddr, taskResult); err != nil {
+	if err := telemetry.ReportTaskResult(reportCtx, masterAddr, s.transportCreds, taskResult); err != nil {
 		log.Printf("Failed to report task result: %v", err)
+		return
+	}
+
+	// The master has the logs now, so the on-disk copy is no longer needed
+	if err := s.executor.GetLogStreamManager().RemoveStoredLogs(task.TaskId); err != nil {
+		log.Printf("[Task %s] Warning: failed to remove stored logs: %v", task.TaskId, err)
 	}
 }
 
@@ -237,8 +474,9 @@ func (s *WorkerServer) CancelTask(ctx context.Context, taskID *pb.TaskID) (*pb.T
 	if err := s.executor.CancelTask(ctx, taskID.TaskId); err != nil {
 		log.Printf("  ✗ Failed to cancel task: %v", err)
 		return &pb.TaskAck{
-			Success: false,
-			Message: fmt.Sprintf("Failed to cancel task: %v", err),
+			Success:   false,
+			Message:   fmt.Sprintf("Failed to cancel task: %v", err),
+			ErrorCode: pb.ErrorCode_EXECUTION_FAILED,
 		}, nil
 	}
 
@@ -281,7 +519,7 @@ func (s *WorkerServer) reportCancellationWithRetry(taskID string, maxRetries int
 	var lastErr error
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		err := telemetry.ReportTaskResult(ctx, masterAddr, taskResult)
+		err := telemetry.ReportTaskResult(ctx, masterAddr, s.transportCreds, taskResult)
 		cancel()
 
 		if err == nil {
@@ -306,13 +544,53 @@ func (s *WorkerServer) reportCancellationWithRetry(taskID string, maxRetries int
 	return fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
 }
 
+// streamPullProgress sends a task's image pull progress while it waits for the task's container
+// It returns the container once it exists; exists is false if the task is not pulling or ended without one
+func (s *WorkerServer) streamPullProgress(taskID string, stream pb.MasterWorker_StreamTaskLogsServer) (string, bool, error) {
+	sent := 0
+	for {
+		lines, pulling := s.executor.PullProgress(taskID)
+		for ; sent < len(lines); sent++ {
+			if err := stream.Send(&pb.LogChunk{
+				TaskId:    taskID,
+				Content:   lines[sent],
+				Timestamp: time.Now().Format(time.RFC3339Nano),
+				Status:    "pulling",
+			}); err != nil {
+				return "", false, fmt.Errorf("failed to send pull progress: %w", err)
+			}
+		}
+
+		if containerID, exists := s.executor.GetContainerID(taskID); exists || !pulling {
+			return containerID, exists, nil
+		}
+
+		select {
+		case <-time.After(pullProgressPollInterval):
+		case <-stream.Context().Done():
+			return "", false, stream.Context().Err()
+		}
+	}
+}
+
 // StreamTaskLogs streams live logs for a task
 func (s *WorkerServer) StreamTaskLogs(req *pb.TaskLogRequest, stream pb.MasterWorker_StreamTaskLogsServer) error {
 	log.Printf("Log stream request for task: %s (user: %s, follow: %v)", req.TaskId, req.UserId, req.Follow)
 
-	// Verify task exists on this worker
+	// Verify task exists on this worker; a task still pulling its image streams the pull's progress first
 	containerID, exists := s.executor.GetContainerID(req.TaskId)
 	if !exists {
+		var err error
+		if containerID, exists, err = s.streamPullProgress(req.TaskId, stream); err != nil {
+			return err
+		}
+	}
+	if !exists {
+		// A finished task whose result has not reached the master still has its logs on disk
+		if stored, err := s.executor.GetLogStreamManager().StoredLogs(req.TaskId); err == nil {
+			return sendStoredLogs(stream, req.TaskId, stored)
+		}
+
 		// Task not running, send error
 		return stream.Send(&pb.LogChunk{
 			TaskId:     req.TaskId,
@@ -353,10 +631,11 @@ func (s *WorkerServer) StreamTaskLogs(req *pb.TaskLogRequest, stream pb.MasterWo
 			// Send log line
 			if err := stream.Send(&pb.LogChunk{
 				TaskId:     req.TaskId,
-				Content:    line,
-				Timestamp:  "", // Could add timestamp from LogLine
+				Content:    line.Content,
+				Timestamp:  line.Timestamp.Format(time.RFC3339Nano),
 				IsComplete: false,
 				Status:     status,
+				Stream:     line.Stream,
 			}); err != nil {
 				return fmt.Errorf("failed to send log chunk: %w", err)
 			}
@@ -378,42 +657,217 @@ func (s *WorkerServer) StreamTaskLogs(req *pb.TaskLogRequest, stream pb.MasterWo
 	}
 }
 
+// sendStoredLogs replays a task's on-disk logs to a log stream and marks it complete
+func sendStoredLogs(stream pb.MasterWorker_StreamTaskLogsServer, taskID string, lines []logstream.LogLine) error {
+	for _, line := range lines {
+		if err := stream.Send(&pb.LogChunk{
+			TaskId:    taskID,
+			Content:   line.Content,
+			Timestamp: line.Timestamp.Format(time.RFC3339Nano),
+			Status:    "finished",
+			Stream:    line.Stream,
+		}); err != nil {
+			return fmt.Errorf("failed to send log chunk: %w", err)
+		}
+	}
+	return stream.Send(&pb.LogChunk{
+		TaskId:     taskID,
+		IsComplete: true,
+		Status:     "finished",
+	})
+}
+
+// StreamTaskStats streams live CPU and memory samples of a running task's container
+// The stream ends with an is_complete message once the task stops running (immediately if it is not running here)
+func (s *WorkerServer) StreamTaskStats(req *pb.TaskID, stream pb.MasterWorker_StreamTaskStatsServer) error {
+	samples, err := s.taskUsageFn(stream.Context(), req.TaskId, executor.DefaultUsageSampleInterval)
+	if err != nil {
+		log.Printf("Stats stream request for task %s: %v", req.TaskId, err)
+		return stream.Send(&pb.TaskStats{TaskId: req.TaskId, IsComplete: true, Timestamp: time.Now().Unix()})
+	}
+
+	for sample := range samples {
+		if err := stream.Send(&pb.TaskStats{
+			TaskId:        req.TaskId,
+			CpuCores:      sample.CPUCores,
+			MemoryGb:      sample.MemoryGB,
+			MemoryLimitGb: sample.MemoryLimitGB,
+			Timestamp:     sample.Timestamp.Unix(),
+		}); err != nil {
+			return fmt.Errorf("failed to send task stats: %w", err)
+		}
+	}
+
+	if err := stream.Context().Err(); err != nil {
+		log.Printf("Client disconnected from stats stream for task: %s", req.TaskId)
+		return err
+	}
+	return stream.Send(&pb.TaskStats{TaskId: req.TaskId, IsComplete: true, Timestamp: time.Now().Unix()})
+}
+
+// PrewarmImages pulls images ahead of task assignment so tasks skip the pull on startup
+func (s *WorkerServer) PrewarmImages(ctx context.Context, req *pb.PrewarmRequest) (*pb.PrewarmAck, error) {
+	log.Printf("🔥 Prewarm request for %d image(s)", len(req.Images))
+
+	if len(req.Images) == 0 {
+		return &pb.PrewarmAck{
+			Success: false,
+			Message: "No images specified",
+		}, nil
+	}
+
+	// Pulls can take much longer than the RPC deadline, so use a dedicated context
+	pullCtx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	pulled, cached, failed := s.executor.PrewarmImages(pullCtx, req.Images)
+
+	return &pb.PrewarmAck{
+		Success: len(failed) == 0,
+		Message: fmt.Sprintf("Prewarm complete: %d pulled, %d already cached, %d failed", len(pulled), len(cached), len(failed)),
+		Pulled:  pulled,
+		Cached:  cached,
+		Failed:  failed,
+	}, nil
+}
+
+// ValidateImage checks that an image exists and can be pulled, without running it
+func (s *WorkerServer) ValidateImage(ctx context.Context, req *pb.ValidateImageRequest) (*pb.ValidateImageAck, error) {
+	log.Printf("🔍 Validate request for image: %s", req.Image)
+
+	// A pull may be needed to validate the image, which can outlast the RPC deadline
+	pullCtx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	result, err := s.executor.ValidateImage(pullCtx, req.Image)
+	if err != nil {
+		log.Printf("❌ Image %s is not usable: %v", req.Image, err)
+		return &pb.ValidateImageAck{
+			Success: false,
+			Message: err.Error(),
+			Image:   req.Image,
+		}, nil
+	}
+
+	message := "Image is available locally"
+	if result.Pulled {
+		message = "Image was pulled from the registry"
+	}
+
+	return &pb.ValidateImageAck{
+		Success:   true,
+		Message:   message,
+		Image:     req.Image,
+		Digest:    result.Digest,
+		SizeBytes: result.SizeBytes,
+		Pulled:    result.Pulled,
+	}, nil
+}
+
 // Close cleans up resources
 func (s *WorkerServer) Close() error {
 	return s.executor.Close()
 }
 
-// Shutdown handles graceful shutdown by reporting all running tasks as failed
+// stopTasksForShutdown stops the tasks' containers in parallel, each within its own stop grace, and returns the ones that stopped
+func (s *WorkerServer) stopTasksForShutdown(taskIDs []string) []string {
+	s.mu.Lock()
+	if s.handingBack == nil {
+		s.handingBack = make(map[string]bool)
+	}
+	for _, taskID := range taskIDs {
+		s.handingBack[taskID] = false
+	}
+	s.mu.Unlock()
+
+	stoppedCh := make(chan string, len(taskIDs))
+	var wg sync.WaitGroup
+	for _, taskID := range taskIDs {
+		wg.Add(1)
+		go func(taskID string) {
+			defer wg.Done()
+			log.Printf("  ■ Stopping task %s...", taskID)
+			if err := s.stopTaskFn(taskID); err != nil {
+				s.mu.Lock()
+				withheld := s.handingBack[taskID]
+				delete(s.handingBack, taskID)
+				s.mu.Unlock()
+				// A task that exited on its own meanwhile had its result withheld, so it is released too
+				if !withheld {
+					log.Printf("  ⚠ Failed to stop task %s: %v - not releasing it", taskID, err)
+					return
+				}
+			}
+			stoppedCh <- taskID
+		}(taskID)
+	}
+	wg.Wait()
+	close(stoppedCh)
+
+	// Keep the running order so releases are logged predictably
+	stoppedSet := make(map[string]bool)
+	for taskID := range stoppedCh {
+		stoppedSet[taskID] = true
+	}
+	var stopped []string
+	for _, taskID := range taskIDs {
+		if stoppedSet[taskID] {
+			stopped = append(stopped, taskID)
+		}
+	}
+	return stopped
+}
+
+// Shutdown handles graceful shutdown by stopping running tasks and handing them back to master (reporting them failed if that fails)
+// A task whose container could not be stopped is left alone, since it may still be running
 func (s *WorkerServer) Shutdown() {
 	fmt.Println("╔═══════════════════════════════════════════════════════")
 	fmt.Println("║  Worker Shutdown - Cleaning up running tasks...")
 	fmt.Println("╚═══════════════════════════════════════════════════════")
 
 	// Get all running tasks
-	runningTasks := s.executor.GetRunningTasks()
+	runningTasks := s.runningTasksFn()
 
 	if len(runningTasks) == 0 {
 		fmt.Println("  ✓ No running tasks to clean up")
 		return
 	}
 
-	fmt.Printf("  Found %d running task(s) to report as failed\n", len(runningTasks))
+	fmt.Printf("  Found %d running task(s) to hand back to master\n", len(runningTasks))
+
+	stopped := s.stopTasksForShutdown(runningTasks)
+	if len(stopped) == 0 {
+		log.Println("  ⚠ No task could be stopped - nothing to release")
+		return
+	}
 
 	s.mu.RLock()
 	masterAddr := s.masterAddr
 	s.mu.RUnlock()
 
 	if masterAddr == "" {
-		log.Println("  ⚠ No master address - cannot report task failures")
+		log.Println("  ⚠ No master address - cannot release stopped tasks")
 		return
 	}
 
-	// Report each task as failed to master
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	for _, taskID := range runningTasks {
-		log.Printf("  📤 Reporting task %s as failed due to worker shutdown...", taskID)
+	// Release each stopped task so master re-queues it; fall back to reporting it failed
+	for _, taskID := range stopped {
+		log.Printf("  ↩ Releasing task %s back to master due to worker shutdown...", taskID)
+
+		release := &pb.TaskRelease{
+			TaskId:   taskID,
+			WorkerId: s.workerID,
+			Reason:   "worker shutting down",
+		}
+		err := telemetry.ReleaseTask(ctx, masterAddr, s.transportCreds, release)
+		if err == nil {
+			log.Printf("  ✓ Task %s released for re-queueing", taskID)
+			continue
+		}
+		log.Printf("  ⚠ Failed to release task %s: %v - reporting it as failed", taskID, err)
 
 		taskResult := &pb.TaskResult{
 			TaskId:         taskID,
@@ -423,7 +877,7 @@ func (s *WorkerServer) Shutdown() {
 			ResultLocation: "",
 		}
 
-		if err := telemetry.ReportTaskResult(ctx, masterAddr, taskResult); err != nil {
+		if err := telemetry.ReportTaskResult(ctx, masterAddr, s.transportCreds, taskResult); err != nil {
 			log.Printf("  ⚠ Failed to report task %s: %v", taskID, err)
 		} else {
 			log.Printf("  ✓ Successfully reported task %s as failed", taskID)
@@ -439,6 +893,7 @@ func (s *WorkerServer) Shutdown() {
 func (s *WorkerServer) uploadOutputFiles(task *pb.Task, result *executor.TaskResult) error {
 	s.mu.RLock()
 	masterAddr := s.masterAddr
+	keepalive := s.keepalive
 	s.mu.RUnlock()
 
 	if masterAddr == "" {
@@ -446,7 +901,8 @@ func (s *WorkerServer) uploadOutputFiles(task *pb.Task, result *executor.TaskRes
 	}
 
 	// Connect to master
-	conn, err := grpc.Dial(masterAddr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(10*time.Second))
+	opts := append([]grpc.DialOption{s.dialCredentials(), grpc.WithBlock(), grpc.WithTimeout(10 * time.Second)}, keepalive.DialOptions()...)
+	conn, err := grpc.Dial(masterAddr, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to connect to master: %w", err)
 	}
@@ -469,6 +925,9 @@ func (s *WorkerServer) uploadOutputFiles(task *pb.Task, result *executor.TaskRes
 			continue
 		}
 
+		// The checksum travels on the last chunk so the master can reject a corrupted file
+		checksum := sha256.Sum256(fileData)
+
 		// Split file into chunks (max 1MB per chunk)
 		const chunkSize = 1024 * 1024 // 1MB
 		for offset := 0; offset < len(fileData); offset += chunkSize {
@@ -487,6 +946,9 @@ func (s *WorkerServer) uploadOutputFiles(task *pb.Task, result *executor.TaskRes
 				IsLastFile:  (i == len(result.OutputFiles)-1) && (end == len(fileData)),
 				Timestamp:   task.SubmittedAt,
 			}
+			if chunk.IsLastChunk {
+				chunk.Sha256 = hex.EncodeToString(checksum[:])
+			}
 
 			if err := stream.Send(chunk); err != nil {
 				return fmt.Errorf("failed to send chunk: %w", err)
@@ -502,8 +964,15 @@ func (s *WorkerServer) uploadOutputFiles(task *pb.Task, result *executor.TaskRes
 		return fmt.Errorf("failed to close stream: %w", err)
 	}
 
+	// Files are committed individually, so report the ones the master discarded
+	for _, fileResult := range ack.Results {
+		if !fileResult.Success {
+			log.Printf("[Task %s] ✗ Master rejected file %s: %s", task.TaskId, fileResult.FilePath, fileResult.Error)
+		}
+	}
+
 	if !ack.Success {
-		return fmt.Errorf("upload failed: %s", ack.Message)
+		return fmt.Errorf("upload failed (%d stored, %d failed): %s", ack.FilesReceived, ack.FilesFailed, ack.Message)
 	}
 
 	log.Printf("[Task %s] ✓ Uploaded %d file(s) successfully", task.TaskId, ack.FilesReceived)
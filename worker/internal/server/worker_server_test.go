@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"worker/internal/executor"
+	pb "worker/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TestAssignTaskRejectedWhenDiskNearlyFull tests that a worker low on output disk space nacks the assignment
+func TestAssignTaskRejectedWhenDiskNearlyFull(t *testing.T) {
+	outputDir := t.TempDir()
+	var checkedPath string
+
+	s := &WorkerServer{
+		workerID:         "worker-1",
+		masterRegistered: true,
+		outputDir:        outputDir,
+		minFreeDiskGB:    DefaultMinFreeDiskGB,
+		freeDiskFn: func(path string) (float64, error) {
+			checkedPath = path
+			return 0.25, nil // 256 MB left
+		},
+	}
+
+	ack, err := s.AssignTask(context.Background(), &pb.Task{TaskId: "task-1", DockerImage: "alpine:latest"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ack.Success {
+		t.Fatal("Expected assignment to be rejected on a nearly full disk")
+	}
+	if ack.ErrorCode != pb.ErrorCode_INSUFFICIENT_STORAGE {
+		t.Errorf("Expected INSUFFICIENT_STORAGE, got %s", ack.ErrorCode)
+	}
+	if checkedPath != outputDir {
+		t.Errorf("Expected free space to be checked under %s, got %s", outputDir, checkedPath)
+	}
+}
+
+// TestCheckDiskSpaceThreshold tests the free-space threshold, including disabling it
+func TestCheckDiskSpaceThreshold(t *testing.T) {
+	s := &WorkerServer{
+		outputDir:     t.TempDir(),
+		minFreeDiskGB: 2.0,
+		freeDiskFn:    func(path string) (float64, error) { return 5.0, nil },
+	}
+	if err := s.checkDiskSpace(); err != nil {
+		t.Errorf("Expected 5 GB free to pass a 2 GB threshold, got %v", err)
+	}
+
+	s.SetMinFreeDisk(10.0)
+	if err := s.checkDiskSpace(); err == nil {
+		t.Error("Expected 5 GB free to fail a 10 GB threshold")
+	}
+
+	s.SetMinFreeDisk(0)
+	if err := s.checkDiskSpace(); err != nil {
+		t.Errorf("Expected a zero threshold to disable the guard, got %v", err)
+	}
+}
+
+// fakeMaster records the task releases and completion reports it receives
+type fakeMaster struct {
+	pb.UnimplementedMasterWorkerServer
+	mu       sync.Mutex
+	released []*pb.TaskRelease
+	reported []*pb.TaskResult
+}
+
+func (f *fakeMaster) ReleaseTask(ctx context.Context, req *pb.TaskRelease) (*pb.Ack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.released = append(f.released, req)
+	return &pb.Ack{Success: true, Message: "released"}, nil
+}
+
+func (f *fakeMaster) ReportTaskCompletion(ctx context.Context, result *pb.TaskResult) (*pb.Ack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reported = append(f.reported, result)
+	return &pb.Ack{Success: true}, nil
+}
+
+// serveFakeMaster starts a fakeMaster on a local port, returning it and its address
+func serveFakeMaster(t *testing.T) (*fakeMaster, string) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	master := &fakeMaster{}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, master)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+	return master, lis.Addr().String()
+}
+
+// TestShutdownReleasesRunningTasks tests that a task still running at shutdown is stopped and handed back to master instead of failed
+func TestShutdownReleasesRunningTasks(t *testing.T) {
+	master, addr := serveFakeMaster(t)
+
+	var stoppedTasks []string
+	s := &WorkerServer{
+		workerID:       "worker-1",
+		masterAddr:     addr,
+		runningTasksFn: func() []string { return []string{"task-1"} },
+		stopTaskFn: func(taskID string) error {
+			stoppedTasks = append(stoppedTasks, taskID)
+			return nil
+		},
+	}
+	s.Shutdown()
+
+	if len(stoppedTasks) != 1 || stoppedTasks[0] != "task-1" {
+		t.Errorf("Expected task-1 to be stopped before release, got %v", stoppedTasks)
+	}
+
+	master.mu.Lock()
+	defer master.mu.Unlock()
+	if len(master.released) != 1 {
+		t.Fatalf("Expected 1 task release, got %d", len(master.released))
+	}
+	if got := master.released[0]; got.TaskId != "task-1" || got.WorkerId != "worker-1" {
+		t.Errorf("Expected task-1 released by worker-1, got %+v", got)
+	}
+	if len(master.reported) != 0 {
+		t.Errorf("Expected no failure report for a released task, got %d", len(master.reported))
+	}
+}
+
+// TestShutdownKeepsTasksThatFailedToStop tests that a task whose container could not be stopped is neither released nor failed
+func TestShutdownKeepsTasksThatFailedToStop(t *testing.T) {
+	master, addr := serveFakeMaster(t)
+
+	s := &WorkerServer{
+		workerID:       "worker-1",
+		masterAddr:     addr,
+		runningTasksFn: func() []string { return []string{"task-1", "task-2"} },
+		stopTaskFn: func(taskID string) error {
+			if taskID == "task-2" {
+				return fmt.Errorf("docker daemon not responding")
+			}
+			return nil
+		},
+	}
+	s.Shutdown()
+
+	master.mu.Lock()
+	defer master.mu.Unlock()
+	if len(master.released) != 1 || master.released[0].TaskId != "task-1" {
+		t.Fatalf("Expected only task-1 to be released, got %+v", master.released)
+	}
+	if len(master.reported) != 0 {
+		t.Errorf("Expected no failure report, got %d", len(master.reported))
+	}
+	if _, tracked := s.handingBack["task-2"]; tracked {
+		t.Error("Expected task-2 to report its own result once it finishes")
+	}
+}
+
+// TestStreamTaskStatsSendsSamplesThenCompletes tests that a task's usage samples are streamed in order and the stream ends once the task stops
+func TestStreamTaskStatsSendsSamplesThenCompletes(t *testing.T) {
+	s := &WorkerServer{
+		workerID: "worker-1",
+		taskUsageFn: func(ctx context.Context, taskID string, interval time.Duration) (<-chan executor.UsageSample, error) {
+			if taskID != "task-1" {
+				return nil, fmt.Errorf("task %s is not running on this worker", taskID)
+			}
+			samples := make(chan executor.UsageSample, 2)
+			samples <- executor.UsageSample{CPUCores: 0.5, MemoryGB: 0.25, MemoryLimitGB: 1.0, Timestamp: time.Unix(100, 0)}
+			samples <- executor.UsageSample{CPUCores: 1.5, MemoryGB: 0.5, MemoryLimitGB: 1.0, Timestamp: time.Unix(102, 0)}
+			close(samples) // The task stopped running
+			return samples, nil
+		},
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterWorkerServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial worker: %v", err)
+	}
+	defer conn.Close()
+	client := pb.NewMasterWorkerClient(conn)
+
+	receive := func(taskID string) []*pb.TaskStats {
+		stream, err := client.StreamTaskStats(context.Background(), &pb.TaskID{TaskId: taskID})
+		if err != nil {
+			t.Fatalf("Failed to open stats stream: %v", err)
+		}
+		var received []*pb.TaskStats
+		for {
+			stats, err := stream.Recv()
+			if err == io.EOF {
+				return received
+			}
+			if err != nil {
+				t.Fatalf("Failed to receive stats: %v", err)
+			}
+			received = append(received, stats)
+		}
+	}
+
+	received := receive("task-1")
+	if len(received) != 3 {
+		t.Fatalf("Expected 2 samples and a completion message, got %d messages", len(received))
+	}
+	if received[0].CpuCores != 0.5 || received[1].CpuCores != 1.5 || received[1].MemoryGb != 0.5 || received[1].Timestamp != 102 {
+		t.Errorf("Expected samples in order, got %+v and %+v", received[0], received[1])
+	}
+	if received[0].IsComplete || !received[2].IsComplete {
+		t.Errorf("Expected only the last message to be complete, got %v, %v, %v", received[0].IsComplete, received[1].IsComplete, received[2].IsComplete)
+	}
+
+	if missing := receive("task-other"); len(missing) != 1 || !missing[0].IsComplete {
+		t.Errorf("Expected a task not running here to complete immediately, got %d messages", len(missing))
+	}
+}
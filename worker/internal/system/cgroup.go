@@ -0,0 +1,86 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where the container's cgroup filesystem is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupUnlimitedBytes is the cgroup v1 memory limit at or above which no limit is set
+// (v1 reports "unlimited" as a page-rounded max int64)
+const cgroupUnlimitedBytes = 1 << 62
+
+// CgroupLimits holds the CPU and memory limits enforced on the worker's cgroup (0 = no limit)
+type CgroupLimits struct {
+	CPU    float64 // Cores: CFS quota / period
+	Memory float64 // GB
+}
+
+// ReadCgroupLimits reads the CPU quota and memory limit under a cgroup mount
+// cgroup v2 (cpu.max, memory.max) is used when root holds cgroup.controllers, otherwise
+// the v1 cpu and memory controllers (cpu.cfs_quota_us, memory.limit_in_bytes) are read
+func ReadCgroupLimits(root string) CgroupLimits {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		return readCgroupV2Limits(root)
+	}
+	return readCgroupV1Limits(root)
+}
+
+// readCgroupV2Limits parses cpu.max ("<quota|max> <period>") and memory.max ("<bytes|max>")
+func readCgroupV2Limits(root string) CgroupLimits {
+	var limits CgroupLimits
+
+	if fields := strings.Fields(readCgroupFile(root, "cpu.max")); len(fields) == 2 && fields[0] != "max" {
+		quota, qErr := strconv.ParseFloat(fields[0], 64)
+		period, pErr := strconv.ParseFloat(fields[1], 64)
+		if qErr == nil && pErr == nil && quota > 0 && period > 0 {
+			limits.CPU = quota / period
+		}
+	}
+
+	if value := readCgroupFile(root, "memory.max"); value != "" && value != "max" {
+		if bytes, err := strconv.ParseUint(value, 10, 64); err == nil && bytes > 0 {
+			limits.Memory = float64(bytes) / (1024.0 * 1024.0 * 1024.0)
+		}
+	}
+
+	return limits
+}
+
+// readCgroupV1Limits parses cpu/cpu.cfs_quota_us (-1 = no quota) and memory/memory.limit_in_bytes
+func readCgroupV1Limits(root string) CgroupLimits {
+	var limits CgroupLimits
+
+	quota, qErr := strconv.ParseFloat(readCgroupFile(root, "cpu", "cpu.cfs_quota_us"), 64)
+	period, pErr := strconv.ParseFloat(readCgroupFile(root, "cpu", "cpu.cfs_period_us"), 64)
+	if qErr == nil && pErr == nil && quota > 0 && period > 0 {
+		limits.CPU = quota / period
+	}
+
+	if bytes, err := strconv.ParseUint(readCgroupFile(root, "memory", "memory.limit_in_bytes"), 10, 64); err == nil && bytes > 0 && bytes < cgroupUnlimitedBytes {
+		limits.Memory = float64(bytes) / (1024.0 * 1024.0 * 1024.0)
+	}
+
+	return limits
+}
+
+// readCgroupFile returns a cgroup file's trimmed contents, or "" when it cannot be read
+func readCgroupFile(root string, elem ...string) string {
+	data, err := os.ReadFile(filepath.Join(append([]string{root}, elem...)...))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// EffectiveCapacity returns the physical capacity capped by a cgroup limit (a limit of 0 means none)
+func EffectiveCapacity(physical, limit float64) float64 {
+	if limit > 0 && limit < physical {
+		return limit
+	}
+	return physical
+}
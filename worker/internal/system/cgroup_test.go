@@ -0,0 +1,104 @@
+package system
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeCgroupFiles writes sample cgroup files (relative path -> contents) under a temp root
+func writeCgroupFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	return root
+}
+
+// TestCgroupLimitsCapEffectiveCapacity tests that v1 and v2 cgroup limits produce the expected effective CPU and memory
+func TestCgroupLimitsCapEffectiveCapacity(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantCPU   float64 // Effective cores on a 16-core host
+		wantMemGB float64 // Effective GB on a 64 GB host
+	}{
+		{
+			name: "v2 quota and memory limit",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"cpu.max":            "250000 100000\n",
+				"memory.max":         "4294967296\n",
+			},
+			wantCPU:   2.5,
+			wantMemGB: 4,
+		},
+		{
+			name: "v2 unlimited",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"cpu.max":            "max 100000\n",
+				"memory.max":         "max\n",
+			},
+			wantCPU:   16,
+			wantMemGB: 64,
+		},
+		{
+			name: "v1 quota and memory limit",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "400000\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+				"memory/memory.limit_in_bytes": "2147483648\n",
+			},
+			wantCPU:   4,
+			wantMemGB: 2,
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "-1\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+				"memory/memory.limit_in_bytes": "9223372036854771712\n",
+			},
+			wantCPU:   16,
+			wantMemGB: 64,
+		},
+		{
+			name: "limit above physical capacity",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"cpu.max":            "3200000 100000\n",
+				"memory.max":         "137438953472\n",
+			},
+			wantCPU:   16,
+			wantMemGB: 64,
+		},
+		{
+			name:      "no cgroup files",
+			files:     map[string]string{},
+			wantCPU:   16,
+			wantMemGB: 64,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := ReadCgroupLimits(writeCgroupFiles(t, tt.files))
+
+			if cpu := EffectiveCapacity(16, limits.CPU); math.Abs(cpu-tt.wantCPU) > 1e-9 {
+				t.Errorf("Expected %.2f effective cores, got %.2f", tt.wantCPU, cpu)
+			}
+			if mem := EffectiveCapacity(64, limits.Memory); math.Abs(mem-tt.wantMemGB) > 1e-9 {
+				t.Errorf("Expected %.2f GB effective memory, got %.2f", tt.wantMemGB, mem)
+			}
+		})
+	}
+}
@@ -122,6 +122,7 @@ func FindAvailablePort(startPort int) (int, error) {
 }
 
 // GetSystemResources retrieves actual system resources (CPU, Memory, Storage, GPU)
+// CPU and memory are the effective capacity: the physical amount capped by any cgroup limit
 func GetSystemResources() (*ResourceInfo, error) {
 	resources := &ResourceInfo{
 		TotalCPU: float64(runtime.NumCPU()),
@@ -137,6 +138,17 @@ func GetSystemResources() (*ResourceInfo, error) {
 		resources.TotalMemory = memory
 	}
 
+	// In a container or VM the cgroup limits, not the host, bound what tasks can actually use
+	limits := ReadCgroupLimits(cgroupRoot)
+	if cpu := EffectiveCapacity(resources.TotalCPU, limits.CPU); cpu < resources.TotalCPU {
+		log.Printf("✓ CPU capped by cgroup quota: %.2f cores (host has %.0f)", cpu, resources.TotalCPU)
+		resources.TotalCPU = cpu
+	}
+	if memory := EffectiveCapacity(resources.TotalMemory, limits.Memory); memory < resources.TotalMemory {
+		log.Printf("✓ Memory capped by cgroup limit: %.2f GB (host has %.2f GB)", memory, resources.TotalMemory)
+		resources.TotalMemory = memory
+	}
+
 	// Get total storage (disk space)
 	storage, err := getTotalStorage()
 	if err != nil {
@@ -218,9 +230,14 @@ func getTotalStorage() (float64, error) {
 
 // GetAvailableStorage returns the available storage in GB for the root filesystem
 func GetAvailableStorage() (float64, error) {
+	return GetAvailableStorageAt("/")
+}
+
+// GetAvailableStorageAt returns the available storage in GB for the filesystem holding path
+func GetAvailableStorageAt(path string) (float64, error) {
 	var stat syscall.Statfs_t
 
-	err := syscall.Statfs("/", &stat)
+	err := syscall.Statfs(path, &stat)
 	if err != nil {
 		return 0, fmt.Errorf("statfs syscall failed: %w", err)
 	}
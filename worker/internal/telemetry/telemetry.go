@@ -16,6 +16,7 @@ import (
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/mem"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -26,7 +27,8 @@ type Monitor struct {
 	interval     time.Duration
 	runningTasks map[string]*pb.RunningTask
 	stopChan     chan struct{}
-	mu           sync.RWMutex // Protects runningTasks and masterAddr
+	creds        credentials.TransportCredentials // Credentials for dials to the master (nil = plaintext)
+	mu           sync.RWMutex                     // Protects runningTasks, masterAddr and creds
 }
 
 // NewMonitor creates a new telemetry monitor
@@ -48,6 +50,21 @@ func (m *Monitor) SetMasterAddress(masterAddr string) {
 	log.Printf("Updated master address to: %s", masterAddr)
 }
 
+// SetTransportCredentials sets the credentials heartbeats use to dial the master
+func (m *Monitor) SetTransportCredentials(creds credentials.TransportCredentials) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.creds = creds
+}
+
+// DialCredentials returns the dial option for creds, falling back to plaintext when creds is nil
+func DialCredentials(creds credentials.TransportCredentials) grpc.DialOption {
+	if creds == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	return grpc.WithTransportCredentials(creds)
+}
+
 // Start begins sending periodic heartbeats to the master
 func (m *Monitor) Start(ctx context.Context) {
 	ticker := time.NewTicker(m.interval)
@@ -89,6 +106,40 @@ func (m *Monitor) AddTask(taskID string, cpuAlloc, memAlloc, gpuAlloc float64) {
 	log.Printf("Task %s added to monitoring (total tasks: %d)", taskID, len(m.runningTasks))
 }
 
+// SetTaskStatus sets the status a running task is reported with in heartbeats (running or ready)
+func (m *Monitor) SetTaskStatus(taskID, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Replaced rather than modified: a heartbeat being sent may still hold the old one
+	if task, ok := m.runningTasks[taskID]; ok {
+		m.runningTasks[taskID] = &pb.RunningTask{
+			TaskId:          task.TaskId,
+			CpuAllocated:    task.CpuAllocated,
+			MemoryAllocated: task.MemoryAllocated,
+			GpuAllocated:    task.GpuAllocated,
+			Status:          status,
+			PublishedPorts:  task.PublishedPorts,
+		}
+	}
+}
+
+// SetTaskPorts sets the published ports a running task is reported with in heartbeats
+func (m *Monitor) SetTaskPorts(taskID string, ports []*pb.PortMapping) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Replaced rather than modified: a heartbeat being sent may still hold the old one
+	if task, ok := m.runningTasks[taskID]; ok {
+		m.runningTasks[taskID] = &pb.RunningTask{
+			TaskId:          task.TaskId,
+			CpuAllocated:    task.CpuAllocated,
+			MemoryAllocated: task.MemoryAllocated,
+			GpuAllocated:    task.GpuAllocated,
+			Status:          task.Status,
+			PublishedPorts:  ports,
+		}
+	}
+}
+
 // RemoveTask removes a task from the running tasks list
 func (m *Monitor) RemoveTask(taskID string) {
 	m.mu.Lock()
@@ -102,6 +153,7 @@ func (m *Monitor) sendHeartbeat(ctx context.Context) error {
 	// Skip heartbeat if master address is not set yet
 	m.mu.RLock()
 	masterAddr := m.masterAddr
+	creds := m.creds
 	m.mu.RUnlock()
 
 	if masterAddr == "" {
@@ -111,7 +163,7 @@ func (m *Monitor) sendHeartbeat(ctx context.Context) error {
 	conn, err := grpc.DialContext(
 		ctx,
 		masterAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		DialCredentials(creds),
 		grpc.WithBlock(),
 	)
 	if err != nil {
@@ -196,11 +248,11 @@ func (m *Monitor) getGPUUsage() float64 {
 }
 
 // RegisterWorker registers the worker with the master
-func RegisterWorker(ctx context.Context, masterAddr string, info *pb.WorkerInfo) error {
+func RegisterWorker(ctx context.Context, masterAddr string, creds credentials.TransportCredentials, info *pb.WorkerInfo) error {
 	conn, err := grpc.DialContext(
 		ctx,
 		masterAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		DialCredentials(creds),
 		grpc.WithBlock(),
 	)
 	if err != nil {
@@ -223,11 +275,11 @@ func RegisterWorker(ctx context.Context, masterAddr string, info *pb.WorkerInfo)
 }
 
 // ReportTaskResult sends task completion result to master
-func ReportTaskResult(ctx context.Context, masterAddr string, result *pb.TaskResult) error {
+func ReportTaskResult(ctx context.Context, masterAddr string, creds credentials.TransportCredentials, result *pb.TaskResult) error {
 	conn, err := grpc.DialContext(
 		ctx,
 		masterAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		DialCredentials(creds),
 		grpc.WithBlock(),
 	)
 	if err != nil {
@@ -248,3 +300,30 @@ func ReportTaskResult(ctx context.Context, masterAddr string, result *pb.TaskRes
 	log.Printf("✓ Task result reported: %s", ack.Message)
 	return nil
 }
+
+// ReleaseTask hands a running task back to master so it is re-queued instead of failed
+func ReleaseTask(ctx context.Context, masterAddr string, creds credentials.TransportCredentials, release *pb.TaskRelease) error {
+	conn, err := grpc.DialContext(
+		ctx,
+		masterAddr,
+		DialCredentials(creds),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := pb.NewMasterWorkerClient(conn)
+	ack, err := client.ReleaseTask(ctx, release)
+	if err != nil {
+		return err
+	}
+
+	if !ack.Success {
+		return fmt.Errorf("release rejected: %s", ack.Message)
+	}
+
+	log.Printf("✓ Task released: %s", ack.Message)
+	return nil
+}
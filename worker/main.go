@@ -8,15 +8,14 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
+	"worker/internal/executor"
 	"worker/internal/server"
 	"worker/internal/system"
 	"worker/internal/telemetry"
-	pb "worker/proto"
-
-	"google.golang.org/grpc"
 )
 
 func main() {
@@ -90,6 +89,91 @@ func main() {
 	}
 	defer workerServer.Close()
 
+	// MIN_FREE_DISK_GB sets the free space required under the output directory to accept a task
+	if value := os.Getenv("MIN_FREE_DISK_GB"); value != "" {
+		if minFree, err := strconv.ParseFloat(value, 64); err == nil && minFree >= 0 {
+			workerServer.SetMinFreeDisk(minFree)
+		} else {
+			log.Printf("⚠️  Invalid MIN_FREE_DISK_GB %q, using default %.1f GB", value, server.DefaultMinFreeDiskGB)
+		}
+	}
+
+	// WORKER_ZONE labels this worker's rack or availability zone for topology-aware scheduling
+	if zone := os.Getenv("WORKER_ZONE"); zone != "" {
+		workerServer.SetZone(zone)
+		log.Printf("✓ Worker zone: %s", zone)
+	}
+
+	// SEPARATE_LOG_STREAMS=true runs tasks without a TTY so log lines are labelled stdout or stderr
+	if os.Getenv("SEPARATE_LOG_STREAMS") == "true" {
+		workerServer.SetSeparateLogStreams(true)
+		log.Println("✓ Task logs keep stdout and stderr separate (no TTY)")
+	}
+
+	// PIN_DIGESTS=true fails tasks using an untagged or :latest image; pinned digests are recorded regardless
+	if os.Getenv("PIN_DIGESTS") == "true" {
+		workerServer.SetPinDigests(true)
+		log.Println("✓ Digest pinning enforced: :latest images are rejected")
+	}
+
+	// READ_ONLY_ROOTFS=true mounts every task's root filesystem read-only (tasks write results to /output)
+	if os.Getenv("READ_ONLY_ROOTFS") == "true" {
+		workerServer.SetReadOnlyRootFS(true)
+		log.Println("✓ Task containers run with a read-only root filesystem")
+	}
+
+	// Task containers drop all capabilities and run with no-new-privileges unless the policy says otherwise:
+	// TASK_CAP_DROP_ALL=false keeps Docker's default capabilities, TASK_CAP_ADD=CHOWN,... gives some back,
+	// TASK_NO_NEW_PRIVILEGES=false allows setuid binaries and TASK_SECCOMP_PROFILE applies a seccomp profile file
+	sandbox := executor.DefaultSandboxPolicy()
+	sandbox.DropCapabilities = os.Getenv("TASK_CAP_DROP_ALL") != "false"
+	sandbox.NoNewPrivileges = os.Getenv("TASK_NO_NEW_PRIVILEGES") != "false"
+	sandbox.AddCapabilities = executor.ParseCapabilities(os.Getenv("TASK_CAP_ADD"))
+	sandbox.SeccompProfile = os.Getenv("TASK_SECCOMP_PROFILE")
+	workerServer.SetSandboxPolicy(sandbox)
+	log.Printf("✓ Task sandbox: drop capabilities=%v (kept: %v), no-new-privileges=%v, seccomp profile=%q",
+		sandbox.DropCapabilities, sandbox.AddCapabilities, sandbox.NoNewPrivileges, sandbox.SeccompProfile)
+
+	// IMAGE_PULL_TIMEOUT bounds how long a task's image pull may take (e.g. 5m)
+	if value := os.Getenv("IMAGE_PULL_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			workerServer.SetImagePullTimeout(d)
+			log.Printf("✓ Image pulls time out after %s", d)
+		} else {
+			log.Printf("⚠️  Invalid IMAGE_PULL_TIMEOUT %q, using default %s", value, executor.DefaultImagePullTimeout)
+		}
+	}
+
+	// TLS_CERT_FILE/TLS_KEY_FILE serve gRPC over TLS and dial the master with TLS (TLS_CA_FILE verifies the master);
+	// TLS_REQUIRE_CLIENT_CERT=true only accepts a master presenting a certificate signed by TLS_CA_FILE;
+	// plaintext requires ALLOW_INSECURE=true and is for development only
+	tlsConfig := server.TLSConfig{
+		CertFile:          os.Getenv("TLS_CERT_FILE"),
+		KeyFile:           os.Getenv("TLS_KEY_FILE"),
+		CAFile:            os.Getenv("TLS_CA_FILE"),
+		RequireClientCert: os.Getenv("TLS_REQUIRE_CLIENT_CERT") == "true",
+	}
+	if !tlsConfig.Enabled() && os.Getenv("ALLOW_INSECURE") != "true" {
+		log.Fatalf("TLS is not configured: set TLS_CERT_FILE and TLS_KEY_FILE, or ALLOW_INSECURE=true for development")
+	}
+	grpcCreds, err := tlsConfig.ServerCredentials()
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+	dialCreds, err := tlsConfig.ClientCredentials()
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+	workerServer.SetTransportCredentials(dialCreds)
+	if tlsConfig.Enabled() {
+		log.Printf("✓ TLS enabled (cert: %s)", tlsConfig.CertFile)
+		if tlsConfig.RequireClientCert {
+			log.Println("✓ Mutual TLS: the master must present a client certificate")
+		}
+	} else {
+		log.Println("⚠️  TLS disabled (ALLOW_INSECURE=true) - do not use outside development")
+	}
+
 	// Start gRPC server
 	workerAddress := workerIP + workerPort
 	lis, err := net.Listen("tcp", workerAddress)
@@ -97,8 +181,38 @@ func main() {
 		log.Fatalf("Failed to listen on %s: %v", workerAddress, err)
 	}
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterMasterWorkerServer(grpcServer, workerServer)
+	// MASTER_ADDR with JOIN_TOKEN joins a master running with AUTO_REGISTER=true, skipping the manual register step
+	if masterAddr := os.Getenv("MASTER_ADDR"); masterAddr != "" {
+		if joinToken := os.Getenv("JOIN_TOKEN"); joinToken != "" {
+			workerServer.SetJoinToken(joinToken, workerAddress)
+		}
+		workerServer.JoinMaster(masterAddr)
+		log.Printf("✓ Joining master at %s", masterAddr)
+	}
+
+	// GRPC_KEEPALIVE_* keep idle master connections from being dropped by NAT or firewalls
+	keepalive := server.DefaultKeepaliveConfig()
+	if value := os.Getenv("GRPC_KEEPALIVE_TIME"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			keepalive.Time = d
+		} else {
+			log.Printf("⚠️  Invalid GRPC_KEEPALIVE_TIME %q, using default %s", value, keepalive.Time)
+		}
+	}
+	if value := os.Getenv("GRPC_KEEPALIVE_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			keepalive.Timeout = d
+		} else {
+			log.Printf("⚠️  Invalid GRPC_KEEPALIVE_TIMEOUT %q, using default %s", value, keepalive.Timeout)
+		}
+	}
+	if value := os.Getenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM"); value != "" {
+		keepalive.PermitWithoutStream = value == "true"
+	}
+	workerServer.SetKeepalive(keepalive)
+
+	// GRPC_REFLECTION=true exposes the reflection service for grpcurl (off by default)
+	grpcServer := server.NewGRPCServer(workerServer, os.Getenv("GRPC_REFLECTION") == "true", keepalive, grpcCreds)
 
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)